@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/crypto"
@@ -283,3 +284,41 @@ func UnpackRevert(data []byte) (string, error) {
 	}
 	return unpacked[0].(string), nil
 }
+
+// panicSelector is the function selector of the Panic(uint256) error, which
+// solidity >= 0.8.0 reverts with when an internal check fails.
+var panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+// panicReasons are the descriptions of the panic codes defined by solidity, see
+// https://docs.soliditylang.org/en/latest/control-structures.html#panic-via-assert-and-error-via-require
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "out-of-bounds array access; popping on an empty array",
+	0x32: "out-of-bounds access of an array or bytesN",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+// UnpackPanic resolves the abi-encoded code of a Panic(uint256) revert and
+// returns it together with its description.
+func UnpackPanic(data []byte) (*big.Int, string, error) {
+	if len(data) < 4 || !bytes.Equal(data[:4], panicSelector) {
+		return nil, "", errors.New("invalid data for unpacking")
+	}
+	typ, _ := NewType("uint256", "", nil)
+	unpacked, err := (Arguments{{Type: typ}}).Unpack(data[4:])
+	if err != nil {
+		return nil, "", err
+	}
+	code := unpacked[0].(*big.Int)
+	reason, ok := panicReasons[code.Uint64()]
+	if !ok || !code.IsUint64() {
+		reason = "unknown panic code"
+	}
+	return code, reason, nil
+}
@@ -1152,6 +1152,39 @@ func TestUnpackRevert(t *testing.T) {
 	}
 }
 
+func TestUnpackPanic(t *testing.T) {
+	t.Parallel()
+
+	var cases = []struct {
+		input      string
+		expectCode uint64
+		expect     string
+		expectErr  bool
+	}{
+		{"", 0, "", true},
+		{"08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000d72657665727420726561736f6e00000000000000000000000000000000000000", 0, "", true},
+		{"4e487b710000000000000000000000000000000000000000000000000000000000000011", 0x11, "arithmetic underflow or overflow", false},
+		{"4e487b710000000000000000000000000000000000000000000000000000000000000099", 0x99, "unknown panic code", false},
+	}
+	for index, c := range cases {
+		t.Run(fmt.Sprintf("case %d", index), func(t *testing.T) {
+			code, got, err := UnpackPanic(common.Hex2Bytes(c.input))
+			if c.expectErr {
+				if err == nil {
+					t.Fatalf("Expected non-nil error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if code.Uint64() != c.expectCode || c.expect != got {
+				t.Fatalf("Output mismatch, want %x %v, got %x %v", c.expectCode, c.expect, code, got)
+			}
+		})
+	}
+}
+
 func TestCustomErrors(t *testing.T) {
 	json := `[{ "inputs": [	{ "internalType": "uint256", "name": "", "type": "uint256" } ],"name": "MyError", "type": "error"} ]`
 	abi, err := JSON(strings.NewReader(json))
@@ -472,7 +472,7 @@ type revertError struct {
 // ErrorCode returns the JSON error code for a revert.
 // See: https://github.com/ethereum/wiki/wiki/JSON-RPC-Error-Codes-Improvement-Proposal
 func (e *revertError) ErrorCode() int {
-	return 3
+	return rpc.ErrCodeReverted
 }
 
 // ErrorData returns the hex encoded revert reason.
@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/crypto"
+)
+
+// ManifestFileName - name of the manifest in the snapshots dir of a publisher
+const ManifestFileName = "manifest.json"
+
+// Manifest - snapshot files of a publisher with their torrents, signed by the publisher: users who trust the publisher's
+// address can download the files by info hashes from any source
+type Manifest struct {
+	Created   int64          `json:"created"`
+	Files     []ManifestFile `json:"files"`
+	Publisher common.Address `json:"publisher"`
+	Signature hexutil.Bytes  `json:"signature,omitempty"`
+}
+
+type ManifestFile struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	InfoHash string `json:"infoHash"`
+}
+
+// BuildManifest reads .torrent files of the given snapshot files, names are relative to snapDir
+func BuildManifest(snapDir string, names []string) (*Manifest, error) {
+	m := &Manifest{Created: time.Now().Unix(), Files: make([]ManifestFile, 0, len(names))}
+	for _, name := range names {
+		mi, err := metainfo.LoadFromFile(filepath.Join(snapDir, name+".torrent"))
+		if err != nil {
+			return nil, fmt.Errorf("torrent of %s: %w", name, err)
+		}
+		info, err := mi.UnmarshalInfo()
+		if err != nil {
+			return nil, fmt.Errorf("torrent of %s: %w", name, err)
+		}
+		m.Files = append(m.Files, ManifestFile{Name: name, Size: info.TotalLength(), InfoHash: mi.HashInfoBytes().String()})
+	}
+	return m, nil
+}
+
+// signingHash - hash of the manifest without the signature
+func (m *Manifest) signingHash() ([]byte, error) {
+	unsigned := *m
+	unsigned.Signature = nil
+	encoded, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// Sign sets the publisher to the address of the key and signs the manifest
+func (m *Manifest) Sign(key *ecdsa.PrivateKey) error {
+	m.Publisher = crypto.PubkeyToAddress(key.PublicKey)
+	hash, err := m.signingHash()
+	if err != nil {
+		return err
+	}
+	m.Signature, err = crypto.Sign(hash, key)
+	return err
+}
+
+// Verify checks that the manifest is signed by its publisher
+func (m *Manifest) Verify() error {
+	hash, err := m.signingHash()
+	if err != nil {
+		return err
+	}
+	pub, err := crypto.SigToPub(hash, m.Signature)
+	if err != nil {
+		return fmt.Errorf("manifest signature: %w", err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != m.Publisher {
+		return fmt.Errorf("manifest of %x is signed by %x", m.Publisher, signer)
+	}
+	return nil
+}
+
+func (m *Manifest) Write(path string) error {
+	encoded, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, encoded, 0644) // nolint
+}
+
+func ReadManifest(path string) (*Manifest, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(encoded, m); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", path, err)
+	}
+	return m, nil
+}
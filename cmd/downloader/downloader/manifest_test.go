@@ -0,0 +1,41 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	snapDir := t.TempDir()
+	name := "v1-000000-000500-headers.seg"
+	require.NoError(t, os.WriteFile(filepath.Join(snapDir, name), make([]byte, 1000), 0600))
+	require.NoError(t, buildTorrentIfNeed(name, snapDir))
+
+	m, err := BuildManifest(snapDir, []string{name})
+	require.NoError(t, err)
+	require.Len(t, m.Files, 1)
+	require.Equal(t, name, m.Files[0].Name)
+	require.Equal(t, int64(1000), m.Files[0].Size)
+	require.Len(t, m.Files[0].InfoHash, 40)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	require.NoError(t, m.Sign(key))
+	path := filepath.Join(snapDir, ManifestFileName)
+	require.NoError(t, m.Write(path))
+
+	read, err := ReadManifest(path)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), read.Publisher)
+	require.NoError(t, read.Verify())
+
+	read.Files[0].Size++
+	require.Error(t, read.Verify())
+
+	_, err = BuildManifest(snapDir, []string{"v1-000500-001000-headers.seg"})
+	require.Error(t, err)
+}
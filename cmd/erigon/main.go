@@ -53,6 +53,10 @@ func runErigon(cliCtx *cli.Context) {
 			log.Warn("failed setting config flags from yaml/toml file", "err", err)
 		}
 	}
+	if err := erigoncli.ApplyStageProfile(cliCtx); err != nil {
+		log.Error("Erigon startup", "err", err)
+		return
+	}
 
 	nodeCfg := node.NewNodConfigUrfave(cliCtx)
 	ethCfg := node.NewEthConfigUrfave(cliCtx, nodeCfg)
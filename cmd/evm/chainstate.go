@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/core/vm/runtime"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// chainState is a read-only view of the chaindata of a local node, positioned
+// at the end of a given block.
+type chainState struct {
+	db     kv.RoDB
+	tx     kv.Tx
+	header *types.Header
+	config *params.ChainConfig
+	reader state.StateReader
+}
+
+// openChainState opens the chaindata of the given datadir in read-only mode and
+// prepares a state reader which sees the state right after block blockNum was
+// executed. If blockNum is negative, the latest executed block is used.
+func openChainState(dataDir string, blockNum int64) (*chainState, error) {
+	dirs := datadir.New(dataDir)
+	db, err := mdbx.NewMDBX(log.New()).Path(dirs.Chaindata).Label(kv.ChainDB).Readonly().Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening chaindata %s: %w", dirs.Chaindata, err)
+	}
+	tx, err := db.BeginRo(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	cs := &chainState{db: db, tx: tx}
+	if err = cs.init(blockNum); err != nil {
+		cs.Close()
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (cs *chainState) init(blockNum int64) error {
+	executed, err := stages.GetStageProgress(cs.tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if blockNum < 0 {
+		blockNum = int64(executed)
+	}
+	if uint64(blockNum) > executed {
+		return fmt.Errorf("block %d is not executed yet, execution stage is at %d", blockNum, executed)
+	}
+	cs.header = rawdb.ReadHeaderByNumber(cs.tx, uint64(blockNum))
+	if cs.header == nil {
+		return fmt.Errorf("header of block %d not found in chaindata", blockNum)
+	}
+	genesisHash, err := rawdb.ReadCanonicalHash(cs.tx, 0)
+	if err != nil {
+		return err
+	}
+	if cs.config, err = rawdb.ReadChainConfig(cs.tx, genesisHash); err != nil {
+		return err
+	}
+	if cs.config == nil {
+		return fmt.Errorf("chain config not found for genesis %x", genesisHash)
+	}
+	if uint64(blockNum) == executed {
+		cs.reader = state.NewPlainStateReader(cs.tx)
+	} else {
+		cs.reader = state.NewPlainState(cs.tx, uint64(blockNum)+1)
+	}
+	return nil
+}
+
+// apply fills the block-related fields of the runtime config from the header
+// of the chosen block.
+func (cs *chainState) apply(cfg *runtime.Config) {
+	cfg.ChainConfig = cs.config
+	cfg.BlockNumber = new(big.Int).SetUint64(cs.header.Number.Uint64())
+	cfg.Time = new(big.Int).SetUint64(cs.header.Time)
+	cfg.Coinbase = cs.header.Coinbase
+	cfg.Difficulty = new(big.Int).Set(cs.header.Difficulty)
+	if cs.header.BaseFee != nil {
+		cfg.BaseFee, _ = uint256.FromBig(cs.header.BaseFee)
+	}
+	cfg.GetHashFn = core.GetHashFn(cs.header, func(hash common.Hash, number uint64) *types.Header {
+		return rawdb.ReadHeader(cs.tx, hash, number)
+	})
+}
+
+// dumpWriter collects the accounts written by IntraBlockState.CommitBlock: with
+// --datadir only the accounts touched by the run are dumped, not the whole chain
+// state. Storage holds the written slots only.
+type dumpWriter struct {
+	dump state.Dump
+}
+
+func newDumpWriter() *dumpWriter {
+	return &dumpWriter{dump: state.Dump{Accounts: map[common.Address]state.DumpAccount{}}}
+}
+
+func (w *dumpWriter) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	acc := w.dump.Accounts[address]
+	acc.Balance = account.Balance.ToBig().String()
+	acc.Nonce = account.Nonce
+	acc.Root = common.Hash{}.Bytes() // storage root is not calculated, as in state.Dumper
+	acc.CodeHash = account.CodeHash.Bytes()
+	w.dump.Accounts[address] = acc
+	return nil
+}
+
+func (w *dumpWriter) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	acc := w.dump.Accounts[address]
+	acc.Code = code
+	w.dump.Accounts[address] = acc
+	return nil
+}
+
+func (w *dumpWriter) DeleteAccount(address common.Address, original *accounts.Account) error {
+	delete(w.dump.Accounts, address)
+	return nil
+}
+
+func (w *dumpWriter) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	acc := w.dump.Accounts[address]
+	if acc.Storage == nil {
+		acc.Storage = map[string]string{}
+	}
+	acc.Storage[key.String()] = common.Bytes2Hex(value.Bytes())
+	w.dump.Accounts[address] = acc
+	return nil
+}
+
+func (w *dumpWriter) CreateContract(address common.Address) error {
+	return nil
+}
+
+// dumpTouched commits the state changes of the run and returns the JSON dump of
+// the touched accounts, with the code of the contracts among them.
+func dumpTouched(statedb *state.IntraBlockState, rules *params.Rules) ([]byte, error) {
+	w := newDumpWriter()
+	if err := statedb.CommitBlock(rules, w); err != nil {
+		return nil, err
+	}
+	for addr, acc := range w.dump.Accounts {
+		if acc.Code == nil {
+			if code := statedb.GetCode(addr); len(code) > 0 {
+				acc.Code = code
+				w.dump.Accounts[addr] = acc
+			}
+		}
+	}
+	return json.MarshalIndent(w.dump, "", "    ")
+}
+
+func (cs *chainState) Close() {
+	cs.tx.Rollback()
+	cs.db.Close()
+}
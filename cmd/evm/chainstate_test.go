@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/internal/cmdtest"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDatadirDump(t *testing.T) {
+	var (
+		contract  = common.HexToAddress("0xc0de")
+		untouched = common.HexToAddress("0xaaaa")
+		dataDir   = t.TempDir()
+	)
+	db := mdbx.NewMDBX(log.New()).Path(datadir.New(dataDir).Chaindata).Label(kv.ChainDB).MustOpen()
+	_, _, err := core.CommitGenesisBlock(db, &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			contract:  {Balance: big.NewInt(1), Code: common.FromHex("6001600055")}, // sstore(0, 1)
+			untouched: {Balance: big.NewInt(2)},
+		},
+	})
+	require.NoError(t, err)
+	db.Close()
+
+	tt := cmdtest.NewTestCmd(t, nil)
+	tt.Run("evm-test", "--datadir", dataDir, "--receiver", contract.Hex(), "--dump", "run")
+	out := tt.Output()
+	tt.WaitExit()
+	require.Zero(t, tt.ExitStatus(), tt.StderrText())
+
+	var dump state.Dump
+	require.NoError(t, json.NewDecoder(bytes.NewReader(out)).Decode(&dump))
+	require.Contains(t, dump.Accounts, contract)
+	require.NotContains(t, dump.Accounts, untouched, "only the accounts touched by the run are dumped")
+	acc := dump.Accounts[contract]
+	require.Equal(t, "1", acc.Balance)
+	require.Equal(t, map[string]string{common.Hash{}.String(): "01"}, acc.Storage)
+	require.Equal(t, common.FromHex("6001600055"), []byte(acc.Code))
+}
@@ -75,7 +75,7 @@ var (
 	}
 	DumpFlag = cli.BoolFlag{
 		Name:  "dump",
-		Usage: "dumps the state after the run",
+		Usage: "dumps the state after the run (only the accounts touched by the run with --datadir)",
 	}
 	InputFlag = cli.StringFlag{
 		Name:  "input",
@@ -129,6 +129,19 @@ var (
 		Name:  "noreturndata",
 		Usage: "disable return data output",
 	}
+	DataDirFlag = cli.StringFlag{
+		Name:  "datadir",
+		Usage: "Data directory of a local node; its chaindata is opened read-only and used as the pre-state",
+	}
+	BlockFlag = cli.Int64Flag{
+		Name:  "block",
+		Usage: "Block whose post-state and header are used when --datadir is set (default: latest executed block)",
+		Value: -1,
+	}
+	RawTxFlag = cli.StringFlag{
+		Name:  "rawtx",
+		Usage: "RLP-encoded signed transaction (hex) to execute instead of --code/--input",
+	}
 )
 
 var stateTransitionCommand = cli.Command{
@@ -179,6 +192,9 @@ func init() {
 		DisableStackFlag,
 		DisableStorageFlag,
 		DisableReturnDataFlag,
+		DataDirFlag,
+		BlockFlag,
+		RawTxFlag,
 	}
 	app.Commands = []cli.Command{
 		compileCommand,
@@ -26,11 +26,13 @@ import (
 	"os"
 	goruntime "runtime"
 	"runtime/pprof"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/holiman/uint256"
 	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/urfave/cli"
 
@@ -39,6 +41,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/core/vm/runtime"
 	"github.com/ledgerwatch/erigon/params"
@@ -46,11 +49,48 @@ import (
 )
 
 var runCommand = cli.Command{
-	Action:      runCmd,
-	Name:        "run",
-	Usage:       "run arbitrary evm binary",
-	ArgsUsage:   "<code>",
-	Description: `The run command runs arbitrary EVM code.`,
+	Action:    runCmd,
+	Name:      "run",
+	Usage:     "run arbitrary evm binary",
+	ArgsUsage: "<code>",
+	Description: `The run command runs arbitrary EVM code.
+
+When --datadir is given, the chaindata of a local node is opened read-only and
+the code (or the transaction given by --rawtx) is executed on top of the state
+right after the block selected by --block. --dump then prints only the accounts
+touched by the run, with their post-run values and the written storage slots.`,
+}
+
+// decodeRawTx decodes a hex-encoded signed transaction and converts it into a
+// message using the chain rules of the runtime config. The gas price of the
+// config is overridden by the effective gas price of the transaction.
+func decodeRawTx(rawTx string, cfg *runtime.Config) (types.Message, error) {
+	txn, err := types.UnmarshalTransactionFromBinary(common.FromHex(strings.TrimSpace(rawTx)))
+	if err != nil {
+		return types.Message{}, fmt.Errorf("decoding transaction: %w", err)
+	}
+	if cfg.BlockNumber == nil {
+		cfg.BlockNumber = new(big.Int)
+	}
+	if cfg.Difficulty == nil {
+		cfg.Difficulty = new(big.Int)
+	}
+	if cfg.GetHashFn == nil {
+		cfg.GetHashFn = func(n uint64) common.Hash { return common.Hash{} }
+	}
+	blockNum := cfg.BlockNumber.Uint64()
+	var baseFee *big.Int
+	if cfg.BaseFee != nil {
+		baseFee = cfg.BaseFee.ToBig()
+	}
+	signer := types.MakeSigner(cfg.ChainConfig, blockNum)
+	msg, err := txn.AsMessage(*signer, baseFee, cfg.ChainConfig.Rules(blockNum))
+	if err != nil {
+		return types.Message{}, fmt.Errorf("converting transaction to message: %w", err)
+	}
+	cfg.Origin = msg.From()
+	cfg.GasPrice = msg.GasPrice().ToBig()
+	return msg, nil
 }
 
 // readGenesis will read the given JSON format genesis file and return
@@ -142,26 +182,48 @@ func runCmd(ctx *cli.Context) error {
 	} else {
 		debugLogger = vm.NewStructLogger(logconfig)
 	}
-	db := memdb.New()
-	if ctx.GlobalString(GenesisFlag.Name) != "" {
-		gen := readGenesis(ctx.GlobalString(GenesisFlag.Name))
-		gen.MustCommit(db)
-		genesisConfig = gen
-		chainConfig = gen.Config
-	} else {
+	var (
+		tx    kv.Tx
+		chain *chainState
+	)
+	if dataDir := ctx.GlobalString(DataDirFlag.Name); dataDir != "" {
+		if ctx.GlobalString(GenesisFlag.Name) != "" {
+			return fmt.Errorf("--%s and --%s are mutually exclusive", DataDirFlag.Name, GenesisFlag.Name)
+		}
+		var err error
+		if chain, err = openChainState(dataDir, ctx.GlobalInt64(BlockFlag.Name)); err != nil {
+			return err
+		}
+		defer chain.Close()
 		genesisConfig = new(core.Genesis)
+		chainConfig = chain.config
+		tx = chain.tx
+		statedb = state.New(chain.reader)
+	} else {
+		db := memdb.New()
+		if ctx.GlobalString(GenesisFlag.Name) != "" {
+			gen := readGenesis(ctx.GlobalString(GenesisFlag.Name))
+			gen.MustCommit(db)
+			genesisConfig = gen
+			chainConfig = gen.Config
+		} else {
+			genesisConfig = new(core.Genesis)
+		}
+		rwTx, err := db.BeginRw(context.Background())
+		if err != nil {
+			return err
+		}
+		defer rwTx.Rollback()
+		tx = rwTx
+		statedb = state.New(state.NewPlainStateReader(rwTx))
 	}
-	tx, err := db.BeginRw(context.Background())
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	statedb = state.New(state.NewPlainStateReader(tx))
 	if ctx.GlobalString(SenderFlag.Name) != "" {
 		sender = common.HexToAddress(ctx.GlobalString(SenderFlag.Name))
 	}
-	statedb.CreateAccount(sender, true)
+	// Live chain state must not be clobbered by resetting an existing sender account
+	if chain == nil || !statedb.Exist(sender) {
+		statedb.CreateAccount(sender, true)
+	}
 
 	if ctx.GlobalString(ReceiverFlag.Name) != "" {
 		receiver = common.HexToAddress(ctx.GlobalString(ReceiverFlag.Name))
@@ -250,6 +312,9 @@ func runCmd(ctx *cli.Context) error {
 	} else {
 		runtimeConfig.ChainConfig = params.AllEthashProtocolChanges
 	}
+	if chain != nil {
+		chain.apply(&runtimeConfig)
+	}
 
 	var hexInput []byte
 	if inputFileFlag := ctx.GlobalString(InputFileFlag.Name); inputFileFlag != "" {
@@ -263,8 +328,27 @@ func runCmd(ctx *cli.Context) error {
 	}
 	input := common.FromHex(string(bytes.TrimSpace(hexInput)))
 
+	bench := ctx.GlobalBool(BenchFlag.Name)
 	var execFunc func() ([]byte, uint64, error)
-	if ctx.GlobalBool(CreateFlag.Name) {
+	if rawTx := ctx.GlobalString(RawTxFlag.Name); rawTx != "" {
+		if bench {
+			return fmt.Errorf("--%s cannot be combined with --%s", RawTxFlag.Name, BenchFlag.Name)
+		}
+		msg, err := decodeRawTx(rawTx, &runtimeConfig)
+		if err != nil {
+			return err
+		}
+		initialGas = msg.Gas()
+		execFunc = func() ([]byte, uint64, error) {
+			evm := runtime.NewEnv(&runtimeConfig)
+			evm.Reset(core.NewEVMTxContext(msg), statedb)
+			result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()), true /* refunds */, false /* gasBailout */)
+			if err != nil {
+				return nil, 0, err
+			}
+			return result.ReturnData, msg.Gas() - result.UsedGas, result.Err
+		}
+	} else if ctx.GlobalBool(CreateFlag.Name) {
 		input = append(code, input...)
 		execFunc = func() ([]byte, uint64, error) {
 			output, _, gasLeft, err := runtime.Create(input, &runtimeConfig, 0)
@@ -279,7 +363,6 @@ func runCmd(ctx *cli.Context) error {
 		}
 	}
 
-	bench := ctx.GlobalBool(BenchFlag.Name)
 	output, leftOverGas, stats, err := timedExec(bench, execFunc)
 
 	if ctx.GlobalBool(DumpFlag.Name) {
@@ -287,11 +370,20 @@ func runCmd(ctx *cli.Context) error {
 		if chainConfig != nil {
 			rules = chainConfig.Rules(runtimeConfig.BlockNumber.Uint64())
 		}
-		if err = statedb.CommitBlock(rules, state.NewNoopWriter()); err != nil {
-			fmt.Println("Could not commit state: ", err)
-			os.Exit(1)
+		if chain != nil {
+			dump, err := dumpTouched(statedb, rules)
+			if err != nil {
+				fmt.Println("Could not commit state: ", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(dump))
+		} else {
+			if err = statedb.CommitBlock(rules, state.NewNoopWriter()); err != nil {
+				fmt.Println("Could not commit state: ", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(state.NewDumper(tx, 0).DefaultDump()))
 		}
-		fmt.Println(string(state.NewDumper(tx, 0).DefaultDump()))
 	}
 
 	if memProfilePath := ctx.GlobalString(MemProfileFlag.Name); memProfilePath != "" {
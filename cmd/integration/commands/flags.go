@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"time"
+
 	"github.com/ledgerwatch/erigon/turbo/cli"
 	"github.com/spf13/cobra"
 
@@ -32,6 +34,10 @@ var (
 
 	_forceSetHistoryV3 bool
 	workers            uint64
+	segmentSize        uint64
+	shardSize          uint64
+	fromBlock, toBlock uint64
+	throttle           time.Duration
 )
 
 func must(err error) {
@@ -8,6 +8,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	kv2 "github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/internal/debug"
 	"github.com/ledgerwatch/erigon/migrations"
 	"github.com/ledgerwatch/log/v3"
@@ -41,7 +42,7 @@ func dbCfg(label kv.Label, path string) kv2.MdbxOpts {
 	limiterB := semaphore.NewWeighted(int64(runtime.NumCPU()*10 + 1))
 	opts := kv2.NewMDBX(log.New()).Path(path).Label(label).RoTxsLimiter(limiterB)
 	if label == kv.ChainDB {
-		opts = opts.MapSize(8 * datasize.TB)
+		opts = opts.MapSize(8 * datasize.TB).WithTableCfg(rawdb.ChaindataTablesCfg)
 	}
 	if databaseVerbosity != -1 {
 		opts = opts.DBVerbosity(kv.DBVerbosityLvl(databaseVerbosity))
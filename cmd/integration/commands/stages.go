@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/c2h5oh/datasize"
+	"github.com/holiman/uint256"
 	common2 "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/cmp"
 	"github.com/ledgerwatch/erigon-lib/common/dir"
@@ -36,6 +37,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/integrity"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/migrations"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
@@ -192,6 +194,22 @@ var cmdLogIndex = &cobra.Command{
 	},
 }
 
+var cmdTokenTransfers = &cobra.Command{
+	Use:   "stage_token_transfers",
+	Short: "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, _ := common2.RootContext()
+		db := openDB(dbCfg(kv.ChainDB, chaindata), true)
+		defer db.Close()
+
+		if err := stageTokenTransfers(db, ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
 var cmdCallTraces = &cobra.Command{
 	Use:   "stage_call_traces",
 	Short: "",
@@ -271,11 +289,73 @@ var cmdRemoveMigration = &cobra.Command{
 
 var cmdRunMigrations = &cobra.Command{
 	Use:   "run_migrations",
-	Short: "",
+	Short: "Apply pending DB migrations and verify that all of them are applied",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		db := openDB(dbCfg(kv.ChainDB, chaindata), true) // applies pending migrations
+		defer db.Close()
+		if err := verifyMigrations(db); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
+var cmdVerifyMigrations = &cobra.Command{
+	Use:   "verify_migrations",
+	Short: "Check that all DB migrations are applied completely, without applying them",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db := openDB(dbCfg(kv.ChainDB, chaindata).Readonly(), false)
+		defer db.Close()
+		if err := verifyMigrations(db); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
+var cmdRepackSnapshots = &cobra.Command{
+	Use:   "repack_snapshots",
+	Short: "Merge small block snapshot segments into segments of --segment.size blocks and build their indices (node must be stopped)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, _ := common2.RootContext()
 		db := openDB(dbCfg(kv.ChainDB, chaindata), true)
 		defer db.Close()
-		// Nothing to do, migrations will be applied automatically
+		if err := repackSnapshots(db, ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
+var cmdRebuildReceipts = &cobra.Command{
+	Use:   "rebuild_receipts",
+	Short: "Re-execute blocks --from..--to to regenerate receipts, logs and call indices. Without --to continues the unfinished rebuild",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, _ := common2.RootContext()
+		db := openDB(dbCfg(kv.ChainDB, chaindata), true)
+		defer db.Close()
+		if err := rebuildReceipts(db, ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
+var cmdReshardIndex = &cobra.Command{
+	Use:   "reshard_index",
+	Short: "Re-shard bitmaps of the call/log index --bucket to --shard.size bytes. Without --bucket continues unfinished re-sharding",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, _ := common2.RootContext()
+		db := openDB(dbCfg(kv.ChainDB, chaindata), true)
+		defer db.Close()
+		if err := reshardIndex(db, ctx); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
 		return nil
 	},
 }
@@ -409,6 +489,15 @@ func init() {
 
 	rootCmd.AddCommand(cmdLogIndex)
 
+	withDataDir(cmdTokenTransfers)
+	withReset(cmdTokenTransfers)
+	withUnwind(cmdTokenTransfers)
+	withPruneTo(cmdTokenTransfers)
+	withChain(cmdTokenTransfers)
+	withHeimdall(cmdTokenTransfers)
+
+	rootCmd.AddCommand(cmdTokenTransfers)
+
 	withDataDir(cmdCallTraces)
 	withReset(cmdCallTraces)
 	withBlock(cmdCallTraces)
@@ -443,6 +532,25 @@ func init() {
 	withHeimdall(cmdRunMigrations)
 	rootCmd.AddCommand(cmdRunMigrations)
 
+	withDataDir(cmdVerifyMigrations)
+	rootCmd.AddCommand(cmdVerifyMigrations)
+
+	withDataDir(cmdRepackSnapshots)
+	cmdRepackSnapshots.Flags().Uint64Var(&segmentSize, "segment.size", snap.Erigon2SegmentSize, "blocks in segment after repacking, multiple of 1000")
+	rootCmd.AddCommand(cmdRepackSnapshots)
+
+	withDataDir(cmdRebuildReceipts)
+	cmdRebuildReceipts.Flags().Uint64Var(&fromBlock, "from", 0, "first block to re-execute")
+	cmdRebuildReceipts.Flags().Uint64Var(&toBlock, "to", 0, "last block to re-execute")
+	cmdRebuildReceipts.Flags().DurationVar(&throttle, "throttle", 0, "pause between batches of blocks")
+	rootCmd.AddCommand(cmdRebuildReceipts)
+
+	withDataDir(cmdReshardIndex)
+	cmdReshardIndex.Flags().StringVar(&bucket, "bucket", "", "index to re-shard: "+kv.CallFromIndex+", "+kv.CallToIndex+", "+kv.LogAddressIndex+" or "+kv.LogTopicIndex)
+	cmdReshardIndex.Flags().Uint64Var(&shardSize, "shard.size", bitmapdb.ChunkLimit, "max size of a shard in bytes")
+	cmdReshardIndex.Flags().DurationVar(&throttle, "throttle", 0, "pause between batches of bitmaps")
+	rootCmd.AddCommand(cmdReshardIndex)
+
 	withDataDir2(cmdSetSnap)
 	withChain(cmdSetSnap)
 	rootCmd.AddCommand(cmdSetSnap)
@@ -717,7 +825,7 @@ func stageExec(db kv.RwDB, ctx context.Context) error {
 	genesis := core.DefaultGenesisBlockByChainName(chain)
 	cfg := stagedsync.StageExecuteBlocksCfg(db, pm, batchSize, nil, chainConfig, engine, vmConfig, nil,
 		/*stateStream=*/ false,
-		/*badBlockHalt=*/ false, historyV3, dirs, getBlockReader(db), nil, genesis, int(workers), agg)
+		/*badBlockHalt=*/ false, historyV3, dirs, getBlockReader(db), nil, genesis, int(workers), agg, nil, nil)
 	if unwind > 0 {
 		u := sync.NewUnwindState(stages.Execution, s.BlockNumber-unwind, s.BlockNumber)
 		err := stagedsync.UnwindExecutionStage(u, s, nil, ctx, cfg, true)
@@ -776,7 +884,7 @@ func stageTrie(db kv.RwDB, ctx context.Context) error {
 
 	log.Info("StageExec", "progress", execStage.BlockNumber)
 	log.Info("StageTrie", "progress", s.BlockNumber)
-	cfg := stagedsync.StageTrieCfg(db, true, true, false, dirs.Tmp, getBlockReader(db), nil, historyV3, agg)
+	cfg := stagedsync.StageTrieCfg(db, true, true, false, dirs, getBlockReader(db), nil, historyV3, agg)
 	if unwind > 0 {
 		u := sync.NewUnwindState(stages.IntermediateHashes, s.BlockNumber-unwind, s.BlockNumber)
 		if err := stagedsync.UnwindIntermediateHashesStage(u, s, tx, cfg, ctx); err != nil {
@@ -912,6 +1020,60 @@ func stageLogIndex(db kv.RwDB, ctx context.Context) error {
 	return tx.Commit()
 }
 
+func stageTokenTransfers(db kv.RwDB, ctx context.Context) error {
+	dirs, pm, historyV3 := datadir.New(datadirCli), fromdb.PruneMode(db), fromdb.HistoryV3(db)
+	if historyV3 {
+		return fmt.Errorf("this stage is disable in --history.v3=true")
+	}
+	_, _, sync, _, _ := newSync(ctx, db, nil)
+	must(sync.SetCurrentStage(stages.TokenTransfers))
+	tx, err := db.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if reset {
+		err = reset2.ResetTokenTransfers(tx)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	execAt := progress(tx, stages.Execution)
+	s := stage(sync, tx, nil, stages.TokenTransfers)
+	if pruneTo > 0 {
+		pm.Receipts = prune.Distance(s.BlockNumber - pruneTo)
+	}
+
+	log.Info("Stage exec", "progress", execAt)
+	log.Info("Stage", "name", s.ID, "progress", s.BlockNumber)
+
+	cfg := stagedsync.StageTokenTransfersCfg(db, pm, dirs.Tmp)
+	if unwind > 0 {
+		u := sync.NewUnwindState(stages.TokenTransfers, s.BlockNumber-unwind, s.BlockNumber)
+		err = stagedsync.UnwindTokenTransfers(u, s, tx, cfg, ctx)
+		if err != nil {
+			return err
+		}
+	} else if pruneTo > 0 {
+		p, err := sync.PruneStageState(stages.TokenTransfers, s.BlockNumber, nil, db)
+		if err != nil {
+			return err
+		}
+		err = stagedsync.PruneTokenTransfers(p, tx, cfg, ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		if err := stagedsync.SpawnTokenTransfers(s, tx, cfg, ctx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func stageCallTraces(db kv.RwDB, ctx context.Context) error {
 	dirs, pm, historyV3 := datadir.New(datadirCli), fromdb.PruneMode(db), fromdb.HistoryV3(db)
 	if historyV3 {
@@ -1127,10 +1289,64 @@ func printAppliedMigrations(db kv.RwDB, ctx context.Context) error {
 		}
 		slices.Sort(appliedStrs)
 		log.Info("Applied", "migrations", strings.Join(appliedStrs, " "))
+		pending, err := migrations.NewMigrator(kv.ChainDB).PendingMigrations(tx)
+		if err != nil {
+			return err
+		}
+		pendingStrs := make([]string, len(pending))
+		for i := range pending {
+			pendingStrs[i] = pending[i].Name
+		}
+		log.Info("Pending", "migrations", strings.Join(pendingStrs, " "))
 		return nil
 	})
 }
 
+func verifyMigrations(db kv.RoDB) error {
+	if err := migrations.NewMigrator(kv.ChainDB).VerifyApplied(db); err != nil {
+		return err
+	}
+	log.Info("All migrations are applied")
+	return nil
+}
+
+func repackSnapshots(db kv.RwDB, ctx context.Context) error {
+	sn, _ := allSnapshots(db)
+	if !sn.Cfg().Enabled {
+		return fmt.Errorf("snapshots are not enabled in this datadir")
+	}
+	dirs := datadir.New(datadirCli)
+	chainConfig := fromdb.ChainConfig(db)
+	chainID, _ := uint256.FromBig(chainConfig.ChainID)
+	return snapshotsync.RepackBlocks(ctx, sn, db, segmentSize, dirs.Tmp, *chainID, log.LvlInfo)
+}
+
+func rebuildReceipts(db kv.RwDB, ctx context.Context) error {
+	if toBlock > 0 {
+		if err := db.Update(ctx, func(tx kv.RwTx) error {
+			return stagedsync.StartRebuildReceipts(tx, fromBlock, toBlock)
+		}); err != nil {
+			return err
+		}
+	}
+	chainConfig := fromdb.ChainConfig(db)
+	sn, _ := allSnapshots(db)
+	engine := initConsensusEngine(chainConfig, log.New(), sn, datadirCli, db)
+	cfg := stagedsync.StageRebuildReceiptsCfg(db, chainConfig, engine, getBlockReader(db), 1000, throttle)
+	return stagedsync.RebuildReceipts(ctx, cfg)
+}
+
+func reshardIndex(db kv.RwDB, ctx context.Context) error {
+	if bucket != "" {
+		if err := db.Update(ctx, func(tx kv.RwTx) error {
+			return stagedsync.StartReshardIndex(tx, bucket, shardSize)
+		}); err != nil {
+			return err
+		}
+	}
+	return stagedsync.ReshardIndex(ctx, db, 1000, throttle)
+}
+
 func removeMigration(db kv.RwDB, ctx context.Context) error {
 	return db.Update(ctx, func(tx kv.RwTx) error {
 		return tx.Delete(kv.Migrations, []byte(migration))
@@ -1249,7 +1465,7 @@ func newSync(ctx context.Context, db kv.RwDB, miningConfig *params.MiningConfig)
 		panic(err)
 	}
 
-	sync, err := stages2.NewStagedSync(context.Background(), db, p2p.Config{}, &cfg, sentryControlServer, &shards.Notifications{}, nil, allSn, agg, nil)
+	sync, err := stages2.NewStagedSync(context.Background(), db, p2p.Config{}, &cfg, sentryControlServer, &shards.Notifications{}, nil, allSn, agg, nil, nil, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -1264,7 +1480,7 @@ func newSync(ctx context.Context, db kv.RwDB, miningConfig *params.MiningConfig)
 			stagedsync.StageMiningCreateBlockCfg(db, miner, *chainConfig, engine, nil, nil, nil, dirs.Tmp),
 			stagedsync.StageMiningExecCfg(db, miner, events, *chainConfig, engine, &vm.Config{}, dirs.Tmp, nil, 0),
 			stagedsync.StageHashStateCfg(db, dirs, historyV3, agg),
-			stagedsync.StageTrieCfg(db, false, true, false, dirs.Tmp, br, nil, historyV3, agg),
+			stagedsync.StageTrieCfg(db, false, true, false, dirs, br, nil, historyV3, agg),
 			stagedsync.StageMiningFinishCfg(db, *chainConfig, engine, miner, miningCancel),
 		),
 		stagedsync.MiningUnwindOrder,
@@ -16,6 +16,8 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common/dir"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/ethdb/kvreaders"
+	"github.com/ledgerwatch/erigon/ethdb/kvstats"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 
 	"github.com/ledgerwatch/erigon-lib/direct"
@@ -63,26 +65,50 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	utils.CobraFlags(rootCmd, append(debug.Flags, utils.MetricFlags...))
 
 	cfg := &httpcfg.HttpCfg{Enabled: true, StateCache: kvcache.DefaultCoherentConfig}
+	rootCmd.PersistentFlags().StringVar(&cfg.ConfigFile, utils.ConfigFlag.Name, "", "Sets rpcdaemon flags from YAML/TOML file, command line flags win. Re-read on SIGHUP: CORS, virtual hosts, access list, batch concurrency, worker pools and websocket limits are replaced, other flags require a restart")
 	rootCmd.PersistentFlags().StringVar(&cfg.PrivateApiAddr, "private.api.addr", "127.0.0.1:9090", "private api network address, for example: 127.0.0.1:9090")
+	rootCmd.PersistentFlags().StringVar(&cfg.DownloaderAddr, utils.DownloaderAddrFlag.Name, "", "Downloader api network address, for example: 127.0.0.1:9093, eth_syncing reports the snapshot download from it (empty - not reported)")
 	rootCmd.PersistentFlags().StringVar(&cfg.DataDir, "datadir", "", "path to Erigon working directory")
 	rootCmd.PersistentFlags().StringVar(&cfg.HttpListenAddress, "http.addr", nodecfg.DefaultHTTPHost, "HTTP-RPC server listening interface")
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSCertfile, "tls.cert", "", "certificate for client side TLS handshake")
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSKeyFile, "tls.key", "", "key file for client side TLS handshake")
 	rootCmd.PersistentFlags().StringVar(&cfg.TLSCACert, "tls.cacert", "", "CA certificate for client side TLS handshake")
 	rootCmd.PersistentFlags().IntVar(&cfg.HttpPort, "http.port", nodecfg.DefaultHTTPPort, "HTTP-RPC server listening port")
-	rootCmd.PersistentFlags().StringSliceVar(&cfg.HttpCORSDomain, "http.corsdomain", []string{}, "Comma separated list of domains from which to accept cross origin requests (browser enforced)")
-	rootCmd.PersistentFlags().StringSliceVar(&cfg.HttpVirtualHost, "http.vhosts", nodecfg.DefaultConfig.HTTPVirtualHosts, "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.")
+	reloadableFlags(rootCmd.PersistentFlags(), cfg)
 	rootCmd.PersistentFlags().BoolVar(&cfg.HttpCompression, "http.compression", true, "Disable http compression")
 	rootCmd.PersistentFlags().StringSliceVar(&cfg.API, "http.api", []string{"eth", "erigon"}, "API's offered over the HTTP-RPC interface: eth,erigon,web3,net,debug,trace,txpool,db. Supported methods: https://github.com/ledgerwatch/erigon/tree/devel/cmd/rpcdaemon")
 	rootCmd.PersistentFlags().Uint64Var(&cfg.Gascap, "rpc.gascap", 50000000, "Sets a cap on gas that can be used in eth_call/estimateGas")
 	rootCmd.PersistentFlags().Uint64Var(&cfg.MaxTraces, "trace.maxtraces", 200, "Sets a limit on traces that can be returned in trace_filter")
+	rootCmd.PersistentFlags().Uint64Var(&cfg.LogsMaxRange, utils.RpcLogsMaxRangeFlag.Name, utils.RpcLogsMaxRangeFlag.Value, utils.RpcLogsMaxRangeFlag.Usage)
+	rootCmd.PersistentFlags().Uint64Var(&cfg.LogsMaxResults, utils.RpcLogsMaxResultsFlag.Name, utils.RpcLogsMaxResultsFlag.Value, utils.RpcLogsMaxResultsFlag.Usage)
+	rootCmd.PersistentFlags().BoolVar(&cfg.ReceiptsRevertReason, utils.RpcReceiptsRevertReasonFlag.Name, false, utils.RpcReceiptsRevertReasonFlag.Usage)
+	rootCmd.PersistentFlags().Uint64Var(&cfg.NonCanonicalTxs, utils.RpcNonCanonicalTxsFlag.Name, utils.RpcNonCanonicalTxsFlag.Value, utils.RpcNonCanonicalTxsFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketEnabled, "ws", false, "Enable Websockets")
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketCompression, "ws.compression", false, "Enable Websocket compression (RFC 7692)")
-	rootCmd.PersistentFlags().StringVar(&cfg.RpcAllowListFilePath, "rpc.accessList", "", "Specify granular (method-by-method) API allowlist")
-	rootCmd.PersistentFlags().UintVar(&cfg.RpcBatchConcurrency, utils.RpcBatchConcurrencyFlag.Name, 2, utils.RpcBatchConcurrencyFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.RpcStreamingDisable, utils.RpcStreamingDisableFlag.Name, false, utils.RpcStreamingDisableFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.DBReadConcurrency, utils.DBReadConcurrencyFlag.Name, utils.DBReadConcurrencyFlag.Value, utils.DBReadConcurrencyFlag.Usage)
+	var readersFreelist, readersAction string
+	var readersMaxAge time.Duration
+	rootCmd.PersistentFlags().StringVar(&readersFreelist, utils.RpcReadersFreelistFlag.Name, "", utils.RpcReadersFreelistFlag.Usage)
+	rootCmd.PersistentFlags().DurationVar(&readersMaxAge, utils.RpcReadersMaxAgeFlag.Name, utils.RpcReadersMaxAgeFlag.Value, utils.RpcReadersMaxAgeFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&readersAction, utils.RpcReadersActionFlag.Name, utils.RpcReadersActionFlag.Value, utils.RpcReadersActionFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.TraceCompatibility, "trace.compat", false, "Bug for bug compatibility with OE for trace_ routines")
+	rootCmd.PersistentFlags().StringVar(&cfg.OtsLabelsPath, utils.OtsLabelsPathFlag.Name, "", utils.OtsLabelsPathFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.OtsCreatorsPath, utils.OtsCreatorsPathFlag.Name, "", utils.OtsCreatorsPathFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.OtsSourcifySource, utils.OtsSourcifySourceFlag.Name, "", utils.OtsSourcifySourceFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.OtsSourcifyCachePath, utils.OtsSourcifyCachePathFlag.Name, "", utils.OtsSourcifyCachePathFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.OtsSignaturesPath, utils.OtsSignaturesPathFlag.Name, "", utils.OtsSignaturesPathFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.OtsSearchWorkers, utils.OtsSearchWorkersFlag.Name, utils.OtsSearchWorkersFlag.Value, utils.OtsSearchWorkersFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.OtsSearchCache, utils.OtsSearchCacheFlag.Name, utils.OtsSearchCacheFlag.Value, utils.OtsSearchCacheFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.OtsOperationsPath, utils.OtsOperationsPathFlag.Name, "", utils.OtsOperationsPathFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.TraceExport.Dir, utils.RpcTraceExportDirFlag.Name, "", utils.RpcTraceExportDirFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.TraceExport.URL, utils.RpcTraceExportURLFlag.Name, "", utils.RpcTraceExportURLFlag.Usage)
+	rootCmd.PersistentFlags().DurationVar(&cfg.TraceExport.Retention, utils.RpcTraceExportRetentionFlag.Name, utils.RpcTraceExportRetentionFlag.Value, utils.RpcTraceExportRetentionFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.TraceBlockWorkers, utils.RpcTraceBlockWorkersFlag.Name, utils.RpcTraceBlockWorkersFlag.Value, utils.RpcTraceBlockWorkersFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.ScheduledTxs.Limit, utils.TxPoolScheduledLimitFlag.Name, utils.TxPoolScheduledLimitFlag.Value, utils.TxPoolScheduledLimitFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.ScheduledTxs.SenderLimit, utils.TxPoolScheduledSenderLimitFlag.Name, utils.TxPoolScheduledSenderLimitFlag.Value, utils.TxPoolScheduledSenderLimitFlag.Usage)
+	rootCmd.PersistentFlags().Uint64Var(&cfg.ScheduledTxs.MaxBlocksAhead, utils.TxPoolScheduledMaxBlocksFlag.Name, utils.TxPoolScheduledMaxBlocksFlag.Value, utils.TxPoolScheduledMaxBlocksFlag.Usage)
+	rootCmd.PersistentFlags().DurationVar(&cfg.ScheduledTxs.MaxTimeAhead, utils.TxPoolScheduledMaxTimeFlag.Name, utils.TxPoolScheduledMaxTimeFlag.Value, utils.TxPoolScheduledMaxTimeFlag.Usage)
 	rootCmd.PersistentFlags().StringVar(&cfg.TxPoolApiAddr, "txpool.api.addr", "", "txpool api network address, for example: 127.0.0.1:9090 (default: use value of --private.api.addr)")
 	rootCmd.PersistentFlags().BoolVar(&cfg.Sync.UseSnapshots, "snapshot", true, utils.SnapshotFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.StateCache.KeysLimit, "state.cache", kvcache.DefaultCoherentConfig.KeysLimit, "Amount of keys to store in StateCache (enabled if no --datadir set). Set 0 to disable StateCache. 1_000_000 keys ~ equal to 2Gb RAM (maybe we will add RAM accounting in future versions).")
@@ -104,6 +130,15 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	}
 
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if cfg.ConfigFile != "" {
+			applied, err := setFlagsFromConfigFile(cmd.Flags(), cfg.ConfigFile)
+			if err != nil {
+				return err
+			}
+			startupConfig = applied
+			cfg.ConfigRevision = &httpcfg.ConfigRevision{}
+			cfg.ConfigRevision.Applied(cfg.ConfigFile, applied.content, nil)
+		}
 		if err := utils.SetupCobra(cmd); err != nil {
 			return err
 		}
@@ -117,6 +152,11 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 		if cfg.TxPoolApiAddr == "" {
 			cfg.TxPoolApiAddr = cfg.PrivateApiAddr
 		}
+		readerPolicy, err := kvreaders.ParsePolicy(readersFreelist, readersMaxAge, readersAction)
+		if err != nil {
+			return fmt.Errorf("invalid policy of long readers: %w", err)
+		}
+		cfg.ReaderPolicy = readerPolicy
 		return nil
 	}
 	rootCmd.PersistentPostRunE = func(cmd *cobra.Command, args []string) error {
@@ -255,7 +295,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		var rwKv kv.RwDB
 		log.Trace("Creating chain db", "path", cfg.Dirs.Chaindata)
 		limiter := semaphore.NewWeighted(int64(cfg.DBReadConcurrency))
-		rwKv, err = kv2.NewMDBX(logger).RoTxsLimiter(limiter).Path(cfg.Dirs.Chaindata).Readonly().Open()
+		rwKv, err = kv2.NewMDBX(logger).RoTxsLimiter(limiter).Path(cfg.Dirs.Chaindata).WithTableCfg(rawdb.ChaindataTablesCfg).Readonly().Open()
 		if err != nil {
 			return nil, nil, nil, nil, nil, nil, nil, ff, nil, err
 		}
@@ -462,6 +502,9 @@ func startRegularRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rp
 
 	log.Trace("TraceRequests = %t\n", cfg.TraceRequests)
 	srv := rpc.NewServer(cfg.RpcBatchConcurrency, cfg.TraceRequests, cfg.RpcStreamingDisable)
+	srv.SetScheduler(rpc.NewScheduler(cfg.RpcWorkers))
+	srv.SetDBStats(kvstats.RPCStats{})
+	srv.SetWebsocketLimits(cfg.WebsocketLimits)
 
 	allowListForRPC, err := parseAllowListForRPC(cfg.RpcAllowListFilePath)
 	if err != nil {
@@ -489,6 +532,11 @@ func startRegularRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rp
 	}
 
 	httpHandler := node.NewHTTPHandlerStack(srv, cfg.HttpCORSDomain, cfg.HttpVirtualHost, cfg.HttpCompression)
+	if cfg.ConfigFile != "" && startupConfig != nil {
+		reloader := newConfigReloader(cfg, rootCmd.Flags(), startupConfig, srv, httpHandler)
+		httpHandler = reloader.handler
+		go reloader.reloadOnSIGHUP(ctx)
+	}
 	var wsHandler http.Handler
 	if cfg.WebsocketEnabled {
 		wsHandler = srv.WebsocketHandler([]string{"*"}, nil, cfg.WebsocketCompression)
@@ -559,6 +607,7 @@ type engineInfo struct {
 func startAuthenticatedRpcServer(cfg httpcfg.HttpCfg, rpcAPI []rpc.API) (*engineInfo, error) {
 	log.Trace("TraceRequests = %t\n", cfg.TraceRequests)
 	srv := rpc.NewServer(cfg.RpcBatchConcurrency, cfg.TraceRequests, cfg.RpcStreamingDisable)
+	srv.SetDBStats(kvstats.RPCStats{})
 
 	engineListener, engineSrv, engineHttpEndpoint, err := createEngineListener(cfg, rpcAPI)
 	if err != nil {
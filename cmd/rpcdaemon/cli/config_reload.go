@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/node"
+	"github.com/ledgerwatch/erigon/node/nodecfg"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/pelletier/go-toml"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// reloadableFlags - flags re-applied from the config file on SIGHUP. They only affect requests and connections
+// accepted after the reload, the ones in progress are served with the previous values.
+func reloadableFlags(f *pflag.FlagSet, cfg *httpcfg.HttpCfg) {
+	f.StringSliceVar(&cfg.HttpCORSDomain, "http.corsdomain", []string{}, "Comma separated list of domains from which to accept cross origin requests (browser enforced)")
+	f.StringSliceVar(&cfg.HttpVirtualHost, "http.vhosts", nodecfg.DefaultConfig.HTTPVirtualHosts, "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.")
+	f.IntVar(&cfg.WebsocketLimits.MaxConnections, utils.WsMaxConnectionsFlag.Name, utils.WsMaxConnectionsFlag.Value, utils.WsMaxConnectionsFlag.Usage)
+	f.DurationVar(&cfg.WebsocketLimits.IdleTimeout, utils.WsIdleTimeoutFlag.Name, utils.WsIdleTimeoutFlag.Value, utils.WsIdleTimeoutFlag.Usage)
+	f.IntVar(&cfg.WebsocketLimits.MaxSubscriptions, utils.WsMaxSubscriptionsFlag.Name, utils.WsMaxSubscriptionsFlag.Value, utils.WsMaxSubscriptionsFlag.Usage)
+	f.IntVar(&cfg.WebsocketLimits.SendQueue, utils.WsSendQueueFlag.Name, utils.WsSendQueueFlag.Value, utils.WsSendQueueFlag.Usage)
+	f.StringVar(&cfg.RpcAllowListFilePath, "rpc.accessList", "", "Specify granular (method-by-method) API allowlist")
+	f.UintVar(&cfg.RpcBatchConcurrency, utils.RpcBatchConcurrencyFlag.Name, 2, utils.RpcBatchConcurrencyFlag.Usage)
+	f.UintVar(&cfg.RpcWorkers.CheapWorkers, utils.RpcWorkersCheapFlag.Name, utils.RpcWorkersCheapFlag.Value, utils.RpcWorkersCheapFlag.Usage)
+	f.UintVar(&cfg.RpcWorkers.TraceWorkers, utils.RpcWorkersTraceFlag.Name, utils.RpcWorkersTraceFlag.Value, utils.RpcWorkersTraceFlag.Usage)
+	f.UintVar(&cfg.RpcWorkers.LogsWorkers, utils.RpcWorkersLogsFlag.Name, utils.RpcWorkersLogsFlag.Value, utils.RpcWorkersLogsFlag.Usage)
+	f.UintVar(&cfg.RpcWorkers.QueueLimit, utils.RpcWorkersQueueFlag.Name, utils.RpcWorkersQueueFlag.Value, utils.RpcWorkersQueueFlag.Usage)
+}
+
+func validateReloadable(cfg *httpcfg.HttpCfg) error {
+	limits := cfg.WebsocketLimits
+	if limits.MaxConnections < 0 || limits.IdleTimeout < 0 || limits.MaxSubscriptions < 0 || limits.SendQueue < 0 {
+		return errors.New("websocket limits can't be negative")
+	}
+	return nil
+}
+
+// configFile - flag values of the --config file, slices are comma separated like on the command line
+type configFile struct {
+	values  map[string]string
+	content []byte
+}
+
+func readConfigFile(path string) (*configFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fileConfig := make(map[string]interface{})
+	switch filepath.Ext(path) {
+	case ".yaml":
+		err = yaml.Unmarshal(content, fileConfig)
+	case ".toml":
+		err = toml.Unmarshal(content, &fileConfig)
+	default:
+		return nil, errors.New("config files only accepted are .yaml and .toml")
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(fileConfig))
+	for key, value := range fileConfig {
+		if slice, ok := value.([]interface{}); ok {
+			s := make([]string, len(slice))
+			for i, v := range slice {
+				s[i] = fmt.Sprintf("%v", v)
+			}
+			values[key] = strings.Join(s, ",")
+		} else {
+			values[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	return &configFile{values: values, content: content}, nil
+}
+
+// startupConfig - the config file applied by RootCommand, nil - rpcdaemon is started without --config
+var startupConfig *appliedConfigFile
+
+type appliedConfigFile struct {
+	*configFile
+	path    string
+	cmdLine map[string]struct{} // flags given on the command line, they win over the file
+}
+
+// setFlagsFromConfigFile sets flags which aren't given on the command line to the values of the file
+func setFlagsFromConfigFile(flags *pflag.FlagSet, path string) (*appliedConfigFile, error) {
+	file, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	applied := &appliedConfigFile{configFile: file, path: path, cmdLine: map[string]struct{}{}}
+	flags.Visit(func(f *pflag.Flag) { applied.cmdLine[f.Name] = struct{}{} })
+	for name, value := range file.values {
+		if _, ok := applied.cmdLine[name]; ok {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("failed setting %s flag with value=%s error=%w", name, value, err)
+		}
+	}
+	return applied, nil
+}
+
+// configReloader re-reads the config file on SIGHUP. The reloadable flags are validated and replaced at once,
+// a file failing validation is rejected as a whole. Other changed flags are reported as requiring a restart.
+type configReloader struct {
+	startup  *appliedConfigFile
+	flags    *pflag.FlagSet // of the command line
+	srv      *rpc.Server
+	handler  *reloadableHandler
+	revision *httpcfg.ConfigRevision
+
+	lock sync.Mutex
+	cfg  httpcfg.HttpCfg // with the applied reloadable flags
+}
+
+func newConfigReloader(cfg httpcfg.HttpCfg, flags *pflag.FlagSet, startup *appliedConfigFile, srv *rpc.Server, httpHandler http.Handler) *configReloader {
+	return &configReloader{
+		startup:  startup,
+		flags:    flags,
+		srv:      srv,
+		handler:  &reloadableHandler{handler: httpHandler},
+		revision: cfg.ConfigRevision,
+		cfg:      cfg,
+	}
+}
+
+func (r *configReloader) reloadOnSIGHUP(ctx context.Context) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigs:
+			if err := r.reload(); err != nil {
+				log.Error("[rpc] config reload is rejected, the previous config is served", "file", r.startup.path, "err", err)
+			}
+		}
+	}
+}
+
+func (r *configReloader) reload() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	file, err := readConfigFile(r.startup.path)
+	if err != nil {
+		r.revision.Failed(err)
+		return err
+	}
+	cfg, restartRequired, err := r.parse(file)
+	if err != nil {
+		r.revision.Failed(err)
+		return err
+	}
+	allowList, err := parseAllowListForRPC(cfg.RpcAllowListFilePath)
+	if err != nil {
+		err = fmt.Errorf("rpc.accessList: %w", err)
+		r.revision.Failed(err)
+		return err
+	}
+
+	if cfg.RpcWorkers != r.cfg.RpcWorkers {
+		r.srv.SetScheduler(rpc.NewScheduler(cfg.RpcWorkers))
+	}
+	r.srv.SetAllowList(allowList)
+	r.srv.SetBatchConcurrency(cfg.RpcBatchConcurrency)
+	r.srv.SetWebsocketLimits(cfg.WebsocketLimits)
+	r.handler.set(node.NewHTTPHandlerStack(r.srv, cfg.HttpCORSDomain, cfg.HttpVirtualHost, cfg.HttpCompression))
+	r.cfg = cfg
+	revision := r.revision.Applied(r.startup.path, file.content, restartRequired)
+	log.Info("[rpc] config reloaded", "file", r.startup.path, "revision", revision)
+	if len(restartRequired) > 0 {
+		log.Warn("[rpc] changed flags are applied after restart", "flags", strings.Join(restartRequired, ","))
+	}
+	return nil
+}
+
+// parse returns the config with the reloadable flags of the file and the changed flags which can't be reloaded.
+// Reloadable flags missing in the file are reset to their defaults, unless given on the command line.
+func (r *configReloader) parse(file *configFile) (httpcfg.HttpCfg, []string, error) {
+	cfg := r.cfg
+	reloadable := pflag.NewFlagSet("reload", pflag.ContinueOnError)
+	reloadableFlags(reloadable, &cfg)
+	for name := range r.startup.cmdLine {
+		if f := reloadable.Lookup(name); f != nil {
+			if err := copyFlagValue(f, r.flags.Lookup(name)); err != nil {
+				return cfg, nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	var restartRequired []string
+	for name, value := range file.values {
+		if _, ok := r.startup.cmdLine[name]; ok {
+			continue
+		}
+		if reloadable.Lookup(name) == nil {
+			if r.flags.Lookup(name) == nil {
+				return cfg, nil, fmt.Errorf("unknown flag %s", name)
+			}
+			if old, ok := r.startup.values[name]; !ok || old != value {
+				restartRequired = append(restartRequired, name)
+			}
+			continue
+		}
+		if err := reloadable.Set(name, value); err != nil {
+			return cfg, nil, fmt.Errorf("failed setting %s flag with value=%s error=%w", name, value, err)
+		}
+	}
+	for name := range r.startup.values {
+		_, inFile := file.values[name]
+		_, inCmdLine := r.startup.cmdLine[name]
+		if !inFile && !inCmdLine && reloadable.Lookup(name) == nil {
+			restartRequired = append(restartRequired, name)
+		}
+	}
+	sort.Strings(restartRequired)
+	if err := validateReloadable(&cfg); err != nil {
+		return cfg, nil, err
+	}
+	return cfg, restartRequired, nil
+}
+
+func copyFlagValue(to, from *pflag.Flag) error {
+	if from == nil {
+		return nil
+	}
+	if slice, ok := from.Value.(pflag.SliceValue); ok {
+		return to.Value.(pflag.SliceValue).Replace(slice.GetSlice())
+	}
+	return to.Value.Set(from.Value.String())
+}
+
+// reloadableHandler - the http handler stack (CORS, virtual hosts) replaced by a reload,
+// requests in progress finish with the previous one
+type reloadableHandler struct {
+	lock    sync.RWMutex
+	handler http.Handler
+}
+
+func (h *reloadableHandler) set(handler http.Handler) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.handler = handler
+}
+
+func (h *reloadableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.lock.RLock()
+	handler := h.handler
+	h.lock.RUnlock()
+	handler.ServeHTTP(w, r)
+}
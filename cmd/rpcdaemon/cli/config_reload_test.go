@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReloader sets flags from the file like RootCommand does, cmdLine - flags given on the command line
+func newTestReloader(t *testing.T, path string, cmdLine map[string]string) *configReloader {
+	t.Helper()
+	var cfg httpcfg.HttpCfg
+	flags := pflag.NewFlagSet("rpcdaemon", pflag.ContinueOnError)
+	reloadableFlags(flags, &cfg)
+	flags.IntVar(&cfg.HttpPort, "http.port", 8545, "")
+	for name, value := range cmdLine {
+		require.NoError(t, flags.Set(name, value))
+	}
+	applied, err := setFlagsFromConfigFile(flags, path)
+	require.NoError(t, err)
+	cfg.ConfigRevision = &httpcfg.ConfigRevision{}
+	cfg.ConfigRevision.Applied(path, applied.content, nil)
+	srv := rpc.NewServer(cfg.RpcBatchConcurrency, false, false)
+	t.Cleanup(srv.Stop)
+	return newConfigReloader(cfg, flags, applied, srv, http.NotFoundHandler())
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func TestConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rpcdaemon.toml")
+	writeFile(t, path, `
+"http.corsdomain" = ["a.example"]
+"http.vhosts" = ["file.example"]
+"ws.max.connections" = 10
+"http.port" = 8545
+`)
+	r := newTestReloader(t, path, map[string]string{"http.vhosts": "cmdline.example"})
+	require.Equal(t, []string{"a.example"}, r.cfg.HttpCORSDomain)
+	require.Equal(t, []string{"cmdline.example"}, r.cfg.HttpVirtualHost)
+
+	allowList := filepath.Join(dir, "allow.json")
+	writeFile(t, allowList, `{"allow": ["eth_blockNumber"]}`)
+	writeFile(t, path, `
+"http.corsdomain" = ["b.example", "c.example"]
+"http.vhosts" = ["other.example"]
+"rpc.accessList" = "`+allowList+`"
+"http.port" = 8546
+`)
+	require.NoError(t, r.reload())
+	require.Equal(t, []string{"b.example", "c.example"}, r.cfg.HttpCORSDomain)
+	require.Equal(t, allowList, r.cfg.RpcAllowListFilePath)
+	// the command line wins over the file
+	require.Equal(t, []string{"cmdline.example"}, r.cfg.HttpVirtualHost)
+	// removed from the file: the default
+	require.Equal(t, utils.WsMaxConnectionsFlag.Value, r.cfg.WebsocketLimits.MaxConnections)
+	info := r.revision.Info()
+	require.Equal(t, uint64(2), info.Revision)
+	require.Equal(t, []string{"http.port"}, info.RestartRequired)
+	require.Empty(t, info.LastError)
+}
+
+func TestConfigReloadRejected(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rpcdaemon.yaml")
+	writeFile(t, path, "http.corsdomain: [a.example]\n")
+	r := newTestReloader(t, path, nil)
+
+	for name, content := range map[string]string{
+		"unknown flag":      "http.corsdomain: [b.example]\nno.such.flag: 1\n",
+		"invalid value":     "http.corsdomain: [b.example]\nws.max.connections: many\n",
+		"negative limit":    "http.corsdomain: [b.example]\nws.max.connections: -1\n",
+		"missing allowlist": "http.corsdomain: [b.example]\nrpc.accessList: " + filepath.Join(dir, "missing.json") + "\n",
+		"unreadable file":   "http.corsdomain: [b.example",
+	} {
+		writeFile(t, path, content)
+		require.Error(t, r.reload(), name)
+		// the previous config is served as a whole
+		require.Equal(t, []string{"a.example"}, r.cfg.HttpCORSDomain, name)
+		info := r.revision.Info()
+		require.Equal(t, uint64(1), info.Revision, name)
+		require.NotEmpty(t, info.LastError, name)
+	}
+
+	writeFile(t, path, "http.corsdomain: [b.example]\n")
+	require.NoError(t, r.reload())
+	require.Equal(t, []string{"b.example"}, r.cfg.HttpCORSDomain)
+	require.Equal(t, uint64(2), r.revision.Info().Revision)
+}
@@ -0,0 +1,54 @@
+package httpcfg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// ConfigRevision - the config file (--config) applied by rpcdaemon, it's re-read on SIGHUP
+type ConfigRevision struct {
+	lock sync.RWMutex
+	info ConfigRevisionInfo
+}
+
+// ConfigRevisionInfo - reported by admin_configRevision
+type ConfigRevisionInfo struct {
+	Revision        uint64     `json:"revision"` // 1 - the file read at startup, incremented by every applied reload
+	File            string     `json:"file"`
+	Hash            string     `json:"hash"` // sha256 of the applied file
+	LoadedAt        time.Time  `json:"loadedAt"`
+	RestartRequired []string   `json:"restartRequired,omitempty"` // changed flags which aren't reloaded, applied after restart
+	LastError       string     `json:"lastError,omitempty"`       // the last reload was rejected, the revision is still served
+	LastErrorAt     *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// Applied records a new revision read from the file content
+func (r *ConfigRevision) Applied(file string, content []byte, restartRequired []string) uint64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	hash := sha256.Sum256(content)
+	r.info = ConfigRevisionInfo{
+		Revision:        r.info.Revision + 1,
+		File:            file,
+		Hash:            hex.EncodeToString(hash[:]),
+		LoadedAt:        time.Now(),
+		RestartRequired: restartRequired,
+	}
+	return r.info.Revision
+}
+
+// Failed records a rejected reload, the current revision stays
+func (r *ConfigRevision) Failed(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := time.Now()
+	r.info.LastError, r.info.LastErrorAt = err.Error(), &now
+}
+
+func (r *ConfigRevision) Info() ConfigRevisionInfo {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.info
+}
@@ -2,8 +2,11 @@ package httpcfg
 
 import (
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/ethdb/kvreaders"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"time"
 )
@@ -28,14 +31,33 @@ type HttpCfg struct {
 	API                      []string
 	Gascap                   uint64
 	MaxTraces                uint64
+	LogsMaxRange             uint64 // max block range of eth_getLogs/eth_getFilterLogs, 0 - unlimited
+	LogsMaxResults           uint64 // max amount of logs returned by eth_getLogs/eth_getFilterLogs, 0 - unlimited
+	ReceiptsRevertReason     bool   // add decoded revert reason to receipts of failed transactions
+	NonCanonicalTxs          uint64 // recent blocks searched for transactions of non-canonical blocks, 0 - disabled
 	WebsocketEnabled         bool
 	WebsocketCompression     bool
+	WebsocketLimits          rpc.WebsocketLimits // per connection limits and max amount of connections
 	RpcAllowListFilePath     string
 	RpcBatchConcurrency      uint
+	RpcWorkers               rpc.SchedulerConfig // per method class worker pools
 	RpcStreamingDisable      bool
 	DBReadConcurrency        int
-	TraceCompatibility       bool // Bug for bug compatibility for trace_ routines with OpenEthereum
+	ReaderPolicy             kvreaders.Policy
+	TraceCompatibility       bool   // Bug for bug compatibility for trace_ routines with OpenEthereum
+	OtsLabelsPath            string // DB of address labels served by ots_getAddressMetadata, empty - disabled
+	OtsCreatorsPath          string // DB indexing creators found by ots_getContractCreator, empty - disabled
+	OtsSourcifySource        string // Sourcify repository (directory or URL) of ots_getContractMetadata, empty - disabled
+	OtsSourcifyCachePath     string // DB caching contract metadata, empty - not cached
+	OtsSignaturesPath        string // function signatures of ots_decodeCalldata, empty - not loaded
+	OtsSearchWorkers         int    // blocks traced at once by ots_ searches of all requests, 0 - estimated
+	OtsSearchCache           int    // (address, block) search results kept in memory, 0 - disabled
+	OtsOperationsPath        string // DB of internal operations backfilled for pruned blocks, empty - disabled
+	ScheduledTxs             ScheduledTxsCfg
+	TraceExport              TraceExportCfg
+	TraceBlockWorkers        int // transactions of a block traced at once by debug_traceBlockBy*, 1 - one by one, 0 - estimated
 	TxPoolApiAddr            string
+	DownloaderAddr           string // bittorrent client reporting the snapshot download to eth_syncing, empty - not reported
 	StateCache               kvcache.CoherentConfig
 	Snap                     ethconfig.Snapshot
 	Sync                     ethconfig.Sync
@@ -49,4 +71,24 @@ type HttpCfg struct {
 	HTTPTimeouts             rpccfg.HTTPTimeouts
 	AuthRpcTimeouts          rpccfg.HTTPTimeouts
 	EvmCallTimeout           time.Duration
+
+	ConfigFile     string          // YAML/TOML file of flag values, re-read on SIGHUP, empty - not used
+	ConfigRevision *ConfigRevision // revision of ConfigFile served by admin_configRevision, nil - not tracked
+
+	RecentStates *state.RecentStates // state changes of the last blocks kept by Execution of the same process, nil - not kept
+}
+
+// ScheduledTxsCfg - limits of transactions held by rpcdaemon until their min block/timestamp, see eth_sendRawTransaction
+type ScheduledTxsCfg struct {
+	Limit          int // 0 - scheduled transactions are rejected
+	SenderLimit    int
+	MaxBlocksAhead uint64
+	MaxTimeAhead   time.Duration
+}
+
+// TraceExportCfg - files with block traces written by debug_traceBlockBy*ToFile
+type TraceExportCfg struct {
+	Dir       string        // empty - disabled
+	URL       string        // base URL of Dir, empty - only file names are returned
+	Retention time.Duration // 0 - files are kept forever
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
@@ -17,17 +18,23 @@ type AdminAPI interface {
 	// Peers returns information about the connected remote nodes.
 	// https://geth.ethereum.org/docs/rpc/ns-admin#admin_peers
 	Peers(ctx context.Context) ([]*p2p.PeerInfo, error)
+
+	// ConfigRevision returns the revision of the config file (--config) served by rpcdaemon,
+	// the file is re-read on SIGHUP.
+	ConfigRevision(ctx context.Context) (*httpcfg.ConfigRevisionInfo, error)
 }
 
 // AdminAPIImpl data structure to store things needed for admin_* commands.
 type AdminAPIImpl struct {
 	ethBackend rpchelper.ApiBackend
+	revision   *httpcfg.ConfigRevision
 }
 
 // NewAdminAPI returns AdminAPIImpl instance.
-func NewAdminAPI(eth rpchelper.ApiBackend) *AdminAPIImpl {
+func NewAdminAPI(eth rpchelper.ApiBackend, revision *httpcfg.ConfigRevision) *AdminAPIImpl {
 	return &AdminAPIImpl{
 		ethBackend: eth,
+		revision:   revision,
 	}
 }
 
@@ -47,3 +54,11 @@ func (api *AdminAPIImpl) NodeInfo(ctx context.Context) (*p2p.NodeInfo, error) {
 func (api *AdminAPIImpl) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
 	return api.ethBackend.Peers(ctx)
 }
+
+func (api *AdminAPIImpl) ConfigRevision(ctx context.Context) (*httpcfg.ConfigRevisionInfo, error) {
+	if api.revision == nil {
+		return nil, errors.New("config revision is reported by rpcdaemon started with --config")
+	}
+	info := api.revision.Info()
+	return &info, nil
+}
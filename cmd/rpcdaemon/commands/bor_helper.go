@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
@@ -65,7 +64,7 @@ func getHeaderByNumber(ctx context.Context, number rpc.BlockNumber, api *BorImpl
 		return nil, err
 	}
 	if header == nil {
-		return nil, fmt.Errorf("block header not found: %d", blockNum)
+		return nil, rpc.NewNotFoundError("header", blockNum)
 	}
 
 	return header, nil
@@ -79,7 +78,7 @@ func getHeaderByHash(ctx context.Context, api *BorImpl, tx kv.Tx, hash common.Ha
 		return nil, err
 	}
 	if header == nil {
-		return nil, fmt.Errorf("block header not found: %s", hash.String())
+		return nil, rpc.NewNotFoundError("header", hash)
 	}
 
 	return header, nil
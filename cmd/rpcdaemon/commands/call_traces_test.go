@@ -8,6 +8,7 @@ import (
 
 	"github.com/holiman/uint256"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
@@ -16,9 +17,10 @@ import (
 	"github.com/valyala/fastjson"
 
 	"github.com/ledgerwatch/erigon/common"
-	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
 )
@@ -65,13 +67,13 @@ func TestCallTraceOneByOne(t *testing.T) {
 	}
 	stream := jsoniter.ConfigDefault.BorrowStream(nil)
 	defer jsoniter.ConfigDefault.ReturnStream(stream)
-	var fromBlock, toBlock uint64
+	var fromBlock, toBlock rpc.BlockNumber
 	fromBlock = 1
 	toBlock = 10
 	toAddress1 := common.Address{1}
 	traceReq1 := TraceFilterRequest{
-		FromBlock: (*hexutil.Uint64)(&fromBlock),
-		ToBlock:   (*hexutil.Uint64)(&toBlock),
+		FromBlock: &fromBlock,
+		ToBlock:   &toBlock,
 		ToAddress: []*common.Address{&toAddress1},
 	}
 	if err = api.Filter(context.Background(), traceReq1, stream); err != nil {
@@ -108,13 +110,13 @@ func TestCallTraceUnwind(t *testing.T) {
 	}
 	var buf bytes.Buffer
 	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
-	var fromBlock, toBlock uint64
+	var fromBlock, toBlock rpc.BlockNumber
 	fromBlock = 1
 	toBlock = 10
 	toAddress1 := common.Address{1}
 	traceReq1 := TraceFilterRequest{
-		FromBlock: (*hexutil.Uint64)(&fromBlock),
-		ToBlock:   (*hexutil.Uint64)(&toBlock),
+		FromBlock: &fromBlock,
+		ToBlock:   &toBlock,
 		ToAddress: []*common.Address{&toAddress1},
 	}
 	if err = api.Filter(context.Background(), traceReq1, stream); err != nil {
@@ -128,8 +130,8 @@ func TestCallTraceUnwind(t *testing.T) {
 	buf.Reset()
 	toBlock = 12
 	traceReq2 := TraceFilterRequest{
-		FromBlock: (*hexutil.Uint64)(&fromBlock),
-		ToBlock:   (*hexutil.Uint64)(&toBlock),
+		FromBlock: &fromBlock,
+		ToBlock:   &toBlock,
 		ToAddress: []*common.Address{&toAddress1},
 	}
 	if err = api.Filter(context.Background(), traceReq2, stream); err != nil {
@@ -144,8 +146,8 @@ func TestCallTraceUnwind(t *testing.T) {
 	fromBlock = 12
 	toBlock = 20
 	traceReq3 := TraceFilterRequest{
-		FromBlock: (*hexutil.Uint64)(&fromBlock),
-		ToBlock:   (*hexutil.Uint64)(&toBlock),
+		FromBlock: &fromBlock,
+		ToBlock:   &toBlock,
 		ToAddress: []*common.Address{&toAddress1},
 	}
 	if err = api.Filter(context.Background(), traceReq3, stream); err != nil {
@@ -175,12 +177,12 @@ func TestFilterNoAddresses(t *testing.T) {
 	}
 	var buf bytes.Buffer
 	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
-	var fromBlock, toBlock uint64
+	var fromBlock, toBlock rpc.BlockNumber
 	fromBlock = 1
 	toBlock = 10
 	traceReq1 := TraceFilterRequest{
-		FromBlock: (*hexutil.Uint64)(&fromBlock),
-		ToBlock:   (*hexutil.Uint64)(&toBlock),
+		FromBlock: &fromBlock,
+		ToBlock:   &toBlock,
 	}
 	if err = api.Filter(context.Background(), traceReq1, stream); err != nil {
 		t.Fatalf("trace_filter failed: %v", err)
@@ -188,6 +190,51 @@ func TestFilterNoAddresses(t *testing.T) {
 	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, blockNumbersFromTraces(t, buf.Bytes()))
 }
 
+func TestFilterPruned(t *testing.T) {
+	m := stages.Mock(t)
+	if m.HistoryV3 {
+		t.Skip()
+	}
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 10, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{1})
+	}, false /* intermediateHashes */)
+	require.NoError(t, err, "generate chain")
+	require.NoError(t, m.InsertChain(chain), "inserting chain")
+	agg := m.HistoryV3Components()
+	api := NewTraceAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB, &httpcfg.HttpCfg{})
+
+	filter := func(from rpc.BlockNumber, addresses ...*common.Address) error {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		to := rpc.BlockNumber(10)
+		return api.Filter(context.Background(), TraceFilterRequest{FromBlock: &from, ToBlock: &to, ToAddress: addresses}, stream)
+	}
+	prune := func(pm prune2.Mode) {
+		require.NoError(t, m.DB.Update(context.Background(), func(tx kv.RwTx) error { return prune2.Override(tx, pm) }))
+	}
+	coinbase := common.Address{1}
+
+	// blocks of the address are found by the call traces index
+	pm := prune2.DefaultMode
+	pm.CallTraces = prune2.Before(5)
+	prune(pm)
+	var pruned *rpc.PrunedError
+	require.ErrorAs(t, filter(1, &coinbase), &pruned)
+	require.Equal(t, "callTraces", pruned.Resource)
+	require.NoError(t, filter(5, &coinbase))
+	require.NoError(t, filter(1)) // without addresses every block is traced, the index isn't used
+
+	// and traced on the state after their parents
+	pm = prune2.DefaultMode
+	pm.History = prune2.Before(5)
+	prune(pm)
+	require.ErrorAs(t, filter(1, &coinbase), &pruned)
+	require.Equal(t, "state", pruned.Resource)
+	require.ErrorAs(t, filter(1), &pruned)
+	require.NoError(t, filter(5, &coinbase))
+	require.NoError(t, filter(5))
+}
+
 func TestFilterAddressIntersection(t *testing.T) {
 	m := stages.Mock(t)
 	agg := m.HistoryV3Components()
@@ -220,14 +267,14 @@ func TestFilterAddressIntersection(t *testing.T) {
 	err = m.InsertChain(chain)
 	require.NoError(t, err, "inserting chain")
 
-	fromBlock, toBlock := uint64(1), uint64(15)
+	fromBlock, toBlock := rpc.BlockNumber(1), rpc.BlockNumber(15)
 	t.Run("second", func(t *testing.T) {
 		stream := jsoniter.ConfigDefault.BorrowStream(nil)
 		defer jsoniter.ConfigDefault.ReturnStream(stream)
 
 		traceReq1 := TraceFilterRequest{
-			FromBlock:   (*hexutil.Uint64)(&fromBlock),
-			ToBlock:     (*hexutil.Uint64)(&toBlock),
+			FromBlock:   &fromBlock,
+			ToBlock:     &toBlock,
 			FromAddress: []*common.Address{&m.Address, &other},
 			ToAddress:   []*common.Address{&m.Address, &toAddress2},
 			Mode:        TraceFilterModeIntersection,
@@ -242,8 +289,8 @@ func TestFilterAddressIntersection(t *testing.T) {
 		defer jsoniter.ConfigDefault.ReturnStream(stream)
 
 		traceReq1 := TraceFilterRequest{
-			FromBlock:   (*hexutil.Uint64)(&fromBlock),
-			ToBlock:     (*hexutil.Uint64)(&toBlock),
+			FromBlock:   &fromBlock,
+			ToBlock:     &toBlock,
 			FromAddress: []*common.Address{&m.Address, &other},
 			ToAddress:   []*common.Address{&toAddress1, &m.Address},
 			Mode:        TraceFilterModeIntersection,
@@ -258,8 +305,8 @@ func TestFilterAddressIntersection(t *testing.T) {
 		defer jsoniter.ConfigDefault.ReturnStream(stream)
 
 		traceReq1 := TraceFilterRequest{
-			FromBlock:   (*hexutil.Uint64)(&fromBlock),
-			ToBlock:     (*hexutil.Uint64)(&toBlock),
+			FromBlock:   &fromBlock,
+			ToBlock:     &toBlock,
 			ToAddress:   []*common.Address{&other},
 			FromAddress: []*common.Address{&toAddress2, &toAddress1, &other},
 			Mode:        TraceFilterModeIntersection,
@@ -269,4 +316,36 @@ func TestFilterAddressIntersection(t *testing.T) {
 		}
 		require.Empty(t, blockNumbersFromTraces(t, stream.Buffer()))
 	})
+	t.Run("fromOnly", func(t *testing.T) {
+		stream := jsoniter.ConfigDefault.BorrowStream(nil)
+		defer jsoniter.ConfigDefault.ReturnStream(stream)
+
+		// an empty list matches any address, reward traces have no from address
+		traceReq1 := TraceFilterRequest{
+			FromBlock:   &fromBlock,
+			ToBlock:     &toBlock,
+			FromAddress: []*common.Address{&m.Address},
+			Mode:        TraceFilterModeIntersection,
+		}
+		if err = api.Filter(context.Background(), traceReq1, stream); err != nil {
+			t.Fatalf("trace_filter failed: %v", err)
+		}
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, blockNumbersFromTraces(t, stream.Buffer()))
+	})
+	t.Run("afterCount", func(t *testing.T) {
+		stream := jsoniter.ConfigDefault.BorrowStream(nil)
+		defer jsoniter.ConfigDefault.ReturnStream(stream)
+
+		after, count := uint64(1), uint64(2)
+		traceReq1 := TraceFilterRequest{
+			FromBlock: &fromBlock,
+			ToAddress: []*common.Address{&toAddress1}, // up to the latest block
+			After:     &after,
+			Count:     &count,
+		}
+		if err = api.Filter(context.Background(), traceReq1, stream); err != nil {
+			t.Fatalf("trace_filter failed: %v", err)
+		}
+		assert.Equal(t, []int{2, 3}, blockNumbersFromTraces(t, stream.Buffer()))
+	})
 }
@@ -22,7 +22,7 @@ func TestNotFoundMustReturnNil(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	api := NewEthAPI(
 		NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout),
-		m.DB, nil, nil, nil, 5000000)
+		m.DB, nil, nil, nil, 5000000, 0, 0)
 	ctx := context.Background()
 
 	a, err := api.GetTransactionByBlockNumberAndIndex(ctx, 10_000, 1)
@@ -37,7 +37,7 @@ func TestNotFoundMustReturnNil(t *testing.T) {
 	require.Nil(c)
 	require.Nil(err)
 
-	d, err := api.GetTransactionReceipt(ctx, common.Hash{})
+	d, err := api.GetTransactionReceipt(ctx, common.Hash{}, nil)
 	require.Nil(d)
 	require.Nil(err)
 
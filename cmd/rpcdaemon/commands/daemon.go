@@ -1,34 +1,112 @@
 package commands
 
 import (
+	"context"
+
+	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/ethdb/kvreaders"
+	"github.com/ledgerwatch/erigon/ethdb/kvstats"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/semaphore"
 )
 
 // APIList describes the list of available RPC apis
 func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
 	filters *rpchelper.Filters, stateCache kvcache.Cache,
-	blockReader services.FullBlockReader, agg *libstate.Aggregator22, cfg httpcfg.HttpCfg) (list []rpc.API) {
+	blockReader services.FullBlockReader, agg *libstate.Aggregator22, downloader proto_downloader.DownloaderClient, cfg httpcfg.HttpCfg) (list []rpc.API) {
 
 	base := NewBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout)
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	base.watchInvalidations(db)
+	db = kvreaders.New(db, cfg.ReaderPolicy) // long readers of calls while the freelist grows, see rpc.handler
+	db = kvstats.New(db)                     // read statistics of calls, see rpc.DBStatsHeader
+	if cfg.NonCanonicalTxs > 0 {
+		base.nonCanonicalTxs = newNonCanonicalTxIndex(cfg.NonCanonicalTxs)
+	}
+	base.recentStates = cfg.RecentStates
+	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.LogsMaxRange, cfg.LogsMaxResults)
+	ethImpl.ReceiptsRevertReason = cfg.ReceiptsRevertReason
+	ethImpl.downloader = downloader
+	ethImpl.dirs = cfg.Dirs
+	if cfg.ScheduledTxs.Limit > 0 {
+		ethImpl.scheduledTxs = newScheduledTxs(cfg.ScheduledTxs, txPool, filters)
+	}
 	erigonImpl := NewErigonAPI(base, db, eth)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool)
-	netImpl := NewNetAPIImpl(eth)
+	netImpl := NewNetAPIImpl(base, db, eth)
 	debugImpl := NewPrivateDebugAPI(base, db, cfg.Gascap)
+	debugImpl.traceBlockWorkers = cfg.TraceBlockWorkers
+	if cfg.TraceExport.Dir != "" {
+		exports, err := newTraceExports(cfg.TraceExport)
+		if err != nil {
+			log.Error("Trace export is disabled", "err", err)
+		} else {
+			debugImpl.traceExports = exports
+		}
+	}
 	traceImpl := NewTraceAPI(base, db, &cfg)
 	web3Impl := NewWeb3APIImpl(eth)
 	dbImpl := NewDBAPIImpl() /* deprecated */
-	adminImpl := NewAdminAPI(eth)
+	adminImpl := NewAdminAPI(eth, cfg.ConfigRevision)
 	parityImpl := NewParityAPIImpl(db)
 	borImpl := NewBorAPI(base, db, borDb) // bor (consensus) specific
 	otsImpl := NewOtterscanAPI(base, db)
+	if cfg.OtsLabelsPath != "" {
+		// kept open for the lifetime of the process, like the rest of APIs
+		labels, err := openAddressLabels(cfg.OtsLabelsPath)
+		if err != nil {
+			log.Error("Address labels are disabled", "err", err)
+		} else {
+			otsImpl.labels = labels
+		}
+	}
+	if cfg.OtsCreatorsPath != "" {
+		creators, err := openContractCreators(cfg.OtsCreatorsPath)
+		if err != nil {
+			log.Error("Contract creator index is disabled", "err", err)
+		} else {
+			otsImpl.creators = creators
+		}
+	}
+	if cfg.OtsSourcifySource != "" {
+		s, err := openSourcify(cfg.OtsSourcifySource, cfg.OtsSourcifyCachePath)
+		if err != nil {
+			log.Error("Contract metadata is disabled", "err", err)
+		} else {
+			otsImpl.sourcify = s
+		}
+	}
+	if cfg.OtsSignaturesPath != "" {
+		sigs, err := loadSignatures(cfg.OtsSignaturesPath)
+		if err != nil {
+			log.Error("Function signatures are disabled", "err", err)
+		} else {
+			otsImpl.signatures = sigs
+		}
+	}
+	if cfg.OtsOperationsPath != "" {
+		operations, err := openInternalOperations(cfg.OtsOperationsPath)
+		if err != nil {
+			log.Error("Internal operations index is disabled", "err", err)
+		} else {
+			otsImpl.operations = operations
+			go otsImpl.backfillInternalOperations(context.Background())
+		}
+	}
+	if cfg.OtsSearchWorkers > 0 {
+		otsImpl.searchWorkers = semaphore.NewWeighted(int64(cfg.OtsSearchWorkers))
+	}
+	if cfg.OtsSearchCache > 0 {
+		otsImpl.searchCache = newSearchCache(cfg.OtsSearchCache)
+	}
+	otsAdminImpl := NewOtsAdminAPI(otsImpl.labels)
 
 	for _, enabledAPI := range cfg.API {
 		switch enabledAPI {
@@ -116,6 +194,13 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 				Service:   OtterscanAPI(otsImpl),
 				Version:   "1.0",
 			})
+		case "otsadmin":
+			list = append(list, rpc.API{
+				Namespace: "otsadmin",
+				Public:    false,
+				Service:   OtsAdminAPI(otsAdminImpl),
+				Version:   "1.0",
+			})
 		}
 	}
 
@@ -127,8 +212,11 @@ func AuthAPIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClien
 	agg *libstate.Aggregator22,
 	cfg httpcfg.HttpCfg) (list []rpc.API) {
 	base := NewBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout)
+	base.watchInvalidations(db)
+	base.recentStates = cfg.RecentStates
 
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.LogsMaxRange, cfg.LogsMaxResults)
+	ethImpl.dirs = cfg.Dirs
 	engineImpl := NewEngineAPI(base, db, eth)
 
 	list = append(list, rpc.API{
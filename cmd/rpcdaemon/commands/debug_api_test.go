@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"regexp"
 	"testing"
 
 	jsoniter "github.com/json-iterator/go"
@@ -44,7 +45,7 @@ func TestTraceBlockByNumber(t *testing.T) {
 	agg := m.HistoryV3Components()
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
-	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000)
+	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 0, 0)
 	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
@@ -92,7 +93,7 @@ func TestTraceBlockByHash(t *testing.T) {
 	agg := m.HistoryV3Components()
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
-	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000)
+	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 0, 0)
 	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
@@ -122,6 +123,38 @@ func TestTraceBlockByHash(t *testing.T) {
 	}
 }
 
+var callTracerTime = regexp.MustCompile(`,"time":"[^"]*"`)
+
+func TestTraceBlockParallel(t *testing.T) {
+	m, chain, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
+	callTracer := "callTracer"
+	for _, config := range []*tracers.TraceConfig{{}, {Tracer: &callTracer}} {
+		for _, block := range chain.Blocks {
+			traceBlock := func(workers int) []byte {
+				api.traceBlockWorkers = workers
+				var buf bytes.Buffer
+				stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+				if err := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(block.NumberU64()), config, stream); err != nil {
+					t.Fatalf("traceBlock %d: %v", block.NumberU64(), err)
+				}
+				if err := stream.Flush(); err != nil {
+					t.Fatalf("error flusing: %v", err)
+				}
+				// durations measured by the call tracer differ between runs
+				return callTracerTime.ReplaceAll(buf.Bytes(), nil)
+			}
+			// the block deploying a token, minting and transferring it depends on the preceding transactions
+			if sequential, parallel := traceBlock(1), traceBlock(4); !bytes.Equal(sequential, parallel) {
+				t.Fatalf("traceBlock %d: parallel traces differ\n%s\n%s", block.NumberU64(), sequential, parallel)
+			}
+		}
+	}
+}
+
 func TestTraceTransaction(t *testing.T) {
 	m, _, _ := rpcdaemontest.CreateTestSentry(t)
 	agg := m.HistoryV3Components()
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	traceExportPrefix    = "block_" // only files named by traceExports are deleted, the directory may be shared
+	traceExportBatchSize = 1 << 20  // bytes of traces buffered before they are written to the file
+
+	// bounds of the period of deleting expired files, a tenth of the retention
+	traceExportMinPrunePeriod = time.Second
+	traceExportMaxPrunePeriod = time.Hour
+)
+
+// TraceExport - a file with the traces of a block in --rpc.trace.export.dir, the same JSON debug_traceBlockByNumber returns
+type TraceExport struct {
+	File    string         `json:"file"`
+	URL     string         `json:"url,omitempty"` // if --rpc.trace.export.url is set
+	Size    hexutil.Uint64 `json:"size"`
+	Expires hexutil.Uint64 `json:"expires,omitempty"` // unix time when the file is deleted, absent if it's kept forever
+}
+
+// traceExports - trace files written by debug_traceBlockBy*ToFile, deleted after the retention
+type traceExports struct {
+	cfg httpcfg.TraceExportCfg
+}
+
+func newTraceExports(cfg httpcfg.TraceExportCfg) (*traceExports, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+	e := &traceExports{cfg: cfg}
+	if cfg.Retention > 0 {
+		e.prune(time.Now())
+		go func() {
+			period := cfg.Retention / 10
+			if period < traceExportMinPrunePeriod {
+				period = traceExportMinPrunePeriod
+			} else if period > traceExportMaxPrunePeriod {
+				period = traceExportMaxPrunePeriod
+			}
+			ticker := time.NewTicker(period)
+			defer ticker.Stop()
+			for now := range ticker.C {
+				e.prune(now)
+			}
+		}()
+	}
+	return e, nil
+}
+
+// export writes the output of trace to a new file. The file gets its name when it's complete, partial files of failed
+// traces are removed.
+func (e *traceExports) export(name string, trace func(stream *jsoniter.Stream) error) (*TraceExport, error) {
+	f, err := os.CreateTemp(e.cfg.Dir, name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	// trace flushes the stream after every transaction, the file is written in batches
+	w := bufio.NewWriterSize(f, traceExportBatchSize)
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, w, 4096)
+	err = trace(stream)
+	if err == nil {
+		err = stream.Flush()
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(e.cfg.Dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return nil, err
+	}
+
+	info, err := os.Stat(filepath.Join(e.cfg.Dir, name))
+	if err != nil {
+		return nil, err
+	}
+	res := &TraceExport{File: name, Size: hexutil.Uint64(info.Size())}
+	if e.cfg.URL != "" {
+		res.URL = strings.TrimSuffix(e.cfg.URL, "/") + "/" + name
+	}
+	if e.cfg.Retention > 0 {
+		res.Expires = hexutil.Uint64(info.ModTime().Add(e.cfg.Retention).Unix())
+	}
+	return res, nil
+}
+
+// prune deletes trace files (and leftovers of interrupted exports) older than the retention
+func (e *traceExports) prune(now time.Time) {
+	entries, err := os.ReadDir(e.cfg.Dir)
+	if err != nil {
+		log.Warn("[rpc] trace export prune failed", "err", err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), traceExportPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < e.cfg.Retention {
+			continue
+		}
+		if err = os.Remove(filepath.Join(e.cfg.Dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn("[rpc] trace export prune failed", "file", entry.Name(), "err", err)
+		}
+	}
+}
+
+// TraceBlockByNumberToFile implements debug_traceBlockByNumberToFile. Writes the traces debug_traceBlockByNumber would
+// return to a file in --rpc.trace.export.dir and returns the file, so huge traces don't go over JSON-RPC.
+func (api *PrivateDebugAPIImpl) TraceBlockByNumberToFile(ctx context.Context, blockNum rpc.BlockNumber, config *tracers.TraceConfig) (*TraceExport, error) {
+	return api.traceBlockToFile(ctx, rpc.BlockNumberOrHashWithNumber(blockNum), config)
+}
+
+// TraceBlockByHashToFile implements debug_traceBlockByHashToFile, see TraceBlockByNumberToFile
+func (api *PrivateDebugAPIImpl) TraceBlockByHashToFile(ctx context.Context, hash common.Hash, config *tracers.TraceConfig) (*TraceExport, error) {
+	return api.traceBlockToFile(ctx, rpc.BlockNumberOrHashWithHash(hash, true), config)
+}
+
+func (api *PrivateDebugAPIImpl) traceBlockToFile(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *tracers.TraceConfig) (*TraceExport, error) {
+	if api.traceExports == nil {
+		return nil, errors.New("trace export is disabled, see --rpc.trace.export.dir")
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	blockNum, hash, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
+	tx.Rollback()
+	if err != nil {
+		return nil, err
+	}
+
+	// the hash pins the block, the head may move while the block is traced
+	name := fmt.Sprintf("%s%d_%x_%d.json", traceExportPrefix, blockNum, hash[:4], time.Now().UnixNano())
+	return api.traceExports.export(name, func(stream *jsoniter.Stream) error {
+		return api.traceBlock(ctx, rpc.BlockNumberOrHashWithHash(hash, false), config, stream)
+	})
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceBlockToFile(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
+	ctx := context.Background()
+
+	_, err := api.TraceBlockByNumberToFile(ctx, rpc.BlockNumber(1), &tracers.TraceConfig{})
+	require.Error(t, err) // disabled
+
+	dir := t.TempDir()
+	api.traceExports, err = newTraceExports(httpcfg.TraceExportCfg{Dir: dir, URL: "https://traces.example/", Retention: time.Hour})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.NoError(t, api.TraceBlockByNumber(ctx, rpc.BlockNumber(1), &tracers.TraceConfig{}, stream))
+	require.NoError(t, stream.Flush())
+
+	res, err := api.TraceBlockByNumberToFile(ctx, rpc.BlockNumber(1), &tracers.TraceConfig{})
+	require.NoError(t, err)
+	require.Equal(t, "https://traces.example/"+res.File, res.URL)
+	require.Equal(t, uint64(buf.Len()), uint64(res.Size))
+	content, err := os.ReadFile(filepath.Join(dir, res.File))
+	require.NoError(t, err)
+	require.Equal(t, buf.Bytes(), content)
+	require.InDelta(t, time.Now().Add(time.Hour).Unix(), int64(res.Expires), 5)
+
+	_, err = api.TraceBlockByNumberToFile(ctx, rpc.BlockNumber(1_000_000), &tracers.TraceConfig{})
+	require.Error(t, err)
+
+	// expired files are deleted, other files of the directory are not
+	other := filepath.Join(dir, "other.json")
+	require.NoError(t, os.WriteFile(other, nil, 0644))
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, res.File), old, old))
+	require.NoError(t, os.Chtimes(other, old, old))
+	api.traceExports.prune(time.Now())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "other.json", entries[0].Name())
+}
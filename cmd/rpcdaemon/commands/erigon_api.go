@@ -18,6 +18,7 @@ import (
 type ErigonAPI interface {
 	// System related (see ./erigon_system.go)
 	Forks(ctx context.Context) (Forks, error)
+	ForkId(ctx context.Context) (*ForkID, error)
 	BlockNumber(ctx context.Context, rpcBlockNumPtr *rpc.BlockNumber) (hexutil.Uint64, error)
 
 	// Blocks related (see ./erigon_blocks.go)
@@ -25,13 +26,27 @@ type ErigonAPI interface {
 	GetHeaderByHash(_ context.Context, hash common.Hash) (*types.Header, error)
 	GetBlockByTimestamp(ctx context.Context, timeStamp rpc.Timestamp, fullTx bool) (map[string]interface{}, error)
 	GetBalanceChangesInBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*hexutil.Big, error)
+	GetBalanceDiffsInBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*BalanceChange, error)
+
+	// Batch variants of transaction and uncle accessors (see ./erigon_block_ranges.go)
+	GetBlockTransactionCountsByRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([]hexutil.Uint, error)
+	GetUncleCountsByRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([]hexutil.Uint, error)
+	GetUnclesByRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([][]map[string]interface{}, error)
+
+	// Account history related (see ./erigon_balance_history.go)
+	GetBalanceHistory(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, step hexutil.Uint64) ([]BalancePoint, error)
+
+	// Storage related (see ./erigon_storage_range.go)
+	GetStorageRange(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash, startKey *common.Hash, pageSize int) (*StorageRange, error)
 
 	// Receipt related (see ./erigon_receipts.go)
 	GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error)
 	//GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error)
 	GetLogs(ctx context.Context, crit ethFilters.FilterCriteria) (types.ErigonLogs, error)
+	GetRevertReason(ctx context.Context, txnHash common.Hash) (*RevertReason, error)
 
 	// WatchTheBurn / reward related (see ./erigon_issuance.go)
+	Issuance(ctx context.Context, blockNr rpc.BlockNumber) (*BlockIssuance, error)
 	WatchTheBurn(ctx context.Context, blockNr rpc.BlockNumber) (Issuance, error)
 
 	// CumulativeChainTraffic / related to chain traffic (see ./erigon_cumulative_index.go)
@@ -39,20 +54,33 @@ type ErigonAPI interface {
 
 	// NodeInfo returns a collection of metadata known about the host.
 	NodeInfo(ctx context.Context) ([]p2p.NodeInfo, error)
+
+	// ChainStats - uncle rate, block interval, gas utilization and reorgs (see ./erigon_chain_stats.go)
+	ChainStats(ctx context.Context) (*ChainStats, error)
+
+	// SyncProgress - progress, throughput and ETA of sync stages (see ./erigon_sync_progress.go)
+	SyncProgress(ctx context.Context) (*SyncProgress, error)
+
+	// SimulateDifficulty - difficulty and block time evolution for private chains (see ./erigon_difficulty_simulation.go)
+	SimulateDifficulty(ctx context.Context, args DifficultySimulationArgs) ([]DifficultySample, error)
 }
 
 // ErigonImpl is implementation of the ErigonAPI interface
 type ErigonImpl struct {
 	*BaseAPI
-	db         kv.RoDB
-	ethBackend rpchelper.ApiBackend
+	db           kv.RoDB
+	ethBackend   rpchelper.ApiBackend
+	chainStats   *chainStatsTracker
+	syncProgress *syncProgressTracker
 }
 
 // NewErigonAPI returns ErigonImpl instance
 func NewErigonAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend) *ErigonImpl {
 	return &ErigonImpl{
-		BaseAPI:    base,
-		db:         db,
-		ethBackend: eth,
+		BaseAPI:      base,
+		db:           db,
+		ethBackend:   eth,
+		chainStats:   newChainStatsTracker(db, base._blockReader, base.filters),
+		syncProgress: newSyncProgressTracker(db, base.filters),
 	}
 }
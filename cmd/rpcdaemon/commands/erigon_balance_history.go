@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// maxBalanceHistoryPoints - limit of points returned by one erigon_getBalanceHistory call
+const maxBalanceHistoryPoints = 10_000
+
+// BalancePoint - balance of an account at the end of the block
+type BalancePoint struct {
+	Block   hexutil.Uint64 `json:"block"`
+	Balance *hexutil.Big   `json:"balance"`
+}
+
+// GetBalanceHistory implements erigon_getBalanceHistory. Returns balances of the account at the end of blocks
+// fromBlock, fromBlock+step, ... up to toBlock. The account history index is read once for the whole range,
+// so every point costs at most one changeset lookup instead of an eth_getBalance call.
+func (api *ErigonImpl) GetBalanceHistory(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, step hexutil.Uint64) ([]BalancePoint, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, _, _, err := rpchelper.GetCanonicalBlockNumber(rpc.BlockNumberOrHashWithNumber(fromBlock), tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	to, _, _, err := rpchelper.GetCanonicalBlockNumber(rpc.BlockNumberOrHashWithNumber(toBlock), tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, err
+	}
+	if step == 0 {
+		return nil, errors.New("step must be positive")
+	}
+	if from > to {
+		return nil, fmt.Errorf("fromBlock %d is after toBlock %d", from, to)
+	}
+	if to > executed {
+		return nil, fmt.Errorf("toBlock %d is not executed yet, latest executed block is %d", to, executed)
+	}
+	if points := (to-from)/uint64(step) + 1; points > maxBalanceHistoryPoints {
+		return nil, &rpc.LimitExceededError{Message: fmt.Sprintf("too many points requested: %d, limit is %d", points, maxBalanceHistoryPoints), Limit: maxBalanceHistoryPoints}
+	}
+
+	if api.historyV3(tx) {
+		return api.balanceHistoryV3(ctx, tx, address, from, to, uint64(step))
+	}
+	return balanceHistory(tx, address, from, to, uint64(step))
+}
+
+// balanceHistory - the changeset of block N has values of accounts before N, so balance at the end of block b is
+// in the changeset of the first change of the account after b, or in PlainState if there are no such changes
+func balanceHistory(tx kv.Tx, address common.Address, from, to, step uint64) ([]BalancePoint, error) {
+	index, err := bitmapdb.Get64(tx, kv.AccountsHistory, address[:], from+1, to+1)
+	if err != nil {
+		return nil, err
+	}
+	changesC, err := tx.CursorDupSort(kv.AccountChangeSet)
+	if err != nil {
+		return nil, err
+	}
+	defer changesC.Close()
+
+	find := changeset.Mapper[kv.AccountChangeSet].Find
+	var result []BalancePoint
+	var lastChange uint64
+	var lastFound bool
+	var lastBalance *hexutil.Big
+	for b := from; b <= to; b += step {
+		change, ok := bitmapdb.SeekInBitmap64(index, b+1)
+		if lastBalance == nil || ok != lastFound || change != lastChange { // balances of points between two changes are the same
+			var enc []byte
+			if ok {
+				if enc, err = find(changesC, change, address[:]); err != nil {
+					return nil, fmt.Errorf("finding %x in the changeset %d: %w", address, change, err)
+				}
+			} else if enc, err = tx.GetOne(kv.PlainState, address[:]); err != nil {
+				return nil, err
+			}
+			if lastBalance, err = decodeBalance(enc); err != nil {
+				return nil, err
+			}
+			lastChange, lastFound = change, ok
+		}
+		result = append(result, BalancePoint{Block: hexutil.Uint64(b), Balance: lastBalance})
+		if to-b < step {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (api *ErigonImpl) balanceHistoryV3(ctx context.Context, tx kv.Tx, address common.Address, from, to, step uint64) ([]BalancePoint, error) {
+	var result []BalancePoint
+	for b := from; b <= to; b += step {
+		reader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(b)), api.filters, api.stateCache, api.recentStates, true, api._agg)
+		if err != nil {
+			return nil, err
+		}
+		acc, err := reader.ReadAccountData(address)
+		if err != nil {
+			return nil, err
+		}
+		balance := new(hexutil.Big)
+		if acc != nil {
+			balance = (*hexutil.Big)(acc.Balance.ToBig())
+		}
+		result = append(result, BalancePoint{Block: hexutil.Uint64(b), Balance: balance})
+		if to-b < step {
+			break
+		}
+	}
+	return result, nil
+}
+
+func decodeBalance(enc []byte) (*hexutil.Big, error) {
+	if len(enc) == 0 { // the account didn't exist
+		return new(hexutil.Big), nil
+	}
+	var acc accounts.Account
+	if err := acc.DecodeForStorage(enc); err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(acc.Balance.ToBig()), nil
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceHistory(t *testing.T) {
+	require := require.New(t)
+	db := memdb.NewTestDB(t)
+	address := common.Address{1}
+
+	encode := func(balance uint64) []byte {
+		if balance == 0 {
+			return nil
+		}
+		acc := accounts.Account{Initialised: true, Balance: *uint256.NewInt(balance)}
+		enc := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(enc)
+		return enc
+	}
+	// account is created in block 5 with balance 100, then balance is 200 after block 10 and 300 after block 20
+	changes := map[uint64]uint64{5: 0, 10: 100, 20: 200}
+	require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+		index := roaring64.New()
+		for block, balance := range changes {
+			cs := changeset.NewAccountChangeSet()
+			if err := cs.Add(address[:], encode(balance)); err != nil {
+				return err
+			}
+			if err := changeset.EncodeAccounts(block, cs, func(k, v []byte) error { return tx.Put(kv.AccountChangeSet, k, v) }); err != nil {
+				return err
+			}
+			index.Add(block)
+		}
+		var buf bytes.Buffer
+		if _, err := index.WriteTo(&buf); err != nil {
+			return err
+		}
+		if err := tx.Put(kv.AccountsHistory, changeset.Mapper[kv.AccountChangeSet].IndexChunkKey(address[:], ^uint64(0)), buf.Bytes()); err != nil {
+			return err
+		}
+		return tx.Put(kv.PlainState, address[:], encode(300))
+	}))
+
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(err)
+	defer tx.Rollback()
+	balances := func(from, to, step uint64) (res []uint64) {
+		points, err := balanceHistory(tx, address, from, to, step)
+		require.NoError(err)
+		for _, p := range points {
+			res = append(res, p.Balance.ToInt().Uint64())
+		}
+		return res
+	}
+	require.Equal([]uint64{0, 0, 100, 200, 200, 300, 300, 300}, balances(0, 30, 4))
+	require.Equal([]uint64{0, 100, 200, 200, 300, 300}, balances(4, 24, 4))
+	require.Equal([]uint64{200}, balances(10, 10, 1))
+	require.Equal([]uint64{300, 300}, balances(20, 25, 5))
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
@@ -49,7 +50,7 @@ func (api *ErigonImpl) GetHeaderByNumber(ctx context.Context, blockNumber rpc.Bl
 	}
 
 	if header == nil {
-		return nil, fmt.Errorf("block header not found: %d", blockNum)
+		return nil, rpc.NewNotFoundError("header", blockNum)
 	}
 
 	return header, nil
@@ -68,7 +69,7 @@ func (api *ErigonImpl) GetHeaderByHash(ctx context.Context, hash common.Hash) (*
 		return nil, err
 	}
 	if header == nil {
-		return nil, fmt.Errorf("block header not found: %s", hash.String())
+		return nil, rpc.NewNotFoundError("header", hash)
 	}
 
 	return header, nil
@@ -175,18 +176,59 @@ func buildBlockResponse(db kv.Tx, blockNum uint64, fullTx bool) (map[string]inte
 	return response, err
 }
 
+// BalanceChange - balance of an account before and after the block
+type BalanceChange struct {
+	Before *hexutil.Big `json:"before"`
+	After  *hexutil.Big `json:"after"`
+}
+
+// GetBalanceChangesInBlock implements erigon_getBalanceChangesInBlock. Returns new balances of accounts whose balances
+// the block changed, read from the account changeset of the block, so no transaction is re-executed.
 func (api *ErigonImpl) GetBalanceChangesInBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*hexutil.Big, error) {
+	changes, err := api.balanceChanges(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	balancesMapping := make(map[common.Address]*hexutil.Big, len(changes))
+	for address, change := range changes {
+		balancesMapping[address] = change.After
+	}
+	return balancesMapping, nil
+}
+
+// GetBalanceDiffsInBlock implements erigon_getBalanceDiffsInBlock. The same as erigon_getBalanceChangesInBlock, with
+// balances before the block too.
+func (api *ErigonImpl) GetBalanceDiffsInBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*BalanceChange, error) {
+	return api.balanceChanges(ctx, blockNrOrHash)
+}
+
+func (api *ErigonImpl) balanceChanges(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*BalanceChange, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
+	if api.historyV3(tx) {
+		return nil, errors.New("balance changes in block are not supported with history v3")
+	}
 	blockNumber, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
 	if err != nil {
 		return nil, err
 	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber > executed {
+		return nil, fmt.Errorf("block %d is not executed yet, latest executed block is %d", blockNumber, executed)
+	}
+	return api.balanceChangesInBlock(ctx, tx, blockNumber, blockNrOrHash)
+}
 
+// balanceChangesInBlock - the changeset of the block has accounts before the block, their balances after the block
+// are read from the state at the end of it
+func (api *ErigonImpl) balanceChangesInBlock(ctx context.Context, tx kv.Tx, blockNumber uint64, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*BalanceChange, error) {
 	c, err := tx.Cursor(kv.AccountChangeSet)
 	if err != nil {
 		return nil, err
@@ -197,9 +239,9 @@ func (api *ErigonImpl) GetBalanceChangesInBlock(ctx context.Context, blockNrOrHa
 
 	decodeFn := changeset.Mapper[kv.AccountChangeSet].Decode
 
-	balancesMapping := make(map[common.Address]*hexutil.Big)
+	changes := make(map[common.Address]*BalanceChange)
 
-	newReader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, api.filters, api.stateCache, api.historyV3(tx), api._agg)
+	newReader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, api.filters, api.stateCache, api.recentStates, api.historyV3(tx), api._agg)
 	if err != nil {
 		return nil, err
 	}
@@ -232,10 +274,9 @@ func (api *ErigonImpl) GetBalanceChangesInBlock(ctx context.Context, blockNrOrHa
 		}
 
 		if !oldBalance.Eq(newBalance) {
-			newBalanceDesc := (*hexutil.Big)(newBalance.ToBig())
-			balancesMapping[address] = newBalanceDesc
+			changes[address] = &BalanceChange{Before: (*hexutil.Big)(oldBalance.ToBig()), After: (*hexutil.Big)(newBalance.ToBig())}
 		}
 	}
 
-	return balancesMapping, nil
+	return changes, nil
 }
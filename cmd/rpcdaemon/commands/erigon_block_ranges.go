@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// maxBlockRange - limit of blocks of one erigon_getBlockTransactionCountsByRange, erigon_getUncleCountsByRange or
+// erigon_getUnclesByRange call
+const maxBlockRange = 10_000
+
+// GetBlockTransactionCountsByRange implements erigon_getBlockTransactionCountsByRange. Returns amounts of transactions
+// of the canonical blocks fromBlock..toBlock, as eth_getBlockTransactionCountByNumber of each of them would.
+func (api *ErigonImpl) GetBlockTransactionCountsByRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([]hexutil.Uint, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var counts []hexutil.Uint
+	err = api.forEachBody(ctx, tx, fromBlock, toBlock, func(_ uint64, _ common.Hash, _ *types.Body, txAmount uint32) error {
+		counts = append(counts, hexutil.Uint(txAmount))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetUncleCountsByRange implements erigon_getUncleCountsByRange. Returns amounts of uncles of the canonical blocks
+// fromBlock..toBlock, as eth_getUncleCountByBlockNumber of each of them would.
+func (api *ErigonImpl) GetUncleCountsByRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([]hexutil.Uint, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var counts []hexutil.Uint
+	err = api.forEachBody(ctx, tx, fromBlock, toBlock, func(_ uint64, _ common.Hash, body *types.Body, _ uint32) error {
+		counts = append(counts, hexutil.Uint(len(body.Uncles)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetUnclesByRange implements erigon_getUnclesByRange. Returns uncles of every canonical block fromBlock..toBlock,
+// each one as eth_getUncleByBlockNumberAndIndex would, an empty list for blocks without uncles.
+func (api *ErigonImpl) GetUnclesByRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([][]map[string]interface{}, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var uncles [][]map[string]interface{}
+	err = api.forEachBody(ctx, tx, fromBlock, toBlock, func(number uint64, hash common.Hash, body *types.Body, _ uint32) error {
+		blockUncles := make([]map[string]interface{}, 0, len(body.Uncles))
+		for _, uncle := range body.Uncles {
+			fields, err := marshalUncle(tx, uncle, hash, number)
+			if err != nil {
+				return err
+			}
+			blockUncles = append(blockUncles, fields)
+		}
+		uncles = append(uncles, blockUncles)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uncles, nil
+}
+
+// forEachBody calls fn with bodies of the canonical blocks fromBlock..toBlock, without transactions: amounts of
+// transactions and uncles are read without downloading the whole blocks
+func (api *ErigonImpl) forEachBody(ctx context.Context, tx kv.Tx, fromBlock, toBlock rpc.BlockNumber, fn func(number uint64, hash common.Hash, body *types.Body, txAmount uint32) error) error {
+	from, _, _, err := rpchelper.GetCanonicalBlockNumber(rpc.BlockNumberOrHashWithNumber(fromBlock), tx, api.filters)
+	if err != nil {
+		return err
+	}
+	to, _, _, err := rpchelper.GetCanonicalBlockNumber(rpc.BlockNumberOrHashWithNumber(toBlock), tx, api.filters)
+	if err != nil {
+		return err
+	}
+	if from > to {
+		return fmt.Errorf("fromBlock %d is after toBlock %d", from, to)
+	}
+	if blocks := to - from + 1; blocks > maxBlockRange {
+		return &rpc.LimitExceededError{Message: fmt.Sprintf("too many blocks requested: %d, limit is %d", blocks, maxBlockRange), Limit: maxBlockRange}
+	}
+
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash, err := api._blockReader.CanonicalHash(ctx, tx, number)
+		if err != nil {
+			return err
+		}
+		if hash == (common.Hash{}) {
+			return rpc.NewNotFoundError("block", number)
+		}
+		body, txAmount, err := api._blockReader.Body(ctx, tx, hash, number)
+		if err != nil {
+			return err
+		}
+		if body == nil {
+			return rpc.NewNotFoundError("block", number)
+		}
+		if err := fn(number, hash, body, txAmount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockRanges(t *testing.T) {
+	require := require.New(t)
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	ctx := context.Background()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
+	ethAPI := NewEthAPI(base, m.DB, nil, nil, nil, 5000000, 0, 0)
+	api := NewErigonAPI(base, m.DB, nil)
+
+	txCounts, err := api.GetBlockTransactionCountsByRange(ctx, 1, 5)
+	require.NoError(err)
+	uncleCounts, err := api.GetUncleCountsByRange(ctx, 1, 5)
+	require.NoError(err)
+	uncles, err := api.GetUnclesByRange(ctx, 1, 5)
+	require.NoError(err)
+	require.Len(txCounts, 5)
+	require.Len(uncleCounts, 5)
+	require.Len(uncles, 5)
+	for i := 0; i < 5; i++ {
+		number := rpc.BlockNumber(i + 1)
+		txCount, err := ethAPI.GetBlockTransactionCountByNumber(ctx, number)
+		require.NoError(err)
+		require.Equal(*txCount, txCounts[i], "block %d", number)
+		uncleCount, err := ethAPI.GetUncleCountByBlockNumber(ctx, number)
+		require.NoError(err)
+		require.Equal(*uncleCount, uncleCounts[i], "block %d", number)
+		require.Len(uncles[i], int(*uncleCount), "block %d", number)
+		for j := range uncles[i] {
+			uncle, err := ethAPI.GetUncleByBlockNumberAndIndex(ctx, number, hexutil.Uint(j))
+			require.NoError(err)
+			require.Equal(uncle, uncles[i][j])
+		}
+	}
+
+	_, err = api.GetUncleCountsByRange(ctx, 5, 1)
+	require.Error(err)
+}
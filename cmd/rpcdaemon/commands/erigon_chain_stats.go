@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// chainStatsWindow - amount of the latest canonical blocks rolling statistics are computed over
+const chainStatsWindow = 1024
+
+var (
+	chainReorgs         = metrics.NewCounter(`chain_reorgs_total`)
+	chainReorgDepth     = metrics.NewHistogram(`chain_reorg_depth`)
+	chainUncleRate      = metrics.NewFloatCounter(`chain_uncle_rate`)
+	chainBlockInterval  = metrics.NewFloatCounter(`chain_block_interval_seconds`)
+	chainGasUtilization = metrics.NewFloatCounter(`chain_gas_utilization`)
+)
+
+// ChainStats - statistics of the latest canonical blocks, and reorgs seen since the start of rpcdaemon
+type ChainStats struct {
+	FromBlock      hexutil.Uint64 `json:"fromBlock"`
+	ToBlock        hexutil.Uint64 `json:"toBlock"`
+	Uncles         uint64         `json:"uncles"`
+	UncleRate      float64        `json:"uncleRate"`            // uncles per block
+	BlockInterval  float64        `json:"averageBlockInterval"` // seconds
+	GasUtilization float64        `json:"gasUtilization"`       // gas used / gas limit
+	Reorgs         uint64         `json:"reorgs"`
+	MaxReorgDepth  uint64         `json:"maxReorgDepth"`
+	LastReorg      *Reorg         `json:"lastReorg"`
+}
+
+// Reorg - replacement of the canonical chain starting at Block. Depth of reorgs deeper than
+// the statistics window is not known exactly, the window size is reported then.
+type Reorg struct {
+	Block hexutil.Uint64 `json:"block"`
+	Depth uint64         `json:"depth"`
+	Time  uint64         `json:"time"` // when the reorg was noticed, unix seconds
+}
+
+type chainStatsBlock struct {
+	number   uint64
+	hash     common.Hash
+	time     uint64
+	gasUsed  uint64
+	gasLimit uint64
+	uncles   int
+}
+
+// chainStatsTracker keeps the window of the latest canonical blocks in sync with the db: on every new head
+// announced by the Finish stage (to update metrics) and on every erigon_chainStats call. Blocks of the window
+// replaced by other canonical blocks are a reorg. An unwind alone isn't: unwound blocks are remembered until the
+// chain grows over their heights again, and count only if different blocks are canonical there then.
+type chainStatsTracker struct {
+	lock        sync.Mutex
+	db          kv.RoDB
+	blockReader services.FullBlockReader
+	blocks      []chainStatsBlock      // ascending canonical blocks, at most chainStatsWindow
+	unwound     map[uint64]common.Hash // blocks above the head which were dropped from the window, not replaced yet
+
+	reorgs        uint64
+	maxReorgDepth uint64
+	lastReorg     *Reorg
+}
+
+func newChainStatsTracker(db kv.RoDB, blockReader services.FullBlockReader, filters *rpchelper.Filters) *chainStatsTracker {
+	t := &chainStatsTracker{db: db, blockReader: blockReader, unwound: map[uint64]common.Hash{}}
+	if filters == nil || db == nil {
+		return t
+	}
+	heads := make(chan *types.Header, 1)
+	id := filters.SubscribeNewHeads(heads)
+	go func() {
+		<-filters.Done()
+		filters.UnsubscribeHeads(id) // closes heads, the goroutines below stop
+	}()
+	newHead := make(chan struct{}, 1)
+	go func() {
+		defer close(newHead)
+		for range heads { // headers are sent synchronously to all subscribers - don't read the db here
+			select {
+			case newHead <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	go func() {
+		for range newHead {
+			if err := t.update(context.Background()); err != nil {
+				log.Warn("[rpc] chain stats update failed", "err", err)
+			}
+		}
+	}()
+	return t
+}
+
+func (t *chainStatsTracker) update(ctx context.Context) error {
+	tx, err := t.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	head, err := stages.GetStageProgress(tx, stages.Finish)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var replaced, firstReplaced uint64
+	isReplaced := func(number uint64, hash common.Hash) (bool, error) {
+		canonical, err := t.blockReader.CanonicalHash(ctx, tx, number)
+		if err != nil {
+			return false, err
+		}
+		return canonical != (common.Hash{}) && canonical != hash, nil
+	}
+	for len(t.blocks) > 0 {
+		last := t.blocks[len(t.blocks)-1]
+		ok, err := isReplaced(last.number, last.hash)
+		if err != nil {
+			return err
+		}
+		if !ok && last.number <= head {
+			break
+		}
+		t.blocks = t.blocks[:len(t.blocks)-1]
+		if ok {
+			replaced, firstReplaced = replaced+1, last.number
+		} else {
+			t.unwound[last.number] = last.hash
+		}
+	}
+	for number, hash := range t.unwound {
+		if number > head {
+			continue
+		}
+		delete(t.unwound, number)
+		ok, err := isReplaced(number, hash)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if replaced == 0 || number < firstReplaced {
+				firstReplaced = number
+			}
+			replaced++
+		}
+	}
+	if replaced > 0 {
+		t.addReorg(firstReplaced, replaced)
+	}
+
+	from := uint64(0)
+	if head >= chainStatsWindow {
+		from = head - chainStatsWindow + 1
+	}
+	if len(t.blocks) > 0 {
+		if next := t.blocks[len(t.blocks)-1].number + 1; next >= from {
+			from = next
+		} else {
+			t.blocks = t.blocks[:0] // all known blocks are out of the window
+		}
+	}
+	for n := from; n <= head; n++ {
+		header, err := t.blockReader.HeaderByNumber(ctx, tx, n)
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			break
+		}
+		block := chainStatsBlock{number: n, hash: header.Hash(), time: header.Time, gasUsed: header.GasUsed, gasLimit: header.GasLimit}
+		if header.UncleHash != types.EmptyUncleHash {
+			body, _, err := t.blockReader.Body(ctx, tx, block.hash, n)
+			if err != nil {
+				return err
+			}
+			if body != nil {
+				block.uncles = len(body.Uncles)
+			}
+		}
+		t.blocks = append(t.blocks, block)
+	}
+	if len(t.blocks) > chainStatsWindow {
+		t.blocks = append(t.blocks[:0], t.blocks[len(t.blocks)-chainStatsWindow:]...)
+	}
+
+	stats := t.statsLocked()
+	chainUncleRate.Set(stats.UncleRate)
+	chainBlockInterval.Set(stats.BlockInterval)
+	chainGasUtilization.Set(stats.GasUtilization)
+	return nil
+}
+
+func (t *chainStatsTracker) addReorg(first, replaced uint64) {
+	reorg := &Reorg{Block: hexutil.Uint64(first), Depth: replaced, Time: uint64(time.Now().Unix())}
+	t.reorgs++
+	if replaced > t.maxReorgDepth {
+		t.maxReorgDepth = replaced
+	}
+	t.lastReorg = reorg
+	chainReorgs.Inc()
+	chainReorgDepth.Update(float64(replaced))
+	log.Debug("[rpc] reorg", "block", uint64(reorg.Block), "depth", replaced)
+}
+
+func (t *chainStatsTracker) stats() *ChainStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.statsLocked()
+}
+
+func (t *chainStatsTracker) statsLocked() *ChainStats {
+	stats := &ChainStats{Reorgs: t.reorgs, MaxReorgDepth: t.maxReorgDepth}
+	if t.lastReorg != nil {
+		reorg := *t.lastReorg
+		stats.LastReorg = &reorg
+	}
+	if len(t.blocks) == 0 {
+		return stats
+	}
+	first, last := t.blocks[0], t.blocks[len(t.blocks)-1]
+	stats.FromBlock, stats.ToBlock = hexutil.Uint64(first.number), hexutil.Uint64(last.number)
+	var gasUsed, gasLimit float64
+	for _, b := range t.blocks {
+		stats.Uncles += uint64(b.uncles)
+		gasUsed += float64(b.gasUsed)
+		gasLimit += float64(b.gasLimit)
+	}
+	stats.UncleRate = float64(stats.Uncles) / float64(len(t.blocks))
+	if gasLimit > 0 {
+		stats.GasUtilization = gasUsed / gasLimit
+	}
+	if len(t.blocks) > 1 && last.time >= first.time {
+		stats.BlockInterval = float64(last.time-first.time) / float64(len(t.blocks)-1)
+	}
+	return stats
+}
+
+// ChainStats implements erigon_chainStats. Returns uncle rate, average block interval and gas utilization
+// of the latest canonical blocks, and reorgs noticed by this rpcdaemon.
+func (api *ErigonImpl) ChainStats(ctx context.Context) (*ChainStats, error) {
+	if err := api.chainStats.update(ctx); err != nil {
+		return nil, err
+	}
+	return api.chainStats.stats(), nil
+}
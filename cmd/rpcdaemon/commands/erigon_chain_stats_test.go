@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainStats(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+	tracker := newChainStatsTracker(db, snapshotsync.NewBlockReader(), nil)
+
+	// canonical blocks [from, to] of the given fork, every 4th block with an uncle, Finish stage at to
+	commit := func(from, to uint64, fork byte) {
+		require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+			for i := from; i <= to; i++ {
+				h := &types.Header{Number: new(big.Int).SetUint64(i), Time: 12 * i, GasLimit: 1000, GasUsed: 500, Extra: []byte{fork}, UncleHash: types.EmptyUncleHash}
+				var uncles []*types.Header
+				if i%4 == 0 {
+					uncles = []*types.Header{{Number: new(big.Int).SetUint64(i - 1), Extra: []byte{fork}}}
+					h.UncleHash = types.CalcUncleHash(uncles)
+				}
+				rawdb.WriteHeader(tx, h)
+				if err := rawdb.WriteBody(tx, h.Hash(), i, &types.Body{Uncles: uncles}); err != nil {
+					return err
+				}
+				if err := rawdb.WriteCanonicalHash(tx, h.Hash(), i); err != nil {
+					return err
+				}
+			}
+			return stages.SaveStageProgress(tx, stages.Finish, to)
+		}))
+	}
+
+	commit(0, 7, 0)
+	require.NoError(tracker.update(ctx))
+	require.Equal(&ChainStats{FromBlock: 0, ToBlock: 7, Uncles: 2, UncleRate: 0.25, BlockInterval: 12, GasUtilization: 0.5}, tracker.stats())
+
+	// blocks 6,7 are replaced by 6,7,8 of another fork
+	commit(6, 8, 1)
+	require.NoError(tracker.update(ctx))
+	stats := tracker.stats()
+	require.Equal(hexutil.Uint64(8), stats.ToBlock)
+	require.Equal(uint64(3), stats.Uncles)
+	require.Equal(uint64(1), stats.Reorgs)
+	require.Equal(uint64(2), stats.MaxReorgDepth)
+	require.Equal(hexutil.Uint64(6), stats.LastReorg.Block)
+	require.Equal(uint64(2), stats.LastReorg.Depth)
+
+	// an unwind alone isn't a reorg: the head goes back and the same blocks are executed again
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error { return stages.SaveStageProgress(tx, stages.Finish, 6) }))
+	require.NoError(tracker.update(ctx))
+	require.Equal(hexutil.Uint64(6), tracker.stats().ToBlock)
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error { return stages.SaveStageProgress(tx, stages.Finish, 8) }))
+	require.NoError(tracker.update(ctx))
+	require.Equal(hexutil.Uint64(8), tracker.stats().ToBlock)
+	require.Equal(uint64(1), tracker.stats().Reorgs)
+
+	// unwound blocks replaced by another fork later are a reorg
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		if err := rawdb.TruncateCanonicalHash(tx, 8, false); err != nil {
+			return err
+		}
+		return stages.SaveStageProgress(tx, stages.Finish, 7)
+	}))
+	require.NoError(tracker.update(ctx))
+	require.Equal(uint64(1), tracker.stats().Reorgs)
+	commit(8, 8, 2)
+	require.NoError(tracker.update(ctx))
+	stats = tracker.stats()
+	require.Equal(uint64(2), stats.Reorgs)
+	require.Equal(hexutil.Uint64(8), stats.LastReorg.Block)
+	require.Equal(uint64(1), stats.LastReorg.Depth)
+
+	// window is limited
+	commit(9, chainStatsWindow+10, 1)
+	require.NoError(tracker.update(ctx))
+	stats = tracker.stats()
+	require.Equal(hexutil.Uint64(11), stats.FromBlock)
+	require.Equal(hexutil.Uint64(chainStatsWindow+10), stats.ToBlock)
+	require.Equal(uint64(2), stats.Reorgs)
+}
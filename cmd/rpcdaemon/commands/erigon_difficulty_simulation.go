@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/difficultysim"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// Each simulated ethash block computes the difficulty for up to 1000 timestamps, the limits keep one call of
+// the unauthenticated method within a second or so
+const (
+	maxSimulatedBlocks  = 5_000 // limit of blocks simulated by one erigon_simulateDifficulty call
+	maxSimulatedSamples = 2_000 // limit of samples returned by one erigon_simulateDifficulty call
+)
+
+// DifficultySimulationArgs - parameters of erigon_simulateDifficulty. Chain config and the parent block are
+// taken from the node unless they are given.
+type DifficultySimulationArgs struct {
+	Config           *params.ChainConfig     `json:"config"`
+	FromBlock        *rpc.BlockNumber        `json:"fromBlock"` // Parent block of the node, latest by default
+	ParentNumber     *hexutil.Uint64         `json:"parentNumber"`
+	ParentTimestamp  *hexutil.Uint64         `json:"parentTimestamp"`
+	ParentDifficulty *hexutil.Big            `json:"parentDifficulty"`
+	Blocks           hexutil.Uint64          `json:"blocks"`
+	SampleEvery      hexutil.Uint64          `json:"sampleEvery"`
+	Hashrates        []ethash.HashrateChange `json:"hashrates"` // Ethash
+	Signers          int                     `json:"signers"`   // Clique
+	Online           *int                    `json:"online"`    // Clique, all signers by default
+	Seed             int64                   `json:"seed"`      // Random block times with this seed if not 0
+}
+
+// DifficultySample - simulated block, BlockTime is an average since the previous sample, in seconds
+type DifficultySample struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Timestamp  hexutil.Uint64 `json:"timestamp"`
+	Difficulty *hexutil.Big   `json:"difficulty"`
+	BlockTime  float64        `json:"blockTime"`
+}
+
+// SimulateDifficulty implements erigon_simulateDifficulty. Simulates difficulty and block time evolution with the
+// difficulty calculator of the consensus engine: for ethash under the given hashrate, for clique with the given
+// amount of online signers.
+func (api *ErigonImpl) SimulateDifficulty(ctx context.Context, args DifficultySimulationArgs) ([]DifficultySample, error) {
+	if args.Blocks == 0 {
+		return nil, errors.New("blocks must be positive")
+	}
+	if args.Blocks > maxSimulatedBlocks {
+		return nil, &rpc.LimitExceededError{Message: fmt.Sprintf("too many blocks requested: %d, limit is %d", args.Blocks, maxSimulatedBlocks), Limit: maxSimulatedBlocks}
+	}
+	sampleEvery := uint64(args.SampleEvery)
+	if sampleEvery == 0 {
+		sampleEvery = 1
+	}
+	if samples := uint64(args.Blocks) / sampleEvery; samples > maxSimulatedSamples {
+		return nil, &rpc.LimitExceededError{Message: fmt.Sprintf("too many samples requested: %d, limit is %d", samples, maxSimulatedSamples), Limit: maxSimulatedSamples}
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	config := args.Config
+	if config == nil {
+		if config, err = api.chainConfig(tx); err != nil {
+			return nil, err
+		}
+	}
+	p := difficultysim.Params{Blocks: uint64(args.Blocks), SampleEvery: sampleEvery, Hashrates: args.Hashrates, Signers: args.Signers, Online: args.Signers, Seed: args.Seed}
+	if args.Online != nil {
+		p.Online = *args.Online
+	}
+	if args.ParentNumber == nil || args.ParentTimestamp == nil || (config.Clique == nil && args.ParentDifficulty == nil) {
+		blockNum := rpc.LatestBlockNumber
+		if args.FromBlock != nil {
+			blockNum = *args.FromBlock
+		}
+		number, hash, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(blockNum), tx, api.filters)
+		if err != nil {
+			return nil, err
+		}
+		header := rawdb.ReadHeader(tx, hash, number)
+		if header == nil {
+			return nil, rpc.NewNotFoundError("header", number)
+		}
+		p.ParentNumber, p.ParentTime, p.ParentDifficulty = number, header.Time, header.Difficulty
+	}
+	if args.ParentNumber != nil {
+		p.ParentNumber = uint64(*args.ParentNumber)
+	}
+	if args.ParentTimestamp != nil {
+		p.ParentTime = uint64(*args.ParentTimestamp)
+	}
+	if args.ParentDifficulty != nil {
+		p.ParentDifficulty = args.ParentDifficulty.ToInt()
+	}
+
+	simulated, err := difficultysim.Run(ctx, config, p)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]DifficultySample, len(simulated))
+	for i, s := range simulated {
+		samples[i] = DifficultySample{Number: hexutil.Uint64(s.Number), Timestamp: hexutil.Uint64(s.Time), Difficulty: (*hexutil.Big)(s.Difficulty), BlockTime: s.BlockTime}
+	}
+	return samples, nil
+}
@@ -8,8 +8,13 @@ import (
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
 // BlockReward returns the block reward for this block
@@ -34,7 +39,126 @@ import (
 //	return api.rewardCalc(tx, blockNr, "uncle") // nolint goconst
 //}
 
-// Issuance implements erigon_issuance. Returns the total issuance (block reward plus uncle reward) for the given block.
+// Issuance implements erigon_issuance. Returns the rewards and burnt fees of the given block, together with
+// the cumulative issuance, burn and supply up to and including it, as maintained by the WatchTheBurn stage.
+func (api *ErigonImpl) Issuance(ctx context.Context, blockNr rpc.BlockNumber) (*BlockIssuance, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	if chainConfig.Consensus != params.EtHashConsensus {
+		return nil, fmt.Errorf("issuance is only tracked for ethash based chains")
+	}
+	blockNum, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(blockNr), tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := stages.GetStageProgress(tx, stages.Issuance)
+	if err != nil {
+		return nil, err
+	}
+	if blockNum > progress {
+		return nil, fmt.Errorf("issuance of block %d is not computed yet (computed up to %d), is --watch-the-burn enabled?", blockNum, progress)
+	}
+	block, err := api.blockByNumberWithSenders(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, rpc.NewNotFoundError("block", blockNum)
+	}
+
+	blockReward, uncleRewards := blockIssuance(chainConfig, block.Header(), block.Uncles())
+	ret := &BlockIssuance{
+		BlockReward:  (*hexutil.Big)(blockReward),
+		UncleRewards: make([]*hexutil.Big, len(uncleRewards)),
+	}
+	issuance := new(big.Int).Set(blockReward)
+	for i, r := range uncleRewards {
+		ret.UncleRewards[i] = (*hexutil.Big)(r)
+		issuance.Add(issuance, r)
+	}
+	ret.Issuance = (*hexutil.Big)(issuance)
+	ret.Burnt = (*hexutil.Big)(blockBurnt(block.Header()))
+
+	totalIssued, err := rawdb.ReadTotalIssued(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	totalBurnt, err := rawdb.ReadTotalBurnt(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	ret.TotalIssued = (*hexutil.Big)(totalIssued)
+	ret.TotalBurnt = (*hexutil.Big)(totalBurnt)
+	ret.Supply = (*hexutil.Big)(new(big.Int).Sub(totalIssued, totalBurnt))
+	return ret, nil
+}
+
+// blockIssuance returns the reward of the block producer and the rewards of the uncles of a block.
+// It follows the accounting of the WatchTheBurn stage, so that the values add up to the totals it maintains:
+// proof-of-stake blocks mint nothing on the execution layer, validators are rewarded by the beacon chain.
+func blockIssuance(chainConfig *params.ChainConfig, header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) {
+	if header.Difficulty.Cmp(serenity.SerenityDifficulty) == 0 {
+		return new(big.Int), nil
+	}
+	minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, header, uncles)
+	rewards := make([]*big.Int, len(uncleRewards))
+	for i := range uncleRewards {
+		rewards[i] = uncleRewards[i].ToBig()
+	}
+	return minerReward.ToBig(), rewards
+}
+
+// issuanceCalculator - rewards created by the consensus engine of the chain for a block
+type issuanceCalculator interface {
+	// blockRewards returns the reward of the block producer and the rewards of the uncles of the block
+	blockRewards(header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int)
+}
+
+// newIssuanceCalculator - ethash chains mint block and uncle rewards (see blockIssuance), engines of the other chains
+// (clique, aura, bor, parlia) mint nothing: their validators are paid by fees or by system contracts
+func newIssuanceCalculator(chainConfig *params.ChainConfig) issuanceCalculator {
+	if chainConfig.Ethash != nil {
+		return ethashIssuance{chainConfig: chainConfig}
+	}
+	return noIssuance{}
+}
+
+type ethashIssuance struct {
+	chainConfig *params.ChainConfig
+}
+
+func (c ethashIssuance) blockRewards(header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) {
+	return blockIssuance(c.chainConfig, header, uncles)
+}
+
+type noIssuance struct{}
+
+func (noIssuance) blockRewards(header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) {
+	rewards := make([]*big.Int, len(uncles))
+	for i := range rewards {
+		rewards[i] = new(big.Int)
+	}
+	return new(big.Int), rewards
+}
+
+// blockBurnt - base fees of the gas used by the block, burnt since London
+func blockBurnt(header *types.Header) *big.Int {
+	burnt := new(big.Int)
+	if header.BaseFee != nil {
+		burnt.Mul(header.BaseFee, new(big.Int).SetUint64(header.GasUsed))
+	}
+	return burnt
+}
+
+// WatchTheBurn implements erigon_watchTheBurn. Returns the total issuance (block reward plus uncle reward) for the given block.
 func (api *ErigonImpl) WatchTheBurn(ctx context.Context, blockNr rpc.BlockNumber) (Issuance, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
@@ -120,6 +244,17 @@ func (api *ErigonImpl) WatchTheBurn(ctx context.Context, blockNr rpc.BlockNumber
 	return ret, nil
 }
 
+// BlockIssuance is the result of erigon_issuance
+type BlockIssuance struct {
+	BlockReward  *hexutil.Big   `json:"blockReward"`  // Reward of the block producer
+	UncleRewards []*hexutil.Big `json:"uncleRewards"` // Rewards of the uncles, in the order of the uncles in the block
+	Issuance     *hexutil.Big   `json:"issuance"`     // Total amount of wei created in the block
+	Burnt        *hexutil.Big   `json:"burnt"`        // Amount of wei burnt by EIP-1559 base fee in the block
+	TotalIssued  *hexutil.Big   `json:"totalIssued"`  // Amount of wei created up to and including the block, genesis allocations included
+	TotalBurnt   *hexutil.Big   `json:"totalBurnt"`   // Amount of wei burnt up to and including the block
+	Supply       *hexutil.Big   `json:"supply"`       // TotalIssued minus TotalBurnt
+}
+
 // Issuance structure to return information about issuance
 type Issuance struct {
 	BlockReward *hexutil.Big `json:"blockReward"` // Block reward for given block
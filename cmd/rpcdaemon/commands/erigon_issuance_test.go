@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockIssuance(t *testing.T) {
+	config := params.MainnetChainConfig
+	header := &types.Header{Number: big.NewInt(100), Difficulty: big.NewInt(1)}
+	uncles := []*types.Header{{Number: big.NewInt(99)}, {Number: big.NewInt(98)}}
+
+	blockReward, uncleRewards := blockIssuance(config, header, uncles)
+	expectedBlock, expectedUncles := ethash.AccumulateRewards(config, header, uncles)
+	require.Equal(t, expectedBlock.ToBig(), blockReward)
+	require.Len(t, uncleRewards, len(uncles))
+	for i := range uncles {
+		require.Equal(t, expectedUncles[i].ToBig(), uncleRewards[i])
+	}
+
+	posHeader := &types.Header{Number: big.NewInt(100), Difficulty: serenity.SerenityDifficulty}
+	blockReward, uncleRewards = blockIssuance(config, posHeader, nil)
+	require.Zero(t, blockReward.Sign())
+	require.Empty(t, uncleRewards)
+}
+
+func TestIssuanceCalculator(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(100), Difficulty: big.NewInt(1)}
+	uncles := []*types.Header{{Number: big.NewInt(99)}}
+
+	blockReward, uncleRewards := newIssuanceCalculator(params.MainnetChainConfig).blockRewards(header, uncles)
+	expectedBlock, expectedUncles := blockIssuance(params.MainnetChainConfig, header, uncles)
+	require.Equal(t, expectedBlock, blockReward)
+	require.Equal(t, expectedUncles, uncleRewards)
+
+	blockReward, uncleRewards = newIssuanceCalculator(params.GoerliChainConfig).blockRewards(header, uncles)
+	require.Zero(t, blockReward.Sign())
+	require.Len(t, uncleRewards, 1)
+	require.Zero(t, uncleRewards[0].Sign())
+
+	require.Zero(t, blockBurnt(header).Sign())
+	londonHeader := &types.Header{Number: big.NewInt(100), BaseFee: big.NewInt(7), GasUsed: 21000}
+	require.Equal(t, big.NewInt(7*21000), blockBurnt(londonHeader))
+}
@@ -19,6 +19,46 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
+// GetRevertReason implements erigon_getRevertReason. Replays the transaction and returns why it failed,
+// with Error(string) and Panic(uint256) return data decoded. Returns nil for successful transactions.
+func (api *ErigonImpl) GetRevertReason(ctx context.Context, txnHash common.Hash) (*RevertReason, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNum, ok, err := api.txnLookup(ctx, tx, txnHash)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, rpc.NewNotFoundError("transaction", txnHash)
+	}
+	block, err := api.blockByNumberWithSenders(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, rpc.NewNotFoundError("block", blockNum)
+	}
+	txnIndex := -1
+	for i, txn := range block.Transactions() {
+		if txn.Hash() == txnHash {
+			txnIndex = i
+			break
+		}
+	}
+	if txnIndex < 0 {
+		return nil, rpc.NewNotFoundError("transaction", txnHash)
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	return api.revertReason(ctx, tx, chainConfig, block, uint64(txnIndex))
+}
+
 // GetLogsByHash implements erigon_getLogsByHash. Returns an array of arrays of logs generated by the transactions in the block given by the block's hash.
 func (api *ErigonImpl) GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	tx, err := api.db.BeginRo(ctx)
@@ -65,7 +105,7 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 	if crit.BlockHash != nil {
 		number := rawdb.ReadHeaderNumber(tx, *crit.BlockHash)
 		if number == nil {
-			return nil, fmt.Errorf("block not found: %x", *crit.BlockHash)
+			return nil, rpc.NewNotFoundError("block", *crit.BlockHash)
 		}
 		begin = *number
 		end = *number
@@ -99,6 +139,9 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 	if end > roaring.MaxUint32 {
 		return nil, fmt.Errorf("end (%d) > MaxUint32", end)
 	}
+	if err := rpchelper.CheckReorgInProgress(tx, end); err != nil {
+		return nil, err
+	}
 	blockNumbers := bitmapdb.NewBitmap()
 	defer bitmapdb.ReturnToPool(blockNumbers)
 	blockNumbers.AddRange(begin, end+1) // [min,max)
@@ -172,7 +215,7 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 			return nil, err
 		}
 		if header == nil {
-			return nil, fmt.Errorf("block header not found: %d", blockNumber)
+			return nil, rpc.NewNotFoundError("header", blockNumber)
 		}
 		timestamp := header.Time
 
@@ -186,7 +229,7 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 			return nil, err
 		}
 		if body == nil {
-			return nil, fmt.Errorf("block not found %d", blockNumber)
+			return nil, rpc.NewNotFoundError("block", blockNumber)
 		}
 		for _, log := range blockLogs {
 			erigonLog := &types.ErigonLog{}
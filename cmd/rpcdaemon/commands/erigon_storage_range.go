@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// maxStorageRangePageSize - limit of slots returned by one erigon_getStorageRange call
+const maxStorageRangePageSize = 1024
+
+// StorageSlot - a non-zero storage slot of a contract
+type StorageSlot struct {
+	Key       common.Hash `json:"key"`       // the slot, Erigon stores plain keys so it's always known
+	HashedKey common.Hash `json:"hashedKey"` // keccak of the slot, the key in the storage trie
+	Value     common.Hash `json:"value"`
+}
+
+// StorageRange - a page of storage slots ordered by slot
+type StorageRange struct {
+	Slots   []StorageSlot `json:"slots"`
+	NextKey *common.Hash  `json:"nextKey,omitempty"` // the first slot of the next page, absent on the last page
+}
+
+// GetStorageRange implements erigon_getStorageRange. Returns non-zero storage slots of the contract at the end of the
+// block, starting from startKey (the first slot if omitted), at most pageSize of them. Slots are read in order from
+// the plain storage and its history, no slot guessing and no trie.
+func (api *ErigonImpl) GetStorageRange(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash, startKey *common.Hash, pageSize int) (*StorageRange, error) {
+	if pageSize <= 0 {
+		return nil, errors.New("pageSize must be positive")
+	}
+	if pageSize > maxStorageRangePageSize {
+		return nil, &rpc.LimitExceededError{Message: fmt.Sprintf("too many slots requested: %d, limit is %d", pageSize, maxStorageRangePageSize), Limit: maxStorageRangePageSize}
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if api.historyV3(tx) {
+		return nil, errors.New("erigon_getStorageRange is not supported with history v3")
+	}
+	blockNumber, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber > executed {
+		return nil, fmt.Errorf("block %d is not executed yet, latest executed block is %d", blockNumber, executed)
+	}
+
+	var start common.Hash
+	if startKey != nil {
+		start = *startKey
+	}
+	return storageRange(state.NewPlainState(tx, blockNumber+1), address, start, pageSize)
+}
+
+// storageRange - one more slot than the page is read to find out the next key
+func storageRange(reader *state.PlainState, address common.Address, start common.Hash, pageSize int) (*StorageRange, error) {
+	result := &StorageRange{Slots: make([]StorageSlot, 0)}
+	if err := reader.ForEachStorage(address, start, func(key, seckey common.Hash, value uint256.Int) bool {
+		if len(result.Slots) == pageSize {
+			next := key
+			result.NextKey = &next
+			return false
+		}
+		result.Slots = append(result.Slots, StorageSlot{Key: key, HashedKey: seckey, Value: value.Bytes32()})
+		return true
+	}, pageSize+1); err != nil {
+		return nil, fmt.Errorf("walking over storage of %x: %w", address, err)
+	}
+	return result, nil
+}
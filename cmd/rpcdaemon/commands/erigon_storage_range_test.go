@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageRange(t *testing.T) {
+	require := require.New(t)
+	db := memdb.NewTestDB(t)
+	address, other := common.Address{1}, common.Address{2}
+
+	// slots 1..5 of the contract, slot 3 of another one
+	require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+		for _, addr := range []common.Address{address, other} {
+			acc := accounts.Account{Initialised: true, Incarnation: 1}
+			enc := make([]byte, acc.EncodingLengthForStorage())
+			acc.EncodeForStorage(enc)
+			if err := tx.Put(kv.PlainState, addr[:], enc); err != nil {
+				return err
+			}
+		}
+		for i := byte(1); i <= 5; i++ {
+			slot := common.Hash{31: i}
+			if err := tx.Put(kv.PlainState, dbutils.PlainGenerateCompositeStorageKey(address[:], 1, slot[:]), []byte{i * 10}); err != nil {
+				return err
+			}
+		}
+		slot := common.Hash{31: 3}
+		return tx.Put(kv.PlainState, dbutils.PlainGenerateCompositeStorageKey(other[:], 1, slot[:]), []byte{0xff})
+	}))
+
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(err)
+	defer tx.Rollback()
+	reader := state.NewPlainState(tx, 1)
+
+	var slots []StorageSlot
+	start := common.Hash{}
+	for pages := 0; ; pages++ {
+		require.Less(pages, 3)
+		page, err := storageRange(reader, address, start, 2)
+		require.NoError(err)
+		require.LessOrEqual(len(page.Slots), 2)
+		slots = append(slots, page.Slots...)
+		if page.NextKey == nil {
+			break
+		}
+		start = *page.NextKey
+	}
+	require.Len(slots, 5)
+	for i, slot := range slots {
+		key := common.Hash{31: byte(i + 1)}
+		require.Equal(key, slot.Key)
+		require.Equal(crypto.Keccak256Hash(key[:]), slot.HashedKey)
+		require.Equal(common.Hash(uint256.NewInt(uint64(i+1)*10).Bytes32()), slot.Value)
+	}
+
+	page, err := storageRange(reader, common.Address{3}, common.Hash{}, 2)
+	require.NoError(err)
+	require.Empty(page.Slots)
+	require.Nil(page.NextKey)
+}
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	syncProgressSampleInterval = 10 * time.Second
+	syncProgressWindow         = 5 * time.Minute // throughput of stages is averaged over samples of this window
+)
+
+// SyncProgress - progress of stages towards the highest known header and the estimated time to reach it
+type SyncProgress struct {
+	CurrentBlock hexutil.Uint64  `json:"currentBlock"` // Finish stage
+	HighestBlock hexutil.Uint64  `json:"highestBlock"` // Headers stage
+	Eta          *float64        `json:"eta"`          // seconds until all stages reach the highest block, null if unknown
+	Stages       []StageProgress `json:"stages"`
+}
+
+type StageProgress struct {
+	Stage           string         `json:"stage"`
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	Remaining       uint64         `json:"remaining"`
+	BlocksPerSecond float64        `json:"blocksPerSecond"` // while the stage runs, 0 if it hasn't run since rpcdaemon start
+	// Eta - seconds until the stage reaches the highest block, stages before it run first. Null if throughput of the
+	// stage or of a stage before it isn't known yet, or if the stage is skipped.
+	Eta     *float64 `json:"eta"`
+	Skipped bool     `json:"skipped,omitempty"` // behind a later stage: disabled, or not run in every cycle (Snapshots)
+}
+
+type syncProgressSample struct {
+	time     time.Time
+	progress []uint64 // of stages.AllStages
+}
+
+// syncProgressTracker samples progress of stages every syncProgressSampleInterval and on every erigon_syncProgress
+// call. Stages run one after another, so the throughput of a stage is averaged only over samples in which it moved,
+// and the last known throughput is kept while the stage waits for the stages before it.
+type syncProgressTracker struct {
+	lock    sync.Mutex
+	db      kv.RoDB
+	samples []syncProgressSample // ascending, within syncProgressWindow
+	rates   []float64            // blocks per second of stages.AllStages, 0 if not known
+}
+
+func newSyncProgressTracker(db kv.RoDB, filters *rpchelper.Filters) *syncProgressTracker {
+	t := &syncProgressTracker{db: db, rates: make([]float64, len(stages.AllStages))}
+	if filters == nil || db == nil { // tests
+		return t
+	}
+	go func() {
+		ticker := time.NewTicker(syncProgressSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-filters.Done():
+				return
+			case now := <-ticker.C:
+				if _, err := t.update(context.Background(), now); err != nil {
+					log.Warn("[rpc] sync progress update failed", "err", err)
+				}
+			}
+		}
+	}()
+	return t
+}
+
+func (t *syncProgressTracker) update(ctx context.Context, now time.Time) (*SyncProgress, error) {
+	tx, err := t.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	sample := syncProgressSample{time: now, progress: make([]uint64, len(stages.AllStages))}
+	for i, stage := range stages.AllStages {
+		if sample.progress[i], err = stages.GetStageProgress(tx, stage); err != nil {
+			return nil, err
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.samples) > 0 && !now.After(t.samples[len(t.samples)-1].time) {
+		return t.progressLocked(sample), nil
+	}
+	t.samples = append(t.samples, sample)
+	for len(t.samples) > 2 && now.Sub(t.samples[0].time) > syncProgressWindow {
+		t.samples = t.samples[1:]
+	}
+	for i := range stages.AllStages {
+		var blocks uint64
+		var seconds float64
+		for j := 1; j < len(t.samples); j++ {
+			prev, next := t.samples[j-1], t.samples[j]
+			if next.progress[i] > prev.progress[i] { // unwinds and idle intervals don't count
+				blocks += next.progress[i] - prev.progress[i]
+				seconds += next.time.Sub(prev.time).Seconds()
+			}
+		}
+		if seconds > 0 {
+			t.rates[i] = float64(blocks) / seconds
+		}
+	}
+
+	progress := t.progressLocked(sample)
+	updateSyncProgressMetrics(progress)
+	return progress, nil
+}
+
+func (t *syncProgressTracker) progressLocked(sample syncProgressSample) *SyncProgress {
+	headers, finish := 0, 0
+	for i, stage := range stages.AllStages {
+		switch stage {
+		case stages.Headers:
+			headers = i
+		case stages.Finish:
+			finish = i
+		}
+	}
+	highest := sample.progress[headers]
+	res := &SyncProgress{CurrentBlock: hexutil.Uint64(sample.progress[finish]), HighestBlock: hexutil.Uint64(highest)}
+
+	// a stage behind a later one doesn't hold the sync back
+	skipped := make([]bool, len(stages.AllStages))
+	var later uint64
+	for i := len(stages.AllStages) - 1; i >= 0; i-- {
+		skipped[i] = sample.progress[i] < later
+		if sample.progress[i] > later {
+			later = sample.progress[i]
+		}
+	}
+
+	var eta float64
+	known := true
+	for i, stage := range stages.AllStages {
+		p := StageProgress{Stage: string(stage), BlockNumber: hexutil.Uint64(sample.progress[i]), BlocksPerSecond: t.rates[i], Skipped: skipped[i]}
+		if highest > sample.progress[i] {
+			p.Remaining = highest - sample.progress[i]
+		}
+		if !skipped[i] {
+			if p.Remaining > 0 {
+				if t.rates[i] > 0 {
+					eta += float64(p.Remaining) / t.rates[i]
+				} else {
+					known = false
+				}
+			}
+			if known {
+				stageEta := eta
+				p.Eta = &stageEta
+			}
+		}
+		res.Stages = append(res.Stages, p)
+	}
+	if known {
+		res.Eta = &eta
+	}
+	return res
+}
+
+// updateSyncProgressMetrics - sync_eta_seconds (-1 if unknown), and per stage with the stage label
+func updateSyncProgressMetrics(progress *SyncProgress) {
+	eta := func(eta *float64) float64 {
+		if eta == nil {
+			return -1
+		}
+		return *eta
+	}
+	metrics.GetOrCreateFloatCounter(`sync_eta_seconds`).Set(eta(progress.Eta))
+	for _, p := range progress.Stages {
+		metrics.GetOrCreateFloatCounter(fmt.Sprintf(`sync_eta_seconds{stage=%q}`, p.Stage)).Set(eta(p.Eta))
+		metrics.GetOrCreateFloatCounter(fmt.Sprintf(`sync_blocks_per_second{stage=%q}`, p.Stage)).Set(p.BlocksPerSecond)
+	}
+}
+
+// SyncProgress implements erigon_syncProgress. Returns progress of every stage, its throughput averaged over the last
+// minutes and the estimated time until the sync reaches the highest known header.
+func (api *ErigonImpl) SyncProgress(ctx context.Context) (*SyncProgress, error) {
+	return api.syncProgress.update(ctx, time.Now())
+}
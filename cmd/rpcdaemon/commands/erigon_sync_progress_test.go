@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncProgress(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+	tracker := newSyncProgressTracker(db, nil)
+	start := time.Unix(1_000_000, 0)
+
+	save := func(progress map[stages.SyncStage]uint64) {
+		require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+			for stage, p := range progress {
+				if err := stages.SaveStageProgress(tx, stage, p); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	}
+	stage := func(progress *SyncProgress, stage stages.SyncStage) StageProgress {
+		for _, p := range progress.Stages {
+			if p.Stage == string(stage) {
+				return p
+			}
+		}
+		t.Fatalf("no stage %s", stage)
+		return StageProgress{}
+	}
+
+	// headers are downloaded, bodies are behind, the rest hasn't run yet
+	all := map[stages.SyncStage]uint64{}
+	for _, s := range stages.AllStages {
+		all[s] = 0
+	}
+	all[stages.Headers], all[stages.BlockHashes], all[stages.Bodies] = 1000, 1000, 100
+	save(all)
+	progress, err := tracker.update(ctx, start)
+	require.NoError(err)
+	require.Equal(hexutil.Uint64(1000), progress.HighestBlock)
+	require.Nil(progress.Eta)
+	require.Equal(uint64(900), stage(progress, stages.Bodies).Remaining)
+
+	// bodies at 10 blocks per second, in 2 samples
+	all[stages.Bodies] = 200
+	save(all)
+	_, err = tracker.update(ctx, start.Add(10*time.Second))
+	require.NoError(err)
+	all[stages.Bodies] = 300
+	save(all)
+	progress, err = tracker.update(ctx, start.Add(20*time.Second))
+	require.NoError(err)
+	bodies := stage(progress, stages.Bodies)
+	require.Equal(10.0, bodies.BlocksPerSecond)
+	require.NotNil(bodies.Eta)
+	require.Equal(70.0, *bodies.Eta)
+	require.Nil(stage(progress, stages.Senders).Eta)
+	require.Nil(progress.Eta)
+
+	// all other stages move 100 blocks in 10 seconds, bodies keep their throughput while idle
+	for _, s := range stages.AllStages {
+		if s != stages.Headers && s != stages.BlockHashes && s != stages.Bodies {
+			all[s] = 100
+		}
+	}
+	all[stages.Snapshots] = 0
+	save(all)
+	_, err = tracker.update(ctx, start.Add(30*time.Second))
+	require.NoError(err)
+	progress, err = tracker.update(ctx, start.Add(30*time.Second)) // the same time, not a sample
+	require.NoError(err)
+	require.Equal(10.0, stage(progress, stages.Bodies).BlocksPerSecond)
+	require.Equal(10.0, stage(progress, stages.Execution).BlocksPerSecond)
+	require.True(stage(progress, stages.Snapshots).Skipped)
+	require.Nil(stage(progress, stages.Snapshots).Eta)
+	require.NotNil(progress.Eta)
+	// bodies 700/10, then every stage after bodies 900/10 (snapshots, headers, block hashes and bodies come first)
+	after := len(stages.AllStages) - 4
+	require.InDelta(70.0+float64(after)*90, *progress.Eta, 1e-9)
+	require.InDelta(70.0+90, *stage(progress, stages.Senders).Eta, 1e-9)
+}
@@ -33,6 +33,32 @@ func (api *ErigonImpl) Forks(ctx context.Context) (Forks, error) {
 	return Forks{genesis.Hash(), forksBlocks}, nil
 }
 
+// ForkID - EIP-2124 fork identifier: checksum of the genesis hash and passed forks, and the block of the next fork
+type ForkID struct {
+	Hash hexutil.Bytes  `json:"hash"`
+	Next hexutil.Uint64 `json:"next"` // 0 if no fork is scheduled
+}
+
+// ForkId implements erigon_forkId. Returns the EIP-2124 fork identifier of the latest block, the one announced to peers
+func (api *ErigonImpl) ForkId(ctx context.Context) (*ForkID, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chainConfig, genesis, err := api.chainConfigWithGenesis(tx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := rpchelper.GetLatestBlockNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	id := forkid.NewID(chainConfig, genesis.Hash(), head)
+	return &ForkID{Hash: id.Hash[:], Next: hexutil.Uint64(id.Next)}, nil
+}
+
 // Post the merge eth_blockNumber will return latest forkChoiceHead block number
 // erigon_blockNumber will return latest executed block number or any block number requested
 func (api *ErigonImpl) BlockNumber(ctx context.Context, rpcBlockNumPtr *rpc.BlockNumber) (hexutil.Uint64, error) {
@@ -9,6 +9,7 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/holiman/uint256"
+	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
@@ -18,14 +19,19 @@ import (
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	ethFilters "github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/eth/gasprice"
+	"github.com/ledgerwatch/erigon/ethdb/kvwatch"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/semaphore"
 )
 
 // EthAPI is a collection of functions that are exposed in the
@@ -45,7 +51,7 @@ type EthAPI interface {
 	GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error)
 
 	// Receipt related (see ./eth_receipts.go)
-	GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error)
+	GetTransactionReceipt(ctx context.Context, hash common.Hash, opts *ReceiptOptions) (map[string]interface{}, error)
 	GetLogs(ctx context.Context, crit ethFilters.FilterCriteria) (types.Logs, error)
 	GetBlockReceipts(ctx context.Context, number rpc.BlockNumber) ([]map[string]interface{}, error)
 
@@ -61,6 +67,7 @@ type EthAPI interface {
 	NewFilter(_ context.Context, crit ethFilters.FilterCriteria) (string, error)
 	UninstallFilter(_ context.Context, index string) (bool, error)
 	GetFilterChanges(_ context.Context, index string) ([]interface{}, error)
+	GetFilterLogs(ctx context.Context, index string) (types.Logs, error)
 
 	// Account related (see ./eth_accounts.go)
 	Accounts(ctx context.Context) ([]common.Address, error)
@@ -83,7 +90,7 @@ type EthAPI interface {
 	SendTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
 	Sign(ctx context.Context, _ common.Address, _ hexutil.Bytes) (hexutil.Bytes, error)
 	SignTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
-	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error)
+	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error)
 	CreateAccessList(ctx context.Context, args ethapi.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, optimizeGas *bool) (*accessListResult, error)
 
 	// Mining related (see ./eth_mining.go)
@@ -96,12 +103,14 @@ type EthAPI interface {
 }
 
 type BaseAPI struct {
-	stateCache   kvcache.Cache // thread-safe
-	blocksLRU    *lru.Cache    // thread-safe
-	filters      *rpchelper.Filters
-	_chainConfig *params.ChainConfig
-	_genesis     *types.Block
-	_genesisLock sync.RWMutex
+	stateCache kvcache.Cache // thread-safe
+	blocksLRU  *lru.Cache    // thread-safe
+	// revertReasons caches results of replays of transactions, see revertReason
+	revertReasons *lru.Cache // thread-safe
+	filters       *rpchelper.Filters
+	_chainConfig  *params.ChainConfig
+	_genesis      *types.Block
+	_genesisLock  sync.RWMutex
 
 	_historyV3     *bool
 	_historyV3Lock sync.RWMutex
@@ -111,6 +120,9 @@ type BaseAPI struct {
 	_agg         *libstate.Aggregator22
 
 	evmCallTimeout time.Duration
+
+	nonCanonicalTxs *nonCanonicalTxIndex // nil if transactions of non-canonical blocks aren't looked up
+	recentStates    *state.RecentStates  // nil if rpcdaemon isn't embedded or the state changes aren't kept
 }
 
 func NewBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader services.FullBlockReader, agg *libstate.Aggregator22, singleNodeMode bool, evmCallTimeout time.Duration) *BaseAPI {
@@ -122,8 +134,53 @@ func NewBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader serv
 	if err != nil {
 		panic(err)
 	}
+	revertReasons, err := lru.New(1024)
+	if err != nil {
+		panic(err)
+	}
 
-	return &BaseAPI{filters: f, stateCache: stateCache, blocksLRU: blocksLRU, _blockReader: blockReader, _txnReader: blockReader, _agg: agg, evmCallTimeout: evmCallTimeout}
+	return &BaseAPI{filters: f, stateCache: stateCache, blocksLRU: blocksLRU, revertReasons: revertReasons, _blockReader: blockReader, _txnReader: blockReader, _agg: agg, evmCallTimeout: evmCallTimeout}
+}
+
+// watchInvalidations drops cached chain config and blocks when they are changed by committed write
+// transactions. It's possible only if the database is in the same process, see kvwatch.DB.
+func (api *BaseAPI) watchInvalidations(db kv.RoDB) {
+	watcher, ok := db.(kvwatch.Watcher)
+	if !ok {
+		return
+	}
+	config := watcher.Watch(kv.ConfigTable, nil)
+	bodies := watcher.Watch(kv.BlockBody, nil)
+	go func() {
+		defer config.Close()
+		defer bodies.Close()
+		for {
+			select {
+			case _, ok := <-config.Notify():
+				if !ok {
+					return
+				}
+				config.Changes()
+				api._genesisLock.Lock()
+				api._chainConfig, api._genesis = nil, nil
+				api._genesisLock.Unlock()
+			case _, ok := <-bodies.Notify():
+				if !ok {
+					return
+				}
+				keys, overflow := bodies.Changes()
+				if overflow {
+					api.blocksLRU.Purge()
+					continue
+				}
+				for _, k := range keys {
+					if len(k) == 8+common.HashLength {
+						api.blocksLRU.Remove(common.BytesToHash(k[8:]))
+					}
+				}
+			}
+		}
+	}()
 }
 
 func (api *BaseAPI) chainConfig(tx kv.Tx) (*params.ChainConfig, error) {
@@ -264,10 +321,26 @@ type APIImpl struct {
 	mining     txpool.MiningClient
 	db         kv.RoDB
 	GasCap     uint64
+
+	logsMaxRange   uint64 // max amount of blocks scanned by eth_getLogs and eth_getFilterLogs, 0 - unlimited
+	logsMaxResults uint64 // max amount of logs returned by eth_getLogs and eth_getFilterLogs, 0 - unlimited
+
+	// ReceiptsRevertReason adds the decoded revert reason of failed transactions to eth_getTransactionReceipt
+	ReceiptsRevertReason bool
+
+	headCache *headCache         // results of eth_blockNumber, eth_gasPrice, eth_syncing for the current head
+	feeCache  *gasprice.FeeCache // effective tips of transactions of recent blocks for eth_feeHistory
+
+	scheduledTxs *scheduledTxs // nil - scheduled transactions are rejected
+
+	downloader proto_downloader.DownloaderClient // nil - eth_syncing doesn't report the snapshot download
+
+	dirs         datadir.Dirs        // temporary files of eth_getProof of historical blocks
+	proofRewinds *semaphore.Weighted // eth_getProof of historical blocks unwinding the state trie at once
 }
 
 // NewEthAPI returns APIImpl instance
-func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, gascap uint64) *APIImpl {
+func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, gascap uint64, logsMaxRange, logsMaxResults uint64) *APIImpl {
 	if gascap == 0 {
 		gascap = uint64(math.MaxUint64 / 2)
 	}
@@ -279,6 +352,14 @@ func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpoo
 		txPool:     txPool,
 		mining:     mining,
 		GasCap:     gascap,
+
+		logsMaxRange:   logsMaxRange,
+		logsMaxResults: logsMaxResults,
+
+		headCache: newHeadCache(base.filters),
+		feeCache:  gasprice.NewFeeCache(0),
+
+		proofRewinds: semaphore.NewWeighted(maxGetProofRewinds),
 	}
 }
 
@@ -89,7 +89,7 @@ func (api *APIImpl) CallBundle(ctx context.Context, txHashes []common.Hash, stat
 
 	parent := rawdb.ReadHeader(tx, hash, stateBlockNumber)
 	if parent == nil {
-		return nil, fmt.Errorf("block %d(%x) not found", stateBlockNumber, hash)
+		return nil, rpc.NewNotFoundError("block", hash)
 	}
 
 	blockNumber := stateBlockNumber + 1
@@ -114,7 +114,7 @@ func (api *APIImpl) CallBundle(ctx context.Context, txHashes []common.Hash, stat
 		return nil, err
 	}
 
-	blockCtx, txCtx := transactions.GetEvmContext(firstMsg, header, stateBlockNumberOrHash.RequireCanonical, tx, api._blockReader)
+	blockCtx, txCtx := transactions.GetEvmContext(firstMsg, header, tx, api._blockReader)
 	evm := vm.NewEVM(blockCtx, txCtx, st, chainConfig, vm.Config{Debug: false})
 
 	timeoutMilliSeconds := int64(5000)
@@ -313,18 +313,7 @@ func (api *APIImpl) GetBlockTransactionCountByNumber(ctx context.Context, blockN
 	if err != nil {
 		return nil, err
 	}
-	_, txAmount, err := api._blockReader.Body(ctx, tx, blockHash, blockNum)
-	if err != nil {
-		return nil, err
-	}
-
-	if txAmount == 0 {
-		return nil, nil
-	}
-
-	numOfTx := hexutil.Uint(txAmount)
-
-	return &numOfTx, nil
+	return api.transactionCount(ctx, tx, blockHash, blockNum)
 }
 
 // GetBlockTransactionCountByHash implements eth_getBlockTransactionCountByHash. Returns the number of transactions in a block given the block's block hash.
@@ -340,18 +329,21 @@ func (api *APIImpl) GetBlockTransactionCountByHash(ctx context.Context, blockHas
 		log.Debug("eth_getBlockTransactionCountByHash GetBlockNumber failed", "err", err)
 		return nil, nil
 	}
-	_, txAmount, err := api._blockReader.Body(ctx, tx, blockHash, blockNum)
+	return api.transactionCount(ctx, tx, blockHash, blockNum)
+}
+
+// transactionCount - amount of transactions of the block, nil if the block is unknown. Only the body is read, not
+// the transactions.
+func (api *APIImpl) transactionCount(ctx context.Context, tx kv.Tx, hash common.Hash, number uint64) (*hexutil.Uint, error) {
+	body, txAmount, err := api._blockReader.Body(ctx, tx, hash, number)
 	if err != nil {
 		return nil, err
 	}
-
-	if txAmount == 0 {
+	if body == nil {
 		return nil, nil
 	}
-
-	numOfTx := hexutil.Uint(txAmount)
-
-	return &numOfTx, nil
+	n := hexutil.Uint(txAmount)
+	return &n, nil
 }
 
 func (api *APIImpl) blockByNumber(ctx context.Context, number rpc.BlockNumber, tx kv.Tx) (*types.Block, error) {
@@ -10,6 +10,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/log/v3"
 	"google.golang.org/grpc"
 
@@ -18,14 +19,19 @@ import (
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/eth/tracers/logger"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/erigon/turbo/trie"
 )
 
 // Call implements eth_call. Executes a new message call immediately without creating a transaction on the block chain.
@@ -57,7 +63,7 @@ func (api *APIImpl) Call(ctx context.Context, args ethapi.CallArgs, blockNrOrHas
 		return nil, nil
 	}
 
-	stateReader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, api.filters, api.stateCache, api.historyV3(tx), api._agg)
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, api.filters, api.stateCache, api.recentStates, api.historyV3(tx), api._agg)
 	if err != nil {
 		return nil, err
 	}
@@ -220,7 +226,7 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi.CallArgs,
 			return false, nil, nil
 		}
 
-		stateReader, err := rpchelper.CreateStateReader(ctx, dbtx, numOrHash, api.filters, api.stateCache, api.historyV3(dbtx), api._agg)
+		stateReader, err := rpchelper.CreateStateReader(ctx, dbtx, numOrHash, api.filters, api.stateCache, api.recentStates, api.historyV3(dbtx), api._agg)
 		if err != nil {
 			return false, nil, err
 		}
@@ -274,10 +280,166 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi.CallArgs,
 	return hexutil.Uint64(hi), nil
 }
 
-// GetProof not implemented
-func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error) {
-	var stub interface{}
-	return &stub, fmt.Errorf(NotImplemented, "eth_getProof")
+const (
+	// maxGetProofRewind - eth_getProof of older blocks unwinds the hashed state and intermediate hashes in memory by the
+	// changes of all blocks after the requested one
+	maxGetProofRewind = 1_000
+	// maxGetProofRewinds - unwinds done at once, other requests of historical blocks wait
+	maxGetProofRewinds = 2
+)
+
+// GetProof implements eth_getProof. Returns the account and its storage slots at the block with Merkle proofs of the
+// state trie. The trie is built of the hashed state and the intermediate hashes, for blocks before the latest one
+// they are unwound in memory first.
+func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNr, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, rpc.NewNotFoundError("header of block", blockNr)
+	}
+	latest, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, err
+	}
+	if blockNr > latest {
+		return nil, fmt.Errorf("block %d is not in the state trie yet, it's at block %d", blockNr, latest)
+	}
+
+	var stateTx kv.Tx = tx
+	if blockNr < latest {
+		// the unwound state is kept in memory until the proofs are built
+		if err := api.proofRewinds.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+		defer api.proofRewinds.Release(1)
+		batch, err := api.unwindStateTrie(ctx, tx, blockNr, latest)
+		if err != nil {
+			return nil, err
+		}
+		defer batch.Rollback()
+		stateTx = batch
+	}
+
+	addrHash, err := common.HashData(address[:])
+	if err != nil {
+		return nil, err
+	}
+	rl := trie.NewRetainList(0)
+	rl.AddKey(addrHash[:])
+	enc, err := stateTx.GetOne(kv.HashedAccounts, addrHash[:])
+	if err != nil {
+		return nil, err
+	}
+	var incarnation uint64
+	if len(enc) > 0 {
+		var acc accounts.Account
+		if err := acc.DecodeForStorage(enc); err != nil {
+			return nil, err
+		}
+		incarnation = acc.Incarnation
+	}
+	keyHashes := make([]common.Hash, len(storageKeys))
+	for i, key := range storageKeys {
+		if keyHashes[i], err = common.HashData(common.HexToHash(key).Bytes()); err != nil {
+			return nil, err
+		}
+		if incarnation > 0 {
+			rl.AddKey(trie.StorageKey(addrHash[:], incarnation, keyHashes[i][:]))
+		}
+	}
+
+	tr, err := trie.NewFlatDBTrieLoader("eth_getProof").CalcProofTrie(stateTx, rl, ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if root := tr.Hash(); root != header.Root {
+		return nil, fmt.Errorf("wrong trie root of block %d: %x, expected (from header): %x", blockNr, root, header.Root)
+	}
+
+	accountProof, err := tr.Prove(addrHash[:], 0, false)
+	if err != nil {
+		return nil, err
+	}
+	result := &ethapi.AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		CodeHash:     common.BytesToHash(trie.EmptyCodeHash[:]),
+		StorageHash:  trie.EmptyRoot,
+		StorageProof: make([]ethapi.StorageResult, len(storageKeys)),
+	}
+	if acc, _ := tr.GetAccount(addrHash[:]); acc != nil {
+		result.Balance = (*hexutil.Big)(acc.Balance.ToBig())
+		result.CodeHash = acc.CodeHash
+		result.Nonce = hexutil.Uint64(acc.Nonce)
+		result.StorageHash = acc.Root
+	}
+	for i, key := range storageKeys {
+		storageKey := append(common.CopyBytes(addrHash[:]), keyHashes[i][:]...)
+		proof, err := tr.Prove(storageKey, 2*common.HashLength, true)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := tr.Get(storageKey)
+		if !ok {
+			return nil, fmt.Errorf("storage key %s of %x is not resolved in the trie", key, address)
+		}
+		result.StorageProof[i] = ethapi.StorageResult{Key: key, Value: (*hexutil.Big)(new(big.Int).SetBytes(v)), Proof: toHexSlice(proof)}
+	}
+	return result, nil
+}
+
+// unwindStateTrie - the hashed state and the intermediate hashes of the block before latest, in memory on top of tx.
+// The unwind of intermediate hashes checks the root against the header of the block.
+func (api *APIImpl) unwindStateTrie(ctx context.Context, tx kv.Tx, blockNr, latest uint64) (*memdb.MemoryMutation, error) {
+	if api.historyV3(tx) {
+		return nil, errors.New("eth_getProof of blocks before the latest one is not supported with history v3")
+	}
+	if latest-blockNr > maxGetProofRewind {
+		return nil, fmt.Errorf("block %d is too old for eth_getProof, it must be within %d blocks of the latest block %d", blockNr, maxGetProofRewind, latest)
+	}
+	pm, err := prune2.Get(tx)
+	if err != nil {
+		return nil, err
+	}
+	// changes of blocks after blockNr are unwound
+	if pruneTo := pm.History.PruneTo(latest); pm.History.Enabled() && blockNr+1 < pruneTo {
+		return nil, &rpc.PrunedError{Resource: "history", Block: blockNr, AvailableFrom: pruneTo - 1}
+	}
+
+	batch := memdb.NewMemoryBatch(tx, api.dirs.Tmp)
+	hashState := &stagedsync.StageState{ID: stages.HashState, BlockNumber: latest}
+	if err := stagedsync.UnwindHashStateStage(&stagedsync.UnwindState{ID: stages.HashState, UnwindPoint: blockNr}, hashState, batch, stagedsync.StageHashStateCfg(nil, api.dirs, false, nil), ctx); err != nil {
+		batch.Rollback()
+		return nil, err
+	}
+	trieCfg := stagedsync.StageTrieCfg(nil, true, true, false, api.dirs, api._blockReader, nil, false, nil)
+	intermediateHashes := &stagedsync.StageState{ID: stages.IntermediateHashes, BlockNumber: latest}
+	if err := stagedsync.UnwindIntermediateHashesStage(&stagedsync.UnwindState{ID: stages.IntermediateHashes, UnwindPoint: blockNr}, intermediateHashes, batch, trieCfg, ctx); err != nil {
+		batch.Rollback()
+		return nil, err
+	}
+	return batch, nil
+}
+
+func toHexSlice(b [][]byte) []string {
+	output := make([]string, len(b))
+	for i := range b {
+		output[i] = hexutil.Encode(b[i])
+	}
+	return output
 }
 
 // accessListResult returns an optional accesslist
@@ -327,6 +489,9 @@ func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi.CallArgs,
 		}
 		stateReader = state.NewCachedReader2(cacheView, tx)
 	} else {
+		if err = rpchelper.CheckHistoryNotPruned(tx, blockNumber); err != nil {
+			return nil, err
+		}
 		stateReader = state.NewPlainState(tx, blockNumber+1)
 	}
 
@@ -395,11 +560,11 @@ func (api *APIImpl) CreateAccessList(ctx context.Context, args ethapi.CallArgs,
 		// Apply the transaction with the access list tracer
 		tracer := logger.NewAccessListTracer(accessList, *args.From, to, precompiles)
 		config := vm.Config{Tracer: tracer, Debug: true, NoBaseFee: true}
-		blockCtx, txCtx := transactions.GetEvmContext(msg, header, bNrOrHash.RequireCanonical, tx, api._blockReader)
+		blockCtx, txCtx := transactions.GetEvmContext(msg, header, tx, api._blockReader)
 
 		evm := vm.NewEVM(blockCtx, txCtx, state, chainConfig, config)
 		gp := new(core.GasPool).AddGas(msg.Gas())
-		res, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+		res, err := transactions.ApplyMessage(ctx, evm, msg, gp, true /* refunds */, false /* gasBailout */)
 		if err != nil {
 			return nil, err
 		}
@@ -130,7 +130,7 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 
 	replayTransactions = block.Transactions()[:transactionIndex]
 
-	stateReader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum-1)), api.filters, api.stateCache, api.historyV3(tx), api._agg)
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum-1)), api.filters, api.stateCache, api.recentStates, api.historyV3(tx), api._agg)
 
 	if err != nil {
 		return nil, err
@@ -141,7 +141,7 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 	parent := block.Header()
 
 	if parent == nil {
-		return nil, fmt.Errorf("block %d(%x) not found", blockNum, hash)
+		return nil, rpc.NewNotFoundError("block", hash)
 	}
 
 	// Get a new instance of the EVM
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/types"
@@ -118,6 +119,29 @@ func (api *APIImpl) GetFilterChanges(_ context.Context, index string) ([]interfa
 	return stub, nil
 }
 
+// GetFilterLogs implements eth_getFilterLogs. Returns an array of all logs matching the criteria of the
+// log filter with the given id. It shares the implementation and the limits with eth_getLogs.
+func (api *APIImpl) GetFilterLogs(ctx context.Context, index string) (types.Logs, error) {
+	if api.filters == nil {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	id, err := hexutil.DecodeUint64(index)
+	if err != nil {
+		return nil, rpc.NewNotFoundError("filter", index)
+	}
+	crit, ok := api.filters.LogsFilterCriteria(rpchelper.LogsSubID(id))
+	if !ok {
+		return nil, rpc.NewNotFoundError("filter", index)
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return api.getLogs(ctx, tx, crit)
+}
+
 // NewHeads send a notification each time a new (header) block is appended to the chain.
 func (api *APIImpl) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 	if api.filters == nil {
@@ -245,7 +269,8 @@ func (api *APIImpl) NewPendingTransactionsWithBody(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
-// Logs send a notification each time a new log appears.
+// Logs send a notification each time a new log appears. If fromBlock of the criteria is a block
+// number, logs of the already executed blocks starting from it are sent first.
 func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc.Subscription, error) {
 	if api.filters == nil {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -262,12 +287,59 @@ func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc
 		logs := make(chan *types.Log, 1)
 		id := api.filters.SubscribeLogs(logs, crit)
 		defer api.filters.UnsubscribeLogs(id)
+
+		// fromBlock in the past: logs of blocks up to the latest executed one are replayed first.
+		// Live logs are subscribed before the replay starts, so none is missed - they are queued
+		// until the replay is done, and the ones of already replayed blocks are dropped.
+		// A failed replay closes the subscription: sending the live logs would leave a gap after the replayed blocks.
+		var history chan *types.Log
+		var replayedTo uint64
+		var replayErr error
+		var queued []*types.Log
+		if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 {
+			replayCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			history = make(chan *types.Log)
+			go func() {
+				defer debug.LogPanic()
+				defer close(history)
+				replayedTo, replayErr = api.replayLogs(replayCtx, crit, history)
+			}()
+		}
 		for {
 			select {
+			case h, ok := <-history:
+				if !ok {
+					if replayErr != nil {
+						log.Warn("[rpc] logs replay failed, closing subscription", "from", crit.FromBlock, "replayed_to", replayedTo, "err", replayErr)
+						return
+					}
+					history = nil
+					for _, q := range queued {
+						if q.BlockNumber <= replayedTo && !q.Removed {
+							continue
+						}
+						if err := notifier.Notify(rpcSub.ID, q); err != nil {
+							log.Warn("error while notifying subscription", "err", err)
+							return
+						}
+					}
+					queued = nil
+					continue
+				}
+				if err := notifier.Notify(rpcSub.ID, h); err != nil {
+					log.Warn("error while notifying subscription", "err", err)
+					return
+				}
 			case h, ok := <-logs:
 				if h != nil {
-					err := notifier.Notify(rpcSub.ID, h)
-					if err != nil {
+					if history != nil {
+						if len(queued) >= maxQueuedLogs {
+							log.Warn("[rpc] too many live logs during replay, closing subscription", "limit", maxQueuedLogs)
+							return
+						}
+						queued = append(queued, h)
+					} else if err := notifier.Notify(rpcSub.ID, h); err != nil {
 						log.Warn("error while notifying subscription", "err", err)
 						return
 					}
@@ -284,3 +356,56 @@ func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc
 
 	return rpcSub, nil
 }
+
+const (
+	logsReplayChunkSize = 1024    // blocks read in one transaction by the logs replay
+	maxQueuedLogs       = 100_000 // live logs kept while the replay of a logs subscription is in progress
+)
+
+// replayLogs sends logs matching the criteria (addresses and topics, toBlock is ignored) of blocks
+// from crit.FromBlock up to the latest executed block to out, until it catches up with the chain.
+// Blocks are read in small chunks, each in its own transaction, and the next chunk is read only
+// after the previous one is consumed - a slow subscriber holds neither memory nor the database.
+// Returns the last replayed block.
+func (api *APIImpl) replayLogs(ctx context.Context, crit filters.FilterCriteria, out chan<- *types.Log) (replayedTo uint64, err error) {
+	begin := crit.FromBlock.Uint64()
+	if begin > 0 {
+		replayedTo = begin - 1
+	}
+	for {
+		var logs types.Logs
+		var end uint64
+		if err = api.db.View(ctx, func(tx kv.Tx) error {
+			latest, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(rpc.LatestExecutedBlockNumber), tx, nil)
+			if err != nil {
+				return err
+			}
+			if begin > latest {
+				return nil
+			}
+			end = begin + logsReplayChunkSize - 1
+			if end > latest {
+				end = latest
+			}
+			if api.historyV3(tx) {
+				logs, err = api.getLogsV3(ctx, tx, begin, end, crit)
+			} else {
+				logs, err = api.appendIndexedLogs(ctx, tx, nil, begin, end, crit, nil)
+			}
+			return err
+		}); err != nil {
+			return replayedTo, err
+		}
+		if end < begin { // caught up
+			return replayedTo, nil
+		}
+		for _, l := range logs {
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return replayedTo, ctx.Err()
+			}
+		}
+		replayedTo, begin = end, end+1
+	}
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"math/big"
 	"math/rand"
 	"sync"
 	"testing"
@@ -29,7 +30,7 @@ func TestNewFilters(t *testing.T) {
 	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
 	mining := txpool.NewMiningClient(conn)
 	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
-	api := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB, nil, nil, nil, 5000000)
+	api := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB, nil, nil, nil, 5000000, 0, 0)
 
 	ptf, err := api.NewPendingTransactionFilter(ctx)
 	assert.Nil(err)
@@ -99,3 +100,73 @@ func TestLogsSubscribeAndUnsubscribe_WithoutConcurrentMapIssue(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestGetFilterLogs(t *testing.T) {
+	assert := assert.New(t)
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
+	mining := txpool.NewMiningClient(conn)
+	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
+	api := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB, nil, nil, nil, 5000000, 0, 0)
+
+	crit := filters.FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(10)}
+	id, err := api.NewFilter(ctx, crit)
+	assert.NoError(err)
+
+	expected, err := api.GetLogs(ctx, crit)
+	assert.NoError(err)
+	logs, err := api.GetFilterLogs(ctx, id)
+	assert.NoError(err)
+	assert.Equal(len(expected), len(logs))
+
+	_, err = api.GetFilterLogs(ctx, "0xdeadbeef")
+	assert.Error(err)
+
+	limited := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB, nil, nil, nil, 5000000, 5, 0)
+	_, err = limited.GetFilterLogs(ctx, id)
+	assert.Error(err, "block range limit must apply to filter logs")
+}
+
+func TestReplayLogs(t *testing.T) {
+	assert := assert.New(t)
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
+	mining := txpool.NewMiningClient(conn)
+	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
+	api := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB, nil, nil, nil, 5000000, 0, 0)
+	limited := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB, nil, nil, nil, 5000000, 0, 1)
+
+	expected, err := api.GetLogs(ctx, filters.FilterCriteria{FromBlock: big.NewInt(1)})
+	assert.NoError(err)
+	assert.NotEmpty(expected)
+
+	// replay is not limited by rpc.logs.maxresults and streams logs in order of blocks
+	out := make(chan *types.Log)
+	var replayed []*types.Log
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for l := range out {
+			replayed = append(replayed, l)
+		}
+	}()
+	replayedTo, err := limited.replayLogs(ctx, filters.FilterCriteria{FromBlock: big.NewInt(1)}, out)
+	close(out)
+	<-done
+	assert.NoError(err)
+	assert.Equal(len(expected), len(replayed))
+	for i := range expected {
+		assert.Equal(expected[i].BlockNumber, replayed[i].BlockNumber)
+		assert.Equal(expected[i].Index, replayed[i].Index)
+	}
+	assert.GreaterOrEqual(replayedTo, expected[len(expected)-1].BlockNumber)
+
+	// fromBlock in the future - nothing is replayed
+	replayedTo, err = api.replayLogs(ctx, filters.FilterCriteria{FromBlock: big.NewInt(1000)}, out)
+	assert.NoError(err)
+	assert.Equal(uint64(999), replayedTo)
+}
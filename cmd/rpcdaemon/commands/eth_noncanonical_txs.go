@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"encoding/binary"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// nonCanonicalTxBlocks - number of non-canonical blocks whose transaction hashes are kept in memory
+const nonCanonicalTxBlocks = 1024
+
+// nonCanonicalTxIndex finds transactions of recent non-canonical blocks. Bodies of such blocks stay in the db after
+// reorgs, but TxLookup has only canonical transactions. The index of their transactions is rebuilt only when the
+// chain changes: a new header or a new head, lookups in between don't touch the db. Hashes of transactions of a block
+// are computed once - body of a block hash never changes.
+type nonCanonicalTxIndex struct {
+	window uint64     // How many blocks below the highest header are looked at
+	blocks *lru.Cache // block hash -> []common.Hash of its transactions
+
+	lock    sync.Mutex
+	version nonCanonicalTxIndexVersion
+	txs     map[common.Hash]nonCanonicalTxLocation
+}
+
+// nonCanonicalTxIndexVersion - state of the chain the index is built for
+type nonCanonicalTxIndexVersion struct {
+	lastHeader   string      // the last key of kv.Headers
+	head         common.Hash // hash of the head header
+	topCanonical common.Hash // canonical hash at the height of the last header
+}
+
+type nonCanonicalTxLocation struct {
+	blockHash common.Hash
+	blockNum  uint64
+}
+
+func newNonCanonicalTxIndex(window uint64) *nonCanonicalTxIndex {
+	blocks, err := lru.New(nonCanonicalTxBlocks)
+	if err != nil {
+		panic(err)
+	}
+	return &nonCanonicalTxIndex{window: window, blocks: blocks}
+}
+
+// find returns the non-canonical block which includes the transaction, ok == false if there is none within the window
+func (idx *nonCanonicalTxIndex) find(tx kv.Tx, txnHash common.Hash) (blockHash common.Hash, blockNum uint64, ok bool, err error) {
+	c, err := tx.Cursor(kv.Headers)
+	if err != nil {
+		return common.Hash{}, 0, false, err
+	}
+	defer c.Close()
+	last, _, err := c.Last()
+	if err != nil {
+		return common.Hash{}, 0, false, err
+	}
+	version := nonCanonicalTxIndexVersion{lastHeader: string(last), head: rawdb.ReadHeadHeaderHash(tx)}
+	if len(last) >= 8 {
+		if version.topCanonical, err = rawdb.ReadCanonicalHash(tx, binary.BigEndian.Uint64(last)); err != nil {
+			return common.Hash{}, 0, false, err
+		}
+	}
+
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if idx.txs == nil || idx.version != version {
+		if idx.txs, err = idx.build(tx, c); err != nil {
+			idx.txs = nil
+			return common.Hash{}, 0, false, err
+		}
+		idx.version = version
+	}
+	location, ok := idx.txs[txnHash]
+	return location.blockHash, location.blockNum, ok, nil
+}
+
+// build indexes transactions of non-canonical blocks within the window below the highest header
+func (idx *nonCanonicalTxIndex) build(tx kv.Tx, c kv.Cursor) (map[common.Hash]nonCanonicalTxLocation, error) {
+	txs := map[common.Hash]nonCanonicalTxLocation{}
+	var top uint64
+	canonicalNum, canonical := ^uint64(0), common.Hash{}
+	for k, _, err := c.Last(); k != nil; k, _, err = c.Prev() {
+		if err != nil {
+			return nil, err
+		}
+		num := binary.BigEndian.Uint64(k)
+		if top == 0 {
+			top = num
+		}
+		if num+idx.window < top {
+			break
+		}
+		if canonicalNum != num {
+			if canonical, err = rawdb.ReadCanonicalHash(tx, num); err != nil {
+				return nil, err
+			}
+			canonicalNum = num
+		}
+		hash := common.BytesToHash(k[8:])
+		if hash == canonical {
+			continue
+		}
+		for _, h := range idx.txHashes(tx, hash, num) {
+			txs[h] = nonCanonicalTxLocation{blockHash: hash, blockNum: num}
+		}
+	}
+	return txs, nil
+}
+
+func (idx *nonCanonicalTxIndex) txHashes(tx kv.Tx, hash common.Hash, num uint64) []common.Hash {
+	if cached, ok := idx.blocks.Get(hash); ok {
+		return cached.([]common.Hash)
+	}
+	body := rawdb.NonCanonicalBodyWithTransactions(tx, hash, num)
+	if body == nil { // body is not downloaded yet, don't cache
+		return nil
+	}
+	hashes := make([]common.Hash, len(body.Transactions))
+	for i, txn := range body.Transactions {
+		hashes[i] = txn.Hash()
+	}
+	idx.blocks.Add(hash, hashes)
+	return hashes
+}
+
+// nonCanonicalTxError - rpc.NonCanonicalTxError if the transaction unknown to TxLookup is included in a recent
+// non-canonical block, nil if there is no such block or the lookup is disabled
+func (api *BaseAPI) nonCanonicalTxError(tx kv.Tx, txnHash common.Hash) error {
+	if api.nonCanonicalTxs == nil {
+		return nil
+	}
+	blockHash, blockNum, ok, err := api.nonCanonicalTxs.find(tx, txnHash)
+	if err != nil || !ok {
+		return err
+	}
+	return &rpc.NonCanonicalTxError{TxHash: txnHash, BlockHash: blockHash, BlockNumber: blockNum}
+}
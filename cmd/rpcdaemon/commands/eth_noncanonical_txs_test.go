@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNonCanonicalTxIndex(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+	logEvery := time.NewTicker(time.Minute)
+	defer logEvery.Stop()
+
+	txns := map[uint64]types.Transaction{}
+	writeBlock := func(number uint64, extra []byte) common.Hash {
+		txn := types.NewTransaction(number, common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), extra)
+		h := &types.Header{Number: new(big.Int).SetUint64(number), Extra: extra}
+		rawdb.WriteHeader(tx, h)
+		require.NoError(rawdb.WriteBody(tx, h.Hash(), number, &types.Body{Transactions: []types.Transaction{txn}}))
+		require.NoError(rawdb.WriteCanonicalHash(tx, h.Hash(), number))
+		txns[number] = txn
+		return h.Hash()
+	}
+	var reorged [11]common.Hash
+	for i := uint64(1); i <= 10; i++ {
+		reorged[i] = writeBlock(i, nil)
+	}
+	idx := newNonCanonicalTxIndex(1)
+	_, _, ok, err := idx.find(tx, txns[10].Hash())
+	require.NoError(err)
+	require.False(ok)
+
+	// blocks 9 and 10 are reorged away: the index is rebuilt for the new chain
+	reorgedTxns := []types.Transaction{txns[9], txns[10]}
+	require.NoError(rawdb.MakeBodiesNonCanonical(tx, 9, false, context.Background(), "test", logEvery))
+	writeBlock(9, []byte{1})
+	writeBlock(10, []byte{1})
+
+	for i, txn := range reorgedTxns {
+		number := uint64(9 + i)
+		blockHash, blockNum, ok, err := idx.find(tx, txn.Hash())
+		require.NoError(err)
+		require.True(ok)
+		require.Equal(number, blockNum)
+		require.Equal(reorged[number], blockHash)
+	}
+	for _, txn := range []types.Transaction{txns[5], txns[10]} { // canonical ones aren't reported
+		_, _, ok, err := idx.find(tx, txn.Hash())
+		require.NoError(err)
+		require.False(ok)
+	}
+
+	// outside of the window
+	_, _, ok, err = newNonCanonicalTxIndex(0).find(tx, reorgedTxns[0].Hash())
+	require.NoError(err)
+	require.False(ok)
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
-	"github.com/ledgerwatch/log/v3"
 
 	"github.com/RoaringBitmap/roaring"
 	"github.com/ledgerwatch/erigon/common"
@@ -27,24 +26,41 @@ import (
 	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
 	"github.com/ledgerwatch/erigon/ethdb/cbor"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/log/v3"
 )
 
 func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, senders []common.Address) (types.Receipts, error) {
-	if cached := rawdb.ReadReceipts(tx, block, senders); cached != nil {
-		return cached, nil
-	}
+	return api.getFirstReceipts(ctx, tx, chainConfig, block, senders, len(block.Transactions()))
+}
 
-	getHeader := func(hash common.Hash, number uint64) *types.Header {
-		h, e := api._blockReader.Header(ctx, tx, hash, number)
-		if e != nil {
-			log.Error("getHeader error", "number", number, "hash", hash, "err", e)
+// getFirstReceipts - receipts of the first n transactions of the block, without receipts in the DB the rest of the block
+// isn't executed. Receipts in the DB are of the canonical block of the height, non-canonical blocks (uncles, reorged
+// blocks) are executed on top of the state after their parent, which is there only if the parent is canonical.
+func (api *BaseAPI) getFirstReceipts(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, senders []common.Address, n int) (types.Receipts, error) {
+	canonicalHash, err := rawdb.ReadCanonicalHash(tx, block.NumberU64())
+	if err != nil {
+		return nil, err
+	}
+	if canonicalHash == block.Hash() {
+		if cached := rawdb.ReadReceipts(tx, block, senders); cached != nil {
+			return cached[:n], nil
+		}
+	} else if block.NumberU64() > 0 {
+		parentHash, err := rawdb.ReadCanonicalHash(tx, block.NumberU64()-1)
+		if err != nil {
+			return nil, err
+		}
+		if parentHash != block.ParentHash() {
+			return nil, fmt.Errorf("receipts of block %d %x can't be computed: the state after its non-canonical parent %x isn't kept", block.NumberU64(), block.Hash(), block.ParentHash())
 		}
-		return h
 	}
-	_, _, _, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, ethash.NewFaker(), tx, block.Hash(), 0)
+
+	blockHashes := core.NewCanonicalBlockHashes(ctx, tx, api._blockReader)
+	_, _, _, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, blockHashes, ethash.NewFaker(), tx, block.Hash(), 0)
 	if err != nil {
 		return nil, err
 	}
@@ -55,12 +71,12 @@ func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *para
 	ethashFaker := ethash.NewFaker()
 	noopWriter := state.NewNoopWriter()
 
-	receipts := make(types.Receipts, len(block.Transactions()))
+	receipts := make(types.Receipts, n)
 
-	for i, txn := range block.Transactions() {
+	for i, txn := range block.Transactions()[:n] {
 		ibs.Prepare(txn.Hash(), block.Hash(), i)
 		header := block.Header()
-		receipt, _, err := core.ApplyTransaction(chainConfig, core.GetHashFn(header, getHeader), ethashFaker, nil, gp, ibs, noopWriter, header, txn, usedGas, vm.Config{})
+		receipt, _, err := core.ApplyTransaction(chainConfig, core.BlockHashFn(header, blockHashes), ethashFaker, nil, gp, ibs, noopWriter, header, txn, usedGas, vm.Config{})
 		if err != nil {
 			return nil, err
 		}
@@ -71,69 +87,122 @@ func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *para
 	return receipts, nil
 }
 
+// logsChunkSize is the amount of blocks whose log indices are intersected at once,
+// it bounds the size of the bitmaps loaded for wide block ranges.
+const logsChunkSize = 1 << 16
+
 // GetLogs implements eth_getLogs. Returns an array of logs matching a given filter object.
 func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (types.Logs, error) {
-	var begin, end uint64
-	logs := types.Logs{}
-
 	tx, beginErr := api.db.BeginRo(ctx)
 	if beginErr != nil {
-		return logs, beginErr
+		return types.Logs{}, beginErr
 	}
 	defer tx.Rollback()
 
-	if crit.BlockHash != nil {
-		header, err := api._blockReader.HeaderByHash(ctx, tx, *crit.BlockHash)
+	return api.getLogs(ctx, tx, crit)
+}
+
+// getLogs is the implementation shared by eth_getLogs and eth_getFilterLogs. It resolves the
+// block range of the criteria, applies the configured limits and collects matching logs using
+// the log indices.
+func (api *APIImpl) getLogs(ctx context.Context, tx kv.Tx, crit filters.FilterCriteria) (types.Logs, error) {
+	begin, end, err := api.logsBlockRange(ctx, tx, crit)
+	if err != nil {
+		return nil, err
+	}
+	if api.logsMaxRange > 0 && end-begin+1 > api.logsMaxRange {
+		return nil, &rpc.LimitExceededError{Message: fmt.Sprintf("block range %d..%d exceeds the limit of %d blocks", begin, end, api.logsMaxRange), Limit: api.logsMaxRange}
+	}
+
+	if api.historyV3(tx) {
+		logs, err := api.getLogsV3(ctx, tx, begin, end, crit)
 		if err != nil {
 			return nil, err
 		}
-		if header == nil {
-			return nil, fmt.Errorf("block not found: %x", *crit.BlockHash)
+		if err = api.checkLogsResults(len(logs)); err != nil {
+			return nil, err
 		}
-		begin = header.Number.Uint64()
-		end = header.Number.Uint64()
-	} else {
-		// Convert the RPC block numbers into internal representations
-		latest, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(rpc.LatestExecutedBlockNumber), tx, nil)
-		if err != nil {
+		return logs, nil
+	}
+
+	logs := types.Logs{}
+	for chunkBegin := begin; chunkBegin <= end; chunkBegin += logsChunkSize {
+		chunkEnd := chunkBegin + logsChunkSize - 1
+		if chunkEnd > end {
+			chunkEnd = end
+		}
+		if logs, err = api.appendIndexedLogs(ctx, tx, logs, chunkBegin, chunkEnd, crit, api.checkLogsResults); err != nil {
 			return nil, err
 		}
+	}
+	return logs, nil
+}
 
-		begin = latest
-		if crit.FromBlock != nil {
-			if crit.FromBlock.Sign() >= 0 {
-				begin = crit.FromBlock.Uint64()
-			} else if !crit.FromBlock.IsInt64() || crit.FromBlock.Int64() != int64(rpc.LatestBlockNumber) {
-				return nil, fmt.Errorf("negative value for FromBlock: %v", crit.FromBlock)
-			}
+// logsBlockRange converts the block hash or the block numbers of the criteria into an
+// inclusive range of block numbers.
+func (api *APIImpl) logsBlockRange(ctx context.Context, tx kv.Tx, crit filters.FilterCriteria) (begin, end uint64, err error) {
+	if crit.BlockHash != nil {
+		header, err := api._blockReader.HeaderByHash(ctx, tx, *crit.BlockHash)
+		if err != nil {
+			return 0, 0, err
 		}
-		end = latest
-		if crit.ToBlock != nil {
-			if crit.ToBlock.Sign() >= 0 {
-				end = crit.ToBlock.Uint64()
-			} else if !crit.ToBlock.IsInt64() || crit.ToBlock.Int64() != int64(rpc.LatestBlockNumber) {
-				return nil, fmt.Errorf("negative value for ToBlock: %v", crit.ToBlock)
-			}
+		if header == nil {
+			return 0, 0, rpc.NewNotFoundError("block", *crit.BlockHash)
+		}
+		return header.Number.Uint64(), header.Number.Uint64(), nil
+	}
+	// Convert the RPC block numbers into internal representations
+	latest, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(rpc.LatestExecutedBlockNumber), tx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	begin = latest
+	if crit.FromBlock != nil {
+		if crit.FromBlock.Sign() >= 0 {
+			begin = crit.FromBlock.Uint64()
+		} else if !crit.FromBlock.IsInt64() || crit.FromBlock.Int64() != int64(rpc.LatestBlockNumber) {
+			return 0, 0, fmt.Errorf("negative value for FromBlock: %v", crit.FromBlock)
+		}
+	}
+	end = latest
+	if crit.ToBlock != nil {
+		if crit.ToBlock.Sign() >= 0 {
+			end = crit.ToBlock.Uint64()
+		} else if !crit.ToBlock.IsInt64() || crit.ToBlock.Int64() != int64(rpc.LatestBlockNumber) {
+			return 0, 0, fmt.Errorf("negative value for ToBlock: %v", crit.ToBlock)
 		}
 	}
 	if end < begin {
-		return nil, fmt.Errorf("end (%d) < begin (%d)", end, begin)
+		return 0, 0, fmt.Errorf("end (%d) < begin (%d)", end, begin)
 	}
 	if end > roaring.MaxUint32 {
 		latest, err := rpchelper.GetLatestBlockNumber(tx)
 		if err != nil {
-			return nil, err
+			return 0, 0, err
 		}
 		if begin > latest {
-			return nil, fmt.Errorf("begin (%d) > latest (%d)", begin, latest)
+			return 0, 0, fmt.Errorf("begin (%d) > latest (%d)", begin, latest)
 		}
 		end = latest
 	}
+	if err = rpchelper.CheckReorgInProgress(tx, end); err != nil {
+		return 0, 0, err
+	}
+	return begin, end, nil
+}
 
-	if api.historyV3(tx) {
-		return api.getLogsV3(ctx, tx, begin, end, crit)
+func (api *APIImpl) checkLogsResults(n int) error {
+	if api.logsMaxResults > 0 && uint64(n) > api.logsMaxResults {
+		return &rpc.LimitExceededError{Message: fmt.Sprintf("query returned more than %d results", api.logsMaxResults), Limit: api.logsMaxResults}
 	}
+	return nil
+}
 
+// appendIndexedLogs appends logs of blocks [begin, end] matching the criteria to logs,
+// only blocks present in the address and topic indices are read. checkResults (if not nil)
+// is called with the amount of collected logs after each block.
+func (api *APIImpl) appendIndexedLogs(ctx context.Context, tx kv.Tx, logs types.Logs, begin, end uint64, crit filters.FilterCriteria, checkResults func(n int) error) (types.Logs, error) {
 	blockNumbers := bitmapdb.NewBitmap()
 	defer bitmapdb.ReturnToPool(blockNumbers)
 	blockNumbers.AddRange(begin, end+1) // [min,max)
@@ -213,7 +282,7 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 			return nil, err
 		}
 		if body == nil {
-			return nil, fmt.Errorf("block not found %d", blockNumber)
+			return nil, rpc.NewNotFoundError("block", blockNumber)
 		}
 		for _, log := range blockLogs {
 			log.BlockNumber = blockNumber
@@ -221,6 +290,11 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 			log.TxHash = body.Transactions[log.TxIndex].Hash()
 		}
 		logs = append(logs, blockLogs...)
+		if checkResults != nil {
+			if err = checkResults(len(logs)); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return logs, nil
@@ -333,6 +407,9 @@ func (api *APIImpl) getLogsV3(ctx context.Context, tx kv.Tx, begin, end uint64,
 		return nil, err
 	}
 	for iter.HasNext() {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
 		txNum := iter.Next()
 		// Find block number
 		ok, blockNum, err := rawdb.TxNums.FindBlockNum(tx, txNum)
@@ -373,7 +450,7 @@ func (api *APIImpl) getLogsV3(ctx context.Context, tx kv.Tx, begin, end uint64,
 		if err != nil {
 			return nil, err
 		}
-		blockCtx, txCtx := transactions.GetEvmContext(msg, lastHeader, true /* requireCanonical */, tx, api._blockReader)
+		blockCtx, txCtx := transactions.GetEvmContext(msg, lastHeader, tx, api._blockReader)
 		stateReader.SetTxNum(txNum - 1)
 		vmConfig := vm.Config{}
 		vmConfig.SkipAnalysis = core.SkipAnalysis(chainConfig, blockNum)
@@ -382,7 +459,7 @@ func (api *APIImpl) getLogsV3(ctx context.Context, tx kv.Tx, begin, end uint64,
 
 		gp := new(core.GasPool).AddGas(msg.Gas())
 		ibs.Prepare(txHash, lastBlockHash, txIndex)
-		_, err = core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+		_, err = transactions.ApplyMessage(ctx, evm, msg, gp, true /* refunds */, false /* gasBailout */)
 		if err != nil {
 			return nil, fmt.Errorf("%w: blockNum=%d, txNum=%d", err, blockNum, txNum)
 		}
@@ -439,8 +516,40 @@ func getTopicsBitmapV3(ac *libstate.Aggregator22Context, tx kv.Tx, topics [][]co
 	return result, nil
 }
 
+// ReceiptOptions - the optional non-standard parameter of eth_getTransactionReceipt
+type ReceiptOptions struct {
+	WithProof bool `json:"withProof"` // include the proofs of the receipt and the transaction against the roots of the header
+}
+
+// InclusionProof - the proof of a receipt or a transaction against the root of the trie of them in the block header
+type InclusionProof struct {
+	Root  common.Hash     `json:"root"`
+	Key   hexutil.Bytes   `json:"key"`   // RLP encoded index of the transaction
+	Proof []hexutil.Bytes `json:"proof"` // RLP encoded trie nodes on the path from the root to the item
+}
+
+func newInclusionProof(list types.DerivableList, index int, root common.Hash) (*InclusionProof, error) {
+	proofRoot, proof, err := types.DeriveShaProof(list, index)
+	if err != nil {
+		return nil, err
+	}
+	if proofRoot != root {
+		return nil, fmt.Errorf("root mismatch: %x, header: %x", proofRoot, root)
+	}
+	key, err := rlp.EncodeToBytes(uint(index))
+	if err != nil {
+		return nil, err
+	}
+	res := &InclusionProof{Root: root, Key: key, Proof: make([]hexutil.Bytes, len(proof))}
+	for i, node := range proof {
+		res.Proof[i] = node
+	}
+	return res, nil
+}
+
 // GetTransactionReceipt implements eth_getTransactionReceipt. Returns the receipt of a transaction given the transaction's hash.
-func (api *APIImpl) GetTransactionReceipt(ctx context.Context, txnHash common.Hash) (map[string]interface{}, error) {
+// With opts.WithProof the receipt has the inclusion proofs of itself and of the transaction, receiptProof and transactionProof.
+func (api *APIImpl) GetTransactionReceipt(ctx context.Context, txnHash common.Hash, opts *ReceiptOptions) (map[string]interface{}, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -461,7 +570,7 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, txnHash common.Ha
 	}
 
 	if !ok && cc.Bor == nil {
-		return nil, nil
+		return nil, api.nonCanonicalTxError(tx, txnHash)
 	}
 
 	// if not ok and cc.Bor != nil then we might have a bor transaction
@@ -471,7 +580,7 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, txnHash common.Ha
 			return nil, err
 		}
 		if blockNumPtr == nil {
-			return nil, nil
+			return nil, api.nonCanonicalTxError(tx, txnHash)
 		}
 
 		blockNum = *blockNumPtr
@@ -510,6 +619,9 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, txnHash common.Ha
 		if borReceipt == nil {
 			return nil, nil
 		}
+		if opts != nil && opts.WithProof {
+			return nil, fmt.Errorf("no inclusion proof of the bor state sync transaction %x", txnHash)
+		}
 		return marshalReceipt(borReceipt, borTx, cc, block, txnHash, false), nil
 	}
 
@@ -520,7 +632,25 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, txnHash common.Ha
 	if len(receipts) <= int(txnIndex) {
 		return nil, fmt.Errorf("block has less receipts than expected: %d <= %d, block: %d", len(receipts), int(txnIndex), blockNum)
 	}
-	return marshalReceipt(receipts[txnIndex], block.Transactions()[txnIndex], cc, block, txnHash, true), nil
+	fields := marshalReceipt(receipts[txnIndex], block.Transactions()[txnIndex], cc, block, txnHash, true)
+	if api.ReceiptsRevertReason && receipts[txnIndex].Status == types.ReceiptStatusFailed {
+		// the receipt is there even if the replay isn't possible, e.g. the state history is pruned
+		if reason, err := api.revertReason(ctx, tx, cc, block, txnIndex); err != nil {
+			log.Debug("Revert reason of the receipt is unavailable", "hash", txnHash, "err", err)
+		} else if reason != nil && reason.Reason != "" {
+			fields["revertReason"] = reason.Reason
+		}
+	}
+	if opts != nil && opts.WithProof {
+		// generated receipts of pre-Byzantium blocks may lack the intermediate state root, the proof fails then
+		if fields["receiptProof"], err = newInclusionProof(receipts, int(txnIndex), block.ReceiptHash()); err != nil {
+			return nil, fmt.Errorf("receipt proof: %w", err)
+		}
+		if fields["transactionProof"], err = newInclusionProof(block.Transactions(), int(txnIndex), block.TxHash()); err != nil {
+			return nil, fmt.Errorf("transaction proof: %w", err)
+		}
+	}
+	return fields, nil
 }
 
 // GetBlockReceipts - receipts for individual block
@@ -3,7 +3,9 @@ package commands
 import (
 	"context"
 	"math/big"
+	"time"
 
+	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
@@ -15,10 +17,15 @@ import (
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/log/v3"
 )
 
 // BlockNumber implements eth_blockNumber. Returns the block number of most recent block.
 func (api *APIImpl) BlockNumber(ctx context.Context) (hexutil.Uint64, error) {
+	if blockNum, ok := api.headCache.getBlockNumber(); ok {
+		return blockNum, nil
+	}
+	gen := api.headCache.gen()
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return 0, err
@@ -28,11 +35,23 @@ func (api *APIImpl) BlockNumber(ctx context.Context) (hexutil.Uint64, error) {
 	if err != nil {
 		return 0, err
 	}
+	// the head is clamped to the unwind point until the stage loop is done, it moves without head notifications
+	_, _, unwinding, err := rawdb.ReadUnwindInProgress(tx)
+	if err != nil {
+		return 0, err
+	}
+	if !unwinding {
+		api.headCache.setBlockNumber(gen, hexutil.Uint64(blockNum))
+	}
 	return hexutil.Uint64(blockNum), nil
 }
 
 // Syncing implements eth_syncing. Returns a data object detailing the status of the sync process or false if not syncing.
 func (api *APIImpl) Syncing(ctx context.Context) (interface{}, error) {
+	if syncing := api.headCache.getSyncing(); syncing != nil {
+		return syncing, nil
+	}
+	gen := api.headCache.gen()
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -49,6 +68,7 @@ func (api *APIImpl) Syncing(ctx context.Context) (interface{}, error) {
 	}
 
 	if currentBlock > 0 && currentBlock >= highestBlock { // Return not syncing if the synchronisation already completed
+		api.headCache.setSyncing(gen, false)
 		return false, nil
 	}
 
@@ -67,15 +87,79 @@ func (api *APIImpl) Syncing(ctx context.Context) (interface{}, error) {
 		stagesMap[i].BlockNumber = hexutil.Uint64(progress)
 	}
 
-	return map[string]interface{}{
+	syncing := map[string]interface{}{
 		"currentBlock": hexutil.Uint64(currentBlock),
 		"highestBlock": hexutil.Uint64(highestBlock),
 		"stages":       stagesMap,
-	}, nil
+		"phase":        SyncPhaseBlocks,
+	}
+	if snapshots := api.snapshotsSyncing(ctx); snapshots != nil {
+		syncing["snapshots"] = snapshots
+		if !snapshots.Completed {
+			syncing["phase"] = SyncPhaseSnapshots
+		}
+	}
+	api.headCache.setSyncing(gen, syncing)
+	return syncing, nil
+}
+
+// Phases of the initial sync in the "phase" field of eth_syncing
+const (
+	SyncPhaseSnapshots = "snapshots" // the bittorrent client downloads snapshots, blocks aren't processed yet
+	SyncPhaseBlocks    = "blocks"    // blocks are downloaded and executed by stages
+)
+
+const snapshotsStatsTimeout = time.Second
+
+// SnapshotsSyncing - progress of the snapshot download reported by the bittorrent client, "snapshots" field of eth_syncing
+type SnapshotsSyncing struct {
+	Completed      bool           `json:"completed"`
+	MetadataReady  hexutil.Uint64 `json:"metadataReady"` // files with resolved torrent metadata, the total size is known when all are
+	FilesTotal     hexutil.Uint64 `json:"filesTotal"`
+	BytesCompleted hexutil.Uint64 `json:"bytesCompleted"`
+	BytesTotal     hexutil.Uint64 `json:"bytesTotal"`
+	Progress       float32        `json:"progress"` // percent
+	Peers          hexutil.Uint64 `json:"peers"`    // unique peers of all torrents
+	Connections    hexutil.Uint64 `json:"connections"`
+	DownloadRate   hexutil.Uint64 `json:"downloadRate"` // bytes/sec
+	UploadRate     hexutil.Uint64 `json:"uploadRate"`   // bytes/sec
+}
+
+// snapshotsSyncing returns nil if the downloader isn't known or doesn't answer
+func (api *APIImpl) snapshotsSyncing(ctx context.Context) *SnapshotsSyncing {
+	if api.downloader == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, snapshotsStatsTimeout)
+	defer cancel()
+	stats, err := api.downloader.Stats(ctx, &proto_downloader.StatsRequest{})
+	if err != nil {
+		log.Debug("Downloader stats are unavailable", "err", err)
+		return nil
+	}
+	return &SnapshotsSyncing{
+		Completed:      stats.Completed,
+		MetadataReady:  hexutil.Uint64(stats.MetadataReady),
+		FilesTotal:     hexutil.Uint64(stats.FilesTotal),
+		BytesCompleted: hexutil.Uint64(stats.BytesCompleted),
+		BytesTotal:     hexutil.Uint64(stats.BytesTotal),
+		Progress:       stats.Progress,
+		Peers:          hexutil.Uint64(stats.PeersUnique),
+		Connections:    hexutil.Uint64(stats.ConnectionsTotal),
+		DownloadRate:   hexutil.Uint64(stats.DownloadRate),
+		UploadRate:     hexutil.Uint64(stats.UploadRate),
+	}
 }
 
 // ChainId implements eth_chainId. Returns the current ethereum chainId.
 func (api *APIImpl) ChainId(ctx context.Context) (hexutil.Uint64, error) {
+	api._genesisLock.RLock()
+	cc := api._chainConfig
+	api._genesisLock.RUnlock()
+	if cc != nil {
+		return hexutil.Uint64(cc.ChainID.Uint64()), nil
+	}
+
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return 0, err
@@ -105,6 +189,10 @@ func (api *APIImpl) ProtocolVersion(ctx context.Context) (hexutil.Uint, error) {
 
 // GasPrice implements eth_gasPrice. Returns the current price per gas in wei.
 func (api *APIImpl) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	if gasPrice := api.headCache.getGasPrice(); gasPrice != nil {
+		return gasPrice, nil
+	}
+	gen := api.headCache.gen()
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -125,6 +213,7 @@ func (api *APIImpl) GasPrice(ctx context.Context) (*hexutil.Big, error) {
 	if head := rawdb.ReadCurrentHeader(tx); head != nil && head.BaseFee != nil {
 		gasResult.Add(tipcap, head.BaseFee)
 	}
+	api.headCache.setGasPrice(gen, (*hexutil.Big)(gasResult))
 	return (*hexutil.Big)(gasResult), err
 }
 
@@ -165,6 +254,7 @@ func (api *APIImpl) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex,
 		return nil, err
 	}
 	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, cc, api.BaseAPI), ethconfig.Defaults.GPO)
+	oracle.SetFeeCache(api.feeCache)
 
 	oldest, reward, baseFee, gasUsed, err := oracle.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
 	if err != nil {
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// syncingMaxAge - progress of the Headers stage is not announced, so cached eth_syncing result
+// is also dropped by age: node which falls behind reports it at most this late
+const syncingMaxAge = time.Second
+
+// blockNumberMaxAge - the unwind marker of the stage loop (rawdb.WriteUnwindInProgress) is not announced either:
+// eth_blockNumber isn't cached while the marker is set, and the value cached before the marker is dropped by age
+const blockNumberMaxAge = time.Second
+
+// headCache keeps results of tiny high-frequency methods (eth_blockNumber, eth_gasPrice, eth_syncing)
+// for the current head - load balancers health-check nodes with them millions of times per day, and
+// they shouldn't open a transaction on every call. Values are computed on the first call after a new head
+// and dropped when the Finish stage notifier announces the next header. Without notifications
+// (no filters, e.g. in some tests) nothing is cached.
+type headCache struct {
+	lock       sync.RWMutex
+	enabled    bool
+	generation uint64 // incremented on each new head - values computed for older head are not stored
+
+	blockNumber    hexutil.Uint64
+	hasBlockNumber bool
+	blockNumberAt  time.Time
+	gasPrice       *hexutil.Big
+	syncing        interface{} // false or syncing progress, nil - not cached
+	syncingAt      time.Time
+}
+
+func newHeadCache(filters *rpchelper.Filters) *headCache {
+	c := &headCache{}
+	if filters == nil {
+		return c
+	}
+	c.enabled = true
+	heads := make(chan *types.Header, 1)
+	id := filters.SubscribeNewHeads(heads)
+	go func() {
+		defer filters.UnsubscribeHeads(id)
+		for {
+			select {
+			case _, ok := <-heads:
+				if !ok {
+					return
+				}
+				c.reset()
+			case <-filters.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *headCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	c.hasBlockNumber, c.gasPrice, c.syncing = false, nil, nil
+}
+
+// gen returns current generation, to be passed to the setter of the value computed after this call
+func (c *headCache) gen() uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.generation
+}
+
+func (c *headCache) getBlockNumber() (hexutil.Uint64, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if !c.hasBlockNumber || time.Since(c.blockNumberAt) > blockNumberMaxAge {
+		return 0, false
+	}
+	return c.blockNumber, true
+}
+
+func (c *headCache) setBlockNumber(gen uint64, blockNumber hexutil.Uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.enabled && gen == c.generation {
+		c.blockNumber, c.hasBlockNumber, c.blockNumberAt = blockNumber, true, time.Now()
+	}
+}
+
+func (c *headCache) getGasPrice() *hexutil.Big {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.gasPrice
+}
+
+func (c *headCache) setGasPrice(gen uint64, gasPrice *hexutil.Big) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.enabled && gen == c.generation {
+		c.gasPrice = gasPrice
+	}
+}
+
+func (c *headCache) getSyncing() interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.syncing == nil || time.Since(c.syncingAt) > syncingMaxAge {
+		return nil
+	}
+	return c.syncing
+}
+
+func (c *headCache) setSyncing(gen uint64, syncing interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.enabled && gen == c.generation {
+		c.syncing, c.syncingAt = syncing, time.Now()
+	}
+}
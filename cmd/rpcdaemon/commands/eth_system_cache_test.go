@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadCache(t *testing.T) {
+	disabled := newHeadCache(nil)
+	disabled.setBlockNumber(disabled.gen(), 10)
+	_, ok := disabled.getBlockNumber()
+	require.False(t, ok, "without head notifications values are not cached")
+
+	c := &headCache{enabled: true}
+	gen := c.gen()
+	c.setBlockNumber(gen, 10)
+	c.setGasPrice(gen, (*hexutil.Big)(big.NewInt(7)))
+	c.setSyncing(gen, false)
+	blockNum, ok := c.getBlockNumber()
+	require.True(t, ok)
+	require.Equal(t, hexutil.Uint64(10), blockNum)
+	require.Equal(t, big.NewInt(7), c.getGasPrice().ToInt())
+	require.Equal(t, false, c.getSyncing())
+
+	// new head drops values, and values computed for the previous head are not stored
+	c.reset()
+	_, ok = c.getBlockNumber()
+	require.False(t, ok)
+	require.Nil(t, c.getGasPrice())
+	require.Nil(t, c.getSyncing())
+	c.setBlockNumber(gen, 10)
+	_, ok = c.getBlockNumber()
+	require.False(t, ok)
+
+	c.setBlockNumber(c.gen(), 11)
+	blockNum, ok = c.getBlockNumber()
+	require.True(t, ok)
+	require.Equal(t, hexutil.Uint64(11), blockNum)
+}
@@ -5,19 +5,27 @@ import (
 	"math"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/holiman/uint256"
+	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
-	"github.com/ledgerwatch/erigon/turbo/stages"
+	stages2 "github.com/ledgerwatch/erigon/turbo/stages"
 )
 
 func TestGasPrice(t *testing.T) {
@@ -45,7 +53,7 @@ func TestGasPrice(t *testing.T) {
 			defer db.Close()
 			stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 			base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, false, rpccfg.DefaultEvmCallTimeout)
-			eth := NewEthAPI(base, db, nil, nil, nil, 5000000)
+			eth := NewEthAPI(base, db, nil, nil, nil, 5000000, 0, 0)
 
 			ctx := context.Background()
 			result, err := eth.GasPrice(ctx)
@@ -71,7 +79,7 @@ func createGasPriceTestKV(t *testing.T, chainSize int) kv.RwDB {
 		}
 		signer = types.LatestSigner(gspec.Config)
 	)
-	m := stages.MockWithGenesis(t, gspec, key, false)
+	m := stages2.MockWithGenesis(t, gspec, key, false)
 
 	// Generate testing blocks
 	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, chainSize, func(i int, b *core.BlockGen) {
@@ -92,3 +100,70 @@ func createGasPriceTestKV(t *testing.T, chainSize int) kv.RwDB {
 
 	return m.DB
 }
+
+type testDownloader struct {
+	proto_downloader.DownloaderClient
+	stats *proto_downloader.StatsReply
+}
+
+func (d *testDownloader) Stats(ctx context.Context, in *proto_downloader.StatsRequest, opts ...grpc.CallOption) (*proto_downloader.StatsReply, error) {
+	return d.stats, nil
+}
+
+func TestSyncingSnapshots(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return stages.SaveStageProgress(tx, stages.Headers, 10)
+	}))
+	api := NewEthAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), nil, false, rpccfg.DefaultEvmCallTimeout), db, nil, nil, nil, 5000000, 0, 0)
+
+	syncing, err := api.Syncing(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, SyncPhaseBlocks, syncing.(map[string]interface{})["phase"])
+	require.NotContains(t, syncing, "snapshots")
+
+	downloader := &testDownloader{stats: &proto_downloader.StatsReply{MetadataReady: 3, FilesTotal: 3, PeersUnique: 7, BytesCompleted: 100, BytesTotal: 400, Progress: 25}}
+	api.downloader = downloader
+	syncing, err = api.Syncing(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, SyncPhaseSnapshots, syncing.(map[string]interface{})["phase"])
+	require.Equal(t, &SnapshotsSyncing{MetadataReady: 3, FilesTotal: 3, Peers: 7, BytesCompleted: 100, BytesTotal: 400, Progress: 25}, syncing.(map[string]interface{})["snapshots"])
+
+	downloader.stats = &proto_downloader.StatsReply{Completed: true, BytesCompleted: 400, BytesTotal: 400, Progress: 100}
+	syncing, err = api.Syncing(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, SyncPhaseBlocks, syncing.(map[string]interface{})["phase"])
+	require.True(t, syncing.(map[string]interface{})["snapshots"].(*SnapshotsSyncing).Completed)
+}
+
+func TestBlockNumberUnwindInProgress(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return stages.SaveStageProgress(tx, stages.Execution, 1000)
+	}))
+	api := NewEthAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), nil, false, rpccfg.DefaultEvmCallTimeout), db, nil, nil, nil, 5000000, 0, 0)
+	api.headCache = &headCache{enabled: true}
+	blockNum, err := api.BlockNumber(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(1000), blockNum)
+
+	// the warm cache doesn't outlive the marker, and the clamped head isn't cached
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return rawdb.WriteUnwindInProgress(tx, 900, 1000)
+	}))
+	api.headCache.blockNumberAt = time.Now().Add(-2 * blockNumberMaxAge)
+	blockNum, err = api.BlockNumber(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(900), blockNum)
+	_, ok := api.headCache.getBlockNumber()
+	require.False(t, ok)
+
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return rawdb.DeleteUnwindInProgress(tx)
+	}))
+	blockNum, err = api.BlockNumber(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(1000), blockNum)
+	_, ok = api.headCache.getBlockNumber()
+	require.True(t, ok)
+}
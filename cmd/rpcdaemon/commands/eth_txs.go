@@ -99,8 +99,8 @@ func (api *APIImpl) GetTransactionByHash(ctx context.Context, txnHash common.Has
 		return newRPCPendingTransaction(txn, curHeader, chainConfig), nil
 	}
 
-	// Transaction unknown, return as such
-	return nil, nil
+	// Transaction unknown, unless it was in a block which was reorged away
+	return nil, api.nonCanonicalTxError(tx, txnHash)
 }
 
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/rawdb"
@@ -25,27 +26,7 @@ func (api *APIImpl) GetUncleByBlockNumberAndIndex(ctx context.Context, number rp
 	if err != nil {
 		return nil, err
 	}
-	block, err := api.blockByNumberWithSenders(tx, blockNum)
-	if err != nil {
-		return nil, err
-	}
-	if block == nil {
-		return nil, nil // not error, see https://github.com/ledgerwatch/erigon/issues/1645
-	}
-	additionalFields := make(map[string]interface{})
-	td, err := rawdb.ReadTd(tx, block.Hash(), blockNum)
-	if err != nil {
-		return nil, err
-	}
-	additionalFields["totalDifficulty"] = (*hexutil.Big)(td)
-
-	uncles := block.Uncles()
-	if index >= hexutil.Uint(len(uncles)) {
-		log.Trace("Requested uncle not found", "number", block.Number(), "hash", hash, "index", index)
-		return nil, nil
-	}
-	uncle := types.NewBlockWithHeader(uncles[index])
-	return ethapi.RPCMarshalBlock(uncle, false, false, additionalFields)
+	return api.uncleByIndex(ctx, tx, hash, blockNum, index)
 }
 
 // GetUncleByBlockHashAndIndex implements eth_getUncleByBlockHashAndIndex. Returns information about an uncle given a block's hash and the index of the uncle.
@@ -56,63 +37,59 @@ func (api *APIImpl) GetUncleByBlockHashAndIndex(ctx context.Context, hash common
 	}
 	defer tx.Rollback()
 
-	block, err := api.blockByHashWithSenders(tx, hash)
-	if err != nil {
-		return nil, err
-	}
-	if block == nil {
+	number := rawdb.ReadHeaderNumber(tx, hash)
+	if number == nil {
 		return nil, nil // not error, see https://github.com/ledgerwatch/erigon/issues/1645
 	}
-	number := block.NumberU64()
-	additionalFields := make(map[string]interface{})
-	td, err := rawdb.ReadTd(tx, hash, number)
+	return api.uncleByIndex(ctx, tx, hash, *number, index)
+}
+
+func (api *APIImpl) uncleByIndex(ctx context.Context, tx kv.Tx, hash common.Hash, number uint64, index hexutil.Uint) (map[string]interface{}, error) {
+	body, _, err := api._blockReader.Body(ctx, tx, hash, number)
 	if err != nil {
 		return nil, err
 	}
-	additionalFields["totalDifficulty"] = (*hexutil.Big)(td)
-
-	uncles := block.Uncles()
-	if index >= hexutil.Uint(len(uncles)) {
-		log.Trace("Requested uncle not found", "number", block.Number(), "hash", hash, "index", index)
+	if body == nil {
+		return nil, nil // not error, see https://github.com/ledgerwatch/erigon/issues/1645
+	}
+	if index >= hexutil.Uint(len(body.Uncles)) {
+		log.Trace("Requested uncle not found", "number", number, "hash", hash, "index", index)
 		return nil, nil
 	}
-	uncle := types.NewBlockWithHeader(uncles[index])
+	return marshalUncle(tx, body.Uncles[index], hash, number)
+}
 
-	return ethapi.RPCMarshalBlock(uncle, false, false, additionalFields)
+// marshalUncle - uncle as eth_getUncleByBlockHashAndIndex returns it: a block without transactions, with total
+// difficulty of the block which includes it
+func marshalUncle(tx kv.Tx, uncle *types.Header, blockHash common.Hash, blockNum uint64) (map[string]interface{}, error) {
+	td, err := rawdb.ReadTd(tx, blockHash, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	additionalFields := map[string]interface{}{"totalDifficulty": (*hexutil.Big)(td)}
+	return ethapi.RPCMarshalBlock(types.NewBlockWithHeader(uncle), false, false, additionalFields)
 }
 
 // GetUncleCountByBlockNumber implements eth_getUncleCountByBlockNumber. Returns the number of uncles in the block, if any.
 func (api *APIImpl) GetUncleCountByBlockNumber(ctx context.Context, number rpc.BlockNumber) (*hexutil.Uint, error) {
-	n := hexutil.Uint(0)
-
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
-		return &n, err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	blockNum, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(number), tx, api.filters)
-	if err != nil {
-		return &n, err
-	}
-
-	block, err := api.blockByNumberWithSenders(tx, blockNum)
+	blockNum, hash, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(number), tx, api.filters)
 	if err != nil {
 		return nil, err
 	}
-	if block == nil {
-		return nil, nil // not error, see https://github.com/ledgerwatch/erigon/issues/1645
-	}
-	n = hexutil.Uint(len(block.Uncles()))
-	return &n, nil
+	return api.uncleCount(ctx, tx, hash, blockNum)
 }
 
 // GetUncleCountByBlockHash implements eth_getUncleCountByBlockHash. Returns the number of uncles in the block, if any.
 func (api *APIImpl) GetUncleCountByBlockHash(ctx context.Context, hash common.Hash) (*hexutil.Uint, error) {
-	n := hexutil.Uint(0)
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
-		return &n, err
+		return nil, err
 	}
 	defer tx.Rollback()
 
@@ -120,14 +97,17 @@ func (api *APIImpl) GetUncleCountByBlockHash(ctx context.Context, hash common.Ha
 	if number == nil {
 		return nil, nil // not error, see https://github.com/ledgerwatch/erigon/issues/1645
 	}
+	return api.uncleCount(ctx, tx, hash, *number)
+}
 
-	block, err := api.blockWithSenders(tx, hash, *number)
+func (api *APIImpl) uncleCount(ctx context.Context, tx kv.Tx, hash common.Hash, number uint64) (*hexutil.Uint, error) {
+	body, _, err := api._blockReader.Body(ctx, tx, hash, number)
 	if err != nil {
 		return nil, err
 	}
-	if block == nil {
+	if body == nil {
 		return nil, nil // not error, see https://github.com/ledgerwatch/erigon/issues/1645
 	}
-	n = hexutil.Uint(len(block.Uncles()))
+	n := hexutil.Uint(len(body.Uncles))
 	return &n, nil
 }
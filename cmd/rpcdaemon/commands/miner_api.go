@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// MinerAPI the interface for the miner_* RPC commands. They are served only by the RPC daemon embedded in Erigon:
+// a separate RPC daemon reaches Erigon over the gRPC interfaces, which have no control of mining.
+type MinerAPI interface {
+	// SetEtherbase sets the address mined blocks are for, clique and bor need it to be the address of the signing key.
+	SetEtherbase(ctx context.Context, etherbase common.Address) (bool, error)
+
+	// SetExtra sets the extra data of mined blocks to the text, at most 32 bytes.
+	SetExtra(ctx context.Context, extra string) (bool, error)
+
+	// SetGasLimit sets the target gas limit of mined blocks, every block moves the gas limit towards it as far as the
+	// consensus rules allow.
+	SetGasLimit(ctx context.Context, gasLimit hexutil.Uint64) (bool, error)
+
+	// Start starts mining, if it isn't running.
+	Start(ctx context.Context) error
+
+	// Stop stops mining after the block being mined.
+	Stop(ctx context.Context) error
+}
+
+// MinerBackend - mining of the node, implemented by eth.Ethereum
+type MinerBackend interface {
+	SetEtherbase(etherbase common.Address) error
+	SetExtra(extra []byte) error
+	SetGasLimit(gasLimit uint64) error
+	MinerStart() error
+	MinerStop() error
+}
+
+// MinerAPIImpl data structure to store things needed for miner_* commands.
+type MinerAPIImpl struct {
+	miner MinerBackend
+}
+
+// NewMinerAPI returns MinerAPIImpl instance.
+func NewMinerAPI(miner MinerBackend) *MinerAPIImpl {
+	return &MinerAPIImpl{miner: miner}
+}
+
+// MinerAPIList - the miner namespace, if it's enabled, for the RPC daemon embedded in Erigon
+func MinerAPIList(miner MinerBackend, cfg httpcfg.HttpCfg) (list []rpc.API) {
+	for _, enabledAPI := range cfg.API {
+		if enabledAPI == "miner" {
+			list = append(list, rpc.API{
+				Namespace: "miner",
+				Public:    false,
+				Service:   MinerAPI(NewMinerAPI(miner)),
+				Version:   "1.0",
+			})
+		}
+	}
+	return list
+}
+
+func (api *MinerAPIImpl) SetEtherbase(ctx context.Context, etherbase common.Address) (bool, error) {
+	if err := api.miner.SetEtherbase(etherbase); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (api *MinerAPIImpl) SetExtra(ctx context.Context, extra string) (bool, error) {
+	if err := api.miner.SetExtra([]byte(extra)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (api *MinerAPIImpl) SetGasLimit(ctx context.Context, gasLimit hexutil.Uint64) (bool, error) {
+	if err := api.miner.SetGasLimit(uint64(gasLimit)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (api *MinerAPIImpl) Start(ctx context.Context) error {
+	return api.miner.MinerStart()
+}
+
+func (api *MinerAPIImpl) Stop(ctx context.Context) error {
+	return api.miner.MinerStop()
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+type minerBackendMock struct {
+	etherbase common.Address
+	extra     []byte
+	gasLimit  uint64
+	running   bool
+}
+
+func (m *minerBackendMock) SetEtherbase(etherbase common.Address) error {
+	m.etherbase = etherbase
+	return nil
+}
+
+func (m *minerBackendMock) SetExtra(extra []byte) error {
+	if len(extra) > 32 {
+		return errors.New("extra exceeds max length")
+	}
+	m.extra = extra
+	return nil
+}
+
+func (m *minerBackendMock) SetGasLimit(gasLimit uint64) error {
+	if gasLimit < params.MinGasLimit {
+		return errors.New("gas limit is out of range")
+	}
+	m.gasLimit = gasLimit
+	return nil
+}
+
+func (m *minerBackendMock) MinerStart() error {
+	m.running = true
+	return nil
+}
+
+func (m *minerBackendMock) MinerStop() error {
+	m.running = false
+	return nil
+}
+
+func TestMinerAPI(t *testing.T) {
+	ctx := context.Background()
+	backend := &minerBackendMock{}
+	require.Empty(t, MinerAPIList(backend, httpcfg.HttpCfg{API: []string{"eth", "admin"}}))
+	list := MinerAPIList(backend, httpcfg.HttpCfg{API: []string{"eth", "miner"}})
+	require.Len(t, list, 1)
+	require.Equal(t, "miner", list[0].Namespace)
+	require.False(t, list[0].Public)
+	api := list[0].Service.(MinerAPI)
+
+	ok, err := api.SetEtherbase(ctx, common.HexToAddress("0x1234"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, common.HexToAddress("0x1234"), backend.etherbase)
+
+	ok, err = api.SetExtra(ctx, "erigon")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("erigon"), backend.extra)
+	ok, err = api.SetExtra(ctx, "an extra data which is longer than 32 bytes")
+	require.Error(t, err)
+	require.False(t, ok)
+
+	ok, err = api.SetGasLimit(ctx, 60_000_000)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(60_000_000), backend.gasLimit)
+	ok, err = api.SetGasLimit(ctx, 1000)
+	require.Error(t, err)
+	require.False(t, ok)
+	require.Equal(t, uint64(60_000_000), backend.gasLimit)
+
+	require.NoError(t, api.Start(ctx))
+	require.True(t, backend.running)
+	require.NoError(t, api.Stop(ctx))
+	require.False(t, backend.running)
+}
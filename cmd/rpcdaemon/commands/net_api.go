@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/log/v3"
 )
 
 // NetAPI the interface for the net_ RPC commands
@@ -18,43 +20,74 @@ type NetAPI interface {
 
 // NetAPIImpl data structure to store things needed for net_ commands
 type NetAPIImpl struct {
+	*BaseAPI
+	db         kv.RoDB
 	ethBackend rpchelper.ApiBackend
 }
 
 // NewNetAPIImpl returns NetAPIImplImpl instance
-func NewNetAPIImpl(eth rpchelper.ApiBackend) *NetAPIImpl {
+func NewNetAPIImpl(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend) *NetAPIImpl {
 	return &NetAPIImpl{
+		BaseAPI:    base,
+		db:         db,
 		ethBackend: eth,
 	}
 }
 
-// Listening implements net_listening. Returns true if client is actively listening for network connections.
-// TODO: Remove hard coded value
-func (api *NetAPIImpl) Listening(_ context.Context) (bool, error) {
-	return true, nil
+// Listening implements net_listening. Returns true if client is actively listening for network connections:
+// at least one of the sentries has an open RLPx listener. Without sentries (--datadir mode) returns false.
+func (api *NetAPIImpl) Listening(ctx context.Context) (bool, error) {
+	if api.ethBackend == nil {
+		return false, nil
+	}
+	nodes, err := api.ethBackend.NodeInfo(ctx, 0)
+	if err != nil {
+		// unreachable sentry is not listening - it's an answer, not an error
+		log.Debug("net_listening: cannot get nodes info", "err", err)
+		return false, nil
+	}
+	for _, node := range nodes {
+		if node.Ports.Listener > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // Version implements net_version. Returns the current network id.
+// In --datadir mode falls back to the chain id of the chain config stored in the database.
 func (api *NetAPIImpl) Version(ctx context.Context) (string, error) {
-	if api.ethBackend == nil {
-		// We're running in --datadir mode or otherwise cannot get the backend
+	if api.ethBackend != nil {
+		res, err := api.ethBackend.NetVersion(ctx)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(res, 10), nil
+	}
+	if api.db == nil {
 		return "", fmt.Errorf(NotAvailableChainData, "net_version")
 	}
 
-	res, err := api.ethBackend.NetVersion(ctx)
+	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return "", err
 	}
-
-	return strconv.FormatUint(res, 10), nil
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return "", err
+	}
+	if chainConfig == nil || chainConfig.ChainID == nil {
+		return "", fmt.Errorf(NotAvailableChainData, "net_version")
+	}
+	return chainConfig.ChainID.String(), nil
 }
 
 // PeerCount implements net_peerCount. Returns number of peers currently
-// connected to the first sentry server.
+// connected to all sentry servers. Without sentries (--datadir mode) returns 0.
 func (api *NetAPIImpl) PeerCount(ctx context.Context) (hexutil.Uint, error) {
 	if api.ethBackend == nil {
-		// We're running in --datadir mode or otherwise cannot get the backend
-		return 0, fmt.Errorf(NotAvailableChainData, "net_peerCount")
+		return 0, nil
 	}
 
 	res, err := api.ethBackend.NetPeerCount(ctx)
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core"
+)
+
+func TestNetAndWeb3WithoutBackend(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	if _, _, err := core.CommitGenesisBlock(db, core.DefaultGenesisBlock()); err != nil {
+		t.Fatalf("setting up genensis block: %v", err)
+	}
+	ctx := context.Background()
+
+	net := NewNetAPIImpl(&BaseAPI{}, db, nil)
+	version, err := net.Version(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1" {
+		t.Fatalf("net_version: have %s, want 1", version)
+	}
+	listening, err := net.Listening(ctx)
+	if err != nil || listening {
+		t.Fatalf("net_listening: have %t, %v", listening, err)
+	}
+	peers, err := net.PeerCount(ctx)
+	if err != nil || peers != 0 {
+		t.Fatalf("net_peerCount: have %d, %v", peers, err)
+	}
+
+	web3 := NewWeb3APIImpl(nil)
+	clientVersion, err := web3.ClientVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(clientVersion, "erigon/") {
+		t.Fatalf("web3_clientVersion: unexpected %s", clientVersion)
+	}
+
+	forkID, err := NewErigonAPI(&BaseAPI{}, db, nil).ForkId(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forkID.Hash.String() != "0xfc64ec04" || forkID.Next != 1_150_000 {
+		t.Fatalf("erigon_forkId: unexpected %s %d", forkID.Hash, forkID.Next)
+	}
+}
@@ -0,0 +1,309 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// ActivityType - kind of an entry of the activity timeline of an address
+type ActivityType string
+
+const (
+	ActivityTransaction ActivityType = "transaction" // sent by the address, to it, or creating it
+	ActivityInternal    ActivityType = "internal"    // internal ETH transfer, creation or self-destruct from or to it
+	ActivityToken       ActivityType = "token"       // ERC-20/ERC-721 Transfer log from or to it
+)
+
+// ActivityItem - an entry of ots_getAddressActivity, Transaction, Internal or Transfer is set by the Type
+type ActivityItem struct {
+	Type             ActivityType       `json:"type"`
+	BlockNumber      hexutil.Uint64     `json:"blockNumber"`
+	Timestamp        hexutil.Uint64     `json:"timestamp"`
+	TransactionHash  common.Hash        `json:"transactionHash"`
+	TransactionIndex hexutil.Uint64     `json:"transactionIndex"`
+	Transaction      *RPCTransaction    `json:"transaction,omitempty"`
+	Status           *hexutil.Uint64    `json:"status,omitempty"` // of the transaction, absent if receipts are pruned
+	Internal         *InternalOperation `json:"internal,omitempty"`
+	Transfer         *TokenTransfer     `json:"transfer,omitempty"`
+}
+
+type AddressActivityPage struct {
+	Items      []*ActivityItem `json:"items"`                // newest first
+	NextCursor *string         `json:"nextCursor,omitempty"` // absent if there are no more blocks to search
+}
+
+const activityCursorVersion = 1
+
+// maxActivityBlocks - blocks read (and traced, if they have calls of the address) for one page of
+// ots_getAddressActivity, the page ends early with a cursor when they are exhausted
+const maxActivityBlocks = 100
+
+// activityCursor - the block of the last item of a page and the number of items of the block returned so far, the
+// next page continues with the rest of the block. Clients treat it as an opaque string.
+type activityCursor struct {
+	block    uint64
+	returned uint64
+}
+
+func (c activityCursor) String() string {
+	var b [17]byte
+	b[0] = activityCursorVersion
+	binary.BigEndian.PutUint64(b[1:], c.block)
+	binary.BigEndian.PutUint64(b[9:], c.returned)
+	return hexutil.Encode(b[:])
+}
+
+func parseActivityCursor(s string) (*activityCursor, error) {
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != 17 || b[0] != activityCursorVersion {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	return &activityCursor{block: binary.BigEndian.Uint64(b[1:]), returned: binary.BigEndian.Uint64(b[9:])}, nil
+}
+
+// GetAddressActivity implements ots_getAddressActivity: one timeline of the address, newest first, of transactions
+// sent by it or to it, internal operations from or to it (ETH transfers, creations, self-destructs) and its ERC-20/
+// ERC-721 token transfers, so explorers and wallets don't stitch together ots_searchTransactionsBefore,
+// ots_getInternalOperations and ots_searchTokenTransfers. Blocks are found by the call indices and the index of the
+// TokenTransfers stage, blocks with calls of the address are traced by the operations tracer. Pages have at most
+// pageSize items, the cursor is nextCursor of the previous page, "" or omitted for the newest items. A page may be
+// shorter, even empty, while nextCursor is set: at most maxActivityBlocks blocks are read for a page, and the index has
+// blocks where the address made no transfer.
+func (api *OtterscanAPIImpl) GetAddressActivity(ctx context.Context, addr common.Address, pageSize uint16, cursor *string) (*AddressActivityPage, error) {
+	if pageSize == 0 {
+		return nil, errors.New("page size must be positive")
+	}
+	var resume *activityCursor
+	if cursor != nil && *cursor != "" {
+		var err error
+		if resume, err = parseActivityCursor(*cursor); err != nil {
+			return nil, err
+		}
+	}
+	dbtx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	if api.historyV3(dbtx) {
+		return nil, errors.New("address activity isn't indexed with --history.v3")
+	}
+	// both indices must have the block, otherwise its items would differ between pages
+	maxBlock, err := stages.GetStageProgress(dbtx, stages.CallTraces)
+	if err != nil {
+		return nil, err
+	}
+	tokenProgress, err := stages.GetStageProgress(dbtx, stages.TokenTransfers)
+	if err != nil {
+		return nil, err
+	}
+	if tokenProgress < maxBlock {
+		maxBlock = tokenProgress
+	}
+	if resume != nil && resume.block < maxBlock {
+		maxBlock = resume.block
+	}
+
+	calls, err := bitmapdb.Get64(dbtx, kv.CallFromIndex, addr[:], 0, maxBlock)
+	if err != nil {
+		return nil, err
+	}
+	callsTo, err := bitmapdb.Get64(dbtx, kv.CallToIndex, addr[:], 0, maxBlock)
+	if err != nil {
+		return nil, err
+	}
+	calls.Or(callsTo)
+	tokens, err := tokenTransferBlocks(dbtx, addr, nil, uint32(maxBlock))
+	if err != nil {
+		return nil, err
+	}
+	blocks := calls.Clone()
+	for it := tokens.Iterator(); it.HasNext(); {
+		blocks.Add(uint64(it.Next()))
+	}
+
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
+	}
+	items, next, err := activityPage(blocks.ReverseIterator(), resume, int(pageSize), maxActivityBlocks, func(blockNum uint64) ([]*ActivityItem, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return api.blockActivity(ctx, dbtx, addr, blockNum, calls.Contains(blockNum), tokens.Contains(uint32(blockNum)), chainConfig)
+	})
+	if err != nil {
+		return nil, err
+	}
+	page := &AddressActivityPage{Items: items}
+	if next != nil {
+		s := next.String()
+		page.NextCursor = &s
+	}
+	return page, nil
+}
+
+// activityPage collects up to pageSize items of at most maxBlocks blocks, which are iterated in descending order,
+// continuing at the cursor position if resume is set. Returns the cursor of the next page, nil if there are no more blocks.
+func activityPage(blocks roaring64.IntIterable64, resume *activityCursor, pageSize, maxBlocks int, blockItems func(blockNum uint64) ([]*ActivityItem, error)) ([]*ActivityItem, *activityCursor, error) {
+	page := make([]*ActivityItem, 0, pageSize)
+	read := 0
+	for blocks.HasNext() {
+		blockNum := blocks.Next()
+		items, err := blockItems(blockNum)
+		if err != nil {
+			return nil, nil, err
+		}
+		skip := 0
+		if resume != nil && blockNum == resume.block {
+			skip = len(items)
+			if resume.returned < uint64(len(items)) {
+				skip = int(resume.returned)
+			}
+		} else {
+			read++ // the block of the cursor was counted by the previous page
+		}
+		rest := items[skip:]
+		if free := pageSize - len(page); len(rest) > free {
+			page = append(page, rest[:free]...)
+			return page, &activityCursor{block: blockNum, returned: uint64(skip + free)}, nil
+		}
+		page = append(page, rest...)
+		if (len(page) == pageSize || read >= maxBlocks) && blocks.HasNext() {
+			return page, &activityCursor{block: blockNum, returned: uint64(len(items))}, nil
+		}
+	}
+	return page, nil, nil
+}
+
+// blockActivity - items of the address in the canonical block, see activityItems. The block is traced only if it's in
+// the call indices of the address, its token transfers are read only if it's in the token transfer index.
+func (api *OtterscanAPIImpl) blockActivity(ctx context.Context, dbtx kv.Tx, addr common.Address, blockNum uint64, inCalls, inTokens bool, chainConfig *params.ChainConfig) ([]*ActivityItem, error) {
+	blockHash, err := rawdb.ReadCanonicalHash(dbtx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := activityCacheKey{addr: addr, blockHash: blockHash}
+	if items, ok := api.searchCache.getActivity(cacheKey); ok {
+		return items, nil
+	}
+	block, senders, err := api._blockReader.BlockWithSenders(ctx, dbtx, blockHash, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, rpc.NewNotFoundError("block", blockNum)
+	}
+	var ops [][]*InternalOperation
+	if inCalls {
+		if ops, err = api.activityOperations(ctx, dbtx, block, chainConfig); err != nil {
+			return nil, fmt.Errorf("internal operations of block %d: %w", blockNum, err)
+		}
+	}
+	var transfers []*TokenTransfer
+	if inTokens {
+		if transfers, err = api.blockTokenTransfers(dbtx, blockNum, addr, nil); err != nil {
+			return nil, err
+		}
+	}
+	items := activityItems(block, senders, rawdb.ReadRawReceipts(dbtx, blockNum), ops, transfers, addr)
+	api.searchCache.addActivity(cacheKey, items)
+	return items, nil
+}
+
+// activityOperations - internal operations of each transaction of the block, traced by at most searchWorkers at once
+// with searches, or read from the index of --ots.operations.path if the state history of the block is pruned
+func (api *OtterscanAPIImpl) activityOperations(ctx context.Context, dbtx kv.Tx, block *types.Block, chainConfig *params.ChainConfig) ([][]*InternalOperation, error) {
+	if block.Transactions().Len() == 0 {
+		return nil, nil
+	}
+	// the block is executed on top of the state after its parent
+	prunedErr := rpchelper.CheckHistoryNotPruned(dbtx, block.NumberU64()-1)
+	if prunedErr == nil {
+		if err := api.searchWorkers.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+		defer api.searchWorkers.Release(1)
+		return api.blockInternalOperations(ctx, dbtx, block, chainConfig)
+	}
+	var pruned *rpc.PrunedError
+	if !errors.As(prunedErr, &pruned) || api.operations == nil {
+		return nil, prunedErr
+	}
+	ops := make([][]*InternalOperation, 0, block.Transactions().Len())
+	for _, txn := range block.Transactions() {
+		txOps, indexed, err := api.operations.get(ctx, txn.Hash(), block.Hash(), block.NumberU64())
+		if err != nil {
+			return nil, err
+		}
+		if !indexed {
+			return nil, prunedErr // pruned before the backfill reached it
+		}
+		ops = append(ops, txOps)
+	}
+	return ops, nil
+}
+
+// activityItems - items of the address in the block: transactions from the last one, each followed by its internal
+// operations and token transfers in execution order. ops are internal operations of each transaction, nil if the
+// block isn't traced, transfers are token transfers of the address in the block, from the last one.
+func activityItems(block *types.Block, senders []common.Address, receipts types.Receipts, ops [][]*InternalOperation, transfers []*TokenTransfer, addr common.Address) []*ActivityItem {
+	txTransfers := map[common.Hash][]*TokenTransfer{}
+	for i := len(transfers) - 1; i >= 0; i-- {
+		txTransfers[transfers[i].TransactionHash] = append(txTransfers[transfers[i].TransactionHash], transfers[i])
+	}
+	blockNum := block.NumberU64()
+	txs := block.Transactions()
+	items := make([]*ActivityItem, 0)
+	for idx := len(txs) - 1; idx >= 0; idx-- {
+		txn := txs[idx]
+		newItem := func(t ActivityType) *ActivityItem {
+			return &ActivityItem{Type: t, BlockNumber: hexutil.Uint64(blockNum), Timestamp: hexutil.Uint64(block.Time()), TransactionHash: txn.Hash(), TransactionIndex: hexutil.Uint64(idx)}
+		}
+		var sender common.Address
+		if idx < len(senders) {
+			sender = senders[idx]
+		}
+		to := txn.GetTo()
+		if sender == addr || (to != nil && *to == addr) || (to == nil && crypto.CreateAddress(sender, txn.GetNonce()) == addr) {
+			item := newItem(ActivityTransaction)
+			item.Transaction = newRPCTransaction(txn, block.Hash(), blockNum, uint64(idx), block.BaseFee())
+			if idx < len(receipts) {
+				status := hexutil.Uint64(receipts[idx].Status)
+				item.Status = &status
+			}
+			items = append(items, item)
+		}
+		if idx < len(ops) {
+			for _, op := range ops[idx] {
+				if op.From == addr || op.To == addr {
+					item := newItem(ActivityInternal)
+					item.Internal = op
+					items = append(items, item)
+				}
+			}
+		}
+		for _, transfer := range txTransfers[txn.Hash()] {
+			item := newItem(ActivityToken)
+			item.Transfer = transfer
+			items = append(items, item)
+		}
+	}
+	return items
+}
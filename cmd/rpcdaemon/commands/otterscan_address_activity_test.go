@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityCursor(t *testing.T) {
+	c := activityCursor{block: 15_000_000, returned: 3}
+	parsed, err := parseActivityCursor(c.String())
+	require.NoError(t, err)
+	require.Equal(t, c, *parsed)
+
+	for _, s := range []string{"0x", "0x02", "not hex", c.String()[:len(c.String())-2]} {
+		_, err = parseActivityCursor(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestActivityPage(t *testing.T) {
+	// blocks 30, 20, 10 with 3, 0 and 2 items
+	blockItems := func(blockNum uint64) ([]*ActivityItem, error) {
+		n := map[uint64]int{30: 3, 20: 0, 10: 2}[blockNum]
+		items := make([]*ActivityItem, n)
+		for i := range items {
+			items[i] = &ActivityItem{BlockNumber: hexutil.Uint64(blockNum), TransactionIndex: hexutil.Uint64(n - 1 - i)}
+		}
+		return items, nil
+	}
+	blocks := roaring64.BitmapOf(10, 20, 30)
+	var all []*ActivityItem
+	var resume *activityCursor
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 10)
+		page, next, err := activityPage(blocks.ReverseIterator(), resume, 2, 10, blockItems)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page), 2)
+		all = append(all, page...)
+		if next == nil {
+			break
+		}
+		// the next page starts at the cursor block, later blocks are not iterated
+		resume = next
+		blocks = roaring64.BitmapOf(10, 20, 30)
+		blocks.RemoveRange(next.block+1, 31)
+	}
+	require.Len(t, all, 5)
+	for i, expected := range []struct{ block, idx uint64 }{{30, 2}, {30, 1}, {30, 0}, {10, 1}, {10, 0}} {
+		require.Equal(t, hexutil.Uint64(expected.block), all[i].BlockNumber, i)
+		require.Equal(t, hexutil.Uint64(expected.idx), all[i].TransactionIndex, i)
+	}
+
+	// the page filled by the last block has no cursor
+	page, next, err := activityPage(roaring64.BitmapOf(10).ReverseIterator(), nil, 2, 10, blockItems)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Nil(t, next)
+
+	// the budget of blocks is exhausted by a block without items, the empty page has a cursor past it
+	page, next, err = activityPage(roaring64.BitmapOf(10, 20).ReverseIterator(), nil, 2, 1, blockItems)
+	require.NoError(t, err)
+	require.Empty(t, page)
+	require.Equal(t, &activityCursor{block: 20, returned: 0}, next)
+	// the block of the cursor isn't counted again
+	page, next, err = activityPage(roaring64.BitmapOf(10, 20).ReverseIterator(), next, 2, 1, blockItems)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Nil(t, next)
+}
+
+func TestActivityItems(t *testing.T) {
+	addr := common.HexToAddress("0xa")
+	other := common.HexToAddress("0xb")
+	created := crypto.CreateAddress(addr, 5)
+	txs := []types.Transaction{
+		types.NewTransaction(0, other, uint256.NewInt(1), 21000, uint256.NewInt(1), nil), // from other to other
+		types.NewTransaction(1, addr, uint256.NewInt(1), 21000, uint256.NewInt(1), nil),  // to addr
+		types.NewContractCreation(5, uint256.NewInt(0), 100000, uint256.NewInt(1), nil),  // by addr
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(7), Time: 100}, txs, nil, nil)
+	senders := []common.Address{other, other, addr}
+	receipts := types.Receipts{{Status: 1}, {Status: 0}, {Status: 1}}
+	ops := [][]*InternalOperation{
+		{{Type: OP_TRANSFER, From: other, To: addr}},
+		{{Type: OP_TRANSFER, From: other, To: other}},
+		{{Type: OP_CREATE, From: addr, To: created}},
+	}
+	// last transfer first, as returned by blockTokenTransfers
+	transfers := []*TokenTransfer{
+		{TransactionHash: txs[0].Hash(), LogIndex: 4},
+		{TransactionHash: txs[0].Hash(), LogIndex: 1},
+	}
+
+	items := activityItems(block, senders, receipts, ops, transfers, addr)
+	itemTypes := make([]ActivityType, len(items))
+	for i, item := range items {
+		itemTypes[i] = item.Type
+	}
+	require.Equal(t, []ActivityType{ActivityTransaction, ActivityInternal, ActivityTransaction, ActivityInternal, ActivityToken, ActivityToken}, itemTypes)
+	require.Equal(t, hexutil.Uint64(2), items[0].TransactionIndex)
+	require.Equal(t, txs[2].Hash(), items[0].Transaction.Hash)
+	require.Equal(t, hexutil.Uint64(1), *items[0].Status)
+	require.Equal(t, created, items[1].Internal.To)
+	require.Equal(t, hexutil.Uint64(0), *items[2].Status)
+	require.Equal(t, hexutil.Uint64(0), items[3].TransactionIndex)
+	require.Equal(t, hexutil.Uint(1), items[4].Transfer.LogIndex)
+	require.Equal(t, hexutil.Uint(4), items[5].Transfer.LogIndex)
+	require.Equal(t, hexutil.Uint64(7), items[5].BlockNumber)
+	require.Equal(t, hexutil.Uint64(100), items[5].Timestamp)
+
+	// receipts are pruned, blocks without calls aren't traced
+	items = activityItems(block, senders, nil, nil, nil, addr)
+	require.Len(t, items, 2)
+	require.Nil(t, items[0].Status)
+}
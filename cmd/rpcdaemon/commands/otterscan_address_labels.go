@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// OtsAddressLabels - address -> JSON of AddressMetadata. Lives in its own small DB (--ots.labels.path),
+// because rpcdaemon can't write into Erigon's chaindata.
+const OtsAddressLabels = "OtsAddressLabels"
+
+var errAddressLabelsDisabled = errors.New("address labels are disabled, start rpcdaemon with --ots.labels.path")
+
+// AddressMetadata - operator's annotation of an address (exchange, known contract, etc.)
+type AddressMetadata struct {
+	Name   string   `json:"name"`
+	Tags   []string `json:"tags,omitempty"`
+	Source string   `json:"source,omitempty"`
+}
+
+// AddressLabel - one entry of imported JSON labels
+type AddressLabel struct {
+	Address common.Address `json:"address"`
+	AddressMetadata
+}
+
+type addressLabels struct {
+	db kv.RwDB
+}
+
+func openAddressLabels(path string) (*addressLabels, error) {
+	db, err := openOtsDB(path, kv.TableCfg{OtsAddressLabels: {}}, 1*datasize.GB, 16*datasize.MB)
+	if err != nil {
+		return nil, fmt.Errorf("open address labels db %s: %w", path, err)
+	}
+	return &addressLabels{db: db}, nil
+}
+
+func (l *addressLabels) get(ctx context.Context, addr common.Address) (*AddressMetadata, error) {
+	var meta *AddressMetadata
+	if err := l.db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(OtsAddressLabels, addr.Bytes())
+		if err != nil || v == nil {
+			return err
+		}
+		meta = new(AddressMetadata)
+		return json.Unmarshal(v, meta)
+	}); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// put writes all labels in one transaction, existing labels of the addresses are replaced
+func (l *addressLabels) put(ctx context.Context, labels []AddressLabel) error {
+	return l.db.Update(ctx, func(tx kv.RwTx) error {
+		for i := range labels {
+			v, err := json.Marshal(&labels[i].AddressMetadata)
+			if err != nil {
+				return err
+			}
+			if err = tx.Put(OtsAddressLabels, labels[i].Address.Bytes(), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (l *addressLabels) delete(ctx context.Context, addr common.Address) (existed bool, err error) {
+	err = l.db.Update(ctx, func(tx kv.RwTx) error {
+		if existed, err = tx.Has(OtsAddressLabels, addr.Bytes()); err != nil || !existed {
+			return err
+		}
+		return tx.Delete(OtsAddressLabels, addr.Bytes())
+	})
+	return existed, err
+}
+
+// parseAddressLabels parses "csv" (columns address,name[,tags[,source]], tags separated by ';',
+// optional header row) or "json" (array of AddressLabel). Source of entries without one is set to source.
+func parseAddressLabels(format, content, source string) ([]AddressLabel, error) {
+	var labels []AddressLabel
+	switch strings.ToLower(format) {
+	case "csv":
+		r := csv.NewReader(strings.NewReader(content))
+		r.FieldsPerRecord = -1
+		r.TrimLeadingSpace = true
+		for line := 1; ; line++ {
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if line == 1 && strings.EqualFold(record[0], "address") {
+				continue
+			}
+			if len(record) < 2 || len(record) > 4 {
+				return nil, fmt.Errorf("line %d: expected address,name[,tags[,source]], got %d columns", line, len(record))
+			}
+			label := AddressLabel{AddressMetadata: AddressMetadata{Name: record[1]}}
+			if err = label.Address.UnmarshalText([]byte(record[0])); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if len(record) > 2 && record[2] != "" {
+				for _, tag := range strings.Split(record[2], ";") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						label.Tags = append(label.Tags, tag)
+					}
+				}
+			}
+			if len(record) > 3 {
+				label.Source = record[3]
+			}
+			labels = append(labels, label)
+		}
+	case "json":
+		if err := json.Unmarshal([]byte(content), &labels); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected csv or json", format)
+	}
+	for i := range labels {
+		if labels[i].Name == "" {
+			return nil, fmt.Errorf("label of %x has no name", labels[i].Address)
+		}
+		if labels[i].Source == "" {
+			labels[i].Source = source
+		}
+	}
+	return labels, nil
+}
+
+// GetAddressMetadata returns the label of the address, nil if it has none or labels are disabled
+func (api *OtterscanAPIImpl) GetAddressMetadata(ctx context.Context, addr common.Address) (*AddressMetadata, error) {
+	if api.labels == nil {
+		return nil, nil
+	}
+	return api.labels.get(ctx, addr)
+}
+
+// OtsAdminAPI - management of address labels served by ots_getAddressMetadata
+type OtsAdminAPI interface {
+	SetAddressMetadata(ctx context.Context, addr common.Address, meta AddressMetadata) error
+	DeleteAddressMetadata(ctx context.Context, addr common.Address) (bool, error)
+	ImportAddressLabels(ctx context.Context, format string, content string, source string) (int, error)
+}
+
+type OtsAdminAPIImpl struct {
+	labels *addressLabels
+}
+
+func NewOtsAdminAPI(labels *addressLabels) *OtsAdminAPIImpl {
+	return &OtsAdminAPIImpl{labels: labels}
+}
+
+// SetAddressMetadata adds or replaces the label of the address
+func (api *OtsAdminAPIImpl) SetAddressMetadata(ctx context.Context, addr common.Address, meta AddressMetadata) error {
+	if api.labels == nil {
+		return errAddressLabelsDisabled
+	}
+	if meta.Name == "" {
+		return errors.New("name is required")
+	}
+	return api.labels.put(ctx, []AddressLabel{{Address: addr, AddressMetadata: meta}})
+}
+
+// DeleteAddressMetadata removes the label of the address, returns false if there was none
+func (api *OtsAdminAPIImpl) DeleteAddressMetadata(ctx context.Context, addr common.Address) (bool, error) {
+	if api.labels == nil {
+		return false, errAddressLabelsDisabled
+	}
+	return api.labels.delete(ctx, addr)
+}
+
+// ImportAddressLabels imports labels from CSV or JSON content (see parseAddressLabels), returns amount of imported labels.
+// Nothing is imported if any entry is malformed.
+func (api *OtsAdminAPIImpl) ImportAddressLabels(ctx context.Context, format string, content string, source string) (int, error) {
+	if api.labels == nil {
+		return 0, errAddressLabelsDisabled
+	}
+	labels, err := parseAddressLabels(format, content, source)
+	if err != nil {
+		return 0, err
+	}
+	if err = api.labels.put(ctx, labels); err != nil {
+		return 0, err
+	}
+	return len(labels), nil
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddressLabels(t *testing.T) {
+	require := require.New(t)
+	exchange := common.HexToAddress("0x28c6c06298d514db089934071355e5743bf21d60")
+	token := common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
+
+	labels, err := parseAddressLabels("csv", "address,name,tags,source\n"+
+		exchange.Hex()+",Binance 14,exchange; hot wallet\n"+
+		token.Hex()+",Tether USD,,etherscan\n", "import")
+	require.NoError(err)
+	require.Equal([]AddressLabel{
+		{Address: exchange, AddressMetadata: AddressMetadata{Name: "Binance 14", Tags: []string{"exchange", "hot wallet"}, Source: "import"}},
+		{Address: token, AddressMetadata: AddressMetadata{Name: "Tether USD", Source: "etherscan"}},
+	}, labels)
+
+	labels, err = parseAddressLabels("json", `[{"address":"`+token.Hex()+`","name":"Tether USD","tags":["token"]}]`, "import")
+	require.NoError(err)
+	require.Equal([]AddressLabel{{Address: token, AddressMetadata: AddressMetadata{Name: "Tether USD", Tags: []string{"token"}, Source: "import"}}}, labels)
+
+	_, err = parseAddressLabels("csv", "0x01,Short address\n", "")
+	require.Error(err)
+	_, err = parseAddressLabels("csv", token.Hex()+"\n", "")
+	require.Error(err)
+	_, err = parseAddressLabels("json", `[{"address":"`+token.Hex()+`"}]`, "")
+	require.Error(err)
+	_, err = parseAddressLabels("xml", "", "")
+	require.Error(err)
+}
+
+func TestAddressLabels(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	labels, err := openAddressLabels(filepath.Join(t.TempDir(), "labels"))
+	require.NoError(err)
+	defer labels.db.Close()
+	ots := &OtterscanAPIImpl{labels: labels}
+	admin := NewOtsAdminAPI(labels)
+	addr := common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
+
+	meta, err := ots.GetAddressMetadata(ctx, addr)
+	require.NoError(err)
+	require.Nil(meta)
+
+	n, err := admin.ImportAddressLabels(ctx, "csv", addr.Hex()+",Tether USD,token\n", "import")
+	require.NoError(err)
+	require.Equal(1, n)
+	meta, err = ots.GetAddressMetadata(ctx, addr)
+	require.NoError(err)
+	require.Equal(&AddressMetadata{Name: "Tether USD", Tags: []string{"token"}, Source: "import"}, meta)
+
+	require.NoError(admin.SetAddressMetadata(ctx, addr, AddressMetadata{Name: "USDT"}))
+	meta, err = ots.GetAddressMetadata(ctx, addr)
+	require.NoError(err)
+	require.Equal(&AddressMetadata{Name: "USDT"}, meta)
+
+	deleted, err := admin.DeleteAddressMetadata(ctx, addr)
+	require.NoError(err)
+	require.True(deleted)
+	deleted, err = admin.DeleteAddressMetadata(ctx, addr)
+	require.NoError(err)
+	require.False(deleted)
+
+	// disabled labels
+	meta, err = (&OtterscanAPIImpl{}).GetAddressMetadata(ctx, addr)
+	require.NoError(err)
+	require.Nil(meta)
+	require.ErrorIs(NewOtsAdminAPI(nil).SetAddressMetadata(ctx, addr, AddressMetadata{Name: "USDT"}), errAddressLabelsDisabled)
+}
@@ -2,13 +2,13 @@ package commands
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"math/big"
-	"sync"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
@@ -16,50 +16,91 @@ import (
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/ethconfig/estimate"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 )
 
 // API_LEVEL Must be incremented every time new additions are made
-const API_LEVEL = 8
+// Deprecated: frontends should detect features by ots_getCapabilities
+const API_LEVEL = 10
 
 type TransactionsWithReceipts struct {
-	Txs       []*RPCTransaction        `json:"txs"`
-	Receipts  []map[string]interface{} `json:"receipts"`
-	FirstPage bool                     `json:"firstPage"`
-	LastPage  bool                     `json:"lastPage"`
+	Txs        []*RPCTransaction        `json:"txs"`
+	Receipts   []map[string]interface{} `json:"receipts"`
+	FirstPage  bool                     `json:"firstPage"`
+	LastPage   bool                     `json:"lastPage"`
+	NextCursor *string                  `json:"nextCursor,omitempty"` // continues the search after the page, see searchPage
 }
 
 type OtterscanAPI interface {
 	GetApiLevel() uint8
+	GetCapabilities(ctx context.Context) (*OtsCapabilities, error)
 	GetInternalOperations(ctx context.Context, hash common.Hash) ([]*InternalOperation, error)
-	SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error)
-	SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error)
+	SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, direction *SearchDirection, cursor *string, compact *bool) (*TransactionsWithReceipts, error)
+	SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, direction *SearchDirection, cursor *string, compact *bool) (*TransactionsWithReceipts, error)
+	SearchTokenTransfers(ctx context.Context, addr common.Address, token *common.Address, blockNum uint64, pageSize uint16) (*TokenTransfersPage, error)
+	GetAddressActivity(ctx context.Context, addr common.Address, pageSize uint16, cursor *string) (*AddressActivityPage, error)
 	GetBlockDetails(ctx context.Context, number rpc.BlockNumber) (map[string]interface{}, error)
 	GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error)
 	GetBlockTransactions(ctx context.Context, number rpc.BlockNumber, pageNumber uint8, pageSize uint8) (map[string]interface{}, error)
-	HasCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (bool, error)
+	GetBlockTransactionsCount(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*BlockTransactionsCount, error)
+	HasCode(ctx context.Context, address common.Address, blockNrOrHash *rpc.BlockNumberOrHash) (bool, error)
+	HasCodes(ctx context.Context, addresses []common.Address, blockNrOrHash *rpc.BlockNumberOrHash) ([]bool, error)
 	TraceTransaction(ctx context.Context, hash common.Hash) ([]*TraceEntry, error)
+	TraceTransactionTree(ctx context.Context, hash common.Hash) (*CallFrame, error)
 	GetTransactionError(ctx context.Context, hash common.Hash) (hexutil.Bytes, error)
 	GetTransactionBySenderAndNonce(ctx context.Context, addr common.Address, nonce uint64) (*common.Hash, error)
 	GetContractCreator(ctx context.Context, addr common.Address) (*ContractCreatorData, error)
+	GetAddressMetadata(ctx context.Context, addr common.Address) (*AddressMetadata, error)
+	GetContractMetadata(ctx context.Context, addr common.Address, withSources *bool) (*ContractMetadata, error)
+	DecodeCalldata(ctx context.Context, hash common.Hash) (*DecodedCalldata, error)
 }
 
 type OtterscanAPIImpl struct {
 	*BaseAPI
-	db kv.RoDB
+	db         kv.RoDB
+	labels     *addressLabels    // nil if address labels are disabled
+	creators   *contractCreators // nil if the creator index is disabled
+	sourcify   *sourcify         // nil if contract metadata is disabled
+	signatures signatures        // function signatures by selector, nil if not loaded
+
+	searchWorkers *semaphore.Weighted // blocks traced at once by searches of all requests, see --ots.search.workers
+	searchCache   *searchCache        // nil if disabled, see --ots.search.cache
+
+	operations *internalOperations // operations of pruned blocks, nil if disabled, see --ots.operations.path
+}
+
+// otsDBLabel - label of the side databases of otterscan methods (--ots.*.path), metrics of chaindata are collected
+// of the databases labeled kv.ChainDB only
+const otsDBLabel = kv.DownloaderDB + 1
+
+// openOtsDB opens a side database of otterscan methods with its own tables: rpcdaemon can't write into chaindata
+func openOtsDB(path string, tables kv.TableCfg, mapSize, growthStep datasize.ByteSize) (kv.RwDB, error) {
+	return mdbx.NewMDBX(log.New()).
+		Path(path).
+		Label(otsDBLabel).
+		WithTableCfg(func(_ kv.TableCfg) kv.TableCfg { return tables }).
+		MapSize(mapSize).
+		GrowthStep(growthStep).
+		Open()
 }
 
 func NewOtterscanAPI(base *BaseAPI, db kv.RoDB) *OtterscanAPIImpl {
 	return &OtterscanAPIImpl{
-		BaseAPI: base,
-		db:      db,
+		BaseAPI:       base,
+		db:            db,
+		searchWorkers: semaphore.NewWeighted(int64(estimate.OtsSearchTrace.Workers())),
 	}
 }
 
+// GetApiLevel - Deprecated: use GetCapabilities
 func (api *OtterscanAPIImpl) GetApiLevel() uint8 {
 	return API_LEVEL
 }
@@ -112,7 +153,7 @@ func (api *OtterscanAPIImpl) runTracer(ctx context.Context, tx kv.Tx, hash commo
 		return nil, err
 	}
 	if txn == nil {
-		return nil, fmt.Errorf("transaction %#x not found", hash)
+		return nil, rpc.NewNotFoundError("transaction", hash)
 	}
 
 	chainConfig, err := api.chainConfig(tx)
@@ -120,10 +161,8 @@ func (api *OtterscanAPIImpl) runTracer(ctx context.Context, tx kv.Tx, hash commo
 		return nil, err
 	}
 
-	getHeader := func(hash common.Hash, number uint64) *types.Header {
-		return rawdb.ReadHeader(tx, hash, number)
-	}
-	msg, blockCtx, txCtx, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, ethash.NewFaker(), tx, blockHash, txIndex)
+	blockHashes := core.NewCanonicalBlockHashes(ctx, tx, api._blockReader)
+	msg, blockCtx, txCtx, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, blockHashes, ethash.NewFaker(), tx, blockHash, txIndex)
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +175,7 @@ func (api *OtterscanAPIImpl) runTracer(ctx context.Context, tx kv.Tx, hash commo
 	}
 	vmenv := vm.NewEVM(blockCtx, txCtx, ibs, chainConfig, vmConfig)
 
-	result, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()), true, false /* gasBailout */)
+	result, err := transactions.ApplyMessage(ctx, vmenv, msg, new(core.GasPool).AddGas(msg.Gas()), true, false /* gasBailout */)
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
@@ -144,6 +183,9 @@ func (api *OtterscanAPIImpl) runTracer(ctx context.Context, tx kv.Tx, hash commo
 	return result, nil
 }
 
+// GetInternalOperations implements ots_getInternalOperations. Transactions of blocks with state history are traced,
+// ones of pruned blocks are read from the index backfilled with --ots.operations.path, an error explains why neither
+// is possible.
 func (api *OtterscanAPIImpl) GetInternalOperations(ctx context.Context, hash common.Hash) ([]*InternalOperation, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
@@ -151,6 +193,9 @@ func (api *OtterscanAPIImpl) GetInternalOperations(ctx context.Context, hash com
 	}
 	defer tx.Rollback()
 
+	if ops, ok, err := api.prunedInternalOperations(ctx, tx, hash); err != nil || ok {
+		return ops, err
+	}
 	tracer := NewOperationsTracer(ctx)
 	if _, err := api.runTracer(ctx, tx, hash, tracer); err != nil {
 		return nil, err
@@ -167,7 +212,22 @@ func (api *OtterscanAPIImpl) GetInternalOperations(ctx context.Context, hash com
 // they are just returned. But it may return a little more than pageSize if there are more txs
 // than the necessary to fill pageSize in the last found block, i.e., let's say you want pageSize == 25,
 // you already found 24 txs, the next block contains 4 matches, then this function will return 28 txs.
-func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+//
+// The optional direction limits the results to transactions calling from the address ("from"), or to it ("to").
+//
+// With the optional cursor, "" for the first page and nextCursor of the previous page for the next ones, blockNum is
+// ignored and pages have exactly pageSize txs: the search continues inside of the block where the previous page ended.
+//
+// With the optional compact flag set, receipts have only the fields address pages show, see compactReceipt.
+func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, direction *SearchDirection, cursor *string, compact *bool) (*TransactionsWithReceipts, error) {
+	dir, err := direction.orBoth()
+	if err != nil {
+		return nil, err
+	}
+	page, err := newSearchPage(cursor, false)
+	if err != nil {
+		return nil, err
+	}
 	dbtx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -192,7 +252,10 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 	}
 
 	isFirstPage := false
-	if blockNum == 0 {
+	if page.resume != nil {
+		blockNum = page.resume.block // the rest of the block where the previous page ended
+	} else if page.exact || blockNum == 0 {
+		blockNum = 0
 		isFirstPage = true
 	} else {
 		// Internal search code considers blockNum [including], so adjust the value
@@ -202,28 +265,20 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 	// Initialize search cursors at the first shard >= desired block number
 	callFromProvider := NewCallCursorBackwardBlockProvider(callFromCursor, addr, blockNum)
 	callToProvider := NewCallCursorBackwardBlockProvider(callToCursor, addr, blockNum)
-	callFromToProvider := newCallFromToBlockProvider(false, callFromProvider, callToProvider)
+	callFromToProvider := dir.blockProvider(false, callFromProvider, callToProvider)
 
 	txs := make([]*RPCTransaction, 0, pageSize)
 	receipts := make([]map[string]interface{}, 0, pageSize)
 
 	resultCount := uint16(0)
-	hasMore := true
+	hasMore, dropped := true, false
 	for {
 		if resultCount >= pageSize || !hasMore {
 			break
 		}
 
-		var results []*TransactionsWithReceipts
-		results, hasMore, err = api.traceBlocks(ctx, addr, chainConfig, pageSize, resultCount, callFromToProvider)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, r := range results {
-			if r == nil {
-				return nil, errors.New("internal error during search tracing")
-			}
+		hasMore, dropped, err = api.traceBlocks(ctx, addr, dir, chainConfig, pageSize, resultCount, callFromToProvider, func(r *TransactionsWithReceipts) (bool, error) {
+			r = page.skipReturned(r)
 
 			for i := len(r.Txs) - 1; i >= 0; i-- {
 				txs = append(txs, r.Txs[i])
@@ -233,13 +288,19 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 			}
 
 			resultCount += uint16(len(r.Txs))
-			if resultCount >= pageSize {
-				break
-			}
+			return resultCount < pageSize, nil
+		})
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return &TransactionsWithReceipts{txs, receipts, isFirstPage, !hasMore}, nil
+	txs, receipts, trimmed := page.trim(txs, receipts, pageSize)
+	more := hasMore || dropped || trimmed
+	if compact != nil && *compact {
+		compactReceipts(receipts)
+	}
+	return &TransactionsWithReceipts{Txs: txs, Receipts: receipts, FirstPage: isFirstPage, LastPage: !more, NextCursor: page.next(txs, more)}, nil
 }
 
 // Search transactions that touch a certain address.
@@ -250,7 +311,22 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 // they are just returned. But it may return a little more than pageSize if there are more txs
 // than the necessary to fill pageSize in the last found block, i.e., let's say you want pageSize == 25,
 // you already found 24 txs, the next block contains 4 matches, then this function will return 28 txs.
-func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+//
+// The optional direction limits the results to transactions calling from the address ("from"), or to it ("to").
+//
+// With the optional cursor, "" for the first page and nextCursor of the previous page for the next ones, blockNum is
+// ignored and pages have exactly pageSize txs: the search continues inside of the block where the previous page ended.
+//
+// With the optional compact flag set, receipts have only the fields address pages show, see compactReceipt.
+func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, direction *SearchDirection, cursor *string, compact *bool) (*TransactionsWithReceipts, error) {
+	dir, err := direction.orBoth()
+	if err != nil {
+		return nil, err
+	}
+	page, err := newSearchPage(cursor, true)
+	if err != nil {
+		return nil, err
+	}
 	dbtx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -275,7 +351,10 @@ func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr c
 	}
 
 	isLastPage := false
-	if blockNum == 0 {
+	if page.resume != nil {
+		blockNum = page.resume.block // the rest of the block where the previous page ended
+	} else if page.exact || blockNum == 0 {
+		blockNum = 0
 		isLastPage = true
 	} else {
 		// Internal search code considers blockNum [including], so adjust the value
@@ -285,78 +364,117 @@ func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr c
 	// Initialize search cursors at the first shard >= desired block number
 	callFromProvider := NewCallCursorForwardBlockProvider(callFromCursor, addr, blockNum)
 	callToProvider := NewCallCursorForwardBlockProvider(callToCursor, addr, blockNum)
-	callFromToProvider := newCallFromToBlockProvider(true, callFromProvider, callToProvider)
+	callFromToProvider := dir.blockProvider(true, callFromProvider, callToProvider)
 
 	txs := make([]*RPCTransaction, 0, pageSize)
 	receipts := make([]map[string]interface{}, 0, pageSize)
 
 	resultCount := uint16(0)
-	hasMore := true
+	hasMore, dropped := true, false
 	for {
 		if resultCount >= pageSize || !hasMore {
 			break
 		}
 
-		var results []*TransactionsWithReceipts
-		results, hasMore, err = api.traceBlocks(ctx, addr, chainConfig, pageSize, resultCount, callFromToProvider)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, r := range results {
-			if r == nil {
-				return nil, errors.New("internal error during search tracing")
-			}
+		hasMore, dropped, err = api.traceBlocks(ctx, addr, dir, chainConfig, pageSize, resultCount, callFromToProvider, func(r *TransactionsWithReceipts) (bool, error) {
+			r = page.skipReturned(r)
 
 			txs = append(txs, r.Txs...)
 			receipts = append(receipts, r.Receipts...)
 
 			resultCount += uint16(len(r.Txs))
-			if resultCount >= pageSize {
-				break
-			}
+			return resultCount < pageSize, nil
+		})
+		if err != nil {
+			return nil, err
 		}
 	}
 
+	txs, receipts, trimmed := page.trim(txs, receipts, pageSize)
+	more := hasMore || dropped || trimmed
+	nextCursor := page.next(txs, more)
+
 	// Reverse results
 	lentxs := len(txs)
 	for i := 0; i < lentxs/2; i++ {
 		txs[i], txs[lentxs-1-i] = txs[lentxs-1-i], txs[i]
 		receipts[i], receipts[lentxs-1-i] = receipts[lentxs-1-i], receipts[i]
 	}
-	return &TransactionsWithReceipts{txs, receipts, !hasMore, isLastPage}, nil
+	if compact != nil && *compact {
+		compactReceipts(receipts)
+	}
+	return &TransactionsWithReceipts{Txs: txs, Receipts: receipts, FirstPage: !more, LastPage: isLastPage, NextCursor: nextCursor}, nil
 }
 
-func (api *OtterscanAPIImpl) traceBlocks(ctx context.Context, addr common.Address, chainConfig *params.ChainConfig, pageSize, resultCount uint16, callFromToProvider BlockProvider) ([]*TransactionsWithReceipts, bool, error) {
-	var wg sync.WaitGroup
+// traceBlocks traces the next blocks of the search concurrently and passes their results to found in search order,
+// each as soon as it and the blocks before it are traced. found returns false when the page is full: tracing of the
+// rest of the blocks is cancelled and dropped reports whether some of them were found. The first failure cancels the
+// tracing of the other blocks and is returned, as is the error of the request's context.
+func (api *OtterscanAPIImpl) traceBlocks(ctx context.Context, addr common.Address, direction SearchDirection, chainConfig *params.ChainConfig, pageSize, resultCount uint16, callFromToProvider BlockProvider, found func(*TransactionsWithReceipts) (bool, error)) (hasMore, dropped bool, err error) {
+	batchCtx, cancel := context.WithCancel(ctx)
+	g, gctx := errgroup.WithContext(batchCtx)
+	defer func() {
+		cancel()
+		_ = g.Wait()
+	}()
+	// tracing of a cancelled request fails with whatever the interrupted step returned, report the cause instead
+	failed := func() error {
+		err := g.Wait()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
 
 	// Estimate the common case of user address having at most 1 interaction/block and
-	// trace N := remaining page matches as number of blocks to trace concurrently.
+	// trace N := remaining page matches as number of blocks, at most searchWorkers of all requests at once.
 	// TODO: this is not optimimal for big contract addresses; implement some better heuristics.
-	estBlocksToTrace := pageSize - resultCount
+	estBlocksToTrace := int(pageSize - resultCount)
 	results := make([]*TransactionsWithReceipts, estBlocksToTrace)
-	totalBlocksTraced := 0
-	hasMore := true
+	errs := make([]error, estBlocksToTrace)
+	traced := make([]chan struct{}, 0, estBlocksToTrace)
+	hasMore = true
 
-	for i := 0; i < int(estBlocksToTrace); i++ {
+	for i := 0; i < estBlocksToTrace; i++ {
+		if gctx.Err() != nil {
+			break // reported by the failed block
+		}
 		var nextBlock uint64
-		var err error
 		nextBlock, hasMore, err = callFromToProvider()
 		if err != nil {
-			return nil, false, err
+			return false, false, err
 		}
 		// TODO: nextBlock == 0 seems redundant with hasMore == false
 		if !hasMore && nextBlock == 0 {
 			break
 		}
 
-		wg.Add(1)
-		totalBlocksTraced++
-		go api.searchTraceBlock(ctx, &wg, addr, chainConfig, i, nextBlock, results)
+		i, done := i, make(chan struct{})
+		traced = append(traced, done)
+		g.Go(func() error {
+			defer close(done)
+			results[i], errs[i] = api.searchTraceBlockPooled(gctx, addr, direction, chainConfig, nextBlock)
+			return errs[i]
+		})
 	}
-	wg.Wait()
 
-	return results[:totalBlocksTraced], hasMore, nil
+	for i, done := range traced {
+		<-done
+		if errs[i] != nil {
+			return false, false, failed()
+		}
+		more, err := found(results[i])
+		if err != nil {
+			return false, false, err
+		}
+		if !more {
+			return hasMore, i < len(traced)-1, nil
+		}
+	}
+	if err := failed(); err != nil {
+		return false, false, err
+	}
+	return hasMore, false, nil
 }
 
 func (api *OtterscanAPIImpl) delegateGetBlockByNumber(tx kv.Tx, b *types.Block, number rpc.BlockNumber, inclTx bool) (map[string]interface{}, error) {
@@ -385,29 +503,32 @@ func (api *OtterscanAPIImpl) delegateGetBlockByNumber(tx kv.Tx, b *types.Block,
 
 // TODO: temporary workaround due to API breakage from watch_the_burn
 type internalIssuance struct {
-	BlockReward string `json:"blockReward,omitempty"`
-	UncleReward string `json:"uncleReward,omitempty"`
-	Issuance    string `json:"issuance,omitempty"`
+	BlockReward string          `json:"blockReward,omitempty"`
+	UncleReward string          `json:"uncleReward,omitempty"`
+	Issuance    string          `json:"issuance,omitempty"`
+	Uncles      []uncleIssuance `json:"uncles,omitempty"` // in the order of the block body, uncleReward is their sum
 }
 
-func (api *OtterscanAPIImpl) delegateIssuance(tx kv.Tx, block *types.Block, chainConfig *params.ChainConfig) (internalIssuance, error) {
-	if chainConfig.Ethash == nil {
-		// Clique for example has no issuance
-		return internalIssuance{}, nil
-	}
+// uncleIssuance - reward of an uncle included by the block, paid to the miner of the uncle
+type uncleIssuance struct {
+	Hash   common.Hash    `json:"hash"`
+	Miner  common.Address `json:"miner"`
+	Reward string         `json:"reward"`
+}
 
-	minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, block.Header(), block.Uncles())
-	issuance := minerReward
-	for _, r := range uncleRewards {
-		p := r // avoids warning?
-		issuance.Add(&issuance, &p)
+func (api *OtterscanAPIImpl) delegateIssuance(tx kv.Tx, block *types.Block, chainConfig *params.ChainConfig) (internalIssuance, error) {
+	uncles := block.Uncles()
+	blockReward, uncleRewards := newIssuanceCalculator(chainConfig).blockRewards(block.Header(), uncles)
+	var ret internalIssuance
+	uncleReward := new(big.Int)
+	for i, r := range uncleRewards {
+		uncleReward.Add(uncleReward, r)
+		ret.Uncles = append(ret.Uncles, uncleIssuance{Hash: uncles[i].Hash(), Miner: uncles[i].Coinbase, Reward: hexutil.EncodeBig(r)})
 	}
 
-	var ret internalIssuance
-	ret.BlockReward = hexutil.EncodeBig(minerReward.ToBig())
-	ret.Issuance = hexutil.EncodeBig(issuance.ToBig())
-	issuance.Sub(&issuance, &minerReward)
-	ret.UncleReward = hexutil.EncodeBig(issuance.ToBig())
+	ret.BlockReward = hexutil.EncodeBig(blockReward)
+	ret.UncleReward = hexutil.EncodeBig(uncleReward)
+	ret.Issuance = hexutil.EncodeBig(uncleReward.Add(uncleReward, blockReward))
 	return ret, nil
 }
 
@@ -448,6 +569,9 @@ func (api *OtterscanAPIImpl) getBlockWithSenders(ctx context.Context, number rpc
 	return block, senders, err
 }
 
+// GetBlockTransactions implements ots_getBlockTransactions. Page 0 is the last pageSize transactions of the block, page 1
+// the ones before them and so on. Only transactions of the page are marshalled, without receipts in the DB the block is
+// executed up to the end of the page.
 func (api *OtterscanAPIImpl) GetBlockTransactions(ctx context.Context, number rpc.BlockNumber, pageNumber uint8, pageSize uint8) (map[string]interface{}, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
@@ -468,53 +592,52 @@ func (api *OtterscanAPIImpl) GetBlockTransactions(ctx context.Context, number rp
 		return nil, err
 	}
 
-	getBlockRes, err := api.delegateGetBlockByNumber(tx, b, number, true)
+	getBlockRes, err := api.delegateGetBlockByNumber(tx, b, number, false)
 	if err != nil {
 		return nil, err
 	}
 
+	// Page
+	pageStart, pageEnd := blockTransactionsPage(b.Transactions().Len(), pageNumber, pageSize)
+
 	// Receipts
-	receipts, err := api.getReceipts(ctx, tx, chainConfig, b, senders)
+	receipts, err := api.getFirstReceipts(ctx, tx, chainConfig, b, senders, pageEnd)
 	if err != nil {
 		return nil, fmt.Errorf("getReceipts error: %v", err)
 	}
-	result := make([]map[string]interface{}, 0, len(receipts))
-	for _, receipt := range receipts {
+	result := make([]map[string]interface{}, 0, pageEnd-pageStart)
+	txs := make([]interface{}, 0, pageEnd-pageStart)
+	for _, receipt := range receipts[pageStart:pageEnd] {
 		txn := b.Transactions()[receipt.TransactionIndex]
 		marshalledRcpt := marshalReceipt(receipt, txn, chainConfig, b, txn.Hash(), true)
 		marshalledRcpt["logs"] = nil
 		marshalledRcpt["logsBloom"] = nil
 		result = append(result, marshalledRcpt)
-	}
-
-	// Pruned block attrs
-	prunedBlock := map[string]interface{}{}
-	for _, k := range []string{"timestamp", "miner", "baseFeePerGas"} {
-		prunedBlock[k] = getBlockRes[k]
-	}
 
-	// Crop tx input to 4bytes
-	var txs = getBlockRes["transactions"].([]interface{})
-	for _, rawTx := range txs {
-		rpcTx := rawTx.(*ethapi.RPCTransaction)
+		// Crop tx input to 4bytes
+		rpcTx := newRPCTransaction(txn, b.Hash(), b.NumberU64(), uint64(receipt.TransactionIndex), b.BaseFee())
 		if len(rpcTx.Input) >= 4 {
 			rpcTx.Input = rpcTx.Input[:4]
 		}
+		txs = append(txs, rpcTx)
 	}
 
-	// Crop page
-	pageEnd := b.Transactions().Len() - int(pageNumber)*int(pageSize)
-	pageStart := pageEnd - int(pageSize)
+	response := map[string]interface{}{}
+	getBlockRes["transactions"] = txs
+	response["fullblock"] = getBlockRes
+	response["receipts"] = result
+	return response, nil
+}
+
+// blockTransactionsPage - indices [pageStart, pageEnd) of the transactions of the page, pages go from the end of the block
+func blockTransactionsPage(txCount int, pageNumber uint8, pageSize uint8) (pageStart, pageEnd int) {
+	pageEnd = txCount - int(pageNumber)*int(pageSize)
+	pageStart = pageEnd - int(pageSize)
 	if pageEnd < 0 {
 		pageEnd = 0
 	}
 	if pageStart < 0 {
 		pageStart = 0
 	}
-
-	response := map[string]interface{}{}
-	getBlockRes["transactions"] = getBlockRes["transactions"].([]interface{})[pageStart:pageEnd]
-	response["fullblock"] = getBlockRes
-	response["receipts"] = result[pageStart:pageEnd]
-	return response, nil
+	return pageStart, pageEnd
 }
@@ -2,12 +2,14 @@ package commands
 
 import (
 	"context"
-	"fmt"
 
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
 func (api *OtterscanAPIImpl) GetBlockDetails(ctx context.Context, number rpc.BlockNumber) (map[string]interface{}, error) {
@@ -24,7 +26,33 @@ func (api *OtterscanAPIImpl) GetBlockDetails(ctx context.Context, number rpc.Blo
 	if b == nil {
 		return nil, nil
 	}
+	return api.blockDetails(ctx, tx, b, senders, number)
+}
+
+// GetBlockDetailsByHash implements ots_getBlockDetailsByHash. Unlike ots_getBlockDetails it serves non-canonical blocks
+// too (reorged blocks, uncles downloaded as blocks), "canonical" of the result tells them apart.
+func (api *OtterscanAPIImpl) GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNumber := rawdb.ReadHeaderNumber(tx, hash)
+	if blockNumber == nil {
+		return nil, nil // e.g. an uncle which was never downloaded as a block
+	}
+	b, senders, err := api._blockReader.BlockWithSenders(ctx, tx, hash, *blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	return api.blockDetails(ctx, tx, b, senders, rpc.BlockNumber(b.Number().Int64()))
+}
 
+func (api *OtterscanAPIImpl) blockDetails(ctx context.Context, tx kv.Tx, b *types.Block, senders []common.Address, number rpc.BlockNumber) (map[string]interface{}, error) {
 	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
 		return nil, err
@@ -42,56 +70,75 @@ func (api *OtterscanAPIImpl) GetBlockDetails(ctx context.Context, number rpc.Blo
 	if err != nil {
 		return nil, err
 	}
+	canonicalHash, err := rawdb.ReadCanonicalHash(tx, b.NumberU64())
+	if err != nil {
+		return nil, err
+	}
 
 	response := map[string]interface{}{}
 	response["block"] = getBlockRes
 	response["issuance"] = getIssuanceRes
 	response["totalFees"] = hexutil.Uint64(feesRes)
+	response["totalBurnt"] = (*hexutil.Big)(blockBurnt(b.Header()))
+	response["canonical"] = canonicalHash == b.Hash()
 	return response, nil
 }
 
-// TODO: remove duplication with GetBlockDetails
-func (api *OtterscanAPIImpl) GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+// BlockTransactionsCount is the result of ots_getBlockTransactionsCount: basic header data and counts
+// of a block, read without loading its transactions
+type BlockTransactionsCount struct {
+	Number           hexutil.Uint64 `json:"number"`
+	Hash             common.Hash    `json:"hash"`
+	ParentHash       common.Hash    `json:"parentHash"`
+	Timestamp        hexutil.Uint64 `json:"timestamp"`
+	Miner            common.Address `json:"miner"`
+	GasUsed          hexutil.Uint64 `json:"gasUsed"`
+	GasLimit         hexutil.Uint64 `json:"gasLimit"`
+	BaseFee          *hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	TransactionCount hexutil.Uint64 `json:"transactionCount"`
+	UncleCount       hexutil.Uint64 `json:"uncleCount"`
+}
+
+// GetBlockTransactionsCount implements ots_getBlockTransactionsCount. It accepts both block numbers and hashes.
+func (api *OtterscanAPIImpl) GetBlockTransactionsCount(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*BlockTransactionsCount, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	// b, senders, err := rawdb.ReadBlockByHashWithSenders(tx, hash)
-	blockNumber := rawdb.ReadHeaderNumber(tx, hash)
-	if blockNumber == nil {
-		return nil, fmt.Errorf("couldn't find block number for hash %v", hash.Bytes())
-	}
-	b, senders, err := api._blockReader.BlockWithSenders(ctx, tx, hash, *blockNumber)
+	blockNum, hash, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
 	if err != nil {
 		return nil, err
 	}
-	if b == nil {
-		return nil, nil
-	}
-
-	chainConfig, err := api.chainConfig(tx)
+	header, err := api._blockReader.Header(ctx, tx, hash, blockNum)
 	if err != nil {
 		return nil, err
 	}
-
-	getBlockRes, err := api.delegateGetBlockByNumber(tx, b, rpc.BlockNumber(b.Number().Int64()), false)
-	if err != nil {
-		return nil, err
+	if header == nil {
+		return nil, nil
 	}
-	getIssuanceRes, err := api.delegateIssuance(tx, b, chainConfig)
+	body, txAmount, err := api._blockReader.Body(ctx, tx, hash, blockNum)
 	if err != nil {
 		return nil, err
 	}
-	feesRes, err := api.delegateBlockFees(ctx, tx, b, senders, chainConfig)
-	if err != nil {
-		return nil, err
+	if body == nil {
+		return nil, nil
 	}
 
-	response := map[string]interface{}{}
-	response["block"] = getBlockRes
-	response["issuance"] = getIssuanceRes
-	response["totalFees"] = hexutil.Uint64(feesRes)
-	return response, nil
+	res := &BlockTransactionsCount{
+		Number:           hexutil.Uint64(blockNum),
+		Hash:             hash,
+		ParentHash:       header.ParentHash,
+		Timestamp:        hexutil.Uint64(header.Time),
+		Miner:            header.Coinbase,
+		GasUsed:          hexutil.Uint64(header.GasUsed),
+		GasLimit:         hexutil.Uint64(header.GasLimit),
+		TransactionCount: hexutil.Uint64(txAmount),
+		UncleCount:       hexutil.Uint64(len(body.Uncles)),
+	}
+	if header.BaseFee != nil {
+		res.BaseFee = (*hexutil.Big)(header.BaseFee)
+	}
+	return res, nil
 }
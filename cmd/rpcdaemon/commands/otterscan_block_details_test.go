@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBlockTransactionsCount(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	ctx := context.Background()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+	blockHash := common.HexToHash("0x6804117de2f3e6ee32953e78ced1db7b20214e0d8c745a03b8fecf7cc8ee76ef")
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	header, err := rawdb.ReadHeaderByHash(tx, blockHash)
+	require.NoError(t, err)
+	body, err := rawdb.ReadBodyWithTransactions(tx, blockHash, header.Number.Uint64())
+	require.NoError(t, err)
+	tx.Rollback()
+
+	byHash, err := api.GetBlockTransactionsCount(ctx, rpc.BlockNumberOrHashWithHash(blockHash, false))
+	require.NoError(t, err)
+	require.Equal(t, blockHash, byHash.Hash)
+	require.Equal(t, hexutil.Uint64(header.Number.Uint64()), byHash.Number)
+	require.Equal(t, hexutil.Uint64(len(body.Transactions)), byHash.TransactionCount)
+	require.Equal(t, hexutil.Uint64(len(body.Uncles)), byHash.UncleCount)
+
+	byNumber, err := api.GetBlockTransactionsCount(ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(header.Number.Int64())))
+	require.NoError(t, err)
+	require.Equal(t, byHash, byNumber)
+
+	details, err := api.GetBlockDetailsByHash(ctx, blockHash)
+	require.NoError(t, err)
+	require.Equal(t, len(body.Transactions), details["block"].(map[string]interface{})["transactionCount"])
+	require.Equal(t, (*hexutil.Big)(blockBurnt(header)), details["totalBurnt"])
+}
+
+func TestGetBlockDetailsByHash(t *testing.T) {
+	m, _, orphanedChain := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	ctx := context.Background()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+
+	canonical, err := api.GetBlockDetails(ctx, rpc.BlockNumber(1))
+	require.NoError(t, err)
+	require.Equal(t, true, canonical["canonical"])
+	byHash, err := api.GetBlockDetailsByHash(ctx, canonical["block"].(map[string]interface{})["hash"].(common.Hash))
+	require.NoError(t, err)
+	require.Equal(t, canonical, byHash)
+
+	// reorged away block of the same height
+	orphan := orphanedChain[0].Blocks[0]
+	details, err := api.GetBlockDetailsByHash(ctx, orphan.Hash())
+	require.NoError(t, err)
+	require.NotNil(t, details)
+	require.Equal(t, false, details["canonical"])
+	require.Equal(t, orphan.Hash(), details["block"].(map[string]interface{})["hash"])
+	require.Equal(t, hexutil.Uint64(0), details["totalFees"])
+
+	// the state after its reorged parent isn't there to execute it
+	_, err = api.GetBlockDetailsByHash(ctx, orphanedChain[0].Blocks[1].Hash())
+	require.ErrorContains(t, err, "non-canonical parent")
+
+	details, err = api.GetBlockDetailsByHash(ctx, common.HexToHash("0x01"))
+	require.NoError(t, err)
+	require.Nil(t, details)
+}
+
+func TestGetBlockTransactions(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	ctx := context.Background()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	var number uint64
+	var block *types.Block
+	for number = 1; ; number++ {
+		block, err = rawdb.ReadBlockByNumber(tx, number)
+		require.NoError(t, err)
+		require.NotNil(t, block, "no block with 2 transactions")
+		if block.Transactions().Len() >= 2 {
+			break
+		}
+	}
+	txs := block.Transactions()
+
+	for page, txIndex := range []int{txs.Len() - 1, txs.Len() - 2} {
+		res, err := api.GetBlockTransactions(ctx, rpc.BlockNumber(number), uint8(page), 1)
+		require.NoError(t, err)
+		pageTxs := res["fullblock"].(map[string]interface{})["transactions"].([]interface{})
+		require.Len(t, pageTxs, 1)
+		require.Equal(t, txs[txIndex].Hash(), pageTxs[0].(*RPCTransaction).Hash)
+		receipts := res["receipts"].([]map[string]interface{})
+		require.Len(t, receipts, 1)
+		require.Equal(t, txs[txIndex].Hash(), receipts[0]["transactionHash"])
+	}
+
+	res, err := api.GetBlockTransactions(ctx, rpc.BlockNumber(number), uint8(txs.Len()), 1)
+	require.NoError(t, err)
+	require.Empty(t, res["receipts"])
+}
+
+func TestBlockTransactionsPage(t *testing.T) {
+	for _, tt := range []struct {
+		txCount, pageNumber, pageSize, start, end int
+	}{
+		{txCount: 10, pageNumber: 0, pageSize: 4, start: 6, end: 10},
+		{txCount: 10, pageNumber: 1, pageSize: 4, start: 2, end: 6},
+		{txCount: 10, pageNumber: 2, pageSize: 4, start: 0, end: 2},
+		{txCount: 10, pageNumber: 3, pageSize: 4, start: 0, end: 0},
+		{txCount: 0, pageNumber: 0, pageSize: 25, start: 0, end: 0},
+	} {
+		start, end := blockTransactionsPage(tt.txCount, uint8(tt.pageNumber), uint8(tt.pageSize))
+		require.Equal(t, tt.start, start, "%+v", tt)
+		require.Equal(t, tt.end, end, "%+v", tt)
+	}
+}
+
+func TestDelegateIssuanceUncles(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(100), Difficulty: big.NewInt(1)}
+	uncles := []*types.Header{
+		{Number: big.NewInt(99), Coinbase: common.Address{1}},
+		{Number: big.NewInt(98), Coinbase: common.Address{2}},
+	}
+	block := types.NewBlockWithHeader(header).WithBody(nil, uncles)
+	api := &OtterscanAPIImpl{}
+
+	issuance, err := api.delegateIssuance(nil, block, params.MainnetChainConfig)
+	require.NoError(t, err)
+	blockReward, uncleRewards := blockIssuance(params.MainnetChainConfig, header, uncles)
+	require.Equal(t, []uncleIssuance{
+		{Hash: uncles[0].Hash(), Miner: common.Address{1}, Reward: hexutil.EncodeBig(uncleRewards[0])},
+		{Hash: uncles[1].Hash(), Miner: common.Address{2}, Reward: hexutil.EncodeBig(uncleRewards[1])},
+	}, issuance.Uncles)
+	require.Equal(t, hexutil.EncodeBig(new(big.Int).Add(uncleRewards[0], uncleRewards[1])), issuance.UncleReward)
+	require.Equal(t, hexutil.EncodeBig(blockReward), issuance.BlockReward)
+
+	// uncles of chains whose engine mints nothing are listed with zero rewards
+	issuance, err = api.delegateIssuance(nil, block, params.GoerliChainConfig)
+	require.NoError(t, err)
+	require.Len(t, issuance.Uncles, 2)
+	require.Equal(t, "0x0", issuance.Uncles[1].Reward)
+
+	issuance, err = api.delegateIssuance(nil, types.NewBlockWithHeader(header), params.MainnetChainConfig)
+	require.NoError(t, err)
+	require.Empty(t, issuance.Uncles)
+}
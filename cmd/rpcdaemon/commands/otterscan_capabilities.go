@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"unicode"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
+)
+
+// OtsCapabilities - what this node can answer, frontends should detect features by it instead of the api level
+type OtsCapabilities struct {
+	ApiLevel uint8       `json:"apiLevel"` // Deprecated: kept for frontends which still compare levels
+	Methods  []string    `json:"methods"`  // Supported ots_ methods
+	Indices  []OtsIndex  `json:"indices"`
+	Limits   OtsLimits   `json:"limits"`
+	Features OtsFeatures `json:"features"`
+}
+
+// OtsIndex - availability of the data behind ots_ methods: index is built up to Progress,
+// blocks before AvailableFrom are pruned. Disabled indices aren't built by the --sync.mode of the node.
+type OtsIndex struct {
+	Name          string         `json:"name"`
+	Enabled       bool           `json:"enabled"`
+	Progress      hexutil.Uint64 `json:"progress"`
+	AvailableFrom hexutil.Uint64 `json:"availableFrom"`
+}
+
+type OtsLimits struct {
+	MaxSearchPageSize           uint64 `json:"maxSearchPageSize"`           // ots_searchTransactionsBefore/After
+	MaxBlockTransactionPageSize uint64 `json:"maxBlockTransactionPageSize"` // ots_getBlockTransactions
+	EvmCallTimeoutMs            uint64 `json:"evmCallTimeoutMs"`            // 0 - unlimited
+}
+
+type OtsFeatures struct {
+	AddressLabels    bool `json:"addressLabels"`    // ots_getAddressMetadata is served, see --ots.labels.path
+	ContractMetadata bool `json:"contractMetadata"` // ots_getContractMetadata is served, see --ots.sourcify.source
+	CalldataDecoding bool `json:"calldataDecoding"` // ots_decodeCalldata is served, see --ots.signatures.path
+	HistoryV3        bool `json:"historyV3"`
+	SearchDirection  bool `json:"searchDirection"` // ots_searchTransactionsBefore/After take the direction parameter
+	SearchCursor     bool `json:"searchCursor"`    // ots_searchTransactionsBefore/After take the cursor parameter
+	SearchStream     bool `json:"searchStream"`    // the search can be streamed with ots_subscribe
+	SearchCompact    bool `json:"searchCompact"`   // ots_searchTransactionsBefore/After take the compact parameter
+	SearchCache      bool `json:"searchCache"`     // matches of traced blocks are cached, see --ots.search.cache
+	SearchLive       bool `json:"searchLive"`      // matches in new blocks can be followed with ots_subscribe
+}
+
+// otsIndices - indices used by ots_ methods, with the stage which builds each and the prune mode which deletes it
+var otsIndices = []struct {
+	name  string
+	stage stages.SyncStage
+	prune func(prune2.Mode) prune2.BlockAmount
+}{
+	{"accountHistory", stages.AccountHistoryIndex, func(m prune2.Mode) prune2.BlockAmount { return m.History }},
+	{"storageHistory", stages.StorageHistoryIndex, func(m prune2.Mode) prune2.BlockAmount { return m.History }},
+	{"callTraces", stages.CallTraces, func(m prune2.Mode) prune2.BlockAmount { return m.CallTraces }},
+	{"logs", stages.LogIndex, func(m prune2.Mode) prune2.BlockAmount { return m.Receipts }},
+	{"tokenTransfers", stages.TokenTransfers, func(m prune2.Mode) prune2.BlockAmount { return m.Receipts }},
+	{"receipts", stages.Execution, func(m prune2.Mode) prune2.BlockAmount { return m.Receipts }},
+	{"txLookup", stages.TxLookup, func(m prune2.Mode) prune2.BlockAmount { return m.TxIndex }},
+}
+
+// GetCapabilities implements ots_getCapabilities
+func (api *OtterscanAPIImpl) GetCapabilities(ctx context.Context) (*OtsCapabilities, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	indices, err := readOtsIndices(tx)
+	if err != nil {
+		return nil, err
+	}
+	operations, err := api.operationsIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indices = append(indices, operations)
+	caps := &OtsCapabilities{
+		ApiLevel: API_LEVEL,
+		Methods:  otsMethods(api.labels != nil, api.sourcify != nil, api.calldataDecoding()),
+		Indices:  indices,
+		Limits: OtsLimits{
+			MaxSearchPageSize:           math.MaxUint16,
+			MaxBlockTransactionPageSize: math.MaxUint8,
+			EvmCallTimeoutMs:            uint64(api.evmCallTimeout.Milliseconds()),
+		},
+		Features: OtsFeatures{
+			AddressLabels:    api.labels != nil,
+			ContractMetadata: api.sourcify != nil,
+			CalldataDecoding: api.calldataDecoding(),
+			HistoryV3:        api.historyV3(tx),
+			SearchDirection:  true,
+			SearchCursor:     true,
+			SearchStream:     true,
+			SearchCompact:    true,
+			SearchCache:      api.searchCache != nil,
+			SearchLive:       api.filters != nil,
+		},
+	}
+	return caps, nil
+}
+
+func readOtsIndices(tx kv.Tx) ([]OtsIndex, error) {
+	pm, err := prune2.Get(tx)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := stages.ReadMode(tx)
+	if err != nil {
+		return nil, err
+	}
+	disabled := map[stages.SyncStage]bool{}
+	for _, stage := range mode.Disabled() {
+		disabled[stage] = true
+	}
+	indices := make([]OtsIndex, 0, len(otsIndices))
+	for _, idx := range otsIndices {
+		progress, err := stages.GetStageProgress(tx, idx.stage)
+		if err != nil {
+			return nil, err
+		}
+		var availableFrom uint64
+		if amount := idx.prune(pm); amount.Enabled() {
+			availableFrom = amount.PruneTo(progress)
+		}
+		indices = append(indices, OtsIndex{Name: idx.name, Enabled: !disabled[idx.stage], Progress: hexutil.Uint64(progress), AvailableFrom: hexutil.Uint64(availableFrom)})
+	}
+	return indices, nil
+}
+
+// operationsIndex - blocks backfilled into the index of internal operations of pruned blocks
+func (api *OtterscanAPIImpl) operationsIndex(ctx context.Context) (OtsIndex, error) {
+	idx := OtsIndex{Name: "internalOperations", Enabled: api.operations != nil}
+	if api.operations == nil {
+		return idx, nil
+	}
+	err := api.operations.db.View(ctx, func(tx kv.Tx) error {
+		from, to, ok, err := api.operations.backfilled(tx)
+		if ok {
+			idx.Progress, idx.AvailableFrom = hexutil.Uint64(to), hexutil.Uint64(from)
+		}
+		return err
+	})
+	return idx, err
+}
+
+// calldataDecoding - ots_decodeCalldata has ABIs or signatures to decode with
+func (api *OtterscanAPIImpl) calldataDecoding() bool {
+	return api.sourcify != nil || api.signatures != nil
+}
+
+// otsMethods - names of OtterscanAPI methods the way rpc server registers them, without the disabled ones
+func otsMethods(addressLabels, contractMetadata, calldataDecoding bool) []string {
+	disabled := map[string]bool{
+		"getAddressMetadata":  !addressLabels,
+		"getContractMetadata": !contractMetadata,
+		"decodeCalldata":      !calldataDecoding,
+	}
+	t := reflect.TypeOf((*OtterscanAPI)(nil)).Elem()
+	methods := make([]string, 0, t.NumMethod())
+	for i := 0; i < t.NumMethod(); i++ {
+		name := []rune(t.Method(i).Name)
+		name[0] = unicode.ToLower(name[0])
+		if disabled[string(name)] {
+			continue
+		}
+		methods = append(methods, "ots_"+string(name))
+	}
+	sort.Strings(methods)
+	return methods
+}
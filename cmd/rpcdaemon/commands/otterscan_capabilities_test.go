@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	ctx := context.Background()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+
+	caps, err := api.GetCapabilities(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint8(API_LEVEL), caps.ApiLevel)
+	require.Contains(t, caps.Methods, "ots_getCapabilities")
+	require.Contains(t, caps.Methods, "ots_searchTransactionsBefore")
+	require.NotContains(t, caps.Methods, "ots_getAddressMetadata") // labels are disabled
+	require.False(t, caps.Features.AddressLabels)
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	progress, err := stages.GetStageProgress(tx, stages.AccountHistoryIndex)
+	require.NoError(t, err)
+	require.Len(t, caps.Indices, len(otsIndices)+1)
+	require.Equal(t, OtsIndex{Name: "accountHistory", Enabled: true, Progress: hexutil.Uint64(progress)}, caps.Indices[0])
+	require.Equal(t, OtsIndex{Name: "internalOperations"}, caps.Indices[len(otsIndices)]) // --ots.operations.path isn't set
+	require.Equal(t, api.searchCache != nil, caps.Features.SearchCache)
+	tx.Rollback()
+
+	// indices the sync mode doesn't build
+	require.NoError(t, m.DB.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.DatabaseInfo, stages.ModeKey, []byte(stages.ModeState))
+	}))
+	caps, err = api.GetCapabilities(ctx)
+	require.NoError(t, err)
+	for _, idx := range caps.Indices {
+		require.Equal(t, idx.Name == "receipts", idx.Enabled, idx.Name)
+	}
+}
@@ -1,17 +1,16 @@
 package commands
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"sort"
 
-	"github.com/RoaringBitmap/roaring/roaring64"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
-	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
-	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/log/v3"
 )
 
@@ -20,6 +19,9 @@ type ContractCreatorData struct {
 	Creator common.Address `json:"creator"`
 }
 
+// GetContractCreator implements ots_getContractCreator. Returns the transaction which created the current incarnation
+// of the contract and its creator, null for accounts without code and contracts of the genesis.
+// With --ots.creators.path found creators are indexed, repeated lookups don't replay anything.
 func (api *OtterscanAPIImpl) GetContractCreator(ctx context.Context, addr common.Address) (*ContractCreatorData, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
@@ -43,127 +45,87 @@ func (api *OtterscanAPIImpl) GetContractCreator(ctx context.Context, addr common
 		return nil, nil
 	}
 
-	// Contract; search for creation tx; navigate forward on AccountsHistory/ChangeSets
-	//
-	// We search shards in forward order on purpose because popular contracts may have
-	// dozens of states changes due to ETH deposits/withdraw after contract creation,
-	// so it is optimal to search from the beginning even if the contract has multiple
-	// incarnations.
-	accHistory, err := tx.Cursor(kv.AccountsHistory)
-	if err != nil {
-		return nil, err
+	if api.creators != nil {
+		indexed, err := api.creators.get(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		if indexed != nil && indexed.Incarnation == plainStateAcc.Incarnation {
+			canonical, err := rawdb.ReadCanonicalHash(tx, indexed.BlockNum)
+			if err != nil {
+				return nil, err
+			}
+			if canonical == indexed.BlockHash {
+				return &indexed.ContractCreatorData, nil
+			}
+		}
 	}
-	defer accHistory.Close()
 
-	accCS, err := tx.CursorDupSort(kv.AccountChangeSet)
+	blockFound, err := api.contractCreationBlock(ctx, tx, addr, plainStateAcc.Incarnation)
 	if err != nil {
 		return nil, err
 	}
-	defer accCS.Close()
 
-	// Locate shard that contains the block where incarnation changed
-	acs := changeset.Mapper[kv.AccountChangeSet]
-	k, v, err := accHistory.Seek(acs.IndexChunkKey(addr.Bytes(), 0))
+	// Trace block, find tx and contract creator
+	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
 		return nil, err
 	}
-	if !bytes.HasPrefix(k, addr.Bytes()) {
-		log.Error("Couldn't find any shard for account history", "addr", addr)
-		return nil, fmt.Errorf("could't find any shard for account history addr=%v", addr)
+	tracer := NewCreateTracer(ctx, addr)
+	if err := api.genericTracer(tx, ctx, blockFound, chainConfig, tracer); err != nil {
+		return nil, err
+	}
+	if !tracer.Found() {
+		return nil, nil // allocated in the genesis
+	}
+	creator := &ContractCreatorData{
+		Tx:      tracer.Tx.Hash(),
+		Creator: tracer.Creator,
 	}
 
-	var acc accounts.Account
-	bm := roaring64.NewBitmap()
-	prevShardMaxBl := uint64(0)
-	for {
-		_, err := bm.ReadFrom(bytes.NewReader(v))
-		if err != nil {
-			return nil, err
-		}
-
-		// Shortcut precheck
-		st, err := acs.Find(accCS, bm.Maximum(), addr.Bytes())
-		if err != nil {
-			return nil, err
-		}
-		if st == nil {
-			log.Error("Unexpected error, couldn't find changeset", "block", bm.Maximum(), "addr", addr)
-			return nil, fmt.Errorf("unexpected error, couldn't find changeset block=%v addr=%v", bm.Maximum(), addr)
-		}
-
-		// Found the shard where the incarnation change happens; ignore all
-		// next shards
-		if err := acc.DecodeForStorage(st); err != nil {
-			return nil, err
-		}
-		if acc.Incarnation >= plainStateAcc.Incarnation {
-			break
-		}
-		prevShardMaxBl = bm.Maximum()
-
-		k, v, err = accHistory.Next()
+	if api.creators != nil {
+		blockHash, err := rawdb.ReadCanonicalHash(tx, blockFound)
 		if err != nil {
 			return nil, err
 		}
-
-		// No more shards; it means the max bl from previous shard
-		// contains the incarnation change
-		if !bytes.HasPrefix(k, addr.Bytes()) {
-			break
+		creation := &contractCreation{ContractCreatorData: *creator, Incarnation: plainStateAcc.Incarnation, BlockNum: blockFound, BlockHash: blockHash}
+		if err := api.creators.put(ctx, addr, creation); err != nil {
+			log.Warn("Failed to index contract creator", "addr", addr, "err", err)
 		}
 	}
+	return creator, nil
+}
 
-	// Binary search block number inside shard; get first block where desired
-	// incarnation appears
-	blocks := bm.ToArray()
+// contractCreationBlock - the block which created the incarnation of the contract: binary search of the first block
+// after which the account has it. Works the same with history of both layouts, a few dozens of history reads.
+func (api *OtterscanAPIImpl) contractCreationBlock(ctx context.Context, tx kv.Tx, addr common.Address, incarnation uint64) (uint64, error) {
+	head, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return 0, err
+	}
+	historyV3 := api.historyV3(tx)
 	var searchErr error
-	r := sort.Search(len(blocks), func(i int) bool {
-		bl := blocks[i]
-		st, err := acs.Find(accCS, bl, addr.Bytes())
-		if err != nil {
-			searchErr = err
+	found := sort.Search(int(head), func(i int) bool {
+		if searchErr != nil {
 			return false
 		}
-		if st == nil {
-			log.Error("Unexpected error, couldn't find changeset", "block", bl, "addr", addr)
+		if searchErr = ctx.Err(); searchErr != nil {
 			return false
 		}
-
-		if err := acc.DecodeForStorage(st); err != nil {
+		reader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(i)), api.filters, api.stateCache, api.recentStates, historyV3, api._agg)
+		if err != nil {
 			searchErr = err
 			return false
 		}
-		if acc.Incarnation < plainStateAcc.Incarnation {
+		acc, err := reader.ReadAccountData(addr)
+		if err != nil {
+			searchErr = err
 			return false
 		}
-		return true
+		return acc != nil && acc.Incarnation >= incarnation
 	})
-
 	if searchErr != nil {
-		return nil, searchErr
-	}
-
-	// The sort.Search function finds the first block where the incarnation has
-	// changed to the desired one, so we get the previous block from the bitmap;
-	// however if the found block is already the first one from the bitmap, it means
-	// the block we want is the max block from the previous shard.
-	blockFound := prevShardMaxBl
-	if r > 0 {
-		blockFound = blocks[r-1]
+		return 0, searchErr
 	}
-
-	// Trace block, find tx and contract creator
-	chainConfig, err := api.chainConfig(tx)
-	if err != nil {
-		return nil, err
-	}
-	tracer := NewCreateTracer(ctx, addr)
-	if err := api.genericTracer(tx, ctx, blockFound, chainConfig, tracer); err != nil {
-		return nil, err
-	}
-
-	return &ContractCreatorData{
-		Tx:      tracer.Tx.Hash(),
-		Creator: tracer.Creator,
-	}, nil
+	return uint64(found), nil
 }
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// OtsContractCreators - address -> JSON of contractCreation. Index of creators found by ots_getContractCreator, lives
+// in its own small DB (--ots.creators.path) as OtsAddressLabels: rpcdaemon can't write into Erigon's chaindata.
+const OtsContractCreators = "OtsContractCreators"
+
+// contractCreation - creator of an incarnation of a contract, valid while BlockHash is canonical
+type contractCreation struct {
+	ContractCreatorData
+	Incarnation uint64      `json:"incarnation"`
+	BlockNum    uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+}
+
+type contractCreators struct {
+	db kv.RwDB
+}
+
+func openContractCreators(path string) (*contractCreators, error) {
+	db, err := openOtsDB(path, kv.TableCfg{OtsContractCreators: {}}, 4*datasize.GB, 16*datasize.MB)
+	if err != nil {
+		return nil, fmt.Errorf("open contract creators db %s: %w", path, err)
+	}
+	return &contractCreators{db: db}, nil
+}
+
+func (c *contractCreators) get(ctx context.Context, addr common.Address) (*contractCreation, error) {
+	var creation *contractCreation
+	if err := c.db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(OtsContractCreators, addr.Bytes())
+		if err != nil || v == nil {
+			return err
+		}
+		creation = new(contractCreation)
+		return json.Unmarshal(v, creation)
+	}); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+// put replaces the indexed creation of the address: of an older incarnation, or of a reorged block
+func (c *contractCreators) put(ctx context.Context, addr common.Address, creation *contractCreation) error {
+	v, err := json.Marshal(creation)
+	if err != nil {
+		return err
+	}
+	return c.db.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(OtsContractCreators, addr.Bytes(), v)
+	})
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetContractCreator(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
+	api := NewOtterscanAPI(base, m.DB)
+	creators, err := openContractCreators(filepath.Join(t.TempDir(), "creators"))
+	require.NoError(err)
+	defer creators.db.Close()
+	api.creators = creators
+
+	// contracts deployed by transactions of the test chain, which still have code
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(err)
+	defer tx.Rollback()
+	signer := types.LatestSignerForChainID(m.ChainConfig.ChainID)
+	reader := state.NewPlainStateReader(tx)
+	expected := map[common.Address]ContractCreatorData{}
+	for number := uint64(1); ; number++ {
+		block, err := rawdb.ReadBlockByNumber(tx, number)
+		require.NoError(err)
+		if block == nil {
+			break
+		}
+		for _, txn := range block.Transactions() {
+			if txn.GetTo() != nil {
+				continue
+			}
+			sender, err := txn.Sender(*signer)
+			require.NoError(err)
+			contract := crypto.CreateAddress(sender, txn.GetNonce())
+			acc, err := reader.ReadAccountData(contract)
+			require.NoError(err)
+			if acc != nil && !acc.IsEmptyCodeHash() {
+				expected[contract] = ContractCreatorData{Tx: txn.Hash(), Creator: sender}
+			}
+		}
+	}
+	require.NotEmpty(expected)
+
+	for contract, want := range expected {
+		creator, err := api.GetContractCreator(ctx, contract)
+		require.NoError(err)
+		require.Equal(&want, creator, "contract %x", contract)
+
+		indexed, err := creators.get(ctx, contract)
+		require.NoError(err)
+		require.NotNil(indexed)
+		require.Equal(want, indexed.ContractCreatorData)
+
+		// an entry of a reorged block is ignored and replaced
+		stale := *indexed
+		stale.BlockHash = common.Hash{1}
+		stale.Creator = common.Address{1}
+		require.NoError(creators.put(ctx, contract, &stale))
+		creator, err = api.GetContractCreator(ctx, contract)
+		require.NoError(err)
+		require.Equal(&want, creator, "contract %x", contract)
+	}
+
+	creator, err := api.GetContractCreator(ctx, common.HexToAddress("0x1234"))
+	require.NoError(err)
+	require.Nil(creator)
+}
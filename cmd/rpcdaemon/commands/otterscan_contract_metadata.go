@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
+)
+
+// OtsContractMetadata - chainID (8 bytes) + address -> JSON of cachedContractMetadata. Lives in its own small DB
+// (--ots.sourcify.cache) as OtsAddressLabels: rpcdaemon can't write into Erigon's chaindata.
+const OtsContractMetadata = "OtsContractMetadata"
+
+const (
+	sourcifyMissTTL     = time.Hour        // unverified contracts are looked up in the source again after it
+	sourcifyMaxFileSize = 16 * 1024 * 1024 // metadata or source file
+	sourcifyTimeout     = 10 * time.Second // of a file download
+	sourcifyMaxFetches  = 4                // contracts downloaded at once by all requests
+	sourcifyRecentSize  = 1024             // contracts, found or not, kept in memory in front of the cache DB
+)
+
+// ContractMetadata - verified contract from a Sourcify repository: the solc metadata.json and what is derived from it
+type ContractMetadata struct {
+	Match    string                 `json:"match"` // "full" or "partial" match of Sourcify
+	Language string                 `json:"language"`
+	Compiler string                 `json:"compiler"`
+	Name     string                 `json:"name"` // the compilation target
+	ABI      json.RawMessage        `json:"abi"`
+	Methods  map[string]string      `json:"methods"` // 4-byte selector -> signature, to decode calldata
+	Events   map[common.Hash]string `json:"events"`  // topic -> signature, to decode logs
+	Sources  []ContractSource       `json:"sources"`
+	Metadata json.RawMessage        `json:"metadata"`
+}
+
+type ContractSource struct {
+	Path    string `json:"path"`
+	License string `json:"license,omitempty"`
+	Content string `json:"content,omitempty"` // only if sources are requested and found
+}
+
+// solcMetadata - the part of the solc metadata.json used here, see https://docs.soliditylang.org/en/latest/metadata.html
+type solcMetadata struct {
+	Compiler struct {
+		Version string `json:"version"`
+	} `json:"compiler"`
+	Language string `json:"language"`
+	Output   struct {
+		ABI json.RawMessage `json:"abi"`
+	} `json:"output"`
+	Settings struct {
+		CompilationTarget map[string]string `json:"compilationTarget"`
+	} `json:"settings"`
+	Sources map[string]struct {
+		License string  `json:"license"`
+		Content *string `json:"content"`
+	} `json:"sources"`
+}
+
+// cachedContractMetadata - Metadata is nil if the contract wasn't found at Fetched (unix time)
+type cachedContractMetadata struct {
+	Metadata *ContractMetadata `json:"metadata"`
+	Fetched  int64             `json:"fetched"`
+}
+
+// expired - contracts not found are looked up again after sourcifyMissTTL, they may have been verified since
+func (c *cachedContractMetadata) expired() bool {
+	return c.Metadata == nil && time.Since(time.Unix(c.Fetched, 0)) >= sourcifyMissTTL
+}
+
+// sourcify - contracts verified by Sourcify: a directory with the layout of its repository
+// (contracts/{full,partial}_match/<chainID>/<address>/{metadata.json,sources/}) or the base URL of one, e.g.
+// https://repo.sourcify.dev
+type sourcify struct {
+	source   string
+	client   *http.Client
+	cache    kv.RwDB            // nil - only recent contracts are kept, in memory
+	recent   *lru.Cache         // string(contractMetadataKey) -> *cachedContractMetadata
+	inflight singleflight.Group // concurrent requests of the same contract share its download
+	fetches  *semaphore.Weighted
+}
+
+func openSourcify(source, cachePath string) (*sourcify, error) {
+	recent, err := lru.New(sourcifyRecentSize)
+	if err != nil {
+		return nil, err
+	}
+	s := &sourcify{
+		source:  strings.TrimSuffix(source, "/"),
+		client:  &http.Client{Timeout: sourcifyTimeout},
+		recent:  recent,
+		fetches: semaphore.NewWeighted(sourcifyMaxFetches),
+	}
+	if !s.remote() {
+		if info, err := os.Stat(source); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("sourcify repository %s is neither a directory nor an http(s) URL", source)
+		}
+	}
+	if cachePath == "" {
+		return s, nil
+	}
+	db, err := openOtsDB(cachePath, kv.TableCfg{OtsContractMetadata: {}}, 4*datasize.GB, 16*datasize.MB)
+	if err != nil {
+		return nil, fmt.Errorf("open contract metadata db %s: %w", cachePath, err)
+	}
+	s.cache = db
+	return s, nil
+}
+
+func (s *sourcify) remote() bool {
+	return strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://")
+}
+
+// file returns the file of the repository by its slash-separated path, nil if there is no such file
+func (s *sourcify) file(ctx context.Context, name string) ([]byte, error) {
+	if !s.remote() {
+		f, err := os.Open(filepath.Join(s.source, filepath.FromSlash(name)))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, sourcifyMaxFileSize))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source+"/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(io.LimitReader(resp.Body, sourcifyMaxFileSize))
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("sourcify %s: %s", name, resp.Status)
+	}
+}
+
+func contractMetadataKey(chainID uint64, addr common.Address) []byte {
+	k := make([]byte, 8+common.AddressLength)
+	binary.BigEndian.PutUint64(k, chainID)
+	copy(k[8:], addr[:])
+	return k
+}
+
+// get returns metadata of the contract with sources, from the cache if it's there and not expired. The result is
+// shared with other requests, it must not be modified.
+func (s *sourcify) get(ctx context.Context, chainID uint64, addr common.Address) (*ContractMetadata, error) {
+	key := contractMetadataKey(chainID, addr)
+	if v, ok := s.recent.Get(string(key)); ok && !v.(*cachedContractMetadata).expired() {
+		return v.(*cachedContractMetadata).Metadata, nil
+	}
+	if s.cache != nil {
+		var cached *cachedContractMetadata
+		if err := s.cache.View(ctx, func(tx kv.Tx) error {
+			v, err := tx.GetOne(OtsContractMetadata, key)
+			if err != nil || v == nil {
+				return err
+			}
+			cached = new(cachedContractMetadata)
+			return json.Unmarshal(v, cached)
+		}); err != nil {
+			return nil, err
+		}
+		if cached != nil && !cached.expired() {
+			s.recent.Add(string(key), cached)
+			return cached.Metadata, nil
+		}
+	}
+
+	meta, err, _ := s.inflight.Do(string(key), func() (interface{}, error) {
+		if err := s.fetches.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+		defer s.fetches.Release(1)
+		meta, err := s.fetch(ctx, chainID, addr)
+		if err != nil {
+			return nil, err
+		}
+		cached := &cachedContractMetadata{Metadata: meta, Fetched: time.Now().Unix()}
+		if s.cache != nil {
+			v, err := json.Marshal(cached)
+			if err != nil {
+				return nil, err
+			}
+			if err = s.cache.Update(ctx, func(tx kv.RwTx) error {
+				return tx.Put(OtsContractMetadata, key, v)
+			}); err != nil {
+				return nil, err
+			}
+		}
+		s.recent.Add(string(key), cached)
+		return meta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meta.(*ContractMetadata), nil
+}
+
+// fetch reads metadata of the full match of the contract, of the partial one if there is no full one
+func (s *sourcify) fetch(ctx context.Context, chainID uint64, addr common.Address) (*ContractMetadata, error) {
+	for _, match := range []string{"full", "partial"} {
+		dir := fmt.Sprintf("contracts/%s_match/%d/%s", match, chainID, addr.Hex())
+		raw, err := s.file(ctx, dir+"/metadata.json")
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			continue
+		}
+		meta, err := parseContractMetadata(match, raw)
+		if err != nil {
+			return nil, fmt.Errorf("metadata of %x: %w", addr, err)
+		}
+		if err = s.fetchSources(ctx, dir, meta); err != nil {
+			return nil, err
+		}
+		return meta, nil
+	}
+	return nil, nil
+}
+
+// fetchSources reads sources which aren't embedded in the metadata from the sources directory of the contract
+func (s *sourcify) fetchSources(ctx context.Context, dir string, meta *ContractMetadata) error {
+	for i := range meta.Sources {
+		src := &meta.Sources[i]
+		if src.Content != "" {
+			continue
+		}
+		name := path.Clean("/" + src.Path)[1:] // can't escape the contract directory
+		content, err := s.file(ctx, dir+"/sources/"+name)
+		if err != nil {
+			return err
+		}
+		src.Content = string(content)
+	}
+	return nil
+}
+
+func parseContractMetadata(match string, raw []byte) (*ContractMetadata, error) {
+	var solc solcMetadata
+	if err := json.Unmarshal(raw, &solc); err != nil {
+		return nil, err
+	}
+	meta := &ContractMetadata{
+		Match:    match,
+		Language: solc.Language,
+		Compiler: solc.Compiler.Version,
+		ABI:      solc.Output.ABI,
+		Methods:  map[string]string{},
+		Events:   map[common.Hash]string{},
+		Metadata: raw,
+	}
+	for _, name := range solc.Settings.CompilationTarget {
+		meta.Name = name
+	}
+	if len(meta.ABI) > 0 {
+		parsed, err := abi.JSON(bytes.NewReader(meta.ABI))
+		if err != nil {
+			return nil, fmt.Errorf("abi: %w", err)
+		}
+		for _, m := range parsed.Methods {
+			meta.Methods[hexutil.Encode(m.ID)] = m.Sig
+		}
+		for _, e := range parsed.Events {
+			meta.Events[e.ID] = e.Sig
+		}
+	}
+	for p, src := range solc.Sources {
+		source := ContractSource{Path: p, License: src.License}
+		if src.Content != nil {
+			source.Content = *src.Content
+		}
+		meta.Sources = append(meta.Sources, source)
+	}
+	sort.Slice(meta.Sources, func(i, j int) bool { return meta.Sources[i].Path < meta.Sources[j].Path })
+	return meta, nil
+}
+
+// GetContractMetadata implements ots_getContractMetadata: ABI, selectors and sources of the contract verified by
+// Sourcify (--ots.sourcify.source), nil if it isn't verified or the source isn't set. Contents of sources are returned
+// only with withSources.
+func (api *OtterscanAPIImpl) GetContractMetadata(ctx context.Context, addr common.Address, withSources *bool) (*ContractMetadata, error) {
+	if api.sourcify == nil {
+		return nil, nil
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chainConfig, err := api.chainConfig(tx)
+	tx.Rollback()
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := api.sourcify.get(ctx, chainConfig.ChainID.Uint64(), addr)
+	if err != nil || meta == nil {
+		return nil, err
+	}
+	if withSources == nil || !*withSources {
+		withoutSources := *meta
+		withoutSources.Sources = make([]ContractSource, len(meta.Sources))
+		for i, src := range meta.Sources {
+			withoutSources.Sources[i] = ContractSource{Path: src.Path, License: src.License}
+		}
+		return &withoutSources, nil
+	}
+	return meta, nil
+}
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+const testSolcMetadata = `{
+  "compiler": {"version": "0.8.17+commit.8df45f5f"},
+  "language": "Solidity",
+  "output": {"abi": [
+    {"type": "function", "name": "transfer", "inputs": [{"name": "to", "type": "address"}, {"name": "amount", "type": "uint256"}], "outputs": [{"name": "", "type": "bool"}], "stateMutability": "nonpayable"},
+    {"type": "event", "name": "Transfer", "inputs": [{"name": "from", "type": "address", "indexed": true}, {"name": "to", "type": "address", "indexed": true}, {"name": "value", "type": "uint256", "indexed": false}], "anonymous": false}
+  ]},
+  "settings": {"compilationTarget": {"contracts/Token.sol": "Token"}},
+  "sources": {
+    "contracts/Token.sol": {"keccak256": "0x01", "license": "MIT", "urls": []},
+    "contracts/Lib.sol": {"keccak256": "0x02", "content": "library Lib {}"}
+  },
+  "version": 1
+}`
+
+func writeSourcifyContract(t *testing.T, repo, match string, chainID uint64, addr common.Address) {
+	dir := filepath.Join(repo, "contracts", match+"_match", strconv.FormatUint(chainID, 10), addr.Hex())
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sources", "contracts"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(testSolcMetadata), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sources", "contracts", "Token.sol"), []byte("contract Token {}"), 0644))
+}
+
+func TestGetContractMetadata(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+	ctx := context.Background()
+
+	meta, err := api.GetContractMetadata(ctx, common.Address{1}, nil)
+	require.NoError(t, err)
+	require.Nil(t, meta) // disabled
+
+	repo := t.TempDir()
+	full, partial := common.HexToAddress("0x00000000000000000000000000000000000000f1"), common.HexToAddress("0x00000000000000000000000000000000000000f2")
+	writeSourcifyContract(t, repo, "full", 1337, full)
+	writeSourcifyContract(t, repo, "partial", 1337, partial)
+	api.sourcify, err = openSourcify(repo, filepath.Join(t.TempDir(), "cache"))
+	require.NoError(t, err)
+	defer api.sourcify.cache.Close()
+
+	withSources := true
+	meta, err = api.GetContractMetadata(ctx, full, &withSources)
+	require.NoError(t, err)
+	require.Equal(t, "full", meta.Match)
+	require.Equal(t, "Token", meta.Name)
+	require.Equal(t, "0.8.17+commit.8df45f5f", meta.Compiler)
+	require.Equal(t, map[string]string{"0xa9059cbb": "transfer(address,uint256)"}, meta.Methods)
+	require.Equal(t, "Transfer(address,address,uint256)", meta.Events[common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")])
+	require.Equal(t, []ContractSource{
+		{Path: "contracts/Lib.sol", Content: "library Lib {}"},
+		{Path: "contracts/Token.sol", License: "MIT", Content: "contract Token {}"},
+	}, meta.Sources)
+
+	meta, err = api.GetContractMetadata(ctx, partial, nil)
+	require.NoError(t, err)
+	require.Equal(t, "partial", meta.Match)
+	require.Equal(t, []ContractSource{{Path: "contracts/Lib.sol"}, {Path: "contracts/Token.sol", License: "MIT"}}, meta.Sources)
+
+	meta, err = api.GetContractMetadata(ctx, common.Address{1}, nil)
+	require.NoError(t, err)
+	require.Nil(t, meta) // not verified
+
+	// served from the cache when the repository is gone
+	require.NoError(t, os.RemoveAll(filepath.Join(repo, "contracts")))
+	meta, err = api.GetContractMetadata(ctx, full, &withSources)
+	require.NoError(t, err)
+	require.Equal(t, "contract Token {}", meta.Sources[1].Content)
+
+	// the shared result isn't stripped of sources
+	meta, err = api.GetContractMetadata(ctx, full, nil)
+	require.NoError(t, err)
+	require.Empty(t, meta.Sources[1].Content)
+	meta, err = api.GetContractMetadata(ctx, full, &withSources)
+	require.NoError(t, err)
+	require.Equal(t, "contract Token {}", meta.Sources[1].Content)
+
+	caps, err := api.GetCapabilities(ctx)
+	require.NoError(t, err)
+	require.True(t, caps.Features.ContractMetadata)
+	require.Contains(t, caps.Methods, "ots_getContractMetadata")
+}
+
+func TestSourcifyRecent(t *testing.T) {
+	ctx := context.Background()
+	repo := t.TempDir()
+	verified, unverified := common.HexToAddress("0x00000000000000000000000000000000000000f1"), common.HexToAddress("0x00000000000000000000000000000000000000f2")
+	writeSourcifyContract(t, repo, "full", 1, verified)
+	s, err := openSourcify(repo, "")
+	require.NoError(t, err)
+
+	meta, err := s.get(ctx, 1, verified)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	meta, err = s.get(ctx, 1, unverified)
+	require.NoError(t, err)
+	require.Nil(t, meta)
+
+	// both are kept in memory without the cache DB, the miss until it expires
+	require.NoError(t, os.RemoveAll(filepath.Join(repo, "contracts")))
+	writeSourcifyContract(t, repo, "full", 1, unverified)
+	meta, err = s.get(ctx, 1, verified)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	meta, err = s.get(ctx, 1, unverified)
+	require.NoError(t, err)
+	require.Nil(t, meta)
+
+	s.recent.Add(string(contractMetadataKey(1, unverified)), &cachedContractMetadata{Fetched: time.Now().Add(-sourcifyMissTTL).Unix()})
+	meta, err = s.get(ctx, 1, unverified)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+}
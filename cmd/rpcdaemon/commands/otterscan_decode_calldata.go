@@ -0,0 +1,266 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/exp/slices"
+)
+
+// Sources of DecodedCalldata
+const (
+	CalldataSourceContract   = "contract"   // ABI of the contract verified by Sourcify, with names of arguments
+	CalldataSourceSignatures = "signatures" // text signature of the selector from --ots.signatures.path
+)
+
+// DecodedCalldata - function called by a transaction and its arguments
+type DecodedCalldata struct {
+	Selector  hexutil.Bytes     `json:"selector"`
+	Name      string            `json:"name"`
+	Signature string            `json:"signature"`
+	Source    string            `json:"source"`
+	Args      []DecodedArgument `json:"args"`
+}
+
+// DecodedArgument - integers are decimal strings, bytes are hex, arrays and tuples are lists of values
+type DecodedArgument struct {
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// signatures - text signatures of functions by 4-byte selector, a selector may have a few colliding ones
+type signatures map[[4]byte][]string
+
+// loadSignatures reads a text file with a function signature per line, e.g. "transfer(address,uint256)",
+// optionally prefixed with its selector like dumps of 4byte.directory: "0xa9059cbb transfer(address,uint256)" or
+// "a9059cbb,transfer(address,uint256)". Lines which don't match their selector are skipped, "#" starts a comment.
+func loadSignatures(path string) (signatures, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sigs := signatures{}
+	var count, skipped int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix, sig := splitSignatureLine(line)
+		var selector [4]byte
+		copy(selector[:], crypto.Keccak256([]byte(sig)))
+		if (prefix != nil && !bytes.Equal(prefix, selector[:])) || !strings.HasSuffix(sig, ")") {
+			skipped++
+			continue
+		}
+		if !slices.Contains(sigs[selector], sig) {
+			sigs[selector] = append(sigs[selector], sig)
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read signatures %s: %w", path, err)
+	}
+	log.Info("Loaded function signatures", "path", path, "signatures", count, "skipped", skipped)
+	return sigs, nil
+}
+
+// splitSignatureLine returns the selector prefix of the line (nil if there is none) and the signature
+func splitSignatureLine(line string) ([]byte, string) {
+	rest := strings.TrimPrefix(line, "0x")
+	if len(rest) > 8 && strings.ContainsRune(" \t,:", rune(rest[8])) {
+		if selector, err := hex.DecodeString(rest[:8]); err == nil {
+			return selector, strings.TrimSpace(rest[9:])
+		}
+	}
+	return nil, line
+}
+
+// parseSignature returns the name and the argument types of a text signature like "f(uint256,(address,bytes)[])"
+func parseSignature(sig string) (string, abi.Arguments, error) {
+	open := strings.IndexByte(sig, '(')
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return "", nil, fmt.Errorf("invalid signature %q", sig)
+	}
+	types, err := splitSignatureTypes(sig[open+1 : len(sig)-1])
+	if err != nil {
+		return "", nil, fmt.Errorf("signature %q: %w", sig, err)
+	}
+	args := make(abi.Arguments, len(types))
+	for i, t := range types {
+		marshaling, err := signatureTypeMarshaling(t)
+		if err != nil {
+			return "", nil, fmt.Errorf("signature %q: %w", sig, err)
+		}
+		if args[i].Type, err = abi.NewType(marshaling.Type, "", marshaling.Components); err != nil {
+			return "", nil, fmt.Errorf("signature %q: %w", sig, err)
+		}
+	}
+	return sig[:open], args, nil
+}
+
+// splitSignatureTypes splits a comma separated list of types, commas inside of tuples don't split it
+func splitSignatureTypes(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var types []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in %q", list)
+			}
+		case ',':
+			if depth == 0 {
+				types, start = append(types, list[start:i]), i+1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in %q", list)
+	}
+	return append(types, list[start:]), nil
+}
+
+// signatureTypeMarshaling converts a type of a text signature into the form of ABI JSON: tuples like
+// "(uint256,address)[]" become "tuple[]" with components, named by position as signatures don't have names
+func signatureTypeMarshaling(t string) (abi.ArgumentMarshaling, error) {
+	if !strings.HasPrefix(t, "(") {
+		return abi.ArgumentMarshaling{Type: t}, nil
+	}
+	closing := strings.LastIndexByte(t, ')')
+	types, err := splitSignatureTypes(t[1:closing])
+	if err != nil {
+		return abi.ArgumentMarshaling{}, err
+	}
+	m := abi.ArgumentMarshaling{Type: "tuple" + t[closing+1:]}
+	for i, componentType := range types {
+		component, err := signatureTypeMarshaling(componentType)
+		if err != nil {
+			return abi.ArgumentMarshaling{}, err
+		}
+		component.Name = fmt.Sprintf("f%d", i)
+		m.Components = append(m.Components, component)
+	}
+	return m, nil
+}
+
+// decodeCalldata decodes arguments of the calldata of a known function, nil if they don't match it
+func decodeCalldata(name, sig, source string, args abi.Arguments, data []byte) *DecodedCalldata {
+	values, err := args.UnpackValues(data[4:])
+	if err != nil {
+		return nil
+	}
+	decoded := &DecodedCalldata{Selector: common.CopyBytes(data[:4]), Name: name, Signature: sig, Source: source, Args: make([]DecodedArgument, len(args))}
+	for i, arg := range args {
+		decoded.Args[i] = DecodedArgument{Name: arg.Name, Type: arg.Type.String(), Value: calldataValue(arg.Type, reflect.ValueOf(values[i]))}
+	}
+	return decoded
+}
+
+// calldataValue converts an unpacked value into the JSON form of DecodedArgument
+func calldataValue(t abi.Type, v reflect.Value) interface{} {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		return fmt.Sprint(v.Interface()) // *big.Int or a sized Go integer
+	case abi.BytesTy:
+		return hexutil.Bytes(v.Bytes())
+	case abi.FixedBytesTy, abi.FunctionTy, abi.HashTy:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return hexutil.Bytes(b)
+	case abi.SliceTy, abi.ArrayTy:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = calldataValue(*t.Elem, v.Index(i))
+		}
+		return list
+	case abi.TupleTy:
+		list := make([]interface{}, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			list[i] = calldataValue(*elem, v.Field(i))
+		}
+		return list
+	default: // bool, string, address
+		return v.Interface()
+	}
+}
+
+// DecodeCalldata implements ots_decodeCalldata: the function called by the transaction and its arguments. The ABI
+// of the contract is used if it's verified by Sourcify (--ots.sourcify.source), signatures of --ots.signatures.path
+// otherwise. nil if the transaction has no calldata or the function is unknown.
+func (api *OtterscanAPIImpl) DecodeCalldata(ctx context.Context, hash common.Hash) (*DecodedCalldata, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txn, _, _, _, _, err := api.getTransactionByHash(ctx, tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if txn == nil {
+		return nil, rpc.NewNotFoundError("transaction", hash)
+	}
+	data, to := txn.GetData(), txn.GetTo()
+	if to == nil || len(data) < 4 {
+		return nil, nil
+	}
+
+	if api.sourcify != nil {
+		chainConfig, err := api.chainConfig(tx)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := api.sourcify.get(ctx, chainConfig.ChainID.Uint64(), *to)
+		if err != nil {
+			// the source may be unreachable, signatures still decode the calldata
+			log.Warn("Contract metadata is unavailable", "address", to, "err", err)
+		}
+		if meta != nil && len(meta.ABI) > 0 {
+			parsed, err := abi.JSON(bytes.NewReader(meta.ABI))
+			if err != nil {
+				return nil, err
+			}
+			if method, err := parsed.MethodById(data); err == nil {
+				if decoded := decodeCalldata(method.RawName, method.Sig, CalldataSourceContract, method.Inputs, data); decoded != nil {
+					return decoded, nil
+				}
+			}
+		}
+	}
+
+	var selector [4]byte
+	copy(selector[:], data)
+	for _, sig := range api.signatures[selector] {
+		name, args, err := parseSignature(sig)
+		if err != nil {
+			continue
+		}
+		if decoded := decodeCalldata(name, sig, CalldataSourceSignatures, args, data); decoded != nil {
+			return decoded, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSignature(t *testing.T) {
+	name, args, err := parseSignature("transfer(address,uint256)")
+	require.NoError(t, err)
+	require.Equal(t, "transfer", name)
+	require.Len(t, args, 2)
+	require.Equal(t, "address", args[0].Type.String())
+	require.Equal(t, "uint256", args[1].Type.String())
+
+	name, args, err = parseSignature("multicall()")
+	require.NoError(t, err)
+	require.Equal(t, "multicall", name)
+	require.Empty(t, args)
+
+	_, args, err = parseSignature("swap((address,(uint256,bytes)[])[],bytes32[2])")
+	require.NoError(t, err)
+	require.Equal(t, "(address,(uint256,bytes)[])[]", args[0].Type.String())
+	require.Equal(t, "bytes32[2]", args[1].Type.String())
+
+	for _, sig := range []string{"transfer", "(uint256)", "f(uint256", "f((uint256)", "f(foo)"} {
+		_, _, err = parseSignature(sig)
+		require.Error(t, err, sig)
+	}
+}
+
+func TestLoadSignatures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signatures.txt")
+	require.NoError(t, os.WriteFile(path, []byte(`# 4byte.directory dump
+transfer(address,uint256)
+0xa9059cbb transfer(address,uint256)
+095ea7b3,approve(address,uint256)
+0x12345678 notMatchingItsSelector(uint256)
+
+balanceOf(address)
+`), 0644))
+	sigs, err := loadSignatures(path)
+	require.NoError(t, err)
+	require.Equal(t, signatures{
+		{0xa9, 0x05, 0x9c, 0xbb}: {"transfer(address,uint256)"},
+		{0x09, 0x5e, 0xa7, 0xb3}: {"approve(address,uint256)"},
+		{0x70, 0xa0, 0x82, 0x31}: {"balanceOf(address)"},
+	}, sigs)
+}
+
+func TestDecodeCalldataValues(t *testing.T) {
+	sig := "f((uint256,address)[],bytes,bytes4,bool,string,uint8[2])"
+	name, args, err := parseSignature(sig)
+	require.NoError(t, err)
+	type pair struct {
+		F0 *big.Int
+		F1 common.Address
+	}
+	packed, err := args.Pack([]pair{{big.NewInt(1), common.Address{1}}, {big.NewInt(2), common.Address{2}}}, []byte{1, 2}, [4]byte{3}, true, "str", [2]uint8{4, 5})
+	require.NoError(t, err)
+
+	decoded := decodeCalldata(name, sig, CalldataSourceSignatures, args, append([]byte{0xaa, 0xbb, 0xcc, 0xdd}, packed...))
+	require.NotNil(t, decoded)
+	require.Equal(t, hexutil.Bytes{0xaa, 0xbb, 0xcc, 0xdd}, decoded.Selector)
+	require.Equal(t, []DecodedArgument{
+		{Type: "(uint256,address)[]", Value: []interface{}{[]interface{}{"1", common.Address{1}}, []interface{}{"2", common.Address{2}}}},
+		{Type: "bytes", Value: hexutil.Bytes{1, 2}},
+		{Type: "bytes4", Value: hexutil.Bytes{3, 0, 0, 0}},
+		{Type: "bool", Value: true},
+		{Type: "string", Value: "str"},
+		{Type: "uint8[2]", Value: []interface{}{"4", "5"}},
+	}, decoded.Args)
+
+	// calldata of another function with the same selector
+	require.Nil(t, decodeCalldata(name, sig, CalldataSourceSignatures, args, []byte{0xaa, 0xbb, 0xcc, 0xdd, 1}))
+}
+
+func TestDecodeCalldata(t *testing.T) {
+	m, chain, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+	ctx := context.Background()
+
+	// the first token transfer of the test chain sends 3 tokens
+	transferSelector := []byte{0xa9, 0x05, 0x9c, 0xbb}
+	var transfer types.Transaction
+	for _, block := range chain.Blocks {
+		for _, txn := range block.Transactions() {
+			if transfer == nil && bytes.HasPrefix(txn.GetData(), transferSelector) {
+				transfer = txn
+			}
+		}
+	}
+	require.NotNil(t, transfer)
+	recipient := common.BytesToAddress(transfer.GetData()[4:36])
+
+	caps, err := api.GetCapabilities(ctx)
+	require.NoError(t, err)
+	require.False(t, caps.Features.CalldataDecoding)
+	require.NotContains(t, caps.Methods, "ots_decodeCalldata")
+	decoded, err := api.DecodeCalldata(ctx, transfer.Hash())
+	require.NoError(t, err)
+	require.Nil(t, decoded)
+
+	// "transfer(string)" collides with the selector and can't decode the calldata
+	api.signatures = signatures{{0xa9, 0x05, 0x9c, 0xbb}: {"transfer(string)", "transfer(address,uint256)"}}
+	decoded, err = api.DecodeCalldata(ctx, transfer.Hash())
+	require.NoError(t, err)
+	require.Equal(t, &DecodedCalldata{
+		Selector:  transferSelector,
+		Name:      "transfer",
+		Signature: "transfer(address,uint256)",
+		Source:    CalldataSourceSignatures,
+		Args:      []DecodedArgument{{Type: "address", Value: recipient}, {Type: "uint256", Value: "3"}},
+	}, decoded)
+
+	// the ABI of the verified contract gives names of arguments
+	repo := t.TempDir()
+	writeSourcifyContract(t, repo, "full", 1337, *transfer.GetTo())
+	api.sourcify, err = openSourcify(repo, "")
+	require.NoError(t, err)
+	decoded, err = api.DecodeCalldata(ctx, transfer.Hash())
+	require.NoError(t, err)
+	require.Equal(t, CalldataSourceContract, decoded.Source)
+	require.Equal(t, []DecodedArgument{{Name: "to", Type: "address", Value: recipient}, {Name: "amount", Type: "uint256", Value: "3"}}, decoded.Args)
+
+	caps, err = api.GetCapabilities(ctx)
+	require.NoError(t, err)
+	require.True(t, caps.Features.CalldataDecoding)
+	require.Contains(t, caps.Methods, "ots_decodeCalldata")
+
+	_, err = api.DecodeCalldata(ctx, common.Hash{1})
+	require.Error(t, err)
+}
@@ -5,24 +5,55 @@ import (
 	"fmt"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/rpc"
-	"github.com/ledgerwatch/erigon/turbo/adapter"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
-func (api *OtterscanAPIImpl) HasCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (bool, error) {
+// maxHasCodesAddresses - limit of addresses checked by one ots_hasCodes call
+const maxHasCodesAddresses = 1024
+
+// HasCode implements ots_hasCode. Returns whether the address is a contract at the end of the block (latest if
+// omitted), the code itself isn't read.
+func (api *OtterscanAPIImpl) HasCode(ctx context.Context, address common.Address, blockNrOrHash *rpc.BlockNumberOrHash) (bool, error) {
+	res, err := api.HasCodes(ctx, []common.Address{address}, blockNrOrHash)
+	if err != nil {
+		return false, err
+	}
+	return res[0], nil
+}
+
+// HasCodes implements ots_hasCodes - ots_hasCode of many addresses at once, results are in the order of addresses
+func (api *OtterscanAPIImpl) HasCodes(ctx context.Context, addresses []common.Address, blockNrOrHash *rpc.BlockNumberOrHash) ([]bool, error) {
+	if len(addresses) > maxHasCodesAddresses {
+		return nil, &rpc.LimitExceededError{Message: fmt.Sprintf("too many addresses: %d, limit is %d", len(addresses), maxHasCodesAddresses), Limit: maxHasCodesAddresses}
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
-		return false, fmt.Errorf("hasCode cannot open tx: %w", err)
+		return nil, fmt.Errorf("hasCode cannot open tx: %w", err)
 	}
 	defer tx.Rollback()
 
-	blockNumber, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
+	reader, err := rpchelper.CreateStateReader(ctx, tx, bNrOrHash, api.filters, api.stateCache, api.recentStates, api.historyV3(tx), api._agg)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	res := make([]bool, len(addresses))
+	for i, address := range addresses {
+		if res[i], err = hasCode(reader, address); err != nil {
+			return nil, err
+		}
 	}
+	return res, nil
+}
 
-	reader := adapter.NewStateReader(tx, blockNumber)
+// hasCode - by the code hash of the account
+func hasCode(reader state.StateReader, address common.Address) (bool, error) {
 	acc, err := reader.ReadAccountData(address)
 	if acc == nil || err != nil {
 		return false, err
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasCode(t *testing.T) {
+	ctx := context.Background()
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+
+	// the first contract deployed by the test chain
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	var contract, sender common.Address
+	var created uint64
+	for number := uint64(1); created == 0; number++ {
+		block, err := rawdb.ReadBlockByNumber(tx, number)
+		require.NoError(t, err)
+		require.NotNil(t, block)
+		for _, txn := range block.Transactions() {
+			if txn.GetTo() == nil {
+				sender, err = txn.Sender(*types.LatestSignerForChainID(m.ChainConfig.ChainID))
+				require.NoError(t, err)
+				contract, created = crypto.CreateAddress(sender, txn.GetNonce()), number
+				break
+			}
+		}
+	}
+
+	before := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(created - 1))
+	has, err := api.HasCode(ctx, contract, &before)
+	require.NoError(t, err)
+	require.False(t, has)
+	at := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(created))
+	has, err = api.HasCode(ctx, contract, &at)
+	require.NoError(t, err)
+	require.True(t, has)
+
+	res, err := api.HasCodes(ctx, []common.Address{sender, contract, {}}, &at)
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, true, false}, res)
+	res, err = api.HasCodes(ctx, []common.Address{contract}, &before)
+	require.NoError(t, err)
+	require.Equal(t, []bool{false}, res)
+
+	_, err = api.HasCodes(ctx, make([]common.Address, maxHasCodesAddresses+1), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,384 @@
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// OtsInternalOperations - tx hash -> hash of its block + internal operations of the tx in the encodeInternalOperations
+// form. Traced by the backfill of --ots.operations.path while the state history of the block is still there, so that
+// ots_getInternalOperations can answer for transactions of pruned blocks. Transactions without operations have no entry.
+const OtsInternalOperations = "OtsInternalOperations"
+
+// OtsInternalOperationsRange - "from", "to" -> big-endian numbers of the backfilled blocks, all transactions of the
+// canonical blocks between them are indexed
+const OtsInternalOperationsRange = "OtsInternalOperationsRange"
+
+// OtsInternalOperationsFailed - big-endian number of a backfilled block which couldn't be traced -> the error. Replaying
+// the same state fails the same way, so such blocks are skipped by the backfill instead of retried.
+const OtsInternalOperationsFailed = "OtsInternalOperationsFailed"
+
+const (
+	operationsBackfillDelay    = 64 // blocks behind the head, traced once they are unlikely to be reorged
+	operationsBackfillBatch    = 100
+	operationsBackfillInterval = 10 * time.Second
+)
+
+type internalOperations struct {
+	db kv.RwDB
+}
+
+func openInternalOperations(path string) (*internalOperations, error) {
+	tables := kv.TableCfg{OtsInternalOperations: {}, OtsInternalOperationsRange: {}, OtsInternalOperationsFailed: {}}
+	db, err := openOtsDB(path, tables, 256*datasize.GB, 128*datasize.MB)
+	if err != nil {
+		return nil, fmt.Errorf("open internal operations db %s: %w", path, err)
+	}
+	return &internalOperations{db: db}, nil
+}
+
+// backfilled returns the range of indexed blocks, ok is false if nothing is indexed yet
+func (o *internalOperations) backfilled(tx kv.Tx) (from, to uint64, ok bool, err error) {
+	v, err := tx.GetOne(OtsInternalOperationsRange, []byte("from"))
+	if err != nil || len(v) != 8 {
+		return 0, 0, false, err
+	}
+	from = binary.BigEndian.Uint64(v)
+	if v, err = tx.GetOne(OtsInternalOperationsRange, []byte("to")); err != nil || len(v) != 8 {
+		return 0, 0, false, err
+	}
+	return from, binary.BigEndian.Uint64(v), true, nil
+}
+
+// get returns the operations of the transaction of the canonical block, indexed is false if the block wasn't
+// backfilled or the entry belongs to a reorged block with the same transaction. Transactions of blocks the backfill
+// couldn't trace get the error of the tracing.
+func (o *internalOperations) get(ctx context.Context, txHash, blockHash common.Hash, blockNum uint64) (ops []*InternalOperation, indexed bool, err error) {
+	err = o.db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(OtsInternalOperations, txHash.Bytes())
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			from, to, ok, err := o.backfilled(tx)
+			if err != nil {
+				return err
+			}
+			if !ok || blockNum < from || blockNum > to {
+				return nil
+			}
+			var key [8]byte
+			binary.BigEndian.PutUint64(key[:], blockNum)
+			failure, err := tx.GetOne(OtsInternalOperationsFailed, key[:])
+			if err != nil {
+				return err
+			}
+			if failure != nil {
+				return fmt.Errorf("internal operations of block %d couldn't be traced: %s", blockNum, failure)
+			}
+			ops, indexed = make([]*InternalOperation, 0), true
+			return nil
+		}
+		if common.BytesToHash(v[:common.HashLength]) != blockHash {
+			return nil
+		}
+		ops, err = decodeInternalOperations(v[common.HashLength:])
+		indexed = err == nil
+		return err
+	})
+	return ops, indexed, err
+}
+
+// put indexes the operations of the traced blocks, records the blocks which failed, and extends the backfilled range
+// to them
+func (o *internalOperations) put(ctx context.Context, from, to uint64, entries map[common.Hash][]byte, failed map[uint64]string) error {
+	return o.db.Update(ctx, func(tx kv.RwTx) error {
+		for txHash, v := range entries {
+			if err := tx.Put(OtsInternalOperations, txHash.Bytes(), v); err != nil {
+				return err
+			}
+		}
+		var buf [8]byte
+		for blockNum, failure := range failed {
+			binary.BigEndian.PutUint64(buf[:], blockNum)
+			if err := tx.Put(OtsInternalOperationsFailed, buf[:], []byte(failure)); err != nil {
+				return err
+			}
+		}
+		binary.BigEndian.PutUint64(buf[:], from)
+		if err := tx.Put(OtsInternalOperationsRange, []byte("from"), buf[:]); err != nil {
+			return err
+		}
+		binary.BigEndian.PutUint64(buf[:], to)
+		return tx.Put(OtsInternalOperationsRange, []byte("to"), buf[:])
+	})
+}
+
+// encodeInternalOperations - per operation: type (0x80 bit set if the CREATE2 preimage follows), from, to, length of
+// the value and its big-endian bytes, then salt and init code hash of the preimage
+func encodeInternalOperations(ops []*InternalOperation) []byte {
+	var buf []byte
+	for _, op := range ops {
+		t := byte(op.Type)
+		if op.Salt != nil && op.InitCodeHash != nil {
+			t |= 0x80
+		}
+		var value []byte
+		if op.Value != nil {
+			value = op.Value.ToInt().Bytes()
+		}
+		buf = append(buf, t)
+		buf = append(buf, op.From.Bytes()...)
+		buf = append(buf, op.To.Bytes()...)
+		buf = append(buf, byte(len(value)))
+		buf = append(buf, value...)
+		if t&0x80 != 0 {
+			buf = append(buf, op.Salt.Bytes()...)
+			buf = append(buf, op.InitCodeHash.Bytes()...)
+		}
+	}
+	return buf
+}
+
+func decodeInternalOperations(buf []byte) ([]*InternalOperation, error) {
+	ops := make([]*InternalOperation, 0)
+	for len(buf) > 0 {
+		if len(buf) < 1+2*common.AddressLength+1 {
+			return nil, errors.New("truncated internal operation")
+		}
+		t := buf[0]
+		op := &InternalOperation{
+			Type: OperationType(t &^ 0x80),
+			From: common.BytesToAddress(buf[1 : 1+common.AddressLength]),
+			To:   common.BytesToAddress(buf[1+common.AddressLength : 1+2*common.AddressLength]),
+		}
+		buf = buf[1+2*common.AddressLength:]
+		valueLen := int(buf[0])
+		if len(buf) < 1+valueLen {
+			return nil, errors.New("truncated value of internal operation")
+		}
+		op.Value = (*hexutil.Big)(new(big.Int).SetBytes(buf[1 : 1+valueLen]))
+		buf = buf[1+valueLen:]
+		if t&0x80 != 0 {
+			if len(buf) < 2*common.HashLength {
+				return nil, errors.New("truncated CREATE2 preimage of internal operation")
+			}
+			salt, initCodeHash := common.BytesToHash(buf[:common.HashLength]), common.BytesToHash(buf[common.HashLength:2*common.HashLength])
+			op.Salt, op.InitCodeHash = &salt, &initCodeHash
+			buf = buf[2*common.HashLength:]
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// prunedInternalOperations answers for a transaction of a block with pruned state history from the operations
+// index, ok is false if the block can be traced instead. Transactions which are neither traceable nor indexed get
+// rpc.PrunedError of the state.
+func (api *OtterscanAPIImpl) prunedInternalOperations(ctx context.Context, tx kv.Tx, hash common.Hash) (ops []*InternalOperation, ok bool, err error) {
+	txn, _, blockHash, blockNum, _, err := api.getTransactionByHash(ctx, tx, hash)
+	if err != nil || txn == nil || blockNum == 0 {
+		return nil, false, err // unknown transactions are reported by the tracing
+	}
+	// the block is executed on top of the state after its parent
+	prunedErr := rpchelper.CheckHistoryNotPruned(tx, blockNum-1)
+	var pruned *rpc.PrunedError
+	if !errors.As(prunedErr, &pruned) || api.operations == nil {
+		return nil, false, prunedErr
+	}
+	ops, indexed, err := api.operations.get(ctx, hash, blockHash, blockNum)
+	if err != nil {
+		return nil, false, err
+	}
+	if !indexed {
+		return nil, false, prunedErr // pruned before the backfill reached it
+	}
+	return ops, true, nil
+}
+
+// backfillInternalOperations traces new blocks into the operations index before their state history is pruned,
+// runs for the lifetime of the process. Blocks pruned before being traced (the index was disabled, rpcdaemon was
+// down for too long) stay unavailable, the index continues from the first block with history.
+func (api *OtterscanAPIImpl) backfillInternalOperations(ctx context.Context) {
+	defer debug.LogPanic()
+	ticker := time.NewTicker(operationsBackfillInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			done, err := api.backfillOperationsBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Warn("Internal operations backfill failed", "err", err)
+				}
+				break
+			}
+			if done {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// backfillOperationsBatch indexes the next operationsBackfillBatch blocks, done is true when the index caught up
+func (api *OtterscanAPIImpl) backfillOperationsBatch(ctx context.Context) (done bool, err error) {
+	dbtx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer dbtx.Rollback()
+
+	pm, err := prune2.Get(dbtx)
+	if err != nil {
+		return false, err
+	}
+	if !pm.History.Enabled() {
+		return true, nil // all blocks can be traced
+	}
+	head, err := stages.GetStageProgress(dbtx, stages.Execution)
+	if err != nil || head < operationsBackfillDelay {
+		return true, err
+	}
+	target := head - operationsBackfillDelay
+	availableFrom := pm.History.PruneTo(head)
+
+	var from, to uint64
+	var ok bool
+	if err := api.operations.db.View(ctx, func(tx kv.Tx) error {
+		from, to, ok, err = api.operations.backfilled(tx)
+		return err
+	}); err != nil {
+		return false, err
+	}
+	next := availableFrom
+	switch {
+	case !ok:
+		from = availableFrom
+	case to+1 >= availableFrom:
+		next = to + 1
+	default:
+		log.Warn("State history was pruned before the internal operations backfill", "from", to+1, "to", availableFrom-1)
+		from = availableFrom
+	}
+	if next > target {
+		return true, nil
+	}
+	last := next + operationsBackfillBatch - 1
+	if last > target {
+		last = target
+	}
+
+	entries, failed, err := api.traceOperationsRange(ctx, dbtx, next, last)
+	if err != nil {
+		return false, err
+	}
+	if err := api.operations.put(ctx, from, last, entries, failed); err != nil {
+		return false, err
+	}
+	return last == target, nil
+}
+
+// traceOperationsRange traces the canonical blocks [from, to], returns index entries of their transactions and
+// the errors of blocks which failed to be traced
+func (api *OtterscanAPIImpl) traceOperationsRange(ctx context.Context, dbtx kv.Tx, from, to uint64) (map[common.Hash][]byte, map[uint64]string, error) {
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, failed := map[common.Hash][]byte{}, map[uint64]string{}
+	for blockNum := from; blockNum <= to; blockNum++ {
+		blockHash, err := rawdb.ReadCanonicalHash(dbtx, blockNum)
+		if err != nil {
+			return nil, nil, err
+		}
+		block, _, err := api._blockReader.BlockWithSenders(ctx, dbtx, blockHash, blockNum)
+		if err != nil {
+			return nil, nil, err
+		}
+		if block == nil {
+			return nil, nil, rpc.NewNotFoundError("block", blockNum)
+		}
+		ops, err := api.blockInternalOperations(ctx, dbtx, block, chainConfig)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			// retrying wouldn't help, and the state history of the block would be pruned meanwhile
+			log.Error("Internal operations of block couldn't be traced, the block is skipped by the index", "block", blockNum, "err", err)
+			failed[blockNum] = err.Error()
+			continue
+		}
+		for i, txn := range block.Transactions() {
+			if len(ops[i]) > 0 {
+				entries[txn.Hash()] = append(blockHash.Bytes(), encodeInternalOperations(ops[i])...)
+			}
+		}
+	}
+	return entries, failed, nil
+}
+
+// blockInternalOperations replays the block, returns the internal operations of each transaction
+func (api *OtterscanAPIImpl) blockInternalOperations(ctx context.Context, dbtx kv.Tx, block *types.Block, chainConfig *params.ChainConfig) ([][]*InternalOperation, error) {
+	blockNum := block.NumberU64()
+	reader := state.NewPlainState(dbtx, blockNum)
+	stateCache := shards.NewStateCache(32, 0 /* no limit */)
+	cachedReader := state.NewCachedReader(reader, stateCache)
+	noop := state.NewNoopWriter()
+	cachedWriter := state.NewCachedWriter(noop, stateCache)
+
+	ibs := state.New(cachedReader)
+	signer := types.MakeSigner(chainConfig, blockNum)
+
+	blockHashes := core.NewCanonicalBlockHashes(ctx, dbtx, api._blockReader)
+	engine := ethash.NewFaker()
+
+	header := block.Header()
+	rules := chainConfig.Rules(blockNum)
+	ops := make([][]*InternalOperation, 0, block.Transactions().Len())
+	for idx, tx := range block.Transactions() {
+		ibs.Prepare(tx.Hash(), block.Hash(), idx)
+
+		msg, _ := tx.AsMessage(*signer, header.BaseFee, rules)
+
+		tracer := NewOperationsTracer(ctx)
+		blockCtx := core.NewEVMBlockContext(header, core.BlockHashFn(header, blockHashes), engine, nil)
+		txCtx := core.NewEVMTxContext(msg)
+
+		vmenv := vm.NewEVM(blockCtx, txCtx, ibs, chainConfig, vm.Config{Debug: true, Tracer: tracer})
+		if _, err := transactions.ApplyMessage(ctx, vmenv, msg, new(core.GasPool).AddGas(tx.GetGas()), true /* refunds */, false /* gasBailout */); err != nil {
+			return nil, err
+		}
+		_ = ibs.FinalizeTx(rules, cachedWriter)
+
+		ops = append(ops, tracer.Results)
+	}
+	return ops, nil
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeInternalOperations(t *testing.T) {
+	salt, initCodeHash := common.Hash{1}, common.Hash{2}
+	ops := []*InternalOperation{
+		{Type: OP_TRANSFER, From: common.Address{1}, To: common.Address{2}, Value: (*hexutil.Big)(big.NewInt(1_000_000_000_000_000))},
+		{Type: OP_CREATE2, From: common.Address{2}, To: common.Address{3}, Value: (*hexutil.Big)(new(big.Int)), Salt: &salt, InitCodeHash: &initCodeHash},
+		{Type: OP_SELF_DESTRUCT, From: common.Address{3}, To: common.Address{1}, Value: (*hexutil.Big)(new(big.Int))},
+	}
+	decoded, err := decodeInternalOperations(encodeInternalOperations(ops))
+	require.NoError(t, err)
+	require.Equal(t, ops, decoded)
+
+	decoded, err = decodeInternalOperations(nil)
+	require.NoError(t, err)
+	require.Equal(t, []*InternalOperation{}, decoded)
+
+	encoded := encodeInternalOperations(ops)
+	_, err = decodeInternalOperations(encoded[:len(encoded)-1])
+	require.Error(t, err)
+}
+
+func TestPrunedInternalOperations(t *testing.T) {
+	m, chain, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+	ctx := context.Background()
+
+	// the last block deploys a contract which creates and destructs another one
+	last := chain.Blocks[len(chain.Blocks)-1]
+	txHash := last.Transactions()[0].Hash()
+	traced, err := api.GetInternalOperations(ctx, txHash)
+	require.NoError(t, err)
+	require.NotEmpty(t, traced)
+	transfer := chain.Blocks[0].Transactions()[0].Hash() // no internal operations
+
+	// backfilled before the history is pruned
+	api.operations, err = openInternalOperations(t.TempDir())
+	require.NoError(t, err)
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	entries, failed, err := api.traceOperationsRange(ctx, tx, 1, last.NumberU64()-1)
+	require.NoError(t, err)
+	require.Empty(t, failed)
+	require.NoError(t, api.operations.put(ctx, 1, last.NumberU64()-1, entries, failed))
+	tx.Rollback()
+
+	require.NoError(t, m.DB.Update(ctx, func(tx kv.RwTx) error {
+		pm := prune2.DefaultMode
+		pm.History = prune2.Before(last.NumberU64() + 2) // the state after the parent of the last block is pruned
+		return prune2.Override(tx, pm)
+	}))
+	ops, err := api.GetInternalOperations(ctx, transfer)
+	require.NoError(t, err)
+	require.Empty(t, ops)
+	require.NotNil(t, ops)
+
+	// the last block isn't backfilled yet
+	_, err = api.GetInternalOperations(ctx, txHash)
+	var pruned *rpc.PrunedError
+	require.ErrorAs(t, err, &pruned)
+	require.Equal(t, last.NumberU64()-1, pruned.Block) // state after the parent block
+
+	require.NoError(t, api.operations.put(ctx, 1, last.NumberU64(), map[common.Hash][]byte{txHash: append(last.Hash().Bytes(), encodeInternalOperations(traced)...)}, nil))
+	ops, err = api.GetInternalOperations(ctx, txHash)
+	require.NoError(t, err)
+	expected, err := json.Marshal(traced) // zero values are big.Int of another representation
+	require.NoError(t, err)
+	actual, err := json.Marshal(ops)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(actual))
+
+	caps, err := api.GetCapabilities(ctx)
+	require.NoError(t, err)
+	require.Equal(t, OtsIndex{Name: "internalOperations", Enabled: true, Progress: hexutil.Uint64(last.NumberU64()), AvailableFrom: 1}, caps.Indices[len(caps.Indices)-1])
+
+	// blocks the backfill couldn't trace report the failure
+	require.NoError(t, api.operations.put(ctx, 1, last.NumberU64(), nil, map[uint64]string{1: "out of gas"}))
+	_, err = api.GetInternalOperations(ctx, transfer)
+	require.ErrorContains(t, err, "out of gas")
+
+	// without the index the pruned state is reported
+	api.operations = nil
+	_, err = api.GetInternalOperations(ctx, transfer)
+	require.ErrorAs(t, err, &pruned)
+	require.Equal(t, last.NumberU64(), pruned.AvailableFrom)
+}
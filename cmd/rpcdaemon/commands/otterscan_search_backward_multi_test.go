@@ -2,6 +2,9 @@ package commands
 
 import (
 	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/vm"
 )
 
 func TestFromToBackwardBlockProviderWith1Chunk(t *testing.T) {
@@ -107,3 +110,54 @@ func TestFromToBackwardBlockProviderWithMultipleChunksBlockBetweenChunks(t *test
 	checkNext(t, blockProvider, 1005, true)
 	checkNext(t, blockProvider, 1000, false)
 }
+
+func TestDirectionBackwardBlockProvider(t *testing.T) {
+	from := createBitmap(t, []uint64{1000, 1010})
+	to := createBitmap(t, []uint64{1005})
+	providers := func() (BlockProvider, BlockProvider) {
+		return NewBackwardBlockProvider(newMockBackwardChunkLocator([][]byte{from}), 0),
+			NewBackwardBlockProvider(newMockBackwardChunkLocator([][]byte{to}), 0)
+	}
+
+	fromProvider, toProvider := providers()
+	blockProvider := SearchFrom.blockProvider(false, fromProvider, toProvider)
+	checkNext(t, blockProvider, 1010, true)
+	checkNext(t, blockProvider, 1000, false)
+
+	fromProvider, toProvider = providers()
+	blockProvider = SearchTo.blockProvider(false, fromProvider, toProvider)
+	checkNext(t, blockProvider, 1005, false)
+}
+
+func TestSearchDirection(t *testing.T) {
+	dir := func(s string) *SearchDirection { d := SearchDirection(s); return &d }
+	for _, tc := range []struct {
+		direction *SearchDirection
+		expected  SearchDirection
+	}{{nil, SearchBoth}, {dir(""), SearchBoth}, {dir("from"), SearchFrom}, {dir("to"), SearchTo}, {dir("both"), SearchBoth}} {
+		d, err := tc.direction.orBoth()
+		if err != nil || d != tc.expected {
+			t.Fatalf("expected %q, got %q, %v", tc.expected, d, err)
+		}
+	}
+	if _, err := dir("in").orBoth(); err == nil {
+		t.Fatal("expected error for invalid direction")
+	}
+
+	addr, other := common.Address{1}, common.Address{2}
+	for _, tc := range []struct {
+		direction SearchDirection
+		from, to  common.Address
+		found     bool
+	}{
+		{SearchBoth, addr, other, true}, {SearchBoth, other, addr, true},
+		{SearchFrom, addr, other, true}, {SearchFrom, other, addr, false},
+		{SearchTo, addr, other, false}, {SearchTo, other, addr, true},
+	} {
+		tracer := NewTouchTracer(addr, tc.direction)
+		tracer.CaptureStart(nil, 0, tc.from, tc.to, false, false, vm.CALLT, nil, 0, nil, nil)
+		if tracer.Found != tc.found {
+			t.Fatalf("direction %s, from %x, to %x: expected found=%t", tc.direction, tc.from, tc.to, tc.found)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// searchCacheKey - results are of the canonical block with the hash, entries of reorged blocks are never hit
+type searchCacheKey struct {
+	addr      common.Address
+	direction SearchDirection
+	blockHash common.Hash
+}
+
+// activityCacheKey - items of ots_getAddressActivity of the canonical block with the hash
+type activityCacheKey struct {
+	addr      common.Address
+	blockHash common.Hash
+}
+
+// searchCache - indices of transactions found by tracing a block for an address, so that paging back and forth
+// through the search results of the address doesn't replay the same blocks, and activity items of the address in the
+// block. Methods of nil do nothing.
+type searchCache struct {
+	blocks *lru.Cache // searchCacheKey -> []uint64, activityCacheKey -> []*ActivityItem
+}
+
+func newSearchCache(size int) *searchCache {
+	blocks, err := lru.New(size)
+	if err != nil {
+		panic(err)
+	}
+	return &searchCache{blocks: blocks}
+}
+
+func (c *searchCache) get(key searchCacheKey) ([]uint64, bool) {
+	if c == nil {
+		return nil, false
+	}
+	found, ok := c.blocks.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return found.([]uint64), true
+}
+
+func (c *searchCache) add(key searchCacheKey, found []uint64) {
+	if c == nil {
+		return
+	}
+	c.blocks.Add(key, found)
+}
+
+func (c *searchCache) getActivity(key activityCacheKey) ([]*ActivityItem, bool) {
+	if c == nil {
+		return nil, false
+	}
+	items, ok := c.blocks.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return items.([]*ActivityItem), true
+}
+
+func (c *searchCache) addActivity(key activityCacheKey, items []*ActivityItem) {
+	if c == nil {
+		return
+	}
+	c.blocks.Add(key, items)
+}
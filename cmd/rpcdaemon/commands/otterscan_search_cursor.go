@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+)
+
+const searchCursorVersion = 1
+
+// searchCursor - position of the last transaction of a page of ots_searchTransactionsBefore/After, the next page
+// continues right after it, also inside of the same block. Clients treat it as an opaque string.
+type searchCursor struct {
+	forward bool // of ots_searchTransactionsAfter
+	block   uint64
+	txIndex uint64
+}
+
+func (c searchCursor) String() string {
+	var b [18]byte
+	b[0] = searchCursorVersion
+	if c.forward {
+		b[1] = 1
+	}
+	binary.BigEndian.PutUint64(b[2:], c.block)
+	binary.BigEndian.PutUint64(b[10:], c.txIndex)
+	return hexutil.Encode(b[:])
+}
+
+func parseSearchCursor(s string, forward bool) (*searchCursor, error) {
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != 18 || b[0] != searchCursorVersion || b[1] > 1 {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	c := &searchCursor{forward: b[1] == 1, block: binary.BigEndian.Uint64(b[2:]), txIndex: binary.BigEndian.Uint64(b[10:])}
+	if c.forward != forward {
+		return nil, fmt.Errorf("cursor %q is of the other search direction", s)
+	}
+	return c, nil
+}
+
+// searchPage - pagination of one ots_searchTransactionsBefore/After call. Without the cursor parameter pages end at
+// block boundaries, so a page may have more than pageSize transactions. With it, "" for the first page, pages have
+// exactly pageSize transactions and continue from the cursor of the previous page.
+type searchPage struct {
+	forward bool
+	exact   bool          // the cursor parameter is given
+	resume  *searchCursor // nil on the first page
+}
+
+func newSearchPage(cursor *string, forward bool) (*searchPage, error) {
+	p := &searchPage{forward: forward, exact: cursor != nil}
+	if cursor != nil && *cursor != "" {
+		var err error
+		if p.resume, err = parseSearchCursor(*cursor, forward); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// skipReturned drops transactions of the cursor's block returned by the previous page. Transactions of a block are
+// in ascending order.
+func (p *searchPage) skipReturned(r *TransactionsWithReceipts) *TransactionsWithReceipts {
+	if p.resume == nil || len(r.Txs) == 0 || r.Txs[0].BlockNumber.ToInt().Uint64() != p.resume.block {
+		return r
+	}
+	res := &TransactionsWithReceipts{}
+	for i, tx := range r.Txs {
+		idx := uint64(*tx.TransactionIndex)
+		if (p.forward && idx > p.resume.txIndex) || (!p.forward && idx < p.resume.txIndex) {
+			res.Txs = append(res.Txs, tx)
+			res.Receipts = append(res.Receipts, r.Receipts[i])
+		}
+	}
+	return res
+}
+
+// trim cuts the transactions, in search order, to pageSize if pages are exact
+func (p *searchPage) trim(txs []*RPCTransaction, receipts []map[string]interface{}, pageSize uint16) ([]*RPCTransaction, []map[string]interface{}, bool) {
+	if !p.exact || len(txs) <= int(pageSize) {
+		return txs, receipts, false
+	}
+	return txs[:pageSize], receipts[:pageSize], true
+}
+
+// next - cursor of the last transaction of the page, in search order, nil if there are no more pages
+func (p *searchPage) next(txs []*RPCTransaction, more bool) *string {
+	if !more || len(txs) == 0 {
+		return nil
+	}
+	last := txs[len(txs)-1]
+	s := searchCursor{forward: p.forward, block: last.BlockNumber.ToInt().Uint64(), txIndex: uint64(*last.TransactionIndex)}.String()
+	return &s
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func cursorTestTxs(block uint64, indices ...uint64) *TransactionsWithReceipts {
+	r := &TransactionsWithReceipts{}
+	for _, idx := range indices {
+		idx := hexutil.Uint64(idx)
+		r.Txs = append(r.Txs, &RPCTransaction{BlockNumber: (*hexutil.Big)(new(big.Int).SetUint64(block)), TransactionIndex: &idx})
+		r.Receipts = append(r.Receipts, map[string]interface{}{"transactionIndex": idx})
+	}
+	return r
+}
+
+func TestSearchCursor(t *testing.T) {
+	c := searchCursor{forward: true, block: 15_000_000, txIndex: 7}
+	parsed, err := parseSearchCursor(c.String(), true)
+	require.NoError(t, err)
+	require.Equal(t, c, *parsed)
+
+	_, err = parseSearchCursor(c.String(), false)
+	require.Error(t, err)
+	for _, s := range []string{"0x", "0x01", "not hex", c.String()[:len(c.String())-2]} {
+		_, err = parseSearchCursor(s, true)
+		require.Error(t, err, s)
+	}
+}
+
+func TestSearchPage(t *testing.T) {
+	empty := ""
+	page, err := newSearchPage(&empty, false)
+	require.NoError(t, err)
+	require.True(t, page.exact)
+	require.Nil(t, page.resume)
+
+	page, err = newSearchPage(nil, false)
+	require.NoError(t, err)
+	require.False(t, page.exact)
+	txs := cursorTestTxs(10, 0, 1, 2)
+	trimmed, receipts, cut := page.trim(txs.Txs, txs.Receipts, 1)
+	require.False(t, cut)
+	require.Len(t, trimmed, 3)
+	require.Len(t, receipts, 3)
+	require.Nil(t, page.next(txs.Txs, false))
+
+	// backward: the first page ends at tx 2 of block 10, searched in descending order
+	page, err = newSearchPage(&empty, false)
+	require.NoError(t, err)
+	desc := []*RPCTransaction{txs.Txs[2], txs.Txs[1], txs.Txs[0]}
+	trimmed, _, cut = page.trim(desc, txs.Receipts, 1)
+	require.True(t, cut)
+	require.Len(t, trimmed, 1)
+	next := page.next(trimmed, true)
+	require.NotNil(t, next)
+
+	page, err = newSearchPage(next, false)
+	require.NoError(t, err)
+	rest := page.skipReturned(txs)
+	require.Len(t, rest.Txs, 2)
+	require.Len(t, rest.Receipts, 2)
+	require.Equal(t, hexutil.Uint64(0), *rest.Txs[0].TransactionIndex)
+	require.Equal(t, hexutil.Uint64(1), *rest.Txs[1].TransactionIndex)
+	require.Equal(t, cursorTestTxs(9, 5), page.skipReturned(cursorTestTxs(9, 5)))
+
+	// forward: the first page ends at tx 1 of block 10
+	page, err = newSearchPage(&empty, true)
+	require.NoError(t, err)
+	next = page.next(txs.Txs[:2], true)
+	page, err = newSearchPage(next, true)
+	require.NoError(t, err)
+	rest = page.skipReturned(txs)
+	require.Len(t, rest.Txs, 1)
+	require.Equal(t, hexutil.Uint64(2), *rest.Txs[0].TransactionIndex)
+	_, err = newSearchPage(next, false)
+	require.Error(t, err)
+}
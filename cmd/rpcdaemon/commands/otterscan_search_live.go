@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// SearchTransactions - ots_subscribe "searchTransactions": transactions of the address in new blocks, notified live as
+// the blocks are executed (new heads announced by the Finish stage), so address pages don't need to poll the search.
+// Notifications are SearchStreamChunk of one block, like of SearchTransactionsBeforeStream; after a reorg the blocks of
+// the new chain are searched again from its forking height. A failure is notified as {"done":true,"error"} and ends it.
+func (api *OtterscanAPIImpl) SearchTransactions(ctx context.Context, addr common.Address, direction *SearchDirection, compact *bool) (*rpc.Subscription, error) {
+	if api.filters == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	dir, err := direction.orBoth()
+	if err != nil {
+		return nil, err
+	}
+
+	// subscribed before reading the head, so that no block is missed in between
+	heads := make(chan *types.Header, 16)
+	headsID := api.filters.SubscribeNewHeads(heads)
+	latest, err := api.latestBlockNumber(ctx)
+	if err != nil {
+		go func() {
+			for range heads {
+			}
+		}()
+		api.filters.UnsubscribeHeads(headsID)
+		return nil, err
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	pending := &liveSearchHeads{wake: make(chan struct{}, 1)}
+	searchCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer debug.LogPanic()
+		defer func() {
+			cancel()
+			// the filters may be sending a header while the subscription is removed
+			go func() {
+				for range heads {
+				}
+			}()
+			api.filters.UnsubscribeHeads(headsID)
+		}()
+		for {
+			select {
+			case h, ok := <-heads:
+				if !ok {
+					return
+				}
+				pending.add(h.Number.Uint64())
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-searchCtx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer debug.LogPanic()
+		defer cancel()
+		compact := compact != nil && *compact
+		notify := func(chunk *SearchStreamChunk) error { return notifier.Notify(rpcSub.ID, chunk) }
+		next := latest + 1
+		for {
+			select {
+			case <-pending.wake:
+			case <-searchCtx.Done():
+				return
+			}
+			from, to, ok := pending.take()
+			if !ok {
+				continue
+			}
+			if from < next {
+				next = from // reorg, blocks of the new chain replace the searched ones
+			}
+			if err := api.searchLiveBlocks(searchCtx, addr, dir, next, to, compact, notify); err != nil {
+				if searchCtx.Err() != nil {
+					return // unsubscribed
+				}
+				if err := notify(&SearchStreamChunk{Done: true, Error: err.Error()}); err != nil {
+					log.Warn("error while notifying subscription", "err", err)
+				}
+				return
+			}
+			next = to + 1
+		}
+	}()
+	return rpcSub, nil
+}
+
+func (api *OtterscanAPIImpl) latestBlockNumber(ctx context.Context) (uint64, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	return rpchelper.GetLatestBlockNumber(tx)
+}
+
+// liveSearchHeads - heights of new heads not searched yet, collected while the previous ones are traced so that
+// the filters aren't blocked by a slow search
+type liveSearchHeads struct {
+	lock     sync.Mutex
+	from, to uint64
+	has      bool
+	wake     chan struct{}
+}
+
+func (p *liveSearchHeads) add(number uint64) {
+	p.lock.Lock()
+	if !p.has || number < p.from {
+		p.from = number
+	}
+	if !p.has || number > p.to {
+		p.to = number
+	}
+	p.has = true
+	p.lock.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the lowest and the highest of the heads added since the previous call
+func (p *liveSearchHeads) take() (from, to uint64, ok bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	from, to, ok = p.from, p.to, p.has
+	p.has = false
+	return from, to, ok
+}
+
+// searchLiveBlocks notifies matches of blocks [from, to] found by the call index, ascending
+func (api *OtterscanAPIImpl) searchLiveBlocks(ctx context.Context, addr common.Address, dir SearchDirection, from, to uint64, compact bool, notify func(*SearchStreamChunk) error) error {
+	dbtx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbtx.Rollback()
+
+	callFromCursor, err := dbtx.Cursor(kv.CallFromIndex)
+	if err != nil {
+		return err
+	}
+	defer callFromCursor.Close()
+
+	callToCursor, err := dbtx.Cursor(kv.CallToIndex)
+	if err != nil {
+		return err
+	}
+	defer callToCursor.Close()
+
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return err
+	}
+
+	blockProvider := dir.blockProvider(true, NewCallCursorForwardBlockProvider(callFromCursor, addr, from), NewCallCursorForwardBlockProvider(callToCursor, addr, from))
+	for {
+		blockNum, hasMore, err := blockProvider()
+		if err != nil {
+			return err
+		}
+		if (!hasMore && blockNum == 0) || blockNum > to {
+			return nil
+		}
+		r, err := api.searchTraceBlockPooled(ctx, addr, dir, chainConfig, blockNum)
+		if err != nil {
+			return err
+		}
+		if r != nil && len(r.Txs) > 0 {
+			if err := notify(newSearchBlockChunk(r.Txs, r.Receipts, compact)); err != nil {
+				return err
+			}
+		}
+		if !hasMore {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchLiveBlocks(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+	addr := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	ctx := context.Background()
+
+	// the whole chain at once gives the same blocks as the forward search
+	page, err := newSearchPage(nil, true)
+	require.NoError(t, err)
+	var expected []*SearchStreamChunk
+	_, err = api.searchChunks(ctx, addr, 0, math.MaxUint16, SearchBoth, page, false, func(chunk *SearchStreamChunk) error {
+		expected = append(expected, chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Greater(t, len(expected), 2)
+
+	var chunks []*SearchStreamChunk
+	notify := func(chunk *SearchStreamChunk) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}
+	require.NoError(t, api.searchLiveBlocks(ctx, addr, SearchBoth, 0, math.MaxUint64, false, notify))
+	require.Equal(t, expected, chunks)
+
+	// only blocks of the range
+	from, to := uint64(*expected[1].BlockNumber), uint64(*expected[len(expected)-2].BlockNumber)
+	chunks = nil
+	require.NoError(t, api.searchLiveBlocks(ctx, addr, SearchBoth, from, to, false, notify))
+	require.Equal(t, expected[1:len(expected)-1], chunks)
+}
+
+func TestLiveSearchHeads(t *testing.T) {
+	heads := &liveSearchHeads{wake: make(chan struct{}, 1)}
+	_, _, ok := heads.take()
+	require.False(t, ok)
+
+	heads.add(10)
+	heads.add(11)
+	heads.add(9) // reorg
+	<-heads.wake
+	from, to, ok := heads.take()
+	require.True(t, ok)
+	require.Equal(t, uint64(9), from)
+	require.Equal(t, uint64(11), to)
+
+	_, _, ok = heads.take()
+	require.False(t, ok)
+}
@@ -1,5 +1,40 @@
 package commands
 
+import "fmt"
+
+// SearchDirection - which transactions of an address ots_searchTransactionsBefore/After return: calling from the
+// address, to it, or both
+type SearchDirection string
+
+const (
+	SearchBoth SearchDirection = "both"
+	SearchFrom SearchDirection = "from"
+	SearchTo   SearchDirection = "to"
+)
+
+// orBoth validates the optional direction parameter, SearchBoth if it's omitted
+func (d *SearchDirection) orBoth() (SearchDirection, error) {
+	if d == nil || *d == "" {
+		return SearchBoth, nil
+	}
+	switch *d {
+	case SearchBoth, SearchFrom, SearchTo:
+		return *d, nil
+	}
+	return "", fmt.Errorf("invalid direction %q, expected %q, %q or %q", *d, SearchFrom, SearchTo, SearchBoth)
+}
+
+// blockProvider - blocks of the CallFromIndex, the CallToIndex, or of both of them merged
+func (d SearchDirection) blockProvider(isBackwards bool, callFromProvider, callToProvider BlockProvider) BlockProvider {
+	switch d {
+	case SearchFrom:
+		return callFromProvider
+	case SearchTo:
+		return callToProvider
+	}
+	return newCallFromToBlockProvider(isBackwards, callFromProvider, callToProvider)
+}
+
 func newCallFromToBlockProvider(isBackwards bool, callFromProvider, callToProvider BlockProvider) BlockProvider {
 	var nextFrom, nextTo uint64
 	var hasMoreFrom, hasMoreTo bool
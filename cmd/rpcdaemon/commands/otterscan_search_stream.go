@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// SearchStreamChunk - a notification of the subscription variants of ots_searchTransactionsBefore/After: matches of one
+// block, sorted descending like pages of the search, or the final summary with Done set
+type SearchStreamChunk struct {
+	BlockNumber *hexutil.Uint64          `json:"blockNumber,omitempty"`
+	Txs         []*RPCTransaction        `json:"txs,omitempty"`
+	Receipts    []map[string]interface{} `json:"receipts,omitempty"`
+
+	Done       bool           `json:"done,omitempty"`
+	TxCount    hexutil.Uint64 `json:"txCount,omitempty"` // Of all chunks
+	FirstPage  bool           `json:"firstPage,omitempty"`
+	LastPage   bool           `json:"lastPage,omitempty"`
+	NextCursor *string        `json:"nextCursor,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// SearchTransactionsBeforeStream - subscription variant of ots_searchTransactionsBefore with the same parameters
+// (ots_subscribe "searchTransactionsBeforeStream"). Matches are notified block by block as soon as the block is traced,
+// in search order, instead of waiting for the whole page. The last notification is the summary of the page.
+func (api *OtterscanAPIImpl) SearchTransactionsBeforeStream(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, direction *SearchDirection, cursor *string, compact *bool) (*rpc.Subscription, error) {
+	return api.searchStream(ctx, addr, blockNum, pageSize, direction, cursor, compact != nil && *compact, false)
+}
+
+// SearchTransactionsAfterStream - subscription variant of ots_searchTransactionsAfter, see SearchTransactionsBeforeStream
+func (api *OtterscanAPIImpl) SearchTransactionsAfterStream(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, direction *SearchDirection, cursor *string, compact *bool) (*rpc.Subscription, error) {
+	return api.searchStream(ctx, addr, blockNum, pageSize, direction, cursor, compact != nil && *compact, true)
+}
+
+func (api *OtterscanAPIImpl) searchStream(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, direction *SearchDirection, cursor *string, compact, forward bool) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	// parameters are checked before subscribing, their errors are errors of the call
+	dir, err := direction.orBoth()
+	if err != nil {
+		return nil, err
+	}
+	page, err := newSearchPage(cursor, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		defer debug.LogPanic()
+		// the context of the call ends with it, the search runs until it's done or unsubscribed
+		searchCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-rpcSub.Err():
+			case <-notifier.Closed():
+			case <-searchCtx.Done():
+			}
+			cancel()
+		}()
+
+		notify := func(chunk *SearchStreamChunk) error { return notifier.Notify(rpcSub.ID, chunk) }
+		summary, err := api.searchChunks(searchCtx, addr, blockNum, pageSize, dir, page, compact, notify)
+		if searchCtx.Err() != nil {
+			return // unsubscribed
+		}
+		if err != nil {
+			summary = &SearchStreamChunk{Done: true, Error: err.Error()}
+		}
+		if err := notify(summary); err != nil {
+			log.Warn("error while notifying subscription", "err", err)
+		}
+	}()
+	return rpcSub, nil
+}
+
+// searchChunks - the search of ots_searchTransactionsBefore/After notifying matches of each block, returns the summary
+func (api *OtterscanAPIImpl) searchChunks(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, dir SearchDirection, page *searchPage, compact bool, notify func(*SearchStreamChunk) error) (*SearchStreamChunk, error) {
+	dbtx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	callFromCursor, err := dbtx.Cursor(kv.CallFromIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer callFromCursor.Close()
+
+	callToCursor, err := dbtx.Cursor(kv.CallToIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer callToCursor.Close()
+
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Internal search code considers blockNum [including], so adjust the value
+	isEdgePage := false
+	if page.resume != nil {
+		blockNum = page.resume.block // the rest of the block where the previous page ended
+	} else if page.exact || blockNum == 0 {
+		blockNum = 0
+		isEdgePage = true
+	} else if page.forward {
+		blockNum++
+	} else {
+		blockNum--
+	}
+	var callFromToProvider BlockProvider
+	if page.forward {
+		callFromToProvider = dir.blockProvider(true, NewCallCursorForwardBlockProvider(callFromCursor, addr, blockNum), NewCallCursorForwardBlockProvider(callToCursor, addr, blockNum))
+	} else {
+		callFromToProvider = dir.blockProvider(false, NewCallCursorBackwardBlockProvider(callFromCursor, addr, blockNum), NewCallCursorBackwardBlockProvider(callToCursor, addr, blockNum))
+	}
+
+	var last *RPCTransaction // in search order
+	resultCount := uint16(0)
+	hasMore, dropped, trimmed := true, false, false
+	for resultCount < pageSize && hasMore {
+		hasMore, dropped, err = api.traceBlocks(ctx, addr, dir, chainConfig, pageSize, resultCount, callFromToProvider, func(r *TransactionsWithReceipts) (bool, error) {
+			r = page.skipReturned(r)
+			if len(r.Txs) == 0 {
+				return true, nil
+			}
+
+			// transactions of a block are ascending, chunks are descending like pages
+			txs, receipts := r.Txs, r.Receipts
+			if page.exact && int(resultCount)+len(txs) > int(pageSize) {
+				n := int(pageSize - resultCount)
+				if page.forward {
+					txs, receipts = txs[:n], receipts[:n]
+				} else {
+					txs, receipts = txs[len(txs)-n:], receipts[len(receipts)-n:]
+				}
+				trimmed = true
+			}
+			if page.forward {
+				last = txs[len(txs)-1]
+			} else {
+				last = txs[0]
+			}
+			if err := notify(newSearchBlockChunk(txs, receipts, compact)); err != nil {
+				return false, err
+			}
+
+			resultCount += uint16(len(txs))
+			return resultCount < pageSize, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	more := hasMore || dropped || trimmed
+	summary := &SearchStreamChunk{Done: true, TxCount: hexutil.Uint64(resultCount)}
+	if last != nil {
+		summary.NextCursor = page.next([]*RPCTransaction{last}, more)
+	}
+	if page.forward {
+		summary.FirstPage, summary.LastPage = !more, isEdgePage
+	} else {
+		summary.FirstPage, summary.LastPage = isEdgePage, !more
+	}
+	return summary, nil
+}
+
+// newSearchBlockChunk - the notification of matches of one block, txs are ascending like transactions of the block
+func newSearchBlockChunk(txs []*RPCTransaction, receipts []map[string]interface{}, compact bool) *SearchStreamChunk {
+	chunk := &SearchStreamChunk{BlockNumber: (*hexutil.Uint64)(new(uint64)), Txs: make([]*RPCTransaction, 0, len(txs)), Receipts: make([]map[string]interface{}, 0, len(receipts))}
+	*chunk.BlockNumber = hexutil.Uint64(txs[0].BlockNumber.ToInt().Uint64())
+	for j := len(txs) - 1; j >= 0; j-- {
+		chunk.Txs = append(chunk.Txs, txs[j])
+		chunk.Receipts = append(chunk.Receipts, receipts[j])
+	}
+	if compact {
+		compactReceipts(chunk.Receipts)
+	}
+	return chunk
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchChunks(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
+	api := NewOtterscanAPI(base, m.DB)
+	addr := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	ctx := context.Background()
+
+	for _, forward := range []bool{false, true} {
+		cursor := ""
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 100)
+			var expected *TransactionsWithReceipts
+			var err error
+			if forward {
+				expected, err = api.SearchTransactionsAfter(ctx, addr, 0, 3, nil, &cursor, nil)
+			} else {
+				expected, err = api.SearchTransactionsBefore(ctx, addr, 0, 3, nil, &cursor, nil)
+			}
+			require.NoError(t, err)
+
+			page, err := newSearchPage(&cursor, forward)
+			require.NoError(t, err)
+			var chunks []*SearchStreamChunk
+			summary, err := api.searchChunks(ctx, addr, 0, 3, SearchBoth, page, false, func(chunk *SearchStreamChunk) error {
+				chunks = append(chunks, chunk)
+				return nil
+			})
+			require.NoError(t, err)
+			require.True(t, summary.Done)
+			require.Equal(t, expected.FirstPage, summary.FirstPage)
+			require.Equal(t, expected.LastPage, summary.LastPage)
+			require.Equal(t, expected.NextCursor, summary.NextCursor)
+			require.Equal(t, len(expected.Txs), int(summary.TxCount))
+
+			// chunks in search order, each descending: pages of After are descending as a whole
+			var txs []*RPCTransaction
+			if forward {
+				for i := len(chunks) - 1; i >= 0; i-- {
+					txs = append(txs, chunks[i].Txs...)
+				}
+			} else {
+				for _, chunk := range chunks {
+					txs = append(txs, chunk.Txs...)
+				}
+			}
+			require.Equal(t, len(expected.Txs), len(txs))
+			for i, tx := range txs {
+				require.Equal(t, expected.Txs[i].Hash, tx.Hash)
+			}
+
+			if summary.NextCursor == nil {
+				break
+			}
+			cursor = *summary.NextCursor
+		}
+	}
+}
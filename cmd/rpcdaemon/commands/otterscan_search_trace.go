@@ -2,10 +2,11 @@ package commands
 
 import (
 	"context"
-	"sync"
+	"fmt"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
@@ -13,35 +14,37 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/shards"
-	"github.com/ledgerwatch/log/v3"
+	"github.com/ledgerwatch/erigon/turbo/transactions"
 )
 
-func (api *OtterscanAPIImpl) searchTraceBlock(ctx context.Context, wg *sync.WaitGroup, addr common.Address, chainConfig *params.ChainConfig, idx int, bNum uint64, results []*TransactionsWithReceipts) {
-	defer wg.Done()
-
-	// Trace block for Txs
+// searchTraceBlock traces the block in its own DB transaction, so that blocks of a page can be traced concurrently
+func (api *OtterscanAPIImpl) searchTraceBlock(ctx context.Context, addr common.Address, direction SearchDirection, chainConfig *params.ChainConfig, bNum uint64) (*TransactionsWithReceipts, error) {
 	newdbtx, err := api.db.BeginRo(ctx)
 	if err != nil {
-		log.Error("Search trace error", "err", err)
-		results[idx] = nil
-		return
+		return nil, err
 	}
 	defer newdbtx.Rollback()
 
-	_, result, err := api.traceBlock(newdbtx, ctx, bNum, addr, chainConfig)
+	_, result, err := api.traceBlock(newdbtx, ctx, bNum, addr, direction, chainConfig)
 	if err != nil {
-		log.Error("Search trace error", "err", err)
-		results[idx] = nil
-		return
+		return nil, fmt.Errorf("search trace of block %d: %w", bNum, err)
 	}
-	results[idx] = result
+	return result, nil
 }
 
-func (api *OtterscanAPIImpl) traceBlock(dbtx kv.Tx, ctx context.Context, blockNum uint64, searchAddr common.Address, chainConfig *params.ChainConfig) (bool, *TransactionsWithReceipts, error) {
-	rpcTxs := make([]*RPCTransaction, 0)
-	receipts := make([]map[string]interface{}, 0)
+// searchTraceBlockPooled waits for a free search worker, blocks of all requests are traced by at most searchWorkers
+// at once so that a search of a busy address can't take all CPUs
+func (api *OtterscanAPIImpl) searchTraceBlockPooled(ctx context.Context, addr common.Address, direction SearchDirection, chainConfig *params.ChainConfig, bNum uint64) (*TransactionsWithReceipts, error) {
+	if err := api.searchWorkers.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	defer api.searchWorkers.Release(1)
+	return api.searchTraceBlock(ctx, addr, direction, chainConfig, bNum)
+}
 
+func (api *OtterscanAPIImpl) traceBlock(dbtx kv.Tx, ctx context.Context, blockNum uint64, searchAddr common.Address, direction SearchDirection, chainConfig *params.ChainConfig) (bool, *TransactionsWithReceipts, error) {
 	// Retrieve the transaction and assemble its EVM context
 	blockHash, err := rawdb.ReadCanonicalHash(dbtx, blockNum)
 	if err != nil {
@@ -52,7 +55,60 @@ func (api *OtterscanAPIImpl) traceBlock(dbtx kv.Tx, ctx context.Context, blockNu
 	if err != nil {
 		return false, nil, err
 	}
+	if block == nil {
+		return false, nil, rpc.NewNotFoundError("block", blockNum)
+	}
+
+	cacheKey := searchCacheKey{addr: searchAddr, direction: direction, blockHash: blockHash}
+	found, ok := api.searchCache.get(cacheKey)
+	if !ok {
+		if found, err = api.searchBlockTxs(dbtx, ctx, block, searchAddr, direction, chainConfig); err != nil {
+			return false, nil, err
+		}
+		api.searchCache.add(cacheKey, found)
+	}
+
+	rpcTxs := make([]*RPCTransaction, 0, len(found))
+	receipts := make([]map[string]interface{}, 0, len(found))
+	if len(found) == 0 {
+		return false, &TransactionsWithReceipts{Txs: rpcTxs, Receipts: receipts}, nil
+	}
+	blockReceipts := rawdb.ReadReceipts(dbtx, block, senders)
+	txs := block.Transactions()
+	for _, idx := range found {
+		tx := txs[idx]
+		rpcTx := newRPCTransaction(tx, block.Hash(), blockNum, idx, block.BaseFee())
+		mReceipt := marshalReceipt(blockReceipts[idx], tx, chainConfig, block, tx.Hash(), true)
+		mReceipt["timestamp"] = block.Time()
+		rpcTxs = append(rpcTxs, rpcTx)
+		receipts = append(receipts, mReceipt)
+	}
+	return true, &TransactionsWithReceipts{Txs: rpcTxs, Receipts: receipts}, nil
+}
+
+// compactReceipt - the fields of a search result receipt an address page shows, the logs are only counted
+func compactReceipt(receipt map[string]interface{}) map[string]interface{} {
+	logs, _ := receipt["logs"].(types.Logs)
+	return map[string]interface{}{
+		"status":            receipt["status"],
+		"gasUsed":           receipt["gasUsed"],
+		"effectiveGasPrice": receipt["effectiveGasPrice"],
+		"timestamp":         receipt["timestamp"],
+		"logCount":          hexutil.Uint64(len(logs)),
+	}
+}
+
+// compactReceipts replaces receipts by their compactReceipt. Receipts of a traced block aren't cached, so they are
+// replaced in place.
+func compactReceipts(receipts []map[string]interface{}) {
+	for i, receipt := range receipts {
+		receipts[i] = compactReceipt(receipt)
+	}
+}
 
+// searchBlockTxs replays the block, returns indices of transactions touching the address in the direction
+func (api *OtterscanAPIImpl) searchBlockTxs(dbtx kv.Tx, ctx context.Context, block *types.Block, searchAddr common.Address, direction SearchDirection, chainConfig *params.ChainConfig) ([]uint64, error) {
+	blockNum := block.NumberU64()
 	reader := state.NewPlainState(dbtx, blockNum)
 	stateCache := shards.NewStateCache(32, 0 /* no limit */)
 	cachedReader := state.NewCachedReader(reader, stateCache)
@@ -62,43 +118,30 @@ func (api *OtterscanAPIImpl) traceBlock(dbtx kv.Tx, ctx context.Context, blockNu
 	ibs := state.New(cachedReader)
 	signer := types.MakeSigner(chainConfig, blockNum)
 
-	getHeader := func(hash common.Hash, number uint64) *types.Header {
-		h, e := api._blockReader.Header(ctx, dbtx, hash, number)
-		if e != nil {
-			log.Error("getHeader error", "number", number, "hash", hash, "err", e)
-		}
-		return h
-	}
+	blockHashes := core.NewCanonicalBlockHashes(ctx, dbtx, api._blockReader)
 	engine := ethash.NewFaker()
 
-	blockReceipts := rawdb.ReadReceipts(dbtx, block, senders)
 	header := block.Header()
 	rules := chainConfig.Rules(block.NumberU64())
-	found := false
+	var found []uint64
 	for idx, tx := range block.Transactions() {
 		ibs.Prepare(tx.Hash(), block.Hash(), idx)
 
 		msg, _ := tx.AsMessage(*signer, header.BaseFee, rules)
 
-		tracer := NewTouchTracer(searchAddr)
-		BlockContext := core.NewEVMBlockContext(header, core.GetHashFn(header, getHeader), engine, nil)
+		tracer := NewTouchTracer(searchAddr, direction)
+		BlockContext := core.NewEVMBlockContext(header, core.BlockHashFn(header, blockHashes), engine, nil)
 		TxContext := core.NewEVMTxContext(msg)
 
 		vmenv := vm.NewEVM(BlockContext, TxContext, ibs, chainConfig, vm.Config{Debug: true, Tracer: tracer})
-		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.GetGas()), true /* refunds */, false /* gasBailout */); err != nil {
-			return false, nil, err
+		if _, err := transactions.ApplyMessage(ctx, vmenv, msg, new(core.GasPool).AddGas(tx.GetGas()), true /* refunds */, false /* gasBailout */); err != nil {
+			return nil, err
 		}
 		_ = ibs.FinalizeTx(vmenv.ChainConfig().Rules(block.NumberU64()), cachedWriter)
 
 		if tracer.Found {
-			rpcTx := newRPCTransaction(tx, block.Hash(), blockNum, uint64(idx), block.BaseFee())
-			mReceipt := marshalReceipt(blockReceipts[idx], tx, chainConfig, block, tx.Hash(), true)
-			mReceipt["timestamp"] = block.Time()
-			rpcTxs = append(rpcTxs, rpcTx)
-			receipts = append(receipts, mReceipt)
-			found = true
+			found = append(found, uint64(idx))
 		}
 	}
-
-	return found, &TransactionsWithReceipts{rpcTxs, receipts, false, false}, nil
+	return found, nil
 }
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestTraceBlocksErrors(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
+	api := NewOtterscanAPI(base, m.DB)
+	addr := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+
+	blocks := func(bNums ...uint64) BlockProvider {
+		return func() (uint64, bool, error) {
+			if len(bNums) == 0 {
+				return 0, false, nil
+			}
+			bNum := bNums[0]
+			bNums = bNums[1:]
+			return bNum, len(bNums) > 0, nil
+		}
+	}
+
+	var results []*TransactionsWithReceipts
+	collect := func(r *TransactionsWithReceipts) (bool, error) {
+		results = append(results, r)
+		return true, nil
+	}
+
+	hasMore, dropped, err := api.traceBlocks(context.Background(), addr, SearchBoth, m.ChainConfig, 3, 0, blocks(1, 2), collect)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.False(t, hasMore)
+	require.False(t, dropped)
+
+	// the page is full with the first block, the second is dropped
+	hasMore, dropped, err = api.traceBlocks(context.Background(), addr, SearchBoth, m.ChainConfig, 2, 0, blocks(1, 2, 3), func(r *TransactionsWithReceipts) (bool, error) {
+		return false, nil
+	})
+	require.NoError(t, err)
+	require.True(t, hasMore)
+	require.True(t, dropped)
+
+	// the error of the block which can't be traced is returned instead of a nil result
+	_, _, err = api.traceBlocks(context.Background(), addr, SearchBoth, m.ChainConfig, 3, 0, blocks(1, 1_000_000, 2), collect)
+	require.ErrorContains(t, err, "block 1000000 not found")
+	var notFound *rpc.NotFoundError
+	require.ErrorAs(t, err, &notFound)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = api.traceBlocks(ctx, addr, SearchBoth, m.ChainConfig, 3, 0, blocks(1, 2), collect)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// one worker traces blocks one by one, requests wait for it
+	api.searchWorkers = semaphore.NewWeighted(1)
+	results = nil
+	_, _, err = api.traceBlocks(context.Background(), addr, SearchBoth, m.ChainConfig, 3, 0, blocks(1, 2), collect)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.True(t, api.searchWorkers.TryAcquire(1))
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = api.traceBlocks(ctx, addr, SearchBoth, m.ChainConfig, 3, 0, blocks(1, 2), collect)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	api.searchWorkers.Release(1)
+}
+
+func TestSearchCache(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
+	api := NewOtterscanAPI(base, m.DB)
+	addr := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	ctx := context.Background()
+
+	var disabled *searchCache
+	disabled.add(searchCacheKey{}, []uint64{1})
+	_, ok := disabled.get(searchCacheKey{})
+	require.False(t, ok)
+
+	uncached, err := api.searchTraceBlock(ctx, addr, SearchBoth, m.ChainConfig, 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, uncached.Txs)
+
+	api.searchCache = newSearchCache(16)
+	cached, err := api.searchTraceBlock(ctx, addr, SearchBoth, m.ChainConfig, 1)
+	require.NoError(t, err)
+	require.Equal(t, uncached, cached)
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	hash, err := rawdb.ReadCanonicalHash(tx, 1)
+	require.NoError(t, err)
+	key := searchCacheKey{addr: addr, direction: SearchBoth, blockHash: hash}
+	found, ok := api.searchCache.get(key)
+	require.True(t, ok)
+	require.Len(t, found, len(uncached.Txs))
+
+	// the next search of the block is served from the cache
+	api.searchCache.add(key, nil)
+	cached, err = api.searchTraceBlock(ctx, addr, SearchBoth, m.ChainConfig, 1)
+	require.NoError(t, err)
+	require.Empty(t, cached.Txs)
+	_, ok = api.searchCache.get(searchCacheKey{addr: addr, direction: SearchFrom, blockHash: hash})
+	require.False(t, ok)
+}
+
+func TestSearchCompact(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout)
+	api := NewOtterscanAPI(base, m.DB)
+	addr := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+	ctx := context.Background()
+
+	compact := true
+	full, err := api.SearchTransactionsBefore(ctx, addr, 0, 10, nil, nil, nil)
+	require.NoError(t, err)
+	res, err := api.SearchTransactionsBefore(ctx, addr, 0, 10, nil, nil, &compact)
+	require.NoError(t, err)
+	require.Equal(t, full.Txs, res.Txs)
+	require.Len(t, res.Receipts, len(full.Receipts))
+	for i, receipt := range res.Receipts {
+		require.Len(t, receipt, 5)
+		for _, field := range []string{"status", "gasUsed", "effectiveGasPrice", "timestamp"} {
+			require.Equal(t, full.Receipts[i][field], receipt[field], field)
+		}
+		logs, _ := full.Receipts[i]["logs"].(types.Logs) // empty ones are [][]*types.Log{}
+		require.Equal(t, hexutil.Uint64(len(logs)), receipt["logCount"])
+	}
+
+	res, err = api.SearchTransactionsAfter(ctx, addr, 0, 10, nil, nil, &compact)
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Receipts)
+	require.Contains(t, res.Receipts[0], "logCount")
+	require.NotContains(t, res.Receipts[0], "logs")
+}
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// TokenTransfer - Transfer log of an ERC-20 (Value) or ERC-721 (TokenID) token
+type TokenTransfer struct {
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash common.Hash    `json:"transactionHash"`
+	LogIndex        hexutil.Uint   `json:"logIndex"`
+	Token           common.Address `json:"token"`
+	From            common.Address `json:"from"`
+	To              common.Address `json:"to"`
+	Value           *hexutil.Big   `json:"value,omitempty"`
+	TokenID         *hexutil.Big   `json:"tokenId,omitempty"`
+}
+
+type TokenTransfersPage struct {
+	Transfers []*TokenTransfer `json:"transfers"` // newest first
+	LastPage  bool             `json:"lastPage"`
+	NextBlock hexutil.Uint64   `json:"nextBlock"` // blockNum of the next page, 0 on the last page
+}
+
+// SearchTokenTransfers implements ots_searchTokenTransfers: token transfers from and to the address in blocks before
+// blockNum (0 - from the latest indexed block), newest first, of all tokens or only of the token. Pages consist of
+// whole blocks with at least pageSize transfers, except the last one. The transfers are found by the index of the
+// TokenTransfers stage.
+func (api *OtterscanAPIImpl) SearchTokenTransfers(ctx context.Context, addr common.Address, token *common.Address, blockNum uint64, pageSize uint16) (*TokenTransfersPage, error) {
+	if pageSize == 0 {
+		return nil, errors.New("page size must be positive")
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if api.historyV3(tx) {
+		return nil, errors.New("token transfers aren't indexed with --history.v3")
+	}
+	progress, err := stages.GetStageProgress(tx, stages.TokenTransfers)
+	if err != nil {
+		return nil, err
+	}
+	if blockNum == 0 || blockNum > progress+1 {
+		blockNum = progress + 1
+	}
+	if blockNum == 0 {
+		return &TokenTransfersPage{Transfers: []*TokenTransfer{}, LastPage: true}, nil
+	}
+
+	blocks, err := tokenTransferBlocks(tx, addr, token, uint32(blockNum-1))
+	if err != nil {
+		return nil, err
+	}
+	page := &TokenTransfersPage{Transfers: []*TokenTransfer{}, LastPage: true}
+	for it := blocks.ReverseIterator(); it.HasNext(); {
+		if len(page.Transfers) >= int(pageSize) {
+			page.LastPage = false
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n := uint64(it.Next())
+		transfers, err := api.blockTokenTransfers(tx, n, addr, token)
+		if err != nil {
+			return nil, err
+		}
+		page.Transfers = append(page.Transfers, transfers...)
+		page.NextBlock = hexutil.Uint64(n)
+	}
+	if page.LastPage {
+		page.NextBlock = 0
+	}
+	return page, nil
+}
+
+// tokenTransferBlocks - blocks up to maxBlock with transfers of the holder, of the token if it's not nil
+func tokenTransferBlocks(tx kv.Tx, holder common.Address, token *common.Address, maxBlock uint32) (*roaring.Bitmap, error) {
+	if token != nil {
+		return bitmapdb.Get(tx, rawdb.TokenTransferIndex, rawdb.TokenTransferKey(holder, *token), 0, maxBlock)
+	}
+	c, err := tx.Cursor(rawdb.TokenTransferIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	blocks := roaring.New()
+	for k, v, err := c.Seek(holder[:]); k != nil && bytes.HasPrefix(k, holder[:]); k, v, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		chunk := roaring.New()
+		if _, err := chunk.FromBuffer(v); err != nil {
+			return nil, fmt.Errorf("token transfers of %x: %w", k[:2*length.Addr], err)
+		}
+		blocks.Or(chunk)
+	}
+	blocks.RemoveRange(uint64(maxBlock)+1, uint64(^uint32(0))+1)
+	return blocks, nil
+}
+
+// blockTokenTransfers - transfers of the holder in the block, from the last one
+func (api *OtterscanAPIImpl) blockTokenTransfers(tx kv.Tx, blockNum uint64, holder common.Address, token *common.Address) ([]*TokenTransfer, error) {
+	block, err := api.blockByNumberWithSenders(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, rpc.NewNotFoundError("block", blockNum)
+	}
+	c, err := tx.Cursor(kv.Log)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var transfers []*TokenTransfer
+	var logIndex uint
+	prefix := dbutils.EncodeBlockNumber(blockNum)
+	for k, v, err := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		var logs types.Logs
+		if err := cbor.Unmarshal(&logs, bytes.NewReader(v)); err != nil {
+			return nil, fmt.Errorf("receipt unmarshal failed: %w, block=%d", err, blockNum)
+		}
+		txIndex := binary.BigEndian.Uint32(k[length.BlockNum:])
+		for _, l := range logs {
+			logIndex++
+			from, to, ok := rawdb.TokenTransfer(l)
+			if !ok || (from != holder && to != holder) || (token != nil && l.Address != *token) {
+				continue
+			}
+			if int(txIndex) >= len(block.Transactions()) {
+				return nil, fmt.Errorf("logs of unknown transaction %d of block %d", txIndex, blockNum)
+			}
+			transfer := &TokenTransfer{
+				BlockNumber:     hexutil.Uint64(blockNum),
+				TransactionHash: block.Transactions()[txIndex].Hash(),
+				LogIndex:        hexutil.Uint(logIndex - 1),
+				Token:           l.Address,
+				From:            from,
+				To:              to,
+			}
+			if len(l.Topics) > 3 {
+				transfer.TokenID = (*hexutil.Big)(new(big.Int).SetBytes(l.Topics[3][:]))
+			} else {
+				transfer.Value = (*hexutil.Big)(new(big.Int).SetBytes(l.Data))
+			}
+			transfers = append(transfers, transfer)
+		}
+	}
+	for i, j := 0, len(transfers)-1; i < j; i, j = i+1, j-1 {
+		transfers[i], transfers[j] = transfers[j], transfers[i]
+	}
+	return transfers, nil
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchTokenTransfers(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	ctx := context.Background()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewOtterscanAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), agg, false, rpccfg.DefaultEvmCallTimeout), m.DB)
+
+	erc20, erc721 := common.Address{0xa1}, common.Address{0xa2}
+	holder, other := common.Address{0xb1}, common.Address{0xb2}
+	transfer := func(token, from, to common.Address, topics ...common.Hash) *types.Log {
+		return &types.Log{Address: token, Topics: append([]common.Hash{rawdb.TransferTopic, from.Hash(), to.Hash()}, topics...), Data: common.LeftPadBytes([]byte{3}, 32)}
+	}
+	// logs of the 1st transaction of block 4 and the 2nd of block 6, indexed the way the TokenTransfers stage does
+	require.NoError(t, m.DB.Update(ctx, func(tx kv.RwTx) error {
+		logs := map[uint64]types.Logs{
+			4: {{Address: erc20, Topics: []common.Hash{{0xff}}}, transfer(erc20, other, holder)},
+			6: {transfer(erc20, holder, other), transfer(erc721, other, holder, common.Hash{31: 7})},
+		}
+		for blockNum, ll := range logs {
+			var buf bytes.Buffer
+			if err := cbor.Marshal(&buf, ll); err != nil {
+				return err
+			}
+			if err := tx.Put(kv.Log, dbutils.LogKey(blockNum, uint32(blockNum/6)), buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		index := map[common.Address]*roaring.Bitmap{erc20: roaring.BitmapOf(4, 6), erc721: roaring.BitmapOf(6)}
+		for token, bm := range index {
+			var buf bytes.Buffer
+			if _, err := bm.WriteTo(&buf); err != nil {
+				return err
+			}
+			k := append(rawdb.TokenTransferKey(holder, token), 0xff, 0xff, 0xff, 0xff)
+			if err := tx.Put(rawdb.TokenTransferIndex, k, buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	txHash := func(blockNum uint64, txIndex int) common.Hash {
+		block, err := api.blockByNumberWithSenders(tx, blockNum)
+		require.NoError(t, err)
+		return block.Transactions()[txIndex].Hash()
+	}
+
+	page, err := api.SearchTokenTransfers(ctx, holder, nil, 0, 1)
+	require.NoError(t, err)
+	require.False(t, page.LastPage)
+	require.Equal(t, hexutil.Uint64(6), page.NextBlock)
+	require.Equal(t, []*TokenTransfer{
+		{BlockNumber: 6, TransactionHash: txHash(6, 1), LogIndex: 1, Token: erc721, From: other, To: holder, TokenID: (*hexutil.Big)(big.NewInt(7))},
+		{BlockNumber: 6, TransactionHash: txHash(6, 1), LogIndex: 0, Token: erc20, From: holder, To: other, Value: (*hexutil.Big)(big.NewInt(3))},
+	}, page.Transfers)
+
+	page, err = api.SearchTokenTransfers(ctx, holder, nil, uint64(page.NextBlock), 1)
+	require.NoError(t, err)
+	require.True(t, page.LastPage)
+	require.Zero(t, page.NextBlock)
+	require.Equal(t, []*TokenTransfer{
+		{BlockNumber: 4, TransactionHash: txHash(4, 0), LogIndex: 1, Token: erc20, From: other, To: holder, Value: (*hexutil.Big)(big.NewInt(3))},
+	}, page.Transfers)
+
+	// transfers of one token
+	page, err = api.SearchTokenTransfers(ctx, holder, &erc721, 0, 10)
+	require.NoError(t, err)
+	require.True(t, page.LastPage)
+	require.Len(t, page.Transfers, 1)
+	require.Equal(t, erc721, page.Transfers[0].Token)
+
+	page, err = api.SearchTokenTransfers(ctx, other, nil, 0, 10)
+	require.NoError(t, err)
+	require.True(t, page.LastPage)
+	require.Empty(t, page.Transfers)
+}
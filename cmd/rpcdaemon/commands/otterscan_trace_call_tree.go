@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+)
+
+// callTreeDataLimit - inputs and outputs of calls are cut to this prefix, enough for the selector and a few arguments;
+// the full input of the top call is the data of the transaction
+const callTreeDataLimit = 256
+
+// TraceTransactionTree implements ots_traceTransactionTree. Returns the calls of the transaction as a tree rooted in the
+// top call, precompiles are left out the same way as in ots_traceTransaction.
+func (api *OtterscanAPIImpl) TraceTransactionTree(ctx context.Context, hash common.Hash) (*CallFrame, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tracer := NewCallTreeTracer(ctx)
+	if _, err := api.runTracer(ctx, tx, hash, tracer); err != nil {
+		return nil, err
+	}
+
+	return tracer.Root, nil
+}
+
+type CallFrame struct {
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	Input   hexutil.Bytes  `json:"input,omitempty"`
+	Gas     hexutil.Uint64 `json:"gas"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Calls   []*CallFrame   `json:"calls,omitempty"`
+}
+
+type CallTreeTracer struct {
+	DefaultTracer
+	ctx   context.Context
+	Root  *CallFrame
+	stack []*CallFrame // frames being executed, nil for precompiles
+}
+
+func NewCallTreeTracer(ctx context.Context) *CallTreeTracer {
+	return &CallTreeTracer{
+		ctx: ctx,
+	}
+}
+
+func callTreeData(data []byte) hexutil.Bytes {
+	if len(data) > callTreeDataLimit {
+		data = data[:callTreeDataLimit]
+	}
+	return common.CopyBytes(data)
+}
+
+func (t *CallTreeTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, callType vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	if precompile {
+		t.stack = append(t.stack, nil)
+		return
+	}
+
+	frame := &CallFrame{From: from, To: to, Input: callTreeData(input), Gas: hexutil.Uint64(gas)}
+	switch callType {
+	case vm.CALLT:
+		frame.Type = "CALL"
+	case vm.STATICCALLT:
+		frame.Type = "STATICCALL"
+	case vm.DELEGATECALLT:
+		frame.Type = "DELEGATECALL"
+	case vm.CALLCODET:
+		frame.Type = "CALLCODE"
+	case vm.CREATET:
+		frame.Type = "CREATE"
+	case vm.CREATE2T:
+		frame.Type = "CREATE2"
+	}
+	if callType != vm.STATICCALLT && callType != vm.DELEGATECALLT {
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+
+	if parent := t.current(); parent != nil {
+		parent.Calls = append(parent.Calls, frame)
+	} else if t.Root == nil {
+		t.Root = frame
+	}
+	t.stack = append(t.stack, frame)
+}
+
+func (t *CallTreeTracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, d time.Duration, err error) {
+	if len(t.stack) == 0 {
+		return
+	}
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	if frame == nil {
+		return
+	}
+	frame.GasUsed = hexutil.Uint64(startGas - endGas)
+	frame.Output = callTreeData(output)
+	if err != nil {
+		frame.Error = err.Error()
+	}
+}
+
+func (t *CallTreeTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+	if parent := t.current(); parent != nil {
+		parent.Calls = append(parent.Calls, &CallFrame{Type: "SELFDESTRUCT", From: from, To: to, Value: (*hexutil.Big)(new(big.Int).Set(value))})
+	}
+}
+
+// current - the innermost frame being executed which isn't a precompile
+func (t *CallTreeTracer) current() *CallFrame {
+	for i := len(t.stack) - 1; i >= 0; i-- {
+		if t.stack[i] != nil {
+			return t.stack[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/runtime"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallTreeTracer(t *testing.T) {
+	tracer := NewCallTreeTracer(context.Background())
+	initCode := []byte{byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.REVERT)}
+	// CREATE2 of the reverting init code, stored at memory[27:32]
+	code := []byte{
+		byte(vm.PUSH5), initCode[0], initCode[1], initCode[2], initCode[3], initCode[4],
+		byte(vm.PUSH1), 0x00,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x01, // salt
+		byte(vm.PUSH1), 0x05, // size
+		byte(vm.PUSH1), 0x1b, // offset
+		byte(vm.PUSH1), 0x00, // value
+		byte(vm.CREATE2),
+		byte(vm.STOP),
+	}
+	_, _, err := runtime.Execute(code, []byte{0x01, 0x02}, &runtime.Config{GasLimit: 1_000_000, EVMConfig: vm.Config{Debug: true, Tracer: tracer}}, 0)
+	require.NoError(t, err)
+
+	contract := common.BytesToAddress([]byte("contract"))
+	root := tracer.Root
+	require.NotNil(t, root)
+	require.Equal(t, "CALL", root.Type)
+	require.Equal(t, contract, root.To)
+	require.Equal(t, hexutil.Bytes{0x01, 0x02}, root.Input)
+	require.Equal(t, hexutil.Uint64(1_000_000), root.Gas)
+	require.NotZero(t, root.GasUsed)
+	require.Empty(t, root.Error)
+
+	require.Len(t, root.Calls, 1)
+	create := root.Calls[0]
+	require.Equal(t, "CREATE2", create.Type)
+	require.Equal(t, contract, create.From)
+	require.Equal(t, crypto.CreateAddress2(contract, common.BytesToHash([]byte{0x01}), crypto.Keccak256(initCode)), create.To)
+	require.Equal(t, hexutil.Bytes(initCode), create.Input)
+	require.Equal(t, vm.ErrExecutionReverted.Error(), create.Error)
+	require.Less(t, uint64(create.GasUsed), uint64(create.Gas))
+	require.Empty(t, create.Calls)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
 )
 
 type OperationType int
@@ -18,17 +19,23 @@ const (
 	OP_CREATE2       OperationType = 3
 )
 
+// InternalOperation - for OP_CREATE2 To is the computed address, Salt and InitCodeHash are the rest of its preimage,
+// so explorers can verify deterministic deployments
 type InternalOperation struct {
-	Type  OperationType  `json:"type"`
-	From  common.Address `json:"from"`
-	To    common.Address `json:"to"`
-	Value *hexutil.Big   `json:"value"`
+	Type         OperationType  `json:"type"`
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Value        *hexutil.Big   `json:"value"`
+	Salt         *common.Hash   `json:"salt,omitempty"`
+	InitCodeHash *common.Hash   `json:"initCodeHash,omitempty"`
 }
 
 type OperationsTracer struct {
 	DefaultTracer
 	ctx     context.Context
 	Results []*InternalOperation
+
+	create2Salt *common.Hash // salt of the CREATE2 being executed, it isn't passed to CaptureStart
 }
 
 func NewOperationsTracer(ctx context.Context) *OperationsTracer {
@@ -44,17 +51,33 @@ func (t *OperationsTracer) CaptureStart(env *vm.EVM, depth int, from common.Addr
 	}
 
 	if calltype == vm.CALLT && value.Uint64() != 0 {
-		t.Results = append(t.Results, &InternalOperation{OP_TRANSFER, from, to, (*hexutil.Big)(value)})
+		t.Results = append(t.Results, &InternalOperation{Type: OP_TRANSFER, From: from, To: to, Value: (*hexutil.Big)(value)})
 		return
 	}
 	if calltype == vm.CREATET {
-		t.Results = append(t.Results, &InternalOperation{OP_CREATE, from, to, (*hexutil.Big)(value)})
+		t.Results = append(t.Results, &InternalOperation{Type: OP_CREATE, From: from, To: to, Value: (*hexutil.Big)(value)})
 	}
 	if calltype == vm.CREATE2T {
-		t.Results = append(t.Results, &InternalOperation{OP_CREATE2, from, to, (*hexutil.Big)(value)})
+		op := &InternalOperation{Type: OP_CREATE2, From: from, To: to, Value: (*hexutil.Big)(value)}
+		// input of creations is the init code
+		if initCodeHash := crypto.Keccak256Hash(input); t.create2Salt != nil && crypto.CreateAddress2(from, *t.create2Salt, initCodeHash.Bytes()) == to {
+			op.Salt = t.create2Salt
+			op.InitCodeHash = &initCodeHash
+		}
+		t.create2Salt = nil
+		t.Results = append(t.Results, op)
+	}
+}
+
+func (t *OperationsTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op != vm.CREATE2 || err != nil {
+		return
 	}
+	// stack of CREATE2: value, offset, size, salt
+	salt := common.Hash(scope.Stack.Back(3).Bytes32())
+	t.create2Salt = &salt
 }
 
 func (l *OperationsTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
-	l.Results = append(l.Results, &InternalOperation{OP_SELF_DESTRUCT, from, to, (*hexutil.Big)(value)})
+	l.Results = append(l.Results, &InternalOperation{Type: OP_SELF_DESTRUCT, From: from, To: to, Value: (*hexutil.Big)(value)})
 }
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/runtime"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationsTracerCreate2(t *testing.T) {
+	tracer := NewOperationsTracer(context.Background())
+	// CREATE2 of the 1 byte init code 0x00 in memory, with salt 0x2a
+	code := []byte{
+		byte(vm.PUSH1), 0x2a, // salt
+		byte(vm.PUSH1), 0x01, // size
+		byte(vm.PUSH1), 0x00, // offset
+		byte(vm.PUSH1), 0x00, // value
+		byte(vm.CREATE2),
+		byte(vm.STOP),
+	}
+	_, _, err := runtime.Execute(code, nil, &runtime.Config{EVMConfig: vm.Config{Debug: true, Tracer: tracer}}, 0)
+	require.NoError(t, err)
+
+	contract := common.BytesToAddress([]byte("contract"))
+	salt := common.BytesToHash([]byte{0x2a})
+	initCodeHash := crypto.Keccak256Hash([]byte{0x00})
+	require.Len(t, tracer.Results, 1)
+	op := tracer.Results[0]
+	require.Equal(t, OP_CREATE2, op.Type)
+	require.Equal(t, contract, op.From)
+	require.Equal(t, crypto.CreateAddress2(contract, salt, initCodeHash.Bytes()), op.To)
+	require.Equal(t, &salt, op.Salt)
+	require.Equal(t, &initCodeHash, op.InitCodeHash)
+}
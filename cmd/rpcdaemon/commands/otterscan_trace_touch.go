@@ -11,17 +11,25 @@ import (
 type TouchTracer struct {
 	DefaultTracer
 	searchAddr common.Address
+	direction  SearchDirection
 	Found      bool
 }
 
-func NewTouchTracer(searchAddr common.Address) *TouchTracer {
+func NewTouchTracer(searchAddr common.Address, direction SearchDirection) *TouchTracer {
 	return &TouchTracer{
 		searchAddr: searchAddr,
+		direction:  direction,
 	}
 }
 
 func (t *TouchTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, calltype vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
-	if !t.Found && (bytes.Equal(t.searchAddr.Bytes(), from.Bytes()) || bytes.Equal(t.searchAddr.Bytes(), to.Bytes())) {
+	if t.Found {
+		return
+	}
+	if t.direction != SearchTo && bytes.Equal(t.searchAddr.Bytes(), from.Bytes()) {
+		t.Found = true
+	}
+	if t.direction != SearchFrom && bytes.Equal(t.searchAddr.Bytes(), to.Bytes()) {
 		t.Found = true
 	}
 }
@@ -44,6 +44,9 @@ func (api *OtterscanAPIImpl) GetTransactionBySenderAndNonce(ctx context.Context,
 	var acc accounts.Account
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if k == nil || !bytes.HasPrefix(k, addr.Bytes()) {
 			// Check plain state
 			data, err := tx.GetOne(kv.PlainState, addr.Bytes())
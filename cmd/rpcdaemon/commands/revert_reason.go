@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/transactions"
+)
+
+// RevertReason describes why a transaction failed
+type RevertReason struct {
+	Error     string        `json:"error"`               // Error of the execution, e.g. "execution reverted" or "out of gas"
+	Reason    string        `json:"reason,omitempty"`    // Decoded Error(string) message or description of the Panic(uint256) code
+	PanicCode *hexutil.Big  `json:"panicCode,omitempty"` // Code of Panic(uint256), if the transaction reverted with it
+	Data      hexutil.Bytes `json:"data,omitempty"`      // Raw return data
+}
+
+// decodeRevertReason builds the RevertReason of a failed execution result
+func decodeRevertReason(result *core.ExecutionResult) *RevertReason {
+	res := &RevertReason{Error: result.Err.Error()}
+	data := result.Revert()
+	if len(data) == 0 {
+		return res
+	}
+	res.Data = data
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		res.Reason = reason
+	} else if code, reason, err := abi.UnpackPanic(data); err == nil {
+		res.PanicCode = (*hexutil.Big)(code)
+		res.Reason = reason
+	}
+	return res
+}
+
+type revertReasonKey struct {
+	block, txn common.Hash
+}
+
+// revertReason replays the transaction with the given index in the block and returns why it failed,
+// or nil if it succeeded. Results are cached, keyed by block and transaction hash.
+func (api *BaseAPI) revertReason(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, txIndex uint64) (*RevertReason, error) {
+	txn := block.Transactions()[txIndex]
+	key := revertReasonKey{block: block.Hash(), txn: txn.Hash()}
+	if cached, ok := api.revertReasons.Get(key); ok {
+		return cached.(*RevertReason), nil
+	}
+
+	blockHashes := core.NewCanonicalBlockHashes(ctx, tx, api._blockReader)
+	msg, blockCtx, txCtx, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, blockHashes, ethash.NewFaker(), tx, block.Hash(), txIndex)
+	if err != nil {
+		return nil, err
+	}
+	vmenv := vm.NewEVM(blockCtx, txCtx, ibs, chainConfig, vm.Config{})
+	result, err := transactions.ApplyMessage(ctx, vmenv, msg, new(core.GasPool).AddGas(msg.Gas()), true, false /* gasBailout */)
+	if err != nil {
+		return nil, fmt.Errorf("replaying transaction %#x: %w", txn.Hash(), err)
+	}
+
+	var reason *RevertReason
+	if result.Failed() {
+		reason = decodeRevertReason(result)
+	}
+	api.revertReasons.Add(key, reason)
+	return reason, nil
+}
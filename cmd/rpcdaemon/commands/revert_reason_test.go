@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeRevertReason(t *testing.T) {
+	errorString := common.Hex2Bytes("08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000d72657665727420726561736f6e00000000000000000000000000000000000000")
+	reason := decodeRevertReason(&core.ExecutionResult{Err: vm.ErrExecutionReverted, ReturnData: errorString})
+	require.Equal(t, "execution reverted", reason.Error)
+	require.Equal(t, "revert reason", reason.Reason)
+	require.Nil(t, reason.PanicCode)
+
+	panicData := common.Hex2Bytes("4e487b710000000000000000000000000000000000000000000000000000000000000012")
+	reason = decodeRevertReason(&core.ExecutionResult{Err: vm.ErrExecutionReverted, ReturnData: panicData})
+	require.Equal(t, "division or modulo by zero", reason.Reason)
+	require.Equal(t, uint64(0x12), reason.PanicCode.ToInt().Uint64())
+
+	reason = decodeRevertReason(&core.ExecutionResult{Err: vm.ErrOutOfGas})
+	require.Equal(t, vm.ErrOutOfGas.Error(), reason.Error)
+	require.Empty(t, reason.Reason)
+	require.Empty(t, reason.Data)
+}
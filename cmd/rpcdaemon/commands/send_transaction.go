@@ -20,7 +20,11 @@ import (
 )
 
 // SendRawTransaction implements eth_sendRawTransaction. Creates new message call transaction or a contract creation for previously-signed transactions.
+// Transactions in ScheduledTxType envelope are held until their min block/timestamp.
 func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	if len(encodedTx) > 0 && encodedTx[0] == ScheduledTxType {
+		return api.sendScheduledTransaction(ctx, encodedTx[1:])
+	}
 	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(encodedTx), uint64(len(encodedTx))))
 	if err != nil {
 		return common.Hash{}, err
@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	txPoolProto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// ScheduledTxType - first byte of the envelope of a transaction which is valid only from some block/timestamp
+// (used on private networks): ScheduledTxType || rlp([minBlock, minTimestamp, rawTx]), where rawTx is what
+// eth_sendRawTransaction takes. 0x7f is the last EIP-2718 type, not used by any transaction type.
+const ScheduledTxType = 0x7f
+
+type scheduledTxEnvelope struct {
+	MinBlock     uint64
+	MinTimestamp uint64
+	Tx           []byte
+}
+
+type scheduledTx struct {
+	hash         common.Hash
+	sender       common.Address
+	nonce        uint64
+	minBlock     uint64
+	minTimestamp uint64
+	raw          []byte
+}
+
+// ready - the transaction can be included into the block after head
+func (t *scheduledTx) ready(head *types.Header) bool {
+	return head.Number.Uint64()+1 >= t.minBlock && head.Time >= t.minTimestamp
+}
+
+// scheduledTxs holds scheduled transactions in memory of rpcdaemon (the pool doesn't know about them) and
+// adds them to the pool when a new head makes them valid
+type scheduledTxs struct {
+	cfg    httpcfg.ScheduledTxsCfg
+	txPool txPoolProto.TxpoolClient
+
+	lock     sync.Mutex
+	txs      map[common.Hash]*scheduledTx
+	bySender map[common.Address]int
+}
+
+func newScheduledTxs(cfg httpcfg.ScheduledTxsCfg, txPool txPoolProto.TxpoolClient, filters *rpchelper.Filters) *scheduledTxs {
+	s := &scheduledTxs{cfg: cfg, txPool: txPool, txs: map[common.Hash]*scheduledTx{}, bySender: map[common.Address]int{}}
+	if filters == nil {
+		return s
+	}
+	heads := make(chan *types.Header, 1)
+	filters.SubscribeNewHeads(heads)
+	latest := make(chan *types.Header, 1)
+	go func() {
+		defer close(latest)
+		for h := range heads { // headers are sent synchronously to all subscribers - only the latest one is kept
+			select {
+			case <-latest:
+			default:
+			}
+			latest <- h
+		}
+	}()
+	go func() {
+		for h := range latest {
+			s.release(context.Background(), h)
+		}
+	}()
+	return s
+}
+
+func (s *scheduledTxs) add(t *scheduledTx, head *types.Header) error {
+	if t.minBlock > head.Number.Uint64()+s.cfg.MaxBlocksAhead {
+		return fmt.Errorf("min block %d is more than %d blocks ahead of the head", t.minBlock, s.cfg.MaxBlocksAhead)
+	}
+	if t.minTimestamp > head.Time+uint64(s.cfg.MaxTimeAhead/time.Second) {
+		return fmt.Errorf("min timestamp %d is more than %s ahead of the head", t.minTimestamp, s.cfg.MaxTimeAhead)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.txs[t.hash]; ok {
+		return errors.New("already known")
+	}
+	if len(s.txs) >= s.cfg.Limit {
+		return errors.New("scheduled transactions limit reached")
+	}
+	if s.bySender[t.sender] >= s.cfg.SenderLimit {
+		return fmt.Errorf("scheduled transactions limit of sender %x reached", t.sender)
+	}
+	s.txs[t.hash] = t
+	s.bySender[t.sender]++
+	return nil
+}
+
+// release adds transactions which became valid to the pool, in order of nonces of their senders
+func (s *scheduledTxs) release(ctx context.Context, head *types.Header) {
+	s.lock.Lock()
+	var ready []*scheduledTx
+	for hash, t := range s.txs {
+		if !t.ready(head) {
+			continue
+		}
+		ready = append(ready, t)
+		delete(s.txs, hash)
+		if s.bySender[t.sender]--; s.bySender[t.sender] == 0 {
+			delete(s.bySender, t.sender)
+		}
+	}
+	s.lock.Unlock()
+	if len(ready) == 0 {
+		return
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if c := bytes.Compare(ready[i].sender[:], ready[j].sender[:]); c != 0 {
+			return c < 0
+		}
+		return ready[i].nonce < ready[j].nonce
+	})
+	req := &txPoolProto.AddRequest{RlpTxs: make([][]byte, len(ready))}
+	for i, t := range ready {
+		req.RlpTxs[i] = t.raw
+	}
+	res, err := s.txPool.Add(ctx, req)
+	if err != nil {
+		log.Warn("[rpc] scheduled transactions were not added to the pool", "block", head.Number.Uint64()+1, "amount", len(ready), "err", err)
+		return
+	}
+	for i, t := range ready {
+		if i < len(res.Imported) && res.Imported[i] != txPoolProto.ImportResult_SUCCESS {
+			log.Warn("[rpc] scheduled transaction rejected by the pool", "hash", t.hash, "result", txPoolProto.ImportResult_name[int32(res.Imported[i])], "err", res.Errors[i])
+		}
+	}
+	log.Info("[rpc] scheduled transactions added to the pool", "block", head.Number.Uint64()+1, "amount", len(ready))
+}
+
+// sendScheduledTransaction - eth_sendRawTransaction of ScheduledTxType envelope (without the type byte)
+func (api *APIImpl) sendScheduledTransaction(ctx context.Context, envelope []byte) (common.Hash, error) {
+	if api.scheduledTxs == nil {
+		return common.Hash{}, errors.New("scheduled transactions are disabled, see --txpool.scheduled.limit")
+	}
+	var env scheduledTxEnvelope
+	if err := rlp.DecodeBytes(envelope, &env); err != nil {
+		return common.Hash{}, fmt.Errorf("scheduled transaction envelope: %w", err)
+	}
+	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(env.Tx), uint64(len(env.Tx))))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err = checkTxFee(txn.GetPrice().ToBig(), txn.GetGas(), ethconfig.Defaults.RPCTxFeeCap); err != nil {
+		return common.Hash{}, err
+	}
+	if !txn.Protected() {
+		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer tx.Rollback()
+	head := rawdb.ReadCurrentHeader(tx)
+	if head == nil {
+		return common.Hash{}, errors.New("current header not found")
+	}
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if cc.ChainID.Cmp(txn.GetChainID().ToBig()) != 0 {
+		return common.Hash{}, fmt.Errorf("invalid chain id, expected: %d got: %d", cc.ChainID, txn.GetChainID())
+	}
+	from, err := txn.Sender(*types.MakeSigner(cc, head.Number.Uint64()))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	tx.Rollback()
+
+	t := &scheduledTx{hash: txn.Hash(), sender: from, nonce: txn.GetNonce(), minBlock: env.MinBlock, minTimestamp: env.MinTimestamp, raw: env.Tx}
+	if t.ready(head) {
+		return api.SendRawTransaction(ctx, env.Tx)
+	}
+	if err = api.scheduledTxs.add(t, head); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Scheduled transaction", "hash", t.hash, "from", from, "nonce", t.nonce, "minBlock", t.minBlock, "minTimestamp", t.minTimestamp)
+	return t.hash, nil
+}
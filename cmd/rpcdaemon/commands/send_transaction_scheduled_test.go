@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	txPoolProto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type addRecorder struct {
+	txPoolProto.TxpoolClient
+	added [][]byte
+}
+
+func (r *addRecorder) Add(_ context.Context, in *txPoolProto.AddRequest, _ ...grpc.CallOption) (*txPoolProto.AddReply, error) {
+	r.added = append(r.added, in.RlpTxs...)
+	reply := &txPoolProto.AddReply{}
+	for range in.RlpTxs {
+		reply.Imported = append(reply.Imported, txPoolProto.ImportResult_SUCCESS)
+		reply.Errors = append(reply.Errors, "")
+	}
+	return reply, nil
+}
+
+func TestScheduledTxs(t *testing.T) {
+	require := require.New(t)
+	pool := &addRecorder{}
+	s := newScheduledTxs(httpcfg.ScheduledTxsCfg{Limit: 3, SenderLimit: 2, MaxBlocksAhead: 100, MaxTimeAhead: time.Hour}, pool, nil)
+	head := func(number, time uint64) *types.Header {
+		return &types.Header{Number: new(big.Int).SetUint64(number), Time: time}
+	}
+	alice, bob := common.Address{1}, common.Address{2}
+	tx := func(id byte, sender common.Address, nonce, minBlock, minTimestamp uint64) *scheduledTx {
+		return &scheduledTx{hash: common.Hash{id}, sender: sender, nonce: nonce, minBlock: minBlock, minTimestamp: minTimestamp, raw: []byte{id}}
+	}
+
+	require.NoError(s.add(tx(1, alice, 1, 20, 0), head(10, 1000)))
+	require.NoError(s.add(tx(2, alice, 0, 15, 0), head(10, 1000)))
+	require.EqualError(s.add(tx(2, bob, 0, 15, 0), head(10, 1000)), "already known")
+	require.Error(s.add(tx(3, alice, 2, 15, 0), head(10, 1000)))      // sender limit
+	require.Error(s.add(tx(3, bob, 0, 111, 0), head(10, 1000)))       // too many blocks ahead
+	require.Error(s.add(tx(3, bob, 0, 0, 1000+3601), head(10, 1000))) // too far in time
+	require.NoError(s.add(tx(3, bob, 0, 0, 1100), head(10, 1000)))
+	require.EqualError(s.add(tx(4, bob, 1, 0, 1100), head(10, 1000)), "scheduled transactions limit reached")
+
+	s.release(context.Background(), head(13, 1050))
+	require.Empty(pool.added)
+	s.release(context.Background(), head(14, 1200)) // tx 2 is valid in block 15, tx 3 after timestamp 1100
+	require.Equal([][]byte{{2}, {3}}, pool.added)
+	require.NoError(s.add(tx(4, bob, 1, 0, 1300), head(14, 1200)))
+
+	s.release(context.Background(), head(20, 1300))
+	require.Equal([][]byte{{2}, {3}, {1}, {4}}, pool.added)
+	require.Empty(s.txs)
+	require.Empty(s.bySender)
+}
@@ -876,6 +876,9 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 		}
 		stateReader = state.NewCachedReader2(cacheView, tx)
 	} else {
+		if err = rpchelper.CheckHistoryNotPruned(tx, blockNumber); err != nil {
+			return nil, err
+		}
 		stateReader = state.NewPlainState(tx, blockNumber+1)
 	}
 	ibs := state.New(stateReader)
@@ -885,7 +888,7 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 		return nil, err
 	}
 	if block == nil {
-		return nil, fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+		return nil, rpc.NewNotFoundError("block", hash)
 	}
 	header := block.Header()
 
@@ -940,7 +943,7 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 		return nil, err
 	}
 
-	blockCtx, txCtx := transactions.GetEvmContext(msg, header, blockNrOrHash.RequireCanonical, tx, api._blockReader)
+	blockCtx, txCtx := transactions.GetEvmContext(msg, header, tx, api._blockReader)
 	blockCtx.GasLimit = math.MaxUint64
 	blockCtx.MaxGasLimit = true
 
@@ -1046,7 +1049,7 @@ func (api *TraceAPIImpl) CallMany(ctx context.Context, calls json.RawMessage, pa
 	}
 	parentHeader := parentBlock.Header()
 	if parentHeader == nil {
-		return nil, fmt.Errorf("parent header %d(%x) not found", blockNumber, hash)
+		return nil, rpc.NewNotFoundError("header", hash)
 	}
 	if parentHeader != nil && parentHeader.BaseFee != nil {
 		var overflow bool
@@ -1088,6 +1091,9 @@ func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx kv.Tx, msgs []type
 		}
 		stateReader = state.NewCachedReader2(cacheView, dbtx) // this cache stays between RPC calls
 	} else {
+		if err = rpchelper.CheckHistoryNotPruned(dbtx, blockNumber); err != nil {
+			return nil, err
+		}
 		stateReader = state.NewPlainState(dbtx, blockNumber+1)
 	}
 	stateCache := shards.NewStateCache(32, 0 /* no limit */) // this cache living only during current RPC call, but required to store state writes
@@ -1102,7 +1108,7 @@ func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx kv.Tx, msgs []type
 	}
 	parentHeader := parentBlock.Header()
 	if parentHeader == nil {
-		return nil, fmt.Errorf("parent header %d(%x) not found", blockNumber, hash)
+		return nil, rpc.NewNotFoundError("header", hash)
 	}
 
 	// Setup context so it may be cancelled the call has completed
@@ -1161,7 +1167,7 @@ func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx kv.Tx, msgs []type
 		}
 
 		// Get a new instance of the EVM.
-		blockCtx, txCtx := transactions.GetEvmContext(msg, header, parentNrOrHash.RequireCanonical, dbtx, api._blockReader)
+		blockCtx, txCtx := transactions.GetEvmContext(msg, header, dbtx, api._blockReader)
 		if useParent {
 			blockCtx.GasLimit = math.MaxUint64
 			blockCtx.MaxGasLimit = true
@@ -1184,7 +1190,7 @@ func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx kv.Tx, msgs []type
 		} else {
 			ibs.Prepare(common.Hash{}, header.Hash(), txIndex)
 		}
-		execResult, err = core.ApplyMessage(evm, msg, gp, true /* refunds */, gasBailout /* gasBailout */)
+		execResult, err = transactions.ApplyMessage(ctx, evm, msg, gp, true /* refunds */, gasBailout /* gasBailout */)
 		if err != nil {
 			return nil, fmt.Errorf("first run for txIndex %d error: %w", txIndex, err)
 		}
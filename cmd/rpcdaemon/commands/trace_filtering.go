@@ -17,8 +17,10 @@ import (
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
@@ -231,19 +233,13 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	}
 	defer dbtx.Rollback()
 
-	var fromBlock uint64
-	var toBlock uint64
-	if req.FromBlock == nil {
-		fromBlock = 0
-	} else {
-		fromBlock = uint64(*req.FromBlock)
+	fromBlock, err := api.traceFilterBlock(dbtx, req.FromBlock, rpc.EarliestBlockNumber)
+	if err != nil {
+		return err
 	}
-
-	if req.ToBlock == nil {
-		headNumber := rawdb.ReadHeaderNumber(dbtx, rawdb.ReadHeadHeaderHash(dbtx))
-		toBlock = *headNumber
-	} else {
-		toBlock = uint64(*req.ToBlock)
+	toBlock, err := api.traceFilterBlock(dbtx, req.ToBlock, rpc.LatestBlockNumber)
+	if err != nil {
+		return err
 	}
 
 	if fromBlock > toBlock {
@@ -254,6 +250,17 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 		return api.filterV3(ctx, dbtx, fromBlock, toBlock, req, stream)
 	}
 
+	if len(req.FromAddress) == 0 && len(req.ToAddress) == 0 {
+		// every block of the range is traced
+		if fromBlock > 0 {
+			if err := rpchelper.CheckHistoryNotPruned(dbtx, fromBlock-1); err != nil {
+				return err
+			}
+		}
+	} else if err := checkCallTracesNotPruned(dbtx, fromBlock); err != nil {
+		return err
+	}
+
 	fromAddresses := make(map[common.Address]struct{}, len(req.FromAddress))
 	toAddresses := make(map[common.Address]struct{}, len(req.ToAddress))
 
@@ -291,13 +298,10 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 		}
 	}
 
-	switch req.Mode {
-	case TraceFilterModeIntersection:
+	if req.Mode == TraceFilterModeIntersection && len(req.FromAddress) > 0 && len(req.ToAddress) > 0 {
 		allBlocks.And(&blocksTo)
-	case TraceFilterModeUnion:
-		fallthrough
-	default:
-		allBlocks.Or(&blocksTo)
+	} else {
+		allBlocks.Or(&blocksTo) // an empty list matches any address in the intersection mode
 	}
 
 	// Special case - if no addresses specified, take all traces
@@ -306,6 +310,12 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	} else {
 		allBlocks.RemoveRange(0, fromBlock)
 		allBlocks.RemoveRange(toBlock+1, uint64(0x100000000))
+		// matching blocks are traced on the state after their parents
+		if !allBlocks.IsEmpty() && allBlocks.Minimum() > 0 {
+			if err := rpchelper.CheckHistoryNotPruned(dbtx, allBlocks.Minimum()-1); err != nil {
+				return err
+			}
+		}
 	}
 
 	chainConfig, err := api.chainConfig(dbtx)
@@ -331,7 +341,11 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 
 	it := allBlocks.Iterator()
 	isPos := false
-	for it.HasNext() {
+	for it.HasNext() && nExported < count {
+		if err := ctx.Err(); err != nil {
+			stream.WriteArrayEnd()
+			return err
+		}
 		b := it.Next()
 		// Extract transactions from block
 		hash, hashErr := rawdb.ReadCanonicalHash(dbtx, b)
@@ -396,7 +410,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 			txHash := txs[i].Hash()
 			// Check if transaction concerns any of the addresses we wanted
 			for _, pt := range trace.Trace {
-				if includeAll || filter_trace(pt, fromAddresses, toAddresses) {
+				if includeAll || filter_trace(pt, fromAddresses, toAddresses, req.Mode) {
 					nSeen++
 					pt.BlockHash = &blockHash
 					pt.BlockNumber = &blockNumber
@@ -434,7 +448,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 		}
 
 		minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, block.Header(), block.Uncles())
-		if _, ok := toAddresses[block.Coinbase()]; ok || includeAll {
+		if includeAll || filter_reward(block.Coinbase(), fromAddresses, toAddresses, req.Mode) {
 			nSeen++
 			var tr ParityTrace
 			var rewardAction = &RewardTraceAction{}
@@ -471,7 +485,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 			}
 		}
 		for i, uncle := range block.Uncles() {
-			if _, ok := toAddresses[uncle.Coinbase]; ok || includeAll {
+			if includeAll || filter_reward(uncle.Coinbase, fromAddresses, toAddresses, req.Mode) {
 				if i < len(uncleRewards) {
 					nSeen++
 					var tr ParityTrace
@@ -559,13 +573,10 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.Tx, fromBlock, to
 		}
 	}
 
-	switch req.Mode {
-	case TraceFilterModeIntersection:
+	if req.Mode == TraceFilterModeIntersection && len(req.FromAddress) > 0 && len(req.ToAddress) > 0 {
 		allTxs.And(&txsTo)
-	case TraceFilterModeUnion:
-		fallthrough
-	default:
-		allTxs.Or(&txsTo)
+	} else {
+		allTxs.Or(&txsTo) // an empty list matches any address in the intersection mode
 	}
 
 	// Special case - if no addresses specified, take all traces
@@ -606,7 +617,11 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.Tx, fromBlock, to
 	stateReader := state.NewHistoryReader22(ac)
 	stateReader.SetTx(dbtx)
 	noop := state.NewNoopWriter()
-	for it.HasNext() {
+	for it.HasNext() && nExported < count {
+		if err := ctx.Err(); err != nil {
+			stream.WriteArrayEnd()
+			return err
+		}
 		txNum := it.Next()
 		// Find block number
 		ok, blockNum, err := rawdb.TxNums.FindBlockNum(dbtx, txNum)
@@ -660,7 +675,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.Tx, fromBlock, to
 			}
 			// Block reward section, handle specially
 			minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, lastHeader, body.Uncles)
-			if _, ok := toAddresses[lastHeader.Coinbase]; ok || includeAll {
+			if includeAll || filter_reward(lastHeader.Coinbase, fromAddresses, toAddresses, req.Mode) {
 				nSeen++
 				var tr ParityTrace
 				var rewardAction = &RewardTraceAction{}
@@ -697,7 +712,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.Tx, fromBlock, to
 				}
 			}
 			for i, uncle := range body.Uncles {
-				if _, ok := toAddresses[uncle.Coinbase]; ok || includeAll {
+				if includeAll || filter_reward(uncle.Coinbase, fromAddresses, toAddresses, req.Mode) {
 					if i < len(uncleRewards) {
 						nSeen++
 						var tr ParityTrace
@@ -772,7 +787,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.Tx, fromBlock, to
 			stream.WriteObjectEnd()
 			continue
 		}
-		blockCtx, txCtx := transactions.GetEvmContext(msg, lastHeader, true /* requireCanonical */, dbtx, api._blockReader)
+		blockCtx, txCtx := transactions.GetEvmContext(msg, lastHeader, dbtx, api._blockReader)
 		stateReader.SetTxNum(txNum)
 		stateCache := shards.NewStateCache(32, 0 /* no limit */) // this cache living only during current RPC call, but required to store state writes
 		cachedReader := state.NewCachedReader(stateReader, stateCache)
@@ -793,7 +808,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.Tx, fromBlock, to
 		gp := new(core.GasPool).AddGas(msg.Gas())
 		ibs.Prepare(txHash, lastBlockHash, int(txIndex))
 		var execResult *core.ExecutionResult
-		execResult, err = core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+		execResult, err = transactions.ApplyMessage(ctx, evm, msg, gp, true /* refunds */, false /* gasBailout */)
 		if err != nil {
 			if first {
 				first = false
@@ -829,7 +844,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.Tx, fromBlock, to
 			continue
 		}
 		for _, pt := range traceResult.Trace {
-			if includeAll || filter_trace(pt, fromAddresses, toAddresses) {
+			if includeAll || filter_trace(pt, fromAddresses, toAddresses, req.Mode) {
 				nSeen++
 				pt.BlockHash = &lastBlockHash
 				pt.BlockNumber = &blockNum
@@ -863,38 +878,76 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.Tx, fromBlock, to
 	return stream.Flush()
 }
 
-func filter_trace(pt *ParityTrace, fromAddresses map[common.Address]struct{}, toAddresses map[common.Address]struct{}) bool {
+// filter_trace - with the union mode the trace matches by its from or to address, with the intersection mode by
+// both of them, an empty list matching any address like in OpenEthereum
+func filter_trace(pt *ParityTrace, fromAddresses map[common.Address]struct{}, toAddresses map[common.Address]struct{}, mode TraceFilterMode) bool {
 	switch action := pt.Action.(type) {
 	case *CallTraceAction:
-		_, f := fromAddresses[action.From]
-		_, t := toAddresses[action.To]
-		if f || t {
-			return true
-		}
+		return filter_addresses(&action.From, &action.To, fromAddresses, toAddresses, mode)
 	case *CreateTraceAction:
-		_, f := fromAddresses[action.From]
-		if f {
-			return true
-		}
-
+		var to *common.Address
 		if res, ok := pt.Result.(*CreateTraceResult); ok {
-			if res.Address != nil {
-				if _, t := toAddresses[*res.Address]; t {
-					return true
-				}
-			}
+			to = res.Address
 		}
+		return filter_addresses(&action.From, to, fromAddresses, toAddresses, mode)
 	case *SuicideTraceAction:
-		_, f := fromAddresses[action.Address]
-		_, t := toAddresses[action.RefundAddress]
-		if f || t {
-			return true
-		}
+		return filter_addresses(&action.Address, &action.RefundAddress, fromAddresses, toAddresses, mode)
 	}
-
 	return false
 }
 
+// filter_reward - reward traces have only the to address, their author
+func filter_reward(author common.Address, fromAddresses map[common.Address]struct{}, toAddresses map[common.Address]struct{}, mode TraceFilterMode) bool {
+	return filter_addresses(nil, &author, fromAddresses, toAddresses, mode)
+}
+
+// filter_addresses - from or to is nil if the trace doesn't have it
+func filter_addresses(from, to *common.Address, fromAddresses map[common.Address]struct{}, toAddresses map[common.Address]struct{}, mode TraceFilterMode) bool {
+	var f, t bool
+	if from != nil {
+		_, f = fromAddresses[*from]
+	}
+	if to != nil {
+		_, t = toAddresses[*to]
+	}
+	if mode == TraceFilterModeIntersection {
+		return (f || len(fromAddresses) == 0) && (t || len(toAddresses) == 0)
+	}
+	return f || t
+}
+
+// traceFilterBlock resolves fromBlock/toBlock of trace_filter, numbers or tags like in OpenEthereum
+func (api *TraceAPIImpl) traceFilterBlock(tx kv.Tx, number *rpc.BlockNumber, defaultNumber rpc.BlockNumber) (uint64, error) {
+	if number == nil {
+		number = &defaultNumber
+	}
+	if *number == rpc.PendingBlockNumber {
+		return rpchelper.GetLatestBlockNumber(tx) // the pending block has no traces
+	}
+	blockNumber, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(*number), tx, api.filters)
+	return blockNumber, err
+}
+
+// checkCallTracesNotPruned - the call from/to index of trace_filter has no blocks pruned by --prune=c, returns
+// rpc.PrunedError instead of silently missing their traces
+func checkCallTracesNotPruned(tx kv.Tx, blockNumber uint64) error {
+	pm, err := prune2.Get(tx)
+	if err != nil {
+		return err
+	}
+	if !pm.CallTraces.Enabled() {
+		return nil
+	}
+	progress, err := stages.GetStageProgress(tx, stages.CallTraces)
+	if err != nil {
+		return err
+	}
+	if pruneTo := pm.CallTraces.PruneTo(progress); blockNumber < pruneTo {
+		return &rpc.PrunedError{Resource: "callTraces", Block: blockNumber, AvailableFrom: pruneTo}
+	}
+	return nil
+}
+
 func (api *TraceAPIImpl) callManyTransactions(ctx context.Context, dbtx kv.Tx, txs []types.Transaction, traceTypes []string, parentHash common.Hash, parentNo rpc.BlockNumber, header *types.Header, txIndex int, signer *types.Signer, rules *params.Rules) ([]*TraceCallResult, error) {
 	callParams := make([]TraceCallParam, 0, len(txs))
 	msgs := make([]types.Message, len(txs))
@@ -923,10 +976,11 @@ func (api *TraceAPIImpl) callManyTransactions(ctx context.Context, dbtx kv.Tx, t
 	return traces, nil
 }
 
-// TraceFilterRequest represents the arguments for trace_filter
+// TraceFilterRequest - the arguments of trace_filter: fromBlock is the genesis and toBlock the latest block if omitted, after skips that many
+// matching traces and count limits the number of returned ones
 type TraceFilterRequest struct {
-	FromBlock   *hexutil.Uint64   `json:"fromBlock"`
-	ToBlock     *hexutil.Uint64   `json:"toBlock"`
+	FromBlock   *rpc.BlockNumber  `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber  `json:"toBlock"`
 	FromAddress []*common.Address `json:"fromAddress"`
 	ToAddress   []*common.Address `json:"toAddress"`
 	Mode        TraceFilterMode   `json:"mode"`
@@ -939,6 +993,7 @@ type TraceFilterMode string
 const (
 	// Default mode for TraceFilter. Unions results referred to addresses from FromAddress or ToAddress
 	TraceFilterModeUnion = "union"
-	// IntersectionMode retrives results referred to addresses provided both in FromAddress and ToAddress
+	// IntersectionMode retrives results referred to addresses provided both in FromAddress and ToAddress, an empty
+	// list matches any address. The semantics of OpenEthereum.
 	TraceFilterModeIntersection = "intersection"
 )
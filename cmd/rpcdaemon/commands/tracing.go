@@ -64,9 +64,9 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 
 	if block == nil {
 		if numberOk {
-			return fmt.Errorf("invalid arguments; block with number %d not found", number)
+			return rpc.NewNotFoundError("block", number)
 		}
-		return fmt.Errorf("invalid arguments; block with hash %x not found", hash)
+		return rpc.NewNotFoundError("block", hash)
 	}
 
 	chainConfig, err := api.chainConfig(tx)
@@ -74,16 +74,13 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 		stream.WriteNil()
 		return err
 	}
-
-	getHeader := func(hash common.Hash, number uint64) *types.Header {
-		h, e := api._blockReader.Header(ctx, tx, hash, number)
-		if e != nil {
-			log.Error("getHeader error", "number", number, "hash", hash, "err", e)
-		}
-		return h
+	if workers := api.blockTraceWorkers(block, config); workers > 1 {
+		return api.traceBlockParallel(ctx, tx, block, chainConfig, config, workers, stream)
 	}
 
-	_, blockCtx, _, ibs, reader, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, ethash.NewFaker(), tx, block.Hash(), 0)
+	blockHashes := core.NewCanonicalBlockHashes(ctx, tx, api._blockReader)
+
+	_, blockCtx, _, ibs, reader, err := transactions.ComputeTxEnv(ctx, block, chainConfig, blockHashes, ethash.NewFaker(), tx, block.Hash(), 0)
 	if err != nil {
 		stream.WriteNil()
 		return err
@@ -169,7 +166,7 @@ func (api *PrivateDebugAPIImpl) TraceTransaction(ctx context.Context, hash commo
 			return nil
 		}
 		stream.WriteNil()
-		return fmt.Errorf("transaction %#x not found", hash)
+		return rpc.NewNotFoundError("transaction", hash)
 	}
 	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
@@ -177,10 +174,8 @@ func (api *PrivateDebugAPIImpl) TraceTransaction(ctx context.Context, hash commo
 		return err
 	}
 
-	getHeader := func(hash common.Hash, number uint64) *types.Header {
-		return rawdb.ReadHeader(tx, hash, number)
-	}
-	msg, blockCtx, txCtx, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, ethash.NewFaker(), tx, blockHash, txnIndex)
+	blockHashes := core.NewCanonicalBlockHashes(ctx, tx, api._blockReader)
+	msg, blockCtx, txCtx, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, blockHashes, ethash.NewFaker(), tx, blockHash, txnIndex)
 	if err != nil {
 		stream.WriteNil()
 		return err
@@ -216,12 +211,16 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 		}
 		stateReader = state.NewCachedReader2(cacheView, dbtx)
 	} else {
+		if err = rpchelper.CheckHistoryNotPruned(dbtx, blockNumber); err != nil {
+			stream.WriteNil()
+			return err
+		}
 		stateReader = state.NewPlainState(dbtx, blockNumber+1)
 	}
 	header := rawdb.ReadHeader(dbtx, hash, blockNumber)
 	if header == nil {
 		stream.WriteNil()
-		return fmt.Errorf("block %d(%x) not found", blockNumber, hash)
+		return rpc.NewNotFoundError("block", hash)
 	}
 	ibs := state.New(stateReader)
 
@@ -244,7 +243,7 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 		return err
 	}
 
-	blockCtx, txCtx := transactions.GetEvmContext(msg, header, blockNrOrHash.RequireCanonical, dbtx, api._blockReader)
+	blockCtx, txCtx := transactions.GetEvmContext(msg, header, dbtx, api._blockReader)
 	// Trace the transaction and return
 	return transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
 }
@@ -316,7 +315,7 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 
 	replayTransactions = block.Transactions()[:transactionIndex]
 
-	stateReader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum-1)), api.filters, api.stateCache, api.historyV3(tx), api._agg)
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum-1)), api.filters, api.stateCache, api.recentStates, api.historyV3(tx), api._agg)
 	if err != nil {
 		stream.WriteNil()
 		return err
@@ -328,7 +327,7 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 
 	if parent == nil {
 		stream.WriteNil()
-		return fmt.Errorf("block %d(%x) not found", blockNum, hash)
+		return rpc.NewNotFoundError("block", hash)
 	}
 
 	// Get a new instance of the EVM
@@ -376,7 +375,7 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 		txCtx = core.NewEVMTxContext(msg)
 		evm = vm.NewEVM(blockCtx, txCtx, evm.IntraBlockState(), chainConfig, vm.Config{Debug: false})
 		// Execute the transaction message
-		_, err = core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+		_, err = transactions.ApplyMessage(ctx, evm, msg, gp, true /* refunds */, false /* gasBailout */)
 		if err != nil {
 			stream.WriteNil()
 			return err
@@ -0,0 +1,472 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/holiman/uint256"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/ethconfig/estimate"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// blockTraceWorkers - amount of transactions of the block traced at once, 1 - one after another on the same state
+func (api *PrivateDebugAPIImpl) blockTraceWorkers(block *types.Block, config *tracers.TraceConfig) int {
+	if config != nil && config.NoRefunds != nil && *config.NoRefunds {
+		return 1 // following transactions are executed on the state left by the traced ones, without refunds
+	}
+	workers := api.traceBlockWorkers
+	if workers == 0 {
+		workers = estimate.TraceBlockTx.Workers()
+	}
+	if n := len(block.Transactions()); workers > n {
+		workers = n
+	}
+	return workers
+}
+
+type blockTxTrace struct {
+	trace []byte
+	err   error
+}
+
+// traceBlockParallel traces transactions of the block by the workers at once. The block is executed without tracers
+// first, recording state changes of every transaction. Then each transaction is traced on its own state: the state of
+// the parent block overlaid by the changes of the preceding transactions. A traced transaction which doesn't reproduce
+// its changes of the first execution is traced again on the state replayed one transaction after another.
+// Traces are written in order of the transactions, each one as soon as it and the preceding ones are done.
+func (api *PrivateDebugAPIImpl) traceBlockParallel(ctx context.Context, dbtx kv.Tx, block *types.Block, chainConfig *params.ChainConfig, config *tracers.TraceConfig, workers int, stream *jsoniter.Stream) error {
+	if block.NumberU64() > 0 {
+		if err := rpchelper.CheckHistoryNotPruned(dbtx, block.NumberU64()-1); err != nil {
+			stream.WriteNil()
+			return err
+		}
+	}
+	writes, err := api.recordBlockStateWrites(ctx, dbtx, block, chainConfig)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+
+	txs := block.Transactions()
+	results := make([]chan blockTxTrace, len(txs))
+	for i := range results {
+		results[i] = make(chan blockTxTrace, 1)
+	}
+	ahead := make(chan struct{}, 4*workers) // bounds traces kept in memory until their turn to be written
+	next := make(chan int)
+	var wg sync.WaitGroup
+	defer wg.Wait() // workers hold their db transactions
+	traceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		defer close(next)
+		for i := range txs {
+			select {
+			case ahead <- struct{}{}:
+			case <-traceCtx.Done():
+				return
+			}
+			select {
+			case next <- i:
+			case <-traceCtx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer debug.LogPanic()
+			api.blockTraceWorker(traceCtx, block, chainConfig, config, writes, next, results)
+		}()
+	}
+
+	stream.WriteArrayStart()
+	for i := range txs {
+		var r blockTxTrace
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			stream.WriteNil()
+			return ctx.Err()
+		}
+		if r.err != nil {
+			stream.WriteNil()
+			return r.err
+		}
+		stream.Write(r.trace)
+		if i != len(txs)-1 {
+			stream.WriteMore()
+		}
+		stream.Flush()
+		<-ahead
+	}
+	stream.WriteArrayEnd()
+	stream.Flush()
+	return nil
+}
+
+func (api *PrivateDebugAPIImpl) blockTraceWorker(ctx context.Context, block *types.Block, chainConfig *params.ChainConfig, config *tracers.TraceConfig, writes *blockStateWrites, next <-chan int, results []chan blockTxTrace) {
+	dbtx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		for i := range next {
+			results[i] <- blockTxTrace{err: err}
+		}
+		return
+	}
+	defer dbtx.Rollback()
+	base := state.NewPlainState(dbtx, block.NumberU64())
+	blockHashes := core.NewCanonicalBlockHashes(ctx, dbtx, api._blockReader)
+	header := block.Header()
+	blockCtx := core.NewEVMBlockContext(header, core.BlockHashFn(header, blockHashes), ethash.NewFaker(), nil)
+	for i := range next {
+		trace, err := api.traceBlockTx(ctx, dbtx, base, blockCtx, blockHashes, block, chainConfig, config, writes, i)
+		results[i] <- blockTxTrace{trace: trace, err: err}
+	}
+}
+
+// traceBlockTx traces the transaction of the block on the state overlaid by the changes of the preceding transactions
+func (api *PrivateDebugAPIImpl) traceBlockTx(ctx context.Context, dbtx kv.Tx, base state.StateReader, blockCtx vm.BlockContext, blockHashes core.BlockHashReader,
+	block *types.Block, chainConfig *params.ChainConfig, config *tracers.TraceConfig, writes *blockStateWrites, txIndex int) ([]byte, error) {
+	txn := block.Transactions()[txIndex]
+	signer := types.MakeSigner(chainConfig, block.NumberU64())
+	rules := chainConfig.Rules(block.NumberU64())
+	msg, _ := txn.AsMessage(*signer, block.BaseFee(), rules)
+	txCtx := vm.TxContext{
+		TxHash:   txn.Hash(),
+		Origin:   msg.From(),
+		GasPrice: msg.GasPrice().ToBig(),
+	}
+
+	reader := &blockStateReader{base: base, writes: writes, txIndex: txIndex}
+	ibs := state.New(reader)
+	ibs.Prepare(txn.Hash(), block.Hash(), txIndex)
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	_ = transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
+	if err := stream.Flush(); err != nil {
+		return nil, err
+	}
+	var recorder stateWriteRecorder
+	if err := ibs.FinalizeTx(rules, &recorder); err != nil {
+		return nil, err
+	}
+	reproduced, err := writes.reproduced(reader, recorder.writes)
+	if err != nil {
+		return nil, err
+	}
+	if reproduced {
+		return buf.Bytes(), nil
+	}
+
+	log.Debug("[rpc] transaction is traced again on the replayed state of its block", "hash", txn.Hash(), "block", block.NumberU64())
+	replayedMsg, blockCtx, _, replayed, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, blockHashes, ethash.NewFaker(), dbtx, block.Hash(), uint64(txIndex))
+	if err != nil {
+		return nil, err
+	}
+	buf.Reset()
+	stream.Reset(&buf)
+	_ = transactions.TraceTx(ctx, replayedMsg, blockCtx, txCtx, replayed, config, chainConfig, stream, api.evmCallTimeout)
+	if err := stream.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// recordBlockStateWrites executes transactions of the block one after another, like ComputeTxEnv,
+// recording state changes of each of them
+func (api *PrivateDebugAPIImpl) recordBlockStateWrites(ctx context.Context, dbtx kv.Tx, block *types.Block, chainConfig *params.ChainConfig) (*blockStateWrites, error) {
+	writes := newBlockStateWrites(len(block.Transactions()))
+	statedb := state.New(state.NewPlainState(dbtx, block.NumberU64()))
+	header := block.Header()
+	blockCtx := core.NewEVMBlockContext(header, core.BlockHashFn(header, core.NewCanonicalBlockHashes(ctx, dbtx, api._blockReader)), ethash.NewFaker(), nil)
+	vmenv := vm.NewEVM(blockCtx, vm.TxContext{}, statedb, chainConfig, vm.Config{})
+	rules := vmenv.ChainRules()
+	signer := types.MakeSigner(chainConfig, block.NumberU64())
+	for idx, txn := range block.Transactions() {
+		select {
+		default:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		statedb.Prepare(txn.Hash(), block.Hash(), idx)
+		msg, _ := txn.AsMessage(*signer, block.BaseFee(), rules)
+		vmenv.Reset(core.NewEVMTxContext(msg), statedb)
+		if _, err := transactions.ApplyMessage(ctx, vmenv, msg, new(core.GasPool).AddGas(txn.GetGas()), true /* refunds */, false /* gasBailout */); err != nil {
+			return nil, fmt.Errorf("transaction %x failed: %w", txn.Hash(), err)
+		}
+		var recorder stateWriteRecorder
+		if err := statedb.FinalizeTx(rules, &recorder); err != nil {
+			return nil, err
+		}
+		writes.add(idx, recorder.writes)
+	}
+	return writes, nil
+}
+
+type stateWriteKind byte
+
+const (
+	writeAccount stateWriteKind = iota
+	writeDelete                 // the account and its storage are deleted
+	writeCreate                 // the contract is created, its storage starts empty
+	writeStorage
+	writeCode
+)
+
+type stateWrite struct {
+	kind        stateWriteKind
+	address     common.Address
+	incarnation uint64
+	key         common.Hash // storage location, or code hash
+	value       []byte      // account encoded for storage, storage value or code
+}
+
+// stateWriteRecorder - state writer collecting the writes of a transaction
+type stateWriteRecorder struct {
+	writes []stateWrite
+}
+
+func (r *stateWriteRecorder) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	r.writes = append(r.writes, stateWrite{kind: writeAccount, address: address, value: encodeAccountForStorage(account)})
+	return nil
+}
+
+func (r *stateWriteRecorder) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	r.writes = append(r.writes, stateWrite{kind: writeCode, address: address, incarnation: incarnation, key: codeHash, value: common.CopyBytes(code)})
+	return nil
+}
+
+func (r *stateWriteRecorder) DeleteAccount(address common.Address, original *accounts.Account) error {
+	r.writes = append(r.writes, stateWrite{kind: writeDelete, address: address})
+	return nil
+}
+
+func (r *stateWriteRecorder) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	r.writes = append(r.writes, stateWrite{kind: writeStorage, address: address, incarnation: incarnation, key: *key, value: value.Bytes()})
+	return nil
+}
+
+func (r *stateWriteRecorder) CreateContract(address common.Address) error {
+	r.writes = append(r.writes, stateWrite{kind: writeCreate, address: address})
+	return nil
+}
+
+func encodeAccountForStorage(account *accounts.Account) []byte {
+	if account == nil {
+		return nil
+	}
+	enc := make([]byte, account.EncodingLengthForStorage())
+	account.EncodeForStorage(enc)
+	return enc
+}
+
+type storageLocation struct {
+	address common.Address
+	key     common.Hash
+}
+
+// stateVersion - value written by the transaction, nil account - deleted
+type stateVersion struct {
+	txIndex int
+	account *accounts.Account
+	value   []byte
+}
+
+// blockStateWrites - state changes of every transaction of a block, versioned by the transaction index.
+// Read-only once recorded, shared by the workers.
+type blockStateWrites struct {
+	txs      [][]stateWrite
+	accounts map[common.Address][]stateVersion
+	storage  map[storageLocation][]stateVersion
+	clears   map[common.Address][]int // transactions deleting or creating the account
+	code     map[common.Hash][]byte
+}
+
+func newBlockStateWrites(txs int) *blockStateWrites {
+	return &blockStateWrites{
+		txs:      make([][]stateWrite, txs),
+		accounts: map[common.Address][]stateVersion{},
+		storage:  map[storageLocation][]stateVersion{},
+		clears:   map[common.Address][]int{},
+		code:     map[common.Hash][]byte{},
+	}
+}
+
+// add records writes of the transaction, transactions are added in order. The state writer sees deletion and
+// creation of an account before the writes of its data and storage, so the later ones win within a transaction.
+func (b *blockStateWrites) add(txIndex int, writes []stateWrite) {
+	b.txs[txIndex] = writes
+	for _, w := range writes {
+		switch w.kind {
+		case writeAccount:
+			account := new(accounts.Account)
+			if err := account.DecodeForStorage(w.value); err != nil {
+				panic(err) // encoded by encodeAccountForStorage
+			}
+			b.accounts[w.address] = append(b.accounts[w.address], stateVersion{txIndex: txIndex, account: account})
+		case writeDelete:
+			b.accounts[w.address] = append(b.accounts[w.address], stateVersion{txIndex: txIndex})
+			b.clears[w.address] = append(b.clears[w.address], txIndex)
+		case writeCreate:
+			b.clears[w.address] = append(b.clears[w.address], txIndex)
+		case writeStorage:
+			loc := storageLocation{address: w.address, key: w.key}
+			b.storage[loc] = append(b.storage[loc], stateVersion{txIndex: txIndex, value: w.value})
+		case writeCode:
+			b.code[w.key] = w.value
+		}
+	}
+}
+
+// before returns the last version written by a transaction preceding txIndex
+func before(versions []stateVersion, txIndex int) (stateVersion, bool) {
+	i := sort.Search(len(versions), func(i int) bool { return versions[i].txIndex >= txIndex }) - 1
+	if i < 0 {
+		return stateVersion{}, false
+	}
+	return versions[i], true
+}
+
+func (b *blockStateWrites) clearedBefore(address common.Address, txIndex int) (int, bool) {
+	clears := b.clears[address]
+	i := sort.SearchInts(clears, txIndex) - 1
+	if i < 0 {
+		return 0, false
+	}
+	return clears[i], true
+}
+
+// reproduced tells whether the writes of the transaction change the state the same way as the recorded ones.
+// Writes not changing the state seen by the transaction are skipped: the recording execution writes again
+// the storage of objects modified by the preceding transactions of the block.
+func (b *blockStateWrites) reproduced(reader *blockStateReader, writes []stateWrite) (bool, error) {
+	expected, err := b.changes(reader, b.txs[reader.txIndex])
+	if err != nil {
+		return false, err
+	}
+	actual, err := b.changes(reader, writes)
+	if err != nil {
+		return false, err
+	}
+	if len(expected) != len(actual) {
+		return false, nil
+	}
+	for k, v := range expected {
+		if av, ok := actual[k]; !ok || av != v {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type stateWriteKey struct {
+	kind stateWriteKind
+	loc  storageLocation
+}
+
+func (b *blockStateWrites) changes(reader *blockStateReader, writes []stateWrite) (map[stateWriteKey]string, error) {
+	changes := make(map[stateWriteKey]string, len(writes))
+	for _, w := range writes {
+		switch w.kind {
+		case writeAccount, writeDelete:
+			account, err := reader.ReadAccountData(w.address)
+			if err != nil {
+				return nil, err
+			}
+			if w.kind == writeDelete && account == nil {
+				continue
+			}
+			if w.kind == writeAccount && bytes.Equal(encodeAccountForStorage(account), w.value) {
+				continue
+			}
+		case writeStorage:
+			prev, err := reader.ReadAccountStorage(w.address, w.incarnation, &w.key)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(prev, w.value) {
+				continue
+			}
+		case writeCreate:
+			if _, ok := b.clearedBefore(w.address, reader.txIndex); ok {
+				continue
+			}
+		case writeCode:
+			continue // code is addressed by its hash, the change of the hash is a change of the account
+		}
+		changes[stateWriteKey{kind: w.kind, loc: storageLocation{address: w.address, key: w.key}}] = string(w.value)
+	}
+	return changes, nil
+}
+
+// blockStateReader - state before the transaction: the state of the parent block overlaid by the changes
+// of the preceding transactions of the block
+type blockStateReader struct {
+	base    state.StateReader
+	writes  *blockStateWrites
+	txIndex int
+}
+
+func (r *blockStateReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	if v, ok := before(r.writes.accounts[address], r.txIndex); ok {
+		if v.account == nil {
+			return nil, nil
+		}
+		account := *v.account
+		return &account, nil
+	}
+	return r.base.ReadAccountData(address)
+}
+
+func (r *blockStateReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	v, written := before(r.writes.storage[storageLocation{address: address, key: *key}], r.txIndex)
+	cleared, ok := r.writes.clearedBefore(address, r.txIndex)
+	if written && (!ok || v.txIndex >= cleared) {
+		if len(v.value) == 0 {
+			return nil, nil
+		}
+		return v.value, nil
+	}
+	if ok {
+		return nil, nil // storage of the deleted or created account starts empty
+	}
+	return r.base.ReadAccountStorage(address, incarnation, key)
+}
+
+func (r *blockStateReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	if code, ok := r.writes.code[codeHash]; ok {
+		return code, nil
+	}
+	return r.base.ReadAccountCode(address, incarnation, codeHash)
+}
+
+func (r *blockStateReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	if code, ok := r.writes.code[codeHash]; ok {
+		return len(code), nil
+	}
+	return r.base.ReadAccountCodeSize(address, incarnation, codeHash)
+}
+
+func (r *blockStateReader) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	return r.base.ReadAccountIncarnation(address)
+}
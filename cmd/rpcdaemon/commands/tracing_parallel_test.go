@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+// testStateReader - state of the parent block in memory
+type testStateReader struct {
+	accounts map[common.Address]*accounts.Account
+	storage  map[storageLocation][]byte
+	code     map[common.Hash][]byte
+}
+
+func (r *testStateReader) ReadAccountData(a common.Address) (*accounts.Account, error) {
+	if acc, ok := r.accounts[a]; ok {
+		c := *acc
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (r *testStateReader) ReadAccountStorage(a common.Address, inc uint64, k *common.Hash) ([]byte, error) {
+	return r.storage[storageLocation{a, *k}], nil
+}
+
+func (r *testStateReader) ReadAccountCode(a common.Address, inc uint64, h common.Hash) ([]byte, error) {
+	return r.code[h], nil
+}
+
+func (r *testStateReader) ReadAccountCodeSize(a common.Address, inc uint64, h common.Hash) (int, error) {
+	return len(r.code[h]), nil
+}
+
+func (r *testStateReader) ReadAccountIncarnation(a common.Address) (uint64, error) { return 0, nil }
+
+func (r *testStateReader) addContract(a common.Address, code []byte) {
+	h := crypto.Keccak256Hash(code)
+	r.code[h] = code
+	acc := accounts.NewAccount()
+	acc.CodeHash = h
+	acc.Incarnation = 1
+	acc.Initialised = true
+	r.accounts[a] = &acc
+}
+
+func TestBlockStateWrites(t *testing.T) {
+	counter := common.FromHex("60005460010160005500") // increments slot 0
+	initCode := append(append([]byte{0x69}, counter...), common.FromHex("600052600a6016f3")...)
+	destruct := common.FromHex("33ff") // selfdestruct to the caller
+	sender, sender2 := common.Address{0xaa}, common.Address{0xbb}
+	C, S := common.Address{0xcc}, common.Address{0xdd}
+	base := &testStateReader{map[common.Address]*accounts.Account{}, map[storageLocation][]byte{}, map[common.Hash][]byte{}}
+	for _, a := range []common.Address{sender, sender2} {
+		acc := accounts.NewAccount()
+		acc.Initialised = true
+		acc.Balance.SetUint64(1e18)
+		base.accounts[a] = &acc
+	}
+	base.addContract(C, counter)
+	base.storage[storageLocation{C, common.Hash{}}] = []byte{5}
+	base.addContract(S, destruct)
+	base.storage[storageLocation{S, common.Hash{}}] = []byte{7}
+	X := crypto.CreateAddress(sender, 2)
+	type tx struct {
+		from common.Address
+		to   *common.Address
+		val  uint64
+		data []byte
+	}
+	txs := []tx{
+		{sender, &C, 0, nil},
+		{sender, &C, 0, nil},
+		{sender, nil, 0, initCode}, // X, its storage starts empty
+		{sender, &X, 0, nil},
+		{sender, &X, 0, nil},
+		{sender, &S, 0, nil}, // deletes S with its storage
+		{sender, &S, 5, nil},
+		{sender2, &sender, 7, nil},
+		{sender, &sender2, 1, nil},
+	}
+	cfg := params.TestChainConfig
+	rules := cfg.Rules(1)
+	blockCtx := vm.BlockContext{CanTransfer: core.CanTransfer, Transfer: core.Transfer, GetHash: func(uint64) common.Hash { return common.Hash{} },
+		Coinbase: common.Address{0xc0}, GasLimit: 30_000_000, BlockNumber: 1, Time: 1, Difficulty: big.NewInt(1), BaseFee: uint256.NewInt(0)}
+	nonces := map[common.Address]uint64{}
+	msgs := make([]types.Message, len(txs))
+	for i, tx := range txs {
+		msgs[i] = types.NewMessage(tx.from, tx.to, nonces[tx.from], uint256.NewInt(tx.val), 1_000_000, uint256.NewInt(1), uint256.NewInt(1), uint256.NewInt(1), tx.data, nil, true)
+		nonces[tx.from]++
+	}
+
+	run := func(ibs *state.IntraBlockState, i int) (*core.ExecutionResult, []stateWrite) {
+		evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msgs[i]), ibs, cfg, vm.Config{})
+		ibs.Prepare(common.Hash{byte(i)}, common.Hash{}, i)
+		res, err := core.ApplyMessage(evm, msgs[i], new(core.GasPool).AddGas(30_000_000), true, false)
+		require.NoError(t, err)
+		var rec stateWriteRecorder
+		require.NoError(t, ibs.FinalizeTx(rules, &rec))
+		return res, rec.writes
+	}
+	// recorded one after another on the same state, then each transaction is executed on its own state
+	writes := newBlockStateWrites(len(txs))
+	seq := state.New(base)
+	var results []*core.ExecutionResult
+	for i := range txs {
+		res, w := run(seq, i)
+		results = append(results, res)
+		writes.add(i, w)
+	}
+	for i := range txs {
+		reader := &blockStateReader{base: base, writes: writes, txIndex: i}
+		res, w := run(state.New(reader), i)
+		require.Equal(t, results[i].UsedGas, res.UsedGas, i)
+		require.Equal(t, results[i].Err, res.Err, i)
+		ok, err := writes.reproduced(reader, w)
+		require.NoError(t, err)
+		require.True(t, ok, i)
+	}
+	// state after the block
+	r := &blockStateReader{base: base, writes: writes, txIndex: len(txs)}
+	v, _ := r.ReadAccountStorage(X, 1, &common.Hash{})
+	require.Equal(t, []byte{2}, v)
+	v, _ = r.ReadAccountStorage(C, 1, &common.Hash{})
+	require.Equal(t, []byte{7}, v)
+	v, _ = r.ReadAccountStorage(S, 1, &common.Hash{})
+	require.Nil(t, v)
+
+	// missing changes of the first transaction aren't reproduced by the second
+	broken := newBlockStateWrites(len(txs))
+	var first []stateWrite
+	for _, w := range writes.txs[0] {
+		if w.kind != writeStorage {
+			first = append(first, w)
+		}
+	}
+	broken.add(0, first)
+	for i := 1; i < len(txs); i++ {
+		broken.add(i, writes.txs[i])
+	}
+	reader := &blockStateReader{base: base, writes: broken, txIndex: 1}
+	_, w := run(state.New(reader), 1)
+	ok, err := broken.reproduced(reader, w)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
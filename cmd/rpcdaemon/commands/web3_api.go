@@ -3,8 +3,10 @@ package commands
 import (
 	"context"
 
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
@@ -28,7 +30,11 @@ func NewWeb3APIImpl(ethBackend rpchelper.ApiBackend) *Web3APIImpl {
 }
 
 // ClientVersion implements web3_clientVersion. Returns the current client version.
+// In --datadir mode the version of the rpcdaemon itself is returned.
 func (api *Web3APIImpl) ClientVersion(ctx context.Context) (string, error) {
+	if api.ethBackend == nil {
+		return common.MakeName("erigon", params.VersionWithCommit(params.GitCommit, "")), nil
+	}
 	return api.ethBackend.ClientVersion(ctx)
 }
 
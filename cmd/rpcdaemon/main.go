@@ -4,6 +4,8 @@ import (
 	"os"
 
 	"github.com/ledgerwatch/erigon-lib/common"
+	proto_downloader "github.com/ledgerwatch/erigon-lib/gointerfaces/downloader"
+	"github.com/ledgerwatch/erigon/cmd/downloader/downloadergrpc"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands"
 	"github.com/ledgerwatch/log/v3"
@@ -26,7 +28,19 @@ func main() {
 			defer borDb.Close()
 		}
 
-		apiList := commands.APIList(db, borDb, backend, txPool, mining, ff, stateCache, blockReader, agg, *cfg)
+		var downloader proto_downloader.DownloaderClient
+		if cfg.DownloaderAddr != "" {
+			if downloader, err = downloadergrpc.NewClient(ctx, cfg.DownloaderAddr); err != nil {
+				log.Error("Could not connect to Downloader", "err", err)
+				return nil
+			}
+		}
+		apiList := commands.APIList(db, borDb, backend, txPool, mining, ff, stateCache, blockReader, agg, downloader, *cfg)
+		for _, enabledAPI := range cfg.API {
+			if enabledAPI == "miner" {
+				log.Warn("The miner namespace is served only by the RPC daemon embedded in Erigon (--http.api of erigon)")
+			}
+		}
 		if err := cli.StartRpcServer(ctx, *cfg, apiList, nil); err != nil {
 			log.Error(err.Error())
 			return nil
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"sync/atomic"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
@@ -275,6 +276,7 @@ func (back *RemoteBackend) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
 				Inbound       bool   `json:"inbound"`
 				Trusted       bool   `json:"trusted"`
 				Static        bool   `json:"static"`
+				Subnet        string `json:"subnet,omitempty"`
 			}{
 				LocalAddress:  rpcPeer.ConnLocalAddr,
 				RemoteAddress: rpcPeer.ConnRemoteAddr,
@@ -284,6 +286,12 @@ func (back *RemoteBackend) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
 			},
 			Protocols: nil,
 		}
+		// the reply has the address only, the subnet is derived as p2p.Peer.Info does
+		if host, _, err := net.SplitHostPort(rpcPeer.ConnRemoteAddr); err == nil {
+			if ip := net.ParseIP(host); ip != nil {
+				peer.Network.Subnet = p2p.Subnet(ip)
+			}
+		}
 
 		peers = append(peers, &peer)
 	}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
 	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/grpcutil"
 	"github.com/ledgerwatch/erigon/cmd/sentry/sentry"
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/common/paths"
 	"github.com/ledgerwatch/erigon/internal/debug"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/erigon/p2p"
 	node2 "github.com/ledgerwatch/erigon/turbo/node"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +30,10 @@ var (
 	discoveryDNS []string
 	nodiscover   bool // disable sentry's discovery mechanism
 	protocol     int
+	transport    string
+	peerExchange bool
+	subnetPeers  int
+	selection    string
 	netRestrict  string // CIDR to restrict peering to
 	maxPeers     int
 	maxPendPeers int
@@ -42,9 +49,13 @@ func init() {
 	rootCmd.Flags().IntVar(&port, utils.ListenPortFlag.Name, utils.ListenPortFlag.Value, utils.ListenPortFlag.Usage)
 	rootCmd.Flags().StringSliceVar(&staticPeers, utils.StaticPeersFlag.Name, []string{}, utils.StaticPeersFlag.Usage)
 	rootCmd.Flags().StringSliceVar(&trustedPeers, utils.TrustedPeersFlag.Name, []string{}, utils.TrustedPeersFlag.Usage)
-	rootCmd.Flags().StringSliceVar(&discoveryDNS, utils.DNSDiscoveryFlag.Name, []string{}, utils.DNSDiscoveryFlag.Usage)
+	rootCmd.Flags().StringSliceVar(&discoveryDNS, utils.DNSDiscoveryFlag.Name, nil, utils.DNSDiscoveryFlag.Usage)
 	rootCmd.Flags().BoolVar(&nodiscover, utils.NoDiscoverFlag.Name, false, utils.NoDiscoverFlag.Usage)
 	rootCmd.Flags().IntVar(&protocol, utils.P2pProtocolVersionFlag.Name, utils.P2pProtocolVersionFlag.Value, utils.P2pProtocolVersionFlag.Usage)
+	rootCmd.Flags().StringVar(&transport, utils.P2pTransportFlag.Name, utils.P2pTransportFlag.Value, utils.P2pTransportFlag.Usage)
+	rootCmd.Flags().BoolVar(&peerExchange, utils.P2pPeerExchangeFlag.Name, false, utils.P2pPeerExchangeFlag.Usage)
+	rootCmd.Flags().IntVar(&subnetPeers, utils.P2pMaxPeersPerSubnetFlag.Name, 0, utils.P2pMaxPeersPerSubnetFlag.Usage)
+	rootCmd.Flags().StringVar(&selection, utils.P2pPeerSelectionFlag.Name, utils.P2pPeerSelectionFlag.Value, utils.P2pPeerSelectionFlag.Usage)
 	rootCmd.Flags().StringVar(&netRestrict, utils.NetrestrictFlag.Name, utils.NetrestrictFlag.Value, utils.NetrestrictFlag.Usage)
 	rootCmd.Flags().IntVar(&maxPeers, utils.MaxPeersFlag.Name, utils.MaxPeersFlag.Value, utils.MaxPeersFlag.Usage)
 	rootCmd.Flags().IntVar(&maxPendPeers, utils.MaxPendingPeersFlag.Name, utils.MaxPendingPeersFlag.Value, utils.MaxPendingPeersFlag.Usage)
@@ -53,6 +64,66 @@ func init() {
 	if err := rootCmd.MarkFlagDirname(utils.DataDirFlag.Name); err != nil {
 		panic(err)
 	}
+
+	adminCmd.PersistentFlags().StringVar(&sentryAddr, "sentry.api.addr", "localhost:9091", "grpc address of running sentry")
+	adminCmd.AddCommand(logLevelCmd, peersCmd)
+	rootCmd.AddCommand(adminCmd)
+}
+
+// adminCmd - commands to a running sentry, they don't start p2p and don't setup logging/metrics
+var adminCmd = &cobra.Command{
+	Use:               "admin",
+	Short:             "Control running sentry",
+	PersistentPreRun:  func(cmd *cobra.Command, args []string) {},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var logLevelCmd = &cobra.Command{
+	Use:   "loglevel <crit|error|warn|info|debug|trace>",
+	Short: "Change log verbosity of running sentry",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient()
+		if err != nil {
+			return err
+		}
+		prev, err := client.SetLogLevel(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("log level changed from %s to %s\n", prev, args[0])
+		return nil
+	},
+}
+
+var peersCmd = &cobra.Command{
+	Use:   "peers",
+	Short: "Print peers of running sentry as JSON, with eth protocol status and fork id",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := adminClient()
+		if err != nil {
+			return err
+		}
+		peers, err := client.Peers(cmd.Context())
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(peers, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	},
+}
+
+func adminClient() (*sentry.AdminClient, error) {
+	conn, err := grpcutil.Connect(nil, sentryAddr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to sentry %s: %w", sentryAddr, err)
+	}
+	return sentry.NewAdminClient(conn), nil
 }
 
 var rootCmd = &cobra.Command{
@@ -85,6 +156,13 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		p2pConfig.Transport = transport
+		p2pConfig.PeerExchange = peerExchange
+		p2pConfig.MaxPeersPerSubnet = subnetPeers
+		if selection != p2p.PeerSelectionPermits && selection != p2p.PeerSelectionLatency {
+			return fmt.Errorf("invalid --%s: %s", utils.P2pPeerSelectionFlag.Name, utils.P2pPeerSelectionFlag.Usage)
+		}
+		p2pConfig.PeerSelection = selection
 
 		return sentry.Sentry(cmd.Context(), dirs, sentryAddr, discoveryDNS, p2pConfig, uint(protocol), healthCheck)
 	},
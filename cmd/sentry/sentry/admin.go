@@ -0,0 +1,174 @@
+package sentry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/VictoriaMetrics/metrics"
+	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/internal/debug"
+	"github.com/ledgerwatch/log/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var handshakeFailures = metrics.NewCounter(`sentry_handshake_failures`)
+
+func inboundMessages(msgID proto_sentry.MessageId) *metrics.Counter {
+	return metrics.GetOrCreateCounter(fmt.Sprintf(`sentry_messages_in{id="%s"}`, msgID))
+}
+
+func outboundMessages(version uint, msgcode uint64) *metrics.Counter {
+	if msgID, ok := eth.ToProto[version][msgcode]; ok {
+		return metrics.GetOrCreateCounter(fmt.Sprintf(`sentry_messages_out{id="%s"}`, msgID))
+	}
+	return metrics.GetOrCreateCounter(fmt.Sprintf(`sentry_messages_out{id="%d"}`, msgcode))
+}
+
+// ethPeerInfo - eth protocol part of p2p.PeerInfo: what the peer told about itself in the handshake
+type ethPeerInfo struct {
+	Version  uint32      `json:"version"`
+	TD       *big.Int    `json:"difficulty"`
+	Head     common.Hash `json:"head"`
+	Height   uint64      `json:"height"`
+	ForkHash string      `json:"forkHash"`
+	ForkNext uint64      `json:"forkNext"`
+
+	LatencyMs     int64  `json:"latencyMs,omitempty"` // moving average of response times, absent until the first response
+	LatencyBucket string `json:"latencyBucket"`       // bucket used by --p2p.peer.selection=latency
+}
+
+func newEthPeerInfo(peerInfo *PeerInfo) *ethPeerInfo {
+	if peerInfo.status == nil {
+		return nil
+	}
+	latency := peerInfo.Latency()
+	return &ethPeerInfo{
+		Version:  peerInfo.status.ProtocolVersion,
+		TD:       peerInfo.status.TD,
+		Head:     peerInfo.status.Head,
+		Height:   peerInfo.Height(),
+		ForkHash: fmt.Sprintf("%x", peerInfo.status.ForkID.Hash),
+		ForkNext: peerInfo.status.ForkID.Next,
+
+		LatencyMs:     latency.Milliseconds(),
+		LatencyBucket: latencyBucketName(latencyBucket(latency)),
+	}
+}
+
+// AdminServer - operator's endpoint of standalone sentry, served on the same address as the Sentry service.
+// There is no .proto for it in erigon-lib, messages are well-known types.
+type AdminServer struct {
+	sentry *GrpcServer
+}
+
+// SetLogLevel changes verbosity of the process, returns the previous one
+func (s *AdminServer) SetLogLevel(_ context.Context, req *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	lvl, err := log.LvlFromString(req.Value)
+	if err != nil {
+		return nil, err
+	}
+	prev := debug.Verbosity()
+	debug.SetVerbosity(lvl)
+	log.Info("[sentry] log level changed", "from", prev, "to", lvl)
+	return wrapperspb.String(prev.String()), nil
+}
+
+// Peers returns p2p.PeerInfo of connected peers, including eth protocol status and fork id
+func (s *AdminServer) Peers(_ context.Context, _ *emptypb.Empty) (*structpb.ListValue, error) {
+	if s.sentry.P2pServer == nil {
+		return nil, errors.New("p2p server was not started")
+	}
+	raw, err := json.Marshal(s.sentry.P2pServer.PeersInfo())
+	if err != nil {
+		return nil, err
+	}
+	var peers []interface{}
+	if err = json.Unmarshal(raw, &peers); err != nil {
+		return nil, err
+	}
+	return structpb.NewList(peers)
+}
+
+type adminServer interface {
+	SetLogLevel(context.Context, *wrapperspb.StringValue) (*wrapperspb.StringValue, error)
+	Peers(context.Context, *emptypb.Empty) (*structpb.ListValue, error)
+}
+
+const adminServiceName = "sentry.Admin"
+
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: adminServiceName,
+	HandlerType: (*adminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetLogLevel",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(wrapperspb.StringValue)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(adminServer).SetLogLevel(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + adminServiceName + "/SetLogLevel"}
+				return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(adminServer).SetLogLevel(ctx, req.(*wrapperspb.StringValue))
+				})
+			},
+		},
+		{
+			MethodName: "Peers",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return srv.(adminServer).Peers(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + adminServiceName + "/Peers"}
+				return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(adminServer).Peers(ctx, req.(*emptypb.Empty))
+				})
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+func RegisterAdminServer(s *grpc.Server, srv *AdminServer) {
+	s.RegisterService(&adminServiceDesc, srv)
+}
+
+// AdminClient - client of AdminServer, used by `sentry admin` commands
+type AdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminClient(cc grpc.ClientConnInterface) *AdminClient {
+	return &AdminClient{cc: cc}
+}
+
+func (c *AdminClient) SetLogLevel(ctx context.Context, lvl string) (prev string, err error) {
+	out := new(wrapperspb.StringValue)
+	if err = c.cc.Invoke(ctx, "/"+adminServiceName+"/SetLogLevel", wrapperspb.String(lvl), out); err != nil {
+		return "", err
+	}
+	return out.Value, nil
+}
+
+func (c *AdminClient) Peers(ctx context.Context) ([]interface{}, error) {
+	out := new(structpb.ListValue)
+	if err := c.cc.Invoke(ctx, "/"+adminServiceName+"/Peers", &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+	return out.AsSlice(), nil
+}
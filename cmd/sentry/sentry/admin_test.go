@@ -0,0 +1,66 @@
+package sentry
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/forkid"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/internal/debug"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestAdminSetLogLevel(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	RegisterAdminServer(server, &AdminServer{sentry: &GrpcServer{}})
+	go server.Serve(listener) //nolint:errcheck
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := NewAdminClient(conn)
+
+	defer debug.SetVerbosity(debug.Verbosity())
+	debug.SetVerbosity(log.LvlInfo)
+	prev, err := client.SetLogLevel(context.Background(), "debug")
+	require.NoError(t, err)
+	require.Equal(t, log.LvlInfo.String(), prev)
+	require.Equal(t, log.LvlDebug, debug.Verbosity())
+
+	_, err = client.SetLogLevel(context.Background(), "loud")
+	require.Error(t, err)
+	require.Equal(t, log.LvlDebug, debug.Verbosity())
+
+	// p2p server is not started
+	_, err = client.Peers(context.Background())
+	require.Error(t, err)
+}
+
+func TestEthPeerInfo(t *testing.T) {
+	peerInfo := &PeerInfo{}
+	require.Nil(t, newEthPeerInfo(peerInfo))
+
+	peerInfo.status = &eth.StatusPacket{
+		ProtocolVersion: eth.ETH66,
+		TD:              big.NewInt(100),
+		Head:            common.HexToHash("0x01"),
+		ForkID:          forkid.ID{Hash: [4]byte{0xfc, 0x64, 0xec, 0x04}, Next: 1150000},
+	}
+	peerInfo.SetIncreasedHeight(10)
+	info := newEthPeerInfo(peerInfo)
+	require.Equal(t, uint32(eth.ETH66), info.Version)
+	require.Equal(t, "fc64ec04", info.ForkHash)
+	require.Equal(t, uint64(1150000), info.ForkNext)
+	require.Equal(t, uint64(10), info.Height)
+}
@@ -2,6 +2,7 @@ package sentry
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
@@ -40,8 +41,12 @@ func tryDecodeStatusMessage(msg *p2p.Msg) (*eth.StatusPacket, error) {
 		return nil, fmt.Errorf("message is too large %d, limit %d", msg.Size, eth.ProtocolMaxMsgSize)
 	}
 
+	data := make([]byte, msg.Size)
+	if _, err := io.ReadFull(msg.Payload, data); err != nil {
+		return nil, fmt.Errorf("read message %v: %w", msg, err)
+	}
 	var reply eth.StatusPacket
-	if err := msg.Decode(&reply); err != nil {
+	if err := eth.DecodeMessage(data, &reply); err != nil {
 		return nil, fmt.Errorf("decode message %v: %w", msg, err)
 	}
 
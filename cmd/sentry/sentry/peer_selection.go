@@ -0,0 +1,108 @@
+package sentry
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p"
+)
+
+const (
+	// requestTTL - deadline of requests sent by SendMessageByMinBlock, the time of a request is its deadline minus the ttl
+	requestTTL = 30 * time.Second
+	// latencyEWMAWeight - weight of the last response time in PeerInfo.Latency
+	latencyEWMAWeight = 0.2
+)
+
+// latencyBuckets - upper bounds of latency buckets, peers slower than the last one are in the last bucket
+var latencyBuckets = []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, time.Second}
+
+// latencyBucket - index of the bucket of the latency. Peers with unknown latency are in the second bucket: they get
+// requests (and so are measured) before slow peers, but after known fast ones.
+func latencyBucket(latency time.Duration) int {
+	if latency == 0 {
+		return 1
+	}
+	for i, bound := range latencyBuckets {
+		if latency < bound {
+			return i
+		}
+	}
+	return len(latencyBuckets)
+}
+
+func latencyBucketName(bucket int) string {
+	if bucket < len(latencyBuckets) {
+		return fmt.Sprintf("<%s", latencyBuckets[bucket])
+	}
+	return fmt.Sprintf(">=%s", latencyBuckets[len(latencyBuckets)-1])
+}
+
+// observeLatency updates the moving average of response times, called under pi.lock
+func (pi *PeerInfo) observeLatency(rtt time.Duration) {
+	if pi.latency == 0 {
+		pi.latency = rtt
+		return
+	}
+	pi.latency = time.Duration(latencyEWMAWeight*float64(rtt) + (1-latencyEWMAWeight)*float64(pi.latency))
+}
+
+// Latency - moving average of response times to SendMessageByMinBlock requests, 0 if the peer didn't respond yet
+func (pi *PeerInfo) Latency() time.Duration {
+	pi.lock.RLock()
+	defer pi.lock.RUnlock()
+	return pi.latency
+}
+
+// findPeerByLatency chooses a random peer of the fastest latency bucket among peers with free permits, so
+// time-critical requests go to fast peers without loading one of them only
+func (ss *GrpcServer) findPeerByLatency(minBlock uint64) (*PeerInfo, bool) {
+	var candidates []*PeerInfo
+	bestBucket := len(latencyBuckets) + 1
+	now := time.Now()
+	ss.rangePeers(func(peerInfo *PeerInfo) bool {
+		if peerInfo.Height() < minBlock || peerInfo.ClearDeadlines(now, false /* givePermit */) >= maxPermitsPerPeer {
+			return true
+		}
+		bucket := latencyBucket(peerInfo.Latency())
+		if bucket < bestBucket {
+			bestBucket, candidates = bucket, candidates[:0]
+		}
+		if bucket == bestBucket {
+			candidates = append(candidates, peerInfo)
+		}
+		return true
+	})
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	return candidates[rand.Intn(len(candidates))], true // nolint: gosec
+}
+
+// findPeerForMessage - findPeerByMinBlock, or findPeerByLatency for block bodies if the latency policy is configured
+func (ss *GrpcServer) findPeerForMessage(msgcode uint64, minBlock uint64) (*PeerInfo, bool) {
+	if msgcode == eth.GetBlockBodiesMsg && ss.p2p != nil && ss.p2p.PeerSelection == p2p.PeerSelectionLatency {
+		return ss.findPeerByLatency(minBlock)
+	}
+	return ss.findPeerByMinBlock(minBlock)
+}
+
+// registerLatencyMetrics - number of peers in each latency bucket
+func (ss *GrpcServer) registerLatencyMetrics() {
+	for bucket := 0; bucket <= len(latencyBuckets); bucket++ {
+		bucket := bucket
+		metrics.GetOrCreateGauge(fmt.Sprintf(`sentry_peers_latency{bucket="%s"}`, latencyBucketName(bucket)), func() float64 {
+			var count int
+			ss.rangePeers(func(peerInfo *PeerInfo) bool {
+				if latencyBucket(peerInfo.Latency()) == bucket {
+					count++
+				}
+				return true
+			})
+			return float64(count)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package sentry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerLatency(t *testing.T) {
+	pi := &PeerInfo{}
+	require.Equal(t, 1, latencyBucket(pi.Latency())) // unknown
+
+	sent := time.Now()
+	pi.AddDeadline(sent.Add(requestTTL))
+	pi.AddDeadline(sent.Add(requestTTL + time.Millisecond))
+	require.Equal(t, 1, pi.ClearDeadlines(sent.Add(50*time.Millisecond), true))
+	require.Equal(t, 50*time.Millisecond, pi.Latency())
+	require.Equal(t, 0, latencyBucket(pi.Latency()))
+
+	// expired requests aren't responses
+	require.Equal(t, 0, pi.ClearDeadlines(sent.Add(time.Minute), false))
+	require.Equal(t, 50*time.Millisecond, pi.Latency())
+
+	pi.AddDeadline(sent.Add(requestTTL))
+	pi.ClearDeadlines(sent.Add(1050*time.Millisecond), true)
+	require.Equal(t, 250*time.Millisecond, pi.Latency())
+	require.Equal(t, "<300ms", latencyBucketName(latencyBucket(pi.Latency())))
+	require.Equal(t, ">=1s", latencyBucketName(latencyBucket(2*time.Second)))
+}
+
+func TestFindPeerByLatency(t *testing.T) {
+	ss := &GrpcServer{p2p: &p2p.Config{PeerSelection: p2p.PeerSelectionLatency}}
+	newPeer := func(id byte, height uint64, latency time.Duration) *PeerInfo {
+		pi := &PeerInfo{height: height, latency: latency}
+		ss.GoodPeers.Store([64]byte{id}, pi)
+		return pi
+	}
+	slow := newPeer(1, 100, 2*time.Second)
+	unknown := newPeer(2, 100, 0)
+	fast := newPeer(3, 100, 20*time.Millisecond)
+	behind := newPeer(4, 10, 10*time.Millisecond)
+
+	found, ok := ss.findPeerForMessage(eth.GetBlockBodiesMsg, 50)
+	require.True(t, ok)
+	require.Equal(t, fast, found)
+	found, ok = ss.findPeerForMessage(eth.GetBlockBodiesMsg, 0)
+	require.True(t, ok)
+	require.Contains(t, []*PeerInfo{fast, behind}, found)
+
+	// busy peers are skipped
+	for i := 0; i < maxPermitsPerPeer; i++ {
+		fast.AddDeadline(time.Now().Add(requestTTL))
+	}
+	found, _ = ss.findPeerForMessage(eth.GetBlockBodiesMsg, 50)
+	require.Equal(t, unknown, found)
+	for i := 0; i < maxPermitsPerPeer; i++ {
+		unknown.AddDeadline(time.Now().Add(requestTTL))
+	}
+	found, _ = ss.findPeerForMessage(eth.GetBlockBodiesMsg, 50)
+	require.Equal(t, slow, found)
+	for i := 0; i < maxPermitsPerPeer; i++ {
+		slow.AddDeadline(time.Now().Add(requestTTL))
+	}
+	_, ok = ss.findPeerForMessage(eth.GetBlockBodiesMsg, 50)
+	require.False(t, ok)
+
+	// other requests go to the peer with the most permits
+	found, ok = ss.findPeerForMessage(eth.GetBlockHeadersMsg, 0)
+	require.True(t, ok)
+	require.Equal(t, behind, found)
+}
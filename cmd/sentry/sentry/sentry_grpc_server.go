@@ -17,6 +17,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/VictoriaMetrics/metrics"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/dir"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
@@ -53,9 +54,11 @@ const (
 type PeerInfo struct {
 	peer      *p2p.Peer
 	lock      sync.RWMutex
-	deadlines []time.Time // Request deadlines
+	deadlines []time.Time   // Request deadlines
+	latency   time.Duration // see Latency
 	height    uint64
 	rw        p2p.MsgReadWriter
+	status    *eth.StatusPacket // status of the peer from the eth handshake, set before the peer is added to GoodPeers
 
 	removed    chan struct{} // close this channel on remove
 	ctx        context.Context
@@ -134,6 +137,8 @@ func (pi *PeerInfo) ClearDeadlines(now time.Time, givePermit bool) int {
 	})
 	cutOff := firstNotPassed
 	if cutOff < len(pi.deadlines) && givePermit {
+		// the response is attributed to the oldest pending request
+		pi.observeLatency(now.Sub(pi.deadlines[cutOff].Add(-requestTTL)))
 		cutOff++
 	}
 	pi.deadlines = pi.deadlines[cutOff:]
@@ -221,7 +226,7 @@ func handShake(
 	rw p2p.MsgReadWriter,
 	version uint,
 	minVersion uint,
-	startSync func(bestHash common.Hash) error,
+	startSync func(status *eth.StatusPacket) error,
 ) error {
 	if status == nil {
 		return fmt.Errorf("could not get status message from core for peer %s connection", peerID)
@@ -251,7 +256,7 @@ func handShake(
 		reply, err := readAndValidatePeerStatusMessage(rw, status, version, minVersion)
 
 		if (err == nil) && (startSync != nil) {
-			err = startSync(reply.Head)
+			err = startSync(reply)
 		}
 
 		errc <- err
@@ -423,6 +428,11 @@ func runPeer(
 			if _, err := io.ReadFull(msg.Payload, b); err != nil {
 				log.Error(fmt.Sprintf("%s: reading msg into bytes: %v", peerID, err))
 			}
+			// the transaction pool decodes the message by itself, its limits are checked here
+			if err := eth.DecodeMessage(b, new(eth.NewPooledTransactionHashesPacket)); err != nil {
+				msg.Discard()
+				return fmt.Errorf("NewPooledTransactionHashesMsg: %w", err)
+			}
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 		case eth.GetPooledTransactionsMsg:
 			if !hasSubscribers(eth.ToProto[protocol][msg.Code]) {
@@ -433,6 +443,11 @@ func runPeer(
 			if _, err := io.ReadFull(msg.Payload, b); err != nil {
 				log.Error(fmt.Sprintf("%s: reading msg into bytes: %v", peerID, err))
 			}
+			// the transaction pool decodes the message by itself, its limits are checked here
+			if err := eth.DecodeMessage(b, new(eth.GetPooledTransactionsPacket66)); err != nil {
+				msg.Discard()
+				return fmt.Errorf("GetPooledTransactionsMsg: %w", err)
+			}
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 		case eth.TransactionsMsg:
 			if !hasSubscribers(eth.ToProto[protocol][msg.Code]) {
@@ -477,6 +492,7 @@ func grpcSentryServer(ctx context.Context, sentryAddr string, ss *GrpcServer, he
 	}
 	grpcServer := grpcutil.NewServer(100, nil)
 	proto_sentry.RegisterSentryServer(grpcServer, ss)
+	RegisterAdminServer(grpcServer, &AdminServer{sentry: ss})
 	var healthServer *health.Server
 	if healthCheck {
 		healthServer = health.NewServer()
@@ -494,11 +510,14 @@ func grpcSentryServer(ctx context.Context, sentryAddr string, ss *GrpcServer, he
 	return grpcServer, nil
 }
 
-func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeInfo func() *eth.NodeInfo, cfg *p2p.Config, protocol uint) *GrpcServer {
+// NewGrpcServer - discoveryDNS are enrtree:// URLs of EIP-1459 node lists, used as dial candidates in addition
+// to discv4: nil - public list of the network (known by genesis), empty - DNS discovery is disabled.
+func NewGrpcServer(ctx context.Context, discoveryDNS []string, readNodeInfo func() *eth.NodeInfo, cfg *p2p.Config, protocol uint) *GrpcServer {
 	ss := &GrpcServer{
 		ctx:          ctx,
 		p2p:          cfg,
 		peersStreams: NewPeersStreams(),
+		discoveryDNS: discoveryDNS,
 	}
 
 	if protocol != eth.ETH66 && protocol != eth.ETH67 {
@@ -506,10 +525,9 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 	}
 
 	ss.Protocol = p2p.Protocol{
-		Name:           eth.ProtocolName,
-		Version:        protocol,
-		Length:         17,
-		DialCandidates: dialCandidates,
+		Name:    eth.ProtocolName,
+		Version: protocol,
+		Length:  17,
 		Run: func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
 			peerID := peer.Pubkey()
 			printablePeerID := hex.EncodeToString(peerID[:])[:20]
@@ -523,12 +541,14 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 			defer peerInfo.Close()
 
 			defer ss.GoodPeers.Delete(peerID)
-			err := handShake(ctx, ss.GetStatus(), peerID, rw, protocol, protocol, func(bestHash common.Hash) error {
+			err := handShake(ctx, ss.GetStatus(), peerID, rw, protocol, protocol, func(status *eth.StatusPacket) error {
+				peerInfo.status = status
 				ss.GoodPeers.Store(peerID, peerInfo)
 				ss.sendNewPeerToClients(gointerfaces.ConvertHashToH512(peerID))
-				return ss.startSync(ctx, bestHash, peerID)
+				return ss.startSync(ctx, status.Head, peerID)
 			})
 			if err != nil {
+				handshakeFailures.Inc()
 				return fmt.Errorf("handshake to peer %s: %w", printablePeerID, err)
 			}
 			log.Trace(fmt.Sprintf("[%s] Received status message OK", printablePeerID), "name", peer.Name())
@@ -550,8 +570,11 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 			return readNodeInfo()
 		},
 		PeerInfo: func(peerID [64]byte) interface{} {
-			// TODO: remember handshake reply per peer ID and return eth-related Status info (see ethPeerInfo in geth)
-			return nil
+			peerInfo := ss.getPeer(peerID)
+			if peerInfo == nil {
+				return nil
+			}
+			return newEthPeerInfo(peerInfo)
 		},
 		//Attributes: []enr.Entry{eth.CurrentENREntry(chainConfig, genesisHash, headHeight)},
 	}
@@ -562,8 +585,9 @@ func NewGrpcServer(ctx context.Context, dialCandidates enode.Iterator, readNodeI
 // Sentry creates and runs standalone sentry
 func Sentry(ctx context.Context, dirs datadir.Dirs, sentryAddr string, discoveryDNS []string, cfg *p2p.Config, protocolVersion uint, healthCheck bool) error {
 	dir.MustExist(dirs.DataDir)
-	sentryServer := NewGrpcServer(ctx, nil, func() *eth.NodeInfo { return nil }, cfg, protocolVersion)
-	sentryServer.discoveryDNS = discoveryDNS
+	sentryServer := NewGrpcServer(ctx, discoveryDNS, func() *eth.NodeInfo { return nil }, cfg, protocolVersion)
+	metrics.GetOrCreateGauge(fmt.Sprintf(`sentry_peers{protocol="eth%d"}`, protocolVersion), func() float64 { return float64(sentryServer.SimplePeerCount()) })
+	sentryServer.registerLatencyMetrics()
 
 	grpcServer, err := grpcSentryServer(ctx, sentryAddr, sentryServer, healthCheck)
 	if err != nil {
@@ -633,6 +657,7 @@ func (ss *GrpcServer) writePeer(logPrefix string, peerInfo *PeerInfo, msgcode ui
 				log.Debug(logPrefix, "msgcode", msgcode, "err", err)
 			}
 		} else {
+			outboundMessages(ss.Protocol.Version, msgcode).Inc()
 			if ttl > 0 {
 				peerInfo.AddDeadline(time.Now().Add(ttl))
 			}
@@ -732,15 +757,15 @@ func (ss *GrpcServer) SendMessageByMinBlock(_ context.Context, inreq *proto_sent
 		msgcode != eth.GetPooledTransactionsMsg {
 		return reply, fmt.Errorf("sendMessageByMinBlock not implemented for message Id: %s", inreq.Data.Id)
 	}
-	peerInfo, found := ss.findPeerByMinBlock(inreq.MinBlock)
+	peerInfo, found := ss.findPeerForMessage(msgcode, inreq.MinBlock)
 	if found {
-		ss.writePeer("sendMessageByMinBlock", peerInfo, msgcode, inreq.Data.Data, 30*time.Second)
+		ss.writePeer("sendMessageByMinBlock", peerInfo, msgcode, inreq.Data.Data, requestTTL)
 		reply.Peers = []*proto_types.H512{gointerfaces.ConvertHashToH512(peerInfo.ID())}
 	} else {
 		// If peer with specified minBlock is not found, send to best peer with permits
 		peerInfo, found = ss.findBestPeerWithPermit()
 		if found {
-			ss.writePeer("sendMessageByMinBlock", peerInfo, msgcode, inreq.Data.Data, 30*time.Second)
+			ss.writePeer("sendMessageByMinBlock", peerInfo, msgcode, inreq.Data.Data, requestTTL)
 			reply.Peers = []*proto_types.H512{gointerfaces.ConvertHashToH512(peerInfo.ID())}
 		}
 	}
@@ -847,12 +872,13 @@ func (ss *GrpcServer) SetStatus(ctx context.Context, statusData *proto_sentry.St
 	if ss.P2pServer == nil {
 		var err error
 		if !ss.p2p.NoDiscovery {
-			if len(ss.discoveryDNS) == 0 {
+			urls := ss.discoveryDNS
+			if urls == nil { // not configured - public node list of the network
 				if url := params.KnownDNSNetwork(genesisHash, "all"); url != "" {
-					ss.discoveryDNS = []string{url}
+					urls = []string{url}
 				}
 			}
-			ss.Protocol.DialCandidates, err = setupDiscovery(ss.discoveryDNS)
+			ss.Protocol.DialCandidates, err = setupDiscovery(urls)
 			if err != nil {
 				return nil, err
 			}
@@ -952,6 +978,7 @@ func setupDiscovery(urls []string) (enode.Iterator, error) {
 	if len(urls) == 0 {
 		return nil, nil
 	}
+	log.Info("[p2p] DNS discovery", "urls", urls)
 	client := dnsdisc.NewClient(dnsdisc.Config{})
 	return client.NewIterator(urls...)
 }
@@ -963,6 +990,7 @@ func (ss *GrpcServer) GetStatus() *proto_sentry.StatusData {
 }
 
 func (ss *GrpcServer) send(msgID proto_sentry.MessageId, peerID [64]byte, b []byte) {
+	inboundMessages(msgID).Inc()
 	ss.messageStreamsLock.RLock()
 	defer ss.messageStreamsLock.RUnlock()
 	req := &proto_sentry.InboundMessage{
@@ -71,6 +71,9 @@ func (cs *MultiClient) RecvUploadMessageLoop(
 		eth.ToProto[eth.ETH66][eth.GetBlockBodiesMsg],
 		eth.ToProto[eth.ETH66][eth.GetReceiptsMsg],
 	}
+	if cs.nodeData != nil {
+		ids = append(ids, eth.ToProto[eth.ETH66][eth.GetNodeDataMsg])
+	}
 	streamFactory := func(streamCtx context.Context, sentry direct.SentryClient) (sentryMessageStream, error) {
 		return sentry.Messages(streamCtx, &proto_sentry.MessagesRequest{Ids: ids}, grpc.WaitForReady(true))
 	}
@@ -211,7 +214,7 @@ func pumpStreamLoop[TMessage interface{}](
 
 	go func() {
 		for req := range reqs {
-			if err := handleInboundMessage(ctx, req, sentry); err != nil {
+			if err := handleRecovered(ctx, handleInboundMessage, req, sentry); err != nil {
 				log.Debug("Handling incoming message", "stream", streamName, "err", err)
 			}
 			if wg != nil {
@@ -241,6 +244,21 @@ func pumpStreamLoop[TMessage interface{}](
 	return ctx.Err()
 }
 
+// handleRecovered returns a panic of the handler as an error, the following messages are handled then
+func handleRecovered[TMessage interface{}](
+	ctx context.Context,
+	handleInboundMessage func(context.Context, TMessage, direct.SentryClient) error,
+	req TMessage,
+	sentry direct.SentryClient,
+) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%+v, trace: %s", rec, dbg.Stack())
+		}
+	}()
+	return handleInboundMessage(ctx, req, sentry)
+}
+
 // MultiClient - does handle request/response/subscriptions to multiple sentries
 // each sentry may support same or different p2p protocol
 type MultiClient struct {
@@ -262,6 +280,7 @@ type MultiClient struct {
 	Engine        consensus.Engine
 	blockReader   services.HeaderAndCanonicalReader
 	logPeerInfo   bool
+	nodeData      *eth.NodeDataServer // nil if serving of GetNodeData is disabled
 
 	historyV3 bool
 }
@@ -313,6 +332,11 @@ func NewMultiClient(
 	cs.genesisHash = genesisHash
 	cs.networkId = networkID
 	var err error
+	if syncCfg.NodeDataRate > 0 {
+		if cs.nodeData, err = eth.NewNodeDataServer(syncCfg.NodeDataRate); err != nil {
+			return nil, err
+		}
+	}
 	err = db.View(context.Background(), func(tx kv.Tx) error {
 		cs.headHeight, cs.headHash, cs.headTd, err = cs.Bd.UpdateFromDb(tx)
 		return err
@@ -328,9 +352,13 @@ func (cs *MultiClient) newBlockHashes66(ctx context.Context, req *proto_sentry.I
 	}
 	//log.Info(fmt.Sprintf("NewBlockHashes from [%s]", ConvertH256ToPeerID(req.PeerId)))
 	var request eth.NewBlockHashesPacket
-	if err := rlp.DecodeBytes(req.Data, &request); err != nil {
+	if err := eth.DecodeMessage(req.Data, &request); err != nil {
 		return fmt.Errorf("decode NewBlockHashes66: %w", err)
 	}
+	if penalty := cs.Hd.AdmitAnnounces(ConvertH512ToPeerID(req.PeerId), len(request)); penalty != headerdownload.NoPenalty {
+		cs.Penalize(ctx, []headerdownload.PenaltyItem{{PeerID: ConvertH512ToPeerID(req.PeerId), Penalty: penalty}})
+		return nil
+	}
 	for _, announce := range request {
 		cs.Hd.SaveExternalAnnounce(announce.Hash)
 		if cs.Hd.HasLink(announce.Hash) {
@@ -370,7 +398,7 @@ func (cs *MultiClient) newBlockHashes66(ctx context.Context, req *proto_sentry.I
 func (cs *MultiClient) blockHeaders66(ctx context.Context, in *proto_sentry.InboundMessage, sentry direct.SentryClient) error {
 	// Parse the entire packet from scratch
 	var pkt eth.BlockHeadersPacket66
-	if err := rlp.DecodeBytes(in.Data, &pkt); err != nil {
+	if err := eth.DecodeMessage(in.Data, &pkt); err != nil {
 		return fmt.Errorf("decode 1 BlockHeadersPacket66: %w", err)
 	}
 
@@ -388,6 +416,10 @@ func (cs *MultiClient) blockHeaders66(ctx context.Context, in *proto_sentry.Inbo
 }
 
 func (cs *MultiClient) blockHeaders(ctx context.Context, pkt eth.BlockHeadersPacket, rlpStream *rlp.Stream, peerID *proto_types.H512, sentry direct.SentryClient) error {
+	if penalty := cs.Hd.AdmitSegment(len(pkt)); penalty != headerdownload.NoPenalty {
+		cs.Penalize(ctx, []headerdownload.PenaltyItem{{PeerID: ConvertH512ToPeerID(peerID), Penalty: penalty}})
+		return nil
+	}
 	// Stream is at the BlockHeadersPacket, which is list of headers
 	if _, err := rlpStream.List(); err != nil {
 		return fmt.Errorf("decode 2 BlockHeadersPacket66: %w", err)
@@ -472,11 +504,12 @@ func (cs *MultiClient) newBlock66(ctx context.Context, inreq *proto_sentry.Inbou
 	}
 	// Parse the entire request from scratch
 	request := &eth.NewBlockPacket{}
-	if err := rlp.DecodeBytes(inreq.Data, &request); err != nil {
+	if err := eth.DecodeMessage(inreq.Data, request); err != nil {
 		return fmt.Errorf("decode 4 NewBlockMsg: %w", err)
 	}
-	if err := request.SanityCheck(); err != nil {
-		return fmt.Errorf("newBlock66: %w", err)
+	if penalty := cs.Hd.AdmitAnnounces(ConvertH512ToPeerID(inreq.PeerId), 1); penalty != headerdownload.NoPenalty {
+		cs.Penalize(ctx, []headerdownload.PenaltyItem{{PeerID: ConvertH512ToPeerID(inreq.PeerId), Penalty: penalty}})
+		return nil
 	}
 
 	if segments, penalty, err := cs.Hd.SingleHeaderAsSegment(headerRaw, request.Block.Header(), true /* penalizePoSBlocks */); err == nil {
@@ -500,6 +533,9 @@ func (cs *MultiClient) newBlock66(ctx context.Context, inreq *proto_sentry.Inbou
 			}
 
 			cs.Hd.ProcessHeaders(segments, true /* newBlock */, ConvertH512ToPeerID(inreq.PeerId)) // There is only one segment in this case
+			if !cs.Hd.POSSync() {
+				cs.backfillParentHole(ctx, request.Block.Header(), inreq.PeerId, sentry)
+			}
 		} else {
 			outreq := proto_sentry.PenalizePeerRequest{
 				PeerId:  inreq.PeerId,
@@ -529,9 +565,53 @@ func (cs *MultiClient) newBlock66(ctx context.Context, inreq *proto_sentry.Inbou
 	return nil
 }
 
+// maxParentHoleBackfill is the maximum number of missing ancestors of an announced block
+// which are requested directly from the announcing peer
+const maxParentHoleBackfill = 32
+
+// backfillParentHole asks the peer which announced a block for its missing ancestors, if
+// there are only a few of them. Otherwise the hole is left to the header downloader.
+func (cs *MultiClient) backfillParentHole(ctx context.Context, header *types.Header, peerID *proto_types.H512, sentry direct.SentryClient) {
+	req := cs.Hd.RequestParentHole(header, maxParentHoleBackfill)
+	if req == nil {
+		return
+	}
+	b, err := rlp.EncodeToBytes(&eth.GetBlockHeadersPacket66{
+		RequestId: rand.Uint64(), // nolint: gosec
+		GetBlockHeadersPacket: &eth.GetBlockHeadersPacket{
+			Amount:  req.Length,
+			Reverse: req.Reverse,
+			Skip:    req.Skip,
+			Origin:  eth.HashOrNumber{Hash: req.Hash},
+		},
+	})
+	if err != nil {
+		log.Error("Could not encode header request", "err", err)
+		return
+	}
+	outreq := proto_sentry.SendMessageByIdRequest{
+		PeerId: peerID,
+		Data: &proto_sentry.OutboundMessageData{
+			Id:   proto_sentry.MessageId_GET_BLOCK_HEADERS_66,
+			Data: b,
+		},
+	}
+	if _, err = sentry.SendMessageById(ctx, &outreq, &grpc.EmptyCallOption{}); err != nil {
+		if !isPeerNotFoundErr(err) {
+			log.Error("Could not send header request", "err", err)
+		}
+		return
+	}
+	currentTime := time.Now()
+	cs.Hd.UpdateStats(req, false /* skeleton */)
+	// If the peer does not deliver, the anchor is retried with other peers by the header downloader
+	cs.Hd.UpdateRetryTime(req, currentTime, 5*time.Second /* timeout */)
+	log.Trace("[Downloader] Backfilling parent hole of announced block", "block", header.Number.Uint64(), "missing", req.Length, "peer", ConvertH512ToPeerID(peerID))
+}
+
 func (cs *MultiClient) blockBodies66(inreq *proto_sentry.InboundMessage, _ direct.SentryClient) error {
 	var request eth.BlockRawBodiesPacket66
-	if err := rlp.DecodeBytes(inreq.Data, &request); err != nil {
+	if err := eth.DecodeMessage(inreq.Data, &request); err != nil {
 		return fmt.Errorf("decode BlockBodiesPacket66: %w", err)
 	}
 	txs, uncles := request.BlockRawBodiesPacket.Unpack()
@@ -545,7 +625,7 @@ func (cs *MultiClient) receipts66(_ context.Context, _ *proto_sentry.InboundMess
 
 func (cs *MultiClient) getBlockHeaders66(ctx context.Context, inreq *proto_sentry.InboundMessage, sentry direct.SentryClient) error {
 	var query eth.GetBlockHeadersPacket66
-	if err := rlp.DecodeBytes(inreq.Data, &query); err != nil {
+	if err := eth.DecodeMessage(inreq.Data, &query); err != nil {
 		return fmt.Errorf("decoding getBlockHeaders66: %w, data: %x", err, inreq.Data)
 	}
 
@@ -586,7 +666,7 @@ func (cs *MultiClient) getBlockHeaders66(ctx context.Context, inreq *proto_sentr
 
 func (cs *MultiClient) getBlockBodies66(ctx context.Context, inreq *proto_sentry.InboundMessage, sentry direct.SentryClient) error {
 	var query eth.GetBlockBodiesPacket66
-	if err := rlp.DecodeBytes(inreq.Data, &query); err != nil {
+	if err := eth.DecodeMessage(inreq.Data, &query); err != nil {
 		return fmt.Errorf("decoding getBlockBodies66: %w, data: %x", err, inreq.Data)
 	}
 	tx, err := cs.db.BeginRo(ctx)
@@ -627,7 +707,7 @@ func (cs *MultiClient) getReceipts66(ctx context.Context, inreq *proto_sentry.In
 	}
 
 	var query eth.GetReceiptsPacket66
-	if err := rlp.DecodeBytes(inreq.Data, &query); err != nil {
+	if err := eth.DecodeMessage(inreq.Data, &query); err != nil {
 		return fmt.Errorf("decoding getReceipts66: %w, data: %x", err, inreq.Data)
 	}
 	tx, err := cs.db.BeginRo(ctx)
@@ -665,6 +745,48 @@ func (cs *MultiClient) getReceipts66(ctx context.Context, inreq *proto_sentry.In
 	return nil
 }
 
+func (cs *MultiClient) getNodeData66(ctx context.Context, inreq *proto_sentry.InboundMessage, sentry direct.SentryClient) error {
+	if cs.nodeData == nil {
+		return nil
+	}
+	var query eth.GetNodeDataPacket66
+	if err := eth.DecodeMessage(inreq.Data, &query); err != nil {
+		return fmt.Errorf("decoding getNodeData66: %w, data: %x", err, inreq.Data)
+	}
+	tx, err := cs.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	nodes, err := cs.nodeData.AnswerGetNodeDataQuery(tx, query.GetNodeDataPacket)
+	if err != nil {
+		return err
+	}
+	tx.Rollback()
+	b, err := rlp.EncodeToBytes(&eth.NodeDataPacket66{
+		RequestId:      query.RequestId,
+		NodeDataPacket: nodes,
+	})
+	if err != nil {
+		return fmt.Errorf("encode node data response: %w", err)
+	}
+	outreq := proto_sentry.SendMessageByIdRequest{
+		PeerId: inreq.PeerId,
+		Data: &proto_sentry.OutboundMessageData{
+			Id:   proto_sentry.MessageId_NODE_DATA_66,
+			Data: b,
+		},
+	}
+	_, err = sentry.SendMessageById(ctx, &outreq, &grpc.EmptyCallOption{})
+	if err != nil {
+		if isPeerNotFoundErr(err) {
+			return nil
+		}
+		return fmt.Errorf("send node data response: %w", err)
+	}
+	return nil
+}
+
 func makeInboundMessage() *proto_sentry.InboundMessage {
 	return new(proto_sentry.InboundMessage)
 }
@@ -678,8 +800,8 @@ func (cs *MultiClient) HandleInboundMessage(ctx context.Context, message *proto_
 
 	err = cs.handleInboundMessage(ctx, message, sentry)
 
-	if (err != nil) && rlp.IsInvalidRLPError(err) {
-		log.Debug("Kick peer for invalid RLP", "err", err)
+	if (err != nil) && (rlp.IsInvalidRLPError(err) || errors.Is(err, eth.ErrMalformedMessage)) {
+		log.Debug("Kick peer for invalid message", "err", err)
 		penalizeRequest := proto_sentry.PenalizePeerRequest{
 			PeerId:  message.PeerId,
 			Penalty: proto_sentry.PenaltyKind_Kick, // TODO: Extend penalty kinds
@@ -712,6 +834,8 @@ func (cs *MultiClient) handleInboundMessage(ctx context.Context, inreq *proto_se
 		return cs.receipts66(ctx, inreq, sentry)
 	case proto_sentry.MessageId_GET_RECEIPTS_66:
 		return cs.getReceipts66(ctx, inreq, sentry)
+	case proto_sentry.MessageId_GET_NODE_DATA_66:
+		return cs.getNodeData66(ctx, inreq, sentry)
 	default:
 		return fmt.Errorf("not implemented for message Id: %s", inreq.Id)
 	}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/cmd/state/stats"
+	"github.com/spf13/cobra"
+)
+
+var codeStatsTop int
+
+func init() {
+	withDataDir(codeStatsCmd)
+	codeStatsCmd.Flags().IntVar(&codeStatsTop, "top", 20, "print this many most shared codes")
+	rootCmd.AddCommand(codeStatsCmd)
+}
+
+var codeStatsCmd = &cobra.Command{
+	Use:   "codeStats",
+	Short: "How many contracts share each code, bytes saved by storing code once per hash, and unreferenced code",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db := mdbx.MustOpen(chaindata)
+		defer db.Close()
+		tx, err := db.BeginRo(context.Background())
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		s, err := stats.CodeStats(tx, codeStatsTop)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("contracts=%d codes=%d codeSize=%s withoutSharing=%s\n", s.References, s.Codes, datasize.ByteSize(s.CodeBytes).HR(), datasize.ByteSize(s.DuplicatedBytes).HR())
+		fmt.Printf("unreferenced codes=%d size=%s\n", s.Unreferenced, datasize.ByteSize(s.UnreferencedBytes).HR())
+		for _, c := range s.Top {
+			fmt.Printf("%x refs=%d size=%d\n", c.Hash, c.Refs, c.Size)
+		}
+		return nil
+	},
+}
@@ -37,6 +37,9 @@ var rootCmd = &cobra.Command{
 		genesis, chainConfig = getChainGenesisAndConfig()
 		if genesisPath != "" {
 			genesis = genesisFromFile(genesisPath)
+			if chain == "" && genesis.Config != nil { // private chain
+				chainConfig = genesis.Config
+			}
 		}
 		if genesis.Config != nil && genesis.Config.ChainID.Cmp(chainConfig.ChainID) != 0 {
 			utils.Fatalf("provided genesis.json chain configuration is invalid: expected chainId to be %v, got %v",
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ledgerwatch/erigon/consensus/difficultysim"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	simParentNumber     uint64
+	simParentTime       uint64
+	simParentDifficulty string
+	simBlocks           uint64
+	simSampleEvery      uint64
+	simHashrate         string
+	simSigners          int
+	simOnline           int
+	simSeed             int64
+)
+
+func init() {
+	withChain(simulateDifficultyCmd)
+	simulateDifficultyCmd.Flags().Uint64Var(&simParentNumber, "parent.number", 0, "number of the block to start after, genesis by default")
+	simulateDifficultyCmd.Flags().Uint64Var(&simParentTime, "parent.time", 0, "timestamp of the parent block, of genesis by default")
+	simulateDifficultyCmd.Flags().StringVar(&simParentDifficulty, "parent.difficulty", "", "difficulty of the parent block, of genesis by default")
+	simulateDifficultyCmd.Flags().Uint64Var(&simBlocks, "blocks", 100_000, "amount of blocks to simulate")
+	simulateDifficultyCmd.Flags().Uint64Var(&simSampleEvery, "every", 1000, "print every N-th block")
+	simulateDifficultyCmd.Flags().StringVar(&simHashrate, "hashrate", "", "ethash: network hashrate, hashes per second (1e12), or changes of it by block (0:1e12,500000:2e12)")
+	simulateDifficultyCmd.Flags().IntVar(&simSigners, "signers", 1, "clique: amount of authorized signers")
+	simulateDifficultyCmd.Flags().IntVar(&simOnline, "online", -1, "clique: amount of signers sealing blocks, all by default")
+	simulateDifficultyCmd.Flags().Int64Var(&simSeed, "seed", 0, "random block times with this seed, expected block times if 0")
+	rootCmd.AddCommand(simulateDifficultyCmd)
+}
+
+var simulateDifficultyCmd = &cobra.Command{
+	Use:   "simulateDifficulty",
+	Short: "Simulate difficulty and block time of the chain (--chain or --genesis) under given hashrate or online signers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p := difficultysim.Params{
+			ParentNumber:     simParentNumber,
+			ParentTime:       genesis.Timestamp,
+			ParentDifficulty: genesis.Difficulty,
+			Blocks:           simBlocks,
+			SampleEvery:      simSampleEvery,
+			Signers:          simSigners,
+			Online:           simOnline,
+			Seed:             simSeed,
+		}
+		if cmd.Flags().Changed("parent.time") {
+			p.ParentTime = simParentTime
+		}
+		if simParentDifficulty != "" {
+			var ok bool
+			if p.ParentDifficulty, ok = new(big.Int).SetString(simParentDifficulty, 0); !ok {
+				return fmt.Errorf("invalid --parent.difficulty: %s", simParentDifficulty)
+			}
+		}
+		if p.Online < 0 {
+			p.Online = p.Signers
+		}
+		var err error
+		if p.Hashrates, err = parseHashrates(simHashrate); err != nil {
+			return err
+		}
+
+		samples, err := difficultysim.Run(cmd.Context(), chainConfig, p)
+		for _, s := range samples {
+			fmt.Printf("block=%d time=%s difficulty=%d blockTime=%.2fs\n", s.Number, time.Unix(int64(s.Time), 0).UTC().Format(time.RFC3339), s.Difficulty, s.BlockTime)
+		}
+		return err
+	},
+}
+
+// parseHashrates parses "1e12" or "0:1e12,500000:2e12"
+func parseHashrates(s string) ([]ethash.HashrateChange, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var changes []ethash.HashrateChange
+	for _, part := range strings.Split(s, ",") {
+		var change ethash.HashrateChange
+		hashrate := part
+		if i := strings.IndexByte(part, ':'); i >= 0 {
+			from, err := strconv.ParseUint(part[:i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --hashrate %s: %w", s, err)
+			}
+			change.FromBlock, hashrate = from, part[i+1:]
+		}
+		var err error
+		if change.Hashrate, err = strconv.ParseFloat(hashrate, 64); err != nil {
+			return nil, fmt.Errorf("invalid --hashrate %s: %w", s, err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// CodeStat - how contract code is stored. Code is content-addressed: Code bucket has every code once, keyed by its
+// hash, and PlainContractCode maps address+incarnation to the hash, so clones of one contract share the code.
+type CodeStat struct {
+	References        uint64 // contracts: entries of PlainContractCode
+	Codes             uint64 // distinct codes: entries of Code
+	CodeBytes         uint64
+	DuplicatedBytes   uint64 // bytes contracts would take if each of them stored its own copy of the code
+	Unreferenced      uint64 // codes of no current contract: self-destructed, unwound, or historical ones
+	UnreferencedBytes uint64
+	Top               []CodeRefs // most shared codes
+}
+
+type CodeRefs struct {
+	Hash common.Hash
+	Refs uint64
+	Size int
+}
+
+// CodeStats counts references of every code hash. Unreferenced codes are still needed by reads of historical state,
+// which find code by the hash of the historical account.
+func CodeStats(tx kv.Tx, top int) (*CodeStat, error) {
+	var s CodeStat
+	refs := map[common.Hash]uint64{}
+	if err := tx.ForEach(kv.PlainContractCode, nil, func(k, v []byte) error {
+		s.References++
+		refs[common.BytesToHash(v)]++
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.ForEach(kv.Code, nil, func(k, v []byte) error {
+		s.Codes++
+		s.CodeBytes += uint64(len(v))
+		hash := common.BytesToHash(k)
+		n := refs[hash]
+		if n == 0 {
+			s.Unreferenced++
+			s.UnreferencedBytes += uint64(len(v))
+			return nil
+		}
+		s.DuplicatedBytes += n * uint64(len(v))
+		s.Top = append(s.Top, CodeRefs{Hash: hash, Refs: n, Size: len(v)})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(s.Top, func(i, j int) bool { return s.Top[i].Refs > s.Top[j].Refs })
+	if len(s.Top) > top {
+		s.Top = s.Top[:top]
+	}
+	return &s, nil
+}
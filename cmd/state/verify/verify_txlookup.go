@@ -1,16 +1,13 @@
 package verify
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"math/big"
 	"os"
 	"os/signal"
 	"time"
 
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
-	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/log/v3"
@@ -39,7 +36,6 @@ func ValidateTxLookups(chaindata string) error {
 	iterations := 0
 	var interrupt bool
 	// Validation Process
-	blockBytes := big.NewInt(0)
 	for !interrupt {
 		if err := libcommon.Stopped(quitCh); err != nil {
 			return err
@@ -54,21 +50,21 @@ func ValidateTxLookups(chaindata string) error {
 			log.Error("Empty body", "blocknum", blockNum)
 			break
 		}
-		blockBytes.SetUint64(blockNum)
-		bn := blockBytes.Bytes()
-
-		for _, txn := range body.Transactions {
-			val, err := tx.GetOne(kv.TxLookup, txn.Hash().Bytes())
+		for i, txn := range body.Transactions {
+			number, txIndex, hasIndex, err := rawdb.ReadTxLookupEntryWithIndex(tx, txn.Hash())
+			if err != nil {
+				panic(err)
+			}
 			iterations++
 			if iterations%100000 == 0 {
 				log.Info("Validated", "entries", iterations, "number", blockNum)
 
 			}
-			if !bytes.Equal(val, bn) {
-				if err != nil {
-					panic(err)
-				}
-				panic(fmt.Sprintf("Validation process failed(%d). Expected %b, got %b", iterations, bn, val))
+			if number == nil {
+				panic(fmt.Sprintf("Validation process failed(%d). Entry not found, expected %d/%d", iterations, blockNum, i))
+			}
+			if *number != blockNum || (hasIndex && txIndex != uint64(i)) {
+				panic(fmt.Sprintf("Validation process failed(%d). Expected %d/%d, got %d/%d", iterations, blockNum, i, *number, txIndex))
 			}
 		}
 		blockNum++
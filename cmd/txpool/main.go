@@ -47,6 +47,8 @@ var (
 	priceLimit   uint64
 	accountSlots uint64
 	priceBump    uint64
+
+	commitEvery time.Duration
 )
 
 func init() {
@@ -68,6 +70,7 @@ func init() {
 	rootCmd.PersistentFlags().Uint64Var(&priceLimit, "txpool.pricelimit", txpool.DefaultConfig.MinFeeCap, "Minimum gas price (fee cap) limit to enforce for acceptance into the pool")
 	rootCmd.PersistentFlags().Uint64Var(&accountSlots, "txpool.accountslots", txpool.DefaultConfig.AccountSlots, "Minimum number of executable transaction slots guaranteed per account")
 	rootCmd.PersistentFlags().Uint64Var(&priceBump, "txpool.pricebump", txpool.DefaultConfig.PriceBump, "Price bump percentage to replace an already existing transaction")
+	rootCmd.PersistentFlags().DurationVar(&commitEvery, utils.TxPoolCommitEveryFlag.Name, 30*time.Second, utils.TxPoolCommitEveryFlag.Usage)
 	rootCmd.Flags().StringSliceVar(&traceSenders, utils.TxPoolTraceSendersFlag.Name, []string{}, utils.TxPoolTraceSendersFlag.Usage)
 }
 
@@ -81,6 +84,9 @@ var rootCmd = &cobra.Command{
 		debug.Exit()
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if commitEvery <= 0 {
+			return fmt.Errorf("--%s must be positive, got %s", utils.TxPoolCommitEveryFlag.Name, commitEvery)
+		}
 		ctx := cmd.Context()
 		creds, err := grpcutil.TLS(TLSCACert, TLSCertfile, TLSKeyFile)
 		if err != nil {
@@ -117,7 +123,7 @@ var rootCmd = &cobra.Command{
 		dirs := datadir.New(datadirCli)
 
 		cfg.DBDir = dirs.TxPool
-		cfg.CommitEvery = 30 * time.Second
+		cfg.CommitEvery = commitEvery
 		cfg.PendingSubPoolLimit = pendingPoolLimit
 		cfg.BaseFeeSubPoolLimit = baseFeePoolLimit
 		cfg.QueuedSubPoolLimit = queuedPoolLimit
@@ -29,6 +29,7 @@ import (
 	"strings"
 	"text/tabwriter"
 	"text/template"
+	"time"
 
 	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/common/cmp"
@@ -160,6 +161,10 @@ var (
 		Name:  "ethash.dagslockmmap",
 		Usage: "Lock memory maps for recent ethash mining DAGs",
 	}
+	EthashSealVerifierFlag = cli.StringFlag{
+		Name:  "ethash.sealverifier",
+		Usage: "URL of a trusted remote service (GPU or worker pool) implementing ethash_hashimoto to offload PoW seal verification to. The service is trusted for seal validity, seals are rejected if it fails",
+	}
 	SnapshotFlag = cli.BoolTFlag{
 		Name:  "snapshots",
 		Usage: `Default: use snapshots "true" for BSC, Mainnet and Goerli. use snapshots "false" in all other cases`,
@@ -221,6 +226,11 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: ethconfig.Defaults.DeprecatedTxPool.Lifetime,
 	}
+	TxPoolCommitEveryFlag = cli.DurationFlag{
+		Name:  "txpool.commit.every",
+		Usage: "How often transactions of the pool are saved to its DB, the pool is restored from it on restart",
+		Value: ethconfig.Defaults.DeprecatedTxPool.CommitEvery,
+	}
 	TxPoolTraceSendersFlag = cli.StringFlag{
 		Name:  "txpool.trace.senders",
 		Usage: "Comma separared list of addresses, whoes transactions will traced in transaction pool with debug printing",
@@ -352,6 +362,26 @@ var (
 		Name:  "ws.compression",
 		Usage: "Enable compression over WebSocket",
 	}
+	WsMaxConnectionsFlag = cli.IntFlag{
+		Name:  "ws.max.connections",
+		Usage: "Max amount of concurrent WebSocket connections, new ones are rejected with 503 (0 - no limit)",
+		Value: 1000,
+	}
+	WsIdleTimeoutFlag = cli.DurationFlag{
+		Name:  "ws.idle.timeout",
+		Usage: "WebSocket connection without requests and subscriptions is closed after it (0 - never)",
+		Value: 10 * time.Minute,
+	}
+	WsMaxSubscriptionsFlag = cli.IntFlag{
+		Name:  "ws.max.subscriptions",
+		Usage: "Max amount of active subscriptions of one WebSocket connection (0 - no limit)",
+		Value: 256,
+	}
+	WsSendQueueFlag = cli.IntFlag{
+		Name:  "ws.send.queue",
+		Usage: "Max amount of notifications waiting to be sent to one WebSocket connection, slower clients are disconnected (0 - no limit, producers wait for the client)",
+		Value: 1024,
+	}
 	HTTPCORSDomainFlag = cli.StringFlag{
 		Name:  "http.corsdomain",
 		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
@@ -377,6 +407,40 @@ var (
 		Usage: "Does limit amount of goroutines to process 1 batch request. Means 1 bach request can't overload server. 1 batch still can have unlimited amount of request",
 		Value: 2,
 	}
+	RpcWorkersCheapFlag = cli.UintFlag{
+		Name:  "rpc.workers.cheap",
+		Usage: "Amount of requests to cheap methods (everything except traces and logs) served in parallel. 0 - no limit",
+		Value: 1024,
+	}
+	RpcWorkersTraceFlag = cli.UintFlag{
+		Name:  "rpc.workers.trace",
+		Usage: "Amount of trace_*, debug_trace* and ots_trace* requests served in parallel. 0 - no limit",
+		Value: uint(cmp.Max(4, runtime.GOMAXPROCS(-1))),
+	}
+	RpcWorkersLogsFlag = cli.UintFlag{
+		Name:  "rpc.workers.logs",
+		Usage: "Amount of eth_getLogs, eth_getFilterLogs and erigon_getLogs* requests served in parallel. 0 - no limit",
+		Value: uint(cmp.Max(4, runtime.GOMAXPROCS(-1))),
+	}
+	RpcWorkersQueueFlag = cli.UintFlag{
+		Name:  "rpc.workers.queue",
+		Usage: "Amount of requests of one class (cheap, trace, logs) waiting for a free worker. Requests above it are rejected",
+		Value: 4096,
+	}
+	RpcReadersFreelistFlag = cli.StringFlag{
+		Name:  "rpc.readers.freelist",
+		Usage: "Freelist size of chaindata (like 4GB) above which read transactions of rpc calls open longer than --rpc.readers.maxage are handled by --rpc.readers.action, they keep the freelist from being reused. Empty - disabled",
+	}
+	RpcReadersMaxAgeFlag = cli.DurationFlag{
+		Name:  "rpc.readers.maxage",
+		Usage: "Read transactions of rpc calls open longer than it are long, see --rpc.readers.freelist",
+		Value: time.Minute,
+	}
+	RpcReadersActionFlag = cli.StringFlag{
+		Name:  "rpc.readers.action",
+		Usage: "What to do with long read transactions while the freelist is above --rpc.readers.freelist: log - only report them, degrade - refuse new read transactions of their methods, kill - terminate them",
+		Value: "kill",
+	}
 	RpcStreamingDisableFlag = cli.BoolFlag{
 		Name:  "rpc.streaming.disable",
 		Usage: "Erigon has enalbed json streaming for some heavy endpoints (like trace_*). It's treadoff: greatly reduce amount of RAM (in some cases from 30GB to 30mb), but it produce invalid json format if error happened in the middle of streaming (because json is not streaming-friendly format)",
@@ -416,6 +480,109 @@ var (
 		Value: 200,
 	}
 
+	RpcLogsMaxRangeFlag = cli.Uint64Flag{
+		Name:  "rpc.logs.maxrange",
+		Usage: "Sets a limit on the block range of eth_getLogs and eth_getFilterLogs (0 - no limit)",
+		Value: 0,
+	}
+	RpcLogsMaxResultsFlag = cli.Uint64Flag{
+		Name:  "rpc.logs.maxresults",
+		Usage: "Sets a limit on the amount of logs returned by eth_getLogs and eth_getFilterLogs (0 - no limit)",
+		Value: 0,
+	}
+	RpcReceiptsRevertReasonFlag = cli.BoolFlag{
+		Name:  "rpc.receipts.revertreason",
+		Usage: "Add the decoded revert reason of failed transactions to eth_getTransactionReceipt (replays the transaction, omitted if it can't be replayed)",
+	}
+	RpcNonCanonicalTxsFlag = cli.Uint64Flag{
+		Name:  "rpc.noncanonical.txs",
+		Usage: "Number of recent blocks in which eth_getTransactionByHash and eth_getTransactionReceipt look for unknown transactions in non-canonical blocks, to report the reorg instead of null (0 - disabled)",
+		Value: 0,
+	}
+	TxPoolScheduledLimitFlag = cli.IntFlag{
+		Name:  "txpool.scheduled.limit",
+		Usage: "Max amount of scheduled transactions (min block/timestamp envelope of eth_sendRawTransaction) held until they become valid, 0 - reject them",
+		Value: 0,
+	}
+	TxPoolScheduledSenderLimitFlag = cli.IntFlag{
+		Name:  "txpool.scheduled.senderlimit",
+		Usage: "Max amount of scheduled transactions of one sender",
+		Value: 16,
+	}
+	TxPoolScheduledMaxBlocksFlag = cli.Uint64Flag{
+		Name:  "txpool.scheduled.maxblocks",
+		Usage: "Max distance of min block of scheduled transactions from the head",
+		Value: 50_000,
+	}
+	TxPoolScheduledMaxTimeFlag = cli.DurationFlag{
+		Name:  "txpool.scheduled.maxtime",
+		Usage: "Max distance of min timestamp of scheduled transactions from the head timestamp",
+		Value: 7 * 24 * time.Hour,
+	}
+
+	OtsLabelsPathFlag = cli.StringFlag{
+		Name:  "ots.labels.path",
+		Usage: "Path to the DB of address labels served by ots_getAddressMetadata and managed by otsadmin_* methods (empty - disabled)",
+	}
+
+	OtsCreatorsPathFlag = cli.StringFlag{
+		Name:  "ots.creators.path",
+		Usage: "Path to the DB indexing contract creators found by ots_getContractCreator, so they are found once (empty - disabled)",
+	}
+
+	OtsSourcifySourceFlag = cli.StringFlag{
+		Name:  "ots.sourcify.source",
+		Usage: "Directory with the layout of the Sourcify repository or its URL (e.g. https://repo.sourcify.dev), verified contracts served by ots_getContractMetadata (empty - disabled)",
+	}
+
+	OtsSourcifyCachePathFlag = cli.StringFlag{
+		Name:  "ots.sourcify.cache",
+		Usage: "Path to the DB caching contract metadata of --ots.sourcify.source, so it's fetched once (empty - not cached)",
+	}
+
+	OtsSignaturesPathFlag = cli.StringFlag{
+		Name:  "ots.signatures.path",
+		Usage: "Text file of function signatures (e.g. a 4byte.directory dump), one per line, ots_decodeCalldata decodes calldata of unverified contracts with them (empty - only verified contracts are decoded)",
+	}
+
+	OtsSearchWorkersFlag = cli.IntFlag{
+		Name:  "ots.search.workers",
+		Usage: "Max number of blocks traced at once by ots_searchTransactionsBefore/After of all requests (0 - estimated from CPUs and memory)",
+		Value: 0,
+	}
+
+	OtsSearchCacheFlag = cli.IntFlag{
+		Name:  "ots.search.cache",
+		Usage: "Number of (address, block) results of ots_searchTransactionsBefore/After and ots_getAddressActivity kept in memory, so paging doesn't trace blocks again (0 - disabled)",
+		Value: 65536,
+	}
+
+	OtsOperationsPathFlag = cli.StringFlag{
+		Name:  "ots.operations.path",
+		Usage: "Path to the DB of internal operations traced in background before the state history of blocks is pruned, ots_getInternalOperations serves transactions of pruned blocks from it (empty - disabled)",
+	}
+
+	RpcTraceExportDirFlag = cli.StringFlag{
+		Name:  "rpc.trace.export.dir",
+		Usage: "Directory where debug_traceBlockByNumberToFile/ByHashToFile write block traces instead of returning them (empty - disabled)",
+	}
+
+	RpcTraceExportURLFlag = cli.StringFlag{
+		Name:  "rpc.trace.export.url",
+		Usage: "Base URL under which --rpc.trace.export.dir is served (by a file server, or a bucket synced with it), returned with names of trace files",
+	}
+
+	RpcTraceExportRetentionFlag = cli.DurationFlag{
+		Name:  "rpc.trace.export.retention",
+		Usage: "Trace files older than this are deleted from --rpc.trace.export.dir (0 - kept forever)",
+		Value: 24 * time.Hour,
+	}
+	RpcTraceBlockWorkersFlag = cli.IntFlag{
+		Name:  "rpc.trace.block.workers",
+		Usage: "Max number of transactions of a block traced at once by debug_traceBlockBy* (1 - one by one, 0 - estimated from CPUs and memory)",
+		Value: 1,
+	}
+
 	HTTPPathPrefixFlag = cli.StringFlag{
 		Name:  "http.rpcprefix",
 		Usage: "HTTP path path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
@@ -512,6 +679,24 @@ var (
 		Name:  "sentry.api.addr",
 		Usage: "comma separated sentry addresses '<host>:<port>,<host>:<port>'",
 	}
+	P2pTransportFlag = cli.StringFlag{
+		Name:  "p2p.transport",
+		Usage: "Wire transport for peer connections: rlpx (default) or tls (experimental, both ends must use it; intended for links between nodes of one operator)",
+		Value: p2p.TransportRLPx,
+	}
+	P2pPeerExchangeFlag = cli.BoolFlag{
+		Name:  "p2p.pex",
+		Usage: "Exchange known peers with --trustedpeers on connect (both ends must enable it), speeds up forming of private networks without discovery",
+	}
+	P2pMaxPeersPerSubnetFlag = cli.IntFlag{
+		Name:  "p2p.subnet.maxpeers",
+		Usage: "Maximum number of discovered peers from one /24 (IPv4) or /48 (IPv6) network, keeps peers of many operators and locations. Static and trusted peers are not limited. 0 - no limit",
+	}
+	P2pPeerSelectionFlag = cli.StringFlag{
+		Name:  "p2p.peer.selection",
+		Usage: "Which peer gets block bodies requests: permits (the least busy one) or latency (a random free one of the fastest responders)",
+		Value: p2p.PeerSelectionPermits,
+	}
 	SentryLogPeerInfoFlag = cli.BoolFlag{
 		Name:  "sentry.log-peer-info",
 		Usage: "Log detailed peer info when a peer connects or disconnects. Enable to integrate with observer.",
@@ -704,6 +889,35 @@ var (
 		Usage: "set mdbx pagesize on db creation: must be power of 2 and '256b <= pagesize <= 64kb'. default: equal to OperationSystem's pageSize",
 		Value: datasize.ByteSize(kv.DefaultPageSize()).String(),
 	}
+	DbSizeLimitFlag = cli.StringFlag{
+		Name:  "db.size.limit",
+		Usage: "upper limit of the chaindata map size. must be a multiple of --db.pagesize",
+		Value: (8 * datasize.TB).String(),
+	}
+	DbGrowthStepFlag = cli.StringFlag{
+		Name:  "db.growth.step",
+		Usage: "step by which the chaindata file grows. must be a multiple of --db.pagesize and not bigger than --db.size.limit",
+		Value: (2 * datasize.GB).String(),
+	}
+	DbSyncModeFlag = cli.StringFlag{
+		Name:  "db.sync",
+		Usage: "chaindata durability mode: durable (fsync on every commit), safe-nosync (a crash may lose recent commits, but db stays consistent), utterly-nosync (a crash of the OS may corrupt db)",
+		Value: nodecfg.MdbxSyncDurable,
+	}
+	DbSyncPeriodFlag = cli.DurationFlag{
+		Name:  "db.sync.period",
+		Usage: "interval of background fsync in the nosync modes of --db.sync, 0 disables it",
+		Value: 0,
+	}
+	DbReadaheadFlag = cli.BoolFlag{
+		Name:  "db.readahead",
+		Usage: "enable OS readahead for chaindata. helps when the db fits in RAM, hurts otherwise",
+	}
+	DbDirtyPagesLimitFlag = cli.Uint64Flag{
+		Name:  "db.dirty.pages.limit",
+		Usage: "limit of dirty pages in a write transaction before they spill to disk, 0 keeps the default (RAM/21)",
+		Value: 0,
+	}
 
 	HealthCheckFlag = cli.BoolFlag{
 		Name:  "healthcheck",
@@ -932,6 +1146,9 @@ func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(SentryAddrFlag.Name) {
 		cfg.SentryAddr = SplitAndTrim(ctx.GlobalString(SentryAddrFlag.Name))
 	}
+	if ctx.GlobalIsSet(P2pTransportFlag.Name) {
+		cfg.Transport = ctx.GlobalString(P2pTransportFlag.Name)
+	}
 }
 
 // setNAT creates a port mapper from command line flags.
@@ -1028,6 +1245,14 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config, nodeName, datadir string) {
 	if ctx.GlobalIsSet(DiscoveryV5Flag.Name) {
 		cfg.DiscoveryV5 = ctx.GlobalBool(DiscoveryV5Flag.Name)
 	}
+	cfg.PeerExchange = ctx.GlobalBool(P2pPeerExchangeFlag.Name)
+	cfg.MaxPeersPerSubnet = ctx.GlobalInt(P2pMaxPeersPerSubnetFlag.Name)
+	if ctx.GlobalIsSet(P2pPeerSelectionFlag.Name) {
+		cfg.PeerSelection = ctx.GlobalString(P2pPeerSelectionFlag.Name)
+		if cfg.PeerSelection != p2p.PeerSelectionPermits && cfg.PeerSelection != p2p.PeerSelectionLatency {
+			Fatalf("invalid --%s: %s", P2pPeerSelectionFlag.Name, P2pPeerSelectionFlag.Usage)
+		}
+	}
 
 	ethPeers := cfg.MaxPeers
 	cfg.Name = nodeName
@@ -1135,6 +1360,39 @@ func setDataDir(ctx *cli.Context, cfg *nodecfg.Config) {
 	if !isPowerOfTwo(sz) || sz < 256 || sz > 64*1024 {
 		panic("invalid --db.pagesize: " + DbPageSizeFlag.Usage)
 	}
+	setMdbxConfig(ctx, cfg)
+}
+
+func setMdbxConfig(ctx *cli.Context, cfg *nodecfg.Config) {
+	pageSize := cfg.MdbxPageSize.Bytes()
+	if err := cfg.MdbxDBSizeLimit.UnmarshalText([]byte(ctx.GlobalString(DbSizeLimitFlag.Name))); err != nil {
+		Fatalf("invalid --%s: %v", DbSizeLimitFlag.Name, err)
+	}
+	if sz := cfg.MdbxDBSizeLimit.Bytes(); sz == 0 || (pageSize > 0 && sz%pageSize != 0) {
+		Fatalf("invalid --%s: %s", DbSizeLimitFlag.Name, DbSizeLimitFlag.Usage)
+	}
+	if err := cfg.MdbxGrowthStep.UnmarshalText([]byte(ctx.GlobalString(DbGrowthStepFlag.Name))); err != nil {
+		Fatalf("invalid --%s: %v", DbGrowthStepFlag.Name, err)
+	}
+	if sz := cfg.MdbxGrowthStep.Bytes(); sz == 0 || sz > cfg.MdbxDBSizeLimit.Bytes() || (pageSize > 0 && sz%pageSize != 0) {
+		Fatalf("invalid --%s: %s", DbGrowthStepFlag.Name, DbGrowthStepFlag.Usage)
+	}
+
+	cfg.MdbxSyncMode = ctx.GlobalString(DbSyncModeFlag.Name)
+	switch cfg.MdbxSyncMode {
+	case nodecfg.MdbxSyncDurable, nodecfg.MdbxSyncSafeNoSync, nodecfg.MdbxSyncUtterlyNoSync:
+	default:
+		Fatalf("invalid --%s=%s: %s", DbSyncModeFlag.Name, cfg.MdbxSyncMode, DbSyncModeFlag.Usage)
+	}
+	cfg.MdbxSyncPeriod = ctx.GlobalDuration(DbSyncPeriodFlag.Name)
+	if cfg.MdbxSyncPeriod < 0 {
+		Fatalf("invalid --%s: must not be negative", DbSyncPeriodFlag.Name)
+	}
+	if cfg.MdbxSyncPeriod > 0 && cfg.MdbxSyncMode == nodecfg.MdbxSyncDurable {
+		log.Warn("--" + DbSyncPeriodFlag.Name + " has no effect in durable mode")
+	}
+	cfg.MdbxReadahead = ctx.GlobalBool(DbReadaheadFlag.Name)
+	cfg.MdbxDirtyPagesLimit = ctx.GlobalUint64(DbDirtyPagesLimitFlag.Name)
 }
 
 func isPowerOfTwo(n uint64) bool {
@@ -1229,6 +1487,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolCommitEveryFlag.Name) {
+		cfg.CommitEvery = ctx.GlobalDuration(TxPoolCommitEveryFlag.Name)
+		if cfg.CommitEvery <= 0 {
+			Fatalf("Option %s: must be positive, got %s", TxPoolCommitEveryFlag.Name, cfg.CommitEvery)
+		}
+	}
 	if ctx.GlobalIsSet(TxPoolTraceSendersFlag.Name) {
 		// Parse the command separated flag
 		senderHexes := SplitAndTrim(ctx.GlobalString(TxPoolTraceSendersFlag.Name))
@@ -1258,6 +1522,9 @@ func setEthash(ctx *cli.Context, datadir string, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(EthashDatasetsLockMmapFlag.Name) {
 		cfg.Ethash.DatasetsLockMmap = ctx.GlobalBool(EthashDatasetsLockMmapFlag.Name)
 	}
+	if ctx.GlobalIsSet(EthashSealVerifierFlag.Name) {
+		cfg.Ethash.SealVerifierURL = ctx.GlobalString(EthashSealVerifierFlag.Name)
+	}
 }
 
 func SetupMinerCobra(cmd *cobra.Command, cfg *params.MiningConfig) {
@@ -0,0 +1,78 @@
+// Package diskspace checks that disk-heavy operations (initial state hashing, trie generation,
+// snapshot compression) have enough space for their temporary files and results before they start,
+// instead of failing in the middle with ENOSPC or MDBX_MAP_FULL.
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/c2h5oh/datasize"
+)
+
+var ErrNotEnoughSpace = errors.New("not enough disk space")
+
+// Requirement - estimated amount of bytes which will be written into Dir
+type Requirement struct {
+	Dir     string
+	Bytes   uint64
+	Purpose string // for the error message, for example "etl temp files"
+}
+
+// freeSpace is replaced in tests
+var freeSpace = Free
+
+// Check fails with ErrNotEnoughSpace if some volume doesn't have space for all requirements on it:
+// requirements of dirs on the same volume are summed up.
+func Check(logPrefix string, requirements ...Requirement) error {
+	type volumeUsage struct {
+		dir      string
+		free     uint64
+		need     uint64
+		purposes []string
+	}
+	var volumes []*volumeUsage
+	byID := map[string]*volumeUsage{}
+	for _, r := range requirements {
+		if r.Bytes == 0 {
+			continue
+		}
+		free, volume, err := freeSpace(existingParent(r.Dir))
+		if err != nil {
+			return fmt.Errorf("[%s] free disk space of %s: %w", logPrefix, r.Dir, err)
+		}
+		v, ok := byID[volume]
+		if !ok {
+			v = &volumeUsage{dir: r.Dir, free: free}
+			byID[volume] = v
+			volumes = append(volumes, v)
+		}
+		v.need += r.Bytes
+		v.purposes = append(v.purposes, fmt.Sprintf("%s %s", r.Purpose, datasize.ByteSize(r.Bytes).HR()))
+	}
+	for _, v := range volumes {
+		if v.need > v.free {
+			return fmt.Errorf("[%s] %w on the volume of %s: need %s (%s), available %s",
+				logPrefix, ErrNotEnoughSpace, v.dir, datasize.ByteSize(v.need).HR(), strings.Join(v.purposes, ", "), datasize.ByteSize(v.free).HR())
+		}
+	}
+	return nil
+}
+
+// existingParent - dirs like tmp are created on demand, space is checked on the volume they will be created on
+func existingParent(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,19 @@
+package diskspace
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// Free returns space available to unprivileged user on the volume of dir, and id of the volume
+func Free(dir string) (free uint64, volume string, err error) {
+	var stat unix.Statfs_t
+	if err = unix.Statfs(dir, &stat); err != nil {
+		return 0, "", fmt.Errorf("statfs: %w", err)
+	}
+	if stat.F_bavail < 0 { // reserved blocks are used by root
+		return 0, fmt.Sprint(stat.F_fsid), nil
+	}
+	return uint64(stat.F_bavail) * uint64(stat.F_bsize), fmt.Sprint(stat.F_fsid), nil
+}
@@ -0,0 +1,38 @@
+package diskspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFree(t *testing.T) {
+	free, volume, err := Free(t.TempDir())
+	require.NoError(t, err)
+	require.NotZero(t, free)
+	require.NotEmpty(t, volume)
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	volumes := map[string]string{dir: "a", filepath.Join(dir, "b"): "b"}
+	freeSpace = func(dir string) (uint64, string, error) {
+		volume, ok := volumes[dir]
+		if !ok {
+			return 0, "", errors.New("unexpected dir " + dir)
+		}
+		return 100, volume, nil
+	}
+	defer func() { freeSpace = Free }()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "b"), 0o755))
+
+	// not existing dir is checked on the volume of its parent, requirements on one volume are summed
+	require.NoError(t, Check("test", Requirement{Dir: filepath.Join(dir, "tmp"), Bytes: 60}, Requirement{Dir: filepath.Join(dir, "b"), Bytes: 100}))
+	err := Check("test", Requirement{Dir: filepath.Join(dir, "tmp", "etl"), Bytes: 60, Purpose: "etl"}, Requirement{Dir: dir, Bytes: 41, Purpose: "db"})
+	require.ErrorIs(t, err, ErrNotEnoughSpace)
+	require.Contains(t, err.Error(), "need 101 B (etl 60 B, db 41 B), available 100 B")
+	require.ErrorIs(t, Check("test", Requirement{Dir: filepath.Join(dir, "b"), Bytes: 101}), ErrNotEnoughSpace)
+}
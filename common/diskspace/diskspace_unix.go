@@ -0,0 +1,18 @@
+//go:build !windows && !openbsd
+
+package diskspace
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// Free returns space available to unprivileged user on the volume of dir, and id of the volume
+func Free(dir string) (free uint64, volume string, err error) {
+	var stat unix.Statfs_t
+	if err = unix.Statfs(dir, &stat); err != nil {
+		return 0, "", fmt.Errorf("statfs: %w", err)
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), fmt.Sprint(stat.Fsid), nil
+}
@@ -0,0 +1,26 @@
+package diskspace
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// Free returns space available to the user on the volume of dir, and id of the volume
+func Free(dir string) (free uint64, volume string, err error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, "", err
+	}
+	path, err := windows.UTF16PtrFromString(abs)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to call UTF16PtrFromString: %w", err)
+	}
+	var total, totalFree uint64
+	if err = windows.GetDiskFreeSpaceEx(path, &free, &total, &totalFree); err != nil {
+		return 0, "", fmt.Errorf("failed to call GetDiskFreeSpaceEx: %w", err)
+	}
+	return free, strings.ToLower(filepath.VolumeName(abs)), nil
+}
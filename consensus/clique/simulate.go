@@ -0,0 +1,90 @@
+package clique
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// Simulate block time and difficulty of a clique chain for `blocks` blocks after the parent, with first `online` of
+// `signers` sealing. In-turn signer seals after the period. If it's offline or signed recently, an out-of-turn
+// signer seals after the period and a random wiggle - minimum of wiggles of eligible signers, expected value of it
+// if rnd == nil. A signer may seal one of signers/2+1 consecutive blocks, the chain halts if too few are online.
+// Every sampleEvery-th block and the last one are returned, the samples so far if ctx is cancelled.
+func Simulate(ctx context.Context, config *params.CliqueConfig, parentNumber, parentTime uint64, blocks, sampleEvery uint64, signers, online int, rnd *rand.Rand) ([]consensus.DifficultySample, error) {
+	if signers <= 0 || online < 0 || online > signers {
+		return nil, fmt.Errorf("invalid amount of signers: %d online of %d", online, signers)
+	}
+	if config.Period == 0 {
+		return nil, errors.New("period is 0: blocks are sealed on demand only")
+	}
+	if sampleEvery == 0 {
+		sampleEvery = 1
+	}
+	addresses := make([]common.Address, signers) // ascending - index of a signer is its turn
+	snap := &Snapshot{config: config, Signers: map[common.Address]struct{}{}, Recents: map[uint64]common.Address{}}
+	for i := range addresses {
+		addresses[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+		snap.Signers[addresses[i]] = struct{}{}
+	}
+	limit := uint64(signers/2 + 1)
+	wiggle := float64(time.Duration(signers/2+1)*wiggleTime) / float64(time.Second)
+
+	samples := make([]consensus.DifficultySample, 0, blocks/sampleEvery+1)
+	clock := float64(parentTime) // Time when the parent was sealed
+	var elapsed float64          // Block times since the last sample
+	var count uint64             // Blocks since the last sample
+	for i := uint64(1); i <= blocks; i++ {
+		if err := ctx.Err(); err != nil {
+			return samples, err
+		}
+		snap.Number = parentNumber + i - 1
+		number := snap.Number + 1
+		if number >= limit {
+			delete(snap.Recents, number-limit) // may seal again
+		}
+		recent := make(map[common.Address]bool, len(snap.Recents))
+		for _, signer := range snap.Recents {
+			recent[signer] = true
+		}
+		var eligible []common.Address
+		turn := int(number % uint64(signers))
+		for _, signer := range addresses[:online] {
+			if !recent[signer] {
+				eligible = append(eligible, signer)
+			}
+		}
+		if len(eligible) == 0 {
+			return samples, fmt.Errorf("chain halts at block %d: %d of %d signers are online, %d are needed", number, online, signers, limit)
+		}
+		signer, delay := eligible[0], wiggle/float64(len(eligible)+1)
+		if turn < online && !recent[addresses[turn]] {
+			signer, delay = addresses[turn], 0
+		} else if rnd != nil {
+			delay = wiggle
+			for _, candidate := range eligible {
+				if d := rnd.Float64() * wiggle; d < delay {
+					signer, delay = candidate, d
+				}
+			}
+		}
+		difficulty := calcDifficulty(snap, signer)
+		snap.Recents[number] = signer
+
+		blockTime := float64(config.Period) + delay
+		clock += blockTime
+		elapsed, count = elapsed+blockTime, count+1
+		if i%sampleEvery == 0 || i == blocks {
+			samples = append(samples, consensus.DifficultySample{Number: number, Time: uint64(clock), Difficulty: difficulty, BlockTime: elapsed / float64(count)})
+			elapsed, count = 0, 0
+		}
+	}
+	return samples, nil
+}
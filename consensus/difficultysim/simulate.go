@@ -0,0 +1,51 @@
+// Package difficultysim simulates difficulty and block time of a chain with the difficulty calculators of its
+// consensus engine, to tune difficulty bomb delays and block periods of private networks
+package difficultysim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/clique"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// Params of a simulation, fields of other consensus engines are ignored
+type Params struct {
+	ParentNumber     uint64
+	ParentTime       uint64
+	ParentDifficulty *big.Int
+	Blocks           uint64
+	SampleEvery      uint64 // Every SampleEvery-th block is returned, and the last one
+
+	Hashrates []ethash.HashrateChange // Ethash: network hashrate, hashes per second
+
+	Signers int // Clique: amount of authorized signers
+	Online  int // Clique: amount of signers sealing blocks
+
+	Seed int64 // Random block times with this seed if not 0, expected block times otherwise
+}
+
+// Run simulates the chain with config after the parent block, until ctx is cancelled
+func Run(ctx context.Context, config *params.ChainConfig, p Params) ([]consensus.DifficultySample, error) {
+	var rnd *rand.Rand
+	if p.Seed != 0 {
+		rnd = rand.New(rand.NewSource(p.Seed)) // nolint: gosec
+	}
+	switch {
+	case config.Clique != nil:
+		return clique.Simulate(ctx, config.Clique, p.ParentNumber, p.ParentTime, p.Blocks, p.SampleEvery, p.Signers, p.Online, rnd)
+	case config.Aura != nil, config.Parlia != nil, config.Bor != nil:
+		return nil, fmt.Errorf("simulation of %s consensus is not supported", config.Consensus)
+	default:
+		if p.ParentDifficulty == nil || p.ParentDifficulty.Sign() <= 0 {
+			return nil, errors.New("difficulty of the parent block must be positive")
+		}
+		return ethash.Simulate(ctx, config, p.ParentNumber, p.ParentTime, p.ParentDifficulty, p.Blocks, p.SampleEvery, p.Hashrates, rnd)
+	}
+}
@@ -0,0 +1,94 @@
+package difficultysim
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateEthash(t *testing.T) {
+	require := require.New(t)
+	p := Params{
+		ParentNumber:     100,
+		ParentTime:       1_000_000,
+		ParentDifficulty: big.NewInt(1_000_000_000),
+		Blocks:           5000,
+		SampleEvery:      1000,
+		Hashrates:        []ethash.HashrateChange{{FromBlock: 0, Hashrate: 1_000_000}},
+	}
+	samples, err := Run(context.Background(), params.AllEthashProtocolChanges, p)
+	require.NoError(err)
+	require.Len(samples, 5)
+	require.Equal(uint64(5100), samples[4].Number)
+	require.Greater(samples[0].BlockTime, samples[4].BlockTime) // difficulty goes down to the hashrate
+	// Byzantium difficulty stays the same if block time is 9-18 seconds
+	require.GreaterOrEqual(samples[4].BlockTime, 8.0)
+	require.Less(samples[4].BlockTime, 18.0)
+	require.Greater(samples[4].Time, samples[3].Time)
+
+	// difficulty goes up after the hashrate, until blocks take 9 seconds
+	p.Hashrates = append(p.Hashrates, ethash.HashrateChange{FromBlock: 3101, Hashrate: 4_000_000})
+	faster, err := Run(context.Background(), params.AllEthashProtocolChanges, p)
+	require.NoError(err)
+	require.Equal(samples[1], faster[1])
+	require.Equal(1, faster[4].Difficulty.Cmp(samples[4].Difficulty))
+	require.Less(faster[4].BlockTime, 9.0)
+
+	// random block times are reproducible with the seed
+	p.Seed = 42
+	random, err := Run(context.Background(), params.AllEthashProtocolChanges, p)
+	require.NoError(err)
+	again, err := Run(context.Background(), params.AllEthashProtocolChanges, p)
+	require.NoError(err)
+	require.Equal(random, again)
+	require.NotEqual(faster, random)
+
+	p.Hashrates = nil
+	_, err = Run(context.Background(), params.AllEthashProtocolChanges, p)
+	require.Error(err)
+}
+
+func TestSimulateClique(t *testing.T) {
+	require := require.New(t)
+	config := &params.ChainConfig{ChainID: big.NewInt(1337), Clique: &params.CliqueConfig{Period: 15, Epoch: 30000}}
+	p := Params{ParentNumber: 0, ParentTime: 1000, Blocks: 30, SampleEvery: 3, Signers: 3, Online: 3}
+
+	samples, err := Run(context.Background(), config, p)
+	require.NoError(err)
+	require.Len(samples, 10)
+	for _, s := range samples {
+		require.Equal(15.0, s.BlockTime)
+		require.Equal(int64(2), s.Difficulty.Int64()) // in-turn
+	}
+	require.Equal(uint64(1000+30*15), samples[9].Time)
+
+	// one of three blocks is sealed out of turn, after a wiggle
+	p.Online = 2
+	samples, err = Run(context.Background(), config, p)
+	require.NoError(err)
+	for _, s := range samples {
+		require.Greater(s.BlockTime, 15.0)
+		require.Less(s.BlockTime, 15.5)
+	}
+
+	// a signer can't seal two blocks in a row
+	p.Online = 1
+	_, err = Run(context.Background(), config, p)
+	require.Error(err)
+}
+
+func TestSimulateCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := Params{ParentDifficulty: big.NewInt(1_000_000), Blocks: 1000, Hashrates: []ethash.HashrateChange{{Hashrate: 1000}}}
+	_, err := Run(ctx, params.AllEthashProtocolChanges, p)
+	require.ErrorIs(t, err, context.Canceled)
+
+	config := &params.ChainConfig{ChainID: big.NewInt(1337), Clique: &params.CliqueConfig{Period: 15, Epoch: 30000}}
+	_, err = Run(ctx, config, Params{Blocks: 1000, Signers: 1, Online: 1})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -501,8 +501,15 @@ func (ethash *Ethash) verifySeal(header *types.Header, fulldag bool) error { //n
 			fulldag = false
 		}
 	}
-	// If slow-but-light PoW verification was requested (or DAG not yet ready), use an ethash cache
+	// Without the DAG at hand, offload verification to the external verifier if there is one
 	if !fulldag {
+		var err error
+		if digest, result, err = ethash.remoteHashimoto(number, ethash.SealHash(header), header.Nonce.Uint64()); err != nil {
+			return err
+		}
+	}
+	// If slow-but-light PoW verification was requested (or DAG not yet ready), use an ethash cache
+	if !fulldag && digest == nil {
 		cache := ethash.cache(number)
 
 		size := datasetSize(number)
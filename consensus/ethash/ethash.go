@@ -424,6 +424,10 @@ type Config struct {
 	// be block header JSON objects instead of work package arrays.
 	NotifyFull bool
 
+	// When set, light seal verification is offloaded to it, with fallback to local CPU.
+	SealVerifier    SealVerifier `toml:"-"`
+	SealVerifierURL string       // remote ethash_hashimoto service, used if SealVerifier is not set
+
 	Log log.Logger `toml:"-"`
 }
 
@@ -461,6 +465,15 @@ func New(config Config, notify []string, noverify bool) *Ethash {
 	if config.DatasetDir != "" && config.DatasetsOnDisk > 0 {
 		config.Log.Info("Disk storage enabled for ethash DAGs", "dir", config.DatasetDir, "count", config.DatasetsOnDisk)
 	}
+	if config.SealVerifier == nil && config.SealVerifierURL != "" {
+		if verifier, err := NewRemoteSealVerifier(config.SealVerifierURL, sealVerifierTimeout); err != nil {
+			config.Log.Error("Remote seal verifier unavailable, seals are rejected", "err", err)
+			config.SealVerifier = unavailableSealVerifier{err: err}
+		} else {
+			config.Log.Info("Seal verification offloaded to remote service", "url", config.SealVerifierURL)
+			config.SealVerifier = verifier
+		}
+	}
 	ethash := &Ethash{
 		config:   config,
 		caches:   newlru("cache", config.CachesInMem, newCache),
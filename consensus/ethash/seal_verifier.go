@@ -0,0 +1,106 @@
+package ethash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// sealVerifierTimeout bounds one remote verification
+const sealVerifierTimeout = 5 * time.Second
+
+var (
+	sealVerifierCalls    = metrics.GetOrCreateCounter("ethash_seal_verifier_calls")
+	sealVerifierFailures = metrics.GetOrCreateCounter("ethash_seal_verifier_failures")
+)
+
+var errSealVerifierFailed = errors.New("seal verifier failed")
+
+// SealVerifier computes the ethash proof-of-work of a header seal outside of the engine:
+// on a GPU or by a pool of remote workers. The verifier is trusted for seal validity: the
+// engine compares the returned values with the header, but doesn't recompute them, so a
+// verifier returning the mix digest of the header and a low result makes any seal valid.
+// Only services under the control of the node operator must be used.
+// Verification fails closed: if the verifier returns an error, the seal is rejected and
+// not verified locally.
+type SealVerifier interface {
+	// Hashimoto returns the mix digest and the PoW result for the given seal hash and nonce
+	// of the block with the given number.
+	Hashimoto(ctx context.Context, number uint64, sealHash common.Hash, nonce uint64) (digest, result []byte, err error)
+}
+
+// HashimotoResult is the reply of the ethash_hashimoto remote method.
+type HashimotoResult struct {
+	MixDigest hexutil.Bytes `json:"mixDigest"`
+	Result    hexutil.Bytes `json:"result"`
+}
+
+// RemoteSealVerifier delegates seal verification to a remote JSON-RPC service implementing
+// ethash_hashimoto(number, sealHash, nonce). The service is trusted for seal validity, and any
+// transport error, timeout or malformed reply fails the verification of the seal.
+type RemoteSealVerifier struct {
+	client  *rpc.Client
+	timeout time.Duration
+}
+
+// NewRemoteSealVerifier connects to the remote seal verification service. For HTTP endpoints the
+// connection is lazy: unreachable service doesn't prevent startup, but seals are rejected until it's back.
+func NewRemoteSealVerifier(url string, timeout time.Duration) (*RemoteSealVerifier, error) {
+	client, err := rpc.DialContext(context.Background(), url)
+	if err != nil {
+		return nil, fmt.Errorf("seal verifier %s: %w", url, err)
+	}
+	return &RemoteSealVerifier{client: client, timeout: timeout}, nil
+}
+
+func (v *RemoteSealVerifier) Hashimoto(ctx context.Context, number uint64, sealHash common.Hash, nonce uint64) ([]byte, []byte, error) {
+	if v.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.timeout)
+		defer cancel()
+	}
+	var res HashimotoResult
+	if err := v.client.CallContext(ctx, &res, "ethash_hashimoto", hexutil.Uint64(number), sealHash, hexutil.Uint64(nonce)); err != nil {
+		return nil, nil, err
+	}
+	if len(res.MixDigest) != common.HashLength || len(res.Result) != common.HashLength {
+		return nil, nil, fmt.Errorf("malformed ethash_hashimoto reply: digest %d bytes, result %d bytes", len(res.MixDigest), len(res.Result))
+	}
+	return res.MixDigest, res.Result, nil
+}
+
+func (v *RemoteSealVerifier) Close() {
+	v.client.Close()
+}
+
+// unavailableSealVerifier replaces the remote verifier which couldn't be connected to at startup:
+// seals are rejected rather than verified locally, as with any other failure of the verifier.
+type unavailableSealVerifier struct{ err error }
+
+func (v unavailableSealVerifier) Hashimoto(context.Context, uint64, common.Hash, uint64) ([]byte, []byte, error) {
+	return nil, nil, v.err
+}
+
+// remoteHashimoto asks the configured SealVerifier for PoW values. Returns nil values if there
+// is no verifier - then the caller must compute them locally. An error of the verifier fails
+// the verification of the seal.
+func (ethash *Ethash) remoteHashimoto(number uint64, sealHash common.Hash, nonce uint64) (digest, result []byte, err error) {
+	verifier := ethash.config.SealVerifier
+	if verifier == nil {
+		return nil, nil, nil
+	}
+	sealVerifierCalls.Inc()
+	if digest, result, err = verifier.Hashimoto(context.Background(), number, sealHash, nonce); err != nil {
+		sealVerifierFailures.Inc()
+		ethash.config.Log.Warn("Seal verifier failed, seal rejected", "number", number, "err", err)
+		return nil, nil, fmt.Errorf("%w: %v", errSealVerifierFailed, err)
+	}
+	return digest, result, nil
+}
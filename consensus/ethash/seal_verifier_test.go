@@ -0,0 +1,99 @@
+package ethash
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+type fakeSealVerifier struct {
+	calls  int
+	digest []byte
+	err    error
+}
+
+func (v *fakeSealVerifier) Hashimoto(_ context.Context, _ uint64, _ common.Hash, _ uint64) ([]byte, []byte, error) {
+	v.calls++
+	return v.digest, make([]byte, common.HashLength), v.err
+}
+
+// sealedTestHeader returns a header with valid seal for the test-mode engine:
+// difficulty 1 accepts any PoW result, so only the mix digest has to match.
+func sealedTestHeader(ethash *Ethash) *types.Header {
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), Nonce: types.EncodeNonce(42)}
+	digest, _ := hashimotoLight(32*1024, ethash.cache(1).cache, ethash.SealHash(header).Bytes(), header.Nonce.Uint64())
+	header.MixDigest = common.BytesToHash(digest)
+	return header
+}
+
+func TestSealVerifierFailsClosed(t *testing.T) {
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+	header := sealedTestHeader(ethash)
+
+	verifier := &fakeSealVerifier{digest: header.MixDigest.Bytes()}
+	ethash.config.SealVerifier = verifier
+	if err := ethash.VerifySeal(nil, header); err != nil {
+		t.Fatalf("valid seal rejected: %v", err)
+	}
+
+	// the engine checks values returned by the verifier itself
+	verifier.digest = make([]byte, common.HashLength)
+	if err := ethash.VerifySeal(nil, header); !errors.Is(err, errInvalidMixDigest) {
+		t.Fatalf("wrong digest accepted: %v", err)
+	}
+
+	// failed verifier rejects the seal, even a valid one, it isn't verified locally
+	verifier.digest = header.MixDigest.Bytes()
+	verifier.err = errors.New("worker is down")
+	if err := ethash.VerifySeal(nil, header); !errors.Is(err, errSealVerifierFailed) {
+		t.Fatalf("seal accepted with failed verifier: %v", err)
+	}
+	if verifier.calls != 3 {
+		t.Fatalf("verifier calls: have %d, want 3", verifier.calls)
+	}
+
+	ethash.config.SealVerifier = unavailableSealVerifier{err: errors.New("connection refused")}
+	if err := ethash.VerifySeal(nil, header); !errors.Is(err, errSealVerifierFailed) {
+		t.Fatalf("seal accepted with unavailable verifier: %v", err)
+	}
+}
+
+type hashimotoService struct{ ethash *Ethash }
+
+func (s *hashimotoService) Hashimoto(number hexutil.Uint64, sealHash common.Hash, nonce hexutil.Uint64) *HashimotoResult {
+	digest, result := hashimotoLight(32*1024, s.ethash.cache(uint64(number)).cache, sealHash.Bytes(), uint64(nonce))
+	return &HashimotoResult{MixDigest: digest, Result: result}
+}
+
+func TestRemoteSealVerifier(t *testing.T) {
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+	header := sealedTestHeader(ethash)
+
+	server := rpc.NewServer(50, false, true)
+	defer server.Stop()
+	if err := server.RegisterName("ethash", &hashimotoService{ethash}); err != nil {
+		t.Fatal(err)
+	}
+	verifier := &RemoteSealVerifier{client: rpc.DialInProc(server)}
+	defer verifier.Close()
+
+	digest, _, err := verifier.Hashimoto(context.Background(), 1, ethash.SealHash(header), header.Nonce.Uint64())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if common.BytesToHash(digest) != header.MixDigest {
+		t.Fatalf("digest: have %x, want %x", digest, header.MixDigest)
+	}
+	ethash.config.SealVerifier = verifier
+	if err := ethash.VerifySeal(nil, header); err != nil {
+		t.Fatalf("valid seal rejected: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package ethash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// adjustmentSeconds - difficulty of a block stops depending on its timestamp this long after the parent:
+// the adjustment is limited by -99 steps of 9 (Byzantium and later) or 10 (Homestead) seconds
+const adjustmentSeconds = 1000
+
+// HashrateChange - network hashrate, hashes per second, from the block on
+type HashrateChange struct {
+	FromBlock uint64  `json:"fromBlock"`
+	Hashrate  float64 `json:"hashrate"`
+}
+
+// Simulate evolution of difficulty and block time for `blocks` blocks after the parent. Miners keep the timestamp
+// of the candidate block current, so its difficulty decreases while it's searched for. A block is found when the
+// expected number of solutions (hashrate/difficulty per second) reaches one, or an exponentially distributed
+// amount if rnd != nil. Blocks have no uncles. Every sampleEvery-th block and the last one are returned, the
+// samples so far if ctx is cancelled.
+func Simulate(ctx context.Context, config *params.ChainConfig, parentNumber, parentTime uint64, parentDifficulty *big.Int, blocks, sampleEvery uint64,
+	hashrates []HashrateChange, rnd *rand.Rand) ([]consensus.DifficultySample, error) {
+	if len(hashrates) == 0 {
+		return nil, errors.New("hashrate is not set")
+	}
+	for i, h := range hashrates {
+		if h.Hashrate <= 0 {
+			return nil, fmt.Errorf("hashrate must be positive, got %v from block %d", h.Hashrate, h.FromBlock)
+		}
+		if i > 0 && h.FromBlock <= hashrates[i-1].FromBlock {
+			return nil, errors.New("hashrate changes must be sorted by block")
+		}
+	}
+	if sampleEvery == 0 {
+		sampleEvery = 1
+	}
+
+	samples := make([]consensus.DifficultySample, 0, blocks/sampleEvery+1)
+	number, timestamp, difficulty := parentNumber, parentTime, new(big.Int).Set(parentDifficulty)
+	var elapsed float64 // Search time of blocks since the last sample
+	var count uint64    // Blocks since the last sample
+	h := 0
+	for i := uint64(1); i <= blocks; i++ {
+		if err := ctx.Err(); err != nil {
+			return samples, err
+		}
+		for h+1 < len(hashrates) && hashrates[h+1].FromBlock <= number+1 {
+			h++
+		}
+		target := 1.0
+		if rnd != nil {
+			target = rnd.ExpFloat64()
+		}
+		blockTime, delta, next := searchBlock(config, number, timestamp, difficulty, hashrates[h].Hashrate, target)
+		number, timestamp, difficulty = number+1, timestamp+delta, next
+		elapsed, count = elapsed+blockTime, count+1
+		if i%sampleEvery == 0 || i == blocks {
+			samples = append(samples, consensus.DifficultySample{Number: number, Time: timestamp, Difficulty: difficulty, BlockTime: elapsed / float64(count)})
+			elapsed, count = 0, 0
+		}
+	}
+	return samples, nil
+}
+
+// searchBlock returns how long the search of the next block takes in seconds, its timestamp relative to the parent
+// and its difficulty. The block found during second s after the parent has timestamp parentTime+s.
+func searchBlock(config *params.ChainConfig, parentNumber, parentTime uint64, parentDifficulty *big.Int, hashrate, target float64) (float64, uint64, *big.Int) {
+	var solutions float64
+	for s := uint64(1); ; s++ {
+		difficulty := CalcDifficulty(config, parentTime+s, parentTime, parentDifficulty, parentNumber, types.EmptyUncleHash)
+		d, _ := new(big.Float).SetInt(difficulty).Float64()
+		rate := hashrate / d
+		if solutions+rate >= target || s >= adjustmentSeconds {
+			// difficulty doesn't change anymore after adjustmentSeconds, the rest of the search is at the same rate
+			blockTime := float64(s-1) + (target-solutions)/rate
+			return blockTime, uint64(blockTime) + 1, difficulty
+		}
+		solutions += rate
+	}
+}
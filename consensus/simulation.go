@@ -0,0 +1,11 @@
+package consensus
+
+import "math/big"
+
+// DifficultySample - state of a simulated chain after the block, see ethash.Simulate and clique.Simulate
+type DifficultySample struct {
+	Number     uint64
+	Time       uint64
+	Difficulty *big.Int
+	BlockTime  float64 // Average block time since the previous sample, seconds
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// BlockHashReader answers BLOCKHASH-style queries: hash of an ancestor of a block by number, at any depth. The EVM
+// limits BLOCKHASH to 256 blocks itself. Implementations may read the canonical hash table, walk parent hashes or, with
+// in-protocol block history (EIP-2935), read the history contract.
+type BlockHashReader interface {
+	// BlockHash returns hash of the ancestor `number` of the block with header `ref`, zero hash if it's unknown
+	BlockHash(ref *types.Header, number uint64) (common.Hash, error)
+}
+
+// CanonicalHeaderReader - source of headers and canonical hashes, services.HeaderAndCanonicalReader satisfies it
+type CanonicalHeaderReader interface {
+	Header(ctx context.Context, tx kv.Getter, hash common.Hash, blockHeight uint64) (*types.Header, error)
+	CanonicalHash(ctx context.Context, tx kv.Getter, blockHeight uint64) (common.Hash, error)
+}
+
+// CanonicalBlockHashes - BlockHashReader of the canonical hash table. Ancestors of non-canonical blocks are found by
+// walking their parents down to the canonical chain, and in the table below it.
+type CanonicalBlockHashes struct {
+	ctx    context.Context
+	tx     kv.Getter
+	reader CanonicalHeaderReader
+}
+
+func NewCanonicalBlockHashes(ctx context.Context, tx kv.Getter, reader CanonicalHeaderReader) *CanonicalBlockHashes {
+	return &CanonicalBlockHashes{ctx: ctx, tx: tx, reader: reader}
+}
+
+func (b *CanonicalBlockHashes) BlockHash(ref *types.Header, number uint64) (common.Hash, error) {
+	refNumber := ref.Number.Uint64()
+	if number >= refNumber {
+		return common.Hash{}, nil
+	}
+	hash, n := ref.ParentHash, refNumber-1
+	for {
+		canonical, err := b.reader.CanonicalHash(b.ctx, b.tx, n)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if canonical == hash {
+			if n == number {
+				return hash, nil
+			}
+			return b.reader.CanonicalHash(b.ctx, b.tx, number)
+		}
+		if n == number {
+			return hash, nil
+		}
+		header, err := b.reader.Header(b.ctx, b.tx, hash, n)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if header == nil {
+			return common.Hash{}, nil
+		}
+		hash, n = header.ParentHash, n-1
+	}
+}
+
+// BlockHashFn adapts the reader to the EVM: errors are logged and give zero hash, as an unknown block does
+func BlockHashFn(ref *types.Header, reader BlockHashReader) vm.GetHashFunc {
+	return func(n uint64) common.Hash {
+		hash, err := reader.BlockHash(ref, n)
+		if err != nil {
+			log.Error("Can't get block hash by number", "number", n, "ref", ref.Number, "err", err)
+			return common.Hash{}
+		}
+		return hash
+	}
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type testHeaderReader struct {
+	canonical []common.Hash
+	headers   map[common.Hash]*types.Header
+}
+
+func (r *testHeaderReader) Header(_ context.Context, _ kv.Getter, hash common.Hash, number uint64) (*types.Header, error) {
+	if h, ok := r.headers[hash]; ok && h.Number.Uint64() == number {
+		return h, nil
+	}
+	return nil, nil
+}
+
+func (r *testHeaderReader) CanonicalHash(_ context.Context, _ kv.Getter, number uint64) (common.Hash, error) {
+	if number < uint64(len(r.canonical)) {
+		return r.canonical[number], nil
+	}
+	return common.Hash{}, nil
+}
+
+func (r *testHeaderReader) add(parent *types.Header, extra byte) *types.Header {
+	h := &types.Header{Number: big.NewInt(0), Extra: []byte{extra}}
+	if parent != nil {
+		h.ParentHash, h.Number = parent.Hash(), new(big.Int).Add(parent.Number, common.Big1)
+	}
+	r.headers[h.Hash()] = h
+	return h
+}
+
+func TestCanonicalBlockHashes(t *testing.T) {
+	r := &testHeaderReader{headers: map[common.Hash]*types.Header{}}
+	var canonical []*types.Header
+	var parent *types.Header
+	for i := 0; i <= 300; i++ {
+		parent = r.add(parent, 0)
+		canonical = append(canonical, parent)
+		r.canonical = append(r.canonical, parent.Hash())
+	}
+	// side chain forked off at 250
+	side := canonical[250]
+	var sideChain []*types.Header
+	for i := 0; i < 3; i++ {
+		side = r.add(side, 1)
+		sideChain = append(sideChain, side)
+	}
+	hashes := NewCanonicalBlockHashes(context.Background(), nil, r)
+
+	blockHash := func(ref *types.Header, n uint64) common.Hash {
+		hash, err := hashes.BlockHash(ref, n)
+		require.NoError(t, err)
+		return hash
+	}
+	require.Equal(t, canonical[299].Hash(), blockHash(canonical[300], 299))
+	require.Equal(t, canonical[1].Hash(), blockHash(canonical[300], 1)) // beyond 256 blocks
+	require.Equal(t, common.Hash{}, blockHash(canonical[300], 300))
+
+	require.Equal(t, sideChain[1].Hash(), blockHash(sideChain[2], 252))
+	require.Equal(t, sideChain[0].Hash(), blockHash(sideChain[2], 251))
+	require.Equal(t, canonical[250].Hash(), blockHash(sideChain[2], 250))
+	require.Equal(t, canonical[10].Hash(), blockHash(sideChain[2], 10))
+
+	// the same as walking parents
+	getHash := GetHashFn(sideChain[2], func(hash common.Hash, number uint64) *types.Header { return r.headers[hash] })
+	blockHashFn := BlockHashFn(sideChain[2], hashes)
+	for n := uint64(0); n < 253; n++ {
+		require.Equal(t, getHash(n), blockHashFn(n))
+	}
+}
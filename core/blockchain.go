@@ -66,6 +66,7 @@ type EphemeralExecResult struct {
 	Difficulty       *math.HexOrDecimal256 `json:"currentDifficulty" gencodec:"required"`
 	GasUsed          math.HexOrDecimal64   `json:"gasUsed"`
 	StateSyncReceipt *types.Receipt        `json:"-"`
+	EncodedReceipts  *EncodedReceipts      `json:"-"` // set if receipts were encoded during execution
 }
 
 func ExecuteBlockEphemerallyForBSC(
@@ -230,6 +231,7 @@ func ExecuteBlockEphemerally(
 	chainReader consensus.ChainHeaderReader,
 	statelessExec bool, // for usage of this API via cli tools wherein some of the validations need to be relaxed.
 	getTracer func(txIndex int, txHash common.Hash) (vm.Tracer, error),
+	receiptsEncoder *ReceiptsEncoder, // optional, encodes receipts in background while the block is executed
 ) (*EphemeralExecResult, error) {
 
 	defer blockExecutionTimer.UpdateDuration(time.Now())
@@ -257,6 +259,9 @@ func ExecuteBlockEphemerally(
 		misc.ApplyDAOHardFork(ibs)
 	}
 	noop := state.NewNoopWriter()
+	if receiptsEncoder != nil {
+		receiptsEncoder.Reset(len(block.Transactions()))
+	}
 	//fmt.Printf("====txs processing start: %d====\n", block.NumberU64())
 	for i, tx := range block.Transactions() {
 		ibs.Prepare(tx.Hash(), block.Hash(), i)
@@ -287,11 +292,24 @@ func ExecuteBlockEphemerally(
 			includedTxs = append(includedTxs, tx)
 			if !vmConfig.NoReceipts {
 				receipts = append(receipts, receipt)
+				if receiptsEncoder != nil {
+					receiptsEncoder.Add(receipt)
+				}
 			}
 		}
 	}
 
-	receiptSha := types.DeriveSha(receipts)
+	var receiptSha common.Hash
+	var encodedReceipts *EncodedReceipts
+	if receiptsEncoder != nil {
+		var err error
+		if encodedReceipts, err = receiptsEncoder.Wait(); err != nil {
+			return nil, err
+		}
+		receiptSha = encodedReceipts.Root
+	} else {
+		receiptSha = types.DeriveSha(receipts)
+	}
 	if !statelessExec && chainConfig.IsByzantium(header.Number.Uint64()) && !vmConfig.NoReceipts && receiptSha != block.ReceiptHash() {
 		return nil, fmt.Errorf("mismatched receipt headers for block %d (%s != %s)", block.NumberU64(), receiptSha.Hex(), block.ReceiptHash().Hex())
 	}
@@ -315,14 +333,15 @@ func ExecuteBlockEphemerally(
 	}
 	blockLogs := ibs.Logs()
 	execRs := &EphemeralExecResult{
-		TxRoot:      types.DeriveSha(includedTxs),
-		ReceiptRoot: receiptSha,
-		Bloom:       bloom,
-		LogsHash:    rlpHash(blockLogs),
-		Receipts:    receipts,
-		Difficulty:  (*math.HexOrDecimal256)(header.Difficulty),
-		GasUsed:     math.HexOrDecimal64(*usedGas),
-		Rejected:    rejectedTxs,
+		TxRoot:          types.DeriveSha(includedTxs),
+		ReceiptRoot:     receiptSha,
+		Bloom:           bloom,
+		LogsHash:        rlpHash(blockLogs),
+		Receipts:        receipts,
+		EncodedReceipts: encodedReceipts,
+		Difficulty:      (*math.HexOrDecimal256)(header.Difficulty),
+		GasUsed:         math.HexOrDecimal64(*usedGas),
+		Rejected:        rejectedTxs,
 	}
 
 	return execRs, nil
@@ -342,6 +361,7 @@ func ExecuteBlockEphemerallyBor(
 	chainReader consensus.ChainHeaderReader,
 	statelessExec bool, // for usage of this API via cli tools wherein some of the validations need to be relaxed.
 	getTracer func(txIndex int, txHash common.Hash) (vm.Tracer, error),
+	receiptsEncoder *ReceiptsEncoder, // optional, encodes receipts in background while the block is executed
 ) (*EphemeralExecResult, error) {
 
 	defer blockExecutionTimer.UpdateDuration(time.Now())
@@ -369,6 +389,9 @@ func ExecuteBlockEphemerallyBor(
 		misc.ApplyDAOHardFork(ibs)
 	}
 	noop := state.NewNoopWriter()
+	if receiptsEncoder != nil {
+		receiptsEncoder.Reset(len(block.Transactions()))
+	}
 	//fmt.Printf("====txs processing start: %d====\n", block.NumberU64())
 	for i, tx := range block.Transactions() {
 		ibs.Prepare(tx.Hash(), block.Hash(), i)
@@ -399,11 +422,24 @@ func ExecuteBlockEphemerallyBor(
 			includedTxs = append(includedTxs, tx)
 			if !vmConfig.NoReceipts {
 				receipts = append(receipts, receipt)
+				if receiptsEncoder != nil {
+					receiptsEncoder.Add(receipt)
+				}
 			}
 		}
 	}
 
-	receiptSha := types.DeriveSha(receipts)
+	var receiptSha common.Hash
+	var encodedReceipts *EncodedReceipts
+	if receiptsEncoder != nil {
+		var err error
+		if encodedReceipts, err = receiptsEncoder.Wait(); err != nil {
+			return nil, err
+		}
+		receiptSha = encodedReceipts.Root
+	} else {
+		receiptSha = types.DeriveSha(receipts)
+	}
 	if !statelessExec && chainConfig.IsByzantium(header.Number.Uint64()) && !vmConfig.NoReceipts && receiptSha != block.ReceiptHash() {
 		return nil, fmt.Errorf("mismatched receipt headers for block %d (%s != %s)", block.NumberU64(), receiptSha.Hex(), block.ReceiptHash().Hex())
 	}
@@ -451,6 +487,7 @@ func ExecuteBlockEphemerallyBor(
 		Bloom:            bloom,
 		LogsHash:         rlpHash(blockLogs),
 		Receipts:         receipts,
+		EncodedReceipts:  encodedReceipts,
 		Difficulty:       (*math.HexOrDecimal256)(header.Difficulty),
 		GasUsed:          math.HexOrDecimal64(*usedGas),
 		Rejected:         rejectedTxs,
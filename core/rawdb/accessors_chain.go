@@ -389,6 +389,9 @@ func CanonicalTxnByID(db kv.Getter, id uint64) (types.Transaction, error) {
 	if err != nil {
 		return nil, err
 	}
+	if v, err = DecompressTxn(db, v, nil); err != nil {
+		return nil, err
+	}
 	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(v), uint64(len(v))))
 	if err != nil {
 		return nil, err
@@ -408,7 +411,10 @@ func CanonicalTransactions(db kv.Getter, baseTxId uint64, amount uint32) ([]type
 	i := uint32(0)
 
 	if err := db.ForAmount(kv.EthTx, txIdKey, amount, func(k, v []byte) error {
-		var decodeErr error
+		v, decodeErr := DecompressTxn(db, v, nil)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		reader.Reset(v)
 		stream.Reset(reader, 0)
 		if txs[i], decodeErr = types.DecodeTransaction(stream); decodeErr != nil {
@@ -435,7 +441,10 @@ func NonCanonicalTransactions(db kv.Getter, baseTxId uint64, amount uint32) ([]t
 	i := uint32(0)
 
 	if err := db.ForAmount(kv.NonCanonicalTxs, txIdKey, amount, func(k, v []byte) error {
-		var decodeErr error
+		v, decodeErr := DecompressTxn(db, v, nil)
+		if decodeErr != nil {
+			return decodeErr
+		}
 		reader.Reset(v)
 		stream.Reset(reader, 0)
 		if txs[i], decodeErr = types.DecodeTransaction(stream); decodeErr != nil {
@@ -462,9 +471,13 @@ func WriteTransactions(db kv.RwTx, txs []types.Transaction, baseTxId uint64) err
 		if err := rlp.Encode(buf, tx); err != nil {
 			return fmt.Errorf("broken tx rlp: %w", err)
 		}
+		v, err := CompressTxn(db, buf.Bytes())
+		if err != nil {
+			return err
+		}
 
 		// If next Append returns KeyExists error - it means you need to open transaction in App code before calling this func. Batch is also fine.
-		if err := db.Append(kv.EthTx, txIdKey, common.CopyBytes(buf.Bytes())); err != nil {
+		if err := db.Append(kv.EthTx, txIdKey, common.CopyBytes(v)); err != nil {
 			return err
 		}
 	}
@@ -476,8 +489,12 @@ func WriteRawTransactions(tx kv.RwTx, txs [][]byte, baseTxId uint64) error {
 	for _, txn := range txs {
 		txIdKey := make([]byte, 8)
 		binary.BigEndian.PutUint64(txIdKey, txId)
+		v, err := CompressTxn(tx, txn)
+		if err != nil {
+			return err
+		}
 		// If next Append returns KeyExists error - it means you need to open transaction in App code before calling this func. Batch is also fine.
-		if err := tx.Append(kv.EthTx, txIdKey, txn); err != nil {
+		if err := tx.Append(kv.EthTx, txIdKey, v); err != nil {
 			return fmt.Errorf("txId=%d, baseTxId=%d, %w", txId, baseTxId, err)
 		}
 		txId++
@@ -575,7 +592,11 @@ func RawTransactionsRange(db kv.Getter, from, to uint64) (res [][]byte, err erro
 
 		binary.BigEndian.PutUint64(encNum, baseTxId)
 		if err = db.ForAmount(kv.EthTx, encNum, txAmount, func(k, v []byte) error {
-			res = append(res, v)
+			txn, err := DecompressTxn(db, v, nil)
+			if err != nil {
+				return err
+			}
+			res = append(res, txn)
 			return nil
 		}); err != nil {
 			return nil, err
@@ -1069,6 +1090,23 @@ func AppendReceipts(tx kv.StatelessWriteTx, blockNumber uint64, receipts types.R
 	return nil
 }
 
+// AppendEncodedReceipts is AppendReceipts for already CBOR-encoded logs of each receipt
+// (nil for receipts without logs) and receipts of the block.
+func AppendEncodedReceipts(tx kv.StatelessWriteTx, blockNumber uint64, logs [][]byte, receipts []byte) error {
+	for txId, l := range logs {
+		if l == nil {
+			continue
+		}
+		if err := tx.Append(kv.Log, dbutils.LogKey(blockNumber, uint32(txId)), l); err != nil {
+			return fmt.Errorf("writing receipts for block %d: %w", blockNumber, err)
+		}
+	}
+	if err := tx.Append(kv.Receipts, dbutils.EncodeBlockNumber(blockNumber), receipts); err != nil {
+		return fmt.Errorf("writing receipts for block %d: %w", blockNumber, err)
+	}
+	return nil
+}
+
 // TruncateReceipts removes all receipt for given block number or newer
 func TruncateReceipts(db kv.RwTx, number uint64) error {
 	if err := db.ForEach(kv.Receipts, dbutils.EncodeBlockNumber(number), func(k, _ []byte) error {
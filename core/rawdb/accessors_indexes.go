@@ -17,11 +17,17 @@
 package rawdb
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"math/big"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/log/v3"
 )
 
@@ -33,25 +39,61 @@ type TxLookupEntry struct {
 	Index      uint64
 }
 
+// TxLookupValue encodes a TxLookup entry: number of the block and position of the transaction in it.
+// Entries written by older versions hold only big-endian block number without leading zeroes,
+// the leading 0x00 byte distinguishes the new format.
+func TxLookupValue(blockNumber, txIndex uint64) []byte {
+	v := make([]byte, 1+2*binary.MaxVarintLen64)
+	n := 1
+	n += binary.PutUvarint(v[n:], blockNumber)
+	n += binary.PutUvarint(v[n:], txIndex)
+	return v[:n]
+}
+
+func decodeTxLookupValue(data []byte) (blockNumber, txIndex uint64, hasIndex bool, err error) {
+	if data[0] != 0 {
+		return new(big.Int).SetBytes(data).Uint64(), 0, false, nil
+	}
+	blockNumber, n := binary.Uvarint(data[1:])
+	if n <= 0 {
+		return 0, 0, false, fmt.Errorf("invalid TxLookup entry: %x", data)
+	}
+	txIndex, m := binary.Uvarint(data[1+n:])
+	if m <= 0 {
+		return 0, 0, false, fmt.Errorf("invalid TxLookup entry: %x", data)
+	}
+	return blockNumber, txIndex, true, nil
+}
+
 // ReadTxLookupEntry retrieves the positional metadata associated with a transaction
 // hash to allow retrieving the transaction or receipt by hash.
 func ReadTxLookupEntry(db kv.Getter, txnHash common.Hash) (*uint64, error) {
+	number, _, _, err := ReadTxLookupEntryWithIndex(db, txnHash)
+	return number, err
+}
+
+// ReadTxLookupEntryWithIndex is like ReadTxLookupEntry, but also returns position of the
+// transaction in the block - if the entry has it (entries of older versions don't).
+func ReadTxLookupEntryWithIndex(db kv.Getter, txnHash common.Hash) (number *uint64, txIndex uint64, hasIndex bool, err error) {
 	data, err := db.GetOne(kv.TxLookup, txnHash.Bytes())
 	if err != nil {
-		return nil, err
+		return nil, 0, false, err
 	}
 	if len(data) == 0 {
-		return nil, nil
+		return nil, 0, false, nil
+	}
+	blockNumber, txIndex, hasIndex, err := decodeTxLookupValue(data)
+	if err != nil {
+		return nil, 0, false, err
 	}
-	number := new(big.Int).SetBytes(data).Uint64()
-	return &number, nil
+	return &blockNumber, txIndex, hasIndex, nil
 }
 
 // WriteTxLookupEntries stores a positional metadata for every transaction from
 // a block, enabling hash based transaction and receipt lookups.
 func WriteTxLookupEntries(db kv.Putter, block *types.Block) {
-	for _, tx := range block.Transactions() {
-		data := block.Number().Bytes()
+	for i, tx := range block.Transactions() {
+		data := TxLookupValue(block.NumberU64(), uint64(i))
 		if err := db.Put(kv.TxLookup, tx.Hash().Bytes(), data); err != nil {
 			log.Crit("Failed to store transaction lookup entry", "err", err)
 		}
@@ -66,66 +108,116 @@ func DeleteTxLookupEntry(db kv.Deleter, hash common.Hash) error {
 // ReadTransactionByHash retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransactionByHash(db kv.Tx, hash common.Hash) (types.Transaction, common.Hash, uint64, uint64, error) {
-	blockNumber, err := ReadTxLookupEntry(db, hash)
+	blockNumber, txIndex, hasIndex, err := ReadTxLookupEntryWithIndex(db, hash)
 	if err != nil {
 		return nil, common.Hash{}, 0, 0, err
 	}
 	if blockNumber == nil {
 		return nil, common.Hash{}, 0, 0, nil
 	}
-	blockHash, err := ReadCanonicalHash(db, *blockNumber)
+	return readTransaction(db, hash, *blockNumber, txIndex, hasIndex)
+}
+
+// ReadTransaction retrieves a specific transaction from the database, along with
+// its added positional metadata.
+func ReadTransaction(db kv.Tx, hash common.Hash, blockNumber uint64) (types.Transaction, common.Hash, uint64, uint64, error) {
+	return readTransaction(db, hash, blockNumber, 0, false)
+}
+
+// readTransaction finds the transaction in the canonical block without decoding the whole body:
+// if position of the transaction is known - only it is read, otherwise raw transactions are hashed
+// and only the matching one is decoded.
+func readTransaction(db kv.Tx, hash common.Hash, blockNumber, txIndex uint64, hasIndex bool) (types.Transaction, common.Hash, uint64, uint64, error) {
+	blockHash, err := ReadCanonicalHash(db, blockNumber)
 	if err != nil {
 		return nil, common.Hash{}, 0, 0, err
 	}
 	if blockHash == (common.Hash{}) {
 		return nil, common.Hash{}, 0, 0, nil
 	}
-	body := ReadCanonicalBodyWithTransactions(db, blockHash, *blockNumber)
+	body, err := ReadBodyForStorageByKey(db, dbutils.BlockBodyKey(blockNumber, blockHash))
+	if err != nil {
+		return nil, common.Hash{}, 0, 0, err
+	}
 	if body == nil {
 		log.Error("Transaction referenced missing", "number", blockNumber, "hash", blockHash)
 		return nil, common.Hash{}, 0, 0, nil
 	}
-	senders, err1 := ReadSenders(db, blockHash, *blockNumber)
-	if err1 != nil {
-		return nil, common.Hash{}, 0, 0, err1
+	baseTxId, txAmount := body.BaseTxId+1, uint64(body.TxAmount)-2 // skip system txs
+	var txn types.Transaction
+	if hasIndex && txIndex < txAmount {
+		if txn, err = CanonicalTxnByID(db, baseTxId+txIndex); err != nil {
+			return nil, common.Hash{}, 0, 0, err
+		}
+		if txn.Hash() != hash {
+			txn = nil
+		}
 	}
-	body.SendersToTxs(senders)
-	for txIndex, tx := range body.Transactions {
-		if tx.Hash() == hash {
-			return tx, blockHash, *blockNumber, uint64(txIndex), nil
+	if txn == nil {
+		if txn, txIndex, err = canonicalTxnByHash(db, hash, baseTxId, txAmount); err != nil {
+			return nil, common.Hash{}, 0, 0, err
 		}
 	}
-	log.Error("Transaction not found", "number", blockNumber, "hash", blockHash, "txhash", hash)
-	return nil, common.Hash{}, 0, 0, nil
-}
-
-// ReadTransaction retrieves a specific transaction from the database, along with
-// its added positional metadata.
-func ReadTransaction(db kv.Tx, hash common.Hash, blockNumber uint64) (types.Transaction, common.Hash, uint64, uint64, error) {
-	blockHash, err := ReadCanonicalHash(db, blockNumber)
+	if txn == nil {
+		log.Error("Transaction not found", "number", blockNumber, "hash", blockHash, "txhash", hash)
+		return nil, common.Hash{}, 0, 0, nil
+	}
+	senders, err := ReadSenders(db, blockHash, blockNumber)
 	if err != nil {
 		return nil, common.Hash{}, 0, 0, err
 	}
-	if blockHash == (common.Hash{}) {
-		return nil, common.Hash{}, 0, 0, nil
+	if txIndex < uint64(len(senders)) {
+		txn.SetSender(senders[txIndex])
 	}
-	body := ReadCanonicalBodyWithTransactions(db, blockHash, blockNumber)
-	if body == nil {
-		log.Error("Transaction referenced missing", "number", blockNumber, "hash", blockHash)
-		return nil, common.Hash{}, 0, 0, nil
+	return txn, blockHash, blockNumber, txIndex, nil
+}
+
+// canonicalTxnByHash hashes raw transactions [baseTxId, baseTxId+amount) and decodes only the one with given hash.
+func canonicalTxnByHash(db kv.Getter, hash common.Hash, baseTxId, amount uint64) (txn types.Transaction, txIndex uint64, err error) {
+	if amount == 0 {
+		return nil, 0, nil
+	}
+	txIdKey := make([]byte, 8)
+	binary.BigEndian.PutUint64(txIdKey, baseTxId)
+	found := false
+	i := uint64(0)
+	if err := db.ForAmount(kv.EthTx, txIdKey, uint32(amount), func(k, v []byte) error {
+		if found {
+			return nil
+		}
+		v, err := DecompressTxn(db, v, nil)
+		if err != nil {
+			return err
+		}
+		h, err := RawTxnHash(v)
+		if err != nil {
+			return err
+		}
+		if h == hash {
+			found, txIndex = true, i
+			if txn, err = types.DecodeTransaction(rlp.NewStream(bytes.NewReader(v), uint64(len(v)))); err != nil {
+				return err
+			}
+		}
+		i++
+		return nil
+	}); err != nil {
+		return nil, 0, err
 	}
-	senders, err1 := ReadSenders(db, blockHash, blockNumber)
-	if err1 != nil {
-		return nil, common.Hash{}, 0, 0, err1
+	return txn, txIndex, nil
+}
+
+// RawTxnHash computes hash of the transaction stored in kv.EthTx without decoding it:
+// legacy transactions are RLP lists, typed ones are RLP strings wrapping type||payload.
+func RawTxnHash(v []byte) (common.Hash, error) {
+	kind, content, _, err := rlp.Split(v)
+	if err != nil {
+		return common.Hash{}, err
 	}
-	body.SendersToTxs(senders)
-	for txIndex, tx := range body.Transactions {
-		if tx.Hash() == hash {
-			return tx, blockHash, blockNumber, uint64(txIndex), nil
-		}
+	if kind == rlp.List {
+		return crypto.Keccak256Hash(v), nil
 	}
-	log.Error("Transaction not found", "number", blockNumber, "hash", blockHash, "txhash", hash)
-	return nil, common.Hash{}, 0, 0, nil
+	return crypto.Keccak256Hash(content), nil
 }
 
 func ReadReceipt(db kv.Tx, txHash common.Hash) (*types.Receipt, common.Hash, uint64, uint64, error) {
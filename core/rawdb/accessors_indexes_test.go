@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"math"
 	"math/big"
 	"testing"
 
@@ -40,6 +41,16 @@ func TestLookupStorage(t *testing.T) {
 				WriteTxLookupEntries(db, block)
 			},
 		},
+		{
+			"BlockNumberOnly", // entries without position of the transaction in the block
+			func(db kv.Putter, block *types.Block) {
+				for _, txn := range block.Transactions() {
+					if err := db.Put(kv.TxLookup, txn.Hash().Bytes(), block.Number().Bytes()); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+		},
 		// Erigon: older databases are removed, no backward compatibility
 	}
 
@@ -49,7 +60,7 @@ func TestLookupStorage(t *testing.T) {
 
 			tx1 := types.NewTransaction(1, common.BytesToAddress([]byte{0x11}), uint256.NewInt(111), 1111, uint256.NewInt(11111), []byte{0x11, 0x11, 0x11})
 			tx2 := types.NewTransaction(2, common.BytesToAddress([]byte{0x22}), uint256.NewInt(222), 2222, uint256.NewInt(22222), []byte{0x22, 0x22, 0x22})
-			tx3 := types.NewTransaction(3, common.BytesToAddress([]byte{0x33}), uint256.NewInt(333), 3333, uint256.NewInt(33333), []byte{0x33, 0x33, 0x33})
+			tx3 := types.NewEIP1559Transaction(*uint256.NewInt(1), 3, common.BytesToAddress([]byte{0x33}), uint256.NewInt(333), 3333, uint256.NewInt(33333), uint256.NewInt(3), uint256.NewInt(33333), []byte{0x33, 0x33, 0x33})
 			txs := []types.Transaction{tx1, tx2, tx3}
 
 			block := types.NewBlock(&types.Header{Number: big.NewInt(314)}, txs, nil, nil)
@@ -96,3 +107,23 @@ func TestLookupStorage(t *testing.T) {
 		})
 	}
 }
+
+func TestTxLookupValue(t *testing.T) {
+	for _, tc := range []struct{ number, index uint64 }{{0, 0}, {314, 2}, {15_000_000, 1023}, {math.MaxUint64, math.MaxUint32}} {
+		number, index, hasIndex, err := decodeTxLookupValue(TxLookupValue(tc.number, tc.index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasIndex || number != tc.number || index != tc.index {
+			t.Fatalf("have %d/%d/%t, want %d/%d", number, index, hasIndex, tc.number, tc.index)
+		}
+	}
+	// older format: only block number
+	number, _, hasIndex, err := decodeTxLookupValue(big.NewInt(314).Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasIndex || number != 314 {
+		t.Fatalf("have %d/%t, want 314", number, hasIndex)
+	}
+}
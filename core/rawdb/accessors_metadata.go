@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 
@@ -61,3 +62,29 @@ func WriteChainConfig(db kv.Putter, hash common.Hash, cfg *params.ChainConfig) e
 func DeleteChainConfig(db kv.Deleter, hash common.Hash) error {
 	return db.Delete(kv.ConfigTable, hash[:])
 }
+
+var UnwindInProgressKey = []byte("unwind_in_progress")
+
+// ReadUnwindInProgress - unwind point and the head before the unwind, if the stage loop is unwinding in separate
+// transactions: blocks above the unwind point are inconsistent until the new chain is synced up
+func ReadUnwindInProgress(db kv.Getter) (unwindPoint, head uint64, ok bool, err error) {
+	data, err := db.GetOne(kv.DatabaseInfo, UnwindInProgressKey)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if len(data) != 16 {
+		return 0, 0, false, nil
+	}
+	return binary.BigEndian.Uint64(data), binary.BigEndian.Uint64(data[8:]), true, nil
+}
+
+func WriteUnwindInProgress(db kv.Putter, unwindPoint, head uint64) error {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data, unwindPoint)
+	binary.BigEndian.PutUint64(data[8:], head)
+	return db.Put(kv.DatabaseInfo, UnwindInProgressKey, data)
+}
+
+func DeleteUnwindInProgress(db kv.Deleter) error {
+	return db.Delete(kv.DatabaseInfo, UnwindInProgressKey)
+}
@@ -0,0 +1,33 @@
+package rawdb
+
+import (
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// TokenTransferIndex - index of ERC-20/ERC-721 Transfer logs, built by the TokenTransfers stage:
+// holder (20 bytes) + token (20 bytes) + chunk suffix (4 bytes) -> roaring bitmap of blocks where the holder sent or
+// received the token. Chunks are written by bitmapdb, like in LogAddressIndex.
+const TokenTransferIndex = "TokenTransferIndex"
+
+// TransferTopic - topic of Transfer(address indexed from, address indexed to, uint256 value|tokenId), the same for
+// ERC-20 (value in data) and ERC-721 (tokenId in the 4th topic)
+var TransferTopic = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+// TokenTransfer returns the sender and the recipient of a token Transfer log
+func TokenTransfer(l *types.Log) (from, to common.Address, ok bool) {
+	if len(l.Topics) < 3 || l.Topics[0] != TransferTopic {
+		return common.Address{}, common.Address{}, false
+	}
+	return common.BytesToAddress(l.Topics[1][12:]), common.BytesToAddress(l.Topics[2][12:]), true
+}
+
+// TokenTransferKey - key of TokenTransferIndex without the chunk suffix. The holder comes first, so that all tokens
+// of a holder are found by the prefix.
+func TokenTransferKey(holder, token common.Address) []byte {
+	k := make([]byte, 2*length.Addr)
+	copy(k, holder[:])
+	copy(k[length.Addr:], token[:])
+	return k
+}
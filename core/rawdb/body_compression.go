@@ -0,0 +1,403 @@
+package rawdb
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"runtime"
+	"sync"
+
+	"github.com/klauspost/compress/huff0"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+)
+
+// Transactions of block bodies (values of kv.EthTx and kv.NonCanonicalTxs) are the bulk of the bodies and may be stored
+// compressed: TxCompressedV1 followed by a zstd frame compressed with the dictionary stored in kv.DatabaseInfo under
+// TxsDictKey. RLP of a transaction never starts with 0x80 (empty string), so values written before the dictionary was
+// trained, or which didn't get smaller, are stored and read as-is.
+const TxCompressedV1 byte = 0x80
+
+var TxsDictKey = []byte("txsZstdDict")
+
+// TxsDictSize - default size of the trained dictionary, the same as of `zstd --train`
+const TxsDictSize = 110 * 1024
+
+const (
+	// TxsDictMinSamples - the dictionary isn't trained on fewer transactions, new nodes get it once they have enough
+	TxsDictMinSamples = 1_000
+	txsDictMaxSamples = 100_000
+)
+
+var dictMagic = []byte{0x37, 0xa4, 0x30, 0xec}
+
+type txsCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+// txsCodecs - codecs by dictionary ID, dictionaries are immutable, so they are shared by all opened DBs
+var txsCodecs = struct {
+	sync.RWMutex
+	byID map[uint32]*txsCodec
+}{byID: map[uint32]*txsCodec{}}
+
+func txsCodecOf(dict []byte) (*txsCodec, error) {
+	if len(dict) < 8 {
+		return nil, fmt.Errorf("invalid txs dictionary of %d bytes", len(dict))
+	}
+	id := binary.LittleEndian.Uint32(dict[4:8])
+	txsCodecs.RLock()
+	codec, ok := txsCodecs.byID[id]
+	txsCodecs.RUnlock()
+	if ok {
+		return codec, nil
+	}
+
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderDict(dict), zstd.WithEncoderCRC(false), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("txs dictionary %d: %w", id, err)
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dict), zstd.WithDecoderConcurrency(runtime.GOMAXPROCS(0)))
+	if err != nil {
+		return nil, fmt.Errorf("txs dictionary %d: %w", id, err)
+	}
+	txsCodecs.Lock()
+	defer txsCodecs.Unlock()
+	if codec, ok = txsCodecs.byID[id]; ok {
+		return codec, nil
+	}
+	codec = &txsCodec{enc: enc, dec: dec}
+	txsCodecs.byID[id] = codec
+	return codec, nil
+}
+
+// CompressTxn returns the value to store in kv.EthTx for the transaction RLP: compressed if the DB has the
+// dictionary and it makes the value smaller, the RLP otherwise
+func CompressTxn(db kv.Getter, txn []byte) ([]byte, error) {
+	if len(txn) == 0 || txn[0] == TxCompressedV1 {
+		return txn, nil
+	}
+	dict, err := db.GetOne(kv.DatabaseInfo, TxsDictKey)
+	if err != nil || len(dict) == 0 {
+		return txn, err
+	}
+	codec, err := txsCodecOf(dict)
+	if err != nil {
+		return nil, err
+	}
+	v := codec.enc.EncodeAll(txn, append(make([]byte, 0, len(txn)), TxCompressedV1))
+	if len(v) >= len(txn) {
+		return txn, nil
+	}
+	return v, nil
+}
+
+// DecompressTxn returns the transaction RLP of a value of kv.EthTx or kv.NonCanonicalTxs. Compressed values are
+// decompressed into buf, which may be nil.
+func DecompressTxn(db kv.Getter, v, buf []byte) ([]byte, error) {
+	if len(v) == 0 || v[0] != TxCompressedV1 {
+		return v, nil
+	}
+	var h zstd.Header
+	if err := h.Decode(v[1:]); err != nil {
+		return nil, fmt.Errorf("compressed txn: %w", err)
+	}
+	txsCodecs.RLock()
+	codec, ok := txsCodecs.byID[h.DictionaryID]
+	txsCodecs.RUnlock()
+	if !ok {
+		dict, err := db.GetOne(kv.DatabaseInfo, TxsDictKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(dict) < 8 || binary.LittleEndian.Uint32(dict[4:8]) != h.DictionaryID {
+			return nil, fmt.Errorf("compressed txn: unknown dictionary %d", h.DictionaryID)
+		}
+		if codec, err = txsCodecOf(dict); err != nil {
+			return nil, err
+		}
+	}
+	txn, err := codec.dec.DecodeAll(v[1:], buf[:0])
+	if err != nil {
+		return nil, fmt.Errorf("compressed txn: %w", err)
+	}
+	return txn, nil
+}
+
+// TrainTxsDictIfMissing trains the dictionary on samples of kv.EthTx and stores it, unless the DB already has one or
+// has fewer than TxsDictMinSamples transactions. Returns whether the dictionary was trained. Transactions stored before
+// stay uncompressed, only the ones written afterwards are compressed.
+func TrainTxsDictIfMissing(tx kv.RwTx) (bool, error) {
+	dict, err := tx.GetOne(kv.DatabaseInfo, TxsDictKey)
+	if err != nil || len(dict) > 0 {
+		return false, err
+	}
+	lastID, err := tx.ReadSequence(kv.EthTx)
+	if err != nil || lastID < TxsDictMinSamples {
+		return false, err
+	}
+	samples, err := txsDictSamples(tx, lastID)
+	if err != nil || len(samples) < TxsDictMinSamples {
+		return false, err
+	}
+	if dict, err = TrainTxsDict(samples, TxsDictSize); err != nil {
+		return false, err
+	}
+	if err = tx.Put(kv.DatabaseInfo, TxsDictKey, dict); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// txsDictSamples - up to txsDictMaxSamples uncompressed transactions evenly spread over kv.EthTx
+func txsDictSamples(tx kv.Tx, lastID uint64) ([][]byte, error) {
+	c, err := tx.Cursor(kv.EthTx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	step := lastID/txsDictMaxSamples + 1
+	var samples [][]byte
+	for id := uint64(0); id < lastID; id += step {
+		k, v, err := c.Seek(dbutils.EncodeBlockNumber(id))
+		if err != nil {
+			return nil, err
+		}
+		if k == nil {
+			break
+		}
+		if len(v) > 0 && v[0] != TxCompressedV1 {
+			samples = append(samples, common.CopyBytes(v))
+		}
+	}
+	return samples, nil
+}
+
+const (
+	dictGramLen    = 8  // length of substrings counted in samples
+	dictSegmentLen = 64 // length of sample pieces the dictionary content is made of
+)
+
+// TrainTxsDict builds a zstd dictionary (https://github.com/facebook/zstd/blob/dev/doc/zstd_compression_format.md#dictionary-format)
+// of at most size bytes of content from samples of transactions RLP. The content consists of the sample segments with
+// the most frequent substrings (like the COVER algorithm of zstd), the best ones at the end, where they are cheaper
+// to reference. The literals table is built from the bytes histogram of the samples, the sequence tables are the
+// predefined ones. ID of the dictionary is derived from its content.
+func TrainTxsDict(samples [][]byte, size int) ([]byte, error) {
+	content := dictContent(samples, size)
+	if len(content) < 8 {
+		return nil, errors.New("not enough samples for txs dictionary")
+	}
+	litTable, err := dictLiteralsTable(samples)
+	if err != nil {
+		return nil, err
+	}
+
+	dict := append(append([]byte{}, dictMagic...), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(dict[4:], 32768+crc32.ChecksumIEEE(content)%(1<<31-32768)) // outside of the reserved ranges
+	dict = append(dict, litTable...)
+	dict = appendNCount(dict, predefinedOffsetsNorm, 5)
+	dict = appendNCount(dict, predefinedMatchLengthsNorm, 6)
+	dict = appendNCount(dict, predefinedLiteralLengthsNorm, 6)
+	for _, rep := range []byte{1, 4, 8} { // initial repeat offsets of zstd frames
+		dict = append(dict, rep, 0, 0, 0)
+	}
+	return append(dict, content...), nil
+}
+
+type dictSegment struct {
+	sample, pos, len int
+	score            uint64
+}
+
+type dictSegments []*dictSegment
+
+func (h dictSegments) Len() int            { return len(h) }
+func (h dictSegments) Less(i, j int) bool  { return h[i].score > h[j].score }
+func (h dictSegments) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *dictSegments) Push(x interface{}) { *h = append(*h, x.(*dictSegment)) }
+func (h *dictSegments) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// dictContent greedily picks the sample segments of the highest score - sum of frequencies of their substrings which
+// are not in the picked segments yet
+func dictContent(samples [][]byte, size int) []byte {
+	freq := map[uint64]uint64{}
+	for _, s := range samples {
+		for i := 0; i+dictGramLen <= len(s); i++ {
+			freq[binary.LittleEndian.Uint64(s[i:])]++
+		}
+	}
+	score := func(seg *dictSegment) (score uint64) {
+		s := samples[seg.sample][seg.pos : seg.pos+seg.len]
+		for i := 0; i+dictGramLen <= len(s); i++ {
+			if f := freq[binary.LittleEndian.Uint64(s[i:])]; f > 1 {
+				score += f
+			}
+		}
+		return score
+	}
+
+	var segments dictSegments
+	for i, s := range samples {
+		for pos := 0; pos < len(s); pos += dictSegmentLen / 4 {
+			seg := &dictSegment{sample: i, pos: pos, len: dictSegmentLen}
+			if pos+seg.len > len(s) {
+				seg.len = len(s) - pos
+			}
+			if seg.len < dictGramLen {
+				break
+			}
+			if seg.score = score(seg); seg.score > 0 {
+				segments = append(segments, seg)
+			}
+		}
+	}
+	heap.Init(&segments)
+
+	var picked []*dictSegment
+	for total := 0; segments.Len() > 0 && total < size; {
+		seg := heap.Pop(&segments).(*dictSegment)
+		// scores only decrease, the segment is the best one if its actual score isn't less than the next one
+		if seg.score = score(seg); seg.score == 0 {
+			continue
+		}
+		if segments.Len() > 0 && seg.score < segments[0].score {
+			heap.Push(&segments, seg)
+			continue
+		}
+		if seg.len > size-total {
+			seg.len = size - total
+		}
+		s := samples[seg.sample][seg.pos : seg.pos+seg.len]
+		for i := 0; i+dictGramLen <= len(s); i++ {
+			delete(freq, binary.LittleEndian.Uint64(s[i:]))
+		}
+		picked = append(picked, seg)
+		total += seg.len
+	}
+
+	var content []byte
+	for i := len(picked) - 1; i >= 0; i-- {
+		seg := picked[i]
+		content = append(content, samples[seg.sample][seg.pos:seg.pos+seg.len]...)
+	}
+	return content
+}
+
+// dictLiteralsTable - Huffman table of literals, every byte gets a code, so the table can be used for any literals
+func dictLiteralsTable(samples [][]byte) ([]byte, error) {
+	var hist [256]uint64
+	var total uint64
+	for _, s := range samples {
+		for _, b := range s {
+			hist[b]++
+		}
+		total += uint64(len(s))
+	}
+	const histogramSize = 64 * 1024 // the table is built from the scaled histogram
+	var in []byte
+	for b, count := range hist {
+		n := 1
+		if total > 0 {
+			n += int(count * histogramSize / total)
+		}
+		for i := 0; i < n; i++ {
+			in = append(in, byte(b))
+		}
+	}
+	var s huff0.Scratch
+	if _, _, err := huff0.Compress1X(in, &s); err != nil {
+		return nil, fmt.Errorf("txs dictionary literals: %w", err)
+	}
+	return append([]byte{}, s.OutTable...), nil
+}
+
+// Predefined distributions of sequence codes, see https://github.com/facebook/zstd/blob/dev/doc/zstd_compression_format.md#default-distributions
+var (
+	predefinedLiteralLengthsNorm = []int16{4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1}
+	predefinedMatchLengthsNorm = []int16{1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1, -1, -1}
+	predefinedOffsetsNorm = []int16{1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1}
+)
+
+// appendNCount appends the FSE table description of the normalized distribution, see
+// https://github.com/facebook/zstd/blob/dev/doc/zstd_compression_format.md#fse-table-description
+func appendNCount(out []byte, norm []int16, tableLog uint) []byte {
+	var (
+		tableSize = int16(1) << tableLog
+		bitStream = uint32(tableLog - 5)
+		bitCount  = uint(4)
+		remaining = tableSize + 1
+		threshold = tableSize
+		nbBits    = tableLog + 1
+		previous0 bool
+		symbol    int
+	)
+	flush := func() {
+		if bitCount > 16 {
+			out = append(out, byte(bitStream), byte(bitStream>>8))
+			bitStream >>= 16
+			bitCount -= 16
+		}
+	}
+	for remaining > 1 {
+		if previous0 {
+			start := symbol
+			for norm[symbol] == 0 {
+				symbol++
+			}
+			for symbol >= start+24 {
+				start += 24
+				bitStream += 0xFFFF << bitCount
+				out = append(out, byte(bitStream), byte(bitStream>>8))
+				bitStream >>= 16
+			}
+			for symbol >= start+3 {
+				start += 3
+				bitStream += 3 << bitCount
+				bitCount += 2
+			}
+			bitStream += uint32(symbol-start) << bitCount
+			bitCount += 2
+			flush()
+		}
+
+		count := norm[symbol]
+		symbol++
+		max := 2*threshold - 1 - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		count++ // -1 is written as 0
+		if count >= threshold {
+			count += max
+		}
+		bitStream += uint32(count) << bitCount
+		bitCount += nbBits
+		if count < max {
+			bitCount--
+		}
+		previous0 = count == 1
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+		flush()
+	}
+	out = append(out, byte(bitStream), byte(bitStream>>8))
+	return out[:len(out)-2+int((bitCount+7)/8)]
+}
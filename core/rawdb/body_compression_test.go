@@ -0,0 +1,244 @@
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+// testTxs - ERC-20 transfers to a few recipients with random signatures, like most of mainnet transactions
+func testTxs(t testing.TB, n int) ([]types.Transaction, [][]byte) {
+	rnd := rand.New(rand.NewSource(1))
+	token := common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
+	txs, rlps := make([]types.Transaction, n), make([][]byte, n)
+	for i := range txs {
+		data := common.FromHex("a9059cbb")
+		data = append(data, common.LeftPadBytes([]byte{byte(rnd.Intn(16)), 0xee}, 32)...)
+		data = append(data, common.LeftPadBytes(uint256.NewInt(uint64(rnd.Intn(1_000_000))*1e6).Bytes(), 32)...)
+		txn := &types.DynamicFeeTransaction{
+			CommonTx: types.CommonTx{ChainID: uint256.NewInt(1), Nonce: uint64(rnd.Intn(10_000)), Gas: 60_000, To: &token, Value: new(uint256.Int), Data: data},
+			Tip:      uint256.NewInt(1e9),
+			FeeCap:   uint256.NewInt(uint64(rnd.Intn(100)) * 1e9),
+		}
+		txn.V.SetUint64(uint64(rnd.Intn(2)))
+		txn.R.SetBytes(common.BytesToHash(randBytes(rnd, 32)).Bytes())
+		txn.S.SetBytes(common.BytesToHash(randBytes(rnd, 32)).Bytes())
+		var buf bytes.Buffer
+		require.NoError(t, txn.EncodeRLP(&buf))
+		txs[i], rlps[i] = txn, buf.Bytes()
+	}
+	return txs, rlps
+}
+
+func randBytes(rnd *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	rnd.Read(b)
+	return b
+}
+
+func TestTrainTxsDict(t *testing.T) {
+	_, samples := testTxs(t, 1000)
+	dict, err := TrainTxsDict(samples, 16*1024)
+	require.NoError(t, err)
+
+	// the dictionary is valid for zstd, not only for the codec of this package
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dict))
+	require.NoError(t, err)
+	defer dec.Close()
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderDict(dict))
+	require.NoError(t, err)
+	defer enc.Close()
+	var size, compressedSize int
+	for _, s := range samples {
+		compressed := enc.EncodeAll(s, nil)
+		decompressed, err := dec.DecodeAll(compressed, nil)
+		require.NoError(t, err)
+		require.Equal(t, s, decompressed)
+		size, compressedSize = size+len(s), compressedSize+len(compressed)
+	}
+	require.Less(t, compressedSize, size*3/4)
+
+	_, err = TrainTxsDict([][]byte{{1, 2, 3}}, 16*1024)
+	require.Error(t, err)
+}
+
+func TestTrainTxsDictIfMissing(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	_, samples := testTxs(t, 2*TxsDictMinSamples)
+	write := func(txs [][]byte) {
+		id, err := tx.IncrementSequence(kv.EthTx, uint64(len(txs)))
+		require.NoError(t, err)
+		require.NoError(t, WriteRawTransactions(tx, txs, id))
+	}
+
+	write(samples[:TxsDictMinSamples/2])
+	trained, err := TrainTxsDictIfMissing(tx)
+	require.NoError(t, err)
+	require.False(t, trained)
+
+	write(samples[TxsDictMinSamples/2 : TxsDictMinSamples*3/2])
+	trained, err = TrainTxsDictIfMissing(tx)
+	require.NoError(t, err)
+	require.True(t, trained)
+	dict, err := tx.GetOne(kv.DatabaseInfo, TxsDictKey)
+	require.NoError(t, err)
+	require.NotEmpty(t, dict)
+
+	// the dictionary isn't replaced, transactions written after it are compressed
+	write(samples[TxsDictMinSamples*3/2:])
+	trained, err = TrainTxsDictIfMissing(tx)
+	require.NoError(t, err)
+	require.False(t, trained)
+	v, err := tx.GetOne(kv.EthTx, dbutils.EncodeBlockNumber(uint64(len(samples)-1)))
+	require.NoError(t, err)
+	require.Equal(t, TxCompressedV1, v[0])
+}
+
+// TestTxsDictReferenceZstd checks the hand-built dictionary format against the reference implementation: frames
+// compressed with the dictionary are decoded by the zstd CLI, and frames of the zstd CLI using the dictionary (it loads
+// the entropy tables for that) are decoded by the codec of this package
+func TestTxsDictReferenceZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip(err)
+	}
+	_, samples := testTxs(t, 1000)
+	dict, err := TrainTxsDict(samples, 16*1024)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	dictFile := filepath.Join(dir, "dict")
+	require.NoError(t, os.WriteFile(dictFile, dict, 0600))
+	codec, err := txsCodecOf(dict)
+	require.NoError(t, err)
+
+	for i, s := range samples[:50] {
+		frame := codec.enc.EncodeAll(s, nil)
+		f := filepath.Join(dir, "txn.zst")
+		require.NoError(t, os.WriteFile(f, frame, 0600))
+		out, err := exec.Command("zstd", "-q", "-d", "-c", "-D", dictFile, f).Output()
+		require.NoError(t, err, i)
+		require.Equal(t, s, out, i)
+
+		f = filepath.Join(dir, "txn")
+		require.NoError(t, os.WriteFile(f, s, 0600))
+		frame, err = exec.Command("zstd", "-q", "-c", "-19", "-D", dictFile, f).Output()
+		require.NoError(t, err, i)
+		var h zstd.Header
+		require.NoError(t, h.Decode(frame))
+		require.Equal(t, binary.LittleEndian.Uint32(dict[4:8]), h.DictionaryID)
+		out, err = codec.dec.DecodeAll(frame, nil)
+		require.NoError(t, err, i)
+		require.Equal(t, s, out, i)
+	}
+}
+
+func TestCompressedTxs(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	txs, samples := testTxs(t, 1000)
+
+	// without the dictionary transactions are stored as-is
+	require.NoError(t, WriteTransactions(tx, txs[:10], 1))
+	v, err := tx.GetOne(kv.EthTx, dbutils.EncodeBlockNumber(1))
+	require.NoError(t, err)
+	require.Equal(t, samples[0], v)
+
+	dict, err := TrainTxsDict(samples, 16*1024)
+	require.NoError(t, err)
+	require.NoError(t, tx.Put(kv.DatabaseInfo, TxsDictKey, dict))
+	require.NoError(t, WriteTransactions(tx, txs[10:20], 11))
+	require.NoError(t, WriteRawTransactions(tx, samples[20:], 21))
+	v, err = tx.GetOne(kv.EthTx, dbutils.EncodeBlockNumber(11))
+	require.NoError(t, err)
+	require.Equal(t, TxCompressedV1, v[0])
+	require.Less(t, len(v), len(samples[10]))
+
+	read, err := CanonicalTransactions(tx, 1, uint32(len(txs)))
+	require.NoError(t, err)
+	require.Len(t, read, len(txs))
+	for i, txn := range read {
+		require.Equal(t, txs[i].Hash(), txn.Hash(), i)
+	}
+	txn, err := CanonicalTxnByID(tx, 500)
+	require.NoError(t, err)
+	require.Equal(t, txs[499].Hash(), txn.Hash())
+
+	// values compressed with an unknown dictionary aren't decoded as garbage
+	require.NoError(t, tx.Delete(kv.DatabaseInfo, TxsDictKey))
+	txsCodecs.Lock()
+	delete(txsCodecs.byID, binary.LittleEndian.Uint32(dict[4:8]))
+	txsCodecs.Unlock()
+	_, err = CanonicalTxnByID(tx, 500)
+	require.Error(t, err)
+}
+
+func benchmarkTxs(b *testing.B, compressed bool) (kv.RwTx, uint32, float64) {
+	_, tx := memdb.NewTestTx(b)
+	_, samples := testTxs(b, 10_000)
+	if compressed {
+		dict, err := TrainTxsDict(samples, TxsDictSize)
+		require.NoError(b, err)
+		require.NoError(b, tx.Put(kv.DatabaseInfo, TxsDictKey, dict))
+	}
+	require.NoError(b, WriteRawTransactions(tx, samples, 0))
+	var size int
+	require.NoError(b, tx.ForEach(kv.EthTx, nil, func(k, v []byte) error {
+		size += len(v)
+		return nil
+	}))
+	return tx, uint32(len(samples)), float64(size) / float64(len(samples))
+}
+
+func BenchmarkCanonicalTransactions(b *testing.B) {
+	for _, compressed := range []bool{false, true} {
+		name := "raw"
+		if compressed {
+			name = "zstd"
+		}
+		b.Run(name, func(b *testing.B) {
+			tx, amount, txnSize := benchmarkTxs(b, compressed)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := CanonicalTransactions(tx, 0, amount); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(txnSize, "B/txn")
+		})
+	}
+}
+
+func BenchmarkCompressTxn(b *testing.B) {
+	_, tx := memdb.NewTestTx(b)
+	_, samples := testTxs(b, 10_000)
+	dict, err := TrainTxsDict(samples, TxsDictSize)
+	require.NoError(b, err)
+	require.NoError(b, tx.Put(kv.DatabaseInfo, TxsDictKey, dict))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CompressTxn(tx, samples[i%len(samples)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTrainTxsDict(b *testing.B) {
+	_, samples := testTxs(b, 10_000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := TrainTxsDict(samples, TxsDictSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
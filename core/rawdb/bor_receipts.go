@@ -64,7 +64,7 @@ func WriteBorReceipt(tx kv.RwTx, hash common.Hash, number uint64, borReceipt *ty
 	// Convert the bor receipt into their storage form and serialize them
 	buf := bytes.NewBuffer(make([]byte, 0, 1024))
 	cbor.Marshal(buf, borReceipt.Logs)
-	if err := tx.Append(kv.Log, dbutils.LogKey(number, uint32(borReceipt.TransactionIndex)), buf.Bytes()); err != nil {
+	if err := tx.Put(kv.Log, dbutils.LogKey(number, uint32(borReceipt.TransactionIndex)), buf.Bytes()); err != nil {
 		return err
 	}
 
@@ -74,7 +74,7 @@ func WriteBorReceipt(tx kv.RwTx, hash common.Hash, number uint64, borReceipt *ty
 		return err
 	}
 	// Store the flattened receipt slice
-	if err := tx.Append(kv.BorReceipts, borReceiptKey(number), buf.Bytes()); err != nil {
+	if err := tx.Put(kv.BorReceipts, borReceiptKey(number), buf.Bytes()); err != nil {
 		return err
 	}
 
@@ -2,6 +2,7 @@ package rawdbreset
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -29,6 +30,9 @@ func ResetState(db kv.RwDB, ctx context.Context, chain string) error {
 	if err := db.Update(ctx, ResetLogIndex); err != nil {
 		return err
 	}
+	if err := db.Update(ctx, ResetTokenTransfers); err != nil {
+		return err
+	}
 	if err := db.Update(ctx, ResetCallTraces); err != nil {
 		return err
 	}
@@ -256,6 +260,19 @@ func ResetLogIndex(tx kv.RwTx) error {
 	return nil
 }
 
+func ResetTokenTransfers(tx kv.RwTx) error {
+	if err := tx.ClearBucket(rawdb.TokenTransferIndex); err != nil {
+		return err
+	}
+	if err := stages.SaveStageProgress(tx, stages.TokenTransfers, 0); err != nil {
+		return err
+	}
+	if err := stages.SaveStagePruneProgress(tx, stages.TokenTransfers, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
 func ResetCallTraces(tx kv.RwTx) error {
 	if err := tx.ClearBucket(kv.CallFromIndex); err != nil {
 		return err
@@ -285,6 +302,34 @@ func ResetTxLookup(tx kv.RwTx) error {
 	return nil
 }
 
+// ResetIssuance keeps totals of the genesis block: they are written with the genesis, not by the stage
+func ResetIssuance(tx kv.RwTx) error {
+	c, err := tx.RwCursor(kv.Issuance)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		// keys are the block number, or "burnt" followed by the block number
+		if binary.BigEndian.Uint64(k[len(k)-8:]) == 0 {
+			continue
+		}
+		if err := c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	if err := stages.SaveStageProgress(tx, stages.Issuance, 0); err != nil {
+		return err
+	}
+	if err := stages.SaveStagePruneProgress(tx, stages.Issuance, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
 func ResetFinish(tx kv.RwTx) error {
 	if err := stages.SaveStageProgress(tx, stages.Finish, 0); err != nil {
 		return err
@@ -0,0 +1,30 @@
+package rawdb
+
+import (
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// ChaindataTables - tables of the chain DB which erigon-lib doesn't know about
+var ChaindataTables = []string{
+	TokenTransferIndex,
+}
+
+// ChaindataTablesCfg - kv.TableCfgFunc of the chain DB: the tables of erigon-lib and ChaindataTables. Every opener
+// of the chain DB passes it to WithTableCfg, otherwise the tables aren't opened.
+func ChaindataTablesCfg(defaultBuckets kv.TableCfg) kv.TableCfg {
+	cfg := make(kv.TableCfg, len(defaultBuckets)+len(ChaindataTables))
+	for name, item := range defaultBuckets {
+		cfg[name] = item
+	}
+	for _, name := range ChaindataTables {
+		cfg[name] = kv.TableCfgItem{}
+	}
+	return cfg
+}
+
+// NewMemDB - in-memory chain DB, like memdb.New() with ChaindataTables
+func NewMemDB() kv.RwDB {
+	return mdbx.NewMDBX(log.New()).InMem("").WithTableCfg(ChaindataTablesCfg).MustOpen()
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+)
+
+// ReceiptsEncoder encodes receipts of a block on worker goroutines while the next transactions
+// of the block are executed: consensus RLP (for the receipts root) and CBOR of logs (for kv.Log).
+// Used by the execution stage when receipts are persisted - then encoding is paid anyway and
+// it doesn't have to be done sequentially after execution of the block.
+// Reset, Add and Wait must be called from one goroutine.
+type ReceiptsEncoder struct {
+	sem chan struct{} // limits amount of busy workers
+	wg  sync.WaitGroup
+
+	receipts  types.Receipts
+	consensus [][]byte // RLP of receipts, by index in the block
+	logs      [][]byte // CBOR of logs of receipts, nil for receipts without logs
+	errs      []error
+}
+
+func NewReceiptsEncoder(workers int) *ReceiptsEncoder {
+	if workers < 1 {
+		workers = 1
+	}
+	return &ReceiptsEncoder{sem: make(chan struct{}, workers)}
+}
+
+// Reset prepares encoder for a block with txCount transactions
+func (e *ReceiptsEncoder) Reset(txCount int) {
+	e.wg.Wait()
+	e.receipts = make(types.Receipts, 0, txCount)
+	e.consensus = make([][]byte, txCount)
+	e.logs = make([][]byte, txCount)
+	e.errs = make([]error, txCount)
+}
+
+// Add schedules encoding of the next receipt of the block. Receipt must not be modified after it.
+func (e *ReceiptsEncoder) Add(r *types.Receipt) {
+	i := len(e.receipts)
+	e.receipts = append(e.receipts, r)
+	if i >= len(e.consensus) { // more receipts than announced by Reset - grow when workers don't write
+		e.wg.Wait()
+		e.consensus = append(e.consensus, make([][]byte, i+1-len(e.consensus))...)
+		e.logs = append(e.logs, make([][]byte, i+1-len(e.logs))...)
+		e.errs = append(e.errs, make([]error, i+1-len(e.errs))...)
+	}
+	consensus, logs, errs := e.consensus, e.logs, e.errs
+	e.sem <- struct{}{}
+	e.wg.Add(1)
+	go func() {
+		defer func() { <-e.sem; e.wg.Done() }()
+		var buf bytes.Buffer
+		types.Receipts{r}.EncodeIndex(0, &buf)
+		consensus[i] = buf.Bytes()
+		if len(r.Logs) == 0 {
+			return
+		}
+		var logsBuf bytes.Buffer
+		if err := cbor.Marshal(&logsBuf, r.Logs); err != nil {
+			errs[i] = fmt.Errorf("encode logs of receipt %d: %w", i, err)
+			return
+		}
+		logs[i] = logsBuf.Bytes()
+	}()
+}
+
+// Wait waits for encoding of all added receipts. Receipts root and CBOR of the whole block
+// receipts (for kv.Receipts) are computed concurrently.
+func (e *ReceiptsEncoder) Wait() (*EncodedReceipts, error) {
+	e.wg.Wait()
+	n := len(e.receipts)
+	for _, err := range e.errs[:n] {
+		if err != nil {
+			return nil, err
+		}
+	}
+	res := &EncodedReceipts{Receipts: e.receipts, Logs: e.logs[:n]}
+	var blockErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var buf bytes.Buffer
+		if blockErr = cbor.Marshal(&buf, res.Receipts); blockErr == nil {
+			res.Block = buf.Bytes()
+		}
+	}()
+	res.Root = types.DeriveSha(encodedList(e.consensus[:n]))
+	<-done
+	if blockErr != nil {
+		return nil, fmt.Errorf("encode block receipts: %w", blockErr)
+	}
+	return res, nil
+}
+
+// EncodedReceipts - receipts of a block with their encodings
+type EncodedReceipts struct {
+	Receipts types.Receipts
+	Root     common.Hash // the same as types.DeriveSha(Receipts)
+	Logs     [][]byte    // CBOR of logs of receipts, nil for receipts without logs
+	Block    []byte      // CBOR of Receipts
+}
+
+// encodedList - types.DerivableList of already encoded items
+type encodedList [][]byte
+
+func (l encodedList) Len() int { return len(l) }
+
+func (l encodedList) EncodeIndex(i int, w *bytes.Buffer) { w.Write(l[i]) }
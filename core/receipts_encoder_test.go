@@ -0,0 +1,53 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptsEncoder(t *testing.T) {
+	receipts := types.Receipts{
+		{Type: types.LegacyTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000},
+		{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusFailed, CumulativeGasUsed: 50000, Logs: []*types.Log{
+			{Address: common.HexToAddress("0x01"), Topics: []common.Hash{common.HexToHash("0x02")}, Data: []byte{3}},
+		}},
+		{Type: types.AccessListTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 90000, Logs: []*types.Log{
+			{Address: common.HexToAddress("0x04"), Data: []byte{5, 6}},
+			{Address: common.HexToAddress("0x07")},
+		}},
+	}
+	for _, r := range receipts {
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+	}
+
+	e := NewReceiptsEncoder(2)
+	for block := 0; block < 2; block++ { // encoder is reused between blocks
+		e.Reset(1) // less than added - encoder must grow
+		for _, r := range receipts {
+			e.Add(r)
+		}
+		encoded, err := e.Wait()
+		require.NoError(t, err)
+		require.Equal(t, types.DeriveSha(receipts), encoded.Root)
+
+		var buf bytes.Buffer
+		require.NoError(t, cbor.Marshal(&buf, receipts))
+		require.Equal(t, buf.Bytes(), encoded.Block)
+		require.Nil(t, encoded.Logs[0])
+		for i := 1; i < len(receipts); i++ {
+			buf.Reset()
+			require.NoError(t, cbor.Marshal(&buf, receipts[i].Logs))
+			require.Equal(t, buf.Bytes(), encoded.Logs[i])
+		}
+	}
+
+	e.Reset(0)
+	encoded, err := e.Wait()
+	require.NoError(t, err)
+	require.Equal(t, types.DeriveSha(types.Receipts{}), encoded.Root)
+}
@@ -0,0 +1,250 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+// RecentStatesCfg - how many of the last executed blocks keep their state changes in memory, see RecentStates
+type RecentStatesCfg struct {
+	Blocks int               // 0 - disabled
+	Memory datasize.ByteSize // the oldest blocks are dropped above it
+}
+
+var DefaultRecentStatesCfg = RecentStatesCfg{Memory: 256 * datasize.MB}
+
+// approximate memory of a map entry besides the key and the value
+const recentStateEntryOverhead = 48
+
+// RecentStates keeps values of accounts and storage changed by the last executed blocks, as they were before each
+// block. The state after a recent block is the latest state overlaid by the values of the following blocks, it's read
+// without walking history indices and changesets in the db. Blocks are added by the Execution stage, readers are
+// created by the rpcdaemon embedded into the same process. Thread-safe.
+type RecentStates struct {
+	cfg RecentStatesCfg
+
+	lock   sync.RWMutex
+	blocks []*recentBlock // contiguous, ascending by number, every block is a child of the previous one
+	size   uint64
+}
+
+// recentBlock - changes of a block, never modified after it's added
+type recentBlock struct {
+	number     uint64
+	hash       common.Hash
+	parentHash common.Hash
+	accounts   map[common.Address][]byte // encoded for storage without code hash, empty - the account didn't exist
+	storage    map[string][]byte         // by the composite key: address, incarnation, location
+	size       uint64
+}
+
+// NewRecentStates returns nil if cfg.Blocks is 0, methods of nil RecentStates are no-op
+func NewRecentStates(cfg RecentStatesCfg) *RecentStates {
+	if cfg.Blocks <= 0 {
+		return nil
+	}
+	return &RecentStates{cfg: cfg}
+}
+
+// Add keeps the changes of the executed block, csw must not be used after it. A block which doesn't continue the kept
+// ones (after a rolled back transaction or a reorg) replaces the blocks from its number on, or all of them if its parent
+// isn't kept. Nil csw - the block is executed without changesets, the kept blocks are dropped then.
+func (r *RecentStates) Add(header *types.Header, csw *ChangeSetWriter) {
+	if r == nil {
+		return
+	}
+	if csw == nil {
+		r.Reset()
+		return
+	}
+	block := &recentBlock{
+		number:     header.Number.Uint64(),
+		hash:       header.Hash(),
+		parentHash: header.ParentHash,
+		accounts:   csw.accountChanges,
+		storage:    csw.storageChanges,
+	}
+	for _, v := range block.accounts {
+		block.size += common.AddressLength + uint64(len(v)) + recentStateEntryOverhead
+	}
+	for k, v := range block.storage {
+		block.size += uint64(len(k)+len(v)) + recentStateEntryOverhead
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.truncate(block.number - 1)
+	if n := len(r.blocks); n > 0 && (r.blocks[n-1].number+1 != block.number || r.blocks[n-1].hash != block.parentHash) {
+		r.blocks, r.size = nil, 0
+	}
+	r.blocks = append(r.blocks, block)
+	r.size += block.size
+	for len(r.blocks) > 0 && (len(r.blocks) > r.cfg.Blocks || r.size > uint64(r.cfg.Memory)) {
+		r.size -= r.blocks[0].size
+		r.blocks[0] = nil
+		r.blocks = r.blocks[1:]
+	}
+}
+
+// Wanted reports if the block is among the kept ones when blocks up to head are executed
+func (r *RecentStates) Wanted(number, head uint64) bool {
+	return r != nil && number+uint64(r.cfg.Blocks) > head
+}
+
+// Unwind drops the blocks above unwindPoint
+func (r *RecentStates) Unwind(unwindPoint uint64) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.truncate(unwindPoint)
+}
+
+// Reset drops all blocks
+func (r *RecentStates) Reset() {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.blocks, r.size = nil, 0
+}
+
+// Range returns the first and the last kept blocks, ok is false if there are none
+func (r *RecentStates) Range() (from, to uint64, ok bool) {
+	if r == nil {
+		return 0, 0, false
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if len(r.blocks) == 0 {
+		return 0, 0, false
+	}
+	return r.blocks[0].number, r.blocks[len(r.blocks)-1].number, true
+}
+
+// truncate drops the blocks above number, must be called under the write lock
+func (r *RecentStates) truncate(number uint64) {
+	for n := len(r.blocks); n > 0 && r.blocks[n-1].number > number; n = len(r.blocks) {
+		r.size -= r.blocks[n-1].size
+		r.blocks[n-1] = nil
+		r.blocks = r.blocks[:n-1]
+	}
+}
+
+// Reader returns a reader of the state after blockNumber, the latest state of tx is after head with headHash.
+// It's nil if blocks after blockNumber up to head aren't kept, the state has to be read from history then.
+func (r *RecentStates) Reader(tx kv.Tx, blockNumber, head uint64, headHash common.Hash) *RecentStateReader {
+	if r == nil || blockNumber > head {
+		return nil
+	}
+	if blockNumber == head {
+		return &RecentStateReader{tx: tx}
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if len(r.blocks) == 0 {
+		return nil
+	}
+	first := r.blocks[0].number
+	if first > blockNumber+1 || head-first >= uint64(len(r.blocks)) || r.blocks[head-first].hash != headHash {
+		return nil
+	}
+	blocks := make([]*recentBlock, head-blockNumber)
+	copy(blocks, r.blocks[blockNumber+1-first:head+1-first])
+	return &RecentStateReader{tx: tx, blocks: blocks}
+}
+
+var _ StateReader = (*RecentStateReader)(nil)
+
+// RecentStateReader reads the state after a recent block: the first value of a key in the blocks after it,
+// or the latest value if they didn't change it.
+type RecentStateReader struct {
+	tx     kv.Tx
+	blocks []*recentBlock // after the read block up to the latest state of tx
+}
+
+func (r *RecentStateReader) readAccount(address common.Address, blocks []*recentBlock) ([]byte, error) {
+	for _, b := range blocks {
+		if enc, ok := b.accounts[address]; ok {
+			return enc, nil
+		}
+	}
+	return r.tx.GetOne(kv.PlainState, address[:])
+}
+
+func (r *RecentStateReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	enc, err := r.readAccount(address, r.blocks)
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) == 0 {
+		return nil, nil
+	}
+	var a accounts.Account
+	if err = a.DecodeForStorage(enc); err != nil {
+		return nil, err
+	}
+	//restore codehash
+	if a.Incarnation > 0 && a.IsEmptyCodeHash() {
+		codeHash, err := r.tx.GetOne(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], a.Incarnation))
+		if err != nil {
+			return nil, err
+		}
+		if len(codeHash) > 0 {
+			a.CodeHash = common.BytesToHash(codeHash)
+		}
+	}
+	return &a, nil
+}
+
+func (r *RecentStateReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	compositeKey := dbutils.PlainGenerateCompositeStorageKey(address.Bytes(), incarnation, key.Bytes())
+	for _, b := range r.blocks {
+		if enc, ok := b.storage[string(compositeKey)]; ok {
+			if len(enc) == 0 {
+				return nil, nil
+			}
+			return enc, nil
+		}
+	}
+	return NewPlainStateReader(r.tx).ReadAccountStorage(address, incarnation, key)
+}
+
+func (r *RecentStateReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	return NewPlainStateReader(r.tx).ReadAccountCode(address, incarnation, codeHash)
+}
+
+func (r *RecentStateReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	return NewPlainStateReader(r.tx).ReadAccountCodeSize(address, incarnation, codeHash)
+}
+
+// ReadAccountIncarnation - like PlainState, the incarnation before the account in the state after the next block
+func (r *RecentStateReader) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	var blocks []*recentBlock
+	if len(r.blocks) > 0 {
+		blocks = r.blocks[1:]
+	}
+	enc, err := r.readAccount(address, blocks)
+	if err != nil {
+		return 0, err
+	}
+	if len(enc) == 0 {
+		return 0, nil
+	}
+	var acc accounts.Account
+	if err = acc.DecodeForStorage(enc); err != nil {
+		return 0, err
+	}
+	if acc.Incarnation == 0 {
+		return 0, nil
+	}
+	return acc.Incarnation - 1, nil
+}
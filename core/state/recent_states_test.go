@@ -0,0 +1,115 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentStates(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	recent := NewRecentStates(RecentStatesCfg{Blocks: 4, Memory: datasize.MB})
+	alice, bob, contract := common.Address{1}, common.Address{2}, common.Address{3}
+	slot := common.Hash{1}
+
+	const blocks = 6
+	headers := make([]*types.Header, blocks+1)
+	headers[0] = &types.Header{Number: big.NewInt(0)}
+	for n := 1; n <= blocks; n++ {
+		ibs := New(NewPlainStateReader(tx))
+		w := NewPlainStateWriter(tx, tx, uint64(n))
+		ibs.AddBalance(alice, uint256.NewInt(uint64(n)))
+		switch n {
+		case 2, 5: // the contract is created again after it's destructed
+			ibs.CreateAccount(contract, true)
+			ibs.SetCode(contract, []byte{byte(n)})
+			ibs.SetState(contract, &slot, *uint256.NewInt(uint64(n)))
+		case 3:
+			ibs.AddBalance(bob, uint256.NewInt(1))
+			ibs.SetState(contract, &slot, *uint256.NewInt(3))
+		case 4:
+			ibs.Suicide(contract)
+		}
+		require.NoError(t, ibs.CommitBlock(&params.Rules{}, w))
+		require.NoError(t, w.WriteChangeSets())
+		require.NoError(t, w.WriteHistory())
+		headers[n] = &types.Header{Number: big.NewInt(int64(n)), ParentHash: headers[n-1].Hash()}
+		recent.Add(headers[n], w.ChangeSetWriter())
+	}
+	from, to, ok := recent.Range()
+	require.True(t, ok)
+	require.Equal(t, []uint64{3, 6}, []uint64{from, to})
+
+	head := headers[blocks].Hash()
+	for b := uint64(0); b <= blocks; b++ {
+		r := recent.Reader(tx, b, blocks, head)
+		if b+1 < from {
+			require.Nil(t, r, b)
+			continue
+		}
+		require.NotNil(t, r, b)
+		history := NewPlainState(tx, b+1)
+		for _, address := range []common.Address{alice, bob, contract} {
+			expected, err := history.ReadAccountData(address)
+			require.NoError(t, err)
+			actual, err := r.ReadAccountData(address)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual, "block %d account %x", b, address)
+		}
+		if acc, err := history.ReadAccountData(contract); err == nil && acc != nil {
+			expected, err := history.ReadAccountStorage(contract, acc.Incarnation, &slot)
+			require.NoError(t, err)
+			actual, err := r.ReadAccountStorage(contract, acc.Incarnation, &slot)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual, "block %d incarnation %d", b, acc.Incarnation)
+		}
+		expected, err := history.ReadAccountIncarnation(contract)
+		require.NoError(t, err)
+		actual, err := r.ReadAccountIncarnation(contract)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual, "block %d", b)
+	}
+	require.Nil(t, recent.Reader(tx, 4, blocks, common.Hash{1}), "another chain")
+	require.Nil(t, recent.Reader(tx, 4, blocks+1, head), "the head isn't kept")
+
+	recent.Unwind(4)
+	require.Nil(t, recent.Reader(tx, 3, blocks, head))
+	require.NotNil(t, recent.Reader(tx, 3, 4, headers[4].Hash()))
+
+	// a block of another chain replaces the kept ones from its number on
+	fork := &types.Header{Number: big.NewInt(4), ParentHash: headers[3].Hash(), Extra: []byte("fork")}
+	recent.Add(fork, NewChangeSetWriter())
+	from, to, _ = recent.Range()
+	require.Equal(t, []uint64{3, 4}, []uint64{from, to})
+	require.Nil(t, recent.Reader(tx, 3, 4, headers[4].Hash()))
+	require.NotNil(t, recent.Reader(tx, 3, 4, fork.Hash()))
+
+	// a block which doesn't continue the kept ones drops them
+	recent.Add(headers[6], NewChangeSetWriter())
+	from, to, _ = recent.Range()
+	require.Equal(t, []uint64{6, 6}, []uint64{from, to})
+
+	// a block executed without changesets
+	recent.Add(&types.Header{Number: big.NewInt(7), ParentHash: headers[6].Hash()}, nil)
+	_, _, ok = recent.Range()
+	require.False(t, ok)
+
+	// above the memory limit
+	recent = NewRecentStates(RecentStatesCfg{Blocks: 4, Memory: 1})
+	csw := NewChangeSetWriter()
+	require.NoError(t, csw.UpdateAccountData(alice, new(accounts.Account), new(accounts.Account)))
+	require.NoError(t, csw.WriteAccountStorage(contract, 1, &slot, uint256.NewInt(0), uint256.NewInt(1)))
+	recent.Add(headers[1], csw)
+	_, _, ok = recent.Range()
+	require.False(t, ok)
+
+	require.Nil(t, NewRecentStates(RecentStatesCfg{}))
+}
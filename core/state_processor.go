@@ -99,7 +99,14 @@ func ApplyTransaction(config *params.ChainConfig, blockHashFunc func(n uint64) c
 		vmenv = &vm.CVMAdapter{Cvm: vm.NewCVM(ibs)}
 	} else {
 		blockContext := NewEVMBlockContext(header, blockHashFunc, engine, author)
-		vmenv = vm.NewEVM(blockContext, vm.TxContext{}, ibs, config, cfg)
+		if cfg.Debug {
+			// tracers may keep the EVM after the transaction
+			vmenv = vm.NewEVM(blockContext, vm.TxContext{}, ibs, config, cfg)
+		} else {
+			evm := vm.AcquireEVM(blockContext, vm.TxContext{}, ibs, config, cfg)
+			defer vm.ReleaseEVM(evm)
+			vmenv = evm
+		}
 	}
 
 	return applyTransaction(config, gp, ibs, stateWriter, header, tx, usedGas, vmenv, cfg)
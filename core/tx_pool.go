@@ -75,6 +75,7 @@ type TxPoolConfig struct {
 	GlobalBaseFeeQueue uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime      time.Duration // Maximum amount of time non-executable transaction are queued
+	CommitEvery   time.Duration // How often the pool is saved to its DB, it's restored from it on restart
 	StartOnInit   bool
 	TracedSenders []string // List of senders for which tx pool should print out debugging info
 }
@@ -91,7 +92,8 @@ var DeprecatedDefaultTxPoolConfig = TxPoolConfig{
 	AccountQueue:       64,
 	GlobalQueue:        30_000,
 
-	Lifetime: 3 * time.Hour,
+	Lifetime:    3 * time.Hour,
+	CommitEvery: 5 * time.Minute,
 }
 
 var DefaultTxPool2Config = func(pool1Cfg TxPoolConfig) txpool.Config {
@@ -103,7 +105,7 @@ var DefaultTxPool2Config = func(pool1Cfg TxPoolConfig) txpool.Config {
 	cfg.MinFeeCap = pool1Cfg.PriceLimit
 	cfg.AccountSlots = pool1Cfg.AccountSlots
 	cfg.LogEvery = 1 * time.Minute
-	cfg.CommitEvery = 5 * time.Minute
+	cfg.CommitEvery = pool1Cfg.CommitEvery
 	cfg.TracedSenders = pool1Cfg.TracedSenders
 	return cfg
 }
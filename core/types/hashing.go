@@ -80,6 +80,30 @@ func DeriveSha(list DerivableList) common.Hash {
 	return hash
 }
 
+// DeriveShaProof returns the root of the list like DeriveSha, and the proof of the item at index against it: the RLP
+// encoded trie nodes on the path from the root to the item. The key of the item in the trie is the RLP encoding of index.
+func DeriveShaProof(list DerivableList, index int) (common.Hash, [][]byte, error) {
+	if index < 0 || index >= list.Len() {
+		return common.Hash{}, nil, fmt.Errorf("index %d out of range [0, %d)", index, list.Len())
+	}
+	var key, value bytes.Buffer
+	t := trie.NewTestRLPTrie(common.Hash{})
+	for i := 0; i < list.Len(); i++ {
+		key.Reset()
+		value.Reset()
+		encodeUint(uint(i), &key)
+		list.EncodeIndex(i, &value)
+		t.Update(common.CopyBytes(key.Bytes()), common.CopyBytes(value.Bytes()))
+	}
+	key.Reset()
+	encodeUint(uint(index), &key)
+	proof, err := t.Prove(key.Bytes(), 0, false)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	return t.Hash(), proof, nil
+}
+
 type bytesWriter interface {
 	WriteByte(byte) error
 }
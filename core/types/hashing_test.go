@@ -8,6 +8,7 @@ import (
 	"github.com/holiman/uint256"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/trie"
 )
@@ -58,6 +59,106 @@ func TestDeriveSha(t *testing.T) {
 	}
 }
 
+func TestDeriveShaProof(t *testing.T) {
+	receipts := Receipts{}
+	for i := 0; i < 300; i++ {
+		receipts = append(receipts, &Receipt{
+			Type:              byte(i % 3),
+			Status:            ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(i),
+			Logs:              []*Log{{Address: common.Address{byte(i)}, Data: make([]byte, i%40)}},
+		})
+	}
+	for _, list := range []DerivableList{genTransactions(1), genTransactions(2), genTransactions(200), receipts} {
+		for _, index := range []int{0, 1, list.Len() / 2, 127, 128, list.Len() - 1} {
+			if index >= list.Len() {
+				continue
+			}
+			root, proof, err := DeriveShaProof(list, index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if root != DeriveSha(list) {
+				t.Fatalf("unexpected root: %x (expected: %x)", root, DeriveSha(list))
+			}
+			var key, value bytes.Buffer
+			encodeUint(uint(index), &key)
+			list.EncodeIndex(index, &value)
+			proved, err := verifyProof(root, key.Bytes(), proof)
+			if err != nil {
+				t.Fatalf("index %d of %d: %v", index, list.Len(), err)
+			}
+			if !bytes.Equal(proved, value.Bytes()) {
+				t.Fatalf("index %d of %d: unexpected value %x (expected: %x)", index, list.Len(), proved, value.Bytes())
+			}
+		}
+	}
+	if _, _, err := DeriveShaProof(receipts, len(receipts)); err == nil {
+		t.Fatal("expected an error for the index out of range")
+	}
+}
+
+// verifyProof follows the path of key from the root through the proof nodes and returns the value at its end
+func verifyProof(root common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	var path []byte
+	for _, b := range key {
+		path = append(path, b/16, b%16)
+	}
+	ref := rlp.RawValue(nil) // an embedded node, or nil if the node is referenced by its hash
+	hash := root
+	for i, node := range proof {
+		if ref != nil && !bytes.Equal(ref, node) || ref == nil && crypto.Keccak256Hash(node) != hash {
+			return nil, fmt.Errorf("node %d doesn't match its reference", i)
+		}
+		var items []rlp.RawValue
+		if err := rlp.DecodeBytes(node, &items); err != nil {
+			return nil, fmt.Errorf("node %d: %w", i, err)
+		}
+		var child rlp.RawValue
+		switch len(items) {
+		case 17:
+			if len(path) == 0 {
+				return nil, fmt.Errorf("node %d: the path ends at a branch", i)
+			}
+			child, path = items[path[0]], path[1:]
+		case 2:
+			var compact []byte
+			if err := rlp.DecodeBytes(items[0], &compact); err != nil {
+				return nil, fmt.Errorf("node %d: %w", i, err)
+			}
+			nibbles := []byte{}
+			if compact[0]&0x10 != 0 {
+				nibbles = append(nibbles, compact[0]&0x0f)
+			}
+			for _, b := range compact[1:] {
+				nibbles = append(nibbles, b/16, b%16)
+			}
+			if !bytes.HasPrefix(path, nibbles) {
+				return nil, fmt.Errorf("node %d: the path diverges", i)
+			}
+			child, path = items[1], path[len(nibbles):]
+			if compact[0]&0x20 != 0 { // leaf
+				if len(path) != 0 || i != len(proof)-1 {
+					return nil, fmt.Errorf("node %d: the leaf isn't at the end of the path", i)
+				}
+				var value []byte
+				if err := rlp.DecodeBytes(child, &value); err != nil {
+					return nil, fmt.Errorf("node %d: %w", i, err)
+				}
+				return value, nil
+			}
+		default:
+			return nil, fmt.Errorf("node %d: %d items", i, len(items))
+		}
+		if kind, content, _, err := rlp.Split(child); err == nil && kind == rlp.String && len(content) == 32 {
+			ref, hash = nil, common.BytesToHash(content)
+		} else {
+			ref = child
+		}
+	}
+	return nil, fmt.Errorf("the proof ends before the leaf")
+}
+
 func checkDeriveSha(t *testing.T, list DerivableList) {
 	legacySha := legacyDeriveSha(list)
 	deriveSha := DeriveSha(list)
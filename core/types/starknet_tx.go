@@ -170,7 +170,7 @@ func (tx StarknetTransaction) FakeSign(address common.Address) (Transaction, err
 	panic("implement me")
 }
 
-func (tx StarknetTransaction) Hash() common.Hash {
+func (tx *StarknetTransaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return *hash.(*common.Hash)
 	}
@@ -23,6 +23,8 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -262,6 +264,39 @@ func (s Transactions) EncodeIndex(i int, w *bytes.Buffer) {
 	}
 }
 
+// parallelHashThreshold - shorter lists are hashed by the caller, goroutines cost more than they save
+const parallelHashThreshold = 256
+
+// Hashes returns hashes of the transactions, the missing ones are computed on all CPUs for long lists (keccak of
+// large blocks shows up in sync and rpc profiles) and cached in the transactions
+func (s Transactions) Hashes() []common.Hash {
+	hashes := make([]common.Hash, len(s))
+	workers := runtime.GOMAXPROCS(0)
+	if len(s) < parallelHashThreshold || workers == 1 {
+		for i, txn := range s {
+			hashes[i] = txn.Hash()
+		}
+		return hashes
+	}
+	chunk := (len(s) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for from := 0; from < len(s); from += chunk {
+		to := from + chunk
+		if to > len(s) {
+			to = len(s)
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			for i := from; i < to; i++ {
+				hashes[i] = s[i].Hash()
+			}
+		}(from, to)
+	}
+	wg.Wait()
+	return hashes
+}
+
 // TransactionsGroupedBySender - lists of transactions grouped by sender
 type TransactionsGroupedBySender []Transactions
 
@@ -575,3 +575,55 @@ func assertEqual(orig Transaction, cpy Transaction) error {
 	}
 	return nil
 }
+
+func hashTestTxs(n int) Transactions {
+	txs := make(Transactions, n)
+	for i := range txs {
+		if i%2 == 0 {
+			txs[i] = NewTransaction(uint64(i), testAddr, uint256.NewInt(uint64(i)), 21000, u256.Num1, make([]byte, 100))
+		} else {
+			txs[i] = &DynamicFeeTransaction{
+				CommonTx: CommonTx{ChainID: u256.Num1, Nonce: uint64(i), To: &testAddr, Value: uint256.NewInt(uint64(i)), Gas: 21000, Data: make([]byte, 100)},
+				Tip:      u256.Num1,
+				FeeCap:   uint256.NewInt(10),
+			}
+		}
+	}
+	return txs
+}
+
+func TestTransactionsHashes(t *testing.T) {
+	for _, n := range []int{0, 1, parallelHashThreshold - 1, parallelHashThreshold, 3*parallelHashThreshold + 7} {
+		expected := hashTestTxs(n)
+		txs := hashTestTxs(n)
+		hashes := txs.Hashes()
+		assert.Len(t, hashes, n)
+		for i := range txs {
+			assert.Equal(t, expected[i].Hash(), hashes[i], "tx %d of %d", i, n)
+			assert.Equal(t, hashes[i], txs[i].Hash())
+		}
+	}
+}
+
+func BenchmarkTransactionsHashes(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("sequential/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				txs := hashTestTxs(n) // hashes are cached, every iteration hashes new transactions
+				b.StartTimer()
+				for _, txn := range txs {
+					txn.Hash()
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("parallel/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				txs := hashTestTxs(n)
+				b.StartTimer()
+				txs.Hashes()
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ package vm
 
 import (
 	"math/big"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -156,6 +157,46 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, state IntraBlockState, chain
 	return evm
 }
 
+var evmPool = sync.Pool{New: func() interface{} { return &EVM{} }}
+
+// AcquireEVM - NewEVM reusing an EVM (and its interpreter) released by ReleaseEVM, for hot paths which execute
+// a transaction per EVM. The EVM must not be used after ReleaseEVM.
+func AcquireEVM(blockCtx BlockContext, txCtx TxContext, state IntraBlockState, chainConfig *params.ChainConfig, vmConfig Config) *EVM {
+	if len(vmConfig.ExtraEips) > 0 {
+		return NewEVM(blockCtx, txCtx, state, chainConfig, vmConfig)
+	}
+	evm := evmPool.Get().(*EVM)
+	in, _ := evm.interpreter.(*EVMInterpreter)
+	*evm = EVM{
+		context:         blockCtx,
+		txContext:       txCtx,
+		intraBlockState: state,
+		config:          vmConfig,
+		chainConfig:     chainConfig,
+		chainRules:      chainConfig.Rules(blockCtx.BlockNumber),
+	}
+	if in == nil {
+		evm.interpreter = NewEVMInterpreter(evm, vmConfig)
+		return evm
+	}
+	*in.VM = VM{evm: evm, cfg: vmConfig}
+	in.jt = jumpTable(evm.chainRules)
+	evm.interpreter = in
+	return evm
+}
+
+// ReleaseEVM returns an EVM of AcquireEVM to the pool. EVMs with a custom interpreter aren't reused.
+func ReleaseEVM(evm *EVM) {
+	in, ok := evm.interpreter.(*EVMInterpreter)
+	if !ok || len(evm.config.ExtraEips) > 0 {
+		return
+	}
+	// drop references to the block, the state and the tracer, they shouldn't live as long as the pool
+	*in.VM = VM{}
+	*evm = EVM{interpreter: in}
+	evmPool.Put(evm)
+}
+
 // Reset resets the EVM with a new transaction context.Reset
 // This is not threadsafe and should only be done very cautiously.
 func (evm *EVM) Reset(txCtx TxContext, ibs IntraBlockState) {
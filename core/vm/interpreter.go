@@ -18,12 +18,15 @@ package vm
 
 import (
 	"hash"
+	"sync"
 	"sync/atomic"
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/core/vm/stack"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/crypto/sha3"
 )
 
 // Config are the configuration options for the Interpreter
@@ -66,6 +69,9 @@ type keccakState interface {
 	Read([]byte) (int, error)
 }
 
+// hasherPool - Keccak256 hashers of VMs which finished their top-level call
+var hasherPool = sync.Pool{New: func() interface{} { return sha3.NewLegacyKeccak256() }}
+
 // EVMInterpreter represents an EVM interpreter
 type EVMInterpreter struct {
 	*VM
@@ -86,33 +92,37 @@ type VM struct {
 	returnData []byte // Last CALL's return data for subsequent reuse
 }
 
-// NewEVMInterpreter returns a new instance of the Interpreter.
-func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
-	var jt *JumpTable
+// jumpTable returns the instruction set of the fork
+func jumpTable(rules *params.Rules) *JumpTable {
 	switch {
-	case evm.ChainRules().IsCancun:
-		jt = &cancunInstructionSet
-	case evm.ChainRules().IsShanghai:
-		jt = &shanghaiInstructionSet
-	case evm.ChainRules().IsLondon:
-		jt = &londonInstructionSet
-	case evm.ChainRules().IsBerlin:
-		jt = &berlinInstructionSet
-	case evm.ChainRules().IsIstanbul:
-		jt = &istanbulInstructionSet
-	case evm.ChainRules().IsConstantinople:
-		jt = &constantinopleInstructionSet
-	case evm.ChainRules().IsByzantium:
-		jt = &byzantiumInstructionSet
-	case evm.ChainRules().IsSpuriousDragon:
-		jt = &spuriousDragonInstructionSet
-	case evm.ChainRules().IsTangerineWhistle:
-		jt = &tangerineWhistleInstructionSet
-	case evm.ChainRules().IsHomestead:
-		jt = &homesteadInstructionSet
+	case rules.IsCancun:
+		return &cancunInstructionSet
+	case rules.IsShanghai:
+		return &shanghaiInstructionSet
+	case rules.IsLondon:
+		return &londonInstructionSet
+	case rules.IsBerlin:
+		return &berlinInstructionSet
+	case rules.IsIstanbul:
+		return &istanbulInstructionSet
+	case rules.IsConstantinople:
+		return &constantinopleInstructionSet
+	case rules.IsByzantium:
+		return &byzantiumInstructionSet
+	case rules.IsSpuriousDragon:
+		return &spuriousDragonInstructionSet
+	case rules.IsTangerineWhistle:
+		return &tangerineWhistleInstructionSet
+	case rules.IsHomestead:
+		return &homesteadInstructionSet
 	default:
-		jt = &frontierInstructionSet
+		return &frontierInstructionSet
 	}
+}
+
+// NewEVMInterpreter returns a new instance of the Interpreter.
+func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
+	jt := jumpTable(evm.ChainRules())
 	if len(cfg.ExtraEips) > 0 {
 		for i, eip := range cfg.ExtraEips {
 			if err := EnableEIP(eip, jt); err != nil {
@@ -133,31 +143,7 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 }
 
 func NewEVMInterpreterByVM(vm *VM) *EVMInterpreter {
-	var jt *JumpTable
-	switch {
-	case vm.evm.ChainRules().IsCancun:
-		jt = &cancunInstructionSet
-	case vm.evm.ChainRules().IsShanghai:
-		jt = &shanghaiInstructionSet
-	case vm.evm.ChainRules().IsLondon:
-		jt = &londonInstructionSet
-	case vm.evm.ChainRules().IsBerlin:
-		jt = &berlinInstructionSet
-	case vm.evm.ChainRules().IsIstanbul:
-		jt = &istanbulInstructionSet
-	case vm.evm.ChainRules().IsConstantinople:
-		jt = &constantinopleInstructionSet
-	case vm.evm.ChainRules().IsByzantium:
-		jt = &byzantiumInstructionSet
-	case vm.evm.ChainRules().IsSpuriousDragon:
-		jt = &spuriousDragonInstructionSet
-	case vm.evm.ChainRules().IsTangerineWhistle:
-		jt = &tangerineWhistleInstructionSet
-	case vm.evm.ChainRules().IsHomestead:
-		jt = &homesteadInstructionSet
-	default:
-		jt = &frontierInstructionSet
-	}
+	jt := jumpTable(vm.evm.ChainRules())
 	if len(vm.cfg.ExtraEips) > 0 {
 		for i, eip := range vm.cfg.ExtraEips {
 			if err := EnableEIP(eip, jt); err != nil {
@@ -183,7 +169,15 @@ func NewEVMInterpreterByVM(vm *VM) *EVMInterpreter {
 func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (ret []byte, err error) {
 	// Increment the call depth which is restricted to 1024
 	in.evm.depth++
-	defer func() { in.evm.depth-- }()
+	defer func() {
+		in.evm.depth--
+		if in.evm.depth == 0 && in.hasher != nil {
+			// the top-level call is done, the next transaction may run in another EVM
+			in.hasher.Reset()
+			hasherPool.Put(in.hasher)
+			in.hasher = nil
+		}
+	}()
 
 	// Make sure the readOnly is only set if we aren't in readOnly yet.
 	// This makes also sure that the readOnly flag isn't removed for child calls.
@@ -202,8 +196,8 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	}
 
 	var (
-		op          OpCode        // current opcode
-		mem         = NewMemory() // bound memory
+		op          OpCode              // current opcode
+		mem         = newPooledMemory() // bound memory
 		locStack    = stack.New()
 		callContext = &ScopeContext{
 			Memory:   mem,
@@ -222,10 +216,11 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		res     []byte // result of the opcode execution function
 	)
 	// Don't move this deferrred function, it's placed before the capturestate-deferred method,
-	// so that it get's executed _after_: the capturestate needs the stacks before
+	// so that it get's executed _after_: the capturestate needs the stacks and the memory before
 	// they are returned to the pools
 	defer func() {
 		stack.ReturnNormalStack(locStack)
+		mem.free()
 	}()
 	contract.Input = input
 
@@ -18,10 +18,16 @@ package vm
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/holiman/uint256"
 )
 
+// maxPooledMemory - memories which grew bigger aren't reused, so that rare huge ones aren't kept by the pool
+const maxPooledMemory = 1 << 20
+
+var memoryPool = sync.Pool{New: func() interface{} { return &Memory{} }}
+
 // Memory implements a simple memory model for the ethereum virtual machine.
 type Memory struct {
 	store       []byte
@@ -33,6 +39,21 @@ func NewMemory() *Memory {
 	return &Memory{}
 }
 
+// newPooledMemory - NewMemory reusing the store of a memory released by free
+func newPooledMemory() *Memory {
+	return memoryPool.Get().(*Memory)
+}
+
+// free returns the memory to the pool. Slices of GetPtr must not be used after it.
+func (m *Memory) free() {
+	if cap(m.store) > maxPooledMemory {
+		return
+	}
+	m.store = m.store[:0]
+	m.lastGasCost = 0
+	memoryPool.Put(m)
+}
+
 // Set sets offset + size to value
 func (m *Memory) Set(offset, size uint64, value []byte) {
 	// It's possible the offset is greater than 0 and size equals 0. This is because
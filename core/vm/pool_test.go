@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+// keccak256 of the 32-byte word 42, returned by hashCode
+var hashCode = hexutil.MustDecode("0x602a600052602060002060005260206000f3")
+
+func newPoolTestState(tb testing.TB) (*state.IntraBlockState, common.Address) {
+	address := common.BytesToAddress([]byte("contract"))
+	_, tx := memdb.NewTestTx(tb)
+	s := state.New(state.NewPlainStateReader(tx))
+	s.CreateAccount(address, true)
+	s.SetCode(address, hashCode)
+	return s, address
+}
+
+var poolTestBlockContext = BlockContext{
+	CanTransfer: func(IntraBlockState, common.Address, *uint256.Int) bool { return true },
+	Transfer:    func(IntraBlockState, common.Address, common.Address, *uint256.Int, bool) {},
+}
+
+func TestAcquireEVM(t *testing.T) {
+	s, address := newPoolTestState(t)
+	want := crypto.Keccak256(common.LeftPadBytes([]byte{42}, 32))
+
+	evm := AcquireEVM(poolTestBlockContext, TxContext{GasPrice: big.NewInt(1)}, s, params.TestChainConfig, Config{})
+	ret, _, err := evm.Call(AccountRef(common.Address{}), address, nil, 100_000, new(uint256.Int), false /* bailout */)
+	require.NoError(t, err)
+	require.Equal(t, want, ret)
+	ReleaseEVM(evm)
+
+	// the released EVM doesn't keep the previous transaction
+	s2, _ := newPoolTestState(t)
+	evm = AcquireEVM(poolTestBlockContext, TxContext{}, s2, params.TestChainConfig, Config{})
+	require.Equal(t, s2, evm.IntraBlockState())
+	require.Nil(t, evm.TxContext().GasPrice)
+	require.Zero(t, evm.depth)
+	in := evm.Interpreter().(*EVMInterpreter)
+	require.Equal(t, evm, in.evm)
+	require.Nil(t, in.hasher)
+	require.Nil(t, in.returnData)
+
+	// the return data of the previous call isn't overwritten by the pooled memory
+	ret2, _, err := evm.Call(AccountRef(common.Address{}), address, nil, 100_000, new(uint256.Int), false /* bailout */)
+	require.NoError(t, err)
+	require.Equal(t, want, ret2)
+	require.Equal(t, want, ret)
+	ret2[0]++
+	require.Equal(t, want, ret)
+	ReleaseEVM(evm)
+
+	// EVMs with extra EIPs aren't pooled
+	evm = AcquireEVM(poolTestBlockContext, TxContext{}, s, params.TestChainConfig, Config{ExtraEips: []int{2200}})
+	ReleaseEVM(evm)
+	require.NotNil(t, evm.IntraBlockState())
+}
+
+func benchmarkEVM(b *testing.B, newEVM func(BlockContext, TxContext, IntraBlockState, *params.ChainConfig, Config) *EVM, release func(*EVM)) {
+	s, address := newPoolTestState(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		evm := newEVM(poolTestBlockContext, TxContext{}, s, params.TestChainConfig, Config{})
+		if _, _, err := evm.Call(AccountRef(common.Address{}), address, nil, 100_000, new(uint256.Int), false /* bailout */); err != nil {
+			b.Fatal(err)
+		}
+		release(evm)
+	}
+}
+
+func BenchmarkNewEVM(b *testing.B) {
+	benchmarkEVM(b, NewEVM, func(*EVM) {})
+}
+
+func BenchmarkAcquireEVM(b *testing.B) {
+	benchmarkEVM(b, AcquireEVM, ReleaseEVM)
+}
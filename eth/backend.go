@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"math/big"
 	"os"
@@ -66,15 +67,18 @@ import (
 	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/ethconsensusconfig"
 	"github.com/ledgerwatch/erigon/eth/ethutils"
+	"github.com/ledgerwatch/erigon/eth/exporter"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/kvwatch"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/ethstats"
@@ -114,6 +118,8 @@ type Ethereum struct {
 
 	gasPrice  *uint256.Int
 	etherbase common.Address
+	extraData []byte
+	gasLimit  uint64
 
 	networkID uint64
 
@@ -130,15 +136,26 @@ type Ethereum struct {
 	sentriesClient *sentry.MultiClient
 	sentryServers  []*sentry.GrpcServer
 
-	stagedSync *stagedsync.Sync
+	stagedSync      *stagedsync.Sync
+	syncWatchdog    *stagedsync.Watchdog
+	rebuildReceipts stagedsync.RebuildReceiptsCfg
+	exporter        *exporter.Exporter
+	recentStates    *state.RecentStates
 
 	downloaderClient proto_downloader.DownloaderClient
 
 	notifications      *shards.Notifications
+	headersNotifier    *stagedsync.HeadersNotifier
 	unsubscribeEthstat func()
 
 	waitForStageLoopStop chan struct{}
-	waitForMiningStop    chan struct{}
+	waitForMiningStop    chan struct{} // Closed when the mining loop exits, nil if it was never started
+
+	miningLock   sync.Mutex    // Serializes starts and stops of the mining loop (miner_start, miner_stop)
+	miningStop   chan struct{} // Closed to stop the running mining loop
+	mining       *stagedsync.Sync
+	miningCfg    *params.MiningConfig // Read by the proof-of-work mining stages, refreshed by the mining loop between steps
+	miningTmpDir string
 
 	txPool2DB               kv.RwDB
 	txPool2                 *txpool2.TxPool
@@ -172,6 +189,9 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	if err != nil {
 		return nil, err
 	}
+	// internal components (headers notifier, embedded rpcdaemon) watch committed changes instead of polling
+	watchKv := kvwatch.New(chainKv)
+	chainKv = watchKv
 
 	if config.Genesis != nil && config.Genesis.Config != nil {
 		types.SetHeaderSealFlag(config.Genesis.Config.IsHeaderWithSeal())
@@ -222,6 +242,12 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		if err != nil {
 			return err
 		}
+		if err = config.Sync.Mode.Validate(config.Prune); err != nil {
+			return err
+		}
+		if err = stages.EnsureModeChangeAllowed(tx, config.Sync.Mode); err != nil {
+			return err
+		}
 		isCorrectSync, useSnapshots, err := snap.EnsureNotChanged(tx, config.Snapshot)
 		if err != nil {
 			return err
@@ -256,15 +282,20 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		chainDB:              chainKv,
 		networkID:            config.NetworkID,
 		etherbase:            config.Miner.Etherbase,
+		extraData:            config.Miner.ExtraData,
+		gasLimit:             config.Miner.GasLimit,
 		chainConfig:          chainConfig,
 		genesisHash:          genesis.Hash(),
 		waitForStageLoopStop: make(chan struct{}),
-		waitForMiningStop:    make(chan struct{}),
 		notifications: &shards.Notifications{
-			Events:      shards.NewEvents(),
-			Accumulator: shards.NewAccumulator(),
+			Events:         shards.NewEvents(),
+			Accumulator:    shards.NewAccumulator(),
+			HeadersWatched: true,
 		},
 	}
+	if backend.headersNotifier, err = stagedsync.NewHeadersNotifier(ctx, chainKv, watchKv, backend.notifications.Events); err != nil {
+		return nil, err
+	}
 	blockReader, allSnapshots, agg, err := backend.setUpBlockReader(ctx, config.Dirs, config.Snapshot, config.Downloader)
 	if err != nil {
 		return nil, err
@@ -296,13 +327,9 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 			return res
 		}
 
-		discovery, err := setupDiscovery(backend.config.EthDiscoveryURLs)
-		if err != nil {
-			return nil, err
-		}
 		cfg := stack.Config().P2P
 		cfg.NodeDatabase = filepath.Join(stack.Config().Dirs.Nodes, eth.ProtocolToString[cfg.ProtocolVersion])
-		server := sentry.NewGrpcServer(backend.sentryCtx, discovery, readNodeInfo, &cfg, cfg.ProtocolVersion)
+		server := sentry.NewGrpcServer(backend.sentryCtx, backend.config.EthDiscoveryURLs, readNodeInfo, &cfg, cfg.ProtocolVersion)
 
 		backend.sentryServers = append(backend.sentryServers, server)
 		sentries = []direct.SentryClient{direct.NewSentryClientDirect(cfg.ProtocolVersion, server)}
@@ -412,7 +439,10 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	backend.pendingBlocks = make(chan *types.Block, 1)
 	backend.minedBlocks = make(chan *types.Block, 1)
 
-	miner := stagedsync.NewMiningState(&config.Miner)
+	// the stages don't read config.Miner, it's shared with miner_setEtherbase, miner_setExtra and miner_setGasLimit
+	powMiningCfg := config.Miner
+	backend.miningCfg = &powMiningCfg
+	miner := stagedsync.NewMiningState(backend.miningCfg)
 	backend.pendingBlocks = miner.PendingResultCh
 	backend.minedBlocks = miner.MiningResultCh
 
@@ -422,7 +452,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 			stagedsync.StageMiningCreateBlockCfg(backend.chainDB, miner, *backend.chainConfig, backend.engine, backend.txPool2, backend.txPool2DB, nil, tmpdir),
 			stagedsync.StageMiningExecCfg(backend.chainDB, miner, backend.notifications.Events, *backend.chainConfig, backend.engine, &vm.Config{}, tmpdir, nil, 0),
 			stagedsync.StageHashStateCfg(backend.chainDB, dirs, config.HistoryV3, backend.agg),
-			stagedsync.StageTrieCfg(backend.chainDB, false, true, true, tmpdir, blockReader, nil, config.HistoryV3, backend.agg),
+			stagedsync.StageTrieCfg(backend.chainDB, false, true, true, dirs, blockReader, nil, config.HistoryV3, backend.agg),
 			stagedsync.StageMiningFinishCfg(backend.chainDB, *backend.chainConfig, backend.engine, miner, backend.miningSealingQuit),
 		), stagedsync.MiningUnwindOrder, stagedsync.MiningPruneOrder)
 
@@ -433,14 +463,15 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 
 	// proof-of-stake mining
 	assembleBlockPOS := func(param *core.BlockBuilderParameters, interrupt *int32) (*types.Block, error) {
-		miningStatePos := stagedsync.NewProposingState(&config.Miner)
-		miningStatePos.MiningConfig.Etherbase = param.SuggestedFeeRecipient
+		miningCfg := backend.miningConfig()
+		miningCfg.Etherbase = param.SuggestedFeeRecipient
+		miningStatePos := stagedsync.NewProposingState(&miningCfg)
 		proposingSync := stagedsync.New(
 			stagedsync.MiningStages(backend.sentryCtx,
 				stagedsync.StageMiningCreateBlockCfg(backend.chainDB, miningStatePos, *backend.chainConfig, backend.engine, backend.txPool2, backend.txPool2DB, param, tmpdir),
 				stagedsync.StageMiningExecCfg(backend.chainDB, miningStatePos, backend.notifications.Events, *backend.chainConfig, backend.engine, &vm.Config{}, tmpdir, interrupt, param.PayloadId),
 				stagedsync.StageHashStateCfg(backend.chainDB, dirs, config.HistoryV3, backend.agg),
-				stagedsync.StageTrieCfg(backend.chainDB, false, true, true, tmpdir, blockReader, nil, config.HistoryV3, backend.agg),
+				stagedsync.StageTrieCfg(backend.chainDB, false, true, true, dirs, blockReader, nil, config.HistoryV3, backend.agg),
 				stagedsync.StageMiningFinishCfg(backend.chainDB, *backend.chainConfig, backend.engine, miningStatePos, backend.miningSealingQuit),
 			), stagedsync.MiningUnwindOrder, stagedsync.MiningPruneOrder)
 		// We start the mining step
@@ -578,13 +609,75 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		return nil, err
 	}
 
-	backend.stagedSync, err = stages2.NewStagedSync(backend.sentryCtx, backend.chainDB, stack.Config().P2P, config, backend.sentriesClient, backend.notifications, backend.downloaderClient, allSnapshots, backend.agg, backend.forkValidator)
+	if config.Export.Sink != "" {
+		sink, err := exporter.OpenSink(config.Export.Sink)
+		if err != nil {
+			return nil, fmt.Errorf("export sink: %w", err)
+		}
+		backend.exporter = exporter.New(sink, config.Export)
+	}
+
+	backend.recentStates = state.NewRecentStates(config.RecentStates)
+
+	backend.stagedSync, err = stages2.NewStagedSync(backend.sentryCtx, backend.chainDB, stack.Config().P2P, config, backend.sentriesClient, backend.notifications, backend.downloaderClient, allSnapshots, backend.agg, backend.forkValidator, backend.exporter, backend.recentStates)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.Sync.WatchdogTimeout > 0 {
+		backend.syncWatchdog = stagedsync.NewWatchdog(stagedsync.WatchdogCfg{
+			Timeout: config.Sync.WatchdogTimeout,
+			Restart: config.Sync.WatchdogRestart,
+			Dir:     filepath.Join(config.Dirs.DataDir, "diagnostics"),
+			Sections: []stagedsync.DiagnosticsSection{
+				{Name: "headers", Write: backend.writeHeadersDiagnostics},
+				{Name: "peers", Write: backend.writePeersDiagnostics},
+				{Name: "db", Write: func(w io.Writer) error { return node.WriteDBInfo(w, backend.chainDB) }},
+			},
+		})
+		backend.stagedSync.SetWatchdog(backend.syncWatchdog)
+	}
+
 	backend.sentriesClient.Hd.StartPoSDownloader(backend.sentryCtx, backend.sentriesClient.SendHeaderRequest, backend.sentriesClient.Penalize)
 
+	if stack.DirtyShutdown() && config.Sync.DirtyShutdownVerifyBlocks > 0 {
+		var frozenBlocks, badBlock uint64
+		var found bool
+		if allSnapshots != nil {
+			// blocks in snapshots are not affected by the crash, skip them
+			if err = allSnapshots.ReopenFolder(); err != nil {
+				return nil, err
+			}
+			frozenBlocks = allSnapshots.BlocksAvailable()
+		}
+		if err = chainKv.Update(context.Background(), func(tx kv.RwTx) error {
+			badBlock, found, err = stagedsync.VerifyAfterDirtyShutdown(tx, chainConfig, config.Sync.DirtyShutdownVerifyBlocks, frozenBlocks, "DirtyShutdown")
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		if found {
+			log.Warn("Unwinding inconsistent blocks left by dirty shutdown", "to", badBlock-1)
+			backend.stagedSync.UnwindTo(badBlock-1, common.Hash{})
+		}
+	}
+
+	if config.Sync.RebuildReceiptsTo > 0 {
+		if err = chainKv.Update(context.Background(), func(tx kv.RwTx) error {
+			return stagedsync.StartRebuildReceipts(tx, config.Sync.RebuildReceiptsFrom, config.Sync.RebuildReceiptsTo)
+		}); err != nil {
+			return nil, fmt.Errorf("rebuild of receipts: %w", err)
+		}
+	}
+	if config.Sync.ReshardIndexTable != "" {
+		if err = chainKv.Update(context.Background(), func(tx kv.RwTx) error {
+			return stagedsync.StartReshardIndex(tx, config.Sync.ReshardIndexTable, config.Sync.ReshardIndexShardSize)
+		}); err != nil {
+			return nil, fmt.Errorf("re-sharding of index: %w", err)
+		}
+	}
+	backend.rebuildReceipts = stagedsync.StageRebuildReceiptsCfg(backend.chainDB, chainConfig, backend.engine, blockReader, 100, config.Sync.RebuildReceiptsThrottle)
+
 	emptyBadHash := config.BadBlockHash == common.Hash{}
 	if !emptyBadHash {
 		var badBlockHeader *types.Header
@@ -617,6 +710,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	}
 	// start HTTP API
 	httpRpcCfg := stack.Config().Http
+	httpRpcCfg.RecentStates = backend.recentStates
 	ethRpcClient, txPoolRpcClient, miningRpcClient, stateCache, ff, err := cli.EmbeddedServices(ctx, chainKv, httpRpcCfg.StateCache, blockReader, allSnapshots, backend.agg, ethBackendRPC, backend.txPool2GrpcServer, miningRPC)
 	if err != nil {
 		return nil, err
@@ -626,7 +720,8 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	if casted, ok := backend.engine.(*bor.Bor); ok {
 		borDb = casted.DB
 	}
-	apiList := commands.APIList(chainKv, borDb, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, blockReader, backend.agg, httpRpcCfg)
+	apiList := commands.APIList(chainKv, borDb, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, blockReader, backend.agg, backend.downloaderClient, httpRpcCfg)
+	apiList = append(apiList, commands.MinerAPIList(backend, httpRpcCfg)...)
 	authApiList := commands.AuthAPIList(chainKv, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, blockReader, backend.agg, httpRpcCfg)
 	go func() {
 		if err := cli.StartRpcServer(ctx, httpRpcCfg, apiList, authApiList); err != nil {
@@ -697,10 +792,19 @@ func (s *Ethereum) shouldPreserve(block *types.Block) bool { //nolint
 // is already running, this method adjust the number of threads allowed to use
 // and updates the minimum price required by the transaction pool.
 func (s *Ethereum) StartMining(ctx context.Context, db kv.RwDB, mining *stagedsync.Sync, cfg params.MiningConfig, gasPrice *uint256.Int, quitCh chan struct{}, tmpDir string) error {
+	s.miningLock.Lock()
+	defer s.miningLock.Unlock()
+	// kept for miner_start
+	s.mining = mining
+	s.miningTmpDir = tmpDir
 	if !cfg.Enabled {
 		return nil
 	}
+	return s.startMiningLoop(ctx, db, mining, cfg, quitCh, tmpDir)
+}
 
+// startMiningLoop - must be called with miningLock held
+func (s *Ethereum) startMiningLoop(ctx context.Context, db kv.RwDB, mining *stagedsync.Sync, cfg params.MiningConfig, quitCh chan struct{}, tmpDir string) error {
 	// Configure the local mining address
 	eb, err := s.Etherbase()
 	if err != nil {
@@ -765,9 +869,11 @@ func (s *Ethereum) StartMining(ctx context.Context, db kv.RwDB, mining *stagedsy
 		})
 	}
 
+	stop, done := make(chan struct{}), make(chan struct{})
+	s.miningStop, s.waitForMiningStop = stop, done
 	go func() {
 		defer debug.LogPanic()
-		defer close(s.waitForMiningStop)
+		defer close(done)
 
 		mineEvery := time.NewTicker(3 * time.Second)
 		defer mineEvery.Stop()
@@ -794,10 +900,17 @@ func (s *Ethereum) StartMining(ctx context.Context, db kv.RwDB, mining *stagedsy
 				}
 			case <-quitCh:
 				return
+			case <-stop:
+				if works {
+					<-errc // the next start must not run a step concurrently with this one
+				}
+				return
 			}
 
 			if !works && hasWork {
 				works = true
+				// changes of miner_setEtherbase, miner_setExtra and miner_setGasLimit, no step reads the config meanwhile
+				*s.miningCfg = s.miningConfig()
 				go func() { errc <- stages2.MiningStep(ctx, db, mining, tmpDir) }()
 			}
 		}
@@ -806,24 +919,126 @@ func (s *Ethereum) StartMining(ctx context.Context, db kv.RwDB, mining *stagedsy
 	return nil
 }
 
-func (s *Ethereum) IsMining() bool { return s.config.Miner.Enabled }
+// IsMining - whether the proof-of-work mining loop is running, it can be started and stopped by miner_start and miner_stop
+func (s *Ethereum) IsMining() bool {
+	s.miningLock.Lock()
+	defer s.miningLock.Unlock()
+	return s.miningRunning()
+}
+
+func (s *Ethereum) miningRunning() bool {
+	if s.waitForMiningStop == nil {
+		return false
+	}
+	select {
+	case <-s.waitForMiningStop:
+		return false
+	default:
+		return true
+	}
+}
+
+// MinerStart implements miner_start: starts the mining loop if it isn't running. Mining stops for good when the chain
+// switches to proof-of-stake.
+func (s *Ethereum) MinerStart() error {
+	s.miningLock.Lock()
+	defer s.miningLock.Unlock()
+	if s.miningRunning() {
+		return nil
+	}
+	select {
+	case <-s.sentriesClient.Hd.QuitPoWMining:
+		return fmt.Errorf("mining is over, the chain is proof-of-stake")
+	default:
+	}
+	if s.mining == nil {
+		return fmt.Errorf("mining is not initialised yet")
+	}
+	return s.startMiningLoop(context.Background(), s.chainDB, s.mining, s.miningConfig(), s.sentriesClient.Hd.QuitPoWMining, s.miningTmpDir)
+}
+
+// miningConfig - copy of the mining config with the changes of miner_setEtherbase, miner_setExtra and miner_setGasLimit
+func (s *Ethereum) miningConfig() params.MiningConfig {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	cfg := s.config.Miner
+	cfg.Etherbase, cfg.ExtraData, cfg.GasLimit = s.etherbase, s.extraData, s.gasLimit
+	return cfg
+}
+
+// MinerStop implements miner_stop: stops the mining loop, after the block being mined
+func (s *Ethereum) MinerStop() error {
+	s.miningLock.Lock()
+	defer s.miningLock.Unlock()
+	if !s.miningRunning() {
+		return nil
+	}
+	close(s.miningStop)
+	<-s.waitForMiningStop
+	return nil
+}
+
+// SetEtherbase implements miner_setEtherbase, the next mined block is for the new etherbase
+func (s *Ethereum) SetEtherbase(etherbase common.Address) error {
+	if key := s.config.Miner.SigKey; key != nil && crypto.PubkeyToAddress(key.PublicKey) != etherbase {
+		return fmt.Errorf("etherbase must be the address of the block signing key %x", crypto.PubkeyToAddress(key.PublicKey))
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.etherbase = etherbase
+	return nil
+}
+
+// SetExtra implements miner_setExtra, the next mined block has the new extra data
+func (s *Ethereum) SetExtra(extra []byte) error {
+	if uint64(len(extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("extra exceeds max length. %d > %v", len(extra), params.MaximumExtraDataSize)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.extraData = common.CopyBytes(extra)
+	return nil
+}
+
+// maxTargetGasLimit - highest target of miner_setGasLimit, far above the gas limits of existing networks: a higher one
+// is a mistake (e.g. of units) and blocks would take days to reach it anyway
+const maxTargetGasLimit = 1_000_000_000
+
+// SetGasLimit implements miner_setGasLimit: the target gas limit of the next mined and proposed blocks. Every block
+// moves the limit of its parent towards the target by less than 1/GasLimitBoundDivisor of it, as the consensus
+// rules allow.
+func (s *Ethereum) SetGasLimit(gasLimit uint64) error {
+	if gasLimit < params.MinGasLimit || gasLimit > maxTargetGasLimit {
+		return fmt.Errorf("gas limit %d is out of range [%d, %d]", gasLimit, params.MinGasLimit, maxTargetGasLimit)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gasLimit = gasLimit
+	return nil
+}
 
 func (s *Ethereum) ChainKV() kv.RwDB            { return s.chainDB }
 func (s *Ethereum) NetVersion() (uint64, error) { return s.networkID, nil }
+
+// NetPeerCount - sum of peers of all sentries. Sentries which are not ready or unreachable are skipped:
+// their peers are not usable anyway.
 func (s *Ethereum) NetPeerCount() (uint64, error) {
 	var sentryPc uint64 = 0
 
-	log.Trace("sentry", "peer count", sentryPc)
 	for _, sc := range s.sentriesClient.Sentries() {
+		if !sc.Ready() {
+			continue
+		}
 		ctx := context.Background()
 		reply, err := sc.PeerCount(ctx, &proto_sentry.PeerCountRequest{})
 		if err != nil {
 			log.Warn("sentry", "err", err)
-			return 0, nil
+			continue
 		}
 		sentryPc += reply.Count
 	}
 
+	log.Trace("sentry", "peer count", sentryPc)
 	return sentryPc, nil
 }
 
@@ -908,6 +1123,26 @@ func (s *Ethereum) Peers(ctx context.Context) (*remote.PeersReply, error) {
 	return &reply, nil
 }
 
+func (s *Ethereum) writePeersDiagnostics(w io.Writer) error {
+	ctx, cancel := context.WithTimeout(s.sentryCtx, 5*time.Second)
+	defer cancel()
+	reply, err := s.Peers(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d peers\n", len(reply.Peers))
+	for _, p := range reply.Peers {
+		fmt.Fprintf(w, "%s %s remote=%s inbound=%t caps=%v\n", p.Id, p.Name, p.ConnRemoteAddr, p.ConnIsInbound, p.Caps)
+	}
+	return nil
+}
+
+func (s *Ethereum) writeHeadersDiagnostics(w io.Writer) error {
+	hd := s.sentriesClient.Hd
+	_, err := fmt.Fprintf(w, "progress: %d\ntop seen height: %d\npos status: %d\n", hd.Progress(), hd.TopSeenHeight(), hd.PosStatus())
+	return err
+}
+
 // Protocols returns all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
@@ -924,7 +1159,22 @@ func (s *Ethereum) Start() error {
 	s.sentriesClient.StartStreamLoops(s.sentryCtx)
 	time.Sleep(10 * time.Millisecond) // just to reduce logs order confusion
 
+	go node.CollectDBStats(s.sentryCtx, s.chainDB, 10*time.Second)
+	go s.headersNotifier.Loop(s.sentryCtx)
+	if s.syncWatchdog != nil {
+		go s.syncWatchdog.Loop(s.sentryCtx)
+	}
 	go stages2.StageLoop(s.sentryCtx, s.chainConfig, s.chainDB, s.stagedSync, s.sentriesClient.Hd, s.notifications, s.sentriesClient.UpdateHead, s.waitForStageLoopStop, s.config.Sync.LoopThrottle)
+	go func() {
+		if err := stagedsync.RebuildReceipts(s.sentryCtx, s.rebuildReceipts); err != nil && !errors.Is(err, context.Canceled) {
+			log.Error("Rebuild of receipts failed", "err", err)
+		}
+	}()
+	go func() {
+		if err := stagedsync.ReshardIndex(s.sentryCtx, s.chainDB, 1000, s.config.Sync.ReshardIndexThrottle); err != nil && !errors.Is(err, context.Canceled) {
+			log.Error("Re-sharding of index failed", "err", err)
+		}
+	}()
 
 	return nil
 }
@@ -956,8 +1206,14 @@ func (s *Ethereum) Stop() error {
 
 	_ = s.engine.Close()
 	<-s.waitForStageLoopStop
-	if s.config.Miner.Enabled {
-		<-s.waitForMiningStop
+	if err := s.exporter.Close(); err != nil {
+		log.Warn("Failed to close export sink", "err", err)
+	}
+	s.miningLock.Lock()
+	waitForMiningStop := s.waitForMiningStop
+	s.miningLock.Unlock()
+	if waitForMiningStop != nil {
+		<-waitForMiningStop
 	}
 	for _, sentryServer := range s.sentryServers {
 		sentryServer.Close()
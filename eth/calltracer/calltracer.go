@@ -57,6 +57,21 @@ func (ct *CallTracer) CaptureAccountWrite(account common.Address) error {
 }
 
 func (ct *CallTracer) WriteToDb(tx kv.StatelessWriteTx, block *types.Block, vmConfig vm.Config) error {
+	var blockNumEnc [8]byte
+	binary.BigEndian.PutUint64(blockNumEnc[:], block.Number().Uint64())
+	first := true
+	return ct.ForEachAddress(block, func(v []byte) error {
+		if first {
+			first = false
+			return tx.Append(kv.CallTraceSet, blockNumEnc[:], v)
+		}
+		return tx.AppendDup(kv.CallTraceSet, blockNumEnc[:], v)
+	})
+}
+
+// ForEachAddress calls f for addresses of the block's calls, sorted and without duplicates, in the format of
+// CallTraceSet values: address and flags (1 - the address is a sender of a call, 2 - a receiver)
+func (ct *CallTracer) ForEachAddress(block *types.Block, f func(v []byte) error) error {
 	ct.tos[block.Coinbase()] = false
 	for _, uncle := range block.Uncles() {
 		ct.tos[uncle.Coinbase] = false
@@ -73,8 +88,6 @@ func (ct *CallTracer) WriteToDb(tx kv.StatelessWriteTx, block *types.Block, vmCo
 	}
 	sort.Sort(list)
 	// List may contain duplicates
-	var blockNumEnc [8]byte
-	binary.BigEndian.PutUint64(blockNumEnc[:], block.Number().Uint64())
 	var prev common.Address
 	for j, addr := range list {
 		if j > 0 && prev == addr {
@@ -88,14 +101,8 @@ func (ct *CallTracer) WriteToDb(tx kv.StatelessWriteTx, block *types.Block, vmCo
 		if _, ok := ct.tos[addr]; ok {
 			v[length.Addr] |= 2
 		}
-		if j == 0 {
-			if err := tx.Append(kv.CallTraceSet, blockNumEnc[:], v[:]); err != nil {
-				return err
-			}
-		} else {
-			if err := tx.AppendDup(kv.CallTraceSet, blockNumEnc[:], v[:]); err != nil {
-				return err
-			}
+		if err := f(v[:]); err != nil {
+			return err
 		}
 		copy(prev[:], addr[:])
 	}
@@ -32,7 +32,10 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/eth/exporter"
 	"github.com/ledgerwatch/erigon/eth/gasprice"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/params"
@@ -70,6 +73,10 @@ var Defaults = Config{
 		ExecWorkerCount:            1,
 		BlockDownloaderWindow:      32768,
 		BodyDownloadTimeoutSeconds: 30,
+		DirtyShutdownVerifyBlocks:  1024,
+		RebuildReceiptsThrottle:    500 * time.Millisecond,
+		ReshardIndexThrottle:       500 * time.Millisecond,
+		Mode:                       stages.ModeFull,
 	},
 	Ethash: ethash.Config{
 		CachesInMem:      2,
@@ -80,6 +87,9 @@ var Defaults = Config{
 	},
 	NetworkID: 1,
 	Prune:     prune.DefaultMode,
+	Export:    exporter.DefaultConfig,
+
+	RecentStates: state.DefaultRecentStatesCfg,
 	Miner: params.MiningConfig{
 		GasLimit: 30_000_000,
 		GasPrice: big.NewInt(params.GWei),
@@ -219,6 +229,12 @@ type Config struct {
 
 	StateStream bool
 
+	// Export - streaming of executed blocks, receipts and logs to an external sink, disabled if Sink is empty
+	Export exporter.Config
+
+	// RecentStates - state changes of the last executed blocks kept in memory for RPC, disabled if Blocks is 0
+	RecentStates state.RecentStatesCfg
+
 	// Enable WatchTheBurn stage
 	EnabledIssuance bool
 
@@ -249,6 +265,35 @@ type Sync struct {
 
 	BlockDownloaderWindow      int
 	BodyDownloadTimeoutSeconds int // TODO: change to duration
+
+	// DirtyShutdownVerifyBlocks - how many last blocks of each stage to re-verify if the node was not shut down cleanly. 0 - don't verify
+	DirtyShutdownVerifyBlocks uint64
+
+	// NodeDataRate - max amount of trie nodes per second served to peers by legacy GetNodeData (eth/66). 0 - don't serve
+	NodeDataRate int
+
+	// RebuildReceiptsFrom, RebuildReceiptsTo - blocks to re-execute in background to regenerate receipts, logs and
+	// call indices. RebuildReceiptsTo == 0 - only continue the unfinished rebuild, if any
+	RebuildReceiptsFrom, RebuildReceiptsTo uint64
+	// RebuildReceiptsThrottle - pause between batches of the rebuild
+	RebuildReceiptsThrottle time.Duration
+
+	// ReshardIndexTable, ReshardIndexShardSize - call/log index to re-shard in background to shards of this size.
+	// Empty table - only continue unfinished re-sharding, if any
+	ReshardIndexTable     string
+	ReshardIndexShardSize uint64
+	// ReshardIndexThrottle - pause between batches of re-sharded bitmaps
+	ReshardIndexThrottle time.Duration
+
+	// WatchdogTimeout - how long a stage may make no progress before diagnostics are dumped. 0 - disabled
+	WatchdogTimeout time.Duration
+	// WatchdogRestart - interrupt the stuck cycle after the dump, the stage loop starts over
+	WatchdogRestart bool
+
+	// Mode - which stages run: full, archive, blocks (no execution), state (no history indices) or external
+	Mode stages.Mode
+	// ExternalExecAddr - gRPC address of the executor of blocks in stages.ModeExternal, see turbo/remoteexec
+	ExternalExecAddr string
 }
 
 // Chains where snapshots are enabled by default
@@ -13,12 +13,14 @@ type estimatedRamPerWorker datasize.ByteSize
 // Workers - return max workers amount based on total Memory/CPU's and estimated RAM per worker
 func (r estimatedRamPerWorker) Workers() int {
 	maxWorkersForGivenMemory := memory.TotalMemory() / uint64(r)
-	maxWorkersForGivenCPU := runtime.NumCPU() - 1 // reserve 1 cpu for "work-producer thread", also IO software on machine in cloud-providers using 1 CPU
+	maxWorkersForGivenCPU := cmp.Max(1, runtime.NumCPU()-1) // reserve 1 cpu for "work-producer thread", also IO software on machine in cloud-providers using 1 CPU - unless it is the only one
 	return cmp.InRange(1, maxWorkersForGivenCPU, int(maxWorkersForGivenMemory))
 }
 
 const (
-	IndexSnapshot     = estimatedRamPerWorker(2 * datasize.MB) //elias-fano index building is single-threaded
-	CompressSnapshot  = estimatedRamPerWorker(1 * datasize.GB) //1-file-compression is multi-threaded
-	ReconstituteState = estimatedRamPerWorker(4 * datasize.GB) //state-reconstitution is multi-threaded
+	IndexSnapshot     = estimatedRamPerWorker(2 * datasize.MB)   //elias-fano index building is single-threaded
+	CompressSnapshot  = estimatedRamPerWorker(1 * datasize.GB)   //1-file-compression is multi-threaded
+	ReconstituteState = estimatedRamPerWorker(4 * datasize.GB)   //state-reconstitution is multi-threaded
+	OtsSearchTrace    = estimatedRamPerWorker(128 * datasize.MB) //block replay of otterscan search, with its state cache
+	TraceBlockTx      = estimatedRamPerWorker(256 * datasize.MB) //transaction of debug_traceBlockBy*, with its trace buffered until written
 )
@@ -42,6 +42,7 @@ func CreateConsensusEngine(chainConfig *params.ChainConfig, logger log.Logger, c
 				DatasetsInMem:    consensusCfg.DatasetsInMem,
 				DatasetsOnDisk:   consensusCfg.DatasetsOnDisk,
 				DatasetsLockMmap: consensusCfg.DatasetsLockMmap,
+				SealVerifierURL:  consensusCfg.SealVerifierURL,
 			}, notify, noverify)
 		}
 	case *params.ConsensusSnapshotConfig:
@@ -0,0 +1,205 @@
+// Package exporter streams chain data to external sinks while blocks are executed: blocks, receipts and logs as
+// Execution produces them, without reading them back over RPC. Delivery is at-least-once: the cursor of the
+// exporter is stored in the database in the same transaction as the progress of Execution, after the sink
+// accepted the messages. Blocks re-executed after a crash or an unwind are sent again.
+package exporter
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/log/v3"
+)
+
+type MessageType string
+
+const (
+	BlockMessage   MessageType = "block"
+	ReceiptMessage MessageType = "receipt"
+	LogMessage     MessageType = "log"
+	// ReorgMessage - blocks above BlockNumber were unwound: messages of them sent before are not canonical anymore,
+	// blocks of the new chain follow
+	ReorgMessage MessageType = "reorg"
+)
+
+// Message - exported piece of chain data. Messages of a block are sent in order: the block, its receipts, then logs.
+type Message struct {
+	Type        MessageType `json:"type"`
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+	Data        interface{} `json:"data,omitempty"` // *types.Header, *types.Receipt or *types.Log
+}
+
+// Sink - destination of the exported messages: a file, a webhook, a message broker. Send returns after the messages
+// are durable in the sink, the cursor moves past them then. Send is never called concurrently.
+type Sink interface {
+	Send(ctx context.Context, msgs []Message) error
+	Close() error
+}
+
+var CursorKey = []byte("exporter_cursor")
+
+// Cursor - last block sent to the sink, ok == false if nothing was exported yet
+func Cursor(db kv.Getter) (block uint64, ok bool, err error) {
+	v, err := db.GetOne(kv.DatabaseInfo, CursorKey)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(v) != 8 {
+		return 0, false, nil
+	}
+	return binary.BigEndian.Uint64(v), true, nil
+}
+
+func writeCursor(db kv.Putter, block uint64) error {
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], block)
+	return db.Put(kv.DatabaseInfo, CursorKey, v[:])
+}
+
+type Config struct {
+	Sink      string // URL of the sink: file:///path/blocks.jsonl, http://host/path, or a registered scheme
+	BatchSize int    // messages buffered before they're sent, sent at least at every commit of Execution
+	NoLogs    bool   // don't export logs separately, they're in receipts anyway
+}
+
+var DefaultConfig = Config{BatchSize: 10_000}
+
+// Backoff of retries while the sink is unavailable
+var (
+	retryMinBackoff = time.Second
+	retryMaxBackoff = time.Minute
+)
+
+// Exporter collects messages of executed blocks and sends them to the sink in batches. Not thread-safe: it's used
+// by the Execution stage only.
+type Exporter struct {
+	sink    Sink
+	cfg     Config
+	pending []Message
+	last    uint64 // last block in pending
+}
+
+func New(sink Sink, cfg Config) *Exporter {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultConfig.BatchSize
+	}
+	return &Exporter{sink: sink, cfg: cfg}
+}
+
+// Reset drops messages which weren't sent: their blocks weren't committed and will be executed again
+func (e *Exporter) Reset() {
+	if e == nil {
+		return
+	}
+	e.pending = nil
+}
+
+// Block adds messages of the executed block. Blocks up to the cursor were sent already and are skipped. The batch is
+// sent if it's full, the cursor is written into tx then.
+func (e *Exporter) Block(ctx context.Context, tx kv.RwTx, block *types.Block, receipts types.Receipts) error {
+	if e == nil {
+		return nil
+	}
+	number, hash := block.NumberU64(), block.Hash()
+	if len(e.pending) == 0 {
+		cursor, ok, err := Cursor(tx)
+		if err != nil {
+			return err
+		}
+		if ok && number <= cursor {
+			return nil
+		}
+		if ok && number > cursor+1 {
+			log.Warn("[exporter] blocks were executed without export", "from", cursor+1, "to", number-1)
+		}
+	}
+	e.pending = append(e.pending, Message{Type: BlockMessage, BlockNumber: number, BlockHash: hash, Data: block.Header()})
+	for _, r := range receipts {
+		e.pending = append(e.pending, Message{Type: ReceiptMessage, BlockNumber: number, BlockHash: hash, Data: r})
+	}
+	if !e.cfg.NoLogs {
+		for _, r := range receipts {
+			for _, l := range r.Logs {
+				e.pending = append(e.pending, Message{Type: LogMessage, BlockNumber: number, BlockHash: hash, Data: l})
+			}
+		}
+	}
+	e.last = number
+	if len(e.pending) >= e.cfg.BatchSize {
+		return e.Flush(ctx, tx)
+	}
+	return nil
+}
+
+// Flush sends the pending messages and writes the cursor into tx. It must be called before tx with the executed
+// blocks is committed.
+func (e *Exporter) Flush(ctx context.Context, tx kv.Putter) error {
+	if e == nil || len(e.pending) == 0 {
+		return nil
+	}
+	if err := e.send(ctx, e.pending); err != nil {
+		return err
+	}
+	if err := writeCursor(tx, e.last); err != nil {
+		return err
+	}
+	e.pending = nil // the sink may keep the sent slice
+	return nil
+}
+
+// Unwind tells the sink that blocks above unwindPoint are not canonical anymore and moves the cursor back, so blocks
+// of the new chain are exported.
+func (e *Exporter) Unwind(ctx context.Context, tx kv.RwTx, unwindPoint uint64, hash common.Hash) error {
+	if e == nil {
+		return nil
+	}
+	e.pending = nil
+	cursor, ok, err := Cursor(tx)
+	if err != nil {
+		return err
+	}
+	if !ok || cursor <= unwindPoint {
+		return nil
+	}
+	if err := e.send(ctx, []Message{{Type: ReorgMessage, BlockNumber: unwindPoint, BlockHash: hash}}); err != nil {
+		return err
+	}
+	return writeCursor(tx, unwindPoint)
+}
+
+// send retries until the sink accepts the messages: Execution waits for it, blocks aren't committed without export.
+// Only cancellation of ctx stops it.
+func (e *Exporter) send(ctx context.Context, msgs []Message) error {
+	backoff := retryMinBackoff
+	for {
+		err := e.sink.Send(ctx, msgs)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("exporter: %w", err)
+		}
+		log.Warn("[exporter] sink unavailable, retrying", "in", backoff, "err", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("exporter: %w", err)
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > retryMaxBackoff {
+			backoff = retryMaxBackoff
+		}
+	}
+}
+
+func (e *Exporter) Close() error {
+	if e == nil {
+		return nil
+	}
+	return e.sink.Close()
+}
@@ -0,0 +1,142 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+type memSink struct{ sent [][]Message }
+
+func (s *memSink) Send(_ context.Context, msgs []Message) error {
+	s.sent = append(s.sent, msgs)
+	return nil
+}
+func (s *memSink) Close() error { return nil }
+
+func testBlock(n int64) (*types.Block, types.Receipts) {
+	header := &types.Header{Number: big.NewInt(n)}
+	receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{{Address: common.HexToAddress("0x01")}}}}
+	return types.NewBlockWithHeader(header), receipts
+}
+
+func TestExporter(t *testing.T) {
+	ctx := context.Background()
+	_, tx := memdb.NewTestTx(t)
+	sink := &memSink{}
+	e := New(sink, Config{BatchSize: 6})
+
+	for n := int64(1); n <= 3; n++ {
+		block, receipts := testBlock(n)
+		require.NoError(t, e.Block(ctx, tx, block, receipts))
+	}
+	// 3 messages per block: the batch of 2 blocks is sent when full
+	require.Len(t, sink.sent, 1)
+	require.Len(t, sink.sent[0], 6)
+	require.Equal(t, []MessageType{BlockMessage, ReceiptMessage, LogMessage}, []MessageType{sink.sent[0][0].Type, sink.sent[0][1].Type, sink.sent[0][2].Type})
+	cursor, ok, err := Cursor(tx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), cursor)
+
+	require.NoError(t, e.Flush(ctx, tx))
+	require.Len(t, sink.sent, 2)
+	cursor, _, _ = Cursor(tx)
+	require.Equal(t, uint64(3), cursor)
+
+	// re-executed blocks up to the cursor aren't sent again
+	block, receipts := testBlock(3)
+	require.NoError(t, e.Block(ctx, tx, block, receipts))
+	require.NoError(t, e.Flush(ctx, tx))
+	require.Len(t, sink.sent, 2)
+
+	// unwind moves the cursor back and tells the sink
+	require.NoError(t, e.Unwind(ctx, tx, 1, common.HexToHash("0x02")))
+	require.Len(t, sink.sent, 3)
+	require.Equal(t, Message{Type: ReorgMessage, BlockNumber: 1, BlockHash: common.HexToHash("0x02")}, sink.sent[2][0])
+	cursor, _, _ = Cursor(tx)
+	require.Equal(t, uint64(1), cursor)
+	block, receipts = testBlock(2)
+	require.NoError(t, e.Block(ctx, tx, block, receipts))
+	require.NoError(t, e.Flush(ctx, tx))
+	require.Len(t, sink.sent, 4)
+}
+
+type flakySink struct {
+	memSink
+	failures int
+}
+
+func (s *flakySink) Send(ctx context.Context, msgs []Message) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+	return s.memSink.Send(ctx, msgs)
+}
+
+func TestExporterWaitsForSink(t *testing.T) {
+	defer func(min time.Duration) { retryMinBackoff = min }(retryMinBackoff)
+	retryMinBackoff = time.Millisecond
+	_, tx := memdb.NewTestTx(t)
+
+	sink := &flakySink{failures: 3}
+	e := New(sink, Config{BatchSize: 100})
+	block, receipts := testBlock(1)
+	require.NoError(t, e.Block(context.Background(), tx, block, receipts))
+	require.NoError(t, e.Flush(context.Background(), tx))
+	require.Len(t, sink.sent, 1)
+	cursor, _, _ := Cursor(tx)
+	require.Equal(t, uint64(1), cursor)
+
+	// cancellation stops waiting, the cursor stays
+	sink.failures = 1 << 30
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	block, receipts = testBlock(2)
+	require.NoError(t, e.Block(ctx, tx, block, receipts))
+	require.Error(t, e.Flush(ctx, tx))
+	cursor, _, _ = Cursor(tx)
+	require.Equal(t, uint64(1), cursor)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.jsonl")
+	sink, err := OpenSink("file://" + path)
+	require.NoError(t, err)
+	block, receipts := testBlock(7)
+	msgs := []Message{{Type: BlockMessage, BlockNumber: 7, BlockHash: block.Hash(), Data: block.Header()}, {Type: ReceiptMessage, BlockNumber: 7, Data: receipts[0]}}
+	require.NoError(t, sink.Send(context.Background(), msgs))
+	require.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	var lines int
+	for scanner.Scan() {
+		var m struct {
+			Type        MessageType `json:"type"`
+			BlockNumber uint64      `json:"blockNumber"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &m))
+		require.Equal(t, msgs[lines].Type, m.Type)
+		require.Equal(t, uint64(7), m.BlockNumber)
+		lines++
+	}
+	require.Equal(t, 2, lines)
+
+	_, err = OpenSink("kafka://localhost:9092/blocks")
+	require.Error(t, err)
+}
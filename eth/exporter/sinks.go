@@ -0,0 +1,113 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// SinkFactory opens a sink by its URL
+type SinkFactory func(u *url.URL) (Sink, error)
+
+var (
+	sinksLock sync.Mutex
+	sinks     = map[string]SinkFactory{
+		"file":  func(u *url.URL) (Sink, error) { return OpenFileSink(u.Path) },
+		"http":  func(u *url.URL) (Sink, error) { return NewHTTPSink(u.String()), nil },
+		"https": func(u *url.URL) (Sink, error) { return NewHTTPSink(u.String()), nil },
+	}
+)
+
+// RegisterSink makes sinks of the URL scheme available to --export.sink, e.g. "kafka" or "nats" in builds with
+// their clients
+func RegisterSink(scheme string, factory SinkFactory) {
+	sinksLock.Lock()
+	defer sinksLock.Unlock()
+	sinks[scheme] = factory
+}
+
+// OpenSink opens the sink by URL with the factory registered for its scheme
+func OpenSink(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export sink %q: %w", rawURL, err)
+	}
+	sinksLock.Lock()
+	factory, ok := sinks[u.Scheme]
+	sinksLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported export sink scheme %q", u.Scheme)
+	}
+	return factory(u)
+}
+
+// FileSink appends messages to a file as JSON lines, synced to disk on every Send
+type FileSink struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func OpenFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (s *FileSink) Send(_ context.Context, msgs []Message) error {
+	enc := json.NewEncoder(s.w)
+	for i := range msgs {
+		if err := enc.Encode(&msgs[i]); err != nil {
+			return err
+		}
+	}
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *FileSink) Close() error { return s.f.Close() }
+
+// HTTPSink posts every batch as a JSON array, the receiver must answer 2xx after it stored the batch
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{url: url, client: &http.Client{Timeout: time.Minute}}
+}
+
+func (s *HTTPSink) Send(ctx context.Context, msgs []Message) error {
+	body, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("export sink %s answered %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *HTTPSink) Close() error { return nil }
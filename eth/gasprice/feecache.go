@@ -0,0 +1,75 @@
+package gasprice
+
+import (
+	"sync"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// FeeCache keeps the effective tips of transactions of the most recent blocks, sorted and weighted by gas used,
+// for eth_feeHistory: reward percentiles of these blocks are computed without reading their bodies and receipts.
+// Blocks are keyed by hash, blocks of a reorged chain are just not requested anymore. It's shared by oracles of
+// concurrent requests, a nil cache is disabled.
+type FeeCache struct {
+	lock   sync.Mutex
+	size   uint64 // blocks older than newest-size are evicted
+	newest uint64
+	blocks map[common.Hash]cachedFees
+}
+
+type cachedFees struct {
+	blockNumber uint64
+	sorted      sortGasAndReward
+}
+
+// NewFeeCache creates the cache of the given number of recent blocks, maxFeeHistory if size is 0
+func NewFeeCache(size int) *FeeCache {
+	if size <= 0 {
+		size = maxFeeHistory
+	}
+	return &FeeCache{size: uint64(size), blocks: map[common.Hash]cachedFees{}}
+}
+
+func (c *FeeCache) get(hash common.Hash) (sortGasAndReward, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	fees, ok := c.blocks[hash]
+	return fees.sorted, ok
+}
+
+func (c *FeeCache) put(hash common.Hash, blockNumber uint64, sorted sortGasAndReward) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if blockNumber+c.size <= c.newest {
+		return
+	}
+	c.blocks[hash] = cachedFees{blockNumber: blockNumber, sorted: sorted}
+	if blockNumber <= c.newest {
+		return
+	}
+	c.newest = blockNumber
+	if uint64(len(c.blocks)) <= c.size {
+		return
+	}
+	for h, fees := range c.blocks {
+		if fees.blockNumber+c.size <= c.newest {
+			delete(c.blocks, h)
+		}
+	}
+}
+
+// Len returns the number of cached blocks
+func (c *FeeCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.blocks)
+}
@@ -0,0 +1,93 @@
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// feeBackend - chain of blocks with transactions of tips 1..n gwei, n is the block number
+type feeBackend struct {
+	blocks     []*types.Block
+	bodyReads  int
+	receipts   map[common.Hash]types.Receipts
+	lastHeader int
+}
+
+func newFeeBackend(n, head int) *feeBackend {
+	b := &feeBackend{receipts: map[common.Hash]types.Receipts{}, lastHeader: head}
+	for i := 0; i <= n; i++ {
+		var txs []types.Transaction
+		var receipts types.Receipts
+		for j := 1; j <= i; j++ {
+			txs = append(txs, types.NewTransaction(uint64(j), common.Address{1}, uint256.NewInt(0), 21000, uint256.NewInt(uint64(j+1)*params.GWei), nil))
+			receipts = append(receipts, &types.Receipt{GasUsed: 21000})
+		}
+		header := &types.Header{Number: big.NewInt(int64(i)), GasLimit: 30_000_000, GasUsed: uint64(21000 * i), BaseFee: big.NewInt(params.GWei)}
+		block := types.NewBlock(header, txs, nil, receipts)
+		b.blocks = append(b.blocks, block)
+		b.receipts[block.Hash()] = receipts
+	}
+	return b
+}
+
+func (b *feeBackend) HeaderByNumber(_ context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(b.lastHeader)
+	}
+	return b.blocks[number].Header(), nil
+}
+
+func (b *feeBackend) BlockByNumber(_ context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	b.bodyReads++
+	return b.blocks[number], nil
+}
+
+func (b *feeBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+
+func (b *feeBackend) GetReceipts(_ context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.receipts[hash], nil
+}
+
+func (b *feeBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) { return nil, nil }
+
+func TestFeeHistoryCache(t *testing.T) {
+	ctx := context.Background()
+	backend := newFeeBackend(11, 10)
+	cache := NewFeeCache(4)
+	oracle := NewOracle(backend, Config{})
+	oracle.SetFeeCache(cache)
+
+	_, reward, _, _, err := oracle.FeeHistory(ctx, 10, rpc.LatestBlockNumber, []float64{0, 50, 100})
+	require.NoError(t, err)
+	require.Len(t, reward, 10)
+	require.Equal(t, []*big.Int{big.NewInt(params.GWei), big.NewInt(5 * params.GWei), big.NewInt(10 * params.GWei)}, reward[9])
+	require.Equal(t, 10, backend.bodyReads)
+	require.Equal(t, 4, cache.Len(), "only the most recent blocks")
+
+	// other percentiles of cached blocks
+	_, cachedReward, _, _, err := oracle.FeeHistory(ctx, 4, rpc.LatestBlockNumber, []float64{20})
+	require.NoError(t, err)
+	require.Equal(t, 10, backend.bodyReads)
+	require.Equal(t, []*big.Int{big.NewInt(2 * params.GWei)}, cachedReward[3])
+
+	uncached := NewOracle(backend, Config{})
+	_, expected, _, _, err := uncached.FeeHistory(ctx, 4, rpc.LatestBlockNumber, []float64{20})
+	require.NoError(t, err)
+	require.Equal(t, expected, cachedReward)
+
+	// the new head evicts the oldest block
+	backend.lastHeader = 11
+	_, _, _, _, err = oracle.FeeHistory(ctx, 1, rpc.LatestBlockNumber, []float64{50})
+	require.NoError(t, err)
+	require.Equal(t, 4, cache.Len())
+	_, ok := cache.get(backend.blocks[7].Hash())
+	require.False(t, ok)
+}
@@ -49,8 +49,10 @@ type blockFees struct {
 	// set by the caller
 	blockNumber uint64
 	header      *types.Header
-	block       *types.Block // only set if reward percentiles are requested
+	block       *types.Block // only set if reward percentiles are requested and the block isn't cached
 	receipts    types.Receipts
+	// set by the caller from FeeCache, or filled by processBlock from block and receipts
+	sorted sortGasAndReward
 	// filled by processBlock
 	reward               []*big.Int
 	baseFee, nextBaseFee *big.Int
@@ -93,13 +95,16 @@ func (oracle *Oracle) processBlock(bf *blockFees, percentiles []float64) {
 		// rewards were not requested, return null
 		return
 	}
-	if bf.block == nil || (bf.receipts == nil && len(bf.block.Transactions()) != 0) {
-		log.Error("Block or receipts are missing while reward percentiles are requested")
-		return
+	if bf.sorted == nil {
+		if bf.block == nil || (len(bf.receipts) != len(bf.block.Transactions())) {
+			log.Error("Block or receipts are missing while reward percentiles are requested", "block", bf.blockNumber)
+			return
+		}
+		bf.sorted = sortedRewards(bf.block, bf.receipts)
 	}
 
 	bf.reward = make([]*big.Int, len(percentiles))
-	if len(bf.block.Transactions()) == 0 {
+	if len(bf.sorted) == 0 {
 		// return an all zero row if there are no transactions to gather data from
 		for i := range bf.reward {
 			bf.reward[i] = new(big.Int)
@@ -107,30 +112,34 @@ func (oracle *Oracle) processBlock(bf *blockFees, percentiles []float64) {
 		return
 	}
 
-	sorter := make(sortGasAndReward, len(bf.block.Transactions()))
-	baseFee := uint256.NewInt(0)
-	if bf.block.BaseFee() != nil {
-		baseFee.SetFromBig(bf.block.BaseFee())
-	}
-	for i, tx := range bf.block.Transactions() {
-		reward := tx.GetEffectiveGasTip(baseFee)
-		sorter[i] = txGasAndReward{gasUsed: bf.receipts[i].GasUsed, reward: reward.ToBig()}
-	}
-	sort.Sort(sorter)
-
 	var txIndex int
-	sumGasUsed := sorter[0].gasUsed
+	sumGasUsed := bf.sorted[0].gasUsed
 
 	for i, p := range percentiles {
-		thresholdGasUsed := uint64(float64(bf.block.GasUsed()) * p / 100)
-		for sumGasUsed < thresholdGasUsed && txIndex < len(bf.block.Transactions())-1 {
+		thresholdGasUsed := uint64(float64(bf.header.GasUsed) * p / 100)
+		for sumGasUsed < thresholdGasUsed && txIndex < len(bf.sorted)-1 {
 			txIndex++
-			sumGasUsed += sorter[txIndex].gasUsed
+			sumGasUsed += bf.sorted[txIndex].gasUsed
 		}
-		bf.reward[i] = sorter[txIndex].reward
+		bf.reward[i] = bf.sorted[txIndex].reward
 	}
 }
 
+// sortedRewards returns the effective tips of transactions of the block with their gas used, in ascending order of tips
+func sortedRewards(block *types.Block, receipts types.Receipts) sortGasAndReward {
+	sorter := make(sortGasAndReward, len(block.Transactions()))
+	baseFee := uint256.NewInt(0)
+	if block.BaseFee() != nil {
+		baseFee.SetFromBig(block.BaseFee())
+	}
+	for i, tx := range block.Transactions() {
+		reward := tx.GetEffectiveGasTip(baseFee)
+		sorter[i] = txGasAndReward{gasUsed: receipts[i].GasUsed, reward: reward.ToBig()}
+	}
+	sort.Sort(sorter)
+	return sorter
+}
+
 // resolveBlockRange resolves the specified block range to absolute block numbers while also
 // enforcing backend specific limitations. The pending block and corresponding receipts are
 // also returned if requested and available.
@@ -256,20 +265,26 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 		if pendingBlock != nil && blockNumber >= pendingBlock.NumberU64() {
 			fees.block, fees.receipts = pendingBlock, pendingReceipts
 		} else {
-			if len(rewardPercentiles) != 0 {
+			fees.header, fees.err = oracle.backend.HeaderByNumber(ctx, rpc.BlockNumber(blockNumber))
+			cached := false
+			if fees.header != nil && fees.err == nil && len(rewardPercentiles) != 0 {
+				fees.sorted, cached = oracle.feeCache.get(fees.header.Hash())
+			}
+			if fees.header != nil && fees.err == nil && len(rewardPercentiles) != 0 && !cached {
 				fees.block, fees.err = oracle.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNumber))
 				if fees.block != nil && fees.err == nil {
 					fees.receipts, fees.err = oracle.backend.GetReceipts(ctx, fees.block.Hash())
 				}
-			} else {
-				fees.header, fees.err = oracle.backend.HeaderByNumber(ctx, rpc.BlockNumber(blockNumber))
 			}
 		}
 		if fees.block != nil {
 			fees.header = fees.block.Header()
 		}
-		if fees.header != nil {
+		if fees.header != nil && fees.err == nil {
 			oracle.processBlock(fees, rewardPercentiles)
+			if fees.block != nil && fees.sorted != nil && fees.block != pendingBlock {
+				oracle.feeCache.put(fees.block.Hash(), blockNumber, fees.sorted)
+			}
 		}
 
 		if fees.err != nil {
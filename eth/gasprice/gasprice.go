@@ -71,6 +71,8 @@ type Oracle struct {
 	checkBlocks                       int
 	percentile                        int
 	maxHeaderHistory, maxBlockHistory int
+
+	feeCache *FeeCache // processed fees of recent blocks shared with other oracles, nil - disabled
 }
 
 // NewOracle returns a new gasprice oracle which can recommend suitable
@@ -112,6 +114,11 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 	}
 }
 
+// SetFeeCache makes FeeHistory use and fill the cache of recent blocks
+func (gpo *Oracle) SetFeeCache(cache *FeeCache) {
+	gpo.feeCache = cache
+}
+
 // SuggestTipCap returns a TipCap so that newly created transaction can
 // have a very high chance to be included in the following blocks.
 // NODE: if caller wants legacy tx SuggestedPrice, we need to add
@@ -0,0 +1,184 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// Limits of the shape of messages received from peers, in addition to ProtocolMaxMsgSize.
+// Messages above them are rejected by DecodeMessage before they're processed.
+const (
+	// maxAnnounces is the maximum number of hashes in NewBlockHashes and NewPooledTransactionHashes
+	maxAnnounces = 4096
+
+	// maxQueryHashes is the maximum number of hashes in GetBlockBodies, GetReceipts, GetNodeData
+	// and GetPooledTransactions, peers ask for much less of them
+	maxQueryHashes = 4096
+
+	// maxUncles is the maximum number of uncles of a block allowed by the consensus rules
+	maxUncles = 2
+
+	// maxTDBitLen is the maximum length of the total difficulty in Status and NewBlock. TD at mainnet
+	// block #7753254 is 76 bits. If it becomes 100 million times larger, it will still fit within 100 bits
+	maxTDBitLen = 100
+
+	// maxDifficultyBitLen is the maximum length of difficulties of delivered headers and of the total difficulty
+	// in Status. Difficulties of AuRa chains are 128 bits, so their total difficulty is above maxTDBitLen
+	maxDifficultyBitLen = 256
+)
+
+// ErrMalformedMessage is returned by DecodeMessage for messages which can't be processed,
+// the peer sending them is buggy or malicious
+var ErrMalformedMessage = errors.New("malformed message")
+
+// ErrDecodingPanicked is the ErrMalformedMessage of a message which panicked the decoder,
+// it's a bug of the decoder to be fixed
+var ErrDecodingPanicked = fmt.Errorf("%w: decoding panicked", ErrMalformedMessage)
+
+// sanityChecker is implemented by packets which check their shape after decoding
+type sanityChecker interface {
+	SanityCheck() error
+}
+
+// DecodeMessage decodes a message of a peer into val and checks its size and shape. It never panics,
+// errors caused by the content of the message are either rlp errors (see rlp.IsInvalidRLPError) or ErrMalformedMessage.
+func DecodeMessage(data []byte, val interface{}) (err error) {
+	if len(data) > ProtocolMaxMsgSize {
+		return fmt.Errorf("%w: size %d, limit %d", ErrMalformedMessage, len(data), ProtocolMaxMsgSize)
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%w: %v", ErrDecodingPanicked, rec)
+		}
+	}()
+	if err = rlp.DecodeBytes(data, val); err != nil {
+		return err
+	}
+	if c, ok := val.(sanityChecker); ok {
+		if err = c.SanityCheck(); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+		}
+	}
+	return nil
+}
+
+// SanityCheck verifies that the values are reasonable, as a DoS protection
+func (p *StatusPacket) SanityCheck() error {
+	if p.TD == nil {
+		return errors.New("missing TD")
+	}
+	if tdLen := p.TD.BitLen(); tdLen > maxDifficultyBitLen {
+		return fmt.Errorf("too large TD: bitlen %d", tdLen)
+	}
+	return nil
+}
+
+// SanityCheck verifies that the number of announces is reasonable
+func (p *NewBlockHashesPacket) SanityCheck() error {
+	if len(*p) > maxAnnounces {
+		return fmt.Errorf("too many block announces: %d, limit %d", len(*p), maxAnnounces)
+	}
+	return nil
+}
+
+// SanityCheck verifies that the number of announces is reasonable
+func (p *NewPooledTransactionHashesPacket) SanityCheck() error {
+	if len(*p) > maxAnnounces {
+		return fmt.Errorf("too many transaction announces: %d, limit %d", len(*p), maxAnnounces)
+	}
+	return nil
+}
+
+// SanityCheck verifies that the query is given
+func (p *GetBlockHeadersPacket66) SanityCheck() error {
+	if p.GetBlockHeadersPacket == nil {
+		return errors.New("missing query")
+	}
+	return nil
+}
+
+// SanityCheck verifies that the headers are no more than a peer serves, and their values are reasonable
+func (p *BlockHeadersPacket66) SanityCheck() error {
+	if len(p.BlockHeadersPacket) > MaxHeadersServe {
+		return fmt.Errorf("too many headers: %d, limit %d", len(p.BlockHeadersPacket), MaxHeadersServe)
+	}
+	for i, header := range p.BlockHeadersPacket {
+		if err := checkHeader(header); err != nil {
+			return fmt.Errorf("header %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// SanityCheck verifies that the bodies are no more than a peer serves, and their uncles are valid
+func (p *BlockRawBodiesPacket66) SanityCheck() error {
+	if len(p.BlockRawBodiesPacket) > MaxBodiesServe {
+		return fmt.Errorf("too many bodies: %d, limit %d", len(p.BlockRawBodiesPacket), MaxBodiesServe)
+	}
+	for i, body := range p.BlockRawBodiesPacket {
+		if body == nil {
+			return fmt.Errorf("body %d: missing", i)
+		}
+		if len(body.Uncles) > maxUncles {
+			return fmt.Errorf("body %d: too many uncles: %d", i, len(body.Uncles))
+		}
+		for j, uncle := range body.Uncles {
+			if err := checkHeader(uncle); err != nil {
+				return fmt.Errorf("body %d uncle %d: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkHeader - the checks of Header.SanityCheck, whose difficulty limit is only for NewBlock of proof-of-work chains
+func checkHeader(header *types.Header) error {
+	if header == nil || header.Number == nil || header.Difficulty == nil {
+		return errors.New("missing number or difficulty")
+	}
+	if !header.Number.IsUint64() {
+		return fmt.Errorf("too large block number: bitlen %d", header.Number.BitLen())
+	}
+	if diffLen := header.Difficulty.BitLen(); diffLen > maxDifficultyBitLen {
+		return fmt.Errorf("too large block difficulty: bitlen %d", diffLen)
+	}
+	if eLen := len(header.Extra); eLen > 100*1024 {
+		return fmt.Errorf("too large block extradata: size %d", eLen)
+	}
+	if header.BaseFee != nil {
+		if bfLen := header.BaseFee.BitLen(); bfLen > 256 {
+			return fmt.Errorf("too large base fee: bitlen %d", bfLen)
+		}
+	}
+	return nil
+}
+
+// SanityCheck verifies that the number of queried hashes is reasonable
+func (p *GetBlockBodiesPacket66) SanityCheck() error {
+	return checkQueryHashes(len(p.GetBlockBodiesPacket))
+}
+
+// SanityCheck verifies that the number of queried hashes is reasonable
+func (p *GetReceiptsPacket66) SanityCheck() error {
+	return checkQueryHashes(len(p.GetReceiptsPacket))
+}
+
+// SanityCheck verifies that the number of queried hashes is reasonable
+func (p *GetNodeDataPacket66) SanityCheck() error {
+	return checkQueryHashes(len(p.GetNodeDataPacket))
+}
+
+// SanityCheck verifies that the number of queried hashes is reasonable
+func (p *GetPooledTransactionsPacket66) SanityCheck() error {
+	return checkQueryHashes(len(p.GetPooledTransactionsPacket))
+}
+
+func checkQueryHashes(n int) error {
+	if n > maxQueryHashes {
+		return fmt.Errorf("too many queried hashes: %d, limit %d", n, maxQueryHashes)
+	}
+	return nil
+}
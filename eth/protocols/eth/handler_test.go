@@ -122,7 +122,8 @@ func TestGetBlockReceipts(t *testing.T) {
 		// GetReceiptsMsg disabled for historyV3
 	} else {
 		m.ReceiveWg.Wait()
-		sent := m.SentMessage(0)
+		// header requests backfilling announced blocks are sent while the chain is inserted
+		sent := m.SentMessage(m.SentMessageCount() - 1)
 		require.Equal(t, eth.ToProto[m.SentryClient.Protocol()][eth.ReceiptsMsg], sent.Id)
 		require.Equal(t, expect, sent.Data)
 	}
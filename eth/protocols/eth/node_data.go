@@ -0,0 +1,115 @@
+package eth
+
+import (
+	"errors"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxNodeDataServe is the maximum number of state trie nodes to serve. This
+	// number is there to limit the number of disk lookups.
+	maxNodeDataServe = 384
+
+	// nodeDataBudget is the maximum number of disk lookups to build one trie node.
+	// Nodes without intermediate hashes of their children are not served.
+	nodeDataBudget = 256
+
+	// nodeDataPaths is the number of remembered locations of served nodes' children.
+	nodeDataPaths = 1_000_000
+)
+
+// NodeDataServer answers legacy GetNodeData queries (eth/66 only). Erigon doesn't store trie nodes by hash:
+// a node is built from the hashed state and intermediate hashes by its path, and the path of a node is known
+// only after its parent was served. So peers can get nodes of the current state only, walking from its root -
+// this is what fast sync does.
+type NodeDataServer struct {
+	limiter *rate.Limiter
+	paths   *lru.Cache // hash -> trie.NodePath
+}
+
+// NewNodeDataServer - nodesPerSecond limits the amount of trie nodes served to all peers
+func NewNodeDataServer(nodesPerSecond int) (*NodeDataServer, error) {
+	paths, err := lru.New(nodeDataPaths)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeDataServer{limiter: rate.NewLimiter(rate.Limit(nodesPerSecond), maxNodeDataServe), paths: paths}, nil
+}
+
+func (s *NodeDataServer) AnswerGetNodeDataQuery(db kv.Tx, query GetNodeDataPacket) ([][]byte, error) {
+	stateRoot, err := readStateRoot(db)
+	if err != nil {
+		return nil, err
+	}
+	// Gather state data until the fetch or network limits is reached
+	var (
+		bytes int
+		nodes [][]byte
+	)
+	for lookups, hash := range query {
+		if bytes >= softResponseLimit || len(nodes) >= maxNodeDataServe ||
+			lookups >= 2*maxNodeDataServe || !s.limiter.Allow() {
+			break
+		}
+		code, err := db.GetOne(kv.Code, hash[:])
+		if err != nil {
+			return nil, err
+		}
+		if len(code) > 0 {
+			nodes = append(nodes, common.CopyBytes(code))
+			bytes += len(code)
+			continue
+		}
+		var path trie.NodePath
+		if cached, ok := s.paths.Get(hash); ok {
+			path = cached.(trie.NodePath)
+		} else if hash != stateRoot {
+			continue
+		}
+		enc, refs, err := trie.ResolveNode(db, path, nodeDataBudget)
+		if err != nil {
+			if errors.Is(err, trie.ErrNodeTooExpensive) {
+				continue
+			}
+			return nil, err
+		}
+		if enc == nil || crypto.Keccak256Hash(enc) != hash { // the state has changed since the parent was served
+			continue
+		}
+		for _, ref := range refs {
+			s.paths.Add(ref.Hash, ref.Path)
+		}
+		nodes = append(nodes, enc)
+		bytes += len(enc)
+	}
+	return nodes, nil
+}
+
+// readStateRoot - root of the state which is in the db: intermediate hashes are up to date at the progress
+// of IntermediateHashes stage
+func readStateRoot(db kv.Tx) (common.Hash, error) {
+	progress, err := stages.GetStageProgress(db, stages.IntermediateHashes)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if execution, err := stages.GetStageProgress(db, stages.Execution); err != nil || execution != progress {
+		return common.Hash{}, err // the state is ahead of intermediate hashes
+	}
+	hash, err := rawdb.ReadCanonicalHash(db, progress)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	header := rawdb.ReadHeader(db, hash, progress)
+	if header == nil {
+		return common.Hash{}, nil
+	}
+	return header.Root, nil
+}
@@ -0,0 +1,74 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnswerGetNodeDataQuery(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+	code := []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+	codeHash := crypto.Keccak256Hash(code)
+	require.NoError(tx.Put(kv.Code, codeHash[:], code))
+	for i := byte(0); i < 10; i++ {
+		acc := accounts.NewAccount()
+		acc.Balance.SetUint64(uint64(i) + 1)
+		if i == 0 {
+			acc.Incarnation, acc.CodeHash = 1, codeHash
+		}
+		v := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(v)
+		addrHash := crypto.Keccak256([]byte{i})
+		require.NoError(tx.Put(kv.HashedAccounts, addrHash, v))
+	}
+	loader := trie.NewFlatDBTrieLoader("test")
+	require.NoError(loader.Reset(trie.NewRetainList(0), nil, nil, false))
+	root, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(err)
+	header := &types.Header{Number: big.NewInt(5), Root: root}
+	rawdb.WriteHeader(tx, header)
+	require.NoError(rawdb.WriteCanonicalHash(tx, header.Hash(), 5))
+	require.NoError(stages.SaveStageProgress(tx, stages.Execution, 5))
+	require.NoError(stages.SaveStageProgress(tx, stages.IntermediateHashes, 5))
+
+	s, err := NewNodeDataServer(10_000)
+	require.NoError(err)
+	nodes, err := s.AnswerGetNodeDataQuery(tx, GetNodeDataPacket{{1}, root, codeHash})
+	require.NoError(err)
+	require.Len(nodes, 2)
+	require.Equal(root, crypto.Keccak256Hash(nodes[0]))
+	require.Equal(code, nodes[1])
+
+	// children are served once their parent was served
+	var children GetNodeDataPacket
+	for _, k := range s.paths.Keys() {
+		children = append(children, k.(common.Hash))
+	}
+	require.NotEmpty(children)
+	nodes, err = s.AnswerGetNodeDataQuery(tx, children)
+	require.NoError(err)
+	require.Len(nodes, len(children))
+	for i, node := range nodes {
+		require.Equal(children[i], crypto.Keccak256Hash(node))
+	}
+
+	// state is ahead of intermediate hashes - the root is unknown
+	require.NoError(stages.SaveStageProgress(tx, stages.Execution, 6))
+	s, err = NewNodeDataServer(10_000)
+	require.NoError(err)
+	nodes, err = s.AnswerGetNodeDataQuery(tx, GetNodeDataPacket{root})
+	require.NoError(err)
+	require.Empty(nodes)
+}
@@ -380,9 +380,7 @@ func (request *NewBlockPacket) SanityCheck() error {
 	if err := request.Block.SanityCheck(); err != nil {
 		return err
 	}
-	//TD at mainnet block #7753254 is 76 bits. If it becomes 100 million times
-	// larger, it will still fit within 100 bits
-	if tdLen := request.TD.BitLen(); tdLen > 100 {
+	if tdLen := request.TD.BitLen(); tdLen > maxTDBitLen {
 		return fmt.Errorf("too large block TD: bitlen %d", tdLen)
 	}
 	return nil
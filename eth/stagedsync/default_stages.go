@@ -8,7 +8,7 @@ import (
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 )
 
-func DefaultStages(ctx context.Context, sm prune.Mode, snapshots SnapshotsCfg, headers HeadersCfg, cumulativeIndex CumulativeIndexCfg, blockHashCfg BlockHashesCfg, bodies BodiesCfg, issuance IssuanceCfg, senders SendersCfg, exec ExecuteBlockCfg, hashState HashStateCfg, trieCfg TrieCfg, history HistoryCfg, logIndex LogIndexCfg, callTraces CallTracesCfg, txLookup TxLookupCfg, finish FinishCfg, test bool) []*Stage {
+func DefaultStages(ctx context.Context, sm prune.Mode, snapshots SnapshotsCfg, headers HeadersCfg, cumulativeIndex CumulativeIndexCfg, blockHashCfg BlockHashesCfg, bodies BodiesCfg, issuance IssuanceCfg, senders SendersCfg, exec ExecuteBlockCfg, externalExec ExternalExecCfg, hashState HashStateCfg, trieCfg TrieCfg, history HistoryCfg, logIndex LogIndexCfg, tokenTransfers TokenTransfersCfg, callTraces CallTracesCfg, txLookup TxLookupCfg, finish FinishCfg, test bool) []*Stage {
 	return []*Stage{
 		{
 			ID:          stages.Snapshots,
@@ -107,6 +107,17 @@ func DefaultStages(ctx context.Context, sm prune.Mode, snapshots SnapshotsCfg, h
 				return PruneExecutionStage(p, tx, exec, ctx, firstCycle)
 			},
 		},
+		{
+			ID:                  stages.ExternalExecution,
+			Description:         "Execute blocks by the external executor",
+			DisabledDescription: "Enabled by --sync.mode=external",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				return SpawnExternalExecStage(s, u, tx, ctx, externalExec, quiet)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return UnwindExternalExecStage(u, s, tx, ctx, externalExec)
+			},
+		},
 		{
 			ID:          stages.HashState,
 			Description: "Hash the key in the state",
@@ -198,6 +209,20 @@ func DefaultStages(ctx context.Context, sm prune.Mode, snapshots SnapshotsCfg, h
 				return PruneLogIndex(p, tx, logIndex, ctx)
 			},
 		},
+		{
+			ID:          stages.TokenTransfers,
+			Description: "Generate token transfers index",
+			Disabled:    bodies.historyV3,
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				return SpawnTokenTransfers(s, tx, tokenTransfers, ctx)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return UnwindTokenTransfers(u, s, tx, tokenTransfers, ctx)
+			},
+			Prune: func(firstCycle bool, p *PruneState, tx kv.RwTx) error {
+				return PruneTokenTransfers(p, tx, tokenTransfers, ctx)
+			},
+		},
 		{
 			ID:          stages.TxLookup,
 			Description: "Generate tx lookup index",
@@ -326,6 +351,7 @@ var DefaultForwardOrder = UnwindOrder{
 	// Stages below don't use Internet
 	stages.Senders,
 	stages.Execution,
+	stages.ExternalExecution,
 	stages.Translation,
 	stages.HashState,
 	stages.IntermediateHashes,
@@ -333,6 +359,7 @@ var DefaultForwardOrder = UnwindOrder{
 	stages.AccountHistoryIndex,
 	stages.StorageHistoryIndex,
 	stages.LogIndex,
+	stages.TokenTransfers,
 	stages.TxLookup,
 	stages.Finish,
 }
@@ -347,6 +374,7 @@ type PruneOrder []stages.SyncStage
 var DefaultUnwindOrder = UnwindOrder{
 	stages.Finish,
 	stages.TxLookup,
+	stages.TokenTransfers,
 	stages.LogIndex,
 	stages.StorageHistoryIndex,
 	stages.AccountHistoryIndex,
@@ -358,6 +386,7 @@ var DefaultUnwindOrder = UnwindOrder{
 
 	stages.Translation,
 	stages.Execution,
+	stages.ExternalExecution,
 	stages.Senders,
 
 	stages.Bodies,
@@ -380,6 +409,7 @@ var DefaultPruneOrder = PruneOrder{
 	stages.Finish,
 	stages.Snapshots,
 	stages.TxLookup,
+	stages.TokenTransfers,
 	stages.LogIndex,
 	stages.StorageHistoryIndex,
 	stages.AccountHistoryIndex,
@@ -0,0 +1,166 @@
+package stagedsync
+
+import (
+	"fmt"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common/cmp"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// VerifyAfterDirtyShutdown re-verifies the output of the stages for the last `blocks` blocks of each
+// stage, after the node was not shut down cleanly. Blocks up to `frozenBlocks` live in snapshots and
+// are not checked.
+//   - Missing TxLookup entries are written again in place.
+//   - For broken headers linkage, missing bodies or senders, receipts not matching the receipts root -
+//     returns the lowest bad block, the caller must unwind to the block before it.
+func VerifyAfterDirtyShutdown(tx kv.RwTx, chainConfig *params.ChainConfig, blocks, frozenBlocks uint64, logPrefix string) (badBlock uint64, found bool, err error) {
+	progress := map[stages.SyncStage]uint64{}
+	for _, stage := range []stages.SyncStage{stages.Headers, stages.Bodies, stages.Senders, stages.Execution, stages.TxLookup} {
+		if progress[stage], err = stages.GetStageProgress(tx, stage); err != nil {
+			return 0, false, err
+		}
+	}
+	verifyFrom := func(stage stages.SyncStage) uint64 {
+		if progress[stage] < blocks {
+			return libcommon.Max(1, frozenBlocks+1)
+		}
+		return libcommon.Max(progress[stage]-blocks+1, frozenBlocks+1)
+	}
+	markBad := func(blockNum uint64, stage stages.SyncStage, reason string) {
+		log.Warn(fmt.Sprintf("[%s] Inconsistency after dirty shutdown", logPrefix), "stage", stage, "block", blockNum, "reason", reason)
+		if !found || blockNum < badBlock {
+			badBlock, found = blockNum, true
+		}
+	}
+
+	log.Info(fmt.Sprintf("[%s] Node was not shut down cleanly, verifying last blocks of each stage", logPrefix), "blocks", blocks)
+
+	// Headers: canonical chain is present and linked
+	for blockNum := verifyFrom(stages.Headers); blockNum <= progress[stages.Headers]; blockNum++ {
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return 0, false, err
+		}
+		header := rawdb.ReadHeader(tx, hash, blockNum)
+		if hash == (common.Hash{}) || header == nil {
+			markBad(blockNum, stages.Headers, "missing canonical header")
+			break
+		}
+		if blockNum <= frozenBlocks+1 {
+			continue
+		}
+		parentHash, err := rawdb.ReadCanonicalHash(tx, blockNum-1)
+		if err != nil {
+			return 0, false, err
+		}
+		if header.ParentHash != parentHash {
+			markBad(blockNum, stages.Headers, "header is not linked to the canonical parent")
+			break
+		}
+	}
+
+	// Bodies and senders
+	for blockNum := verifyFrom(stages.Bodies); blockNum <= progress[stages.Bodies]; blockNum++ {
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return 0, false, err
+		}
+		body, err := rawdb.ReadBodyForStorageByKey(tx, dbutils.BlockBodyKey(blockNum, hash))
+		if err != nil {
+			return 0, false, err
+		}
+		if body == nil {
+			markBad(blockNum, stages.Bodies, "missing body")
+			break
+		}
+		if blockNum < verifyFrom(stages.Senders) || blockNum > progress[stages.Senders] {
+			continue
+		}
+		senders, err := rawdb.ReadSenders(tx, hash, blockNum)
+		if err != nil {
+			return 0, false, err
+		}
+		if len(senders) != int(body.TxAmount)-2 {
+			markBad(blockNum, stages.Senders, fmt.Sprintf("have %d senders for %d transactions", len(senders), body.TxAmount-2))
+			break
+		}
+	}
+
+	// Receipts: only stored, not pruned ones
+	for blockNum := verifyFrom(stages.Execution); blockNum <= progress[stages.Execution]; blockNum++ {
+		if !chainConfig.IsByzantium(blockNum) {
+			continue // receipts root of older blocks includes intermediate state roots, which are not stored
+		}
+		receipts := rawdb.ReadRawReceipts(tx, blockNum)
+		if receipts == nil {
+			continue
+		}
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return 0, false, err
+		}
+		block, _, err := rawdb.ReadBlockWithSenders(tx, hash, blockNum)
+		if err != nil {
+			return 0, false, err
+		}
+		if block == nil || len(block.Transactions()) != len(receipts) {
+			markBad(blockNum, stages.Execution, "amount of receipts doesn't match amount of transactions")
+			break
+		}
+		for i, r := range receipts {
+			r.Type = block.Transactions()[i].Type()
+			r.Bloom = types.CreateBloom(types.Receipts{r})
+		}
+		if root := types.DeriveSha(receipts); root != block.ReceiptHash() {
+			markBad(blockNum, stages.Execution, fmt.Sprintf("receipts root %x, header has %x", root, block.ReceiptHash()))
+			break
+		}
+	}
+
+	// TxLookup: entries can be restored right away
+	pruneMode, err := prune.Get(tx)
+	if err != nil {
+		return 0, false, err
+	}
+	from := libcommon.Max(verifyFrom(stages.TxLookup), pruneMode.TxIndex.PruneTo(progress[stages.TxLookup]))
+	repaired := 0
+	for blockNum := from; blockNum <= progress[stages.TxLookup]; blockNum++ {
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return 0, false, err
+		}
+		body := rawdb.ReadCanonicalBodyWithTransactions(tx, hash, blockNum)
+		if body == nil {
+			continue // reported by bodies check
+		}
+		for i, txn := range body.Transactions {
+			number, err := rawdb.ReadTxLookupEntry(tx, txn.Hash())
+			if err != nil {
+				return 0, false, err
+			}
+			if number != nil && *number == blockNum {
+				continue
+			}
+			if err := tx.Put(kv.TxLookup, txn.Hash().Bytes(), rawdb.TxLookupValue(blockNum, uint64(i))); err != nil {
+				return 0, false, err
+			}
+			repaired++
+		}
+	}
+	if repaired > 0 {
+		log.Warn(fmt.Sprintf("[%s] Restored missing transaction lookup entries", logPrefix), "amount", repaired)
+	}
+
+	if !found {
+		log.Info(fmt.Sprintf("[%s] No inconsistencies found", logPrefix))
+	}
+	return badBlock, found, nil
+}
@@ -0,0 +1,68 @@
+package stagedsync
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAfterDirtyShutdown(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require := require.New(t)
+
+	var blocks []*types.Block
+	parentHash := common.Hash{}
+	for i := uint64(0); i <= 10; i++ {
+		txn := types.NewTransaction(i, common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil)
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i), ParentHash: parentHash}, []types.Transaction{txn}, nil, nil)
+		require.NoError(rawdb.WriteBlock(tx, block))
+		require.NoError(rawdb.WriteCanonicalHash(tx, block.Hash(), i))
+		require.NoError(rawdb.WriteSenders(tx, block.Hash(), i, []common.Address{{2}}))
+		rawdb.WriteTxLookupEntries(tx, block)
+		blocks = append(blocks, block)
+		parentHash = block.Hash()
+	}
+	for _, stage := range []stages.SyncStage{stages.Headers, stages.Bodies, stages.Senders, stages.Execution, stages.TxLookup} {
+		require.NoError(stages.SaveStageProgress(tx, stage, 10))
+	}
+
+	_, found, err := VerifyAfterDirtyShutdown(tx, params.TestChainConfig, 5, 0, "test")
+	require.NoError(err)
+	require.False(found)
+
+	// missing lookup entry is restored in place
+	lost := blocks[9].Transactions()[0].Hash()
+	require.NoError(rawdb.DeleteTxLookupEntry(tx, lost))
+	_, found, err = VerifyAfterDirtyShutdown(tx, params.TestChainConfig, 5, 0, "test")
+	require.NoError(err)
+	require.False(found)
+	number, err := rawdb.ReadTxLookupEntry(tx, lost)
+	require.NoError(err)
+	require.NotNil(number)
+	require.Equal(uint64(9), *number)
+
+	// missing senders require re-doing the blocks, but only the last ones are checked
+	require.NoError(tx.Delete(kv.Senders, dbutils.BlockBodyKey(8, blocks[8].Hash())))
+	require.NoError(tx.Delete(kv.Senders, dbutils.BlockBodyKey(3, blocks[3].Hash())))
+	badBlock, found, err := VerifyAfterDirtyShutdown(tx, params.TestChainConfig, 5, 0, "test")
+	require.NoError(err)
+	require.True(found)
+	require.Equal(uint64(8), badBlock)
+
+	// broken headers linkage
+	require.NoError(rawdb.WriteCanonicalHash(tx, common.Hash{0xff}, 7))
+	badBlock, found, err = VerifyAfterDirtyShutdown(tx, params.TestChainConfig, 5, 0, "test")
+	require.NoError(err)
+	require.True(found)
+	require.Equal(uint64(7), badBlock)
+}
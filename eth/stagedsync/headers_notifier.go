@@ -0,0 +1,98 @@
+package stagedsync
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/kvwatch"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// HeadersNotifier announces new canonical headers to RPC subscribers after each commit of the Finish
+// stage progress, instead of the stage loop doing it inline. Canonical chain changes committed since
+// previous announcement tell how deep the chain was unwound.
+type HeadersNotifier struct {
+	db        kv.RoDB
+	notifier  ChainEventNotifier
+	progress  *kvwatch.Subscription
+	canonical *kvwatch.Subscription
+	notified  uint64 // Finish stage progress of the last announcement
+}
+
+// NewHeadersNotifier subscribes right away - commits which happen before Loop is started are not missed
+func NewHeadersNotifier(ctx context.Context, db kv.RoDB, watcher kvwatch.Watcher, notifier ChainEventNotifier) (*HeadersNotifier, error) {
+	n := &HeadersNotifier{
+		db:        db,
+		notifier:  notifier,
+		progress:  watcher.Watch(kv.SyncStageProgress, []byte(stages.Finish)),
+		canonical: watcher.Watch(kv.HeaderCanonical, nil),
+	}
+	if err := db.View(ctx, func(tx kv.Tx) (err error) {
+		n.notified, err = stages.GetStageProgress(tx, stages.Finish)
+		return err
+	}); err != nil {
+		n.progress.Close()
+		n.canonical.Close()
+		return nil, err
+	}
+	return n, nil
+}
+
+// Loop runs until ctx is done or database is closed
+func (n *HeadersNotifier) Loop(ctx context.Context) {
+	defer n.progress.Close()
+	defer n.canonical.Close()
+
+	var unwindTo *uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-n.canonical.Notify():
+			if !ok {
+				return
+			}
+			unwindTo = n.canonicalUnwind(unwindTo)
+		case _, ok := <-n.progress.Notify():
+			if !ok {
+				return
+			}
+			n.progress.Changes()
+			// canonical chain is committed before or together with the Finish stage progress
+			unwindTo = n.canonicalUnwind(unwindTo)
+			if err := n.db.View(ctx, func(tx kv.Tx) error {
+				finish, err := stages.GetStageProgress(tx, stages.Finish)
+				if err != nil {
+					return err
+				}
+				if err = NotifyNewHeaders(ctx, n.notified, finish, unwindTo, n.notifier, tx, n.db); err != nil {
+					return err
+				}
+				n.notified = finish
+				return nil
+			}); err != nil {
+				log.Warn("[HeadersNotifier] notification failed", "err", err)
+			}
+			unwindTo = nil
+		}
+	}
+}
+
+// canonicalUnwind returns the block before the lowest already announced block, which canonical hash was changed
+func (n *HeadersNotifier) canonicalUnwind(unwindTo *uint64) *uint64 {
+	keys, _ := n.canonical.Changes() // on overflow the depth of unwind is unknown - only new blocks are announced
+	if len(keys) == 0 || len(keys[0]) != 8 {
+		return unwindTo
+	}
+	lowest := binary.BigEndian.Uint64(keys[0])
+	if lowest == 0 || lowest > n.notified {
+		return unwindTo
+	}
+	if unwindTo == nil || lowest-1 < *unwindTo {
+		unwindPoint := lowest - 1
+		return &unwindPoint
+	}
+	return unwindTo
+}
@@ -0,0 +1,63 @@
+package stagedsync
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/kvwatch"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadersNotifier(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := kvwatch.New(memdb.NewTestDB(t))
+	events := shards.NewEvents()
+	headers, clean := events.AddHeaderSubscription()
+	defer clean()
+
+	// canonical chain [from, to] with given difficulty, Finish stage at to
+	commit := func(from, to uint64, difficulty int64) {
+		require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+			for i := from; i <= to; i++ {
+				h := &types.Header{Number: new(big.Int).SetUint64(i), Difficulty: big.NewInt(difficulty)}
+				rawdb.WriteHeader(tx, h)
+				if err := rawdb.WriteCanonicalHash(tx, h.Hash(), i); err != nil {
+					return err
+				}
+			}
+			return stages.SaveStageProgress(tx, stages.Finish, to)
+		}))
+	}
+	received := func() (numbers []uint64) {
+		for _, raw := range <-headers {
+			h := new(types.Header)
+			require.NoError(rlp.DecodeBytes(raw, h))
+			numbers = append(numbers, h.Number.Uint64())
+		}
+		return numbers
+	}
+
+	commit(1, 2, 1)
+	notifier, err := NewHeadersNotifier(ctx, db, db, events)
+	require.NoError(err)
+	commit(3, 3, 1) // before the loop is started
+	go notifier.Loop(ctx)
+	require.Equal([]uint64{3}, received())
+
+	commit(4, 5, 1)
+	require.Equal([]uint64{4, 5}, received())
+
+	// reorg of blocks 4,5 into 4,5,6 of another chain - announced from the fork point
+	commit(4, 6, 2)
+	require.Equal([]uint64{4, 5, 6}, received())
+}
@@ -0,0 +1,303 @@
+package stagedsync
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/RoaringBitmap/roaring/roaring64"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/calltracer"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// rebuildReceiptsKey - key of the unfinished RebuildReceiptsJob in DatabaseInfo
+var rebuildReceiptsKey = []byte("rebuildReceipts")
+
+// RebuildReceiptsJob - blocks [From, To] are re-executed to regenerate receipts, logs and call indices,
+// blocks before Next are done
+type RebuildReceiptsJob struct {
+	From, To, Next uint64
+}
+
+type RebuildReceiptsCfg struct {
+	db          kv.RwDB
+	chainConfig *params.ChainConfig
+	engine      consensus.Engine
+	blockReader services.FullBlockReader
+	batchSize   uint64        // blocks re-executed and committed in one transaction
+	throttle    time.Duration // pause between transactions, lets the sync and other writers in
+}
+
+func StageRebuildReceiptsCfg(db kv.RwDB, chainConfig *params.ChainConfig, engine consensus.Engine, blockReader services.FullBlockReader, batchSize uint64, throttle time.Duration) RebuildReceiptsCfg {
+	if batchSize == 0 {
+		batchSize = 1
+	}
+	return RebuildReceiptsCfg{
+		db:          db,
+		chainConfig: chainConfig,
+		engine:      engine,
+		blockReader: blockReader,
+		batchSize:   batchSize,
+		throttle:    throttle,
+	}
+}
+
+// ReadRebuildReceiptsJob returns the unfinished job, nil if there is no such
+func ReadRebuildReceiptsJob(tx kv.Getter) (*RebuildReceiptsJob, error) {
+	v, err := tx.GetOne(kv.DatabaseInfo, rebuildReceiptsKey)
+	if err != nil || len(v) == 0 {
+		return nil, err
+	}
+	if len(v) != 24 {
+		return nil, fmt.Errorf("unexpected length of receipts rebuild job: %d", len(v))
+	}
+	return &RebuildReceiptsJob{From: binary.BigEndian.Uint64(v), To: binary.BigEndian.Uint64(v[8:]), Next: binary.BigEndian.Uint64(v[16:])}, nil
+}
+
+func writeRebuildReceiptsJob(tx kv.Putter, job *RebuildReceiptsJob) error {
+	v := make([]byte, 24)
+	binary.BigEndian.PutUint64(v, job.From)
+	binary.BigEndian.PutUint64(v[8:], job.To)
+	binary.BigEndian.PutUint64(v[16:], job.Next)
+	return tx.Put(kv.DatabaseInfo, rebuildReceiptsKey, v)
+}
+
+// StartRebuildReceipts persists the job to rebuild receipts of blocks [from, to], replacing an unfinished one
+// (an unfinished job of the same range is kept, to continue from its progress). The state history of the range must be available and receipts of the range must not be pruned by the prune mode
+// of the db, otherwise they would be deleted again by the next prune.
+func StartRebuildReceipts(tx kv.RwTx, from, to uint64) error {
+	if historyV3, err := rawdb.HistoryV3.Enabled(tx); err != nil {
+		return err
+	} else if historyV3 {
+		return errors.New("receipts are not stored in the db with history.v3")
+	}
+	if from == 0 {
+		from = 1 // genesis has no transactions
+	}
+	if from > to {
+		return fmt.Errorf("from block %d is after to block %d", from, to)
+	}
+	if job, err := ReadRebuildReceiptsJob(tx); err != nil {
+		return err
+	} else if job != nil && job.From == from && job.To == to {
+		return nil
+	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if to > executed {
+		return fmt.Errorf("block %d is not executed yet, latest executed block is %d", to, executed)
+	}
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return err
+	}
+	if pm.History.Enabled() && from <= pm.History.PruneTo(executed) {
+		return fmt.Errorf("state history before block %d is pruned, can't re-execute block %d", pm.History.PruneTo(executed), from)
+	}
+	if pm.Receipts.Enabled() && from < pm.Receipts.PruneTo(executed) {
+		return fmt.Errorf("receipts before block %d are pruned by the prune mode of the db", pm.Receipts.PruneTo(executed))
+	}
+	if pm.CallTraces.Enabled() && from < pm.CallTraces.PruneTo(executed) {
+		return fmt.Errorf("call traces before block %d are pruned by the prune mode of the db", pm.CallTraces.PruneTo(executed))
+	}
+	return writeRebuildReceiptsJob(tx, &RebuildReceiptsJob{From: from, To: to, Next: from})
+}
+
+// RebuildReceipts runs the persisted job (if any) until it's done or ctx is cancelled. Progress is committed
+// after every batch, so the job continues from there after restart.
+func RebuildReceipts(ctx context.Context, cfg RebuildReceiptsCfg) error {
+	logPrefix := "RebuildReceipts"
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+	var job *RebuildReceiptsJob
+	if err := cfg.db.View(ctx, func(tx kv.Tx) (err error) {
+		job, err = ReadRebuildReceiptsJob(tx)
+		return err
+	}); err != nil || job == nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("[%s] Started", logPrefix), "from", job.From, "to", job.To, "next", job.Next)
+	prev := job.Next
+	for {
+		if err := cfg.db.Update(ctx, func(tx kv.RwTx) (err error) {
+			job, err = rebuildReceiptsBatch(ctx, tx, cfg)
+			return err
+		}); err != nil {
+			return fmt.Errorf("[%s] %w", logPrefix, err)
+		}
+		if job == nil {
+			log.Info(fmt.Sprintf("[%s] Done", logPrefix))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "block", job.Next-1, "to", job.To,
+				"blk/second", float64(job.Next-prev)/float64(logInterval/time.Second))
+			prev = job.Next
+		case <-time.After(cfg.throttle):
+		}
+	}
+}
+
+// rebuildReceiptsBatch re-executes the next batch of blocks of the job, returns the job or nil if it's done
+func rebuildReceiptsBatch(ctx context.Context, tx kv.RwTx, cfg RebuildReceiptsCfg) (*RebuildReceiptsJob, error) {
+	job, err := ReadRebuildReceiptsJob(tx)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	logIndexProgress, err := stages.GetStageProgress(tx, stages.LogIndex)
+	if err != nil {
+		return nil, err
+	}
+	callTracesProgress, err := stages.GetStageProgress(tx, stages.CallTraces)
+	if err != nil {
+		return nil, err
+	}
+	// blocks above progress of the index stages will be indexed by the stages
+	topics, addresses := map[string]*roaring.Bitmap{}, map[string]*roaring.Bitmap{}
+	froms, tos := map[string]*roaring64.Bitmap{}, map[string]*roaring64.Bitmap{}
+	to := job.Next + cfg.batchSize - 1
+	if to > job.To {
+		to = job.To
+	}
+	for blockNum := job.Next; blockNum <= to; blockNum++ {
+		if err = libcommon.Stopped(ctx.Done()); err != nil {
+			return nil, err
+		}
+		receipts, callTracer, block, err := reExecuteBlock(ctx, tx, cfg, blockNum)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", blockNum, err)
+		}
+		if blockNum <= logIndexProgress {
+			for _, r := range receipts {
+				for _, l := range r.Logs {
+					for _, topic := range l.Topics {
+						addToBitmap(topics, topic[:], blockNum)
+					}
+					addToBitmap(addresses, l.Address[:], blockNum)
+				}
+			}
+		}
+		if blockNum <= callTracesProgress {
+			if err = callTracer.ForEachAddress(block, func(v []byte) error {
+				if v[length.Addr]&1 > 0 {
+					addToBitmap64(froms, v[:length.Addr], blockNum)
+				}
+				if v[length.Addr]&2 > 0 {
+					addToBitmap64(tos, v[:length.Addr], blockNum)
+				}
+				return nil
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for table, bitmaps := range map[string]map[string]*roaring.Bitmap{kv.LogTopicIndex: topics, kv.LogAddressIndex: addresses} {
+		for k, bm := range bitmaps {
+			if err = bitmapdb.MergeRange(tx, table, []byte(k), bm); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for table, bitmaps := range map[string]map[string]*roaring64.Bitmap{kv.CallFromIndex: froms, kv.CallToIndex: tos} {
+		for k, bm := range bitmaps {
+			if err = bitmapdb.MergeRange64(tx, table, []byte(k), bm); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	job.Next = to + 1
+	if job.Next > job.To {
+		return nil, tx.Delete(kv.DatabaseInfo, rebuildReceiptsKey)
+	}
+	return job, writeRebuildReceiptsJob(tx, job)
+}
+
+// reExecuteBlock executes the block on top of the historical state and writes its receipts and logs
+func reExecuteBlock(ctx context.Context, tx kv.RwTx, cfg RebuildReceiptsCfg, blockNum uint64) (types.Receipts, *calltracer.CallTracer, *types.Block, error) {
+	hash, err := cfg.blockReader.CanonicalHash(ctx, tx, blockNum)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	block, _, err := cfg.blockReader.BlockWithSenders(ctx, tx, hash, blockNum)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if block == nil {
+		return nil, nil, nil, errors.New("block not found")
+	}
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		h, _ := cfg.blockReader.Header(ctx, tx, hash, number)
+		return h
+	}
+	getTracer := func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+		return vm.NewStructLogger(&vm.LogConfig{}), nil
+	}
+	callTracer := calltracer.NewCallTracer()
+	vmConfig := vm.Config{Debug: true, Tracer: callTracer}
+	getHashFn := core.GetHashFn(block.Header(), getHeader)
+	stateReader, stateWriter := state.NewPlainState(tx, blockNum), state.NewNoopWriter()
+	er, cr := epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}
+
+	var execRs *core.EphemeralExecResult
+	if _, isPoSa := cfg.engine.(consensus.PoSA); isPoSa {
+		execRs, err = core.ExecuteBlockEphemerallyForBSC(cfg.chainConfig, &vmConfig, getHashFn, cfg.engine, block, stateReader, stateWriter, er, cr, false, getTracer)
+	} else if cfg.chainConfig.Bor != nil {
+		execRs, err = core.ExecuteBlockEphemerallyBor(cfg.chainConfig, &vmConfig, getHashFn, cfg.engine, block, stateReader, stateWriter, er, cr, false, getTracer, nil)
+	} else {
+		execRs, err = core.ExecuteBlockEphemerally(cfg.chainConfig, &vmConfig, getHashFn, cfg.engine, block, stateReader, stateWriter, er, cr, false, getTracer, nil)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err = rawdb.WriteReceipts(tx, blockNum, execRs.Receipts); err != nil {
+		return nil, nil, nil, err
+	}
+	if execRs.StateSyncReceipt != nil && execRs.StateSyncReceipt.Status == types.ReceiptStatusSuccessful {
+		if err = rawdb.WriteBorReceipt(tx, block.Hash(), blockNum, execRs.StateSyncReceipt); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return execRs.Receipts, callTracer, block, nil
+}
+
+func addToBitmap(bitmaps map[string]*roaring.Bitmap, k []byte, blockNum uint64) {
+	m, ok := bitmaps[string(k)]
+	if !ok {
+		m = roaring.New()
+		bitmaps[string(k)] = m
+	}
+	m.Add(uint32(blockNum))
+}
+
+func addToBitmap64(bitmaps map[string]*roaring64.Bitmap, k []byte, blockNum uint64) {
+	m, ok := bitmaps[string(k)]
+	if !ok {
+		m = roaring64.New()
+		bitmaps[string(k)] = m
+	}
+	m.Add(blockNum)
+}
@@ -0,0 +1,82 @@
+package stagedsync
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebuildReceipts(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+	coinbase := common.Address{0xc0}
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		parent := common.Hash{}
+		for i := uint64(1); i <= 10; i++ {
+			h := &types.Header{Number: new(big.Int).SetUint64(i), ParentHash: parent, Coinbase: coinbase, Difficulty: big.NewInt(1),
+				GasLimit: 8_000_000, Time: 10 * i, UncleHash: types.EmptyUncleHash, TxHash: types.EmptyRootHash, ReceiptHash: types.EmptyRootHash}
+			rawdb.WriteHeader(tx, h)
+			if err := rawdb.WriteBody(tx, h.Hash(), i, &types.Body{}); err != nil {
+				return err
+			}
+			if err := rawdb.WriteCanonicalHash(tx, h.Hash(), i); err != nil {
+				return err
+			}
+			parent = h.Hash()
+		}
+		for _, s := range []stages.SyncStage{stages.Execution, stages.LogIndex, stages.CallTraces} {
+			if err := stages.SaveStageProgress(tx, s, 10); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	require.Error(db.Update(ctx, func(tx kv.RwTx) error { return StartRebuildReceipts(tx, 5, 11) })) // not executed
+	require.Error(db.Update(ctx, func(tx kv.RwTx) error { return StartRebuildReceipts(tx, 5, 4) }))
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error { return StartRebuildReceipts(tx, 0, 7) }))
+
+	cfg := StageRebuildReceiptsCfg(db, params.TestChainConfig, ethash.NewFaker(), snapshotsync.NewBlockReader(), 3, 0)
+	// one batch, then restart
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		job, err := rebuildReceiptsBatch(ctx, tx, cfg)
+		require.Equal(&RebuildReceiptsJob{From: 1, To: 7, Next: 4}, job)
+		return err
+	}))
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error { return StartRebuildReceipts(tx, 1, 7) })) // same range - progress is kept
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		job, err := ReadRebuildReceiptsJob(tx)
+		require.Equal(&RebuildReceiptsJob{From: 1, To: 7, Next: 4}, job)
+		return err
+	}))
+	require.NoError(RebuildReceipts(ctx, cfg))
+
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		job, err := ReadRebuildReceiptsJob(tx)
+		require.NoError(err)
+		require.Nil(job)
+		for i := uint64(1); i <= 10; i++ {
+			has, err := tx.Has(kv.Receipts, dbutils.EncodeBlockNumber(i))
+			require.NoError(err)
+			require.Equal(i <= 7, has, i)
+		}
+		bm, err := bitmapdb.Get64(tx, kv.CallToIndex, coinbase[:], 0, 100)
+		require.NoError(err)
+		require.Equal([]uint64{1, 2, 3, 4, 5, 6, 7}, bm.ToArray())
+		return nil
+	}))
+}
@@ -0,0 +1,127 @@
+package stagedsync
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// reshardIndexKeyPrefix - prefix of keys of unfinished ReshardIndexJob in DatabaseInfo, followed by the table
+var reshardIndexKeyPrefix = []byte("reshardIndex.")
+
+// ReshardIndexTables - bitmap indices which can be re-sharded, with the length of the shard suffix of their keys
+var ReshardIndexTables = map[string]int{
+	kv.CallFromIndex:   8,
+	kv.CallToIndex:     8,
+	kv.LogAddressIndex: 4,
+	kv.LogTopicIndex:   4,
+}
+
+// minReshardSizeLimit - smaller shards would make iterators of the indices slower, not faster
+const minReshardSizeLimit = 256
+
+// ReshardIndexJob - bitmaps of Table are re-sharded to SizeLimit bytes, bitmaps before the shard key Next are done
+type ReshardIndexJob struct {
+	Table     string
+	SizeLimit uint64
+	Next      []byte
+}
+
+// ReadReshardIndexJobs returns unfinished jobs, ordered by table
+func ReadReshardIndexJobs(tx kv.Tx) ([]*ReshardIndexJob, error) {
+	var jobs []*ReshardIndexJob
+	if err := tx.ForPrefix(kv.DatabaseInfo, reshardIndexKeyPrefix, func(k, v []byte) error {
+		if len(v) < 8 {
+			return fmt.Errorf("unexpected length of index re-sharding job: %d", len(v))
+		}
+		jobs = append(jobs, &ReshardIndexJob{Table: string(k[len(reshardIndexKeyPrefix):]), SizeLimit: binary.BigEndian.Uint64(v), Next: libcommon.Copy(v[8:])})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func writeReshardIndexJob(tx kv.RwTx, job *ReshardIndexJob) error {
+	k := append(libcommon.Copy(reshardIndexKeyPrefix), job.Table...)
+	if job.Next == nil {
+		return tx.Delete(kv.DatabaseInfo, k)
+	}
+	v := make([]byte, 8+len(job.Next))
+	binary.BigEndian.PutUint64(v, job.SizeLimit)
+	copy(v[8:], job.Next)
+	return tx.Put(kv.DatabaseInfo, k, v)
+}
+
+// StartReshardIndex persists the job to re-shard bitmaps of the table to sizeLimit bytes, replacing an unfinished job
+// of the table with another limit (an unfinished job with the same limit continues from its progress)
+func StartReshardIndex(tx kv.RwTx, table string, sizeLimit uint64) error {
+	if _, ok := ReshardIndexTables[table]; !ok {
+		return fmt.Errorf("table %s can't be re-sharded, expected one of %s, %s, %s, %s", table, kv.CallFromIndex, kv.CallToIndex, kv.LogAddressIndex, kv.LogTopicIndex)
+	}
+	if sizeLimit < minReshardSizeLimit {
+		return fmt.Errorf("shard size %d is less than %d bytes", sizeLimit, minReshardSizeLimit)
+	}
+	jobs, err := ReadReshardIndexJobs(tx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.Table == table && job.SizeLimit == sizeLimit {
+			return nil
+		}
+	}
+	// the empty key is the first one, and a job with nil Next is done
+	return writeReshardIndexJob(tx, &ReshardIndexJob{Table: table, SizeLimit: sizeLimit, Next: []byte{}})
+}
+
+// ReshardIndex runs the persisted jobs (if any) until they are done or ctx is cancelled. Every batch of bitmaps is
+// re-sharded and committed with the progress in its own transaction, so it can run next to the sync (writers
+// wait only for one batch) and continues from the last batch after restart.
+func ReshardIndex(ctx context.Context, db kv.RwDB, batchSize int, throttle time.Duration) error {
+	logPrefix := "ReshardIndex"
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+	var jobs []*ReshardIndexJob
+	if err := db.View(ctx, func(tx kv.Tx) (err error) {
+		jobs, err = ReadReshardIndexJobs(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		log.Info(fmt.Sprintf("[%s] Started", logPrefix), "table", job.Table, "shardSize", job.SizeLimit, "next", fmt.Sprintf("%x", job.Next))
+		var stats bitmapdb.ReshardStats
+		for job.Next != nil {
+			if err := db.Update(ctx, func(tx kv.RwTx) (err error) {
+				job.Next, err = bitmapdb.Reshard(tx, job.Table, ReshardIndexTables[job.Table], job.SizeLimit, job.Next, batchSize, &stats)
+				if err != nil {
+					return err
+				}
+				return writeReshardIndexJob(tx, job)
+			}); err != nil {
+				return fmt.Errorf("[%s] %s: %w", logPrefix, job.Table, err)
+			}
+			if job.Next == nil {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-logEvery.C:
+				log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "table", job.Table, "key", fmt.Sprintf("%x", job.Next),
+					"bitmaps", stats.Keys, "rewritten", stats.Rewritten, "shardsBefore", stats.ShardsBefore, "shardsAfter", stats.ShardsAfter)
+			case <-time.After(throttle):
+			}
+		}
+		log.Info(fmt.Sprintf("[%s] Done", logPrefix), "table", job.Table,
+			"bitmaps", stats.Keys, "rewritten", stats.Rewritten, "shardsBefore", stats.ShardsBefore, "shardsAfter", stats.ShardsAfter)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package stagedsync
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReshardIndex(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := memdb.NewTestDB(t)
+
+	// bitmaps of 3 addresses, each in many tiny shards
+	addresses := [][]byte{{1}, {2}, {3}}
+	expected := roaring.New()
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		for i := uint32(0); i < 1000; i += 10 {
+			bm := roaring.New()
+			bm.AddRange(uint64(i), uint64(i)+5)
+			expected.Or(bm)
+			v, err := bm.ToBytes()
+			if err != nil {
+				return err
+			}
+			for _, addr := range addresses {
+				shardKey := append(append([]byte{}, addr...), 0xff, 0xff, 0xff, 0xff) // the last shard
+				if i < 990 {
+					binary.BigEndian.PutUint32(shardKey[len(addr):], bm.Maximum())
+				}
+				if err = tx.Put(kv.LogAddressIndex, shardKey, v); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}))
+
+	require.Error(db.Update(ctx, func(tx kv.RwTx) error { return StartReshardIndex(tx, kv.PlainState, 4096) }))
+	require.Error(db.Update(ctx, func(tx kv.RwTx) error { return StartReshardIndex(tx, kv.LogAddressIndex, 10) }))
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error { return StartReshardIndex(tx, kv.LogAddressIndex, 4096) }))
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error { return StartReshardIndex(tx, kv.LogAddressIndex, 4096) }))
+
+	require.NoError(ReshardIndex(ctx, db, 1, 0))
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		jobs, err := ReadReshardIndexJobs(tx)
+		require.NoError(err)
+		require.Empty(jobs)
+		for _, addr := range addresses {
+			bm, err := bitmapdb.Get(tx, kv.LogAddressIndex, addr, 0, ^uint32(0))
+			require.NoError(err)
+			require.True(expected.Equals(bm))
+		}
+		shards := 0
+		require.NoError(tx.ForEach(kv.LogAddressIndex, nil, func(k, v []byte) error {
+			shards++
+			return nil
+		}))
+		require.Equal(len(addresses), shards) // every bitmap fits one shard
+		return nil
+	}))
+}
@@ -46,6 +46,14 @@ type StageState struct {
 
 func (s *StageState) LogPrefix() string { return s.state.LogPrefix() }
 
+// watchdog - nil for stages run outside of Sync (tests, integration tool)
+func (s *StageState) watchdog() *Watchdog {
+	if s.state == nil {
+		return nil
+	}
+	return s.state.watchdog
+}
+
 // Update updates the stage state (current block number) in the database. Can be called multiple times during stage execution.
 func (s *StageState) Update(db kv.Putter, newBlockNum uint64) error {
 	if s.ID == stages.Execution && newBlockNum == 0 {
@@ -54,15 +62,17 @@ func (s *StageState) Update(db kv.Putter, newBlockNum uint64) error {
 	if m, ok := syncMetrics[s.ID]; ok {
 		m.Set(newBlockNum)
 	}
+	s.watchdog().progress()
 	return stages.SaveStageProgress(db, s.ID, newBlockNum)
 }
 func (s *StageState) UpdatePrune(db kv.Putter, blockNum uint64) error {
 	return stages.SaveStagePruneProgress(db, s.ID, blockNum)
 }
 
-// ExecutionAt gets the current state of the "Execution" stage, which block is currently executed.
+// ExecutionAt gets the current state of the "Execution" stage, which block is currently executed. In sync modes
+// without execution - progress of the head stage of the mode.
 func (s *StageState) ExecutionAt(db kv.Getter) (uint64, error) {
-	execution, err := stages.GetStageProgress(db, stages.Execution)
+	execution, err := stages.GetStageProgress(db, s.state.headStage())
 	return execution, err
 }
 
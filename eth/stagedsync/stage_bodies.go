@@ -220,10 +220,13 @@ func BodiesForward(
 		}
 
 		start = time.Now()
-		requestedLow, delivered, err := cfg.bd.GetDeliveries(innerTx)
+		requestedLow, delivered, penalties, err := cfg.bd.GetDeliveries(innerTx)
 		if err != nil {
 			return false, err
 		}
+		if len(penalties) > 0 && cfg.penalise != nil {
+			cfg.penalise(ctx, penalties)
+		}
 		totalDelivered += delivered
 		d4 += time.Since(start)
 		start = time.Now()
@@ -232,11 +235,14 @@ func BodiesForward(
 		toProcess := cfg.bd.NextProcessingCount()
 
 		if toProcess > 0 {
+			if err := cfg.bd.PrepareVerification(innerTx, requestedLow, toProcess); err != nil {
+				return false, err
+			}
 			var i uint64
 			for i = 0; i < toProcess; i++ {
 				nextBlock := requestedLow + i
 
-				header, _, err := cfg.bd.GetHeader(nextBlock, cfg.blockReader, innerTx)
+				header, headerHash, err := cfg.bd.GetHeader(nextBlock, cfg.blockReader, innerTx)
 				if err != nil {
 					return false, err
 				}
@@ -254,16 +260,24 @@ func BodiesForward(
 					return false, fmt.Errorf("[%s] Body was nil when reading from bucket, block: %v", logPrefix, nextBlock)
 				}
 
-				// Txn & uncle roots are verified via bd.requestedMap
+				// Deliveries are matched to headers by txn & uncle roots, bodies of the bucket of an earlier run,
+				// of the database and prefetched are verified here: a corrupted body must not get to execution
+				if err := cfg.bd.VerifyBody(nextBlock, header, rawBody); err != nil {
+					log.Warn(fmt.Sprintf("[%s] Invalid body, requesting again", logPrefix), "number", blockHeight, "hash", headerHash.String(), "err", err)
+					if err := cfg.bd.RejectBody(innerTx, nextBlock, header); err != nil {
+						return false, err
+					}
+					break
+				}
 				err = cfg.bd.Engine.VerifyUncles(cr, header, rawBody.Uncles)
 				if err != nil {
-					log.Error(fmt.Sprintf("[%s] Uncle verification failed", logPrefix), "number", blockHeight, "hash", header.Hash().String(), "err", err)
-					u.UnwindTo(blockHeight-1, header.Hash())
+					log.Error(fmt.Sprintf("[%s] Uncle verification failed", logPrefix), "number", blockHeight, "hash", headerHash.String(), "err", err)
+					u.UnwindTo(blockHeight-1, headerHash)
 					return true, nil
 				}
 
 				// Check existence before write - because WriteRawBody isn't idempotent (it allocates new sequence range for transactions on every call)
-				ok, lastTxnNum, err := rawdb.WriteRawBodyIfNotExists(innerTx, header.Hash(), blockHeight, rawBody)
+				ok, lastTxnNum, err := rawdb.WriteRawBodyIfNotExists(innerTx, headerHash, blockHeight, rawBody)
 				if err != nil {
 					return false, fmt.Errorf("WriteRawBodyIfNotExists: %w", err)
 				}
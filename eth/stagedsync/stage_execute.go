@@ -32,6 +32,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/calltracer"
+	"github.com/ledgerwatch/erigon/eth/exporter"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/ethdb/olddb"
@@ -78,6 +79,10 @@ type ExecuteBlockCfg struct {
 	workersCount int
 	genesis      *core.Genesis
 	agg          *libstate.Aggregator22
+	exporter     *exporter.Exporter  // streams executed blocks to an external sink, nil if disabled
+	recentStates *state.RecentStates // state changes of the last blocks for RPC, nil if disabled
+
+	receiptsEncoder *core.ReceiptsEncoder // encodes receipts of the block while it's executed, if receipts are persisted
 }
 
 func StageExecuteBlocksCfg(
@@ -99,6 +104,8 @@ func StageExecuteBlocksCfg(
 	genesis *core.Genesis,
 	workersCount int,
 	agg *libstate.Aggregator22,
+	exporter *exporter.Exporter,
+	recentStates *state.RecentStates,
 ) ExecuteBlockCfg {
 	return ExecuteBlockCfg{
 		db:            db,
@@ -118,6 +125,10 @@ func StageExecuteBlocksCfg(
 		historyV3:     historyV3,
 		workersCount:  workersCount,
 		agg:           agg,
+		exporter:      exporter,
+		recentStates:  recentStates,
+
+		receiptsEncoder: core.NewReceiptsEncoder(runtime.NumCPU()),
 	}
 }
 
@@ -130,13 +141,14 @@ func executeBlock(
 	writeChangesets bool,
 	writeReceipts bool,
 	writeCallTraces bool,
+	keepRecentState bool,
 	initialCycle bool,
 	effectiveEngine consensus.Engine,
-) error {
+) (types.Receipts, error) {
 	blockNum := block.NumberU64()
 	stateReader, stateWriter, err := newStateReaderWriter(batch, tx, block, writeChangesets, cfg.accumulator, initialCycle, cfg.stateStream)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// where the magic happens
@@ -160,27 +172,37 @@ func executeBlock(
 	isBor := cfg.chainConfig.Bor != nil
 	getHashFn := core.GetHashFn(block.Header(), getHeader)
 
+	var receiptsEncoder *core.ReceiptsEncoder
+	if writeReceipts {
+		receiptsEncoder = cfg.receiptsEncoder
+	}
+
 	if isPoSa {
 		execRs, err = core.ExecuteBlockEphemerallyForBSC(cfg.chainConfig, &vmConfig, getHashFn, cfg.engine, block, stateReader, stateWriter, epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, false, getTracer)
 	} else if isBor {
-		execRs, err = core.ExecuteBlockEphemerallyBor(cfg.chainConfig, &vmConfig, getHashFn, cfg.engine, block, stateReader, stateWriter, epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, false, getTracer)
+		execRs, err = core.ExecuteBlockEphemerallyBor(cfg.chainConfig, &vmConfig, getHashFn, cfg.engine, block, stateReader, stateWriter, epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, false, getTracer, receiptsEncoder)
 	} else {
-		execRs, err = core.ExecuteBlockEphemerally(cfg.chainConfig, &vmConfig, getHashFn, cfg.engine, block, stateReader, stateWriter, epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, false, getTracer)
+		execRs, err = core.ExecuteBlockEphemerally(cfg.chainConfig, &vmConfig, getHashFn, cfg.engine, block, stateReader, stateWriter, epochReader{tx: tx}, chainReader{config: cfg.chainConfig, tx: tx, blockReader: cfg.blockReader}, false, getTracer, receiptsEncoder)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	receipts = execRs.Receipts
 	stateSyncReceipt = execRs.StateSyncReceipt
 
 	if writeReceipts {
-		if err = rawdb.AppendReceipts(tx, blockNum, receipts); err != nil {
-			return err
+		if execRs.EncodedReceipts != nil {
+			err = rawdb.AppendEncodedReceipts(tx, blockNum, execRs.EncodedReceipts.Logs, execRs.EncodedReceipts.Block)
+		} else {
+			err = rawdb.AppendReceipts(tx, blockNum, receipts)
+		}
+		if err != nil {
+			return nil, err
 		}
 
 		if stateSyncReceipt != nil && stateSyncReceipt.Status == types.ReceiptStatusSuccessful {
 			if err := rawdb.WriteBorReceipt(tx, block.Hash(), block.NumberU64(), stateSyncReceipt); err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
@@ -190,10 +212,19 @@ func executeBlock(
 			cfg.changeSetHook(blockNum, hasChangeSet.ChangeSetWriter())
 		}
 	}
+	if keepRecentState {
+		var csw *state.ChangeSetWriter // nil - changesets aren't written, the kept blocks are dropped
+		if hasChangeSet, ok := stateWriter.(HasChangeSetWriter); ok {
+			csw = hasChangeSet.ChangeSetWriter()
+		}
+		cfg.recentStates.Add(block.Header(), csw)
+	}
 	if writeCallTraces {
-		return callTracer.WriteToDb(tx, block, *cfg.vmConfig)
+		if err = callTracer.WriteToDb(tx, block, *cfg.vmConfig); err != nil {
+			return nil, err
+		}
 	}
-	return nil
+	return receipts, nil
 }
 
 func newStateReaderWriter(
@@ -394,7 +425,7 @@ func SpawnExecuteBlocksStage(s *StageState, u Unwinder, tx kv.RwTx, toBlock uint
 
 	var batch ethdb.DbWithPendingMutations
 	// state is stored through ethdb batches
-	batch = olddb.NewHashBatch(tx, quit, cfg.dirs.Tmp)
+	batch = newExecutionBatch(tx, quit, cfg, useExternalTx)
 
 	defer batch.Rollback()
 	// changes are stored through memory buffer
@@ -419,6 +450,7 @@ func SpawnExecuteBlocksStage(s *StageState, u Unwinder, tx kv.RwTx, toBlock uint
 		return err
 	}
 	var stoppedErr error
+	cfg.exporter.Reset() // messages of blocks which weren't committed last time
 
 	effectiveEngine := cfg.engine
 	if asyncEngine, ok := effectiveEngine.(consensus.AsyncEngine); ok {
@@ -450,7 +482,9 @@ Loop:
 		writeChangeSets := nextStagesExpectData || blockNum > cfg.prune.History.PruneTo(to)
 		writeReceipts := nextStagesExpectData || blockNum > cfg.prune.Receipts.PruneTo(to)
 		writeCallTraces := nextStagesExpectData || blockNum > cfg.prune.CallTraces.PruneTo(to)
-		if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, initialCycle, effectiveEngine); err != nil {
+		keepRecentState := cfg.recentStates.Wanted(blockNum, to)
+		receipts, err := executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, keepRecentState, initialCycle, effectiveEngine)
+		if err != nil {
 			if !errors.Is(err, context.Canceled) {
 				log.Warn(fmt.Sprintf("[%s] Execution failed", logPrefix), "block", blockNum, "hash", block.Hash().String(), "err", err)
 				if cfg.hd != nil {
@@ -464,14 +498,20 @@ Loop:
 			break Loop
 		}
 		stageProgress = blockNum
+		if err = cfg.exporter.Block(ctx, tx, block, receipts); err != nil {
+			return err
+		}
 
-		if currentStateGas >= gasState {
-			log.Info("Committed State", "gas reached", currentStateGas, "gasTarget", gasState)
+		if currentStateGas >= gasState || batch.BatchSize() >= int(cfg.batchSize) {
+			log.Info("Committed State", "gas reached", currentStateGas, "gasTarget", gasState, "batch", common.ByteCount(uint64(batch.BatchSize())))
 			currentStateGas = 0
 			if err = batch.Commit(); err != nil {
 				return err
 			}
 			if !useExternalTx {
+				if err = cfg.exporter.Flush(ctx, tx); err != nil {
+					return err
+				}
 				if err = s.Update(tx, stageProgress); err != nil {
 					return err
 				}
@@ -485,7 +525,7 @@ Loop:
 				// TODO: This creates stacked up deferrals
 				defer tx.Rollback()
 			}
-			batch = olddb.NewHashBatch(tx, quit, cfg.dirs.Tmp)
+			batch = newExecutionBatch(tx, quit, cfg, useExternalTx)
 			// TODO: This creates stacked up deferrals
 			defer batch.Rollback()
 		}
@@ -513,6 +553,9 @@ Loop:
 		}
 	}
 
+	if err = cfg.exporter.Flush(ctx, tx); err != nil {
+		return err
+	}
 	if err = s.Update(batch, stageProgress); err != nil {
 		return err
 	}
@@ -532,6 +575,16 @@ Loop:
 	return stoppedErr
 }
 
+// newExecutionBatch - the stage commits its own transaction when the batch reaches --batchSize, an external
+// transaction can't be committed, so the batch is flushed into it instead to keep the memory bounded
+func newExecutionBatch(tx kv.RwTx, quit <-chan struct{}, cfg ExecuteBlockCfg, useExternalTx bool) ethdb.DbWithPendingMutations {
+	batch := olddb.NewHashBatch(tx, quit, cfg.dirs.Tmp)
+	if useExternalTx {
+		batch.SetAutoFlush(int(cfg.batchSize))
+	}
+	return batch
+}
+
 func logProgress(logPrefix string, prevBlock uint64, prevTime time.Time, currentBlock uint64, prevTx, currentTx uint64, gas uint64, gasState float64, estimatedTime commonold.PrettyDuration, batch ethdb.DbWithPendingMutations) (uint64, uint64, time.Time) {
 	currentTime := time.Now()
 	interval := currentTime.Sub(prevTime)
@@ -578,6 +631,16 @@ func UnwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 	if err = unwindExecutionStage(u, s, tx, ctx, cfg, initialCycle); err != nil {
 		return err
 	}
+	cfg.recentStates.Unwind(u.UnwindPoint)
+	if cfg.exporter != nil {
+		hash, err := rawdb.ReadCanonicalHash(tx, u.UnwindPoint)
+		if err != nil {
+			return err
+		}
+		if err = cfg.exporter.Unwind(ctx, tx, u.UnwindPoint, hash); err != nil {
+			return err
+		}
+	}
 	if err = u.Done(tx); err != nil {
 		return err
 	}
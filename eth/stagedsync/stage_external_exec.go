@@ -0,0 +1,222 @@
+package stagedsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// ErrInvalidExternalBlock is returned (wrapped) by ExternalExecutor.ExecuteBlock for blocks which can't be executed
+// by the consensus rules, the stage unwinds them as bad blocks. Other errors stop the cycle, the block is retried.
+var ErrInvalidExternalBlock = errors.New("block rejected by the external executor")
+
+// ExternalExecutor - execution engine outside of erigon, fed with blocks by the headers, bodies and senders stages
+// in stages.ModeExternal. It keeps its own state: ExecuteBlock applies a child of the head block on top of it,
+// UnwindTo reverts the state to an older block, it's a no-op for blocks at or above the head.
+type ExternalExecutor interface {
+	// Head returns the last executed block, genesis if none
+	Head(ctx context.Context) (blockNum uint64, blockHash common.Hash, err error)
+	ExecuteBlock(ctx context.Context, block *types.Block, senders []common.Address) (*ExternalExecResult, error)
+	UnwindTo(ctx context.Context, blockNum uint64) error
+}
+
+// ExternalExecResult - what the executor computed, it's verified against the header of the block
+type ExternalExecResult struct {
+	StateRoot   common.Hash
+	ReceiptHash common.Hash
+	GasUsed     uint64
+}
+
+// Verify compares the result with the header
+func (r *ExternalExecResult) Verify(header *types.Header) error {
+	if r.GasUsed != header.GasUsed {
+		return fmt.Errorf("gas used by execution: %d, in header: %d", r.GasUsed, header.GasUsed)
+	}
+	if r.ReceiptHash != header.ReceiptHash {
+		return fmt.Errorf("receiptHash mismatch: %x != %x", r.ReceiptHash, header.ReceiptHash)
+	}
+	if r.StateRoot != header.Root {
+		return fmt.Errorf("state root mismatch: %x != %x", r.StateRoot, header.Root)
+	}
+	return nil
+}
+
+// ExternalExecCfg - the ExternalExecution stage sends the blocks with senders to the ExternalExecutor and verifies
+// the results. Nothing is written locally but the stage progress: there is no state, receipts nor logs.
+type ExternalExecCfg struct {
+	db           kv.RwDB
+	executor     ExternalExecutor
+	blockReader  services.FullBlockReader
+	hd           *headerdownload.HeaderDownload
+	badBlockHalt bool
+}
+
+func StageExternalExecCfg(db kv.RwDB, executor ExternalExecutor, blockReader services.FullBlockReader, hd *headerdownload.HeaderDownload, badBlockHalt bool) ExternalExecCfg {
+	return ExternalExecCfg{
+		db:           db,
+		executor:     executor,
+		blockReader:  blockReader,
+		hd:           hd,
+		badBlockHalt: badBlockHalt,
+	}
+}
+
+func SpawnExternalExecStage(s *StageState, u Unwinder, tx kv.RwTx, ctx context.Context, cfg ExternalExecCfg, quiet bool) error {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		var err error
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+	if cfg.executor == nil {
+		return errors.New("sync mode external, but no external executor is configured")
+	}
+	logPrefix := s.LogPrefix()
+
+	to, err := stages.GetStageProgress(tx, stages.Senders)
+	if err != nil {
+		return err
+	}
+	from, err := syncExternalHead(logPrefix, tx, ctx, cfg, s.BlockNumber)
+	if err != nil {
+		return err
+	}
+	if to <= from {
+		return nil
+	}
+	if !quiet && to > from+16 {
+		log.Info(fmt.Sprintf("[%s] Blocks execution", logPrefix), "from", from, "to", to)
+	}
+
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+	stageProgress := s.BlockNumber
+	logBlock, logTime := from, time.Now()
+	for blockNum := from + 1; blockNum <= to; blockNum++ {
+		if err = libcommon.Stopped(ctx.Done()); err != nil {
+			return err
+		}
+		blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return err
+		}
+		block, senders, err := cfg.blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			log.Error(fmt.Sprintf("[%s] Empty block", logPrefix), "blocknum", blockNum)
+			break
+		}
+		res, err := cfg.executor.ExecuteBlock(ctx, block, senders)
+		if err == nil {
+			err = res.Verify(block.Header())
+		} else if !errors.Is(err, ErrInvalidExternalBlock) {
+			return fmt.Errorf("[%s] external execution of block %d: %w", logPrefix, blockNum, err)
+		}
+		if err != nil {
+			log.Warn(fmt.Sprintf("[%s] Execution failed", logPrefix), "block", blockNum, "hash", blockHash.String(), "err", err)
+			if cfg.hd != nil {
+				cfg.hd.ReportBadHeaderPoS(blockHash, block.ParentHash())
+			}
+			if cfg.badBlockHalt {
+				return err
+			}
+			u.UnwindTo(blockNum-1, blockHash)
+			break
+		}
+		if blockNum > stageProgress {
+			stageProgress = blockNum
+		}
+
+		select {
+		default:
+		case <-logEvery.C:
+			speed := float64(blockNum-logBlock) / time.Since(logTime).Seconds()
+			log.Info(fmt.Sprintf("[%s] Executed blocks", logPrefix), "number", blockNum, "blk/s", fmt.Sprintf("%.1f", speed))
+			logBlock, logTime = blockNum, time.Now()
+		}
+	}
+	if err = s.Update(tx, stageProgress); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncExternalHead brings the executor to the canonical chain at most at block progress, returns its head then.
+// The executor is ahead of the stage if the progress wasn't committed after the execution, behind it - if the
+// unwind wasn't committed after UnwindTo, or on another chain if the reorg wasn't committed.
+func syncExternalHead(logPrefix string, tx kv.Tx, ctx context.Context, cfg ExternalExecCfg, progress uint64) (uint64, error) {
+	for unwound, unwindTo := false, uint64(0); ; unwound = true {
+		headNum, headHash, err := cfg.executor.Head(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("[%s] head of the external executor: %w", logPrefix, err)
+		}
+		if unwound && headNum > unwindTo {
+			return 0, fmt.Errorf("[%s] the external executor didn't unwind to %d, its head is %d", logPrefix, unwindTo, headNum)
+		}
+		if headNum > progress {
+			unwindTo = progress
+		} else {
+			canonical, err := rawdb.ReadCanonicalHash(tx, headNum)
+			if err != nil {
+				return 0, err
+			}
+			if canonical == headHash {
+				return headNum, nil
+			}
+			if headNum == 0 {
+				return 0, fmt.Errorf("[%s] genesis of the external executor %x doesn't match %x", logPrefix, headHash, canonical)
+			}
+			unwindTo = headNum - 1
+		}
+		log.Info(fmt.Sprintf("[%s] Unwinding the external executor", logPrefix), "head", headNum, "to", unwindTo)
+		if err = cfg.executor.UnwindTo(ctx, unwindTo); err != nil {
+			return 0, fmt.Errorf("[%s] unwind of the external executor: %w", logPrefix, err)
+		}
+	}
+}
+
+func UnwindExternalExecStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context.Context, cfg ExternalExecCfg) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+	if cfg.executor != nil {
+		if err = cfg.executor.UnwindTo(ctx, u.UnwindPoint); err != nil {
+			return fmt.Errorf("[%s] unwind of the external executor: %w", u.LogPrefix(), err)
+		}
+	}
+	if err = u.Done(tx); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package stagedsync
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+// testExecutor "executes" a block by taking the state root from its header, unless the block is bad
+type testExecutor struct {
+	chain []*types.Header // executed blocks, from genesis
+	bad   uint64
+}
+
+func (e *testExecutor) Head(context.Context) (uint64, common.Hash, error) {
+	head := e.chain[len(e.chain)-1]
+	return head.Number.Uint64(), head.Hash(), nil
+}
+
+func (e *testExecutor) ExecuteBlock(_ context.Context, block *types.Block, _ []common.Address) (*ExternalExecResult, error) {
+	if block.ParentHash() != e.chain[len(e.chain)-1].Hash() {
+		return nil, errors.New("not a child of the head")
+	}
+	e.chain = append(e.chain, block.Header())
+	res := &ExternalExecResult{StateRoot: block.Root(), ReceiptHash: block.ReceiptHash(), GasUsed: block.GasUsed()}
+	if block.NumberU64() == e.bad {
+		res.StateRoot = common.Hash{0xba, 0xd}
+	}
+	return res, nil
+}
+
+func (e *testExecutor) UnwindTo(_ context.Context, blockNum uint64) error {
+	if blockNum+1 < uint64(len(e.chain)) {
+		e.chain = e.chain[:blockNum+1]
+	}
+	return nil
+}
+
+type testUnwinder struct {
+	unwindPoint uint64
+	badBlock    common.Hash
+}
+
+func (u *testUnwinder) UnwindTo(unwindPoint uint64, badBlock common.Hash) {
+	u.unwindPoint, u.badBlock = unwindPoint, badBlock
+}
+
+func TestExternalExecution(t *testing.T) {
+	ctx := context.Background()
+	db, tx := memdb.NewTestTx(t)
+
+	var headers []*types.Header
+	parent := common.Hash{}
+	for i := int64(0); i <= 5; i++ {
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(i), Difficulty: big.NewInt(1), Root: common.BigToHash(big.NewInt(i + 100)), ReceiptHash: types.EmptyRootHash}
+		block := types.NewBlockWithHeader(header)
+		require.NoError(t, rawdb.WriteBlock(tx, block))
+		require.NoError(t, rawdb.WriteCanonicalHash(tx, block.Hash(), block.NumberU64()))
+		headers = append(headers, header)
+		parent = block.Hash()
+	}
+	require.NoError(t, stages.SaveStageProgress(tx, stages.Senders, 5))
+
+	executor := &testExecutor{chain: append([]*types.Header{}, headers[:1]...), bad: 4}
+	cfg := StageExternalExecCfg(db, executor, snapshotsync.NewBlockReader(), nil, false)
+	u := &testUnwinder{}
+	require.NoError(t, SpawnExternalExecStage(&StageState{ID: stages.ExternalExecution}, u, tx, ctx, cfg, true))
+	progress, err := stages.GetStageProgress(tx, stages.ExternalExecution)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), progress)
+	require.Equal(t, uint64(3), u.unwindPoint)
+	require.Equal(t, headers[4].Hash(), u.badBlock)
+
+	require.NoError(t, UnwindExternalExecStage(&UnwindState{ID: stages.ExternalExecution, UnwindPoint: 2}, &StageState{ID: stages.ExternalExecution, BlockNumber: 3}, tx, ctx, cfg))
+	head, _, _ := executor.Head(ctx)
+	require.Equal(t, uint64(2), head)
+
+	// the executor lost blocks the stage has, they're executed again
+	executor.bad = 0
+	executor.chain = append([]*types.Header{}, headers[:2]...)
+	require.NoError(t, SpawnExternalExecStage(&StageState{ID: stages.ExternalExecution, BlockNumber: 2}, u, tx, ctx, cfg, true))
+	progress, err = stages.GetStageProgress(tx, stages.ExternalExecution)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), progress)
+	require.Len(t, executor.chain, 6)
+
+	// the executor is ahead of the stage, on another chain
+	fork4 := &types.Header{ParentHash: headers[3].Hash(), Number: big.NewInt(4), Extra: []byte("fork")}
+	fork5 := &types.Header{ParentHash: fork4.Hash(), Number: big.NewInt(5)}
+	executor.chain = append(append([]*types.Header{}, headers[:4]...), fork4, fork5)
+	require.NoError(t, SpawnExternalExecStage(&StageState{ID: stages.ExternalExecution, BlockNumber: 4}, u, tx, ctx, cfg, true))
+	require.Equal(t, headers[5].Hash(), executor.chain[len(executor.chain)-1].Hash())
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/engineapi"
 	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/errgroup"
 )
 
 type FinishCfg struct {
@@ -119,67 +120,156 @@ func PruneFinish(u *PruneState, tx kv.RwTx, cfg FinishCfg, ctx context.Context)
 	return nil
 }
 
-func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishStageAfterSync uint64, unwindTo *uint64, notifier ChainEventNotifier, tx kv.Tx) error {
+const (
+	// notifyBatchSize is the maximum number of headers passed to a single OnNewHeader call,
+	// so subscribers receive a long range as a few range events instead of one per block.
+	notifyBatchSize = 256
+	// notifyMaxHeaders caps the amount of headers announced after one sync cycle. After a long
+	// catch-up only the most recent ones are sent: subscribers are snapped to the head.
+	notifyMaxHeaders = 1024
+	// notifyReadWorkers is the number of concurrent readers used to load header batches.
+	notifyReadWorkers = 4
+)
+
+// NotifyNewHeaders sends canonical headers of the last sync cycle (or of the unwound range) to
+// subscribers in batches of at most notifyBatchSize, followed by their logs. If db is not nil,
+// batches are read concurrently using separate read transactions, otherwise they are read via tx.
+func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishStageAfterSync uint64, unwindTo *uint64, notifier ChainEventNotifier, tx kv.Tx, db kv.RoDB) error {
 	t := time.Now()
 	if notifier == nil {
 		log.Trace("RPC Daemon notification channel not set. No headers notifications will be sent")
 		return nil
 	}
-	// Notify all headers we have (either canonical or not) in a maximum range span of 1024
 	var notifyFrom uint64
 	var isUnwind bool
 	if unwindTo != nil && *unwindTo != 0 && (*unwindTo) < finishStageBeforeSync {
 		notifyFrom = *unwindTo
 		isUnwind = true
 	} else {
-		heightSpan := finishStageAfterSync - finishStageBeforeSync
-		if heightSpan > 1024 {
-			heightSpan = 1024
-		}
-		notifyFrom = finishStageAfterSync - heightSpan
+		notifyFrom = finishStageBeforeSync
 	}
 	notifyFrom++
+	if notifyFrom > finishStageAfterSync {
+		return nil
+	}
+	var snapped bool
+	if finishStageAfterSync-notifyFrom+1 > notifyMaxHeaders {
+		notifyFrom = finishStageAfterSync - notifyMaxHeaders + 1
+		snapped = true
+	}
 
-	var notifyTo = notifyFrom
-	var notifyToHash common.Hash
-	var headersRlp [][]byte
-	if err := tx.ForEach(kv.Headers, dbutils.EncodeBlockNumber(notifyFrom), func(k, headerRLP []byte) error {
-		if len(headerRLP) == 0 {
-			return nil
-		}
-		notifyTo = binary.BigEndian.Uint64(k)
-		var err error
-		if notifyToHash, err = rawdb.ReadCanonicalHash(tx, notifyTo); err != nil {
-			log.Warn("[Finish] failed checking if header is cannonical")
+	hashes, err := readCanonicalHashes(ctx, tx, notifyFrom, finishStageAfterSync)
+	if err != nil {
+		log.Error("RPC Daemon notification failed", "err", err)
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	batches, err := readHeaderBatches(ctx, tx, db, notifyFrom, hashes)
+	if err != nil {
+		log.Error("RPC Daemon notification failed", "err", err)
+		return err
+	}
+	var sent int
+	for _, batch := range batches {
+		if len(batch) == 0 {
+			continue
 		}
+		notifier.OnNewHeader(batch)
+		sent += len(batch)
+	}
+	if sent == 0 {
+		return nil
+	}
+	headerTiming := time.Since(t)
 
-		headerHash := common.BytesToHash(k[8:])
-		if notifyToHash == headerHash {
-			headersRlp = append(headersRlp, common2.CopyBytes(headerRLP))
+	t = time.Now()
+	if notifier.HasLogSubsriptions() {
+		logs, err := ReadLogs(tx, notifyFrom, isUnwind)
+		if err != nil {
+			return err
 		}
-
-		return libcommon.Stopped(ctx.Done())
-	}); err != nil {
-		log.Error("RPC Daemon notification failed", "err", err)
-		return err
+		notifier.OnLogs(logs)
 	}
+	logTiming := time.Since(t)
+	notifyTo := notifyFrom + uint64(len(hashes)) - 1
+	log.Info("RPC Daemon notified of new headers", "from", notifyFrom-1, "to", notifyTo, "hash", hashes[len(hashes)-1], "headers", sent, "batches", len(batches), "snapped", snapped, "header sending", headerTiming, "log sending", logTiming)
+	return nil
+}
 
-	if len(headersRlp) > 0 {
-		notifier.OnNewHeader(headersRlp)
-		headerTiming := time.Since(t)
+// readCanonicalHashes returns canonical hashes of blocks [from, to] in one cursor pass.
+// The result stops at the first gap in the canonical chain.
+func readCanonicalHashes(ctx context.Context, tx kv.Tx, from, to uint64) ([]common.Hash, error) {
+	c, err := tx.Cursor(kv.HeaderCanonical)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	hashes := make([]common.Hash, 0, to-from+1)
+	expected := from
+	for k, v, err := c.Seek(dbutils.EncodeBlockNumber(from)); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		blockNum := binary.BigEndian.Uint64(k)
+		if blockNum > to || blockNum != expected {
+			break
+		}
+		hashes = append(hashes, common.BytesToHash(v))
+		expected++
+		if err = libcommon.Stopped(ctx.Done()); err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
 
-		t = time.Now()
-		if notifier.HasLogSubsriptions() {
-			logs, err := ReadLogs(tx, notifyFrom, isUnwind)
+// readHeaderBatches loads RLP of the headers with the given hashes, starting at block number from,
+// split into batches of notifyBatchSize. Headers missing in the db are skipped.
+func readHeaderBatches(ctx context.Context, tx kv.Tx, db kv.RoDB, from uint64, hashes []common.Hash) ([][][]byte, error) {
+	batches := make([][][]byte, (len(hashes)+notifyBatchSize-1)/notifyBatchSize)
+	readBatch := func(tx kv.Tx, i int) error {
+		start := i * notifyBatchSize
+		end := start + notifyBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		batch := make([][]byte, 0, end-start)
+		for j := start; j < end; j++ {
+			headerRLP, err := tx.GetOne(kv.Headers, dbutils.HeaderKey(from+uint64(j), hashes[j]))
 			if err != nil {
 				return err
 			}
-			notifier.OnLogs(logs)
+			if len(headerRLP) == 0 {
+				continue
+			}
+			batch = append(batch, common2.CopyBytes(headerRLP))
 		}
-		logTiming := time.Since(t)
-		log.Info("RPC Daemon notified of new headers", "from", notifyFrom-1, "to", notifyTo, "hash", notifyToHash, "header sending", headerTiming, "log sending", logTiming)
+		batches[i] = batch
+		return libcommon.Stopped(ctx.Done())
 	}
-	return nil
+
+	if db == nil || len(batches) == 1 {
+		for i := range batches {
+			if err := readBatch(tx, i); err != nil {
+				return nil, err
+			}
+		}
+		return batches, nil
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(notifyReadWorkers)
+	for i := range batches {
+		i := i
+		g.Go(func() error {
+			return db.View(gctx, func(tx kv.Tx) error { return readBatch(tx, i) })
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return batches, nil
 }
 
 func ReadLogs(tx kv.Tx, from uint64, isUnwind bool) ([]*remote.SubscribeLogsReply, error) {
@@ -0,0 +1,54 @@
+package stagedsync
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+type headersCollector struct {
+	calls   int
+	headers [][]byte
+}
+
+func (c *headersCollector) OnNewHeader(newHeadersRlp [][]byte) {
+	c.calls++
+	c.headers = append(c.headers, newHeadersRlp...)
+}
+func (c *headersCollector) OnNewPendingLogs(types.Logs)         {}
+func (c *headersCollector) OnLogs([]*remote.SubscribeLogsReply) {}
+func (c *headersCollector) HasLogSubsriptions() bool            { return false }
+
+func TestNotifyNewHeadersBatchedAndSnapped(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+
+	const head = notifyMaxHeaders + 100
+	for i := uint64(1); i <= head; i++ {
+		h := &types.Header{Number: new(big.Int).SetUint64(i), Difficulty: big.NewInt(1)}
+		rawdb.WriteHeader(tx, h)
+		require.NoError(rawdb.WriteCanonicalHash(tx, h.Hash(), i))
+	}
+
+	c := &headersCollector{}
+	require.NoError(NotifyNewHeaders(context.Background(), 0, head, nil, c, tx, nil))
+	require.Equal(notifyMaxHeaders, len(c.headers))
+	require.Equal((notifyMaxHeaders+notifyBatchSize-1)/notifyBatchSize, c.calls)
+
+	// only the most recent headers are sent, the last one being the head
+	last := new(types.Header)
+	require.NoError(rlp.DecodeBytes(c.headers[len(c.headers)-1], last))
+	require.Equal(uint64(head), last.Number.Uint64())
+
+	c = &headersCollector{}
+	require.NoError(NotifyNewHeaders(context.Background(), head-3, head, nil, c, tx, nil))
+	require.Equal(3, len(c.headers))
+	require.Equal(1, c.calls)
+}
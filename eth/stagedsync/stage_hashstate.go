@@ -16,6 +16,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/changeset"
 	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/diskspace"
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
@@ -70,6 +71,9 @@ func SpawnHashStateStage(s *StageState, tx kv.RwTx, cfg HashStateCfg, ctx contex
 		log.Info(fmt.Sprintf("[%s] Promoting plain state", logPrefix), "from", s.BlockNumber, "to", to)
 	}
 	if s.BlockNumber == 0 { // Initial hashing of the state is performed at the previous stage
+		if err := checkPromoteCleanlyDiskSpace(logPrefix, tx, cfg.dirs); err != nil {
+			return err
+		}
 		if err := PromoteHashedStateCleanly(logPrefix, tx, cfg, ctx); err != nil {
 			return err
 		}
@@ -145,6 +149,22 @@ func unwindHashStateStageImpl(logPrefix string, u *UnwindState, s *StageState, t
 	return nil
 }
 
+// checkPromoteCleanlyDiskSpace - hashed state is about the size of plain state, and it's sorted in etl files of about the same size
+func checkPromoteCleanlyDiskSpace(logPrefix string, tx kv.Tx, dirs datadir.Dirs) error {
+	var plainState uint64
+	for _, table := range []string{kv.PlainState, kv.PlainContractCode} {
+		size, err := tx.BucketSize(table)
+		if err != nil {
+			return err
+		}
+		plainState += size
+	}
+	return diskspace.Check(logPrefix,
+		diskspace.Requirement{Dir: dirs.Tmp, Bytes: plainState, Purpose: "etl temp files"},
+		diskspace.Requirement{Dir: dirs.Chaindata, Bytes: plainState, Purpose: "hashed state"},
+	)
+}
+
 func PromoteHashedStateCleanly(logPrefix string, tx kv.RwTx, cfg HashStateCfg, ctx context.Context) error {
 	if err := promotePlainState(
 		logPrefix,
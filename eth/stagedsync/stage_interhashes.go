@@ -14,11 +14,13 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/changeset"
 	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/diskspace"
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
 	"github.com/ledgerwatch/erigon/turbo/trie"
@@ -30,7 +32,7 @@ type TrieCfg struct {
 	db                kv.RwDB
 	checkRoot         bool
 	badBlockHalt      bool
-	tmpDir            string
+	dirs              datadir.Dirs
 	saveNewHashesToDB bool // no reason to save changes when calculating root for mining
 	blockReader       services.FullBlockReader
 	hd                *headerdownload.HeaderDownload
@@ -39,11 +41,11 @@ type TrieCfg struct {
 	agg       *state.Aggregator22
 }
 
-func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB, badBlockHalt bool, tmpDir string, blockReader services.FullBlockReader, hd *headerdownload.HeaderDownload, historyV3 bool, agg *state.Aggregator22) TrieCfg {
+func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB, badBlockHalt bool, dirs datadir.Dirs, blockReader services.FullBlockReader, hd *headerdownload.HeaderDownload, historyV3 bool, agg *state.Aggregator22) TrieCfg {
 	return TrieCfg{
 		db:                db,
 		checkRoot:         checkRoot,
-		tmpDir:            tmpDir,
+		dirs:              dirs,
 		saveNewHashesToDB: saveNewHashesToDB,
 		badBlockHalt:      badBlockHalt,
 		blockReader:       blockReader,
@@ -98,6 +100,9 @@ func SpawnIntermediateHashesStage(s *StageState, u Unwinder, tx kv.RwTx, cfg Tri
 	var root common.Hash
 	tooBigJump := to > s.BlockNumber && to-s.BlockNumber > 100_000 // RetainList is in-memory structure and it will OOM if jump is too big, such big jump anyway invalidate most of existing Intermediate hashes
 	if s.BlockNumber == 0 || tooBigJump {
+		if err = checkRegenerateTrieDiskSpace(logPrefix, tx, cfg.dirs); err != nil {
+			return trie.EmptyRoot, err
+		}
 		if root, err = RegenerateIntermediateHashes(logPrefix, tx, cfg, expectedRootHash, quit); err != nil {
 			return trie.EmptyRoot, err
 		}
@@ -133,17 +138,37 @@ func SpawnIntermediateHashesStage(s *StageState, u Unwinder, tx kv.RwTx, cfg Tri
 	return root, err
 }
 
+// trieToHashedStateRatio - trie tables are ~1/4 of hashed state on mainnet, 1/3 leaves a margin
+const trieToHashedStateRatio = 3
+
+// checkRegenerateTrieDiskSpace - trie is collected in etl files and then loaded into the db
+func checkRegenerateTrieDiskSpace(logPrefix string, tx kv.Tx, dirs datadir.Dirs) error {
+	var hashedState uint64
+	for _, table := range []string{kv.HashedAccounts, kv.HashedStorage} {
+		size, err := tx.BucketSize(table)
+		if err != nil {
+			return err
+		}
+		hashedState += size
+	}
+	need := hashedState / trieToHashedStateRatio
+	return diskspace.Check(logPrefix,
+		diskspace.Requirement{Dir: dirs.Tmp, Bytes: need, Purpose: "etl temp files"},
+		diskspace.Requirement{Dir: dirs.Chaindata, Bytes: need, Purpose: "trie tables"},
+	)
+}
+
 func RegenerateIntermediateHashes(logPrefix string, db kv.RwTx, cfg TrieCfg, expectedRootHash common.Hash, quit <-chan struct{}) (common.Hash, error) {
 	log.Info(fmt.Sprintf("[%s] Regeneration trie hashes started", logPrefix))
 	defer log.Info(fmt.Sprintf("[%s] Regeneration ended", logPrefix))
 	_ = db.ClearBucket(kv.TrieOfAccounts)
 	_ = db.ClearBucket(kv.TrieOfStorage)
 
-	accTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	accTrieCollector := etl.NewCollector(logPrefix, cfg.dirs.Tmp, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer accTrieCollector.Close()
 	accTrieCollectorFunc := accountTrieCollector(accTrieCollector)
 
-	stTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	stTrieCollector := etl.NewCollector(logPrefix, cfg.dirs.Tmp, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer stTrieCollector.Close()
 	stTrieCollectorFunc := storageTrieCollector(stTrieCollector)
 
@@ -509,7 +534,7 @@ func (p *HashPromoter) Unwind(logPrefix string, s *StageState, u *UnwindState, s
 }
 
 func incrementIntermediateHashes(logPrefix string, s *StageState, db kv.RwTx, to uint64, cfg TrieCfg, expectedRootHash common.Hash, quit <-chan struct{}) (common.Hash, error) {
-	p := NewHashPromoter(db, cfg.tmpDir, quit, logPrefix)
+	p := NewHashPromoter(db, cfg.dirs.Tmp, quit, logPrefix)
 	rl := trie.NewRetainList(0)
 	if cfg.historyV3 {
 		cfg.agg.SetTx(db)
@@ -557,11 +582,11 @@ func incrementIntermediateHashes(logPrefix string, s *StageState, db kv.RwTx, to
 			return trie.EmptyRoot, err
 		}
 	}
-	accTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	accTrieCollector := etl.NewCollector(logPrefix, cfg.dirs.Tmp, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer accTrieCollector.Close()
 	accTrieCollectorFunc := accountTrieCollector(accTrieCollector)
 
-	stTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	stTrieCollector := etl.NewCollector(logPrefix, cfg.dirs.Tmp, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer stTrieCollector.Close()
 	stTrieCollectorFunc := storageTrieCollector(stTrieCollector)
 
@@ -623,7 +648,7 @@ func UnwindIntermediateHashesStage(u *UnwindState, s *StageState, tx kv.RwTx, cf
 }
 
 func unwindIntermediateHashesStageImpl(logPrefix string, u *UnwindState, s *StageState, db kv.RwTx, cfg TrieCfg, expectedRootHash common.Hash, quit <-chan struct{}) error {
-	p := NewHashPromoter(db, cfg.tmpDir, quit, logPrefix)
+	p := NewHashPromoter(db, cfg.dirs.Tmp, quit, logPrefix)
 	rl := trie.NewRetainList(0)
 	if cfg.historyV3 {
 		cfg.agg.SetTx(db)
@@ -649,11 +674,11 @@ func unwindIntermediateHashesStageImpl(logPrefix string, u *UnwindState, s *Stag
 		}
 	}
 
-	accTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	accTrieCollector := etl.NewCollector(logPrefix, cfg.dirs.Tmp, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer accTrieCollector.Close()
 	accTrieCollectorFunc := accountTrieCollector(accTrieCollector)
 
-	stTrieCollector := etl.NewCollector(logPrefix, cfg.tmpDir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	stTrieCollector := etl.NewCollector(logPrefix, cfg.dirs.Tmp, etl.NewSortableBuffer(etl.BufferOptimalSize))
 	defer stTrieCollector.Close()
 	stTrieCollectorFunc := storageTrieCollector(stTrieCollector)
 
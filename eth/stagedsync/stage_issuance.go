@@ -124,10 +124,8 @@ func SpawnStageIssuance(cfg IssuanceCfg, s *StageState, tx kv.RwTx, ctx context.
 			burnt.Mul(burnt, big.NewInt(int64(header.GasUsed)))
 		}
 		// TotalIssued, BlockReward and UncleReward, depends on consensus engine
-		if header.Difficulty.Cmp(serenity.SerenityDifficulty) == 0 {
-			// Proof-of-stake is 0.3 ether per block
-			totalIssued.Add(totalIssued, serenity.RewardSerenity)
-		} else {
+		// Proof-of-stake blocks mint nothing on the execution layer, validators are rewarded by the beacon chain
+		if header.Difficulty.Cmp(serenity.SerenityDifficulty) != 0 {
 			var blockReward uint256.Int
 			var uncleRewards []uint256.Int
 			if header.UncleHash == types.EmptyUncleHash {
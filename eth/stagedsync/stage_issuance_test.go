@@ -62,5 +62,42 @@ func TestIssuanceStage(t *testing.T) {
 
 	ti, err := rawdb.ReadTotalIssued(tx, 3)
 	assert.NoError(err)
-	assert.Equal(ti, big.NewInt(900000000000000000))
+	// proof-of-stake blocks (zero difficulty) mint nothing on the execution layer
+	assert.Zero(ti.Sign())
+}
+
+func TestIssuanceStageMerge(t *testing.T) {
+	ctx, assert := context.Background(), assert.New(t)
+	db, tx := memdb.NewTestTx(t)
+
+	// blocks 1 and 2 are mined, block 3 is the first proof-of-stake block
+	for n, difficulty := range []int64{1, 1, 0} {
+		header := &types.Header{
+			Number:     big.NewInt(int64(n + 1)),
+			Difficulty: big.NewInt(difficulty),
+			UncleHash:  types.EmptyUncleHash,
+		}
+		rawdb.WriteHeader(tx, header)
+		rawdb.WriteCanonicalHash(tx, header.Hash(), header.Number.Uint64())
+	}
+	stages.SaveStageProgress(tx, stages.Bodies, 3)
+
+	err := SpawnStageIssuance(StageIssuanceCfg(db, &params.ChainConfig{
+		Consensus: params.EtHashConsensus,
+	}, snapshotsync.NewBlockReader(), true), &StageState{
+		ID: stages.Issuance,
+	}, tx, ctx)
+	assert.NoError(err)
+
+	frontierReward := big.NewInt(5e18)
+	ti, err := rawdb.ReadTotalIssued(tx, 1)
+	assert.NoError(err)
+	assert.Equal(frontierReward, ti)
+	ti, err = rawdb.ReadTotalIssued(tx, 2)
+	assert.NoError(err)
+	assert.Equal(new(big.Int).Mul(frontierReward, big.NewInt(2)), ti)
+	// the proof-of-stake block doesn't change the total
+	ti, err = rawdb.ReadTotalIssued(tx, 3)
+	assert.NoError(err)
+	assert.Equal(new(big.Int).Mul(frontierReward, big.NewInt(2)), ti)
 }
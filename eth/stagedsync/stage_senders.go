@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
 	"github.com/ledgerwatch/log/v3"
@@ -63,11 +65,11 @@ func StageSendersCfg(db kv.RwDB, chainCfg *params.ChainConfig, badBlockHalt bool
 	}
 }
 
-func SpawnRecoverSendersStage(cfg SendersCfg, s *StageState, u Unwinder, tx kv.RwTx, toBlock uint64, ctx context.Context, quiet bool) error {
-	if cfg.blockRetire != nil && cfg.blockRetire.Snapshots() != nil && cfg.blockRetire.Snapshots().Cfg().Enabled && s.BlockNumber < cfg.blockRetire.Snapshots().BlocksAvailable() {
-		s.BlockNumber = cfg.blockRetire.Snapshots().BlocksAvailable()
-	}
+// sendersSnapshotSamples is the amount of blocks, taken from snapshots, whose senders are recovered
+// to validate the senders stored in the snapshots when the stage skips over them
+const sendersSnapshotSamples = 32
 
+func SpawnRecoverSendersStage(cfg SendersCfg, s *StageState, u Unwinder, tx kv.RwTx, toBlock uint64, ctx context.Context, quiet bool) error {
 	quitCh := ctx.Done()
 	useExternalTx := tx != nil
 	if !useExternalTx {
@@ -79,6 +81,19 @@ func SpawnRecoverSendersStage(cfg SendersCfg, s *StageState, u Unwinder, tx kv.R
 		defer tx.Rollback()
 	}
 
+	if cfg.blockRetire != nil && cfg.blockRetire.Snapshots() != nil && cfg.blockRetire.Snapshots().Cfg().Enabled && s.BlockNumber < cfg.blockRetire.Snapshots().BlocksAvailable() {
+		// Senders of the blocks in snapshots are stored in the snapshots, no need to recover them
+		available := cfg.blockRetire.Snapshots().BlocksAvailable()
+		blockReader := snapshotsync.NewBlockReaderWithSnapshots(cfg.blockRetire.Snapshots())
+		if err := validateSnapshotSenders(ctx, blockReader, cfg.chainConfig, tx, s.BlockNumber+1, available, s.LogPrefix()); err != nil {
+			return err
+		}
+		if !quiet {
+			log.Info(fmt.Sprintf("[%s] Using senders from snapshots", s.LogPrefix()), "from", s.BlockNumber+1, "to", available)
+		}
+		s.BlockNumber = available
+	}
+
 	prevStageProgress, errStart := stages.GetStageProgress(tx, stages.Bodies)
 	if errStart != nil {
 		return errStart
@@ -280,6 +295,14 @@ Loop:
 		if err = s.Update(tx, to); err != nil {
 			return err
 		}
+		// new nodes don't have the dictionary of transactions yet, train it once the bodies have enough of them
+		trained, err := rawdb.TrainTxsDictIfMissing(tx)
+		if err != nil {
+			return err
+		}
+		if trained && !quiet {
+			log.Info(fmt.Sprintf("[%s] Trained txs dictionary", logPrefix))
+		}
 	}
 
 	if !useExternalTx {
@@ -306,6 +329,41 @@ type senderRecoveryJob struct {
 	err         error
 }
 
+// validateSnapshotSenders recovers the senders of a sample of blocks in [from, to] and compares them
+// with the senders stored in the snapshots, to catch corrupted or malicious snapshot files.
+func validateSnapshotSenders(ctx context.Context, blockReader services.FullBlockReader, chainConfig *params.ChainConfig, tx kv.Tx, from, to uint64, logPrefix string) error {
+	if from > to {
+		return nil
+	}
+	samples := cmp.Min(uint64(sendersSnapshotSamples), to-from+1)
+	for i := uint64(0); i < samples; i++ {
+		blockNum := from + uint64(rand.Int63n(int64(to-from+1))) // nolint: gosec
+		hash, err := blockReader.CanonicalHash(ctx, tx, blockNum)
+		if err != nil {
+			return err
+		}
+		block, senders, err := blockReader.BlockWithSenders(ctx, tx, hash, blockNum)
+		if err != nil {
+			return err
+		}
+		if block == nil || len(senders) != block.Transactions().Len() {
+			// no senders in snapshot is fine, they are recovered on the fly when needed
+			continue
+		}
+		signer := types.MakeSigner(chainConfig, blockNum)
+		for j, txn := range block.Transactions() {
+			sender, err := signer.Sender(txn)
+			if err != nil {
+				return fmt.Errorf("[%s] recovering sender of txn %d in block %d: %w", logPrefix, j, blockNum, err)
+			}
+			if sender != senders[j] {
+				return fmt.Errorf("[%s] snapshot has wrong sender of txn %d in block %d: %x, recovered %x", logPrefix, j, blockNum, senders[j], sender)
+			}
+		}
+	}
+	return nil
+}
+
 func recoverSenders(ctx context.Context, logPrefix string, cryptoContext *secp256k1.Context, config *params.ChainConfig, in, out chan *senderRecoveryJob, quit <-chan struct{}) {
 	var job *senderRecoveryJob
 	var ok bool
@@ -2,6 +2,7 @@ package stagedsync
 
 import (
 	"context"
+	"math/big"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
@@ -149,3 +150,33 @@ func TestSenders(t *testing.T) {
 		assert.Equal(t, 3, len(txs))
 	}
 }
+
+func TestValidateSnapshotSenders(t *testing.T) {
+	ctx := context.Background()
+	_, tx := memdb.NewTestTx(t)
+	require := require.New(t)
+
+	testKey, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr := crypto.PubkeyToAddress(testKey.PublicKey)
+	signer := types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+	for blockNum := uint64(1); blockNum <= 2; blockNum++ {
+		txn, err := types.SignTx(types.NewTransaction(blockNum, testAddr, u256.Num1, 21000, u256.Num1, nil), *signer, testKey)
+		require.NoError(err)
+		header := &types.Header{Number: new(big.Int).SetUint64(blockNum), Extra: []byte("test")}
+		rawdb.WriteHeader(tx, header)
+		require.NoError(rawdb.WriteBody(tx, header.Hash(), blockNum, &types.Body{Transactions: []types.Transaction{txn}}))
+		require.NoError(rawdb.WriteCanonicalHash(tx, header.Hash(), blockNum))
+		require.NoError(rawdb.WriteSenders(tx, header.Hash(), blockNum, []common.Address{testAddr}))
+	}
+	blockReader := snapshotsync.NewBlockReader()
+
+	require.NoError(validateSnapshotSenders(ctx, blockReader, params.TestChainConfig, tx, 1, 2, "test"))
+	require.NoError(validateSnapshotSenders(ctx, blockReader, params.TestChainConfig, tx, 3, 2, "test")) // empty range
+
+	// every sampled block is checked: the range has only the block with the wrong sender
+	hash, err := rawdb.ReadCanonicalHash(tx, 2)
+	require.NoError(err)
+	require.NoError(rawdb.WriteSenders(tx, hash, 2, []common.Address{{1}}))
+	require.ErrorContains(validateSnapshotSenders(ctx, blockReader, params.TestChainConfig, tx, 2, 2, "test"), "wrong sender of txn 0 in block 2")
+	require.NoError(validateSnapshotSenders(ctx, blockReader, params.TestChainConfig, tx, 1, 1, "test"))
+}
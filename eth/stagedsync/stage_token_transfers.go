@@ -0,0 +1,252 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/c2h5oh/datasize"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/etl"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// TokenTransfersCfg - the TokenTransfers stage indexes Transfer logs of ERC-20/ERC-721 tokens by (holder, token) in
+// rawdb.TokenTransferIndex. The logs come from receipts, so the index is pruned with them.
+type TokenTransfersCfg struct {
+	tmpdir     string
+	db         kv.RwDB
+	prune      prune.Mode
+	bufLimit   datasize.ByteSize
+	flushEvery time.Duration
+}
+
+func StageTokenTransfersCfg(db kv.RwDB, prune prune.Mode, tmpDir string) TokenTransfersCfg {
+	return TokenTransfersCfg{
+		db:         db,
+		prune:      prune,
+		bufLimit:   bitmapsBufLimit,
+		flushEvery: bitmapsFlushEvery,
+		tmpdir:     tmpDir,
+	}
+}
+
+func SpawnTokenTransfers(s *StageState, tx kv.RwTx, cfg TokenTransfersCfg, ctx context.Context) error {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		var err error
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	endBlock, err := s.ExecutionAt(tx)
+	if err != nil {
+		return fmt.Errorf("getting last executed block: %w", err)
+	}
+	if endBlock <= s.BlockNumber {
+		return nil
+	}
+
+	startBlock := s.BlockNumber
+	pruneTo := cfg.prune.Receipts.PruneTo(endBlock)
+	if startBlock < pruneTo {
+		startBlock = pruneTo
+	}
+	if startBlock > 0 {
+		startBlock++
+	}
+	if err = promoteTokenTransfers(s.LogPrefix(), tx, startBlock, endBlock, cfg, ctx); err != nil {
+		return err
+	}
+	if err = s.Update(tx, endBlock); err != nil {
+		return err
+	}
+
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTokenTransfers calls walker with the index keys (holder + token) of Transfer logs of blocks [from, to]
+func walkTokenTransfers(tx kv.Tx, from, to uint64, quit <-chan struct{}, walker func(blockNum uint64, key []byte) error) error {
+	logs, err := tx.Cursor(kv.Log)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	reader := bytes.NewReader(nil)
+	for k, v, err := logs.Seek(dbutils.LogKey(from, 0)); k != nil; k, v, err = logs.Next() {
+		if err != nil {
+			return err
+		}
+		if err := libcommon.Stopped(quit); err != nil {
+			return err
+		}
+		blockNum := binary.BigEndian.Uint64(k[:8])
+		if blockNum > to {
+			break
+		}
+
+		var ll types.Logs
+		reader.Reset(v)
+		if err := cbor.Unmarshal(&ll, reader); err != nil {
+			return fmt.Errorf("receipt unmarshal failed: %w, block=%d", err, blockNum)
+		}
+		for _, l := range ll {
+			sender, recipient, ok := rawdb.TokenTransfer(l)
+			if !ok {
+				continue
+			}
+			if err := walker(blockNum, rawdb.TokenTransferKey(sender, l.Address)); err != nil {
+				return err
+			}
+			if recipient != sender {
+				if err := walker(blockNum, rawdb.TokenTransferKey(recipient, l.Address)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func promoteTokenTransfers(logPrefix string, tx kv.RwTx, start, end uint64, cfg TokenTransfersCfg, ctx context.Context) error {
+	quit := ctx.Done()
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+	checkFlushEvery := time.NewTicker(cfg.flushEvery)
+	defer checkFlushEvery.Stop()
+
+	collector := etl.NewCollector(logPrefix, cfg.tmpdir, etl.NewSortableBuffer(etl.BufferOptimalSize))
+	defer collector.Close()
+
+	if end-start > 100 {
+		log.Info(fmt.Sprintf("[%s] processing", logPrefix), "from", start, "to", end)
+	}
+
+	transfers := map[string]*roaring.Bitmap{}
+	if err := walkTokenTransfers(tx, start, end, quit, func(blockNum uint64, key []byte) error {
+		select {
+		default:
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Progress", logPrefix), "number", blockNum)
+		case <-checkFlushEvery.C:
+			if needFlush(transfers, cfg.bufLimit) {
+				if err := flushBitmaps(collector, transfers); err != nil {
+					return err
+				}
+				transfers = map[string]*roaring.Bitmap{}
+			}
+		}
+
+		m, ok := transfers[string(key)]
+		if !ok {
+			m = roaring.New()
+			transfers[string(key)] = m
+		}
+		m.Add(uint32(blockNum))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := flushBitmaps(collector, transfers); err != nil {
+		return err
+	}
+	return collector.Load(tx, rawdb.TokenTransferIndex, bitmapChunksLoader(), etl.TransformArgs{Quit: quit})
+}
+
+func UnwindTokenTransfers(u *UnwindState, s *StageState, tx kv.RwTx, cfg TokenTransfersCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	if err = unwindTokenTransfers(tx, u.UnwindPoint, ctx.Done()); err != nil {
+		return err
+	}
+	if err = u.Done(tx); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unwindTokenTransfers(tx kv.RwTx, to uint64, quit <-chan struct{}) error {
+	keys := map[string]struct{}{}
+	if err := walkTokenTransfers(tx, to+1, ^uint64(0), quit, func(_ uint64, key []byte) error {
+		keys[string(key)] = struct{}{}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return truncateBitmaps(tx, rawdb.TokenTransferIndex, keys, to)
+}
+
+func PruneTokenTransfers(s *PruneState, tx kv.RwTx, cfg TokenTransfersCfg, ctx context.Context) (err error) {
+	if !cfg.prune.Receipts.Enabled() {
+		return nil
+	}
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	pruneTo := cfg.prune.Receipts.PruneTo(s.ForwardProgress)
+	if err = pruneTokenTransfers(s.LogPrefix(), tx, cfg.tmpdir, pruneTo, ctx); err != nil {
+		return err
+	}
+	if err = s.Done(tx); err != nil {
+		return err
+	}
+
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneTokenTransfers deletes chunks of blocks before pruneTo, keys are found in the logs which are not pruned yet
+func pruneTokenTransfers(logPrefix string, tx kv.RwTx, tmpDir string, pruneTo uint64, ctx context.Context) error {
+	keys := etl.NewCollector(logPrefix, tmpDir, etl.NewOldestEntryBuffer(etl.BufferOptimalSize))
+	defer keys.Close()
+	if pruneTo == 0 {
+		return nil
+	}
+	if err := walkTokenTransfers(tx, 0, pruneTo-1, ctx.Done(), func(_ uint64, key []byte) error {
+		return keys.Collect(key, nil)
+	}); err != nil {
+		return err
+	}
+	return pruneOldLogChunks(tx, rawdb.TokenTransferIndex, keys, pruneTo, ctx)
+}
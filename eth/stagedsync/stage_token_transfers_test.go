@@ -0,0 +1,86 @@
+package stagedsync
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/stretchr/testify/require"
+)
+
+func transferLog(token, from, to common.Address) *types.Log {
+	return &types.Log{
+		Address: token,
+		Topics:  []common.Hash{rawdb.TransferTopic, from.Hash(), to.Hash()},
+	}
+}
+
+// genTokenTransfers - in block i holder 1 sends token 1 to holder 2, every 3rd block holder 2 sends token 2 to holder 3
+func genTokenTransfers(t *testing.T, tx kv.RwTx, blocks uint64) {
+	token1, token2 := common.Address{0xa1}, common.Address{0xa2}
+	h1, h2, h3 := common.Address{1}, common.Address{2}, common.Address{3}
+	for i := uint64(0); i < blocks; i++ {
+		receipts := types.Receipts{{Logs: []*types.Log{transferLog(token1, h1, h2)}}}
+		if i%3 == 0 {
+			receipts = append(receipts, &types.Receipt{Logs: []*types.Log{
+				{Address: token2, Topics: []common.Hash{{0xff}, h2.Hash(), h3.Hash()}}, // not a transfer
+				transferLog(token2, h2, h3),
+			}})
+		}
+		require.NoError(t, rawdb.AppendReceipts(tx, i, receipts))
+	}
+}
+
+func TestTokenTransfers(t *testing.T) {
+	require, tmpDir, ctx := require.New(t), t.TempDir(), context.Background()
+	db := rawdb.NewMemDB()
+	defer db.Close()
+	tx, err := db.BeginRw(ctx)
+	require.NoError(err)
+	defer tx.Rollback()
+	genTokenTransfers(t, tx, 100)
+
+	cfg := StageTokenTransfersCfg(nil, prune.DefaultMode, tmpDir)
+	cfg.bufLimit = 10
+	cfg.flushEvery = time.Nanosecond
+	require.NoError(promoteTokenTransfers("tokenTransfers", tx, 0, 49, cfg, ctx))
+	require.NoError(promoteTokenTransfers("tokenTransfers", tx, 50, 99, cfg, ctx))
+
+	token1, token2 := common.Address{0xa1}, common.Address{0xa2}
+	h1, h2, h3 := common.Address{1}, common.Address{2}, common.Address{3}
+	cardinality := func(holder, token common.Address) uint64 {
+		m, err := bitmapdb.Get(tx, rawdb.TokenTransferIndex, rawdb.TokenTransferKey(holder, token), 0, 10_000_000)
+		require.NoError(err)
+		return m.GetCardinality()
+	}
+	require.Equal(uint64(100), cardinality(h1, token1))
+	require.Equal(uint64(100), cardinality(h2, token1))
+	require.Equal(uint64(34), cardinality(h2, token2))
+	require.Equal(uint64(34), cardinality(h3, token2))
+	require.Zero(cardinality(h1, token2))
+	require.Zero(cardinality(h3, token1))
+
+	require.NoError(unwindTokenTransfers(tx, 70, nil))
+	m, err := bitmapdb.Get(tx, rawdb.TokenTransferIndex, rawdb.TokenTransferKey(h3, token2), 0, 10_000_000)
+	require.NoError(err)
+	require.Equal(uint32(69), m.Maximum())
+	require.Equal(uint64(71), cardinality(h1, token1))
+
+	// chunks of the pruned blocks are deleted, the last chunk of every key is kept
+	require.NoError(pruneTokenTransfers("tokenTransfers", tx, tmpDir, 50, ctx))
+	keys := 0
+	require.NoError(tx.ForEach(rawdb.TokenTransferIndex, nil, func(k, v []byte) error {
+		require.Equal(^uint32(0), binary.BigEndian.Uint32(k[2*length.Addr:]))
+		keys++
+		return nil
+	}))
+	require.Equal(4, keys)
+}
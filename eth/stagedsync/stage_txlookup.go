@@ -112,7 +112,6 @@ func SpawnTxLookup(s *StageState, tx kv.RwTx, toBlock uint64, cfg TxLookupCfg, c
 
 // txnLookupTransform - [startKey, endKey)
 func txnLookupTransform(logPrefix string, tx kv.RwTx, blockFrom, blockTo uint64, quitCh <-chan struct{}, cfg TxLookupCfg) error {
-	bigNum := new(big.Int)
 	return etl.Transform(logPrefix, tx, kv.HeaderCanonical, kv.TxLookup, cfg.tmpdir, func(k, v []byte, next etl.ExtractNextFunc) error {
 		blocknum, blockHash := binary.BigEndian.Uint64(k), common.CastToHash(v)
 		body := rawdb.ReadCanonicalBodyWithTransactions(tx, blockHash, blocknum)
@@ -120,9 +119,8 @@ func txnLookupTransform(logPrefix string, tx kv.RwTx, blockFrom, blockTo uint64,
 			return fmt.Errorf("transform: empty block body %d, hash %x", blocknum, v)
 		}
 
-		blockNumBytes := bigNum.SetUint64(blocknum).Bytes()
-		for _, txn := range body.Transactions {
-			if err := next(k, txn.Hash().Bytes(), blockNumBytes); err != nil {
+		for i, txnHash := range types.Transactions(body.Transactions).Hashes() {
+			if err := next(k, txnHash.Bytes(), rawdb.TxLookupValue(blocknum, uint64(i))); err != nil {
 				return err
 			}
 		}
@@ -259,8 +257,8 @@ func deleteTxLookupRange(tx kv.RwTx, logPrefix string, blockFrom, blockTo uint64
 			return fmt.Errorf("empty block body %d, hash %x", blocknum, v)
 		}
 
-		for _, txn := range body.Transactions {
-			if err := next(k, txn.Hash().Bytes(), nil); err != nil {
+		for _, txnHash := range types.Transactions(body.Transactions).Hashes() {
+			if err := next(k, txnHash.Bytes(), nil); err != nil {
 				return err
 			}
 		}
@@ -29,7 +29,7 @@ func SpawnVerkleTrie(s *StageState, u Unwinder, tx kv.RwTx, cfg TrieCfg, ctx con
 	if err != nil {
 		return common.Hash{}, err
 	}
-	verkleWriter := verkletrie.NewVerkleTreeWriter(tx, cfg.tmpDir)
+	verkleWriter := verkletrie.NewVerkleTreeWriter(tx, cfg.dirs.Tmp)
 	if err := verkletrie.IncrementAccount(tx, tx, 10, verkleWriter, from, to); err != nil {
 		return common.Hash{}, err
 	}
@@ -69,7 +69,7 @@ func UnwindVerkleTrie(u *UnwindState, s *StageState, tx kv.RwTx, cfg TrieCfg, ct
 	if err != nil {
 		return err
 	}
-	verkleWriter := verkletrie.NewVerkleTreeWriter(tx, cfg.tmpDir)
+	verkleWriter := verkletrie.NewVerkleTreeWriter(tx, cfg.dirs.Tmp)
 	if err := verkletrie.IncrementAccount(tx, tx, 10, verkleWriter, from, to); err != nil {
 		return err
 	}
@@ -0,0 +1,125 @@
+package stages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+)
+
+// Mode - profile of the node: which stages it runs and which data it serves
+type Mode string
+
+const (
+	ModeFull    Mode = "full"    // all stages, history is pruned according to --prune
+	ModeArchive Mode = "archive" // all stages, nothing may be pruned
+	ModeBlocks  Mode = "blocks"  // history-only: headers, bodies, senders and transaction lookup, blocks aren't executed
+	ModeState   Mode = "state"   // latest state only: blocks are executed, history indices aren't built
+	// ModeExternal - like ModeBlocks, but blocks are executed by an external service, see stagedsync.ExternalExecutor
+	ModeExternal Mode = "external"
+)
+
+var Modes = []Mode{ModeFull, ModeArchive, ModeBlocks, ModeState, ModeExternal}
+
+var ModeKey = []byte("sync_mode")
+
+func ModeFromString(s string) (Mode, error) {
+	if s == "" {
+		return ModeFull, nil
+	}
+	for _, m := range Modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	names := make([]string, len(Modes))
+	for i, m := range Modes {
+		names[i] = string(m)
+	}
+	return "", fmt.Errorf("unknown sync mode %q, expected one of: %s", s, strings.Join(names, ", "))
+}
+
+// Disabled - stages the mode doesn't run, nor unwind
+func (m Mode) Disabled() []SyncStage {
+	switch m {
+	case ModeBlocks:
+		return []SyncStage{Execution, ExternalExecution, Translation, HashState, IntermediateHashes, CallTraces, AccountHistoryIndex, StorageHistoryIndex, LogIndex, TokenTransfers}
+	case ModeState:
+		return []SyncStage{ExternalExecution, CallTraces, AccountHistoryIndex, StorageHistoryIndex, LogIndex, TokenTransfers, TxLookup}
+	case ModeExternal:
+		return []SyncStage{Execution, Translation, HashState, IntermediateHashes, CallTraces, AccountHistoryIndex, StorageHistoryIndex, LogIndex, TokenTransfers}
+	}
+	return []SyncStage{ExternalExecution}
+}
+
+// Head - stage which progress is the head of the node: stages after it process blocks up to it, "latest" RPC block
+// is at most it. Execution if the mode executes blocks.
+func (m Mode) Head() SyncStage {
+	switch m {
+	case ModeBlocks:
+		return Senders
+	case ModeExternal:
+		return ExternalExecution
+	}
+	return Execution
+}
+
+func (m Mode) indexed() bool { return m == ModeFull || m == ModeArchive }
+
+// HasState - the node executes blocks, state of the head block is available
+func (m Mode) HasState() bool { return m != ModeBlocks && m != ModeExternal }
+
+// Validate checks that the prune mode makes sense for the sync mode
+func (m Mode) Validate(pm prune.Mode) error {
+	switch m {
+	case ModeArchive:
+		if pm.History.Enabled() || pm.Receipts.Enabled() || pm.TxIndex.Enabled() || pm.CallTraces.Enabled() {
+			return fmt.Errorf("sync mode %s keeps all history, but pruning is enabled: %s", m, pm.String())
+		}
+	case ModeState:
+		// execution writes changesets and receipts, nothing else reads them in this mode
+		if !pm.History.Enabled() || !pm.Receipts.Enabled() {
+			return fmt.Errorf("sync mode %s keeps only recent state, add --prune=hr", m)
+		}
+	case ModeBlocks, ModeExternal:
+		if pm.History.Enabled() || pm.Receipts.Enabled() || pm.CallTraces.Enabled() {
+			return fmt.Errorf("sync mode %s doesn't execute blocks, --prune of history, receipts and call traces makes no sense", m)
+		}
+	}
+	return nil
+}
+
+// ReadMode - sync mode the node ran with last time, ModeFull for databases created before sync modes
+func ReadMode(db kv.Getter) (Mode, error) {
+	v, err := db.GetOne(kv.DatabaseInfo, ModeKey)
+	if err != nil {
+		return "", err
+	}
+	if len(v) == 0 {
+		return ModeFull, nil
+	}
+	return Mode(v), nil
+}
+
+// EnsureModeChangeAllowed stores the sync mode. Modes with history indices can't be switched to after blocks were
+// executed in a mode without them: the indices of these blocks would be missing.
+func EnsureModeChangeAllowed(tx kv.RwTx, m Mode) error {
+	prev, err := ReadMode(tx)
+	if err != nil {
+		return err
+	}
+	if prev == m {
+		return nil
+	}
+	if m.indexed() && !prev.indexed() {
+		executed, err := GetStageProgress(tx, Execution)
+		if err != nil {
+			return err
+		}
+		if executed > 0 {
+			return fmt.Errorf("sync mode can't be changed from %s to %s: history of executed blocks isn't indexed, resync is needed", prev, m)
+		}
+	}
+	return tx.Put(kv.DatabaseInfo, ModeKey, []byte(m))
+}
@@ -0,0 +1,59 @@
+package stages
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModeValidate(t *testing.T) {
+	pruned, err := prune.FromCli(1337, "hr", 0, 0, 0, 0, 0, 0, 0, 0, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, ModeFull.Validate(prune.DefaultMode))
+	require.NoError(t, ModeFull.Validate(pruned))
+	require.NoError(t, ModeArchive.Validate(prune.DefaultMode))
+	require.Error(t, ModeArchive.Validate(pruned))
+	require.Error(t, ModeState.Validate(prune.DefaultMode))
+	require.NoError(t, ModeState.Validate(pruned))
+	require.NoError(t, ModeBlocks.Validate(prune.DefaultMode))
+	require.Error(t, ModeBlocks.Validate(pruned))
+	require.NoError(t, ModeExternal.Validate(prune.DefaultMode))
+	require.Error(t, ModeExternal.Validate(pruned))
+
+	_, err = ModeFromString("light")
+	require.Error(t, err)
+	m, err := ModeFromString("")
+	require.NoError(t, err)
+	require.Equal(t, ModeFull, m)
+}
+
+func TestModeExternal(t *testing.T) {
+	require.Equal(t, ExternalExecution, ModeExternal.Head())
+	require.False(t, ModeExternal.HasState())
+	require.Contains(t, ModeExternal.Disabled(), Execution)
+	require.NotContains(t, ModeExternal.Disabled(), TxLookup)
+	for _, m := range []Mode{ModeFull, ModeArchive, ModeBlocks, ModeState} {
+		require.Contains(t, m.Disabled(), ExternalExecution, m)
+	}
+}
+
+func TestEnsureModeChangeAllowed(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	m, err := ReadMode(tx)
+	require.NoError(t, err)
+	require.Equal(t, ModeFull, m)
+
+	require.NoError(t, EnsureModeChangeAllowed(tx, ModeState))
+	require.NoError(t, EnsureModeChangeAllowed(tx, ModeFull)) // nothing executed yet
+	require.NoError(t, EnsureModeChangeAllowed(tx, ModeState))
+	require.NoError(t, SaveStageProgress(tx, Execution, 100))
+	require.Error(t, EnsureModeChangeAllowed(tx, ModeFull))
+	require.NoError(t, EnsureModeChangeAllowed(tx, ModeBlocks))
+	m, err = ReadMode(tx)
+	require.NoError(t, err)
+	require.Equal(t, ModeBlocks, m)
+	require.Error(t, EnsureModeChangeAllowed(tx, ModeArchive))
+}
@@ -29,20 +29,22 @@ import (
 type SyncStage string
 
 var (
-	Snapshots           SyncStage = "Snapshots"       // Snapshots
-	Headers             SyncStage = "Headers"         // Headers are downloaded, their Proof-Of-Work validity and chaining is verified
-	CumulativeIndex     SyncStage = "CumulativeIndex" // Calculate how much gas has been used up to each block.
-	BlockHashes         SyncStage = "BlockHashes"     // Headers Number are written, fills blockHash => number bucket
-	Bodies              SyncStage = "Bodies"          // Block bodies are downloaded, TxHash and UncleHash are getting verified
-	Senders             SyncStage = "Senders"         // "From" recovered from signatures, bodies re-written
-	Execution           SyncStage = "Execution"       // Executing each block w/o buildinf a trie
-	Translation         SyncStage = "Translation"     // Translation each marked for translation contract (from EVM to TEVM)
+	Snapshots           SyncStage = "Snapshots"         // Snapshots
+	Headers             SyncStage = "Headers"           // Headers are downloaded, their Proof-Of-Work validity and chaining is verified
+	CumulativeIndex     SyncStage = "CumulativeIndex"   // Calculate how much gas has been used up to each block.
+	BlockHashes         SyncStage = "BlockHashes"       // Headers Number are written, fills blockHash => number bucket
+	Bodies              SyncStage = "Bodies"            // Block bodies are downloaded, TxHash and UncleHash are getting verified
+	Senders             SyncStage = "Senders"           // "From" recovered from signatures, bodies re-written
+	Execution           SyncStage = "Execution"         // Executing each block w/o buildinf a trie
+	ExternalExecution   SyncStage = "ExternalExecution" // Blocks are executed by an external service, state root and receipts hash are verified
+	Translation         SyncStage = "Translation"       // Translation each marked for translation contract (from EVM to TEVM)
 	VerkleTrie          SyncStage = "VerkleTrie"
 	IntermediateHashes  SyncStage = "IntermediateHashes"  // Generate intermediate hashes, calculate the state root hash
 	HashState           SyncStage = "HashState"           // Apply Keccak256 to all the keys in the state
 	AccountHistoryIndex SyncStage = "AccountHistoryIndex" // Generating history index for accounts
 	StorageHistoryIndex SyncStage = "StorageHistoryIndex" // Generating history index for storage
 	LogIndex            SyncStage = "LogIndex"            // Generating logs index (from receipts)
+	TokenTransfers      SyncStage = "TokenTransfers"      // Generating index of ERC-20/ERC-721 transfers by holder (from receipts)
 	CallTraces          SyncStage = "CallTraces"          // Generating call traces index
 	TxLookup            SyncStage = "TxLookup"            // Generating transactions lookup index
 	Issuance            SyncStage = "WatchTheBurn"        // Compute ether issuance for each block
@@ -60,12 +62,14 @@ var AllStages = []SyncStage{
 	Bodies,
 	Senders,
 	Execution,
+	ExternalExecution,
 	Translation,
 	HashState,
 	IntermediateHashes,
 	AccountHistoryIndex,
 	StorageHistoryIndex,
 	LogIndex,
+	TokenTransfers,
 	CallTraces,
 	TxLookup,
 	Finish,
@@ -9,6 +9,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/log/v3"
 )
@@ -24,6 +25,8 @@ type Sync struct {
 	currentStage uint
 	timings      []Timing
 	logPrefixes  []string
+	watchdog     *Watchdog
+	mode         stages.Mode
 }
 
 type Timing struct {
@@ -156,13 +159,17 @@ func New(stagesList []*Stage, unwindOrder UnwindOrder, pruneOrder PruneOrder) *S
 		logPrefixes[i] = fmt.Sprintf("%d/%d %s", i+1, len(stagesList), stagesList[i].ID)
 	}
 
-	return &Sync{
+	s := &Sync{
 		stages:       stagesList,
 		currentStage: 0,
 		unwindOrder:  unwindStages,
 		pruningOrder: pruneStages,
 		logPrefixes:  logPrefixes,
 	}
+	// stages of other modes need configuration of their own (ExternalExecution - an executor), they run only after
+	// SetMode enables them
+	s.SetMode(stages.ModeFull)
+	return s
 }
 
 func (s *Sync) StageState(stage stages.SyncStage, tx kv.Tx, db kv.RoDB) (*StageState, error) {
@@ -193,6 +200,9 @@ func (s *Sync) RunUnwind(db kv.RwDB, tx kv.RwTx) error {
 	if s.unwindPoint == nil {
 		return nil
 	}
+	if err := s.markUnwind(db, tx); err != nil {
+		return err
+	}
 	for j := 0; j < len(s.unwindOrder); j++ {
 		if s.unwindOrder[j] == nil || s.unwindOrder[j].Disabled || s.unwindOrder[j].Unwind == nil {
 			continue
@@ -207,15 +217,28 @@ func (s *Sync) RunUnwind(db kv.RwDB, tx kv.RwTx) error {
 	if err := s.SetCurrentStage(s.stages[0].ID); err != nil {
 		return err
 	}
-	return nil
+	return s.unmarkUnwind(db, tx)
 }
-func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool, quiet bool) error {
+func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool, quiet bool) (err error) {
 	s.prevUnwindPoint = nil
 	s.timings = s.timings[:0]
+	s.watchdog.cycleStarted(tx != nil)
+	defer s.watchdog.cycleDone()
+	defer func() {
+		if err == nil || tx != nil {
+			return
+		}
+		if repairErr := s.RepairUnwindMarker(db); repairErr != nil {
+			log.Warn("[sync] Failed to repair unwind marker", "err", repairErr)
+		}
+	}()
 
 	for !s.IsDone() {
 		var badBlockUnwind bool
 		if s.unwindPoint != nil {
+			if err := s.markUnwind(db, tx); err != nil {
+				return err
+			}
 			for j := 0; j < len(s.unwindOrder); j++ {
 				if s.unwindOrder[j] == nil || s.unwindOrder[j].Disabled || s.unwindOrder[j].Unwind == nil {
 					continue
@@ -223,6 +246,9 @@ func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool, quiet bool) error {
 				if err := s.unwindStage(firstCycle, s.unwindOrder[j], db, tx); err != nil {
 					return err
 				}
+				if s.watchdog.takeRestart() {
+					return ErrWatchdogRestart
+				}
 			}
 			s.prevUnwindPoint = s.unwindPoint
 			s.unwindPoint = nil
@@ -255,6 +281,9 @@ func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool, quiet bool) error {
 		if err := s.runStage(stage, db, tx, firstCycle, badBlockUnwind, quiet); err != nil {
 			return err
 		}
+		if s.watchdog.takeRestart() {
+			return ErrWatchdogRestart
+		}
 
 		if string(stage.ID) == debug.StopAfterStage() { // stop process for debugging reasons
 			log.Warn("STOP_AFTER_STAGE env flag forced to stop app")
@@ -263,6 +292,10 @@ func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool, quiet bool) error {
 
 		s.NextStage()
 	}
+	// all stages are synced up, also after an unwind of an earlier cycle which failed or was interrupted by a restart
+	if err := s.unmarkUnwind(db, tx); err != nil {
+		return err
+	}
 
 	for i := 0; i < len(s.pruningOrder); i++ {
 		if s.pruningOrder[i] == nil || s.pruningOrder[i].Disabled || s.pruningOrder[i].Prune == nil {
@@ -280,6 +313,74 @@ func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool, quiet bool) error {
 	return nil
 }
 
+// markUnwind tells RPC readers that blocks above the unwind point are being replaced. Stages commit separately
+// without an external tx, so readers could mix data of the old and the new chain until the forward stages are done.
+// The whole cycle in one tx is committed at once and needs no marker.
+func (s *Sync) markUnwind(db kv.RwDB, tx kv.RwTx) error {
+	if tx != nil {
+		return nil
+	}
+	return db.Update(context.Background(), func(tx kv.RwTx) error {
+		unwindPoint, head, ok, err := rawdb.ReadUnwindInProgress(tx)
+		if err != nil {
+			return err
+		}
+		if ok { // previous unwind isn't synced up yet: keep its head, the lower unwind point wins
+			if unwindPoint > *s.unwindPoint {
+				unwindPoint = *s.unwindPoint
+			}
+			return rawdb.WriteUnwindInProgress(tx, unwindPoint, head)
+		}
+		if head, err = stages.GetStageProgress(tx, stages.Finish); err != nil {
+			return err
+		}
+		return rawdb.WriteUnwindInProgress(tx, *s.unwindPoint, head)
+	})
+}
+
+func (s *Sync) unmarkUnwind(db kv.RwDB, tx kv.RwTx) error {
+	if tx != nil {
+		return nil
+	}
+	var marked bool
+	if err := db.View(context.Background(), func(tx kv.Tx) (err error) {
+		_, _, marked, err = rawdb.ReadUnwindInProgress(tx)
+		return err
+	}); err != nil || !marked {
+		return err
+	}
+	return db.Update(context.Background(), func(tx kv.RwTx) error {
+		return rawdb.DeleteUnwindInProgress(tx)
+	})
+}
+
+// RepairUnwindMarker clears the marker of markUnwind left by a cycle which failed or was interrupted by a restart, if
+// all enabled stages are at the same block anyway: there is no mix of the old and the new chain. Otherwise the marker
+// stays until a cycle runs all stages. It's called on start of the stage loop and after a failed cycle.
+func (s *Sync) RepairUnwindMarker(db kv.RwDB) error {
+	return db.Update(context.Background(), func(tx kv.RwTx) error {
+		_, _, ok, err := rawdb.ReadUnwindInProgress(tx)
+		if err != nil || !ok {
+			return err
+		}
+		var progress *uint64
+		for _, stage := range s.stages {
+			if stage.Disabled || stage.Forward == nil {
+				continue
+			}
+			stageProgress, err := stages.GetStageProgress(tx, stage.ID)
+			if err != nil {
+				return err
+			}
+			if progress != nil && stageProgress != *progress {
+				return nil
+			}
+			progress = &stageProgress
+		}
+		return rawdb.DeleteUnwindInProgress(tx)
+	})
+}
+
 func (s *Sync) PrintTimings() []interface{} {
 	var logCtx []interface{}
 	count := 0
@@ -337,6 +438,7 @@ func PrintTables(db kv.RoDB, tx kv.RwTx) []interface{} {
 
 func (s *Sync) runStage(stage *Stage, db kv.RwDB, tx kv.RwTx, firstCycle bool, badBlockUnwind bool, quiet bool) (err error) {
 	start := time.Now()
+	s.watchdog.stageStarted(stage.ID, "forward")
 	stageState, err := s.StageState(stage.ID, tx, db)
 	if err != nil {
 		return err
@@ -362,6 +464,7 @@ func (s *Sync) runStage(stage *Stage, db kv.RwDB, tx kv.RwTx, firstCycle bool, b
 func (s *Sync) unwindStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx) error {
 	start := time.Now()
 	log.Trace("Unwind...", "stage", stage.ID)
+	s.watchdog.stageStarted(stage.ID, "unwind")
 	stageState, err := s.StageState(stage.ID, tx, db)
 	if err != nil {
 		return err
@@ -395,6 +498,7 @@ func (s *Sync) unwindStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx
 func (s *Sync) pruneStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx) error {
 	start := time.Now()
 	log.Trace("Prune...", "stage", stage.ID)
+	s.watchdog.stageStarted(stage.ID, "prune")
 
 	stageState, err := s.StageState(stage.ID, tx, db)
 	if err != nil {
@@ -448,6 +552,22 @@ func (s *Sync) DisableStages(ids ...stages.SyncStage) {
 	}
 }
 
+// SetMode disables stages the sync mode doesn't run and enables its head stage. Stages processing executed blocks
+// follow the head stage of the mode instead of Execution.
+func (s *Sync) SetMode(mode stages.Mode) {
+	s.mode = mode
+	s.DisableStages(mode.Disabled()...)
+	s.EnableStages(mode.Head())
+}
+
+// headStage - stage which progress the stages after it follow
+func (s *Sync) headStage() stages.SyncStage {
+	if s == nil {
+		return stages.Execution
+	}
+	return s.mode.Head()
+}
+
 func (s *Sync) EnableStages(ids ...stages.SyncStage) {
 	for i := range s.stages {
 		for _, id := range ids {
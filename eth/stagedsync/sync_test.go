@@ -1,6 +1,7 @@
 package stagedsync
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -8,8 +9,10 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStagesSuccess(t *testing.T) {
@@ -613,6 +616,196 @@ func TestSyncInterruptLongUnwind(t *testing.T) {
 	assert.Equal(t, 500, int(stageState.BlockNumber))
 }
 
+func TestUnwindInProgressMarker(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		if err := stages.SaveStageProgress(tx, stages.Headers, 1000); err != nil {
+			return err
+		}
+		return stages.SaveStageProgress(tx, stages.Finish, 1000)
+	}))
+	readMarker := func() (unwindPoint, head uint64, ok bool) {
+		require.NoError(t, db.View(context.Background(), func(tx kv.Tx) (err error) {
+			unwindPoint, head, ok, err = rawdb.ReadUnwindInProgress(tx)
+			return err
+		}))
+		return unwindPoint, head, ok
+	}
+
+	unwound := false
+	s := []*Stage{
+		{
+			ID:          stages.Headers,
+			Description: "Downloading headers",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if !unwound {
+					_, _, ok := readMarker()
+					require.False(t, ok)
+					u.UnwindTo(500, common.Hash{})
+				}
+				return nil
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				unwound = true
+				unwindPoint, head, ok := readMarker()
+				require.True(t, ok)
+				require.Equal(t, uint64(500), unwindPoint)
+				require.Equal(t, uint64(1000), head)
+				return nil
+			},
+		},
+		{
+			ID:          stages.Bodies,
+			Description: "Downloading block bodies",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if unwound { // forward stages of the new chain are still running
+					_, _, ok := readMarker()
+					require.True(t, ok)
+				}
+				return nil
+			},
+		},
+	}
+	state := New(s, []stages.SyncStage{s[1].ID, s[0].ID}, nil)
+	require.NoError(t, state.Run(db, nil, false /* initialCycle */, false /* quiet */))
+	require.True(t, unwound)
+	_, _, ok := readMarker()
+	require.False(t, ok)
+}
+
+func TestRepairUnwindMarker(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	saveProgress := func(headers, bodies uint64) {
+		require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+			if err := stages.SaveStageProgress(tx, stages.Headers, headers); err != nil {
+				return err
+			}
+			return stages.SaveStageProgress(tx, stages.Bodies, bodies)
+		}))
+	}
+	marked := func() bool {
+		var ok bool
+		require.NoError(t, db.View(context.Background(), func(tx kv.Tx) (err error) {
+			_, _, ok, err = rawdb.ReadUnwindInProgress(tx)
+			return err
+		}))
+		return ok
+	}
+	// a cycle unwound to 900 and failed (or the node restarted) while bodies of the new chain were downloaded
+	saveProgress(1000, 950)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return rawdb.WriteUnwindInProgress(tx, 900, 1000)
+	}))
+
+	fail := errors.New("bodies failed")
+	s := []*Stage{
+		{
+			ID:          stages.Headers,
+			Description: "Downloading headers",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				return nil
+			},
+		},
+		{
+			ID:          stages.Bodies,
+			Description: "Downloading block bodies",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				return fail
+			},
+		},
+	}
+	state := New(s, []stages.SyncStage{s[1].ID, s[0].ID}, nil)
+	require.NoError(t, state.RepairUnwindMarker(db))
+	require.True(t, marked(), "stages are not synced up")
+	require.ErrorIs(t, state.Run(db, nil, false /* initialCycle */, false /* quiet */), fail)
+	require.True(t, marked())
+
+	// the failed cycle got the stages synced up before it failed
+	saveProgress(1000, 1000)
+	require.ErrorIs(t, state.Run(db, nil, false /* initialCycle */, false /* quiet */), fail)
+	require.False(t, marked())
+
+	// a complete cycle clears the marker even if it didn't unwind itself
+	saveProgress(1000, 950)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return rawdb.WriteUnwindInProgress(tx, 900, 1000)
+	}))
+	s[1].Forward = func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+		return nil
+	}
+	require.NoError(t, state.Run(db, nil, false /* initialCycle */, false /* quiet */))
+	require.False(t, marked())
+}
+
+func TestSyncModeBlocks(t *testing.T) {
+	flow := make([]stages.SyncStage, 0)
+	var txLookupAt uint64
+	s := []*Stage{
+		{
+			ID:          stages.Senders,
+			Description: "Recovering senders from tx signatures",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				flow = append(flow, stages.Senders)
+				return s.Update(tx, 100)
+			},
+		},
+		{
+			ID:          stages.Execution,
+			Description: "Executing blocks w/o hash checks",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				flow = append(flow, stages.Execution)
+				return nil
+			},
+		},
+		{
+			ID:          stages.TxLookup,
+			Description: "Generating transactions lookup index",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) (err error) {
+				flow = append(flow, stages.TxLookup)
+				txLookupAt, err = s.ExecutionAt(tx)
+				return err
+			},
+		},
+	}
+	state := New(s, []stages.SyncStage{s[2].ID, s[1].ID, s[0].ID}, nil)
+	state.SetMode(stages.ModeBlocks)
+	db, tx := memdb.NewTestTx(t)
+	require.NoError(t, state.Run(db, tx, true /* initialCycle */, false /* quiet */))
+	require.Equal(t, []stages.SyncStage{stages.Senders, stages.TxLookup}, flow)
+	require.Equal(t, uint64(100), txLookupAt) // follows senders instead of execution
+}
+
+func TestSyncModeExternalStageDisabledByDefault(t *testing.T) {
+	flow := make([]stages.SyncStage, 0)
+	s := []*Stage{
+		{
+			ID:          stages.Execution,
+			Description: "Executing blocks w/o hash checks",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				flow = append(flow, stages.Execution)
+				return nil
+			},
+		},
+		{
+			ID:          stages.ExternalExecution,
+			Description: "Execute blocks by the external executor",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				flow = append(flow, stages.ExternalExecution)
+				return nil
+			},
+		},
+	}
+	state := New(s, []stages.SyncStage{s[1].ID, s[0].ID}, nil)
+	db, tx := memdb.NewTestTx(t)
+	require.NoError(t, state.Run(db, tx, true /* initialCycle */, false /* quiet */))
+	require.Equal(t, []stages.SyncStage{stages.Execution}, flow)
+
+	flow = flow[:0]
+	state.SetMode(stages.ModeExternal)
+	require.NoError(t, state.Run(db, tx, true /* initialCycle */, false /* quiet */))
+	require.Equal(t, []stages.SyncStage{stages.ExternalExecution}, flow)
+}
+
 func unwindOf(s stages.SyncStage) stages.SyncStage {
 	return stages.SyncStage(append([]byte(s), 0xF0))
 }
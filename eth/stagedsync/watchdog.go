@@ -0,0 +1,216 @@
+package stagedsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// ErrWatchdogRestart - the cycle was interrupted because a stage made no progress, the stage loop starts
+// the next cycle as after any other error
+var ErrWatchdogRestart = errors.New("stage made no progress, restarting the stage loop")
+
+var stuckStages = metrics.GetOrCreateCounter(`sync_stuck_stages`)
+
+// DiagnosticsSection - part of the diagnostics dump provided by other components (peers, database, etc.)
+type DiagnosticsSection struct {
+	Name  string
+	Write func(w io.Writer) error
+}
+
+type WatchdogCfg struct {
+	Timeout  time.Duration // How long a stage may make no progress, 0 - disabled
+	Restart  bool          // Interrupt the cycle after the dump
+	Dir      string        // Where dumps are written
+	Sections []DiagnosticsSection
+}
+
+// Watchdog notices stages which make no progress for cfg.Timeout. Start of a stage and saving of its progress
+// (StageState.Update) are progress. Once per stuck stage it dumps goroutine stacks, age of the cycle transaction
+// and cfg.Sections into a file in cfg.Dir, then interrupts the cycle if cfg.Restart. Headers stage waiting for
+// peers is interrupted right away, other stages - when they return.
+type Watchdog struct {
+	cfg WatchdogCfg
+
+	lock         sync.Mutex
+	stage        stages.SyncStage // empty between cycles
+	action       string           // forward, unwind or prune
+	lastProgress time.Time
+	cycleStart   time.Time // zero if stages use own transactions
+	dumped       bool
+	restart      chan struct{} // closed when the cycle has to be interrupted
+	restarting   bool
+}
+
+func NewWatchdog(cfg WatchdogCfg) *Watchdog {
+	return &Watchdog{cfg: cfg, restart: make(chan struct{})}
+}
+
+// SetWatchdog is to be called before the stage loop starts
+func (s *Sync) SetWatchdog(w *Watchdog) { s.watchdog = w }
+
+// Methods called by Sync and stages are no-op on nil Watchdog
+
+func (w *Watchdog) cycleStarted(externalTx bool) {
+	if w == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.cycleStart = time.Time{}
+	if externalTx {
+		w.cycleStart = time.Now()
+	}
+	if w.restarting { // requested after the last check of the previous cycle
+		w.restart, w.restarting = make(chan struct{}), false
+	}
+}
+
+func (w *Watchdog) cycleDone() {
+	if w == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.stage = ""
+}
+
+func (w *Watchdog) stageStarted(id stages.SyncStage, action string) {
+	if w == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.stage, w.action = id, action
+	w.lastProgress, w.dumped = time.Now(), false
+}
+
+func (w *Watchdog) progress() {
+	if w == nil {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.lastProgress, w.dumped = time.Now(), false
+}
+
+// restartCh - closed when the cycle has to be interrupted, stages waiting in loops select on it
+func (w *Watchdog) restartCh() <-chan struct{} {
+	if w == nil {
+		return nil
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.restart
+}
+
+// takeRestart returns true once per restart request
+func (w *Watchdog) takeRestart() bool {
+	if w == nil {
+		return false
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.restarting {
+		return false
+	}
+	w.restart, w.restarting = make(chan struct{}), false
+	return true
+}
+
+// Loop checks progress until ctx is done
+func (w *Watchdog) Loop(ctx context.Context) {
+	if w.cfg.Timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.cfg.Timeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// stuckStage - state of the watchdog at the check, written to the dump without the lock held
+type stuckStage struct {
+	stage        stages.SyncStage
+	action       string
+	lastProgress time.Time
+	cycleStart   time.Time
+}
+
+func (w *Watchdog) check(now time.Time) {
+	w.lock.Lock()
+	if w.stage == "" || w.dumped || now.Sub(w.lastProgress) < w.cfg.Timeout {
+		w.lock.Unlock()
+		return
+	}
+	w.dumped = true
+	stuck := stuckStage{stage: w.stage, action: w.action, lastProgress: w.lastProgress, cycleStart: w.cycleStart}
+	w.lock.Unlock()
+
+	// sections may take seconds (peers, database), stages must not wait for the lock meanwhile
+	stuckStages.Inc()
+	path, err := w.dump(now, stuck)
+	if err != nil {
+		log.Warn(fmt.Sprintf("[%s] No progress, failed to write diagnostics", stuck.stage), "action", stuck.action, "for", now.Sub(stuck.lastProgress), "err", err)
+	} else {
+		log.Warn(fmt.Sprintf("[%s] No progress, diagnostics written", stuck.stage), "action", stuck.action, "for", now.Sub(stuck.lastProgress), "file", path)
+	}
+	if !w.cfg.Restart {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	// the stage made progress or the cycle ended during the dump
+	if w.stage != stuck.stage || !w.dumped || w.restarting {
+		return
+	}
+	log.Warn(fmt.Sprintf("[%s] Restarting the stage loop", stuck.stage))
+	close(w.restart)
+	w.restarting = true
+}
+
+func (w *Watchdog) dump(now time.Time, stuck stuckStage) (string, error) {
+	if err := os.MkdirAll(w.cfg.Dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(w.cfg.Dir, fmt.Sprintf("stuck-%s-%s.txt", stuck.stage, now.Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "Stage %s (%s) made no progress for %s, at %s\n", stuck.stage, stuck.action, now.Sub(stuck.lastProgress), now.Format(time.RFC3339))
+	if stuck.cycleStart.IsZero() {
+		fmt.Fprintf(f, "Cycle transaction: none, stages commit own transactions\n")
+	} else {
+		fmt.Fprintf(f, "Cycle transaction age: %s\n", now.Sub(stuck.cycleStart))
+	}
+	for _, section := range w.cfg.Sections {
+		fmt.Fprintf(f, "\n== %s ==\n", section.Name)
+		if err := section.Write(f); err != nil {
+			fmt.Fprintf(f, "error: %v\n", err)
+		}
+	}
+	fmt.Fprintf(f, "\n== goroutines ==\n")
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		return "", err
+	}
+	return path, f.Close()
+}
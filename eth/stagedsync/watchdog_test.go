@@ -0,0 +1,87 @@
+package stagedsync
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdog(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+	w := NewWatchdog(WatchdogCfg{Timeout: time.Minute, Restart: true, Dir: dir, Sections: []DiagnosticsSection{
+		{Name: "peers", Write: func(w io.Writer) error { _, err := io.WriteString(w, "3 peers\n"); return err }},
+	}})
+	var flow []stages.SyncStage
+	s := New([]*Stage{
+		{
+			ID: stages.Headers,
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				flow = append(flow, stages.Headers)
+				if len(flow) > 1 {
+					return nil
+				}
+				w.check(time.Now().Add(30 * time.Second)) // not stuck yet
+				require.NoError(s.Update(tx, 1))
+				w.check(time.Now().Add(2 * time.Minute))
+				select {
+				case <-s.watchdog().restartCh():
+				default:
+					t.Fatal("restart is not requested")
+				}
+				w.check(time.Now().Add(3 * time.Minute)) // dumped once per stuck stage
+				return nil
+			},
+		},
+		{
+			ID: stages.Bodies,
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				flow = append(flow, stages.Bodies)
+				return nil
+			},
+		},
+	}, nil, nil)
+	s.SetWatchdog(w)
+	db, tx := memdb.NewTestTx(t)
+
+	require.ErrorIs(s.Run(db, tx, true /* initialCycle */, false /* quiet */), ErrWatchdogRestart)
+	require.Equal([]stages.SyncStage{stages.Headers}, flow)
+	files, err := filepath.Glob(filepath.Join(dir, "stuck-Headers-*.txt"))
+	require.NoError(err)
+	require.Len(files, 1)
+	dump, err := os.ReadFile(files[0])
+	require.NoError(err)
+	require.Contains(string(dump), "Stage Headers (forward) made no progress")
+	require.Contains(string(dump), "Cycle transaction age")
+	require.Contains(string(dump), "== peers ==\n3 peers\n")
+	require.Contains(string(dump), "== goroutines ==")
+
+	// next cycle runs normally, nothing is checked between cycles
+	require.NoError(s.Run(db, tx, false /* initialCycle */, false /* quiet */))
+	require.Equal([]stages.SyncStage{stages.Headers, stages.Headers, stages.Bodies}, flow)
+	w.check(time.Now().Add(time.Hour))
+	files, err = filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(err)
+	require.Len(files, 1)
+}
+
+func TestWatchdogDumpWithoutLock(t *testing.T) {
+	require := require.New(t)
+	var w *Watchdog
+	// the section reports progress, it would deadlock if the lock was held during the dump
+	w = NewWatchdog(WatchdogCfg{Timeout: time.Minute, Restart: true, Dir: t.TempDir(), Sections: []DiagnosticsSection{
+		{Name: "progress", Write: func(io.Writer) error { w.progress(); return nil }},
+	}})
+	w.cycleStarted(false)
+	w.stageStarted(stages.Execution, "forward")
+	w.check(time.Now().Add(2 * time.Minute))
+	// the stage progressed during the dump, the cycle isn't interrupted
+	require.False(w.takeRestart())
+}
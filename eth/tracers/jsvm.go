@@ -18,6 +18,12 @@ func JSVMNew() *JSVM {
 	}
 }
 
+// Interrupt aborts the running script (and the next one, if none is running) with the given reason.
+// It's safe to call from another goroutine.
+func (vm *JSVM) Interrupt(v interface{}) {
+	vm.vm.Interrupt(v)
+}
+
 func (vm *JSVM) Pop() {
 	vm.stack = vm.stack[:len(vm.stack)-1]
 }
@@ -104,6 +110,9 @@ func (vm *JSVM) GetBuffer(index int) (rawPtr unsafe.Pointer, outSize uint) {
 func (vm *JSVM) GetPropString(objIndex int, key string) bool {
 	obj := vm.stack[objIndex].ToObject(vm.vm)
 	v := obj.Get(key)
+	if v == nil { // missing property
+		v = goja.Undefined()
+	}
 	vm.stack = append(vm.stack, v)
 	return !goja.IsUndefined(v)
 }
@@ -0,0 +1,39 @@
+package tracers
+
+import (
+	"encoding/json"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/vm"
+)
+
+// ResultTracer is a tracer selected by name or JavaScript code in TraceConfig,
+// its result is returned by the debug_trace* methods
+type ResultTracer interface {
+	vm.Tracer
+	// GetResult returns the result of the tracing, or the error which stopped it
+	GetResult() (json.RawMessage, error)
+	// Stop interrupts the tracing, GetResult returns err then
+	Stop(err error)
+}
+
+// TxStartTracer is implemented by tracers which read the state before the transaction:
+// CaptureTxStart is called before the gas is bought and the nonce of the sender is incremented
+type TxStartTracer interface {
+	CaptureTxStart(ibs vm.IntraBlockState, from common.Address, to *common.Address, coinbase common.Address)
+}
+
+// native contains the tracers implemented in Go by name, they replace the JavaScript tracers of the same name.
+// The replaced JavaScript tracers stay available with the "Legacy" suffix.
+var native = map[string]func(ctx *Context, cfg json.RawMessage) (ResultTracer, error){
+	"prestateTracer": newPrestateTracer,
+}
+
+// NewTracer returns the native tracer of the given name, or the JavaScript tracer
+// of the given name or code, see New. cfg is the tracer specific configuration.
+func NewTracer(code string, ctx *Context, cfg json.RawMessage) (ResultTracer, error) {
+	if constructor, ok := native[code]; ok {
+		return constructor(ctx, cfg)
+	}
+	return New(code, ctx, cfg)
+}
@@ -0,0 +1,240 @@
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+)
+
+// prestateAccount - an account as the prestateTracer outputs it
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+func (a *prestateAccount) exists() bool {
+	return a.Nonce > 0 || len(a.Code) > 0 || a.Balance.ToInt().Sign() != 0
+}
+
+type prestateConfig struct {
+	DiffMode bool `json:"diffMode"` // output the state before and after the transaction, only the changed part of it
+}
+
+// prestateTracer outputs the accounts and the storage touched by the transaction as they were before it,
+// enough to re-execute the transaction on top of them. In diffMode it outputs the changed accounts before
+// and after the transaction, see prestateTracer.diff.
+type prestateTracer struct {
+	cfg prestateConfig
+	ibs vm.IntraBlockState
+
+	pre       map[common.Address]*prestateAccount
+	txStarted bool
+	create    bool           // the transaction creates a contract
+	to        common.Address // the recipient or the created contract
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+func newPrestateTracer(_ *Context, cfg json.RawMessage) (ResultTracer, error) {
+	t := &prestateTracer{pre: map[common.Address]*prestateAccount{}}
+	if len(cfg) > 0 {
+		if err := json.Unmarshal(cfg, &t.cfg); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// lookupAccount adds the account to the prestate at its first touch
+func (t *prestateTracer) lookupAccount(addr common.Address) *prestateAccount {
+	if acc, ok := t.pre[addr]; ok {
+		return acc
+	}
+	acc := &prestateAccount{
+		Balance: (*hexutil.Big)(t.ibs.GetBalance(addr).ToBig()),
+		Nonce:   t.ibs.GetNonce(addr),
+		Code:    common.CopyBytes(t.ibs.GetCode(addr)),
+		Storage: map[common.Hash]common.Hash{},
+	}
+	t.pre[addr] = acc
+	return acc
+}
+
+// lookupStorage adds the storage slot to the prestate at its first touch, the committed value is the one before the transaction
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	acc := t.lookupAccount(addr)
+	if _, ok := acc.Storage[key]; ok {
+		return
+	}
+	var value uint256.Int
+	t.ibs.GetCommittedState(addr, &key, &value)
+	acc.Storage[key] = value.Bytes32()
+}
+
+func (t *prestateTracer) CaptureTxStart(ibs vm.IntraBlockState, from common.Address, to *common.Address, coinbase common.Address) {
+	t.ibs = ibs
+	t.txStarted = true
+	t.lookupAccount(from)
+	if to != nil {
+		t.lookupAccount(*to)
+	}
+	t.lookupAccount(coinbase)
+}
+
+func (t *prestateTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, calltype vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	if depth != 0 {
+		return
+	}
+	t.create, t.to = create, to
+	if !t.txStarted {
+		// The gas is bought already and the nonce of the sender is incremented for a call, they're restored here
+		t.ibs = env.IntraBlockState()
+		acc := t.lookupAccount(from)
+		if !create && acc.Nonce > 0 {
+			acc.Nonce--
+		}
+		rules := env.ChainConfig().Rules(env.Context().BlockNumber)
+		if intrinsicGas, err := core.IntrinsicGas(input, nil, create, rules.IsHomestead, rules.IsIstanbul); err == nil {
+			spent := new(big.Int).Mul(env.TxContext().GasPrice, new(big.Int).SetUint64(gas+intrinsicGas))
+			acc.Balance = (*hexutil.Big)(spent.Add(spent, acc.Balance.ToInt()))
+		}
+	}
+	t.lookupAccount(to)
+}
+
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	stack, caller := scope.Stack, scope.Contract.Address()
+	switch {
+	case (op == vm.SLOAD || op == vm.SSTORE) && stack.Len() >= 1:
+		t.lookupStorage(caller, common.Hash(stack.Back(0).Bytes32()))
+	case (op == vm.EXTCODECOPY || op == vm.EXTCODESIZE || op == vm.EXTCODEHASH || op == vm.BALANCE || op == vm.SELFDESTRUCT) && stack.Len() >= 1:
+		t.lookupAccount(common.Address(stack.Back(0).Bytes20()))
+	case (op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL) && stack.Len() >= 2:
+		t.lookupAccount(common.Address(stack.Back(1).Bytes20()))
+	case op == vm.CREATE:
+		t.lookupAccount(crypto.CreateAddress(caller, t.ibs.GetNonce(caller)))
+	case op == vm.CREATE2 && stack.Len() >= 4:
+		// stack: endowment, offset, size, salt
+		offset, size := stack.Back(1), stack.Back(2)
+		if !offset.IsUint64() || !size.IsUint64() || offset.Uint64()+size.Uint64() > uint64(scope.Memory.Len()) {
+			return
+		}
+		initCode := scope.Memory.GetPtr(offset.Uint64(), size.Uint64())
+		t.lookupAccount(crypto.CreateAddress2(caller, stack.Back(3).Bytes32(), crypto.Keccak256(initCode)))
+	}
+}
+
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *prestateTracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, d time.Duration, err error) {
+}
+
+func (t *prestateTracer) CaptureSelfDestruct(from, to common.Address, value *big.Int) {
+}
+
+func (t *prestateTracer) CaptureAccountRead(account common.Address) error {
+	return nil
+}
+
+func (t *prestateTracer) CaptureAccountWrite(account common.Address) error {
+	return nil
+}
+
+// GetResult must be called after the transaction is executed, before it's finalized
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return nil, t.reason
+	}
+	if !t.cfg.DiffMode {
+		// the created contract didn't exist before the transaction, otherwise it would fail
+		if acc, ok := t.pre[t.to]; ok && t.create && !acc.exists() && len(acc.Storage) == 0 {
+			delete(t.pre, t.to)
+		}
+		return json.Marshal(t.pre)
+	}
+	pre, post := t.diff()
+	return json.Marshal(struct {
+		Pre  map[common.Address]*prestateAccount `json:"pre"`
+		Post map[common.Address]*prestateAccount `json:"post"`
+	}{pre, post})
+}
+
+// diff returns the changed accounts before and after the transaction: the fields changed by it in post, and the whole
+// accounts, with the changed storage only, in pre. An account which didn't exist before the transaction is only in post,
+// a self-destructed account is only in pre. Zero storage slots are omitted.
+func (t *prestateTracer) diff() (pre, post map[common.Address]*prestateAccount) {
+	pre, post = map[common.Address]*prestateAccount{}, map[common.Address]*prestateAccount{}
+	for addr, before := range t.pre {
+		if t.ibs.HasSuicided(addr) {
+			if before.exists() || len(before.Storage) > 0 {
+				pre[addr] = withoutZeroSlots(before, before.Storage)
+			}
+			continue
+		}
+		after := &prestateAccount{Storage: map[common.Hash]common.Hash{}}
+		changed := false
+		if balance := t.ibs.GetBalance(addr).ToBig(); balance.Cmp(before.Balance.ToInt()) != 0 {
+			after.Balance, changed = (*hexutil.Big)(balance), true
+		}
+		if nonce := t.ibs.GetNonce(addr); nonce != before.Nonce {
+			after.Nonce, changed = nonce, true
+		}
+		if code := t.ibs.GetCode(addr); !bytes.Equal(code, before.Code) {
+			after.Code, changed = common.CopyBytes(code), true
+		}
+		changedSlots := map[common.Hash]common.Hash{}
+		for key, value := range before.Storage {
+			key := key
+			var current uint256.Int
+			t.ibs.GetState(addr, &key, &current)
+			if common.Hash(current.Bytes32()) == value {
+				continue
+			}
+			changedSlots[key], changed = value, true
+			if !current.IsZero() {
+				after.Storage[key] = current.Bytes32()
+			}
+		}
+		if !changed {
+			continue
+		}
+		if before.exists() || len(changedSlots) > 0 {
+			pre[addr] = withoutZeroSlots(before, changedSlots)
+		}
+		post[addr] = withoutZeroSlots(after, after.Storage)
+	}
+	return pre, post
+}
+
+func withoutZeroSlots(acc *prestateAccount, storage map[common.Hash]common.Hash) *prestateAccount {
+	res := *acc
+	res.Storage = make(map[common.Hash]common.Hash, len(storage))
+	for key, value := range storage {
+		if value != (common.Hash{}) {
+			res.Storage[key] = value
+		}
+	}
+	return &res
+}
+
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
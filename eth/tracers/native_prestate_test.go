@@ -0,0 +1,94 @@
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/stretchr/testify/require"
+)
+
+func runPrestateTracer(t *testing.T, cfg string) map[common.Address]*prestateAccount {
+	t.Helper()
+	sender, contract, coinbase := common.Address{1}, common.Address{2}, common.Address{3}
+	slot0, slot1 := common.Hash{}, common.BigToHash(big.NewInt(1))
+
+	_, tx := memdb.NewTestTx(t)
+	ibs := state.New(state.NewPlainStateReader(tx))
+	ibs.SetBalance(sender, uint256.NewInt(1_000_000))
+	ibs.SetNonce(sender, 1)
+	// SSTORE(0, 2) SLOAD(1)
+	ibs.SetCode(contract, hexutil.MustDecode("0x600260005560015400"))
+	ibs.SetState(contract, &slot0, *uint256.NewInt(1))
+	ibs.SetState(contract, &slot1, *uint256.NewInt(5))
+	require.NoError(t, ibs.FinalizeTx(&params.Rules{}, state.NewNoopWriter()))
+
+	tracer, err := NewTracer("prestateTracer", new(Context), json.RawMessage(cfg))
+	require.NoError(t, err)
+	blockCtx := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    coinbase,
+		BlockNumber: 8000000,
+		GasLimit:    1_000_000,
+	}
+	evm := vm.NewEVM(blockCtx, vm.TxContext{Origin: sender, GasPrice: big.NewInt(1)}, ibs, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	msg := types.NewMessage(sender, &contract, 1, uint256.NewInt(10), 100_000, uint256.NewInt(1), nil, nil, nil, nil, true)
+	tracer.(TxStartTracer).CaptureTxStart(ibs, sender, &contract, coinbase)
+	_, err = core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.Gas())).TransitionDb(true, false)
+	require.NoError(t, err)
+
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+	if cfg == "" {
+		result := map[common.Address]*prestateAccount{}
+		require.NoError(t, json.Unmarshal(res, &result))
+		return result
+	}
+	var result struct {
+		Pre, Post map[common.Address]*prestateAccount
+	}
+	require.NoError(t, json.Unmarshal(res, &result))
+	for addr, acc := range result.Post {
+		result.Pre[common.Address{0xff, addr[0]}] = acc // post accounts by a distinct key
+	}
+	return result.Pre
+}
+
+func TestPrestateTracer(t *testing.T) {
+	sender, contract, coinbase := common.Address{1}, common.Address{2}, common.Address{3}
+	slot0, slot1 := common.Hash{}, common.BigToHash(big.NewInt(1))
+	balance := func(acc *prestateAccount) uint64 { return acc.Balance.ToInt().Uint64() }
+
+	pre := runPrestateTracer(t, "")
+	require.Len(t, pre, 3)
+	require.Equal(t, uint64(1_000_000), balance(pre[sender]))
+	require.Equal(t, uint64(1), pre[sender].Nonce)
+	require.Equal(t, hexutil.Bytes(hexutil.MustDecode("0x600260005560015400")), pre[contract].Code)
+	require.Equal(t, map[common.Hash]common.Hash{slot0: common.BigToHash(big.NewInt(1)), slot1: common.BigToHash(big.NewInt(5))}, pre[contract].Storage)
+	require.Equal(t, uint64(0), balance(pre[coinbase]))
+
+	diff := runPrestateTracer(t, `{"diffMode": true}`)
+	postSender, postContract, postCoinbase := common.Address{0xff, 1}, common.Address{0xff, 2}, common.Address{0xff, 3}
+	require.Len(t, diff, 5, "the coinbase didn't exist before")
+	gasUsed := 1_000_000 - 10 - balance(diff[postSender])
+	require.Equal(t, gasUsed, balance(diff[postCoinbase]))
+	require.Equal(t, uint64(2), diff[postSender].Nonce)
+	require.Equal(t, map[common.Hash]common.Hash{slot0: common.BigToHash(big.NewInt(1))}, diff[contract].Storage, "only changed slots")
+	require.Equal(t, uint64(0), balance(diff[contract]))
+	require.Equal(t, map[common.Hash]common.Hash{slot0: common.BigToHash(big.NewInt(2))}, diff[postContract].Storage)
+	require.Equal(t, uint64(10), balance(diff[postContract]))
+	require.Empty(t, diff[postContract].Code, "unchanged")
+
+	_, err := NewTracer("prestateTracer", new(Context), json.RawMessage("{"))
+	require.Error(t, err)
+}
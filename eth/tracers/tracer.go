@@ -330,8 +330,9 @@ type Context struct {
 
 // New instantiates a new tracer instance. code specifies a Javascript snippet,
 // which must evaluate to an expression returning an object with 'step', 'fault'
-// and 'result' functions.
-func New(code string, ctx *Context) (*Tracer, error) {
+// and 'result' functions. If the object has a 'setup' function, it's called with
+// the JSON encoded cfg ("{}" if it's empty) before the tracing.
+func New(code string, ctx *Context, cfg json.RawMessage) (*Tracer, error) {
 	// Resolve any tracers by name and assemble the tracer object
 	if tracer, ok := tracer(code); ok {
 		code = tracer
@@ -486,6 +487,21 @@ func New(code string, ctx *Context) (*Tracer, error) {
 	tracer.vm.EvalString(bigIntegerJS)
 	tracer.vm.PutGlobalString("bigInt")
 
+	if tracer.vm.GetPropString(tracer.tracerObject, "setup") {
+		tracer.vm.Pop()
+		if len(cfg) == 0 {
+			cfg = json.RawMessage("{}")
+		}
+		tracer.vm.PushString("setup")
+		tracer.vm.PushString(string(cfg))
+		if tracer.vm.PcallProp(tracer.tracerObject, 1) != 0 {
+			return nil, wrapError("setup", errors.New(tracer.vm.SafeToString(-1)))
+		}
+		tracer.vm.Pop()
+	} else {
+		tracer.vm.Pop()
+	}
+
 	// Push the global environment state as object #1 into the JSVM stack
 	tracer.stateObject = tracer.vm.PushObject()
 
@@ -536,10 +552,12 @@ func New(code string, ctx *Context) (*Tracer, error) {
 	return tracer, nil
 }
 
-// Stop terminates execution of the tracer at the first opportune moment.
+// Stop terminates execution of the tracer at the first opportune moment,
+// a running JavaScript function is aborted.
 func (jst *Tracer) Stop(err error) {
 	jst.reason = err
 	atomic.StoreUint32(&jst.interrupt, 1)
+	jst.vm.Interrupt(err)
 }
 
 // call executes a method on a JS object, catching any errors, formatting and
@@ -554,6 +572,9 @@ func (jst *Tracer) call(noret bool, method string, args ...string) (json.RawMess
 	defer jst.vm.Pop()
 
 	if code != 0 {
+		if atomic.LoadUint32(&jst.interrupt) > 0 {
+			return nil, jst.reason
+		}
 		err := jst.vm.SafeToString(-1)
 		return nil, errors.New(err)
 	}
@@ -680,6 +701,13 @@ func (jst *Tracer) CaptureAccountWrite(account common.Address) error {
 
 // GetResult calls the Javascript 'result' function and returns its value, or any accumulated error
 func (jst *Tracer) GetResult() (json.RawMessage, error) {
+	// The interrupted JSVM doesn't run 'result' anymore
+	if atomic.LoadUint32(&jst.interrupt) > 0 {
+		if jst.err == nil {
+			jst.err = jst.reason
+		}
+		return nil, jst.err
+	}
 	// Transform the context into a JavaScript object and inject into the state
 	obj := jst.vm.PushObject()
 
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
@@ -86,7 +87,7 @@ func TestTracer(t *testing.T) {
 		ctx := &vmContext{blockCtx: vm.BlockContext{
 			BlockNumber: 1,
 		}, txCtx: vm.TxContext{GasPrice: big.NewInt(100000)}}
-		tracer, err := New(code, new(Context))
+		tracer, err := New(code, new(Context), nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -131,11 +132,9 @@ func TestTracer(t *testing.T) {
 }
 
 func TestHalt(t *testing.T) {
-	t.Skip("duktape doesn't support abortion")
-
 	timeout := errors.New("stahp")
 	vmctx := testCtx()
-	tracer, err := New("{step: function() { while(1); }, result: function() { return null; }}", new(Context))
+	tracer, err := New("{step: function() { while(1); }, fault: function() {}, result: function() { return null; }}", new(Context), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -151,7 +150,7 @@ func TestHalt(t *testing.T) {
 }
 
 func TestHaltBetweenSteps(t *testing.T) {
-	tracer, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }}", new(Context))
+	tracer, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }}", new(Context), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -170,6 +169,30 @@ func TestHaltBetweenSteps(t *testing.T) {
 	}
 }
 
+func TestSetup(t *testing.T) {
+	code := "{steps: 0, setup: function(cfg) { this.cfg = JSON.parse(cfg); }, step: function() { this.steps++; }, fault: function() {}, result: function() { return this.cfg.limit ? Math.min(this.steps, this.cfg.limit) : this.steps; }}"
+	for i, tt := range []struct {
+		cfg  string
+		want string
+	}{
+		{cfg: "", want: "3"},
+		{cfg: `{"limit": 2}`, want: "2"},
+	} {
+		tracer, err := New(code, new(Context), json.RawMessage(tt.cfg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, err := runTrace(tracer, testCtx()); err != nil || string(have) != tt.want {
+			t.Errorf("testcase %d: expected '%s' got '%s', error %v", i, tt.want, string(have), err)
+		}
+	}
+
+	_, err := New("{setup: function(cfg) { throw 'bad config'; }, step: function() {}, fault: function() {}, result: function() {}}", new(Context), nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "bad config") || !strings.HasSuffix(err.Error(), "in server-side tracer function 'setup'") {
+		t.Errorf("Expected setup error, got %v", err)
+	}
+}
+
 // TestNoStepExec tests a regular value transfer (no exec), and accessing the statedb
 // in 'result'
 func TestNoStepExec(t *testing.T) {
@@ -184,7 +207,7 @@ func TestNoStepExec(t *testing.T) {
 	execTracer := func(code string) []byte {
 		t.Helper()
 		ctx := &vmContext{blockCtx: vm.BlockContext{BlockNumber: 1}, txCtx: vm.TxContext{GasPrice: big.NewInt(100000)}}
-		tracer, err := New(code, new(Context))
+		tracer, err := New(code, new(Context), nil)
 		if err != nil {
 			t.Fatal(err)
 		}
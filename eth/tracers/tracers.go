@@ -40,6 +40,9 @@ func camel(str string) string {
 func init() {
 	for _, file := range tracers.AssetNames() {
 		name := camel(strings.TrimSuffix(file, ".js"))
+		if _, ok := native[name]; ok {
+			name += "Legacy"
+		}
 		all[name] = string(tracers.MustAsset(file))
 	}
 }
@@ -148,6 +148,65 @@ func TruncateRange(db kv.RwTx, bucket string, key []byte, to uint32) error {
 	})
 }
 
+// MergeRange - adds bm to the existing bitmap in db. Unlike loaders of the index stages, which merge new values
+// into the hot shard, bm may be in the middle of the existing bitmap: shards overlapping with
+// [bm.Minimum(), bm.Maximum()] are merged with it and rewritten, other shards are not touched.
+func MergeRange(db kv.RwTx, bucket string, key []byte, bm *roaring.Bitmap) error {
+	if bm.IsEmpty() {
+		return nil
+	}
+	fromKey := make([]byte, len(key)+4)
+	copy(fromKey, key)
+	binary.BigEndian.PutUint32(fromKey[len(fromKey)-4:], bm.Minimum())
+	c, err := db.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	merged := bm.Clone()
+	hot := true // the last overlapping shard is the hot one, or there are no shards after bm
+	var shards [][]byte
+	for k, v, err := c.Seek(fromKey); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, key) {
+			break
+		}
+		shard := roaring.New()
+		if _, err := shard.ReadFrom(bytes.NewReader(v)); err != nil {
+			return err
+		}
+		merged.Or(shard)
+		shards = append(shards, libcommon.Copy(k))
+		if shardMax := binary.BigEndian.Uint32(k[len(k)-4:]); shardMax >= bm.Maximum() {
+			hot = shardMax == ^uint32(0)
+			break
+		}
+	}
+	for _, k := range shards {
+		if err := db.Delete(bucket, k); err != nil {
+			return err
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	return WalkChunks(merged, ChunkLimit, func(chunk *roaring.Bitmap, isLast bool) error {
+		chunkKey := make([]byte, len(key)+4)
+		copy(chunkKey, key)
+		if isLast && hot {
+			binary.BigEndian.PutUint32(chunkKey[len(key):], ^uint32(0))
+		} else {
+			binary.BigEndian.PutUint32(chunkKey[len(key):], chunk.Maximum())
+		}
+		buf.Reset()
+		if _, err := chunk.WriteTo(buf); err != nil {
+			return err
+		}
+		return db.Put(bucket, chunkKey, libcommon.Copy(buf.Bytes()))
+	})
+}
+
 // Get - reading as much chunks as needed to satisfy [from, to] condition
 // join all chunks to 1 bitmap by Or operator
 func Get(db kv.Tx, bucket string, key []byte, from, to uint32) (*roaring.Bitmap, error) {
@@ -303,6 +362,63 @@ func TruncateRange64(db kv.RwTx, bucket string, key []byte, to uint64) error {
 	})
 }
 
+// MergeRange64 - same as MergeRange, for 64-bit bitmaps
+func MergeRange64(db kv.RwTx, bucket string, key []byte, bm *roaring64.Bitmap) error {
+	if bm.IsEmpty() {
+		return nil
+	}
+	fromKey := make([]byte, len(key)+8)
+	copy(fromKey, key)
+	binary.BigEndian.PutUint64(fromKey[len(fromKey)-8:], bm.Minimum())
+	c, err := db.Cursor(bucket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	merged := bm.Clone()
+	hot := true
+	var shards [][]byte
+	for k, v, err := c.Seek(fromKey); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, key) {
+			break
+		}
+		shard := roaring64.New()
+		if _, err := shard.ReadFrom(bytes.NewReader(v)); err != nil {
+			return err
+		}
+		merged.Or(shard)
+		shards = append(shards, libcommon.Copy(k))
+		if shardMax := binary.BigEndian.Uint64(k[len(k)-8:]); shardMax >= bm.Maximum() {
+			hot = shardMax == ^uint64(0)
+			break
+		}
+	}
+	for _, k := range shards {
+		if err := db.Delete(bucket, k); err != nil {
+			return err
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	return WalkChunks64(merged, ChunkLimit, func(chunk *roaring64.Bitmap, isLast bool) error {
+		chunkKey := make([]byte, len(key)+8)
+		copy(chunkKey, key)
+		if isLast && hot {
+			binary.BigEndian.PutUint64(chunkKey[len(key):], ^uint64(0))
+		} else {
+			binary.BigEndian.PutUint64(chunkKey[len(key):], chunk.Maximum())
+		}
+		buf.Reset()
+		if _, err := chunk.WriteTo(buf); err != nil {
+			return err
+		}
+		return db.Put(bucket, chunkKey, libcommon.Copy(buf.Bytes()))
+	})
+}
+
 // Get - reading as much chunks as needed to satisfy [from, to] condition
 // join all chunks to 1 bitmap by Or operator
 func Get64(db kv.Tx, bucket string, key []byte, from, to uint64) (*roaring64.Bitmap, error) {
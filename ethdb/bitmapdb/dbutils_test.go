@@ -1,9 +1,13 @@
 package bitmapdb_test
 
 import (
+	"encoding/binary"
 	"testing"
 
 	"github.com/RoaringBitmap/roaring"
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
 	"github.com/stretchr/testify/require"
 )
@@ -53,3 +57,60 @@ func TestCutLeft(t *testing.T) {
 	require.True(t, lft == nil)
 	require.True(t, bm.GetCardinality() == 0)
 }
+
+func TestMergeRange(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	key := []byte{1}
+	existing := roaring.New()
+	for j := uint64(0); j < 100_000; j += 3 {
+		existing.Add(uint32(j))
+	}
+	existing.AddRange(200_000, 200_010)
+	require.NoError(t, bitmapdb.MergeRange(tx, kv.LogAddressIndex, key, existing))
+	require.NoError(t, bitmapdb.MergeRange(tx, kv.LogAddressIndex, []byte{2}, roaring.BitmapOf(7)))
+
+	// in the middle of shards, in the gap between shards and in the hot shard
+	added := roaring.New()
+	added.AddRange(50_000, 50_100)
+	added.AddRange(150_000, 150_010)
+	added.Add(200_020)
+	require.NoError(t, bitmapdb.MergeRange(tx, kv.LogAddressIndex, key, added))
+
+	expected := roaring.Or(existing, added)
+	var prevMax uint32
+	shards := 0
+	require.NoError(t, tx.ForPrefix(kv.LogAddressIndex, key, func(k, v []byte) error {
+		shard := roaring.New()
+		_, err := shard.FromBuffer(v)
+		require.NoError(t, err)
+		shardMax := binary.BigEndian.Uint32(k[len(key):])
+		require.LessOrEqual(t, shard.Maximum(), shardMax)
+		require.True(t, shards == 0 || shard.Minimum() > prevMax)
+		prevMax = shardMax
+		shards++
+		return nil
+	}))
+	require.Greater(t, shards, 2)
+	require.Equal(t, ^uint32(0), prevMax)
+	for _, r := range [][2]uint32{{0, 300_000}, {50_050, 50_050}, {150_000, 150_000}, {49_000, 52_000}, {200_020, 200_020}} {
+		bm, err := bitmapdb.Get(tx, kv.LogAddressIndex, key, r[0], r[1])
+		require.NoError(t, err)
+		bm.RemoveRange(0, uint64(r[0]))
+		bm.RemoveRange(uint64(r[1])+1, 1<<32)
+		want := expected.Clone()
+		want.RemoveRange(0, uint64(r[0]))
+		want.RemoveRange(uint64(r[1])+1, 1<<32)
+		require.True(t, want.Equals(bm), "range %d-%d", r[0], r[1])
+	}
+	bm, err := bitmapdb.Get(tx, kv.LogAddressIndex, []byte{2}, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{7}, bm.ToArray())
+
+	bm64 := roaring64.New()
+	bm64.AddRange(10, 20)
+	require.NoError(t, bitmapdb.MergeRange64(tx, kv.CallFromIndex, key, bm64))
+	require.NoError(t, bitmapdb.MergeRange64(tx, kv.CallFromIndex, key, roaring64.BitmapOf(5)))
+	bm64, err = bitmapdb.Get64(tx, kv.CallFromIndex, key, 0, 100)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{5, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}, bm64.ToArray())
+}
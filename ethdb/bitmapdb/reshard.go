@@ -0,0 +1,140 @@
+package bitmapdb
+
+import (
+	"bytes"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/RoaringBitmap/roaring/roaring64"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// ReshardStats - work done by Reshard
+type ReshardStats struct {
+	Keys         uint64 // bitmaps processed
+	Rewritten    uint64 // bitmaps whose shards were replaced
+	ShardsBefore uint64
+	ShardsAfter  uint64
+}
+
+// Reshard re-shards bitmaps of the index table so that every shard is at most sizeLimit bytes and shards are as big
+// as the limit allows: oversized shards are split, small ones (left by unwinds, prunes and older limits) merged. Shard
+// keys are the key of the bitmap + big-endian maximum of the shard (^0 for the last one), suffixLen is 4 for roaring
+// and 8 for roaring64 bitmaps. Bitmaps whose shards already have the layout aren't written.
+// Processes at most `limit` bitmaps starting at the shard key `from`, returns the shard key to continue from, nil when
+// the table is done.
+func Reshard(tx kv.RwTx, table string, suffixLen int, sizeLimit uint64, from []byte, limit int, stats *ReshardStats) ([]byte, error) {
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	k, v, err := c.Seek(from)
+	for done := 0; k != nil && done < limit; done++ {
+		if err != nil {
+			return nil, err
+		}
+		key := libcommon.Copy(k[:len(k)-suffixLen])
+		var shardKeys, shards [][]byte
+		for ; k != nil && len(k) == len(key)+suffixLen && bytes.HasPrefix(k, key); k, v, err = c.Next() {
+			shardKeys = append(shardKeys, libcommon.Copy(k))
+			shards = append(shards, libcommon.Copy(v))
+		}
+		if err != nil {
+			return nil, err
+		}
+		var next []byte
+		if k != nil {
+			next = libcommon.Copy(k)
+		}
+
+		newKeys, newShards, err := reshardBitmap(key, shards, suffixLen, sizeLimit)
+		if err != nil {
+			return nil, err
+		}
+		stats.Keys++
+		stats.ShardsBefore += uint64(len(shardKeys))
+		stats.ShardsAfter += uint64(len(newKeys))
+		// a shard key is the maximum of the shard, so the same keys mean the same shards
+		if !sameKeys(shardKeys, newKeys) {
+			for _, shardKey := range shardKeys {
+				if err = tx.Delete(table, shardKey); err != nil {
+					return nil, err
+				}
+			}
+			for i, shardKey := range newKeys {
+				if err = tx.Put(table, shardKey, newShards[i]); err != nil {
+					return nil, err
+				}
+			}
+			stats.Rewritten++
+		}
+
+		if next == nil {
+			return nil, nil
+		}
+		// writes invalidate the position of the cursor
+		k, v, err = c.Seek(next)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, nil
+	}
+	return libcommon.Copy(k), nil
+}
+
+// reshardBitmap - shard keys and shards of the union of the shards, cut by sizeLimit
+func reshardBitmap(key []byte, shards [][]byte, suffixLen int, sizeLimit uint64) (newKeys, newShards [][]byte, err error) {
+	var buf bytes.Buffer
+	if suffixLen == 8 {
+		bm := roaring64.New()
+		for _, shard := range shards {
+			part := roaring64.New()
+			if _, err = part.ReadFrom(bytes.NewReader(shard)); err != nil {
+				return nil, nil, err
+			}
+			bm.Or(part)
+		}
+		err = WalkChunkWithKeys64(key, bm, sizeLimit, func(chunkKey []byte, chunk *roaring64.Bitmap) error {
+			buf.Reset()
+			if _, err := chunk.WriteTo(&buf); err != nil {
+				return err
+			}
+			newKeys, newShards = append(newKeys, chunkKey), append(newShards, libcommon.Copy(buf.Bytes()))
+			return nil
+		})
+		return newKeys, newShards, err
+	}
+	bm := roaring.New()
+	for _, shard := range shards {
+		part := roaring.New()
+		if _, err = part.ReadFrom(bytes.NewReader(shard)); err != nil {
+			return nil, nil, err
+		}
+		bm.Or(part)
+	}
+	err = WalkChunkWithKeys(key, bm, sizeLimit, func(chunkKey []byte, chunk *roaring.Bitmap) error {
+		buf.Reset()
+		if _, err := chunk.WriteTo(&buf); err != nil {
+			return err
+		}
+		newKeys, newShards = append(newKeys, chunkKey), append(newShards, libcommon.Copy(buf.Bytes()))
+		return nil
+	})
+	return newKeys, newShards, err
+}
+
+func sameKeys(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
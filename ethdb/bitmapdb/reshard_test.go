@@ -0,0 +1,79 @@
+package bitmapdb_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReshard(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	table := kv.CallFromIndex
+	keys := [][]byte{{1}, {2}}
+
+	// key 1: 100 tiny shards of 50 blocks, key 2: a single oversized shard
+	put := func(key []byte, bm *roaring64.Bitmap, last bool) {
+		var buf bytes.Buffer
+		_, err := bm.WriteTo(&buf)
+		require.NoError(t, err)
+		shardKey := make([]byte, len(key)+8)
+		copy(shardKey, key)
+		binary.BigEndian.PutUint64(shardKey[len(key):], bm.Maximum())
+		if last {
+			binary.BigEndian.PutUint64(shardKey[len(key):], ^uint64(0))
+		}
+		require.NoError(t, tx.Put(table, shardKey, buf.Bytes()))
+	}
+	expected := []*roaring64.Bitmap{roaring64.New(), roaring64.New()}
+	for i := uint64(0); i < 100; i++ {
+		bm := roaring64.New()
+		for j := i * 500; j < i*500+500; j += 10 {
+			bm.Add(j)
+		}
+		expected[0].Or(bm)
+		put(keys[0], bm, i == 99)
+	}
+	for j := uint64(0); j < 100_000; j += 7 {
+		expected[1].Add(j)
+	}
+	put(keys[1], expected[1], true)
+
+	// one bitmap at a time, continuing from the returned key
+	var stats bitmapdb.ReshardStats
+	next, err := bitmapdb.Reshard(tx, table, 8, bitmapdb.ChunkLimit, nil, 1, &stats)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	next, err = bitmapdb.Reshard(tx, table, 8, bitmapdb.ChunkLimit, next, 1, &stats)
+	require.NoError(t, err)
+	require.Nil(t, next)
+	require.Equal(t, uint64(2), stats.Keys)
+	require.Equal(t, uint64(2), stats.Rewritten)
+	require.Equal(t, uint64(101), stats.ShardsBefore)
+	require.Less(t, stats.ShardsAfter, uint64(101))
+	require.Greater(t, stats.ShardsAfter, uint64(2))
+
+	for i, key := range keys {
+		bm, err := bitmapdb.Get64(tx, table, key, 0, ^uint64(0))
+		require.NoError(t, err)
+		require.True(t, expected[i].Equals(bm))
+	}
+	require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+		require.LessOrEqual(t, uint64(len(v)), bitmapdb.ChunkLimit+256)
+		return nil
+	}))
+
+	// the layout is kept
+	stats = bitmapdb.ReshardStats{}
+	next, err = bitmapdb.Reshard(tx, table, 8, bitmapdb.ChunkLimit, nil, 10, &stats)
+	require.NoError(t, err)
+	require.Nil(t, next)
+	require.Equal(t, uint64(2), stats.Keys)
+	require.Zero(t, stats.Rewritten)
+	require.Equal(t, stats.ShardsBefore, stats.ShardsAfter)
+}
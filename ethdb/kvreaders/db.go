@@ -0,0 +1,431 @@
+package kvreaders
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+	mdbx2 "github.com/torquem-ch/mdbx-go/mdbx"
+)
+
+// dbFreelist - the last measured freelist size in bytes, exported as the db_freelist_size gauge
+var dbFreelist uint64
+
+func init() {
+	metrics.GetOrCreateGauge(`db_freelist_size`, func() float64 { return float64(atomic.LoadUint64(&dbFreelist)) })
+}
+
+var (
+	readersLong       = metrics.GetOrCreateCounter(`rpc_readers_long`)
+	readersTerminated = metrics.GetOrCreateCounter(`rpc_readers_terminated`)
+	readersRefused    = metrics.GetOrCreateCounter(`rpc_readers_refused`)
+)
+
+// DB - kv.RoDB which tracks read transactions of rpc calls, opened with Caller in the context (see WithCaller), and
+// applies Policy to the long ones while the freelist of the database is above the limit. The freelist is measured
+// in the background in a transaction of its own, not limited by --db.read.concurrency: the monitor must work when
+// all readers are busy. Other transactions and databases other than local mdbx (remote) are passed through.
+type DB struct {
+	kv.RoDB
+	policy   Policy
+	freelist func() (uint64, error) // nil - not monitored
+
+	lock    sync.Mutex
+	readers map[*readerTx]struct{}
+	refused map[string]struct{} // methods of long readers, while ActionDegrade is in effect
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+func New(db kv.RoDB, policy Policy) *DB {
+	if !policy.Enabled() {
+		return &DB{RoDB: db}
+	}
+	env := mdbxEnv(db)
+	if env == nil {
+		log.Warn("[db] Policy of long readers is disabled: freelist is known only for a local database, use --datadir")
+		return &DB{RoDB: db}
+	}
+	d := newDB(db, policy, func() (uint64, error) { return freelistSize(env) })
+	d.quit, d.done = make(chan struct{}), make(chan struct{})
+	go d.monitor()
+	return d
+}
+
+func newDB(db kv.RoDB, policy Policy, freelist func() (uint64, error)) *DB {
+	if policy.CheckInterval <= 0 {
+		policy.CheckInterval = defaultCheckInterval
+	}
+	return &DB{RoDB: db, policy: policy, freelist: freelist, readers: map[*readerTx]struct{}{}, refused: map[string]struct{}{}}
+}
+
+// Unwrap returns underlying database
+func (db *DB) Unwrap() kv.RoDB { return db.RoDB }
+
+func (db *DB) Close() {
+	if db.quit != nil {
+		close(db.quit)
+		<-db.done
+	}
+	db.RoDB.Close()
+}
+
+func (db *DB) BeginRo(ctx context.Context) (kv.Tx, error) {
+	caller := CallerFromContext(ctx)
+	if caller == nil || db.freelist == nil {
+		return db.RoDB.BeginRo(ctx)
+	}
+	db.lock.Lock()
+	_, refused := db.refused[caller.Method]
+	db.lock.Unlock()
+	if refused {
+		readersRefused.Inc()
+		return nil, fmt.Errorf("%w: %s", ErrRefused, caller.Method)
+	}
+	tx, err := db.RoDB.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rtx := &readerTx{Tx: tx, db: db, caller: caller, started: time.Now()}
+	db.lock.Lock()
+	db.readers[rtx] = struct{}{}
+	db.lock.Unlock()
+	return rtx, nil
+}
+
+func (db *DB) View(ctx context.Context, f func(tx kv.Tx) error) error {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+func (db *DB) remove(tx *readerTx) {
+	db.lock.Lock()
+	delete(db.readers, tx)
+	db.lock.Unlock()
+}
+
+func (db *DB) monitor() {
+	defer close(db.done)
+	ticker := time.NewTicker(db.policy.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-db.quit:
+			return
+		case <-ticker.C:
+			if err := db.check(time.Now()); err != nil {
+				log.Warn("[db] Failed to measure freelist", "err", err)
+			}
+		}
+	}
+}
+
+// check applies the policy to transactions open longer than MaxAge at now. Transactions to terminate are only
+// marked, they're rolled back by goroutines using them, see readerTx.alive.
+func (db *DB) check(now time.Time) error {
+	freelist, err := db.freelist()
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint64(&dbFreelist, freelist)
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if freelist <= uint64(db.policy.FreelistLimit) {
+		if len(db.refused) > 0 {
+			log.Info("[db] Freelist is below the limit, read transactions are not refused anymore", "freelist", datasize.ByteSize(freelist).HR())
+			db.refused = map[string]struct{}{}
+		}
+		return nil
+	}
+	for tx := range db.readers {
+		age := now.Sub(tx.started)
+		if age < db.policy.MaxAge {
+			continue
+		}
+		if !tx.reported {
+			tx.reported = true
+			readersLong.Inc()
+			log.Warn("[db] Long read transaction while freelist is above the limit", "method", tx.caller.Method,
+				"client", tx.caller.Client, "age", age.Truncate(time.Second), "freelist", datasize.ByteSize(freelist).HR(), "action", db.policy.Action)
+		}
+		switch db.policy.Action {
+		case ActionKill:
+			if atomic.CompareAndSwapUint32(&tx.killed, 0, 1) {
+				readersTerminated.Inc()
+			}
+		case ActionDegrade:
+			db.refused[tx.caller.Method] = struct{}{}
+		}
+	}
+	return nil
+}
+
+func mdbxEnv(db kv.RoDB) *mdbx2.Env {
+	switch wrapper := db.(type) {
+	case interface{ Unwrap() kv.RwDB }:
+		db = wrapper.Unwrap()
+	case interface{ Unwrap() kv.RoDB }:
+		db = wrapper.Unwrap()
+	}
+	mdbxDB, ok := db.(*mdbx.MdbxKV)
+	if !ok {
+		return nil
+	}
+	return mdbxDB.Env()
+}
+
+// freelistSize - estimated size of the pages freed and not reused yet, in bytes. Records of the table of free pages
+// (DBI 0, "GC" in mdbx terms) are lists of page numbers, the number of free pages is estimated by the size of the
+// records as the db_gc_pages metric of erigon-lib does.
+func freelistSize(env *mdbx2.Env) (uint64, error) {
+	txn, err := env.BeginTxn(nil, mdbx2.Readonly)
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Abort()
+	st, err := txn.StatDBI(mdbx2.DBI(0))
+	if err != nil {
+		return 0, err
+	}
+	freePages := (st.LeafPages + st.OverflowPages) * uint64(st.PSize) / 8
+	return freePages * uint64(st.PSize), nil
+}
+
+// readerTx - tracked read transaction. It's used by one goroutine at a time, the monitor only sets killed.
+type readerTx struct {
+	kv.Tx
+	db       *DB
+	caller   *Caller
+	started  time.Time
+	reported bool   // guarded by db.lock
+	killed   uint32 // atomic, set by the monitor
+	closed   bool
+}
+
+// alive is checked before every operation: the transaction marked by the monitor is rolled back here, by its owner,
+// and the operation fails
+func (tx *readerTx) alive() error {
+	if atomic.LoadUint32(&tx.killed) == 0 {
+		return nil
+	}
+	tx.Rollback()
+	return fmt.Errorf("%w: %s", ErrTerminated, tx.caller.Method)
+}
+
+func (tx *readerTx) Rollback() {
+	if tx.closed {
+		return
+	}
+	tx.closed = true
+	tx.db.remove(tx)
+	tx.Tx.Rollback()
+}
+
+func (tx *readerTx) Commit() error {
+	if err := tx.alive(); err != nil {
+		return err
+	}
+	tx.closed = true
+	tx.db.remove(tx)
+	return tx.Tx.Commit()
+}
+
+func (tx *readerTx) ViewID() uint64 {
+	if tx.closed {
+		return 0
+	}
+	return tx.Tx.ViewID()
+}
+
+func (tx *readerTx) Has(table string, key []byte) (bool, error) {
+	if err := tx.alive(); err != nil {
+		return false, err
+	}
+	return tx.Tx.Has(table, key)
+}
+
+func (tx *readerTx) GetOne(table string, key []byte) ([]byte, error) {
+	if err := tx.alive(); err != nil {
+		return nil, err
+	}
+	return tx.Tx.GetOne(table, key)
+}
+
+func (tx *readerTx) ReadSequence(table string) (uint64, error) {
+	if err := tx.alive(); err != nil {
+		return 0, err
+	}
+	return tx.Tx.ReadSequence(table)
+}
+
+func (tx *readerTx) BucketSize(table string) (uint64, error) {
+	if err := tx.alive(); err != nil {
+		return 0, err
+	}
+	return tx.Tx.BucketSize(table)
+}
+
+func (tx *readerTx) DBSize() (uint64, error) {
+	if err := tx.alive(); err != nil {
+		return 0, err
+	}
+	return tx.Tx.DBSize()
+}
+
+func (tx *readerTx) ForEach(table string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	return tx.walk(walker, func(walker func(k, v []byte) error) error { return tx.Tx.ForEach(table, fromPrefix, walker) })
+}
+
+func (tx *readerTx) ForPrefix(table string, prefix []byte, walker func(k, v []byte) error) error {
+	return tx.walk(walker, func(walker func(k, v []byte) error) error { return tx.Tx.ForPrefix(table, prefix, walker) })
+}
+
+func (tx *readerTx) ForAmount(table string, prefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	return tx.walk(walker, func(walker func(k, v []byte) error) error { return tx.Tx.ForAmount(table, prefix, amount, walker) })
+}
+
+// walk stops long iterations too, the transaction is rolled back when the iteration is over
+func (tx *readerTx) walk(walker func(k, v []byte) error, iterate func(walker func(k, v []byte) error) error) error {
+	if err := tx.alive(); err != nil {
+		return err
+	}
+	err := iterate(func(k, v []byte) error {
+		if atomic.LoadUint32(&tx.killed) != 0 {
+			return ErrTerminated
+		}
+		return walker(k, v)
+	})
+	if aliveErr := tx.alive(); aliveErr != nil {
+		return aliveErr
+	}
+	return err
+}
+
+func (tx *readerTx) Cursor(table string) (kv.Cursor, error) {
+	if err := tx.alive(); err != nil {
+		return nil, err
+	}
+	c, err := tx.Tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrapCursor(c), nil
+}
+
+func (tx *readerTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	if err := tx.alive(); err != nil {
+		return nil, err
+	}
+	c, err := tx.Tx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrapCursor(c).(kv.CursorDupSort), nil
+}
+
+// wrapCursor keeps the set of interfaces implemented by the cursor: callers type-assert
+// cursors of DupSort tables to kv.CursorDupSort
+func (tx *readerTx) wrapCursor(c kv.Cursor) kv.Cursor {
+	if dup, ok := c.(kv.CursorDupSort); ok {
+		return &readerCursorDupSort{readerCursor: readerCursor{Cursor: c, tx: tx}, dup: dup}
+	}
+	return &readerCursor{Cursor: c, tx: tx}
+}
+
+// readerCursor fails after the transaction is terminated, cursors of it are closed by the rollback
+type readerCursor struct {
+	kv.Cursor
+	tx *readerTx
+}
+
+func (c *readerCursor) move(move func() ([]byte, []byte, error)) ([]byte, []byte, error) {
+	if err := c.tx.alive(); err != nil {
+		return nil, nil, err
+	}
+	return move()
+}
+
+func (c *readerCursor) First() ([]byte, []byte, error) { return c.move(c.Cursor.First) }
+
+func (c *readerCursor) Seek(seek []byte) ([]byte, []byte, error) {
+	return c.move(func() ([]byte, []byte, error) { return c.Cursor.Seek(seek) })
+}
+
+func (c *readerCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	return c.move(func() ([]byte, []byte, error) { return c.Cursor.SeekExact(key) })
+}
+
+func (c *readerCursor) Next() ([]byte, []byte, error)    { return c.move(c.Cursor.Next) }
+func (c *readerCursor) Prev() ([]byte, []byte, error)    { return c.move(c.Cursor.Prev) }
+func (c *readerCursor) Last() ([]byte, []byte, error)    { return c.move(c.Cursor.Last) }
+func (c *readerCursor) Current() ([]byte, []byte, error) { return c.move(c.Cursor.Current) }
+
+func (c *readerCursor) Count() (uint64, error) {
+	if err := c.tx.alive(); err != nil {
+		return 0, err
+	}
+	return c.Cursor.Count()
+}
+
+func (c *readerCursor) Close() {
+	if c.tx.closed {
+		return
+	}
+	c.Cursor.Close()
+}
+
+type readerCursorDupSort struct {
+	readerCursor
+	dup kv.CursorDupSort
+}
+
+func (c *readerCursorDupSort) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
+	return c.move(func() ([]byte, []byte, error) { return c.dup.SeekBothExact(key, value) })
+}
+
+func (c *readerCursorDupSort) SeekBothRange(key, value []byte) ([]byte, error) {
+	_, v, err := c.move(func() ([]byte, []byte, error) {
+		v, err := c.dup.SeekBothRange(key, value)
+		return nil, v, err
+	})
+	return v, err
+}
+
+func (c *readerCursorDupSort) FirstDup() ([]byte, error) {
+	_, v, err := c.move(func() ([]byte, []byte, error) {
+		v, err := c.dup.FirstDup()
+		return nil, v, err
+	})
+	return v, err
+}
+
+func (c *readerCursorDupSort) NextDup() ([]byte, []byte, error)   { return c.move(c.dup.NextDup) }
+func (c *readerCursorDupSort) NextNoDup() ([]byte, []byte, error) { return c.move(c.dup.NextNoDup) }
+
+func (c *readerCursorDupSort) LastDup() ([]byte, error) {
+	_, v, err := c.move(func() ([]byte, []byte, error) {
+		v, err := c.dup.LastDup()
+		return nil, v, err
+	})
+	return v, err
+}
+
+func (c *readerCursorDupSort) CountDuplicates() (uint64, error) {
+	if err := c.tx.alive(); err != nil {
+		return 0, err
+	}
+	return c.dup.CountDuplicates()
+}
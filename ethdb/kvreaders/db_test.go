@@ -0,0 +1,108 @@
+package kvreaders
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	require := require.New(t)
+	rwDB := memdb.NewTestDB(t)
+	require.NoError(rwDB.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.HeaderCanonical, []byte{1}, []byte{1})
+	}))
+	_, err := freelistSize(mdbxEnv(rwDB))
+	require.NoError(err)
+	freelist := uint64(0)
+	policy := Policy{FreelistLimit: datasize.MB, MaxAge: time.Minute, Action: ActionKill}
+	db := newDB(rwDB, policy, func() (uint64, error) { return freelist, nil })
+	later := time.Now().Add(2 * time.Minute)
+
+	trace := WithCaller(context.Background(), "trace_block", "127.0.0.1:1234")
+	tx, err := db.BeginRo(trace)
+	require.NoError(err)
+	defer tx.Rollback()
+	c, err := tx.Cursor(kv.HeaderCanonical)
+	require.NoError(err)
+	defer c.Close()
+	internal, err := db.BeginRo(context.Background())
+	require.NoError(err)
+	defer internal.Rollback()
+
+	// long readers are fine while the freelist is small
+	require.NoError(db.check(later))
+	k, _, err := c.First()
+	require.NoError(err)
+	require.Equal([]byte{1}, k)
+
+	freelist = uint64(2 * datasize.MB)
+	require.NoError(db.check(time.Now()))
+	_, err = tx.GetOne(kv.HeaderCanonical, []byte{1})
+	require.NoError(err, "not long yet")
+
+	require.NoError(db.check(later))
+	_, _, err = c.Next()
+	require.ErrorIs(err, ErrTerminated)
+	_, err = tx.GetOne(kv.HeaderCanonical, []byte{1})
+	require.ErrorIs(err, ErrTerminated)
+	require.Empty(db.readers)
+	v, err := internal.GetOne(kv.HeaderCanonical, []byte{1})
+	require.NoError(err, "transactions not opened by rpc calls are not tracked")
+	require.Equal([]byte{1}, v)
+}
+
+func TestPolicyDegrade(t *testing.T) {
+	require := require.New(t)
+	freelist := uint64(2 * datasize.MB)
+	policy := Policy{FreelistLimit: datasize.MB, MaxAge: time.Minute, Action: ActionDegrade}
+	db := newDB(memdb.NewTestDB(t), policy, func() (uint64, error) { return freelist, nil })
+	later := time.Now().Add(2 * time.Minute)
+
+	trace := WithCaller(context.Background(), "trace_block", "127.0.0.1:1234")
+	tx, err := db.BeginRo(trace)
+	require.NoError(err)
+	require.NoError(db.check(later))
+	_, err = tx.GetOne(kv.HeaderCanonical, []byte{1})
+	require.NoError(err, "long reader is let finish")
+	tx.Rollback()
+
+	_, err = db.BeginRo(trace)
+	require.ErrorIs(err, ErrRefused)
+	other, err := db.BeginRo(WithCaller(context.Background(), "eth_blockNumber", "127.0.0.1:1234"))
+	require.NoError(err)
+	other.Rollback()
+
+	freelist = 0
+	require.NoError(db.check(later))
+	tx, err = db.BeginRo(trace)
+	require.NoError(err)
+	tx.Rollback()
+}
+
+func TestFreelistSize(t *testing.T) {
+	require := require.New(t)
+	rwDB := memdb.NewTestDB(t)
+	value := make([]byte, 1024)
+	require.NoError(rwDB.Update(context.Background(), func(tx kv.RwTx) error {
+		for i := 0; i < 10_000; i++ {
+			if err := tx.Put(kv.HeaderCanonical, []byte(fmt.Sprintf("%08d", i)), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	require.NoError(rwDB.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.ClearBucket(kv.HeaderCanonical)
+	}))
+	// about 10MB of pages are freed, the table of free pages is a few KB
+	size, err := freelistSize(mdbxEnv(rwDB))
+	require.NoError(err)
+	require.Greater(size, uint64(datasize.MB))
+}
@@ -0,0 +1,84 @@
+package kvreaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+)
+
+// Action - what is done with long read transactions while the freelist is above the limit
+type Action string
+
+const (
+	ActionLog     Action = "log"     // only report them
+	ActionDegrade Action = "degrade" // let them finish, refuse new read transactions of their methods
+	ActionKill    Action = "kill"    // terminate them, their next operation fails with ErrTerminated
+)
+
+const defaultCheckInterval = 10 * time.Second
+
+var (
+	ErrTerminated = errors.New("read transaction is terminated: it was open too long while the database freelist grew")
+	ErrRefused    = errors.New("read transactions of the method are refused while the database freelist is above the limit")
+)
+
+// Policy - when and how long read transactions of rpc calls are handled. The freelist holds pages of old versions
+// of the data which can't be reused while a reader of these versions exists, so a few long readers (trace storms)
+// during sync make the database file grow. Only the age of the oldest readers matters, it's their snapshot which
+// holds the pages.
+type Policy struct {
+	FreelistLimit datasize.ByteSize // freelist size above which long readers are handled, 0 - disabled
+	MaxAge        time.Duration     // read transactions open longer than it are long
+	Action        Action
+	CheckInterval time.Duration // how often the freelist is measured, 0 - default
+}
+
+// ParsePolicy - Policy of command line flags, the empty freelist limit disables it
+func ParsePolicy(freelistLimit string, maxAge time.Duration, action string) (Policy, error) {
+	p := Policy{MaxAge: maxAge, Action: Action(action)}
+	if freelistLimit != "" {
+		if err := p.FreelistLimit.UnmarshalText([]byte(freelistLimit)); err != nil {
+			return Policy{}, fmt.Errorf("freelist limit of readers: %w", err)
+		}
+	}
+	return p, p.Validate()
+}
+
+func (p Policy) Enabled() bool { return p.FreelistLimit > 0 }
+
+func (p Policy) Validate() error {
+	if !p.Enabled() {
+		return nil
+	}
+	switch p.Action {
+	case ActionLog, ActionDegrade, ActionKill:
+	default:
+		return fmt.Errorf("unknown action of long readers %q, expected one of: %s, %s, %s", p.Action, ActionLog, ActionDegrade, ActionKill)
+	}
+	if p.MaxAge <= 0 {
+		return errors.New("max age of readers must be positive")
+	}
+	return nil
+}
+
+// Caller - the rpc call which opened a read transaction
+type Caller struct {
+	Method string
+	Client string // remote address, empty for in-process clients
+}
+
+type callerKey struct{}
+
+// WithCaller makes read transactions opened by DB with the returned context tracked as transactions of the call
+func WithCaller(ctx context.Context, method, client string) context.Context {
+	return context.WithValue(ctx, callerKey{}, &Caller{Method: method, Client: client})
+}
+
+// CallerFromContext returns the Caller set by WithCaller, nil if there is none
+func CallerFromContext(ctx context.Context) *Caller {
+	c, _ := ctx.Value(callerKey{}).(*Caller)
+	return c
+}
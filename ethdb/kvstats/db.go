@@ -0,0 +1,227 @@
+package kvstats
+
+import (
+	"context"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// DB - kv.RoDB which gathers read statistics of transactions opened with Stats in the context, see WithStats.
+// Transactions are wrapped only when the context carries Stats - there is no overhead for the rest.
+type DB struct {
+	kv.RoDB
+}
+
+func New(db kv.RoDB) *DB {
+	return &DB{RoDB: db}
+}
+
+// Unwrap returns underlying database
+func (db *DB) Unwrap() kv.RoDB { return db.RoDB }
+
+func (db *DB) BeginRo(ctx context.Context) (kv.Tx, error) {
+	stats := FromContext(ctx)
+	if stats == nil {
+		return db.RoDB.BeginRo(ctx)
+	}
+	start := time.Now()
+	tx, err := db.RoDB.BeginRo(ctx)
+	stats.spent(time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	stats.opened()
+	return &statsTx{Tx: tx, stats: stats}, nil
+}
+
+func (db *DB) View(ctx context.Context, f func(tx kv.Tx) error) error {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+type statsTx struct {
+	kv.Tx
+	stats *Stats
+}
+
+func (tx *statsTx) Has(table string, key []byte) (bool, error) {
+	start := time.Now()
+	has, err := tx.Tx.Has(table, key)
+	tx.stats.spent(time.Since(start))
+	tx.stats.seek()
+	if has {
+		tx.stats.read(key, nil)
+	}
+	return has, err
+}
+
+func (tx *statsTx) GetOne(table string, key []byte) ([]byte, error) {
+	start := time.Now()
+	v, err := tx.Tx.GetOne(table, key)
+	tx.stats.spent(time.Since(start))
+	tx.stats.seek()
+	if v != nil {
+		tx.stats.read(key, v)
+	}
+	return v, err
+}
+
+func (tx *statsTx) ForEach(table string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	return tx.walk(walker, func(walker func(k, v []byte) error) error { return tx.Tx.ForEach(table, fromPrefix, walker) })
+}
+
+func (tx *statsTx) ForPrefix(table string, prefix []byte, walker func(k, v []byte) error) error {
+	return tx.walk(walker, func(walker func(k, v []byte) error) error { return tx.Tx.ForPrefix(table, prefix, walker) })
+}
+
+func (tx *statsTx) ForAmount(table string, prefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	return tx.walk(walker, func(walker func(k, v []byte) error) error { return tx.Tx.ForAmount(table, prefix, amount, walker) })
+}
+
+// walk counts pairs passed to the walker, time spent in the walker is not time spent in the database
+func (tx *statsTx) walk(walker func(k, v []byte) error, iterate func(walker func(k, v []byte) error) error) error {
+	var inWalker time.Duration
+	start := time.Now()
+	err := iterate(func(k, v []byte) error {
+		tx.stats.read(k, v)
+		walkerStart := time.Now()
+		err := walker(k, v)
+		inWalker += time.Since(walkerStart)
+		return err
+	})
+	tx.stats.spent(time.Since(start) - inWalker)
+	tx.stats.seek()
+	return err
+}
+
+func (tx *statsTx) Cursor(table string) (kv.Cursor, error) {
+	c, err := tx.Tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrapCursor(c), nil
+}
+
+func (tx *statsTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	c, err := tx.Tx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrapCursor(c).(kv.CursorDupSort), nil
+}
+
+// wrapCursor keeps the set of interfaces implemented by the cursor: callers type-assert
+// cursors of DupSort tables to kv.CursorDupSort
+func (tx *statsTx) wrapCursor(c kv.Cursor) kv.Cursor {
+	if dup, ok := c.(kv.CursorDupSort); ok {
+		return &statsCursorDupSort{statsCursor: statsCursor{Cursor: c, stats: tx.stats}, dup: dup}
+	}
+	return &statsCursor{Cursor: c, stats: tx.stats}
+}
+
+type statsCursor struct {
+	kv.Cursor
+	stats *Stats
+}
+
+func (c *statsCursor) done(start time.Time, seek bool, k, v []byte, err error) ([]byte, []byte, error) {
+	c.stats.spent(time.Since(start))
+	if seek {
+		c.stats.seek()
+	}
+	c.stats.read(k, v)
+	return k, v, err
+}
+
+func (c *statsCursor) First() ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.Cursor.First()
+	return c.done(start, true, k, v, err)
+}
+
+func (c *statsCursor) Seek(seek []byte) ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.Cursor.Seek(seek)
+	return c.done(start, true, k, v, err)
+}
+
+func (c *statsCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.Cursor.SeekExact(key)
+	return c.done(start, true, k, v, err)
+}
+
+func (c *statsCursor) Next() ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.Cursor.Next()
+	return c.done(start, false, k, v, err)
+}
+
+func (c *statsCursor) Prev() ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.Cursor.Prev()
+	return c.done(start, false, k, v, err)
+}
+
+func (c *statsCursor) Last() ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.Cursor.Last()
+	return c.done(start, true, k, v, err)
+}
+
+func (c *statsCursor) Current() ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.Cursor.Current()
+	return c.done(start, false, k, v, err)
+}
+
+type statsCursorDupSort struct {
+	statsCursor
+	dup kv.CursorDupSort
+}
+
+func (c *statsCursorDupSort) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.dup.SeekBothExact(key, value)
+	return c.done(start, true, k, v, err)
+}
+
+func (c *statsCursorDupSort) SeekBothRange(key, value []byte) ([]byte, error) {
+	start := time.Now()
+	v, err := c.dup.SeekBothRange(key, value)
+	_, v, err = c.done(start, true, nil, v, err)
+	return v, err
+}
+
+func (c *statsCursorDupSort) FirstDup() ([]byte, error) {
+	start := time.Now()
+	v, err := c.dup.FirstDup()
+	_, v, err = c.done(start, false, nil, v, err)
+	return v, err
+}
+
+func (c *statsCursorDupSort) NextDup() ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.dup.NextDup()
+	return c.done(start, false, k, v, err)
+}
+
+func (c *statsCursorDupSort) NextNoDup() ([]byte, []byte, error) {
+	start := time.Now()
+	k, v, err := c.dup.NextNoDup()
+	return c.done(start, false, k, v, err)
+}
+
+func (c *statsCursorDupSort) LastDup() ([]byte, error) {
+	start := time.Now()
+	v, err := c.dup.LastDup()
+	_, v, err = c.done(start, false, nil, v, err)
+	return v, err
+}
+
+func (c *statsCursorDupSort) CountDuplicates() (uint64, error) { return c.dup.CountDuplicates() }
@@ -0,0 +1,97 @@
+package kvstats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	rwDB := memdb.NewTestDB(t)
+	require.NoError(rwDB.Update(ctx, func(tx kv.RwTx) error {
+		for _, k := range []string{"a", "b", "c"} {
+			if err := tx.Put(kv.HeaderCanonical, []byte(k), []byte("val")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	db := New(rwDB)
+
+	// without stats in the context transactions are not wrapped
+	tx, err := db.BeginRo(ctx)
+	require.NoError(err)
+	_, wrapped := tx.(*statsTx)
+	require.False(wrapped)
+	tx.Rollback()
+
+	stats := &Stats{}
+	require.NoError(db.View(WithStats(ctx, stats), func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.HeaderCanonical, []byte("a"))
+		require.Equal([]byte("val"), v)
+		require.NoError(err)
+		_, err = tx.GetOne(kv.HeaderCanonical, []byte("x")) // not found - seek without read
+		require.NoError(err)
+
+		c, err := tx.Cursor(kv.HeaderCanonical)
+		require.NoError(err)
+		defer c.Close()
+		for k, _, err := c.Seek([]byte("b")); k != nil; k, _, err = c.Next() {
+			require.NoError(err)
+		}
+		return tx.ForEach(kv.HeaderCanonical, nil, func(k, v []byte) error { return nil })
+	}))
+	s := stats.Snapshot()
+	require.Equal(uint64(1), s.Txs)
+	require.Equal(uint64(1+2+3), s.KeysRead)
+	require.Equal(uint64(6*4), s.BytesRead)
+	require.Equal(uint64(2+1+1), s.CursorSeeks)
+}
+
+func TestStatsDupSortCursor(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	rwDB := memdb.NewTestDB(t)
+	require.NoError(rwDB.Update(ctx, func(tx kv.RwTx) error {
+		for _, v := range []string{"1", "2"} {
+			if err := tx.Put(kv.AccountChangeSet, []byte("k"), []byte(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	db := New(rwDB)
+
+	stats := &Stats{}
+	require.NoError(db.View(WithStats(ctx, stats), func(tx kv.Tx) error {
+		c, err := tx.Cursor(kv.AccountChangeSet)
+		require.NoError(err)
+		defer c.Close()
+		dup, ok := c.(kv.CursorDupSort)
+		require.True(ok)
+		v, err := dup.SeekBothRange([]byte("k"), []byte("2"))
+		require.NoError(err)
+		require.Equal([]byte("2"), v)
+		return nil
+	}))
+	s := stats.Snapshot()
+	require.Equal(uint64(1), s.KeysRead)
+	require.Equal(uint64(1), s.CursorSeeks)
+}
+
+func TestRPCStats(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := New(memdb.NewTestDB(t))
+
+	var rpcStats RPCStats
+	require.Nil(rpcStats.Snapshot(ctx))
+	ctx = rpcStats.WithStats(ctx)
+	require.NoError(db.View(ctx, func(tx kv.Tx) error { return nil }))
+	require.Equal(&Snapshot{Txs: 1, DBTimeUs: rpcStats.Snapshot(ctx).(*Snapshot).DBTimeUs}, rpcStats.Snapshot(ctx))
+}
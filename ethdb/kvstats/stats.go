@@ -0,0 +1,75 @@
+package kvstats
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Stats - read-path I/O of transactions opened by DB with the context carrying it, see WithStats.
+// Safe for concurrent use: one request may read in several goroutines.
+type Stats struct {
+	txs, keys, bytes, seeks, dbTime uint64 // dbTime - nanoseconds
+}
+
+// Snapshot - values of Stats at some moment, in the form returned to rpc clients
+type Snapshot struct {
+	Txs         uint64 `json:"txs"`         // Read transactions opened
+	KeysRead    uint64 `json:"keysRead"`    // Key/value pairs returned by the database
+	BytesRead   uint64 `json:"bytesRead"`   // Sizes of keys and values returned by the database
+	CursorSeeks uint64 `json:"cursorSeeks"` // Positioning operations: Seek*, First, Last, GetOne, Has, start of ForEach
+	DBTimeUs    uint64 `json:"dbTimeUs"`    // Time spent inside the database, in microseconds
+}
+
+func (s *Stats) Snapshot() Snapshot {
+	return Snapshot{
+		Txs:         atomic.LoadUint64(&s.txs),
+		KeysRead:    atomic.LoadUint64(&s.keys),
+		BytesRead:   atomic.LoadUint64(&s.bytes),
+		CursorSeeks: atomic.LoadUint64(&s.seeks),
+		DBTimeUs:    uint64(time.Duration(atomic.LoadUint64(&s.dbTime)).Microseconds()),
+	}
+}
+
+func (s *Stats) opened() { atomic.AddUint64(&s.txs, 1) }
+
+func (s *Stats) seek() { atomic.AddUint64(&s.seeks, 1) }
+
+// read records one returned key/value pair, nothing if both are nil - end of table or key not found
+func (s *Stats) read(k, v []byte) {
+	if k == nil && v == nil {
+		return
+	}
+	atomic.AddUint64(&s.keys, 1)
+	atomic.AddUint64(&s.bytes, uint64(len(k)+len(v)))
+}
+
+func (s *Stats) spent(d time.Duration) { atomic.AddUint64(&s.dbTime, uint64(d)) }
+
+type statsKey struct{}
+
+// WithStats makes transactions opened by DB with the returned context gather statistics into s
+func WithStats(ctx context.Context, s *Stats) context.Context {
+	return context.WithValue(ctx, statsKey{}, s)
+}
+
+// FromContext returns Stats set by WithStats, nil if there are none
+func FromContext(ctx context.Context) *Stats {
+	s, _ := ctx.Value(statsKey{}).(*Stats)
+	return s
+}
+
+// RPCStats - rpc.DBStats giving each call its own Stats, see rpc.Server.SetDBStats
+type RPCStats struct{}
+
+func (RPCStats) WithStats(ctx context.Context) context.Context { return WithStats(ctx, &Stats{}) }
+
+// Snapshot returns *Snapshot of Stats of the context, nil if there are none
+func (RPCStats) Snapshot(ctx context.Context) interface{} {
+	s := FromContext(ctx)
+	if s == nil {
+		return nil
+	}
+	snapshot := s.Snapshot()
+	return &snapshot
+}
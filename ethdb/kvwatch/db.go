@@ -0,0 +1,308 @@
+package kvwatch
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// DB - kv.RwDB which publishes keys of watched tables changed by committed write transactions.
+// Internal components use it to react on commits (new headers, stage progress, etc.) instead of polling.
+// Write transactions are wrapped only while there are subscriptions, and only writes into watched tables
+// are recorded - it keeps overhead of the commit path low. Subscription created in the middle of write
+// transaction doesn't see changes of this transaction.
+type DB struct {
+	kv.RwDB
+	hub *Hub
+}
+
+func New(db kv.RwDB) *DB {
+	return &DB{RwDB: db, hub: NewHub()}
+}
+
+func (db *DB) Watch(table string, prefix []byte) *Subscription { return db.hub.Watch(table, prefix) }
+
+// Unwrap returns underlying database, for example to access engine-specific stats
+func (db *DB) Unwrap() kv.RwDB { return db.RwDB }
+
+func (db *DB) Close() {
+	db.RwDB.Close()
+	db.hub.Close()
+}
+
+func (db *DB) BeginRw(ctx context.Context) (kv.RwTx, error) {
+	tx, err := db.RwDB.BeginRw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	watched := db.hub.watchedTables()
+	if len(watched) == 0 {
+		return tx, nil
+	}
+	return &watchTx{RwTx: tx, hub: db.hub, watched: watched}, nil
+}
+
+func (db *DB) Update(ctx context.Context, f func(tx kv.RwTx) error) error {
+	tx, err := db.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// watchTx records changed keys of watched tables and publishes them after successful commit
+type watchTx struct {
+	kv.RwTx
+	hub     *Hub
+	watched map[string]struct{}
+	changes map[string]map[string]struct{} // table -> changed keys
+	cleared map[string]struct{}            // tables with unknown changes
+}
+
+func (tx *watchTx) record(table string, k []byte) {
+	if _, ok := tx.watched[table]; !ok {
+		return
+	}
+	if _, ok := tx.cleared[table]; ok {
+		return
+	}
+	if tx.changes == nil {
+		tx.changes = map[string]map[string]struct{}{}
+	}
+	keys, ok := tx.changes[table]
+	if !ok {
+		keys = map[string]struct{}{}
+		tx.changes[table] = keys
+	}
+	keys[string(k)] = struct{}{}
+	if len(keys) > maxPendingKeys {
+		tx.clear(table)
+	}
+}
+
+func (tx *watchTx) clear(table string) {
+	if _, ok := tx.watched[table]; !ok {
+		return
+	}
+	if tx.cleared == nil {
+		tx.cleared = map[string]struct{}{}
+	}
+	tx.cleared[table] = struct{}{}
+	delete(tx.changes, table)
+}
+
+func (tx *watchTx) Commit() error {
+	if err := tx.RwTx.Commit(); err != nil {
+		return err
+	}
+	if len(tx.changes) == 0 && len(tx.cleared) == 0 {
+		return nil
+	}
+	changes := make(map[string][][]byte, len(tx.changes))
+	for table, keys := range tx.changes {
+		sorted := make([][]byte, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, []byte(k))
+		}
+		sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+		changes[table] = sorted
+	}
+	tx.hub.publish(changes, tx.cleared)
+	return nil
+}
+
+func (tx *watchTx) Put(table string, k, v []byte) error {
+	if err := tx.RwTx.Put(table, k, v); err != nil {
+		return err
+	}
+	tx.record(table, k)
+	return nil
+}
+
+func (tx *watchTx) Delete(table string, k []byte) error {
+	if err := tx.RwTx.Delete(table, k); err != nil {
+		return err
+	}
+	tx.record(table, k)
+	return nil
+}
+
+func (tx *watchTx) Append(table string, k, v []byte) error {
+	if err := tx.RwTx.Append(table, k, v); err != nil {
+		return err
+	}
+	tx.record(table, k)
+	return nil
+}
+
+func (tx *watchTx) AppendDup(table string, k, v []byte) error {
+	if err := tx.RwTx.AppendDup(table, k, v); err != nil {
+		return err
+	}
+	tx.record(table, k)
+	return nil
+}
+
+func (tx *watchTx) ClearBucket(table string) error {
+	if err := tx.RwTx.ClearBucket(table); err != nil {
+		return err
+	}
+	tx.clear(table)
+	return nil
+}
+
+func (tx *watchTx) DropBucket(table string) error {
+	if err := tx.RwTx.DropBucket(table); err != nil {
+		return err
+	}
+	tx.clear(table)
+	return nil
+}
+
+// Cursor - for write transaction cursors are writable, some code relies on it
+func (tx *watchTx) Cursor(table string) (kv.Cursor, error) {
+	c, err := tx.RwTx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrapCursor(table, c), nil
+}
+
+func (tx *watchTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	c, err := tx.RwTx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrapCursor(table, c).(kv.CursorDupSort), nil
+}
+
+func (tx *watchTx) RwCursor(table string) (kv.RwCursor, error) {
+	c, err := tx.RwTx.RwCursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrapCursor(table, c).(kv.RwCursor), nil
+}
+
+func (tx *watchTx) RwCursorDupSort(table string) (kv.RwCursorDupSort, error) {
+	c, err := tx.RwTx.RwCursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrapCursor(table, c).(kv.RwCursorDupSort), nil
+}
+
+// wrapCursor keeps the set of interfaces implemented by the cursor: callers type-assert
+// cursors of DupSort tables to kv.RwCursorDupSort
+func (tx *watchTx) wrapCursor(table string, c kv.Cursor) kv.Cursor {
+	if _, ok := tx.watched[table]; !ok {
+		return c
+	}
+	switch c := c.(type) {
+	case kv.RwCursorDupSort:
+		return &watchCursorDupSort{RwCursorDupSort: c, watchCursor: watchCursor{RwCursor: c, tx: tx, table: table}}
+	case kv.RwCursor:
+		return &watchCursor{RwCursor: c, tx: tx, table: table}
+	default:
+		return c
+	}
+}
+
+type watchCursor struct {
+	kv.RwCursor
+	tx    *watchTx
+	table string
+}
+
+func (c *watchCursor) Put(k, v []byte) error {
+	if err := c.RwCursor.Put(k, v); err != nil {
+		return err
+	}
+	c.tx.record(c.table, k)
+	return nil
+}
+
+func (c *watchCursor) Append(k, v []byte) error {
+	if err := c.RwCursor.Append(k, v); err != nil {
+		return err
+	}
+	c.tx.record(c.table, k)
+	return nil
+}
+
+func (c *watchCursor) Delete(k []byte) error {
+	if err := c.RwCursor.Delete(k); err != nil {
+		return err
+	}
+	c.tx.record(c.table, k)
+	return nil
+}
+
+func (c *watchCursor) DeleteCurrent() error {
+	k, _, err := c.RwCursor.Current()
+	if err != nil {
+		return err
+	}
+	k = common.CopyBytes(k)
+	if err := c.RwCursor.DeleteCurrent(); err != nil {
+		return err
+	}
+	c.tx.record(c.table, k)
+	return nil
+}
+
+type watchCursorDupSort struct {
+	kv.RwCursorDupSort
+	watchCursor
+}
+
+// write methods of kv.RwCursor are ambiguous between embedded fields - route them to watchCursor
+
+func (c *watchCursorDupSort) Put(k, v []byte) error    { return c.watchCursor.Put(k, v) }
+func (c *watchCursorDupSort) Append(k, v []byte) error { return c.watchCursor.Append(k, v) }
+func (c *watchCursorDupSort) Delete(k []byte) error    { return c.watchCursor.Delete(k) }
+func (c *watchCursorDupSort) DeleteCurrent() error     { return c.watchCursor.DeleteCurrent() }
+
+func (c *watchCursorDupSort) PutNoDupData(k, v []byte) error {
+	if err := c.RwCursorDupSort.PutNoDupData(k, v); err != nil {
+		return err
+	}
+	c.tx.record(c.table, k)
+	return nil
+}
+
+func (c *watchCursorDupSort) AppendDup(k, v []byte) error {
+	if err := c.RwCursorDupSort.AppendDup(k, v); err != nil {
+		return err
+	}
+	c.tx.record(c.table, k)
+	return nil
+}
+
+func (c *watchCursorDupSort) DeleteExact(k1, k2 []byte) error {
+	if err := c.RwCursorDupSort.DeleteExact(k1, k2); err != nil {
+		return err
+	}
+	c.tx.record(c.table, k1)
+	return nil
+}
+
+func (c *watchCursorDupSort) DeleteCurrentDuplicates() error {
+	k, _, err := c.RwCursorDupSort.Current()
+	if err != nil {
+		return err
+	}
+	k = common.CopyBytes(k)
+	if err := c.RwCursorDupSort.DeleteCurrentDuplicates(); err != nil {
+		return err
+	}
+	c.tx.record(c.table, k)
+	return nil
+}
@@ -0,0 +1,109 @@
+package kvwatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func pending(sub *Subscription) bool {
+	select {
+	case <-sub.Notify():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestWatch(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := New(memdb.NewTestDB(t))
+
+	progress := db.Watch(kv.SyncStageProgress, []byte("Fin"))
+	defer progress.Close()
+	canonical := db.Watch(kv.HeaderCanonical, nil)
+	defer canonical.Close()
+
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		if err := tx.Put(kv.SyncStageProgress, []byte("Headers"), []byte{1}); err != nil {
+			return err
+		}
+		if err := tx.Put(kv.SyncStageProgress, []byte("Finish"), []byte{1}); err != nil {
+			return err
+		}
+		c, err := tx.RwCursor(kv.HeaderCanonical)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		for _, k := range []string{"c", "a", "b", "a"} {
+			if err := c.Put([]byte(k), []byte{1}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	require.True(pending(progress))
+	keys, overflow := progress.Changes()
+	require.False(overflow)
+	require.Equal([][]byte{[]byte("Finish")}, keys)
+	require.True(pending(canonical))
+	keys, overflow = canonical.Changes()
+	require.False(overflow)
+	require.Equal([][]byte{[]byte("a"), []byte("b"), []byte("c")}, keys)
+
+	// rolled back changes are not published, changes of several commits are coalesced
+	tx, err := db.BeginRw(ctx)
+	require.NoError(err)
+	require.NoError(tx.Put(kv.HeaderCanonical, []byte("x"), []byte{1}))
+	tx.Rollback()
+	require.False(pending(canonical))
+	for _, k := range []string{"d", "b"} {
+		require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+			// olddb.TxDb writes via read cursors of write transaction
+			c, err := tx.Cursor(kv.HeaderCanonical)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			return c.(kv.RwCursor).Delete([]byte(k))
+		}))
+	}
+	require.True(pending(canonical))
+	require.False(pending(canonical))
+	keys, _ = canonical.Changes()
+	require.Equal([][]byte{[]byte("b"), []byte("d")}, keys)
+	require.False(pending(progress))
+
+	// table cleared - subscriber must re-read everything
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error { return tx.ClearBucket(kv.HeaderCanonical) }))
+	require.True(pending(canonical))
+	keys, overflow = canonical.Changes()
+	require.True(overflow)
+	require.Nil(keys)
+
+	// dupsort cursors keep their interface
+	accounts := db.Watch(kv.AccountChangeSet, nil)
+	require.NoError(db.Update(ctx, func(tx kv.RwTx) error {
+		c, err := tx.RwCursor(kv.AccountChangeSet)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		return c.(kv.RwCursorDupSort).AppendDup([]byte{1}, []byte{2})
+	}))
+	require.True(pending(accounts))
+	keys, _ = accounts.Changes()
+	require.Equal([][]byte{{1}}, keys)
+
+	accounts.Close()
+	_, open := <-accounts.Notify()
+	require.False(open)
+	db.Close()
+	_, open = <-progress.Notify()
+	require.False(open)
+}
@@ -0,0 +1,226 @@
+package kvwatch
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// maxPendingKeys - if subscriber didn't read more keys than this, they are dropped
+// and the subscriber is told to re-read the whole watched range, see Subscription.Changes
+const maxPendingKeys = 4096
+
+// Watcher is implemented by databases which publish committed changes
+type Watcher interface {
+	// Watch subscribes to changes of keys of the table starting with the prefix (nil prefix - whole table).
+	Watch(table string, prefix []byte) *Subscription
+}
+
+// Subscription receives keys of a table, starting with a prefix, changed by committed write transactions.
+// Changes of several commits are coalesced until the subscriber reads them, so a slow subscriber never
+// blocks a commit.
+//
+// Common pattern:
+//
+//	sub := db.Watch(kv.SyncStageProgress, []byte(stages.Finish))
+//	defer sub.Close()
+//	for range sub.Notify() {
+//	    keys, overflow := sub.Changes()
+//	    ... re-read changed keys (or whole range if overflow) in new read transaction
+//	}
+type Subscription struct {
+	hub    *Hub
+	table  string
+	prefix []byte
+	notify chan struct{}
+
+	lock     sync.Mutex
+	keys     [][]byte
+	overflow bool
+	closed   bool
+}
+
+// Notify returns channel which receives a value after commit of changes of the watched keys.
+// Channel is closed by Close or when database is closed.
+func (s *Subscription) Notify() <-chan struct{} { return s.notify }
+
+// Changes returns changed keys since previous call, sorted and without duplicates.
+// overflow=true means some changes are not listed (too many keys or table was cleared) -
+// subscriber must re-read all watched keys.
+func (s *Subscription) Changes() (keys [][]byte, overflow bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys, overflow = s.keys, s.overflow
+	s.keys, s.overflow = nil, false
+	return keys, overflow
+}
+
+// Close unsubscribes, it's safe to call it many times
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+	s.close()
+}
+
+func (s *Subscription) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.notify)
+}
+
+// add - keys are sorted and unique
+func (s *Subscription) add(keys [][]byte, overflow bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+	if overflow || s.overflow || len(s.keys)+len(keys) > maxPendingKeys {
+		s.keys, s.overflow = nil, true
+	} else {
+		s.keys = mergeKeys(s.keys, keys)
+	}
+	select {
+	case s.notify <- struct{}{}:
+	default: // subscriber already has pending notification
+	}
+}
+
+// Hub keeps subscriptions and delivers committed changes to them
+type Hub struct {
+	lock   sync.RWMutex
+	subs   map[string][]*Subscription // table -> subscriptions
+	closed bool
+
+	watched atomic.Value // map[string]struct{} - copy-on-write set of watched tables, read by every write tx
+}
+
+func NewHub() *Hub {
+	h := &Hub{subs: map[string][]*Subscription{}}
+	h.watched.Store(map[string]struct{}{})
+	return h
+}
+
+func (h *Hub) Watch(table string, prefix []byte) *Subscription {
+	s := &Subscription{hub: h, table: table, prefix: common.CopyBytes(prefix), notify: make(chan struct{}, 1)}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.closed {
+		s.close()
+		return s
+	}
+	h.subs[table] = append(h.subs[table], s)
+	h.updateWatched()
+	return s
+}
+
+// Close closes all subscriptions
+func (h *Hub) Close() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.closed = true
+	for _, subs := range h.subs {
+		for _, s := range subs {
+			s.close()
+		}
+	}
+	h.subs = map[string][]*Subscription{}
+	h.updateWatched()
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	subs := h.subs[s.table]
+	for i := range subs {
+		if subs[i] == s {
+			h.subs[s.table] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(h.subs[s.table]) == 0 {
+		delete(h.subs, s.table)
+	}
+	h.updateWatched()
+}
+
+// updateWatched must be called under h.lock
+func (h *Hub) updateWatched() {
+	watched := make(map[string]struct{}, len(h.subs))
+	for table := range h.subs {
+		watched[table] = struct{}{}
+	}
+	h.watched.Store(watched)
+}
+
+func (h *Hub) watchedTables() map[string]struct{} {
+	return h.watched.Load().(map[string]struct{})
+}
+
+// publish - keys of every table are sorted and unique
+func (h *Hub) publish(changes map[string][][]byte, cleared map[string]struct{}) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	for table, subs := range h.subs {
+		keys := changes[table]
+		_, overflow := cleared[table]
+		if len(keys) == 0 && !overflow {
+			continue
+		}
+		for _, s := range subs {
+			if overflow {
+				s.add(nil, true)
+				continue
+			}
+			if matched := withPrefix(keys, s.prefix); len(matched) > 0 {
+				s.add(matched, false)
+			}
+		}
+	}
+}
+
+// withPrefix - keys must be sorted
+func withPrefix(keys [][]byte, prefix []byte) [][]byte {
+	if len(prefix) == 0 {
+		return keys
+	}
+	from := 0
+	for from < len(keys) && bytes.Compare(keys[from], prefix) < 0 {
+		from++
+	}
+	to := from
+	for to < len(keys) && bytes.HasPrefix(keys[to], prefix) {
+		to++
+	}
+	return keys[from:to]
+}
+
+// mergeKeys merges two sorted lists of unique keys
+func mergeKeys(a, b [][]byte) [][]byte {
+	if len(a) == 0 {
+		return b
+	}
+	res := make([][]byte, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch c := bytes.Compare(a[i], b[j]); {
+		case c < 0:
+			res = append(res, a[i])
+			i++
+		case c > 0:
+			res = append(res, b[j])
+			j++
+		default:
+			res = append(res, a[i])
+			i++
+			j++
+		}
+	}
+	res = append(res, a[i:]...)
+	return append(res, b[j:]...)
+}
@@ -0,0 +1,27 @@
+package olddb
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	batchCommitSize     = metrics.GetOrCreateHistogram(`db_batch_commit_size_bytes`)
+	batchCommitDuration = metrics.GetOrCreateHistogram(`db_batch_commit_seconds`)
+	batchAutoFlushes    = metrics.GetOrCreateCounter(`db_batch_autoflush_total`)
+)
+
+// Approximate memory held by a pending write besides its key and value: the MutationItem with its pointer in
+// a btree node, or the key string header, the value slice header and the slot of the table map
+const (
+	mutationItemOverhead    = int(unsafe.Sizeof(MutationItem{})) + int(unsafe.Sizeof(uintptr(0)))
+	mapMutationItemOverhead = int(unsafe.Sizeof("")) + int(unsafe.Sizeof([]byte(nil))) + 8
+)
+
+// recordBatchCommit - size of the pending writes of a batch and the time of writing them into its transaction
+func recordBatchCommit(size int, start time.Time) {
+	batchCommitSize.Update(float64(size))
+	batchCommitDuration.UpdateDuration(start)
+}
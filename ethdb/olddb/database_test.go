@@ -246,3 +246,48 @@ func TestWalk(t *testing.T) {
 
 	assert.Equal(t, keysInRange, gotKeys)
 }
+
+func TestBatchSize(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	for _, batch := range []ethdb.DbWithPendingMutations{NewBatch(tx, nil), NewHashBatch(tx, nil, t.TempDir())} {
+		require.Zero(t, batch.BatchSize())
+		require.NoError(t, batch.Put(testBucket, []byte("key"), []byte("value")))
+		size := batch.BatchSize()
+		require.Greater(t, size, len("key")+len("value"))
+		// overwrite only changes the size by the difference of values
+		require.NoError(t, batch.Put(testBucket, []byte("key"), []byte("longer value")))
+		require.Equal(t, size+len("longer value")-len("value"), batch.BatchSize())
+		require.NoError(t, batch.Put(testBucket, []byte("key2"), []byte("value")))
+		require.Greater(t, batch.BatchSize(), 2*size)
+		batch.Rollback()
+		require.Zero(t, batch.BatchSize())
+	}
+}
+
+func TestBatchAutoFlush(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	mutation, mapMutation := NewBatch(tx, nil), NewHashBatch(tx, nil, t.TempDir())
+	mutation.SetAutoFlush(1000)
+	mapMutation.SetAutoFlush(1000)
+	for i, batch := range []ethdb.DbWithPendingMutations{mutation, mapMutation} {
+		table := []string{kv.HashedAccounts, kv.HashedStorage}[i]
+		flushes := batchAutoFlushes.Get()
+		for j := 0; j < 100; j++ {
+			require.NoError(t, batch.Put(table, []byte(fmt.Sprintf("key%03d", j)), make([]byte, 50)))
+			require.Less(t, batch.BatchSize(), 1000)
+		}
+		require.Greater(t, batchAutoFlushes.Get(), flushes)
+
+		// flushed writes are in the transaction, the rest after Commit
+		v, err := tx.GetOne(table, []byte("key000"))
+		require.NoError(t, err)
+		require.Len(t, v, 50)
+		require.NoError(t, batch.Commit())
+		n := 0
+		require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+			n++
+			return nil
+		}))
+		require.Equal(t, 100, n)
+	}
+}
@@ -20,9 +20,11 @@ type mapmutation struct {
 	quit   <-chan struct{}
 	clean  func()
 	mu     sync.RWMutex
-	size   int
+	size   int // approximate memory of pending writes
 	count  uint64
 	tmpdir string
+
+	autoFlush int // see SetAutoFlush
 }
 
 // NewBatch - starts in-mem batch
@@ -167,18 +169,28 @@ func (m *mapmutation) Put(table string, k, v []byte) error {
 
 	stringKey := *(*string)(unsafe.Pointer(&k))
 
-	var ok bool
-	if _, ok = m.puts[table][stringKey]; !ok {
-		m.size += len(v) - len(m.puts[table][stringKey])
-		m.puts[table][stringKey] = v
-		return nil
+	if old, ok := m.puts[table][stringKey]; ok {
+		m.size += len(v) - len(old)
+	} else {
+		m.size += mapMutationItemOverhead + len(k) + len(v)
+		m.count++
 	}
 	m.puts[table][stringKey] = v
-	m.size += len(k) + len(v)
-	m.count++
+	if m.autoFlush > 0 && m.size >= m.autoFlush && m.db != nil {
+		batchAutoFlushes.Inc()
+		return m.flush()
+	}
 	return nil
 }
 
+// SetAutoFlush makes Put write pending writes into the transaction once they take threshold bytes of memory,
+// so that a batch whose transaction can't be committed yet doesn't grow without bound. 0 - disabled (default).
+func (m *mapmutation) SetAutoFlush(threshold int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.autoFlush = threshold
+}
+
 func (m *mapmutation) Append(table string, key []byte, value []byte) error {
 	return m.Put(table, key, value)
 }
@@ -246,14 +258,23 @@ func (m *mapmutation) Commit() error {
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if err := m.doCommit(m.db); err != nil {
+	if err := m.flush(); err != nil {
 		return err
 	}
+	m.clean()
+	return nil
+}
 
+// flush writes pending writes into the transaction, the caller holds the lock
+func (m *mapmutation) flush() error {
+	start := time.Now()
+	if err := m.doCommit(m.db); err != nil {
+		return err
+	}
+	recordBatchCommit(m.size, start)
 	m.puts = map[string]map[string][]byte{}
 	m.size = 0
 	m.count = 0
-	m.clean()
 	return nil
 }
 
@@ -8,7 +8,6 @@ import (
 	"strings"
 	"sync"
 	"time"
-	"unsafe"
 
 	"github.com/google/btree"
 	"github.com/ledgerwatch/erigon-lib/common"
@@ -24,7 +23,8 @@ type mutation struct {
 	clean      func()
 	searchItem MutationItem
 	mu         sync.RWMutex
-	size       int
+	size       int // approximate memory of pending writes
+	autoFlush  int // see SetAutoFlush
 }
 
 type MutationItem struct {
@@ -192,14 +192,26 @@ func (m *mutation) Put(table string, k, v []byte) error {
 
 	newMi := &MutationItem{table: table, key: k, value: v}
 	i := m.puts.ReplaceOrInsert(newMi)
-	m.size += int(unsafe.Sizeof(newMi)) + len(k) + len(v)
+	m.size += mutationItemOverhead + len(k) + len(v)
 	if i != nil {
 		oldMi := i.(*MutationItem)
-		m.size -= int(unsafe.Sizeof(oldMi)) + len(oldMi.key) + len(oldMi.value)
+		m.size -= mutationItemOverhead + len(oldMi.key) + len(oldMi.value)
+	}
+	if m.autoFlush > 0 && m.size >= m.autoFlush && m.db != nil {
+		batchAutoFlushes.Inc()
+		return m.flush()
 	}
 	return nil
 }
 
+// SetAutoFlush makes Put write pending writes into the transaction once they take threshold bytes of memory,
+// so that a batch whose transaction can't be committed yet doesn't grow without bound. 0 - disabled (default).
+func (m *mutation) SetAutoFlush(threshold int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.autoFlush = threshold
+}
+
 func (m *mutation) Append(table string, key []byte, value []byte) error {
 	return m.Put(table, key, value)
 }
@@ -307,13 +319,22 @@ func (m *mutation) Commit() error {
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if err := m.doCommit(m.db); err != nil {
+	if err := m.flush(); err != nil {
 		return err
 	}
+	m.clean()
+	return nil
+}
 
+// flush writes pending writes into the transaction, the caller holds the lock
+func (m *mutation) flush() error {
+	start := time.Now()
+	if err := m.doCommit(m.db); err != nil {
+		return err
+	}
+	recordBatchCommit(m.size, start)
 	m.puts.Clear(false /* addNodesToFreelist */)
 	m.size = 0
-	m.clean()
 	return nil
 }
 
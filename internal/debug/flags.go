@@ -119,7 +119,7 @@ func SetupCobra(cmd *cobra.Command) error {
 		_, glogger = log.SetupDefaultTerminalLogger(log.Lvl(lvl), vmodule, backtrace)
 		log.PrintOrigins(dbg)
 	*/
-	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(lvl), log.StderrHandler))
+	setupLogger(log.Lvl(lvl), log.StderrHandler)
 
 	traceFile, err := flags.GetString(traceFlag.Name)
 	if err != nil {
@@ -185,10 +185,10 @@ func Setup(ctx *cli.Context) error {
 	//var ostream log.Handler
 	//output := io.Writer(os.Stderr)
 	if ctx.Bool(logjsonFlag.Name) {
-		log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(ctx.Int(verbosityFlag.Name)), log.StreamHandler(os.Stderr, log.JsonFormat())))
+		setupLogger(log.Lvl(ctx.Int(verbosityFlag.Name)), log.StreamHandler(os.Stderr, log.JsonFormat()))
 		//ostream = log.StreamHandler(output, log.JsonFormat())
 	} else {
-		log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(ctx.Int(verbosityFlag.Name)), log.StderrHandler))
+		setupLogger(log.Lvl(ctx.Int(verbosityFlag.Name)), log.StderrHandler)
 	}
 	//log.Root().SetHandler(ostream)
 
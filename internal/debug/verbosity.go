@@ -0,0 +1,35 @@
+package debug
+
+import (
+	"sync"
+
+	"github.com/ledgerwatch/log/v3"
+)
+
+var (
+	loggerLock sync.Mutex
+	logOutput  log.Handler = log.StderrHandler // handler of the root logger, without level filter
+	verbosity              = log.LvlInfo
+)
+
+func setupLogger(lvl log.Lvl, output log.Handler) {
+	loggerLock.Lock()
+	logOutput = output
+	loggerLock.Unlock()
+	SetVerbosity(lvl)
+}
+
+// SetVerbosity changes the level of the root logger at runtime, keeping its output
+func SetVerbosity(lvl log.Lvl) {
+	loggerLock.Lock()
+	defer loggerLock.Unlock()
+	verbosity = lvl
+	log.Root().SetHandler(log.LvlFilterHandler(lvl, logOutput))
+}
+
+// Verbosity returns the current level of the root logger
+func Verbosity() log.Lvl {
+	loggerLock.Lock()
+	defer loggerLock.Unlock()
+	return verbosity
+}
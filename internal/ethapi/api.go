@@ -29,6 +29,7 @@ import (
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/log/v3"
 )
 
@@ -182,7 +183,7 @@ type RevertError struct {
 // ErrorCode returns the JSON error code for a revertal.
 // See: https://github.com/ethereum/wiki/wiki/JSON-RPC-Error-Codes-Improvement-Proposal
 func (e *RevertError) ErrorCode() int {
-	return 3
+	return rpc.ErrCodeReverted
 }
 
 // ErrorData returns the hex encoded revert reason.
@@ -253,9 +254,13 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 
 // RPCMarshalHeader converts the given header to the RPC output .
 func RPCMarshalHeader(head *types.Header) map[string]interface{} {
+	return rpcMarshalHeader(head, head.Hash())
+}
+
+func rpcMarshalHeader(head *types.Header, hash common.Hash) map[string]interface{} {
 	result := map[string]interface{}{
 		"number":           (*hexutil.Big)(head.Number),
-		"hash":             head.Hash(),
+		"hash":             hash,
 		"parentHash":       head.ParentHash,
 		"nonce":            head.Nonce,
 		"mixHash":          head.MixDigest,
@@ -287,22 +292,23 @@ func RPCMarshalBlock(block *types.Block, inclTx bool, fullTx bool) (map[string]i
 }
 
 func RPCMarshalBlockEx(block *types.Block, inclTx bool, fullTx bool, borTx types.Transaction, borTxHash common.Hash) (map[string]interface{}, error) {
-	fields := RPCMarshalHeader(block.Header())
+	fields := rpcMarshalHeader(block.Header(), block.Hash()) // the hash is cached by the block
 	fields["size"] = hexutil.Uint64(block.Size())
 	if _, ok := fields["transactions"]; !ok {
 		fields["transactions"] = make([]interface{}, 0)
 	}
 
 	if inclTx {
+		txs := block.Transactions()
+		hashes := txs.Hashes()
 		formatTx := func(tx types.Transaction, index int) (interface{}, error) {
-			return tx.Hash(), nil
+			return hashes[index], nil
 		}
 		if fullTx {
 			formatTx = func(tx types.Transaction, index int) (interface{}, error) {
 				return newRPCTransactionFromBlockAndTxGivenIndex(block, tx, uint64(index)), nil
 			}
 		}
-		txs := block.Transactions()
 		transactions := make([]interface{}, len(txs), len(txs)+1)
 		var err error
 		for i, tx := range txs {
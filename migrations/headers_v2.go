@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Suffixes of the records which early versions of the database kept in kv.Headers next to full headers
+const (
+	legacyHeaderTDSuffix        = 't' // block number + hash + 't' -> RLP-encoded total difficulty
+	legacyHeaderCanonicalSuffix = 'n' // block number + 'n' -> canonical hash
+)
+
+// headersV2 moves total difficulty and canonical hashes left in kv.Headers by early versions of the database into
+// kv.HeaderTD and kv.HeaderCanonical: afterwards kv.Headers has only full headers (number + hash keys), verification
+// of headers reads TD from its own table and headers are packed into snapshots without filtering. Values already in
+// the new tables win, records are moved only if missing.
+var headersV2 = Migration{
+	Name: "headers_v2",
+	Up: func(db kv.RwDB, dirs datadir.Dirs, progress []byte, BeforeCommit Callback) (err error) {
+		logEvery := time.NewTicker(30 * time.Second)
+		defer logEvery.Stop()
+
+		tx, err := db.BeginRw(context.Background())
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		c, err := tx.RwCursor(kv.Headers)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		var moved, dropped int
+		for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			var table string
+			var newKey []byte
+			switch {
+			case len(k) == 8+common.HashLength:
+				continue
+			case len(k) == 8+common.HashLength+1 && k[len(k)-1] == legacyHeaderTDSuffix:
+				table, newKey = kv.HeaderTD, k[:len(k)-1]
+			case len(k) == 8+1 && k[len(k)-1] == legacyHeaderCanonicalSuffix:
+				table, newKey = kv.HeaderCanonical, k[:len(k)-1]
+			}
+			if table != "" {
+				existing, err := tx.GetOne(table, newKey)
+				if err != nil {
+					return err
+				}
+				if existing == nil {
+					if err = tx.Put(table, common.CopyBytes(newKey), common.CopyBytes(v)); err != nil {
+						return err
+					}
+					moved++
+				}
+			}
+			// unknown records aren't read by anything
+			if err = c.DeleteCurrent(); err != nil {
+				return err
+			}
+			dropped++
+			select {
+			default:
+			case <-logEvery.C:
+				log.Info("[database version migration] Moving legacy header records", "block", binary.BigEndian.Uint64(k[:8]), "moved", moved)
+			}
+		}
+		if dropped > 0 {
+			log.Info("[database version migration] Legacy header records moved", "moved", moved, "removed", dropped)
+		}
+		if err := BeforeCommit(tx, nil, true); err != nil {
+			return err
+		}
+		return tx.Commit()
+	},
+}
@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadersV2(t *testing.T) {
+	require, tmpDir, db := require.New(t), t.TempDir(), memdb.NewTestDB(t)
+	header1 := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(7)}
+	header2 := &types.Header{Number: big.NewInt(2), Difficulty: big.NewInt(7)}
+	legacyTD := func(n uint64, hash common.Hash) []byte {
+		return append(dbutils.HeaderKey(n, hash), legacyHeaderTDSuffix)
+	}
+	legacyCanonical := func(n uint64) []byte {
+		return append(dbutils.EncodeBlockNumber(n), legacyHeaderCanonicalSuffix)
+	}
+	require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+		rawdb.WriteHeader(tx, header1)
+		rawdb.WriteHeader(tx, header2)
+		td, _ := rlp.EncodeToBytes(big.NewInt(8))
+		require.NoError(tx.Put(kv.Headers, legacyTD(1, header1.Hash()), td))
+		require.NoError(tx.Put(kv.Headers, legacyCanonical(1), header1.Hash().Bytes()))
+		// already in the new tables: kept
+		require.NoError(rawdb.WriteTd(tx, header2.Hash(), 2, big.NewInt(15)))
+		require.NoError(tx.Put(kv.Headers, legacyTD(2, header2.Hash()), td))
+		return nil
+	}))
+
+	migrator := NewMigrator(kv.ChainDB)
+	migrator.Migrations = []Migration{headersV2}
+	require.NoError(migrator.Apply(db, tmpDir))
+
+	require.NoError(db.View(context.Background(), func(tx kv.Tx) error {
+		td, err := rawdb.ReadTd(tx, header1.Hash(), 1)
+		require.NoError(err)
+		require.Equal(big.NewInt(8), td)
+		td, err = rawdb.ReadTd(tx, header2.Hash(), 2)
+		require.NoError(err)
+		require.Equal(big.NewInt(15), td)
+		hash, err := rawdb.ReadCanonicalHash(tx, 1)
+		require.NoError(err)
+		require.Equal(header1.Hash(), hash)
+
+		var keys int
+		require.NoError(tx.ForEach(kv.Headers, nil, func(k, v []byte) error {
+			require.Len(k, 8+common.HashLength)
+			keys++
+			return nil
+		}))
+		require.Equal(2, keys)
+		require.NotNil(rawdb.ReadHeader(tx, header1.Hash(), 1))
+		return nil
+	}))
+
+	// applying again is a no-op
+	require.NoError(migrator.Apply(db, tmpDir))
+}
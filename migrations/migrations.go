@@ -35,6 +35,9 @@ var migrations = map[kv.Label][]Migration{
 		dbSchemaVersion5,
 		txsBeginEnd,
 		resetBlocks4,
+		txsCompression,
+		headersV2,
+		resetIssuance,
 	},
 	kv.TxPoolDB: {},
 	kv.SentryDB: {},
@@ -254,6 +257,43 @@ func (m *Migrator) Apply(db kv.RwDB, dataDir string) error {
 	return nil
 }
 
+// VerifyApplied - checks that all migrations are applied completely (no progress of interrupted migrations left)
+// and DB schema version is the current one
+func (m *Migrator) VerifyApplied(db kv.RoDB) error {
+	return db.View(context.Background(), func(tx kv.Tx) error {
+		pending, err := m.PendingMigrations(tx)
+		if err != nil {
+			return err
+		}
+		for _, v := range pending {
+			progress, err := tx.GetOne(kv.Migrations, []byte("_progress_"+v.Name))
+			if err != nil {
+				return err
+			}
+			if len(progress) > 0 {
+				return fmt.Errorf("migration %s was interrupted", v.Name)
+			}
+			return fmt.Errorf("migration %s is not applied", v.Name)
+		}
+		if len(m.Migrations) == 0 {
+			return nil
+		}
+		version, err := tx.GetOne(kv.DatabaseInfo, kv.DBSchemaVersionKey)
+		if err != nil {
+			return fmt.Errorf("reading DB schema version: %w", err)
+		}
+		if len(version) != 12 {
+			return fmt.Errorf("DB schema version is not written")
+		}
+		major, minor, patch := binary.BigEndian.Uint32(version), binary.BigEndian.Uint32(version[4:]), binary.BigEndian.Uint32(version[8:])
+		// versions differing only in the patch are the same schema, see the compatibility check of rpcdaemon
+		if major != kv.DBSchemaVersion.Major || minor != kv.DBSchemaVersion.Minor {
+			return fmt.Errorf("DB schema version is %d.%d.%d, expected %d.%d.%d", major, minor, patch, kv.DBSchemaVersion.Major, kv.DBSchemaVersion.Minor, kv.DBSchemaVersion.Patch)
+		}
+		return nil
+	})
+}
+
 func MarshalMigrationPayload(db kv.Getter) ([]byte, error) {
 	s := map[string][]byte{}
 
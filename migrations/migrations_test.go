@@ -2,6 +2,7 @@ package migrations
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"testing"
 
@@ -296,3 +297,57 @@ func TestCommitCallRequired(t *testing.T) {
 	})
 	require.NoError(err)
 }
+
+func TestVerifyApplied(t *testing.T) {
+	require, db := require.New(t), memdb.NewTestDB(t)
+	fail := true
+	m := []Migration{
+		{
+			Name: "one",
+			Up: func(db kv.RwDB, dirs datadir.Dirs, progress []byte, BeforeCommit Callback) (err error) {
+				tx, err := db.BeginRw(context.Background())
+				if err != nil {
+					return err
+				}
+				defer tx.Rollback()
+				if fail {
+					if err := BeforeCommit(tx, []byte{1}, false); err != nil {
+						return err
+					}
+					if err := tx.Commit(); err != nil {
+						return err
+					}
+					return errors.New("interrupted")
+				}
+				if err := BeforeCommit(tx, nil, true); err != nil {
+					return err
+				}
+				return tx.Commit()
+			},
+		},
+	}
+	migrator := NewMigrator(kv.ChainDB)
+	migrator.Migrations = m
+	require.EqualError(migrator.VerifyApplied(db), "migration one is not applied")
+
+	require.Error(migrator.Apply(db, ""))
+	require.EqualError(migrator.VerifyApplied(db), "migration one was interrupted")
+
+	fail = false
+	require.NoError(migrator.Apply(db, ""))
+	require.NoError(migrator.VerifyApplied(db))
+
+	writeVersion := func(major, minor, patch uint32) {
+		var version [12]byte
+		binary.BigEndian.PutUint32(version[:], major)
+		binary.BigEndian.PutUint32(version[4:], minor)
+		binary.BigEndian.PutUint32(version[8:], patch)
+		require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+			return tx.Put(kv.DatabaseInfo, kv.DBSchemaVersionKey, version[:])
+		}))
+	}
+	writeVersion(kv.DBSchemaVersion.Major, kv.DBSchemaVersion.Minor, kv.DBSchemaVersion.Patch+1)
+	require.NoError(migrator.VerifyApplied(db))
+	writeVersion(kv.DBSchemaVersion.Major, kv.DBSchemaVersion.Minor+1, kv.DBSchemaVersion.Patch)
+	require.ErrorContains(migrator.VerifyApplied(db), "DB schema version is")
+}
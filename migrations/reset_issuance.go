@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/rawdb/rawdbreset"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+)
+
+// resetIssuance clears totals of the Issuance stage: they were accumulated with a block reward of proof-of-stake
+// blocks, which mint nothing on the execution layer. The stage recomputes them from the genesis, whose total loses
+// the reward too if the genesis is a proof-of-stake block.
+var resetIssuance = Migration{
+	Name: "reset_issuance",
+	Up: func(db kv.RwDB, dirs datadir.Dirs, progress []byte, BeforeCommit Callback) (err error) {
+		tx, err := db.BeginRw(context.Background())
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := rawdbreset.ResetIssuance(tx); err != nil {
+			return err
+		}
+		genesis := rawdb.ReadHeaderByNumber(tx, 0)
+		if genesis != nil && genesis.Difficulty.Cmp(serenity.SerenityDifficulty) == 0 {
+			issued, err := rawdb.ReadTotalIssued(tx, 0)
+			if err != nil {
+				return err
+			}
+			if issued.Cmp(serenity.RewardSerenity) >= 0 {
+				if err := rawdb.WriteTotalIssued(tx, 0, issued.Sub(issued, serenity.RewardSerenity)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := BeforeCommit(tx, nil, true); err != nil {
+			return err
+		}
+		return tx.Commit()
+	},
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/consensus/serenity"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetIssuance(t *testing.T) {
+	require, tmpDir, db := require.New(t), t.TempDir(), memdb.NewTestDB(t)
+	alloc := big.NewInt(1000)
+	require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+		// proof-of-stake genesis, its total has the reward of the old rule
+		genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(0)}
+		rawdb.WriteHeader(tx, genesis)
+		require.NoError(rawdb.WriteCanonicalHash(tx, genesis.Hash(), 0))
+		require.NoError(rawdb.WriteTotalIssued(tx, 0, new(big.Int).Add(alloc, serenity.RewardSerenity)))
+		require.NoError(rawdb.WriteTotalBurnt(tx, 0, big.NewInt(0)))
+		for n := uint64(1); n <= 2; n++ {
+			require.NoError(rawdb.WriteTotalIssued(tx, n, new(big.Int).Add(alloc, new(big.Int).Mul(serenity.RewardSerenity, big.NewInt(int64(n+1))))))
+			require.NoError(rawdb.WriteTotalBurnt(tx, n, big.NewInt(int64(n))))
+		}
+		require.NoError(stages.SaveStageProgress(tx, stages.Issuance, 2))
+		return nil
+	}))
+
+	migrator := NewMigrator(kv.ChainDB)
+	migrator.Migrations = []Migration{resetIssuance}
+	require.NoError(migrator.Apply(db, tmpDir))
+
+	require.NoError(db.View(context.Background(), func(tx kv.Tx) error {
+		progress, err := stages.GetStageProgress(tx, stages.Issuance)
+		require.NoError(err)
+		require.Zero(progress)
+		issued, err := rawdb.ReadTotalIssued(tx, 0)
+		require.NoError(err)
+		require.Equal(alloc, issued)
+
+		var keys int
+		require.NoError(tx.ForEach(kv.Issuance, nil, func(k, v []byte) error {
+			keys++
+			return nil
+		}))
+		require.Equal(2, keys) // issued and burnt of the genesis
+		return nil
+	}))
+}
@@ -0,0 +1,121 @@
+package migrations
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const txsCompressionBatch = 1_000_000
+
+// txsCompression trains the zstd dictionary of transactions on samples of kv.EthTx and compresses the stored
+// transactions with it. DBs with too few transactions to train on (new nodes) get the dictionary later from the Senders
+// stage and keep the transactions stored before it uncompressed.
+var txsCompression = Migration{
+	Name: "txs_zstd_compression",
+	Up: func(db kv.RwDB, dirs datadir.Dirs, progress []byte, BeforeCommit Callback) (err error) {
+		logEvery := time.NewTicker(30 * time.Second)
+		defer logEvery.Stop()
+
+		tx, err := db.BeginRw(context.Background())
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		trained, err := rawdb.TrainTxsDictIfMissing(tx)
+		if err != nil {
+			return err
+		}
+		if trained {
+			log.Info("[database version migration] Trained txs dictionary")
+		}
+		dict, err := tx.GetOne(kv.DatabaseInfo, rawdb.TxsDictKey)
+		if err != nil {
+			return err
+		}
+		if len(dict) == 0 {
+			// nothing to compress with, the Senders stage trains the dictionary once the DB has enough transactions
+			if err := BeforeCommit(tx, nil, true); err != nil {
+				return err
+			}
+			return tx.Commit()
+		}
+
+		// progress - table index and the next txn id
+		table, from := 0, uint64(0)
+		if progress != nil {
+			table, from = int(progress[0]), binary.BigEndian.Uint64(progress[1:])
+			log.Info("[database version migration] Continue migration", "table", table, "from_txn", from)
+		}
+		tables := []string{kv.EthTx, kv.NonCanonicalTxs}
+		for ; table < len(tables); table, from = table+1, 0 {
+			for {
+				next, err := compressTxs(tx, tables[table], from, txsCompressionBatch, logEvery)
+				if err != nil {
+					return err
+				}
+				if next == from {
+					break
+				}
+				from = next
+				if err := BeforeCommit(tx, append([]byte{byte(table)}, dbutils.EncodeBlockNumber(from)...), false); err != nil {
+					return err
+				}
+				if err := tx.Commit(); err != nil {
+					return err
+				}
+				if tx, err = db.BeginRw(context.Background()); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := BeforeCommit(tx, nil, true); err != nil {
+			return err
+		}
+		return tx.Commit()
+	},
+}
+
+// compressTxs compresses up to limit transactions of the table from the txn id, returns the id to continue from
+func compressTxs(tx kv.RwTx, table string, from uint64, limit int, logEvery *time.Ticker) (uint64, error) {
+	c, err := tx.RwCursor(table)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	next := from
+	for k, v, err := c.Seek(dbutils.EncodeBlockNumber(from)); k != nil && limit > 0; k, v, err = c.Next() {
+		if err != nil {
+			return 0, err
+		}
+		next, limit = binary.BigEndian.Uint64(k)+1, limit-1
+		select {
+		case <-logEvery.C:
+			log.Info("[database version migration] Compressing txs", "table", table, "txn", next-1)
+		default:
+		}
+
+		compressed, err := rawdb.CompressTxn(tx, v)
+		if err != nil {
+			return 0, fmt.Errorf("%s %d: %w", table, next-1, err)
+		}
+		if len(compressed) == len(v) {
+			continue
+		}
+		if err = c.Put(common.CopyBytes(k), compressed); err != nil {
+			return 0, err
+		}
+	}
+	return next, nil
+}
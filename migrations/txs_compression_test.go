@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common/u256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxsCompression(t *testing.T) {
+	require, tmpDir, db := require.New(t), t.TempDir(), memdb.NewTestDB(t)
+	var txs [][]byte
+	for i := 0; i < 2*rawdb.TxsDictMinSamples; i++ {
+		to := common.Address{byte(i % 7)}
+		txn := types.NewTransaction(uint64(i), to, u256.N1, 21_000, u256.N1, common.LeftPadBytes([]byte{byte(i)}, 32))
+		buf := bytes.NewBuffer(nil)
+		require.NoError(txn.MarshalBinary(buf))
+		txs = append(txs, buf.Bytes())
+	}
+	require.NoError(db.Update(context.Background(), func(tx kv.RwTx) error {
+		if _, err := tx.IncrementSequence(kv.EthTx, uint64(len(txs))); err != nil {
+			return err
+		}
+		return rawdb.WriteRawTransactions(tx, txs, 0)
+	}))
+
+	migrator := NewMigrator(kv.ChainDB)
+	migrator.Migrations = []Migration{txsCompression}
+	require.NoError(migrator.Apply(db, tmpDir))
+
+	require.NoError(db.View(context.Background(), func(tx kv.Tx) error {
+		dict, err := tx.GetOne(kv.DatabaseInfo, rawdb.TxsDictKey)
+		require.NoError(err)
+		require.NotEmpty(dict)
+		for i, txn := range txs {
+			v, err := tx.GetOne(kv.EthTx, dbutils.EncodeBlockNumber(uint64(i)))
+			require.NoError(err)
+			require.Equal(rawdb.TxCompressedV1, v[0])
+			v, err = rawdb.DecompressTxn(tx, v, nil)
+			require.NoError(err)
+			require.Equal(txn, v)
+		}
+		return nil
+	}))
+}
+
+func TestTxsCompressionFewTxs(t *testing.T) {
+	require, tmpDir, db := require.New(t), t.TempDir(), memdb.NewTestDB(t)
+	migrator := NewMigrator(kv.ChainDB)
+	migrator.Migrations = []Migration{txsCompression}
+	require.NoError(migrator.Apply(db, tmpDir))
+
+	require.NoError(db.View(context.Background(), func(tx kv.Tx) error {
+		dict, err := tx.GetOne(kv.DatabaseInfo, rawdb.TxsDictKey)
+		require.NoError(err)
+		require.Empty(dict)
+		return nil
+	}))
+}
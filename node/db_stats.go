@@ -0,0 +1,98 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+	mdbx2 "github.com/torquem-ch/mdbx-go/mdbx"
+)
+
+// Commit latency of the chaindata is reported by erigon-lib as db_commit_seconds.
+// The gauges below complement it with the state of the environment itself, page
+// faults of the process only grow and are counters.
+var (
+	dbMapSize     = newSampledGauge(`db_map_size`)
+	dbGrowthStep  = newSampledGauge(`db_growth_step`)
+	dbLastPageNo  = newSampledGauge(`db_last_pgno`)
+	dbReaders     = newSampledGauge(`db_readers`)
+	dbSinceSync   = newSampledGauge(`db_since_sync_seconds`)
+	dbMinorFaults = metrics.GetOrCreateCounter(`process_page_faults{type="minor"}`)
+	dbMajorFaults = metrics.GetOrCreateCounter(`process_page_faults{type="major"}`)
+)
+
+// sampledGauge - the last value sampled by CollectDBStats, exported as a gauge
+type sampledGauge struct{ v uint64 }
+
+func newSampledGauge(name string) *sampledGauge {
+	g := &sampledGauge{}
+	metrics.GetOrCreateGauge(name, func() float64 { return float64(atomic.LoadUint64(&g.v)) })
+	return g
+}
+
+func (g *sampledGauge) Set(v uint64) { atomic.StoreUint64(&g.v, v) }
+
+// CollectDBStats periodically reports geometry, readers and sync lag of an mdbx
+// database together with page faults of the process, until ctx is cancelled.
+// Databases of other kinds are ignored, wrappers are unwrapped.
+func CollectDBStats(ctx context.Context, db kv.RoDB, every time.Duration) {
+	env := mdbxEnv(db)
+	if env == nil {
+		return
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := env.Info(nil)
+			if err != nil {
+				log.Warn("[db] failed to read env info", "err", err)
+				continue
+			}
+			dbMapSize.Set(uint64(info.MapSize))
+			dbGrowthStep.Set(info.Geo.Grow)
+			dbLastPageNo.Set(uint64(info.LastPNO))
+			dbReaders.Set(uint64(info.NumReaders))
+			dbSinceSync.Set(uint64(info.SinceSync.Seconds()))
+			minor, major := pageFaults()
+			dbMinorFaults.Set(minor)
+			dbMajorFaults.Set(major)
+		}
+	}
+}
+
+func mdbxEnv(db kv.RoDB) *mdbx2.Env {
+	if wrapper, ok := db.(interface{ Unwrap() kv.RwDB }); ok {
+		db = wrapper.Unwrap()
+	}
+	mdbxDB, ok := db.(*mdbx.MdbxKV)
+	if !ok {
+		return nil
+	}
+	return mdbxDB.Env()
+}
+
+// WriteDBInfo writes the state of an mdbx database environment in human-readable form, for diagnostics dumps
+func WriteDBInfo(w io.Writer, db kv.RoDB) error {
+	env := mdbxEnv(db)
+	if env == nil {
+		_, err := fmt.Fprintf(w, "not an mdbx database\n")
+		return err
+	}
+	info, err := env.Info(nil)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "readers: %d of %d\nlast txn id: %d\nsince sync: %s\nsince reader check: %s\nmap size: %d\nlast pgno: %d\n",
+		info.NumReaders, info.MaxReaders, info.LastTxnID, info.SinceSync, info.SinceReaderCheck, info.MapSize, info.LastPNO)
+	return err
+}
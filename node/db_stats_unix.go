@@ -0,0 +1,14 @@
+//go:build !windows
+
+package node
+
+import "syscall"
+
+// pageFaults returns the minor and major page faults of the process so far.
+func pageFaults() (minor, major uint64) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, 0
+	}
+	return uint64(ru.Minflt), uint64(ru.Majflt)
+}
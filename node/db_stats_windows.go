@@ -0,0 +1,4 @@
+package node
+
+// pageFaults is not implemented on windows.
+func pageFaults() (minor, major uint64) { return 0, 0 }
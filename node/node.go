@@ -36,9 +36,11 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/migrations"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/log/v3"
+	mdbx2 "github.com/torquem-ch/mdbx-go/mdbx"
 )
 
 // Node is a container on which services can be registered.
@@ -46,6 +48,7 @@ type Node struct {
 	config        *nodecfg.Config
 	log           log.Logger
 	dirLock       *flock.Flock  // prevents concurrent use of instance directory
+	dirtyShutdown bool          // previous run didn't remove the running marker
 	stop          chan struct{} // Channel to wait for termination notifications
 	server        *p2p.Server   // Currently running P2P networking layer
 	startStopLock sync.Mutex    // Start/Stop are protected by an additional lock
@@ -57,6 +60,9 @@ type Node struct {
 	databases []kv.Closer
 }
 
+// runningMarker is the file in the datadir which exists only while the node is running
+const runningMarker = "RUNNING"
+
 const (
 	initializingState = iota
 	runningState
@@ -248,12 +254,24 @@ func (n *Node) openDataDir() error {
 		return fmt.Errorf("%w: %s", ErrDataDirUsed, instdir)
 	}
 	n.dirLock = l
-	return nil
+
+	// The marker lives while the node is running: if it's already there - previous run crashed or was killed
+	marker := filepath.Join(instdir, runningMarker)
+	if _, err := os.Stat(marker); err == nil {
+		n.dirtyShutdown = true
+		n.log.Warn("Previous run was not shut down cleanly", "datadir", instdir)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(marker, nil, 0600)
 }
 
 func (n *Node) closeDataDir() {
 	// Release instance directory lock.
 	if n.dirLock != nil {
+		if err := os.Remove(filepath.Join(n.config.Dirs.DataDir, runningMarker)); err != nil && !os.IsNotExist(err) {
+			n.log.Error("Can't remove running marker", "err", err)
+		}
 		if err := n.dirLock.Unlock(); err != nil {
 			n.log.Error("Can't release datadir lock", "err", err)
 		}
@@ -261,6 +279,12 @@ func (n *Node) closeDataDir() {
 	}
 }
 
+// DirtyShutdown reports whether the previous run on the same datadir was not shut down cleanly,
+// so its databases may have lost or half-written data of the last blocks.
+func (n *Node) DirtyShutdown() bool {
+	return n.dirtyShutdown
+}
+
 // Wait blocks until the node is closed.
 func (n *Node) Wait() {
 	<-n.stop
@@ -312,6 +336,9 @@ func OpenDatabase(config *nodecfg.Config, logger log.Logger, label kv.Label) (kv
 	}
 	var db kv.RwDB
 	if config.Dirs.DataDir == "" {
+		if label == kv.ChainDB {
+			return rawdb.NewMemDB(), nil
+		}
 		db = memdb.New()
 		return db, nil
 	}
@@ -332,11 +359,21 @@ func OpenDatabase(config *nodecfg.Config, logger log.Logger, label kv.Label) (kv
 			opts = opts.Exclusive()
 		}
 		if label == kv.ChainDB {
-			opts = opts.PageSize(config.MdbxPageSize.Bytes()).MapSize(8 * datasize.TB)
+			opts = chainDBOpts(config, opts)
 		} else {
 			opts = opts.GrowthStep(16 * datasize.MB)
 		}
-		return opts.Open()
+		db, err := opts.Open()
+		if err != nil {
+			return nil, err
+		}
+		if label == kv.ChainDB && config.MdbxDirtyPagesLimit > 0 {
+			if err = db.(*mdbx.MdbxKV).Env().SetOption(mdbx2.OptTxnDpLimit, config.MdbxDirtyPagesLimit); err != nil {
+				db.Close()
+				return nil, fmt.Errorf("setting dirty pages limit: %w", err)
+			}
+		}
+		return db, nil
 	}
 	var err error
 	db, err = openFunc(false)
@@ -378,6 +415,38 @@ func OpenDatabase(config *nodecfg.Config, logger log.Logger, label kv.Label) (kv
 	return db, nil
 }
 
+// chainDBOpts applies the geometry and durability settings of the config to the chaindata options.
+func chainDBOpts(config *nodecfg.Config, opts mdbx.MdbxOpts) mdbx.MdbxOpts {
+	opts = opts.PageSize(config.MdbxPageSize.Bytes()).WithTableCfg(rawdb.ChaindataTablesCfg)
+	if config.MdbxDBSizeLimit > 0 {
+		opts = opts.MapSize(config.MdbxDBSizeLimit)
+	} else {
+		opts = opts.MapSize(8 * datasize.TB)
+	}
+	if config.MdbxGrowthStep > 0 {
+		opts = opts.GrowthStep(config.MdbxGrowthStep)
+	}
+	opts = opts.Flags(func(f uint) uint {
+		f &^= mdbx2.Durable | mdbx2.SafeNoSync | mdbx2.UtterlyNoSync
+		switch config.MdbxSyncMode {
+		case nodecfg.MdbxSyncSafeNoSync:
+			f |= mdbx2.SafeNoSync
+		case nodecfg.MdbxSyncUtterlyNoSync:
+			f |= mdbx2.UtterlyNoSync
+		default:
+			f |= mdbx2.Durable
+		}
+		if config.MdbxReadahead {
+			f &^= mdbx2.NoReadahead
+		}
+		return f
+	})
+	if config.MdbxSyncPeriod > 0 && config.MdbxSyncMode != nodecfg.MdbxSyncDurable {
+		opts = opts.SyncPeriod(config.MdbxSyncPeriod)
+	}
+	return opts
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
@@ -23,13 +23,16 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/node/nodecfg"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/stretchr/testify/require"
+	mdbx2 "github.com/torquem-ch/mdbx-go/mdbx"
 )
 
 var (
@@ -230,6 +233,39 @@ func TestNodeOpenDatabaseFromLifecycleStop(t *testing.T) {
 	stack.Close()
 }
 
+// This test checks that the mdbx settings of the config are applied to chaindata.
+func TestOpenDatabaseMdbxConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+
+	cfg := testNodeConfig(t)
+	cfg.MdbxDBSizeLimit = 64 * datasize.MB
+	cfg.MdbxGrowthStep = 4 * datasize.MB
+	cfg.MdbxSyncMode = nodecfg.MdbxSyncSafeNoSync
+	cfg.MdbxReadahead = true
+	cfg.MdbxDirtyPagesLimit = 1024
+
+	db, err := OpenDatabase(cfg, log.New(), kv.ChainDB)
+	require.NoError(t, err)
+	defer db.Close()
+
+	env := db.(*mdbx.MdbxKV).Env()
+	flags, err := env.Flags()
+	require.NoError(t, err)
+	require.NotZero(t, flags&mdbx2.SafeNoSync)
+	require.Zero(t, flags&mdbx2.NoReadahead)
+
+	limit, err := env.GetOption(mdbx2.OptTxnDpLimit)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1024), limit)
+
+	info, err := env.Info(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(64*datasize.MB), info.Geo.Upper)
+	require.Equal(t, uint64(4*datasize.MB), info.Geo.Grow)
+}
+
 // Tests that registered Lifecycles get started and stopped correctly.
 func TestLifecycleLifeCycle(t *testing.T) {
 	stack, _ := New(testNodeConfig(t))
@@ -417,3 +453,27 @@ func containsProtocol(stackProtocols []p2p.Protocol, protocol p2p.Protocol) bool
 	}
 	return false
 }
+
+func TestNodeDirtyShutdown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	config := testNodeConfig(t)
+
+	stack, err := New(config)
+	require.NoError(t, err)
+	require.False(t, stack.DirtyShutdown())
+	require.NoError(t, stack.Close())
+
+	// clean shutdown removes the marker
+	stack, err = New(config)
+	require.NoError(t, err)
+	require.False(t, stack.DirtyShutdown())
+
+	// simulate a crash: the process is gone, but the marker is left behind
+	require.NoError(t, stack.dirLock.Unlock())
+	stack, err = New(config)
+	require.NoError(t, err)
+	require.True(t, stack.DirtyShutdown())
+	require.NoError(t, stack.Close())
+}
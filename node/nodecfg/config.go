@@ -23,6 +23,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -42,6 +43,13 @@ const (
 	datadirNodeDatabase = "nodes"              // Path within the datadir to store the node infos
 )
 
+// Durability modes of the chaindata, see MdbxSyncMode.
+const (
+	MdbxSyncDurable       = "durable"        // fsync on every commit
+	MdbxSyncSafeNoSync    = "safe-nosync"    // no fsync on commit, a crash may lose the last transactions but not corrupt the db
+	MdbxSyncUtterlyNoSync = "utterly-nosync" // no fsync at all, a crash of the OS may corrupt the db
+)
+
 // Config represents a small collection of configuration values to fine tune the
 // P2P network layer of a protocol stack. These values can be further extended by
 // all registered services.
@@ -161,6 +169,17 @@ type Config struct {
 	TLSCACert           string
 
 	MdbxPageSize datasize.ByteSize
+	// MdbxDBSizeLimit is the upper bound of the chaindata map size
+	MdbxDBSizeLimit datasize.ByteSize
+	// MdbxGrowthStep is the step by which the chaindata file grows
+	MdbxGrowthStep datasize.ByteSize
+	// MdbxSyncMode is one of MdbxSyncDurable, MdbxSyncSafeNoSync, MdbxSyncUtterlyNoSync
+	MdbxSyncMode string
+	// MdbxSyncPeriod is the interval of background flushes in the no-sync modes, 0 disables them
+	MdbxSyncPeriod time.Duration
+	MdbxReadahead  bool
+	// MdbxDirtyPagesLimit overrides the dirty pages limit of a write transaction, 0 keeps the mdbx default
+	MdbxDirtyPagesLimit uint64
 
 	// HealthCheck enables standard grpc health check
 	HealthCheck bool
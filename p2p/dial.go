@@ -81,6 +81,7 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errSubnetLimit      = errors.New("too many peers in the subnet")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -109,9 +110,11 @@ type dialScheduler struct {
 
 	// Everything below here belongs to loop and
 	// should only be accessed by code on the loop goroutine.
-	dialing   map[enode.ID]*dialTask // active tasks
-	peers     map[enode.ID]connFlag  // all connected peers
-	dialPeers int                    // current number of dialed peers
+	dialing        map[enode.ID]*dialTask // active tasks
+	peers          map[enode.ID]connFlag  // all connected peers
+	dialPeers      int                    // current number of dialed peers
+	subnets        map[string]int         // connected peers by Subnet, if maxSubnetPeers is set
+	dialingSubnets map[enode.ID]string    // Subnet of active dynamic dials, if maxSubnetPeers is set
 
 	// The static map tracks all static dial tasks. The subset of usable static dial tasks
 	// (i.e. those passing checkDial) is kept in staticPool. The scheduler prefers
@@ -137,6 +140,7 @@ type dialConfig struct {
 	maxDialPeers   int              // maximum number of dialed peers
 	maxActiveDials int              // maximum number of active dials
 	netRestrict    *netutil.Netlist // IP whitelist, disabled if nil
+	maxSubnetPeers int              // maximum number of connected peers of one Subnet for dynamic dials, disabled if 0
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -164,17 +168,19 @@ func (cfg dialConfig) withDefaults() dialConfig {
 
 func newDialScheduler(config dialConfig, it enode.Iterator, setupFunc dialSetupFunc, subProtocolVersion uint) *dialScheduler {
 	d := &dialScheduler{
-		dialConfig:  config.withDefaults(),
-		setupFunc:   setupFunc,
-		dialing:     make(map[enode.ID]*dialTask),
-		static:      make(map[enode.ID]*dialTask),
-		peers:       make(map[enode.ID]connFlag),
-		doneCh:      make(chan *dialTask),
-		nodesIn:     make(chan *enode.Node),
-		addStaticCh: make(chan *enode.Node),
-		remStaticCh: make(chan *enode.Node),
-		addPeerCh:   make(chan *conn),
-		remPeerCh:   make(chan *conn),
+		dialConfig:     config.withDefaults(),
+		setupFunc:      setupFunc,
+		dialing:        make(map[enode.ID]*dialTask),
+		static:         make(map[enode.ID]*dialTask),
+		peers:          make(map[enode.ID]connFlag),
+		subnets:        make(map[string]int),
+		dialingSubnets: make(map[enode.ID]string),
+		doneCh:         make(chan *dialTask),
+		nodesIn:        make(chan *enode.Node),
+		addStaticCh:    make(chan *enode.Node),
+		remStaticCh:    make(chan *enode.Node),
+		addPeerCh:      make(chan *conn),
+		remPeerCh:      make(chan *conn),
 
 		subProtocolVersion: subProtocolVersion,
 	}
@@ -253,13 +259,20 @@ loop:
 		case node := <-nodesCh:
 			if err := d.checkDial(node); err != nil {
 				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
+			} else if err := d.checkSubnet(node); err != nil {
+				subnetLimitMeter.Inc()
+				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
 			} else {
+				if d.maxSubnetPeers > 0 && node.IP() != nil {
+					d.dialingSubnets[node.ID()] = Subnet(node.IP())
+				}
 				d.startDial(newDialTask(node, dynDialedConn))
 			}
 
 		case task := <-d.doneCh:
 			id := task.dest.ID()
 			delete(d.dialing, id)
+			delete(d.dialingSubnets, id)
 			d.updateStaticPool(id)
 			d.doneSinceLastLog++
 
@@ -269,6 +282,9 @@ loop:
 			}
 			id := c.node.ID()
 			d.peers[id] = connFlag(atomic.LoadInt32((*int32)(&c.flags)))
+			if d.maxSubnetPeers > 0 && c.node.IP() != nil {
+				d.subnets[Subnet(c.node.IP())]++
+			}
 			// Remove from static pool because the node is now connected.
 			task := d.static[id]
 			if task != nil && task.staticPoolIndex >= 0 {
@@ -281,6 +297,13 @@ loop:
 				d.dialPeers--
 			}
 			delete(d.peers, c.node.ID())
+			if d.maxSubnetPeers > 0 && c.node.IP() != nil {
+				if subnet := Subnet(c.node.IP()); d.subnets[subnet] <= 1 {
+					delete(d.subnets, subnet)
+				} else {
+					d.subnets[subnet]--
+				}
+			}
 			d.updateStaticPool(c.node.ID())
 
 		case node := <-d.addStaticCh:
@@ -416,6 +439,34 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	return nil
 }
 
+// checkSubnet keeps peers of many networks: a discovered node isn't dialed if its subnet has maxSubnetPeers peers
+// (or dials in progress) already. Static dials don't check it, they are chosen by the operator.
+func (d *dialScheduler) checkSubnet(n *enode.Node) error {
+	if d.maxSubnetPeers == 0 || n.IP() == nil {
+		return nil
+	}
+	subnet := Subnet(n.IP())
+	count := d.subnets[subnet]
+	for _, s := range d.dialingSubnets {
+		if s == subnet {
+			count++
+		}
+	}
+	if count >= d.maxSubnetPeers {
+		return errSubnetLimit
+	}
+	return nil
+}
+
+// Subnet - the /24 network of an IPv4 address or the /48 network of an IPv6 address. Hosts of one subnet usually
+// belong to one operator or hosting, so it's a cheap stand-in for the AS of the address.
+func Subnet(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
 // startStaticDials starts n static dial tasks.
 func (d *dialScheduler) startStaticDials() {
 	for len(d.staticPool) > 0 {
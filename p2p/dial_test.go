@@ -152,6 +152,44 @@ func TestDialSchedNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that dynamic dials keep at most maxSubnetPeers peers of one subnet.
+func TestDialSchedSubnetLimit(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*enode.Node{
+		newNode(uintID(0x01), "127.0.1.1:30303"),
+		newNode(uintID(0x02), "127.0.1.2:30303"),
+		newNode(uintID(0x03), "127.0.1.3:30303"),
+		newNode(uintID(0x04), "127.0.2.4:30303"),
+		newNode(uintID(0x05), "127.0.2.5:30303"),
+		newNode(uintID(0x06), "127.0.3.6:30303"),
+	}
+	config := dialConfig{
+		maxActiveDials: 10,
+		maxDialPeers:   10,
+		maxSubnetPeers: 1,
+	}
+	runDialTest(t, config, []dialTestRound{
+		// 127.0.1.0/24 has a peer already, one dial per other subnet is launched.
+		{
+			peersAdded: []*conn{
+				{flags: inboundConn, node: newNode(uintID(0x10), "127.0.1.16:30303")},
+			},
+			discovered:   nodes,
+			wantNewDials: []*enode.Node{nodes[3], nodes[5]},
+		},
+		{
+			succeeded: []enode.ID{nodes[3].ID(), nodes[5].ID()},
+		},
+		// The subnet is free again when its peer disconnects.
+		{
+			peersRemoved: []enode.ID{uintID(0x10)},
+			discovered:   nodes[:3],
+			wantNewDials: []*enode.Node{nodes[0]},
+		},
+	})
+}
+
 // This test checks that static dials work and obey the limits.
 func TestDialSchedStaticDial(t *testing.T) {
 	t.Parallel()
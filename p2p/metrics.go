@@ -35,6 +35,7 @@ var (
 	egressConnectMeter  = metrics.GetOrCreateCounter("p2p_dials")
 	egressTrafficMeter  = metrics.GetOrCreateCounter(egressMeterName)
 	activePeerGauge     = metrics.GetOrCreateCounter("p2p_peers")
+	subnetLimitMeter    = metrics.GetOrCreateCounter("p2p_dials_subnet_limited") // dial candidates skipped by MaxPeersPerSubnet
 )
 
 // meteredConn is a wrapper around a net.Conn that meters both the
@@ -483,6 +483,7 @@ type PeerInfo struct {
 		Inbound       bool   `json:"inbound"`
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
+		Subnet        string `json:"subnet,omitempty"` // see Subnet
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
 }
@@ -510,6 +511,9 @@ func (p *Peer) Info() *PeerInfo {
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	if addr, ok := p.RemoteAddr().(*net.TCPAddr); ok {
+		info.Network.Subnet = Subnet(addr.IP)
+	}
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
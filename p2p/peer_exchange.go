@@ -0,0 +1,185 @@
+package p2p
+
+import (
+	"sync"
+
+	"github.com/ledgerwatch/erigon/p2p/enode"
+)
+
+const (
+	peerExchangeName    = "pex"
+	peerExchangeVersion = 1
+
+	peersMsg = 0x00
+
+	maxExchangedPeers  = 256  // limit of nodes sent to and accepted from one peer
+	peerExchangeQueued = 1024 // limit of received nodes waiting to be dialed, the oldest ones are dropped
+)
+
+// peerExchange implements the "pex" subprotocol, enabled by Config.PeerExchange. On connect, trusted peers send each
+// other enode URLs of the nodes they know to be good: dialed peers, static and trusted nodes. The received nodes are
+// dialed as the discovered ones are. It speeds up forming of the mesh of private networks, which nodes the public
+// discovery DHT doesn't know. Nodes sent by peers which aren't trusted are ignored.
+type peerExchange struct {
+	srv        *Server
+	candidates *nodeQueue
+}
+
+// setupPeerExchange adds the "pex" protocol to the protocols of the server, a new instance on every start: the
+// iterator of its dial candidates is closed on stop
+func (srv *Server) setupPeerExchange() {
+	protocols := make([]Protocol, 0, len(srv.Protocols)+1)
+	for _, p := range srv.Protocols {
+		if p.Name != peerExchangeName {
+			protocols = append(protocols, p)
+		}
+	}
+	srv.Protocols = append(protocols, newPeerExchange(srv).protocol())
+}
+
+func newPeerExchange(srv *Server) *peerExchange {
+	return &peerExchange{srv: srv, candidates: newNodeQueue(peerExchangeQueued)}
+}
+
+func (pex *peerExchange) protocol() Protocol {
+	return Protocol{
+		Name:           peerExchangeName,
+		Version:        peerExchangeVersion,
+		Length:         1,
+		Run:            pex.run,
+		DialCandidates: pex.candidates,
+	}
+}
+
+func (pex *peerExchange) run(p *Peer, rw MsgReadWriter) error {
+	trusted := p.rw.is(trustedConn)
+	if trusted {
+		if err := Send(rw, peersMsg, pex.knownPeers(p.ID())); err != nil {
+			return err
+		}
+	}
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Code != peersMsg {
+			msg.Discard()
+			return newPeerError(errInvalidMsgCode, "%d", msg.Code)
+		}
+		var urls []string
+		if err := msg.Decode(&urls); err != nil {
+			return newPeerError(errInvalidMsg, "%v", err)
+		}
+		if !trusted {
+			p.log.Trace("Ignoring peers of untrusted peer", "count", len(urls))
+			continue
+		}
+		if len(urls) > maxExchangedPeers {
+			urls = urls[:maxExchangedPeers]
+		}
+		pex.received(p, urls)
+	}
+}
+
+// knownPeers - enode URLs of the dialed peers, static and trusted nodes, except the peer itself. Inbound peers aren't
+// sent: the port of their connection isn't the port they listen on.
+func (pex *peerExchange) knownPeers(except enode.ID) []string {
+	seen := map[enode.ID]bool{except: true}
+	var urls []string
+	add := func(n *enode.Node) {
+		if len(urls) >= maxExchangedPeers || seen[n.ID()] || n.IP() == nil || n.TCP() == 0 {
+			return
+		}
+		seen[n.ID()] = true
+		urls = append(urls, n.URLv4())
+	}
+	for _, p := range pex.srv.Peers() {
+		if !p.Inbound() {
+			add(p.Node())
+		}
+	}
+	for _, n := range pex.srv.StaticNodes {
+		add(n)
+	}
+	for _, n := range pex.srv.TrustedNodes {
+		add(n)
+	}
+	return urls
+}
+
+func (pex *peerExchange) received(p *Peer, urls []string) {
+	self := pex.srv.localnode.ID()
+	added := 0
+	for _, url := range urls {
+		n, err := enode.ParseV4(url)
+		if err != nil {
+			p.log.Trace("Invalid node in peer exchange", "url", url, "err", err)
+			continue
+		}
+		if n.ID() == self || n.IP() == nil || n.TCP() == 0 {
+			continue
+		}
+		pex.candidates.push(n)
+		added++
+	}
+	p.log.Debug("Received peers", "count", len(urls), "added", added)
+}
+
+// nodeQueue - enode.Iterator of the nodes pushed into it, Next blocks until a node is pushed or the queue is closed
+type nodeQueue struct {
+	mu     sync.Mutex
+	nodes  []*enode.Node
+	limit  int
+	cur    *enode.Node
+	wake   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newNodeQueue(limit int) *nodeQueue {
+	return &nodeQueue{limit: limit, wake: make(chan struct{}, 1), closed: make(chan struct{})}
+}
+
+func (q *nodeQueue) push(n *enode.Node) {
+	q.mu.Lock()
+	if len(q.nodes) >= q.limit {
+		q.nodes = q.nodes[1:]
+	}
+	q.nodes = append(q.nodes, n)
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *nodeQueue) Next() bool {
+	for {
+		select {
+		case <-q.closed:
+			return false
+		default:
+		}
+		q.mu.Lock()
+		if len(q.nodes) > 0 {
+			q.cur, q.nodes = q.nodes[0], q.nodes[1:]
+			q.mu.Unlock()
+			return true
+		}
+		q.mu.Unlock()
+		select {
+		case <-q.wake:
+		case <-q.closed:
+			return false
+		}
+	}
+}
+
+func (q *nodeQueue) Node() *enode.Node {
+	return q.cur
+}
+
+func (q *nodeQueue) Close() {
+	q.once.Do(func() { close(q.closed) })
+}
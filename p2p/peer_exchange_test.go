@@ -0,0 +1,87 @@
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/log/v3"
+)
+
+func TestPeerExchange(t *testing.T) {
+	srv := startTestServer(t, nil, nil)
+	defer srv.Stop()
+	static := enode.NewV4(&newkey().PublicKey, net.IP{10, 0, 0, 10}, 30303, 30303)
+	srv.StaticNodes = []*enode.Node{static}
+	pex := newPeerExchange(srv)
+
+	exchange := func(flags connFlag, urls []string) {
+		remote := enode.NewV4(&newkey().PublicKey, net.IP{10, 0, 0, 1}, 30303, 30303)
+		peer := newPeer(log.Root(), &conn{node: remote, flags: flags}, nil, [64]byte{})
+		rw, remoteRW := MsgPipe()
+		errc := make(chan error, 1)
+		go func() { errc <- pex.run(peer, rw) }()
+		if flags&trustedConn != 0 {
+			if err := ExpectMsg(remoteRW, peersMsg, []string{static.URLv4()}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := Send(remoteRW, peersMsg, urls); err != nil {
+			t.Fatal(err)
+		}
+		remoteRW.Close()
+		select {
+		case <-errc:
+		case <-time.After(time.Second):
+			t.Fatal("protocol didn't return")
+		}
+	}
+
+	node := enode.NewV4(&newkey().PublicKey, net.IP{10, 0, 0, 11}, 30303, 30303)
+	exchange(0, []string{node.URLv4()})
+	if len(pex.candidates.nodes) != 0 {
+		t.Fatalf("nodes of untrusted peer queued: %v", pex.candidates.nodes)
+	}
+
+	exchange(trustedConn, []string{node.URLv4(), "enode://invalid", srv.localnode.Node().URLv4()})
+	if !pex.candidates.Next() || pex.candidates.Node().ID() != node.ID() {
+		t.Fatalf("node of trusted peer isn't a dial candidate")
+	}
+	if len(pex.candidates.nodes) != 0 {
+		t.Fatalf("unexpected dial candidates: %v", pex.candidates.nodes)
+	}
+}
+
+func TestPeerExchangeProtocol(t *testing.T) {
+	srv := &Server{Config: Config{Protocols: []Protocol{discard}, PeerExchange: true}}
+	srv.setupPeerExchange()
+	srv.setupPeerExchange()
+	if len(srv.Protocols) != 2 || srv.Protocols[1].Name != peerExchangeName {
+		t.Fatalf("unexpected protocols: %v", srv.Protocols)
+	}
+}
+
+func TestNodeQueue(t *testing.T) {
+	q := newNodeQueue(2)
+	for i := uint16(1); i <= 3; i++ {
+		q.push(newNode(uintID(i), "10.0.0.1:30303"))
+	}
+	for _, want := range []uint16{2, 3} {
+		if !q.Next() || q.Node().ID() != uintID(want) {
+			t.Fatalf("expected node %d", want)
+		}
+	}
+
+	done := make(chan bool)
+	go func() { done <- q.Next() }()
+	q.Close()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("Next returned true after Close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't unblock Next")
+	}
+}
@@ -69,6 +69,13 @@ const (
 
 var errServerStopped = errors.New("server stopped")
 
+const (
+	// PeerSelectionPermits sends a request to the peer with the fewest requests in flight.
+	PeerSelectionPermits = "permits"
+	// PeerSelectionLatency sends a request to a random free peer of the fastest latency bucket.
+	PeerSelectionLatency = "latency"
+)
+
 // Config holds Server options.
 type Config struct {
 	// This field must be set to a valid secp256k1 private key.
@@ -117,6 +124,18 @@ type Config struct {
 	// allowed to connect, even above the peer limit.
 	TrustedNodes []*enode.Node
 
+	// PeerExchange enables the "pex" protocol: trusted peers exchange the nodes they know on connect.
+	// Useful on private networks, which nodes aren't known to the public discovery.
+	PeerExchange bool `toml:",omitempty"`
+
+	// MaxPeersPerSubnet keeps peers of many networks for resilience: discovered nodes aren't dialed if that many peers
+	// of their Subnet are connected already. Static and trusted nodes aren't limited. Zero disables the limit.
+	MaxPeersPerSubnet int `toml:",omitempty"`
+
+	// PeerSelection - how the sentry chooses the peer of a block bodies request: PeerSelectionPermits (the one with
+	// the fewest requests in flight) or PeerSelectionLatency (one of the fastest responders). Empty means permits.
+	PeerSelection string `toml:",omitempty"`
+
 	// Connectivity can be restricted to certain IP networks.
 	// If this option is set to a non-nil value, only hosts which match one of the
 	// IP networks contained in the list are considered.
@@ -144,6 +163,10 @@ type Config struct {
 
 	SentryAddr []string
 
+	// Transport selects the wire transport by name, see Transports().
+	// Empty value means the standard RLPx transport.
+	Transport string `toml:",omitempty"`
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
@@ -486,7 +509,11 @@ func (srv *Server) Start(ctx context.Context) error {
 		return errors.New("MaxPendingPeers must be greater than zero")
 	}
 	if srv.newTransport == nil {
-		srv.newTransport = newRLPX
+		newTransport, err := lookupTransport(srv.Transport)
+		if err != nil {
+			return err
+		}
+		srv.newTransport = newTransport
 	}
 	if srv.listenFunc == nil {
 		srv.listenFunc = net.Listen
@@ -501,6 +528,9 @@ func (srv *Server) Start(ctx context.Context) error {
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
+	if srv.PeerExchange {
+		srv.setupPeerExchange()
+	}
 	if err := srv.setupLocalNode(); err != nil {
 		return err
 	}
@@ -654,6 +684,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Log,
 		netRestrict:    srv.NetRestrict,
+		maxSubnetPeers: srv.MaxPeersPerSubnet,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
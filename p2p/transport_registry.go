@@ -0,0 +1,120 @@
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+const (
+	// TransportRLPx is the standard devp2p transport.
+	TransportRLPx = "rlpx"
+	// TransportTLS is an experimental transport which uses TLS 1.3 instead of RLPx
+	// encryption and framing. Both ends of a connection must use it, so it is only
+	// suitable for links between nodes of one operator.
+	TransportTLS = "tls"
+)
+
+type transportFactory func(conn net.Conn, dialDest *ecdsa.PublicKey) transport
+
+var transports = map[string]transportFactory{}
+
+func init() {
+	registerTransport(TransportRLPx, newRLPX)
+	registerTransport(TransportTLS, newTLSTransport)
+}
+
+// registerTransport makes a transport available for selection by name via Config.Transport.
+func registerTransport(name string, factory transportFactory) {
+	if _, ok := transports[name]; ok {
+		panic(fmt.Sprintf("p2p: transport %q registered twice", name))
+	}
+	transports[name] = factory
+}
+
+// Transports returns the names of all registered transports.
+func Transports() []string {
+	names := make([]string, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupTransport returns the metered factory of the named transport.
+// The empty name selects RLPx.
+func lookupTransport(name string) (transportFactory, error) {
+	if name == "" {
+		name = TransportRLPx
+	}
+	factory, ok := transports[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown p2p transport %q, available: %s", name, strings.Join(Transports(), ","))
+	}
+	m := newTransportMetrics(name)
+	return func(conn net.Conn, dialDest *ecdsa.PublicKey) transport {
+		return &meteredTransport{transport: factory(conn, dialDest), metrics: m}
+	}, nil
+}
+
+type transportMetrics struct {
+	handshakeOk, handshakeFail *metrics.Counter
+	handshakeTime              *metrics.Summary
+	msgIn, msgOut              *metrics.Counter
+	bytesIn, bytesOut          *metrics.Counter
+}
+
+func newTransportMetrics(name string) *transportMetrics {
+	return &transportMetrics{
+		handshakeOk:   metrics.GetOrCreateCounter(fmt.Sprintf(`p2p_transport_handshakes{transport="%s",result="ok"}`, name)),
+		handshakeFail: metrics.GetOrCreateCounter(fmt.Sprintf(`p2p_transport_handshakes{transport="%s",result="fail"}`, name)),
+		handshakeTime: metrics.GetOrCreateSummary(fmt.Sprintf(`p2p_transport_handshake_seconds{transport="%s"}`, name)),
+		msgIn:         metrics.GetOrCreateCounter(fmt.Sprintf(`p2p_transport_msgs{transport="%s",dir="in"}`, name)),
+		msgOut:        metrics.GetOrCreateCounter(fmt.Sprintf(`p2p_transport_msgs{transport="%s",dir="out"}`, name)),
+		bytesIn:       metrics.GetOrCreateCounter(fmt.Sprintf(`p2p_transport_payload_bytes{transport="%s",dir="in"}`, name)),
+		bytesOut:      metrics.GetOrCreateCounter(fmt.Sprintf(`p2p_transport_payload_bytes{transport="%s",dir="out"}`, name)),
+	}
+}
+
+// meteredTransport counts handshakes and messages going through a transport.
+type meteredTransport struct {
+	transport
+	metrics *transportMetrics
+}
+
+func (t *meteredTransport) doEncHandshake(prv *ecdsa.PrivateKey) (*ecdsa.PublicKey, error) {
+	start := time.Now()
+	pub, err := t.transport.doEncHandshake(prv)
+	if err != nil {
+		t.metrics.handshakeFail.Inc()
+		return nil, err
+	}
+	t.metrics.handshakeOk.Inc()
+	t.metrics.handshakeTime.UpdateDuration(start)
+	return pub, nil
+}
+
+func (t *meteredTransport) ReadMsg() (Msg, error) {
+	msg, err := t.transport.ReadMsg()
+	if err == nil {
+		t.metrics.msgIn.Inc()
+		t.metrics.bytesIn.Add(int(msg.Size))
+	}
+	return msg, err
+}
+
+func (t *meteredTransport) WriteMsg(msg Msg) error {
+	size := msg.Size
+	err := t.transport.WriteMsg(msg)
+	if err == nil {
+		t.metrics.msgOut.Inc()
+		t.metrics.bytesOut.Add(int(size))
+	}
+	return err
+}
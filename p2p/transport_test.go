@@ -146,3 +146,87 @@ func TestProtocolHandshakeErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestTLSTransportHandshake(t *testing.T) {
+	var (
+		prv0, _ = crypto.GenerateKey()
+		hs0     = &protoHandshake{Version: 5, Pubkey: crypto.MarshalPubkey(&prv0.PublicKey), Caps: []Cap{{"a", 0}}}
+
+		prv1, _ = crypto.GenerateKey()
+		hs1     = &protoHandshake{Version: 5, Pubkey: crypto.MarshalPubkey(&prv1.PublicKey), Caps: []Cap{{"b", 1}}}
+
+		wg sync.WaitGroup
+	)
+	newTLS, err := lookupTransport(TransportTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd0, fd1, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer fd0.Close()
+		tr := newTLS(fd0, &prv1.PublicKey)
+		rpubkey, err := tr.doEncHandshake(prv0)
+		if err != nil {
+			t.Errorf("dial side enc handshake failed: %v", err)
+			return
+		}
+		if !reflect.DeepEqual(rpubkey, &prv1.PublicKey) {
+			t.Errorf("dial side remote pubkey mismatch: got %v, want %v", rpubkey, &prv1.PublicKey)
+			return
+		}
+		if _, err := tr.doProtoHandshake(hs0); err != nil {
+			t.Errorf("dial side proto handshake error: %v", err)
+			return
+		}
+		if err := Send(tr, 0x10, []uint{1, 2, 3}); err != nil {
+			t.Errorf("send error: %v", err)
+		}
+		tr.close(DiscQuitting)
+	}()
+	go func() {
+		defer wg.Done()
+		defer fd1.Close()
+		tr := newTLS(fd1, nil)
+		rpubkey, err := tr.doEncHandshake(prv1)
+		if err != nil {
+			t.Errorf("listen side enc handshake failed: %v", err)
+			return
+		}
+		if !reflect.DeepEqual(rpubkey, &prv0.PublicKey) {
+			t.Errorf("listen side remote pubkey mismatch: got %v, want %v", rpubkey, &prv0.PublicKey)
+			return
+		}
+		phs, err := tr.doProtoHandshake(hs1)
+		if err != nil {
+			t.Errorf("listen side proto handshake error: %v", err)
+			return
+		}
+		phs.Rest = nil
+		if !reflect.DeepEqual(phs, hs0) {
+			t.Errorf("listen side proto handshake mismatch:\ngot: %s\nwant: %s\n", spew.Sdump(phs), spew.Sdump(hs0))
+			return
+		}
+		if err := ExpectMsg(tr, 0x10, []uint{1, 2, 3}); err != nil {
+			t.Errorf("error receiving message: %v", err)
+		}
+		if err := ExpectMsg(tr, discMsg, []DiscReason{DiscQuitting}); err != nil {
+			t.Errorf("error receiving disconnect: %v", err)
+		}
+	}()
+	wg.Wait()
+}
+
+func TestLookupTransport(t *testing.T) {
+	if _, err := lookupTransport(""); err != nil {
+		t.Fatalf("default transport: %v", err)
+	}
+	if _, err := lookupTransport("carrier-pigeon"); err == nil {
+		t.Fatal("expected error for unknown transport")
+	}
+}
@@ -0,0 +1,286 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// tlsTransport is an experimental transport which replaces the RLPx encryption
+// handshake and framing with TLS 1.3. It is meant for links between nodes run by
+// the same operator (e.g. sentry to sentry), where the peers are known in advance.
+//
+// TLS certificates are ephemeral and not verified. Instead, after the TLS handshake
+// both sides sign keying material exported from the TLS session with their node key,
+// which binds the devp2p identity to this particular TLS connection.
+//
+// Frames on the wire are: uvarint message code, 4-byte big endian payload size, payload.
+type tlsTransport struct {
+	rmu, wmu sync.Mutex
+	wbuf     bytes.Buffer
+	fd       net.Conn
+	conn     *tls.Conn
+	r        *bufio.Reader
+	dialDest *ecdsa.PublicKey
+	snappy   bool
+}
+
+const (
+	tlsExporterLabel = "EXPERIMENTAL erigon devp2p identity"
+	// tlsMaxFrameSize matches the maximum message size of RLPx.
+	tlsMaxFrameSize = 0xFFFFFF
+	// size of the identity message: 64 bytes pubkey + 65 bytes signature
+	tlsIdentitySize = 64 + crypto.SignatureLength
+)
+
+var (
+	errTLSIdentityMismatch = errors.New("tls transport: remote identity mismatch")
+	errTLSMessageTooLarge  = errors.New("tls transport: message too large")
+)
+
+func newTLSTransport(conn net.Conn, dialDest *ecdsa.PublicKey) transport {
+	return &tlsTransport{fd: conn, dialDest: dialDest}
+}
+
+func (t *tlsTransport) doEncHandshake(prv *ecdsa.PrivateKey) (*ecdsa.PublicKey, error) {
+	if err := t.fd.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return nil, err
+	}
+	cert, err := ephemeralCertificate()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+		// Peer identity is established with node keys below, not with certificates.
+		InsecureSkipVerify: true, //nolint:gosec
+	}
+	initiator := t.dialDest != nil
+	if initiator {
+		t.conn = tls.Client(t.fd, cfg)
+	} else {
+		t.conn = tls.Server(t.fd, cfg)
+	}
+	if err = t.conn.Handshake(); err != nil {
+		return nil, err
+	}
+	t.r = bufio.NewReader(t.conn)
+
+	state := t.conn.ConnectionState()
+	material, err := state.ExportKeyingMaterial(tlsExporterLabel, nil, 32)
+	if err != nil {
+		return nil, err
+	}
+	// Send our identity and read the remote one concurrently, net.Pipe based
+	// connections are unbuffered.
+	werr := make(chan error, 1)
+	go func() { werr <- t.writeIdentity(prv, material, initiator) }()
+	remote, err := t.readIdentity(material, !initiator)
+	if werr := <-werr; err == nil && werr != nil {
+		err = werr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.dialDest != nil && !t.dialDest.Equal(remote) {
+		return nil, errTLSIdentityMismatch
+	}
+	return remote, nil
+}
+
+func identityDigest(material []byte, initiator bool) []byte {
+	role := []byte{0}
+	if initiator {
+		role[0] = 1
+	}
+	return crypto.Keccak256(material, role)
+}
+
+func (t *tlsTransport) writeIdentity(prv *ecdsa.PrivateKey, material []byte, initiator bool) error {
+	sig, err := crypto.Sign(identityDigest(material, initiator), prv)
+	if err != nil {
+		return err
+	}
+	msg := make([]byte, 0, tlsIdentitySize)
+	msg = append(msg, crypto.MarshalPubkey(&prv.PublicKey)...)
+	msg = append(msg, sig...)
+	_, err = t.conn.Write(msg)
+	return err
+}
+
+func (t *tlsTransport) readIdentity(material []byte, initiator bool) (*ecdsa.PublicKey, error) {
+	msg := make([]byte, tlsIdentitySize)
+	if _, err := io.ReadFull(t.r, msg); err != nil {
+		return nil, err
+	}
+	claimed, err := crypto.UnmarshalPubkey(msg[:64])
+	if err != nil {
+		return nil, err
+	}
+	recovered, err := crypto.SigToPub(identityDigest(material, initiator), msg[64:])
+	if err != nil {
+		return nil, err
+	}
+	if !claimed.Equal(recovered) {
+		return nil, errTLSIdentityMismatch
+	}
+	return claimed, nil
+}
+
+func (t *tlsTransport) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err error) {
+	werr := make(chan error, 1)
+	go func() { werr <- Send(t, handshakeMsg, our) }()
+	if their, err = readProtocolHandshake(t); err != nil {
+		<-werr // make sure the write terminates too
+		return nil, err
+	}
+	if err := <-werr; err != nil {
+		return nil, fmt.Errorf("write error: %w", err)
+	}
+	t.rmu.Lock()
+	t.wmu.Lock()
+	t.snappy = their.Version >= snappyProtocolVersion
+	t.wmu.Unlock()
+	t.rmu.Unlock()
+	return their, nil
+}
+
+func (t *tlsTransport) ReadMsg() (Msg, error) {
+	t.rmu.Lock()
+	defer t.rmu.Unlock()
+
+	var msg Msg
+	if err := t.conn.SetReadDeadline(time.Now().Add(frameReadTimeout)); err != nil {
+		return msg, err
+	}
+	code, err := binary.ReadUvarint(t.r)
+	if err != nil {
+		return msg, err
+	}
+	var sizeBuf [4]byte
+	if _, err = io.ReadFull(t.r, sizeBuf[:]); err != nil {
+		return msg, err
+	}
+	size := binary.BigEndian.Uint32(sizeBuf[:])
+	if size > tlsMaxFrameSize {
+		return msg, errTLSMessageTooLarge
+	}
+	data := make([]byte, size)
+	if _, err = io.ReadFull(t.r, data); err != nil {
+		return msg, err
+	}
+	if t.snappy {
+		actualSize, err := snappy.DecodedLen(data)
+		if err != nil {
+			return msg, err
+		}
+		if actualSize > tlsMaxFrameSize {
+			return msg, errTLSMessageTooLarge
+		}
+		if data, err = snappy.Decode(nil, data); err != nil {
+			return msg, err
+		}
+	}
+	msg = Msg{
+		ReceivedAt: time.Now(),
+		Code:       code,
+		Size:       uint32(len(data)),
+		meterSize:  size,
+		Payload:    bytes.NewReader(data),
+	}
+	return msg, nil
+}
+
+func (t *tlsTransport) WriteMsg(msg Msg) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	return t.writeMsg(msg.Code, msg.Payload, msg.Size)
+}
+
+func (t *tlsTransport) writeMsg(code uint64, payload io.Reader, size uint32) error {
+	if size > tlsMaxFrameSize {
+		return errTLSMessageTooLarge
+	}
+	t.wbuf.Reset()
+	if _, err := io.CopyN(&t.wbuf, payload, int64(size)); err != nil {
+		return err
+	}
+	data := t.wbuf.Bytes()
+	if t.snappy {
+		data = snappy.Encode(nil, data)
+	}
+	frame := make([]byte, binary.MaxVarintLen64+4, binary.MaxVarintLen64+4+len(data))
+	n := binary.PutUvarint(frame, code)
+	binary.BigEndian.PutUint32(frame[n:], uint32(len(data)))
+	frame = append(frame[:n+4], data...)
+
+	if err := t.conn.SetWriteDeadline(time.Now().Add(frameWriteTimeout)); err != nil {
+		return err
+	}
+	_, err := t.conn.Write(frame)
+	return err
+}
+
+func (t *tlsTransport) close(err error) {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	if t.conn == nil {
+		t.fd.Close() //nolint:errcheck
+		return
+	}
+	if r, ok := err.(DiscReason); ok && r != DiscNetworkError {
+		if err := t.conn.SetWriteDeadline(time.Now().Add(discWriteTimeout)); err == nil {
+			var buf bytes.Buffer
+			rlp.Encode(&buf, []DiscReason{r})                                    //nolint:errcheck
+			t.writeMsg(discMsg, bytes.NewReader(buf.Bytes()), uint32(buf.Len())) //nolint:errcheck
+		}
+	}
+	t.conn.Close() //nolint:errcheck
+}
+
+// ephemeralCertificate creates a self-signed certificate with a fresh P-256 key.
+func ephemeralCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(common.Big1, 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "erigon-p2p"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,27 @@
+package rpc
+
+import (
+	"context"
+)
+
+// DBStatsHeader - http header requesting database read statistics of the calls, they are returned in the "dbStats"
+// member of every response of the request. Statistics are gathered by the DBStats set with Server.SetDBStats.
+const DBStatsHeader = "X-Erigon-Db-Stats"
+
+// DBStats gathers database read statistics of calls, see DBStatsHeader
+type DBStats interface {
+	// WithStats returns the context of a call which gathers statistics of reads done with it
+	WithStats(ctx context.Context) context.Context
+	// Snapshot returns statistics gathered for the context returned by WithStats, nil for other contexts
+	Snapshot(ctx context.Context) interface{}
+}
+
+type dbStatsRequestedKey struct{}
+
+// withDBStats gives each call its own statistics if the request asked for them
+func withDBStats(ctx context.Context, dbStats DBStats) context.Context {
+	if requested, _ := ctx.Value(dbStatsRequestedKey{}).(bool); !requested || dbStats == nil {
+		return ctx
+	}
+	return dbStats.WithStats(ctx)
+}
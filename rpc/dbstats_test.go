@@ -0,0 +1,87 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sync/atomic"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+type testDBStatsKey struct{}
+
+// testDBStats counts reads of dbStatsService
+type testDBStats struct{}
+
+func (testDBStats) WithStats(ctx context.Context) context.Context {
+	return context.WithValue(ctx, testDBStatsKey{}, new(uint64))
+}
+
+func (testDBStats) Snapshot(ctx context.Context) interface{} {
+	reads, ok := ctx.Value(testDBStatsKey{}).(*uint64)
+	if !ok {
+		return nil
+	}
+	return map[string]uint64{"reads": atomic.LoadUint64(reads)}
+}
+
+type dbStatsService struct{}
+
+func (s dbStatsService) Read(ctx context.Context) (string, error) {
+	if reads, ok := ctx.Value(testDBStatsKey{}).(*uint64); ok {
+		atomic.AddUint64(reads, 1)
+	}
+	return "value", nil
+}
+
+func (s dbStatsService) ReadStream(ctx context.Context, stream *jsoniter.Stream) error {
+	v, err := s.Read(ctx)
+	stream.WriteString(v)
+	return err
+}
+
+func TestHTTPDBStats(t *testing.T) {
+	require := require.New(t)
+
+	const stats = `"dbStats":{"reads":1}`
+	for _, disableStreaming := range []bool{true, false} {
+		s := NewServer(50, false /* traceRequests */, disableStreaming)
+		s.SetDBStats(testDBStats{})
+		require.NoError(s.RegisterName("test", dbStatsService{}))
+		ts := httptest.NewServer(s)
+
+		post := func(body string, header bool) string {
+			request, err := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+			require.NoError(err)
+			request.Header.Set("Content-Type", contentType)
+			if header {
+				request.Header.Set(DBStatsHeader, "1")
+			}
+			resp, err := http.DefaultClient.Do(request)
+			require.NoError(err)
+			defer resp.Body.Close()
+			respBody, err := io.ReadAll(resp.Body)
+			require.NoError(err)
+			return string(respBody)
+		}
+		for _, method := range []string{"test_read", "test_readStream"} {
+			body := `{"jsonrpc":"2.0","id":1,"method":"` + method + `"}`
+			require.NotContains(post(body, false), "dbStats")
+			resp := post(body, true)
+			require.Contains(resp, `"result":"value"`, method)
+			require.Contains(resp, stats, method)
+
+			// every call of a batch has its own statistics
+			resp = post("["+body+","+body+"]", true)
+			require.Equal(2, strings.Count(resp, stats), method)
+		}
+		ts.Close()
+		s.Stop()
+	}
+}
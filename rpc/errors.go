@@ -16,7 +16,11 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common"
+)
 
 var (
 	_ Error = new(methodNotFoundError)
@@ -26,6 +30,34 @@ var (
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
 	_ Error = new(CustomError)
+
+	_ DataError = new(NotFoundError)
+	_ DataError = new(PrunedError)
+	_ DataError = new(ReorgedError)
+	_ DataError = new(NonCanonicalTxError)
+	_ DataError = new(ReorgInProgressError)
+	_ DataError = new(LimitExceededError)
+	_ DataError = new(limitExceededError)
+)
+
+// Codes of the errors below are stable and the same in all namespaces (eth_, trace_, debug_, ots_, ...),
+// "kind" field of the error data tells them apart too. Clients should match them instead of messages.
+// Reverted execution keeps code 3 with the revert data, as other clients do.
+const (
+	ErrCodeNotFound        = -32001
+	ErrCodePruned          = -32002
+	ErrCodeLimitExceeded   = -32005
+	ErrCodeReorged         = -32010
+	ErrCodeReorgInProgress = -32011
+	ErrCodeReverted        = 3
+)
+
+const (
+	ErrKindNotFound        = "notFound"
+	ErrKindPruned          = "pruned"
+	ErrKindLimitExceeded   = "limitExceeded"
+	ErrKindReorged         = "reorged"
+	ErrKindReorgInProgress = "reorgInProgress"
 )
 
 const defaultErrorCode = -32000
@@ -82,3 +114,129 @@ type CustomError struct {
 func (e *CustomError) ErrorCode() int { return e.Code }
 
 func (e *CustomError) Error() string { return e.Message }
+
+// ErrorDetails - data of the typed errors
+type ErrorDetails struct {
+	Kind          string       `json:"kind"`
+	Resource      string       `json:"resource,omitempty"`
+	ID            interface{}  `json:"id,omitempty"`
+	AvailableFrom *uint64      `json:"availableFrom,omitempty"`
+	Limit         *uint64      `json:"limit,omitempty"`
+	BlockHash     *common.Hash `json:"blockHash,omitempty"`
+	BlockNumber   *uint64      `json:"blockNumber,omitempty"`
+	UnwindPoint   *uint64      `json:"unwindPoint,omitempty"`
+}
+
+// NotFoundError - requested block, header, transaction, filter, etc. doesn't exist
+type NotFoundError struct {
+	Resource string      // "block", "header", "transaction", ...
+	ID       interface{} // number or hash the resource was requested by
+}
+
+func NewNotFoundError(resource string, id interface{}) *NotFoundError {
+	return &NotFoundError{Resource: resource, ID: id}
+}
+
+func (e *NotFoundError) ErrorCode() int { return ErrCodeNotFound }
+
+func (e *NotFoundError) Error() string {
+	if e.ID == nil {
+		return fmt.Sprintf("%s not found", e.Resource)
+	}
+	return fmt.Sprintf("%s %v not found", e.Resource, e.ID)
+}
+
+func (e *NotFoundError) ErrorData() interface{} {
+	return ErrorDetails{Kind: ErrKindNotFound, Resource: e.Resource, ID: e.ID}
+}
+
+// PrunedError - requested data existed, but was pruned from this node
+type PrunedError struct {
+	Resource      string // "state", "receipts", ...
+	Block         uint64
+	AvailableFrom uint64 // first block which still has the data
+}
+
+func (e *PrunedError) ErrorCode() int { return ErrCodePruned }
+
+func (e *PrunedError) Error() string {
+	return fmt.Sprintf("%s of block %d is pruned, available from block %d", e.Resource, e.Block, e.AvailableFrom)
+}
+
+func (e *PrunedError) ErrorData() interface{} {
+	return ErrorDetails{Kind: ErrKindPruned, Resource: e.Resource, ID: e.Block, AvailableFrom: &e.AvailableFrom}
+}
+
+// ReorgedError - requested block was reorged away: it's not in the canonical chain anymore
+type ReorgedError struct{ Hash common.Hash }
+
+func (e *ReorgedError) ErrorCode() int { return ErrCodeReorged }
+
+func (e *ReorgedError) Error() string {
+	return fmt.Sprintf("hash %x is not currently canonical", e.Hash)
+}
+
+func (e *ReorgedError) ErrorData() interface{} {
+	return ErrorDetails{Kind: ErrKindReorged, Resource: "block", ID: e.Hash}
+}
+
+// NonCanonicalTxError - requested transaction isn't in the canonical chain, but is included in a block which was reorged away
+type NonCanonicalTxError struct {
+	TxHash      common.Hash
+	BlockHash   common.Hash
+	BlockNumber uint64
+}
+
+func (e *NonCanonicalTxError) ErrorCode() int { return ErrCodeReorged }
+
+func (e *NonCanonicalTxError) Error() string {
+	return fmt.Sprintf("transaction %x is included in non-canonical block %d (%x)", e.TxHash, e.BlockNumber, e.BlockHash)
+}
+
+func (e *NonCanonicalTxError) ErrorData() interface{} {
+	return ErrorDetails{Kind: ErrKindReorged, Resource: "transaction", ID: e.TxHash, BlockHash: &e.BlockHash, BlockNumber: &e.BlockNumber}
+}
+
+// ReorgInProgressError - requested block is above the point the node is unwinding to: data of the old and the new
+// chain may be mixed until the new chain is synced up. Blocks up to UnwindPoint are consistent.
+type ReorgInProgressError struct {
+	Block       uint64
+	UnwindPoint uint64
+}
+
+func (e *ReorgInProgressError) ErrorCode() int { return ErrCodeReorgInProgress }
+
+func (e *ReorgInProgressError) Error() string {
+	return fmt.Sprintf("reorg in progress: block %d is above unwind point %d, retry later", e.Block, e.UnwindPoint)
+}
+
+func (e *ReorgInProgressError) ErrorData() interface{} {
+	return ErrorDetails{Kind: ErrKindReorgInProgress, Resource: "block", ID: e.Block, UnwindPoint: &e.UnwindPoint}
+}
+
+// LimitExceededError - request asks for more than the node is configured to serve
+type LimitExceededError struct {
+	Message string
+	Limit   uint64
+}
+
+func (e *LimitExceededError) ErrorCode() int { return ErrCodeLimitExceeded }
+
+func (e *LimitExceededError) Error() string { return e.Message }
+
+func (e *LimitExceededError) ErrorData() interface{} {
+	return ErrorDetails{Kind: ErrKindLimitExceeded, Limit: &e.Limit}
+}
+
+// request rejected because the worker pool of its method class is saturated
+type limitExceededError struct{ class MethodClass }
+
+func (e *limitExceededError) ErrorCode() int { return ErrCodeLimitExceeded }
+
+func (e *limitExceededError) Error() string {
+	return fmt.Sprintf("too many %s requests in queue, try again later", e.class)
+}
+
+func (e *limitExceededError) ErrorData() interface{} {
+	return ErrorDetails{Kind: ErrKindLimitExceeded, Resource: e.class.String()}
+}
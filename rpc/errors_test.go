@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedErrorMessage(t *testing.T) {
+	hash := common.HexToHash("0x01")
+	tests := []struct {
+		err  error
+		code int
+		msg  string
+		data string
+	}{
+		{NewNotFoundError("block", uint64(5)), ErrCodeNotFound, "block 5 not found",
+			`{"kind":"notFound","resource":"block","id":5}`},
+		{NewNotFoundError("block", hash), ErrCodeNotFound, "block " + hash.Hex() + " not found",
+			`{"kind":"notFound","resource":"block","id":"` + hash.Hex() + `"}`},
+		{&PrunedError{Resource: "state", Block: 10, AvailableFrom: 90}, ErrCodePruned, "state of block 10 is pruned, available from block 90",
+			`{"kind":"pruned","resource":"state","id":10,"availableFrom":90}`},
+		{&ReorgedError{Hash: hash}, ErrCodeReorged, fmt.Sprintf("hash %x is not currently canonical", hash),
+			`{"kind":"reorged","resource":"block","id":"` + hash.Hex() + `"}`},
+		{&NonCanonicalTxError{TxHash: hash, BlockHash: hash, BlockNumber: 7}, ErrCodeReorged, fmt.Sprintf("transaction %x is included in non-canonical block 7 (%x)", hash, hash),
+			`{"kind":"reorged","resource":"transaction","id":"` + hash.Hex() + `","blockHash":"` + hash.Hex() + `","blockNumber":7}`},
+		{&ReorgInProgressError{Block: 12, UnwindPoint: 10}, ErrCodeReorgInProgress, "reorg in progress: block 12 is above unwind point 10, retry later",
+			`{"kind":"reorgInProgress","resource":"block","id":12,"unwindPoint":10}`},
+		{&LimitExceededError{Message: "too many", Limit: 0}, ErrCodeLimitExceeded, "too many",
+			`{"kind":"limitExceeded","limit":0}`},
+		// wrapped errors keep code and data
+		{fmt.Errorf("tracing: %w", NewNotFoundError("transaction", nil)), ErrCodeNotFound, "tracing: transaction not found",
+			`{"kind":"notFound","resource":"transaction"}`},
+	}
+	for _, tt := range tests {
+		msg := errorMessage(tt.err)
+		require.Equal(t, tt.code, msg.Error.Code)
+		require.Equal(t, tt.msg, msg.Error.Message)
+		data, err := json.Marshal(msg.Error.Data)
+		require.NoError(t, err)
+		require.JSONEq(t, tt.data, string(data))
+	}
+}
@@ -0,0 +1,315 @@
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// fieldSelector - "fields" extension member of a request: clients which need only some fields of large results
+// (e.g. hash and sender of transactions of a block) ask for them, and the rest is not serialized at all.
+// Keys are JSON names of object fields, arrays are transparent: "transactions.hash" selects "hash" of
+// every transaction. nil selector keeps the whole value.
+type fieldSelector map[string]fieldSelector
+
+func newFieldSelector(fields []string) fieldSelector {
+	if len(fields) == 0 {
+		return nil
+	}
+	root := fieldSelector{}
+	for _, field := range fields {
+		s := root
+		path := strings.Split(field, ".")
+		for i, name := range path {
+			sub, ok := s[name]
+			if ok && sub == nil { // the whole value is already selected
+				break
+			}
+			if i == len(path)-1 {
+				s[name] = nil
+				break
+			}
+			if !ok {
+				sub = fieldSelector{}
+				s[name] = sub
+			}
+			s = sub
+		}
+	}
+	return root
+}
+
+// filter returns value which is marshalled into JSON of v with selected fields only
+func (s fieldSelector) filter(v interface{}) (interface{}, error) {
+	if s == nil || v == nil {
+		return v, nil
+	}
+	return s.filterValue(reflect.ValueOf(v))
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (s fieldSelector) filterValue(v reflect.Value) (interface{}, error) {
+	if s == nil {
+		return v.Interface(), nil
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		(v.CanAddr() && (reflect.PtrTo(t).Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType))) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil, nil
+		}
+		if v.CanAddr() {
+			v = v.Addr()
+		}
+		// custom encoding - filter what it produces
+		raw, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return s.filterJSON(raw)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return s.filterValue(v.Elem())
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if t.Key().Kind() != reflect.String {
+			return v.Interface(), nil
+		}
+		obj := &filteredObject{}
+		for name, sub := range s {
+			value := v.MapIndex(reflect.ValueOf(name).Convert(t.Key()))
+			if !value.IsValid() {
+				continue
+			}
+			filtered, err := sub.filterValue(value)
+			if err != nil {
+				return nil, err
+			}
+			obj.add(name, filtered)
+		}
+		sort.Sort(obj) // the same order as encoding/json
+		return obj, nil
+	case reflect.Struct:
+		obj := &filteredObject{}
+		for _, f := range cachedJSONFields(t) {
+			sub, ok := s[f.name]
+			if !ok {
+				continue
+			}
+			value, ok := fieldByIndex(v, f.index)
+			if !ok || (f.omitEmpty && isEmptyValue(value)) {
+				continue
+			}
+			filtered, err := sub.filterValue(value)
+			if err != nil {
+				return nil, err
+			}
+			obj.add(f.name, filtered)
+		}
+		return obj, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 { // base64 of []byte - nothing to select
+			return v.Interface(), nil
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			filtered, err := s.filterValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = filtered
+		}
+		return list, nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// filterJSON keeps selected fields of already encoded value, in their original order
+func (s fieldSelector) filterJSON(raw []byte) (json.RawMessage, error) {
+	iter := jsoniter.ConfigDefault.BorrowIterator(raw)
+	defer jsoniter.ConfigDefault.ReturnIterator(iter)
+	stream := jsoniter.ConfigDefault.BorrowStream(nil)
+	defer jsoniter.ConfigDefault.ReturnStream(stream)
+	s.copyJSON(iter, stream)
+	if iter.Error != nil && iter.Error != io.EOF { // EOF - number at the end of raw
+		return nil, iter.Error
+	}
+	return append(json.RawMessage(nil), stream.Buffer()...), nil
+}
+
+func (s fieldSelector) copyJSON(iter *jsoniter.Iterator, stream *jsoniter.Stream) {
+	if s == nil {
+		stream.Write(iter.SkipAndReturnBytes())
+		return
+	}
+	switch iter.WhatIsNext() {
+	case jsoniter.ObjectValue:
+		stream.WriteObjectStart()
+		first := true
+		iter.ReadMapCB(func(iter *jsoniter.Iterator, field string) bool {
+			sub, ok := s[field]
+			if !ok {
+				iter.Skip()
+				return true
+			}
+			if !first {
+				stream.WriteMore()
+			}
+			first = false
+			stream.WriteObjectField(field)
+			sub.copyJSON(iter, stream)
+			return true
+		})
+		stream.WriteObjectEnd()
+	case jsoniter.ArrayValue:
+		stream.WriteArrayStart()
+		first := true
+		iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
+			if !first {
+				stream.WriteMore()
+			}
+			first = false
+			s.copyJSON(iter, stream)
+			return true
+		})
+		stream.WriteArrayEnd()
+	default:
+		stream.Write(iter.SkipAndReturnBytes())
+	}
+}
+
+// filteredObject - JSON object with fields in the given order
+type filteredObject struct {
+	names  []string
+	values []interface{}
+}
+
+func (o *filteredObject) add(name string, value interface{}) {
+	o.names = append(o.names, name)
+	o.values = append(o.values, value)
+}
+
+func (o *filteredObject) Len() int           { return len(o.names) }
+func (o *filteredObject) Less(i, j int) bool { return o.names[i] < o.names[j] }
+func (o *filteredObject) Swap(i, j int) {
+	o.names[i], o.names[j] = o.names[j], o.names[i]
+	o.values[i], o.values[j] = o.values[j], o.values[i]
+}
+
+func (o *filteredObject) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, name := range o.names {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(append(buf, key...), ':'), value...)
+	}
+	return append(buf, '}'), nil
+}
+
+// jsonField - field of a struct as encoding/json sees it
+type jsonField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var jsonFieldsCache sync.Map // reflect.Type -> []jsonField
+
+func cachedJSONFields(t reflect.Type) []jsonField {
+	if fields, ok := jsonFieldsCache.Load(t); ok {
+		return fields.([]jsonField)
+	}
+	fields, _ := jsonFieldsCache.LoadOrStore(t, jsonFields(t, nil))
+	return fields.([]jsonField)
+}
+
+// jsonFields lists exported fields by their JSON names, fields of embedded structs without a name are promoted.
+// Conflicting names of promoted fields are not resolved - they don't happen in RPC results.
+func jsonFields(t reflect.Type, index []int) (fields []jsonField) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(ft, fieldIndex)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, jsonField{name: name, index: fieldIndex, omitEmpty: strings.Contains(opts, "omitempty")})
+	}
+	return fields
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex which doesn't panic on nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
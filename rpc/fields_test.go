@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fieldsTestTx struct {
+	Hash  string   `json:"hash"`
+	From  string   `json:"from"`
+	To    *string  `json:"to,omitempty"`
+	Value *big.Int `json:"value"`
+	Input []byte   `json:"input"`
+}
+
+type fieldsTestEmbedded struct {
+	Number uint64 `json:"number"`
+}
+
+type fieldsTestBlock struct {
+	fieldsTestEmbedded
+	Hash         string          `json:"hash"`
+	Transactions []interface{}   `json:"transactions"`
+	Uncles       []string        `json:"uncles"`
+	Raw          json.RawMessage `json:"raw"`
+	private      int
+}
+
+func TestFieldSelector(t *testing.T) {
+	to := "0x02"
+	block := &fieldsTestBlock{
+		fieldsTestEmbedded: fieldsTestEmbedded{Number: 7},
+		Hash:               "0xb1",
+		Transactions: []interface{}{
+			&fieldsTestTx{Hash: "0xt1", From: "0x01", To: &to, Value: big.NewInt(1), Input: []byte{1}},
+			fieldsTestTx{Hash: "0xt2", From: "0x02", Value: big.NewInt(2)},
+		},
+		Uncles: []string{"0xu1"},
+		Raw:    json.RawMessage(`{"b":1,"a":{"x":2,"y":3}}`),
+	}
+	for _, tt := range []struct {
+		fields []string
+		result interface{}
+		exp    string
+	}{
+		{nil, block, `{"number":7,"hash":"0xb1","transactions":[{"hash":"0xt1","from":"0x01","to":"0x02","value":1,"input":"AQ=="},{"hash":"0xt2","from":"0x02","value":2,"input":null}],"uncles":["0xu1"],"raw":{"b":1,"a":{"x":2,"y":3}}}`},
+		{[]string{"hash", "number"}, block, `{"number":7,"hash":"0xb1"}`},
+		{[]string{"transactions.hash", "transactions.to", "transactions.value"}, block, `{"transactions":[{"hash":"0xt1","to":"0x02","value":1},{"hash":"0xt2","value":2}]}`},
+		{[]string{"transactions.hash", "transactions"}, block, `{"transactions":[{"hash":"0xt1","from":"0x01","to":"0x02","value":1,"input":"AQ=="},{"hash":"0xt2","from":"0x02","value":2,"input":null}]}`},
+		{[]string{"uncles.x", "raw.a.y", "raw.c"}, block, `{"uncles":["0xu1"],"raw":{"a":{"y":3}}}`},
+		{[]string{"private"}, block, `{}`},
+		{[]string{"b", "a.a.x"}, map[string]interface{}{"a": block.Raw, "b": []int{1}, "c": 3}, `{"a":{"a":{"x":2}},"b":[1]}`},
+		{[]string{"x"}, (*fieldsTestBlock)(nil), `null`},
+		{[]string{"x"}, "0x1", `"0x1"`},
+		{[]string{"x"}, json.RawMessage(`12`), `12`},
+	} {
+		filtered, err := newFieldSelector(tt.fields).filter(tt.result)
+		require.NoError(t, err, tt.fields)
+		enc, err := json.Marshal(filtered)
+		require.NoError(t, err, tt.fields)
+		require.JSONEq(t, tt.exp, string(enc), tt.fields)
+	}
+}
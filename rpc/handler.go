@@ -25,9 +25,11 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon/ethdb/kvreaders"
 	"github.com/ledgerwatch/log/v3"
 )
 
@@ -71,6 +73,14 @@ type handler struct {
 	serverSubs          map[ID]*Subscription
 	maxBatchConcurrency uint
 	traceRequests       bool
+	scheduler           *Scheduler // nil - requests are not limited
+	dbStats             DBStats    // nil - statistics are not gathered
+
+	wsLimits    WebsocketLimits // zero - connection is not limited
+	sendQueue   *sendQueue      // nil - notifications are written on the goroutine of Notify
+	pendingSubs int             // subscribe calls in progress, guarded by subLock
+	calls       int32           // atomic, calls in progress
+	lastCall    int64           // atomic, unix nanoseconds when the last call started or ended
 }
 
 type callProc struct {
@@ -110,7 +120,7 @@ func HandleError(err error, stream *jsoniter.Stream) error {
 	return nil
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, allowList AllowList, maxBatchConcurrency uint, traceRequests bool) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, allowList AllowList, maxBatchConcurrency uint, traceRequests bool, scheduler *Scheduler) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	forbiddenList := newForbiddenList()
 	h := &handler{
@@ -129,6 +139,7 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 
 		maxBatchConcurrency: maxBatchConcurrency,
 		traceRequests:       traceRequests,
+		scheduler:           scheduler,
 	}
 
 	if conn.remoteAddr() != "" {
@@ -287,6 +298,7 @@ func (h *handler) addSubscriptions(nn []*Notifier) {
 	h.subLock.Lock()
 	defer h.subLock.Unlock()
 
+	h.pendingSubs -= len(nn)
 	for _, n := range nn {
 		if sub := n.takeSubscription(); sub != nil {
 			h.serverSubs[sub.ID] = sub
@@ -309,10 +321,16 @@ func (h *handler) cancelServerSubscriptions(err error) {
 // startCallProc runs fn in a new goroutine and starts tracking it in the h.calls wait group.
 func (h *handler) startCallProc(fn func(*callProc)) {
 	h.callWG.Add(1)
+	atomic.AddInt32(&h.calls, 1)
+	atomic.StoreInt64(&h.lastCall, time.Now().UnixNano())
 	go func() {
 		ctx, cancel := context.WithCancel(h.rootCtx)
 		defer h.callWG.Done()
 		defer cancel()
+		defer func() {
+			atomic.StoreInt64(&h.lastCall, time.Now().UnixNano())
+			atomic.AddInt32(&h.calls, -1)
+		}()
 		fn(&callProc{ctx: ctx})
 	}()
 }
@@ -440,8 +458,21 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
+	var answer *jsonrpcMessage
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args, stream)
+	ctx := withDBStats(cp.ctx, h.dbStats)
+	ctx = kvreaders.WithCaller(ctx, msg.Method, h.conn.remoteAddr()) // reported if the call holds a read transaction too long
+	if callb == h.unsubscribeCb {
+		answer = h.runMethod(ctx, msg, callb, args, stream)
+	} else if release, err := h.scheduler.acquire(ctx, msg.Method); err != nil {
+		answer = msg.errorResponse(err)
+	} else {
+		answer = h.runMethod(ctx, msg, callb, args, stream)
+		release()
+	}
+	if answer != nil && h.dbStats != nil {
+		answer.DBStats = h.dbStats.Snapshot(ctx)
+	}
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -480,6 +511,11 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage, stream *jso
 	}
 	args = args[1:]
 
+	if !h.reserveSubscription() {
+		wsSubscriptionsRejected.Inc()
+		limit := uint64(h.wsLimits.MaxSubscriptions)
+		return msg.errorResponse(&LimitExceededError{Message: "too many subscriptions on the connection", Limit: limit})
+	}
 	// Install notifier in context so the subscription handler can find it.
 	n := &Notifier{h: h, namespace: namespace}
 	cp.notifiers = append(cp.notifiers, n)
@@ -490,13 +526,32 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage, stream *jso
 
 // runMethod runs the Go callback for an RPC method.
 func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value, stream *jsoniter.Stream) *jsonrpcMessage {
+	fields := newFieldSelector(msg.Fields)
 	if !callb.streamable {
 		result, err := callb.call(ctx, msg.Method, args, stream)
 		if err != nil {
 			return msg.errorResponse(err)
 		}
+		if result, err = fields.filter(result); err != nil {
+			return msg.errorResponse(err)
+		}
 		return msg.response(result)
 	}
+	if fields != nil {
+		// streamed result is filtered when it's complete
+		buf := jsoniter.NewStream(jsoniter.ConfigDefault, nil, 4096)
+		if _, err := callb.call(ctx, msg.Method, args, buf); err != nil {
+			return msg.errorResponse(err)
+		}
+		if buf.Error != nil {
+			return msg.errorResponse(buf.Error)
+		}
+		result, err := fields.filterJSON(buf.Buffer())
+		if err != nil {
+			return msg.errorResponse(err)
+		}
+		return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: result}
+	}
 
 	stream.WriteObjectStart()
 	stream.WriteObjectField("jsonrpc")
@@ -514,6 +569,13 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 		stream.WriteMore()
 		HandleError(err, stream)
 	}
+	if h.dbStats != nil {
+		if snapshot := h.dbStats.Snapshot(ctx); snapshot != nil {
+			stream.WriteMore()
+			stream.WriteObjectField("dbStats")
+			stream.WriteVal(snapshot)
+		}
+	}
 	stream.WriteObjectEnd()
 	stream.Flush()
 	return nil
@@ -55,6 +55,8 @@ type jsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+	Fields  []string        `json:"fields,omitempty"`  // extension: only these fields of the result are returned, see fieldSelector
+	DBStats interface{}     `json:"dbStats,omitempty"` // extension: database reads of the call, see DBStatsHeader
 }
 
 func (msg *jsonrpcMessage) isNotification() bool {
@@ -111,12 +113,13 @@ func errorMessage(err error) *jsonrpcMessage {
 		Code:    defaultErrorCode,
 		Message: err.Error(),
 	}}
-	ec, ok := err.(Error)
-	if ok {
+	// errors wrapped with context keep their code and data
+	var ec Error
+	if errors.As(err, &ec) {
 		msg.Error.Code = ec.ErrorCode()
 	}
-	de, ok := err.(DataError)
-	if ok {
+	var de DataError
+	if errors.As(err, &de) {
 		msg.Error.Data = de.ErrorData()
 	}
 	return msg
@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// MethodClass groups RPC methods with similar cost, each class is served by its own worker pool.
+type MethodClass int
+
+const (
+	ClassCheap MethodClass = iota // plain state/chain reads
+	ClassTrace                    // debug_trace*, trace_*, ots_trace* - re-execute transactions
+	ClassLogs                     // eth_getLogs and friends - may walk big block ranges
+
+	classCount
+)
+
+func (c MethodClass) String() string {
+	switch c {
+	case ClassCheap:
+		return "cheap"
+	case ClassTrace:
+		return "trace"
+	case ClassLogs:
+		return "logs"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(c))
+	}
+}
+
+var logsMethods = map[string]struct{}{
+	"eth_getLogs":          {},
+	"eth_getFilterLogs":    {},
+	"erigon_getLogs":       {},
+	"erigon_getLogsByHash": {},
+	"erigon_getLatestLogs": {},
+}
+
+// ClassifyMethod returns the worker pool class of the given RPC method.
+func ClassifyMethod(method string) MethodClass {
+	if _, ok := logsMethods[method]; ok {
+		return ClassLogs
+	}
+	if strings.HasPrefix(method, "trace_") || strings.HasPrefix(method, "debug_trace") || strings.HasPrefix(method, "ots_trace") {
+		return ClassTrace
+	}
+	return ClassCheap
+}
+
+// SchedulerConfig sets amount of workers per method class. 0 workers - class is not limited.
+// QueueLimit - how many requests of one class may wait for a free worker, requests above it are rejected.
+type SchedulerConfig struct {
+	CheapWorkers uint
+	TraceWorkers uint
+	LogsWorkers  uint
+	QueueLimit   uint
+}
+
+// Scheduler bounds the amount of concurrently executing RPC requests per method class,
+// so heavy methods (traces, logs) queue up instead of starving cheap ones.
+// Shared by all connections of a Server.
+type Scheduler struct {
+	pools [classCount]*workerPool
+}
+
+type workerPool struct {
+	workers    chan struct{}
+	queued     int32
+	queueLimit int32
+
+	queueLen *metrics.Counter
+	active   *metrics.Counter
+	rejected *metrics.Counter
+}
+
+func NewScheduler(cfg SchedulerConfig) *Scheduler {
+	s := &Scheduler{}
+	workers := [classCount]uint{ClassCheap: cfg.CheapWorkers, ClassTrace: cfg.TraceWorkers, ClassLogs: cfg.LogsWorkers}
+	for class, n := range workers {
+		if n == 0 {
+			continue
+		}
+		name := MethodClass(class).String()
+		s.pools[class] = &workerPool{
+			workers:    make(chan struct{}, n),
+			queueLimit: int32(cfg.QueueLimit),
+			queueLen:   metrics.GetOrCreateCounter(fmt.Sprintf(`rpc_scheduler_queue{class="%s"}`, name)),
+			active:     metrics.GetOrCreateCounter(fmt.Sprintf(`rpc_scheduler_active{class="%s"}`, name)),
+			rejected:   metrics.GetOrCreateCounter(fmt.Sprintf(`rpc_scheduler_rejected{class="%s"}`, name)),
+		}
+	}
+	return s
+}
+
+// acquire blocks until a worker of the method's class is free. Returned func must be called to release the worker.
+// Fails immediately if the class queue is full, or when ctx is canceled while waiting.
+func (s *Scheduler) acquire(ctx context.Context, method string) (release func(), err error) {
+	if s == nil {
+		return func() {}, nil
+	}
+	class := ClassifyMethod(method)
+	p := s.pools[class]
+	if p == nil {
+		return func() {}, nil
+	}
+	select {
+	case p.workers <- struct{}{}:
+		p.active.Inc()
+		return p.release, nil
+	default:
+	}
+
+	if atomic.AddInt32(&p.queued, 1) > p.queueLimit {
+		atomic.AddInt32(&p.queued, -1)
+		p.rejected.Inc()
+		return nil, &limitExceededError{class: class}
+	}
+	p.queueLen.Inc()
+	defer func() {
+		atomic.AddInt32(&p.queued, -1)
+		p.queueLen.Dec()
+	}()
+	select {
+	case p.workers <- struct{}{}:
+		p.active.Inc()
+		return p.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *workerPool) release() {
+	p.active.Dec()
+	<-p.workers
+}
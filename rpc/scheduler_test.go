@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyMethod(t *testing.T) {
+	require.Equal(t, ClassCheap, ClassifyMethod("eth_blockNumber"))
+	require.Equal(t, ClassCheap, ClassifyMethod("debug_storageRangeAt"))
+	require.Equal(t, ClassTrace, ClassifyMethod("trace_block"))
+	require.Equal(t, ClassTrace, ClassifyMethod("debug_traceTransaction"))
+	require.Equal(t, ClassTrace, ClassifyMethod("ots_traceTransaction"))
+	require.Equal(t, ClassLogs, ClassifyMethod("eth_getLogs"))
+	require.Equal(t, ClassLogs, ClassifyMethod("erigon_getLogsByHash"))
+}
+
+func TestSchedulerQueueAndReject(t *testing.T) {
+	s := NewScheduler(SchedulerConfig{TraceWorkers: 1, QueueLimit: 1})
+	ctx := context.Background()
+
+	// cheap class is not limited
+	for i := 0; i < 10; i++ {
+		_, err := s.acquire(ctx, "eth_blockNumber")
+		require.NoError(t, err)
+	}
+
+	release, err := s.acquire(ctx, "trace_block")
+	require.NoError(t, err)
+
+	acquired := make(chan func())
+	go func() {
+		r, err := s.acquire(ctx, "trace_transaction")
+		if err == nil {
+			acquired <- r
+		}
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&s.pools[ClassTrace].queued) == 1 }, time.Second, time.Millisecond)
+
+	// worker is busy and queue is full
+	_, err = s.acquire(ctx, "trace_call")
+	var limitErr *limitExceededError
+	require.ErrorAs(t, err, &limitErr)
+
+	release()
+	r := <-acquired
+	r()
+
+	// waiting request gives up when its context is canceled
+	release, err = s.acquire(ctx, "trace_block")
+	require.NoError(t, err)
+	defer release()
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.acquire(cancelCtx, "trace_block")
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"io"
+	"sync"
 	"sync/atomic"
 
 	mapset "github.com/deckarep/golang-set"
@@ -45,6 +46,10 @@ const (
 type Server struct {
 	services        serviceRegistry
 	methodAllowList AllowList
+	scheduler       *Scheduler
+	dbStats         DBStats
+	wsLimits        WebsocketLimits
+	wsConns         int32 // atomic, served websocket connections
 	idgen           func() ID
 	run             int32
 	codecs          mapset.Set
@@ -52,6 +57,10 @@ type Server struct {
 	batchConcurrency uint
 	disableStreaming bool
 	traceRequests    bool // Whether to print requests at INFO level
+
+	// guards settings which may be replaced while serving (configuration reload): methodAllowList, scheduler,
+	// wsLimits, batchConcurrency. Requests and connections keep the settings they were accepted with.
+	settingsLock sync.RWMutex
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -66,9 +75,44 @@ func NewServer(batchConcurrency uint, traceRequests, disableStreaming bool) *Ser
 
 // SetAllowList sets the allow list for methods that are handled by this server
 func (s *Server) SetAllowList(allowList AllowList) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
 	s.methodAllowList = allowList
 }
 
+// SetScheduler sets the per-method-class worker pools used to limit concurrent requests
+func (s *Server) SetScheduler(scheduler *Scheduler) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.scheduler = scheduler
+}
+
+// SetDBStats sets the gatherer of database read statistics returned to http requests with DBStatsHeader
+func (s *Server) SetDBStats(dbStats DBStats) {
+	s.dbStats = dbStats
+}
+
+// SetWebsocketLimits sets limits of websocket connections and of resources held by each of them
+func (s *Server) SetWebsocketLimits(limits WebsocketLimits) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.wsLimits = limits
+}
+
+// SetBatchConcurrency sets amount of requests of a batch executed at once
+func (s *Server) SetBatchConcurrency(batchConcurrency uint) {
+	s.settingsLock.Lock()
+	defer s.settingsLock.Unlock()
+	s.batchConcurrency = batchConcurrency
+}
+
+// settings returns the current settings of new requests and connections
+func (s *Server) settings() (allowList AllowList, scheduler *Scheduler, wsLimits WebsocketLimits, batchConcurrency uint) {
+	s.settingsLock.RLock()
+	defer s.settingsLock.RUnlock()
+	return s.methodAllowList, s.scheduler, s.wsLimits, s.batchConcurrency
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -83,6 +127,11 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, nil)
+}
+
+// serveCodec is ServeCodec with limits of a websocket connection, nil - the connection is not limited
+func (s *Server) serveCodec(codec ServerCodec, wsLimits *WebsocketLimits) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -94,7 +143,8 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	_, scheduler, _, _ := s.settings()
+	c := initClient(codec, s.idgen, &s.services, scheduler, wsLimits)
 	<-codec.closed()
 	c.Close()
 }
@@ -108,8 +158,10 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stre
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.traceRequests)
+	allowList, scheduler, _, batchConcurrency := s.settings()
+	h := newHandler(ctx, codec, s.idgen, &s.services, allowList, batchConcurrency, s.traceRequests, scheduler)
 	h.allowSubscribe = false
+	h.dbStats = s.dbStats
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"io"
 	"net"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"sort"
@@ -47,7 +48,7 @@ func TestServerRegisterName(t *testing.T) {
 		t.Fatalf("Expected service calc to be registered")
 	}
 
-	wantCallbacks := 9
+	wantCallbacks := 10
 	if len(svc.callbacks) != wantCallbacks {
 		t.Errorf("Expected %d callbacks for service 'service', got %d", wantCallbacks, len(svc.callbacks))
 	}
@@ -166,3 +167,36 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+// Settings replaced while serving apply to the following requests.
+func TestServerSettingsReplaced(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal("can't dial:", err)
+	}
+	defer client.Close()
+
+	var resp echoResult
+	if err := client.Call(&resp, "test_echo", "x", 1); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	server.SetAllowList(AllowList{"rpc_modules": {}})
+	err = client.Call(&resp, "test_echo", "x", 1)
+	if err == nil || !strings.Contains(err.Error(), "is not available") {
+		t.Fatalf("expected the method not allowed, got %v", err)
+	}
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	server.SetAllowList(nil)
+	if err := client.Call(&resp, "test_echo", "x", 1); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
@@ -137,6 +137,9 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	if n.activated {
 		return n.send(n.sub, enc)
 	}
+	if q := n.h.sendQueue; q != nil && len(n.buffer) >= cap(q.msgs) {
+		return q.evict(len(n.buffer) + 1)
+	}
 	n.buffer = append(n.buffer, enc)
 	return nil
 }
@@ -174,12 +177,15 @@ func (n *Notifier) activate() error {
 
 func (n *Notifier) send(sub *Subscription, data json.RawMessage) error {
 	params, _ := json.Marshal(&subscriptionResult{ID: string(sub.ID), Result: data})
-	ctx := context.Background()
-	return n.h.conn.writeJSON(ctx, &jsonrpcMessage{
+	msg := &jsonrpcMessage{
 		Version: vsn,
 		Method:  n.namespace + notificationMethodSuffix,
 		Params:  params,
-	})
+	}
+	if n.h.sendQueue != nil {
+		return n.h.sendQueue.send(msg)
+	}
+	return n.h.conn.writeJSON(context.Background(), msg)
 }
 
 // A Subscription is created by a notifier and tied to that notifier. The client can use
@@ -0,0 +1,14 @@
+// This test calls the test_echo method with the "fields" extension member:
+// only selected fields of the result are returned.
+
+--> {"jsonrpc": "2.0", "id": 2, "method": "test_echo", "params": ["x", 3, {"S": "foo"}], "fields": ["Int"]}
+<-- {"jsonrpc":"2.0","id":2,"result":{"Int":3}}
+
+--> {"jsonrpc": "2.0", "id": 2, "method": "test_echo", "params": ["x", 3, {"S": "foo"}], "fields": ["Args.S", "String", "Missing"]}
+<-- {"jsonrpc":"2.0","id":2,"result":{"String":"x","Args":{"S":"foo"}}}
+
+--> {"jsonrpc": "2.0", "id": 2, "method": "test_echo", "params": ["x", 3], "fields": ["Args.S"]}
+<-- {"jsonrpc":"2.0","id":2,"result":{"Args":null}}
+
+--> {"jsonrpc": "2.0", "id": 2, "method": "test_echoStream", "params": ["x", 3], "fields": ["Int"]}
+<-- {"jsonrpc":"2.0","id":2,"result":{"Int":3}}
@@ -23,6 +23,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	jsoniter "github.com/json-iterator/go"
 )
 
 func newTestServer() *Server {
@@ -80,6 +82,17 @@ func (s *testService) EchoWithCtx(ctx context.Context, str string, i int, args *
 	return echoResult{str, i, args}
 }
 
+func (s *testService) EchoStream(str string, i int, stream *jsoniter.Stream) error {
+	stream.WriteObjectStart()
+	stream.WriteObjectField("String")
+	stream.WriteString(str)
+	stream.WriteMore()
+	stream.WriteObjectField("Int")
+	stream.WriteInt(i)
+	stream.WriteObjectEnd()
+	return nil
+}
+
 func (s *testService) Sleep(ctx context.Context, duration time.Duration) {
 	time.Sleep(duration)
 }
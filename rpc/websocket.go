@@ -25,6 +25,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	mapset "github.com/deckarep/golang-set"
@@ -58,13 +59,23 @@ func (s *Server) WebsocketHandler(allowedOrigins []string, jwtSecret []byte, com
 		if jwtSecret != nil && !CheckJwtSecret(w, r, jwtSecret) {
 			return
 		}
+		_, _, limits, _ := s.settings()
+		if n := atomic.AddInt32(&s.wsConns, 1); limits.MaxConnections > 0 && int(n) > limits.MaxConnections {
+			atomic.AddInt32(&s.wsConns, -1)
+			wsConnectionsRejected.Inc()
+			http.Error(w, "too many websocket connections", http.StatusServiceUnavailable)
+			return
+		}
+		defer atomic.AddInt32(&s.wsConns, -1)
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Warn("WebSocket upgrade failed", "err", err)
 			return
 		}
+		wsConnections.Inc()
+		defer wsConnections.Dec()
 		codec := newWebsocketCodec(conn)
-		s.ServeCodec(codec, 0)
+		s.serveCodec(codec, &limits)
 	})
 }
 
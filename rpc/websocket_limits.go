@@ -0,0 +1,141 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// WebsocketLimits bound resources which one websocket client can hold. Zero values - not limited.
+type WebsocketLimits struct {
+	MaxConnections   int           // concurrent websocket connections of a Server, new ones are rejected with 503
+	IdleTimeout      time.Duration // connection without requests in progress and subscriptions is closed after it
+	MaxSubscriptions int           // active subscriptions of a connection, eth_subscribe above it fails with -32005
+	SendQueue        int           // notifications waiting to be written to a connection, slow client is evicted above it
+}
+
+var (
+	wsConnections           = metrics.GetOrCreateCounter("rpc_ws_connections")
+	wsConnectionsRejected   = metrics.GetOrCreateCounter("rpc_ws_connections_rejected")
+	wsIdleClosed            = metrics.GetOrCreateCounter("rpc_ws_idle_closed")
+	wsSubscriptionsRejected = metrics.GetOrCreateCounter("rpc_ws_subscriptions_rejected")
+	wsEvicted               = metrics.GetOrCreateCounter("rpc_ws_evicted")
+	wsDroppedNotifications  = metrics.GetOrCreateCounter("rpc_ws_dropped_notifications")
+)
+
+// ErrSlowConsumer is returned by Notify when the client doesn't read notifications fast enough and is disconnected
+var ErrSlowConsumer = errors.New("client is too slow to receive notifications, disconnected")
+
+// sendQueue writes notifications of a connection on its own goroutine: producers of events don't wait for
+// slow clients, and a client which lets more than the queue size pile up is disconnected instead of buffering
+// without bound.
+type sendQueue struct {
+	codec   ServerCodec
+	msgs    chan *jsonrpcMessage
+	evicted int32 // atomic
+	log     log.Logger
+}
+
+func newSendQueue(codec ServerCodec, size int, logger log.Logger) *sendQueue {
+	q := &sendQueue{codec: codec, msgs: make(chan *jsonrpcMessage, size), log: logger}
+	go q.loop()
+	return q
+}
+
+func (q *sendQueue) loop() {
+	for {
+		select {
+		case msg := <-q.msgs:
+			if err := q.codec.writeJSON(context.Background(), msg); err != nil {
+				q.codec.close()
+				return
+			}
+		case <-q.codec.closed():
+			return
+		}
+	}
+}
+
+func (q *sendQueue) send(msg *jsonrpcMessage) error {
+	select {
+	case <-q.codec.closed():
+		return ErrClientQuit
+	default:
+	}
+	select {
+	case q.msgs <- msg:
+		return nil
+	default:
+		return q.evict(1)
+	}
+}
+
+// evict disconnects the client, dropped - notifications which didn't fit into the queue
+func (q *sendQueue) evict(dropped int) error {
+	if atomic.CompareAndSwapInt32(&q.evicted, 0, 1) {
+		dropped += len(q.msgs)
+		q.log.Warn("Evicting slow websocket client", "queued", dropped)
+		wsEvicted.Inc()
+		q.codec.close()
+	}
+	wsDroppedNotifications.Add(dropped)
+	return ErrSlowConsumer
+}
+
+// setWebsocketLimits applies per-connection limits to the handler of a served websocket connection
+func (h *handler) setWebsocketLimits(codec ServerCodec, limits WebsocketLimits) {
+	h.wsLimits = limits
+	if limits.SendQueue > 0 {
+		h.sendQueue = newSendQueue(codec, limits.SendQueue, h.log)
+	}
+	if limits.IdleTimeout > 0 {
+		go h.closeIdle(codec, limits.IdleTimeout)
+	}
+}
+
+// reserveSubscription counts a subscribe call in progress against MaxSubscriptions, false if the limit is reached.
+// The reservation is released by addSubscriptions when the call is done.
+func (h *handler) reserveSubscription() bool {
+	h.subLock.Lock()
+	defer h.subLock.Unlock()
+	if h.wsLimits.MaxSubscriptions > 0 && len(h.serverSubs)+h.pendingSubs >= h.wsLimits.MaxSubscriptions {
+		return false
+	}
+	h.pendingSubs++
+	return true
+}
+
+func (h *handler) subscriptionsCount() int {
+	h.subLock.Lock()
+	defer h.subLock.Unlock()
+	return len(h.serverSubs) + h.pendingSubs
+}
+
+// closeIdle closes the connection when it has no requests in progress and no subscriptions for timeout
+func (h *handler) closeIdle(codec ServerCodec, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-codec.closed():
+			return
+		case <-timer.C:
+		}
+		if atomic.LoadInt32(&h.calls) > 0 || h.subscriptionsCount() > 0 {
+			timer.Reset(timeout)
+			continue
+		}
+		if idle := time.Since(time.Unix(0, atomic.LoadInt64(&h.lastCall))); idle < timeout {
+			timer.Reset(timeout - idle)
+			continue
+		}
+		h.log.Debug("Closing idle websocket connection", "timeout", timeout)
+		wsIdleClosed.Inc()
+		codec.close()
+		return
+	}
+}
@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimitedWebsocketServer(t *testing.T, limits WebsocketLimits) (srv *Server, wsURL string) {
+	srv = newTestServer()
+	srv.SetWebsocketLimits(limits)
+	httpsrv := httptest.NewServer(srv.WebsocketHandler([]string{"*"}, nil, false))
+	t.Cleanup(func() {
+		httpsrv.Close()
+		srv.Stop()
+	})
+	return srv, "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+}
+
+// waitClosed reads from the connection until the server closes it
+func waitClosed(t *testing.T, conn *websocket.Conn, timeout time.Duration) {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(timeout)))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var netErr interface{ Timeout() bool }
+			require.False(t, errors.As(err, &netErr) && netErr.Timeout(), "connection wasn't closed")
+			return
+		}
+	}
+}
+
+func TestWebsocketMaxConnections(t *testing.T) {
+	_, wsURL := newLimitedWebsocketServer(t, WebsocketLimits{MaxConnections: 1})
+	ctx := context.Background()
+
+	c1, err := DialWebsocket(ctx, wsURL, "")
+	require.NoError(t, err)
+	_, err = DialWebsocket(ctx, wsURL, "")
+	require.EqualError(t, err, wsHandshakeError{websocket.ErrBadHandshake, "503 Service Unavailable"}.Error())
+
+	c1.Close()
+	require.Eventually(t, func() bool {
+		c, err := DialWebsocket(ctx, wsURL, "")
+		if err != nil {
+			return false
+		}
+		c.Close()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWebsocketMaxSubscriptions(t *testing.T) {
+	_, wsURL := newLimitedWebsocketServer(t, WebsocketLimits{MaxSubscriptions: 2})
+	ctx := context.Background()
+	client, err := DialWebsocket(ctx, wsURL, "")
+	require.NoError(t, err)
+	defer client.Close()
+
+	ch := make(chan int)
+	sub1, err := client.Subscribe(ctx, "nftest", ch, "someSubscription", 0, 0)
+	require.NoError(t, err)
+	_, err = client.Subscribe(ctx, "nftest", ch, "someSubscription", 0, 0)
+	require.NoError(t, err)
+	_, err = client.Subscribe(ctx, "nftest", ch, "someSubscription", 0, 0)
+	var rpcErr Error
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, ErrCodeLimitExceeded, rpcErr.ErrorCode())
+
+	// unsubscribing frees a slot
+	sub1.Unsubscribe()
+	require.Eventually(t, func() bool {
+		sub, err := client.Subscribe(ctx, "nftest", ch, "someSubscription", 0, 0)
+		if err != nil {
+			return false
+		}
+		sub.Unsubscribe()
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWebsocketIdleTimeout(t *testing.T) {
+	_, wsURL := newLimitedWebsocketServer(t, WebsocketLimits{IdleTimeout: 100 * time.Millisecond})
+
+	idle, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer idle.Close()
+	waitClosed(t, idle, 5*time.Second)
+
+	// connection with a subscription isn't idle
+	subscribed, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer subscribed.Close()
+	require.NoError(t, subscribed.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"method":"nftest_subscribe","params":["someSubscription",0,0]}`)))
+	require.NoError(t, subscribed.SetReadDeadline(time.Now().Add(500*time.Millisecond)))
+	_, _, err = subscribed.ReadMessage()
+	require.NoError(t, err)
+	_, _, err = subscribed.ReadMessage()
+	var netErr interface{ Timeout() bool }
+	require.True(t, errors.As(err, &netErr) && netErr.Timeout(), err)
+}
+
+func TestWebsocketSlowConsumer(t *testing.T) {
+	_, wsURL := newLimitedWebsocketServer(t, WebsocketLimits{SendQueue: 4})
+	evicted, dropped := wsEvicted.Get(), wsDroppedNotifications.Get()
+
+	// the client subscribes to a flood of notifications and doesn't read them
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"method":"nftest_subscribe","params":["someSubscription",1000000,0]}`)))
+
+	require.Eventually(t, func() bool { return wsEvicted.Get() > evicted }, 5*time.Second, 10*time.Millisecond)
+	require.Greater(t, wsDroppedNotifications.Get(), dropped)
+	waitClosed(t, conn, 5*time.Second)
+}
@@ -0,0 +1,72 @@
+package eth
+
+import (
+	"errors"
+
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+)
+
+// newPacket returns the packet which the message of the given code is decoded into by the sentry and its clients
+func newPacket(code byte) interface{} {
+	switch code % 12 {
+	case 0:
+		return new(eth.StatusPacket)
+	case 1:
+		return new(eth.NewBlockHashesPacket)
+	case 2:
+		return new(eth.NewPooledTransactionHashesPacket)
+	case 3:
+		return new(eth.GetBlockHeadersPacket66)
+	case 4:
+		return new(eth.BlockHeadersPacket66)
+	case 5:
+		return new(eth.GetBlockBodiesPacket66)
+	case 6:
+		return new(eth.BlockRawBodiesPacket66)
+	case 7:
+		return new(eth.NewBlockPacket)
+	case 8:
+		return new(eth.GetReceiptsPacket66)
+	case 9:
+		return new(eth.GetNodeDataPacket66)
+	case 10:
+		return new(eth.GetPooledTransactionsPacket66)
+	default:
+		return new(eth.PooledTransactionsPacket66)
+	}
+}
+
+// Fuzz decodes a devp2p message: the first byte of the input selects the packet, the rest is its payload.
+// Decoding must fail with an error instead of a panic, and a decoded packet must be safe for its handler.
+func Fuzz(input []byte) int {
+	if len(input) == 0 {
+		return 0
+	}
+	packet := newPacket(input[0])
+	if err := eth.DecodeMessage(input[1:], packet); err != nil {
+		if errors.Is(err, eth.ErrDecodingPanicked) {
+			panic(err)
+		}
+		return 0
+	}
+	// the values read by the handlers right after decoding
+	switch p := packet.(type) {
+	case *eth.StatusPacket:
+		_ = p.TD.BitLen()
+	case *eth.NewBlockHashesPacket:
+		p.Unpack()
+	case *eth.GetBlockHeadersPacket66:
+		_ = p.Origin.Hash
+	case *eth.BlockHeadersPacket66:
+		for _, header := range p.BlockHeadersPacket {
+			_ = header.Number.Uint64()
+			_ = header.Hash()
+		}
+	case *eth.BlockRawBodiesPacket66:
+		p.BlockRawBodiesPacket.Unpack()
+	case *eth.NewBlockPacket:
+		_ = p.Block.NumberU64()
+		_ = p.Block.Hash()
+	}
+	return 1
+}
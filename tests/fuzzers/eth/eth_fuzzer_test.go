@@ -0,0 +1,83 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func seed(t testing.TB, code byte, packet interface{}) []byte {
+	b, err := rlp.EncodeToBytes(packet)
+	require.NoError(t, err)
+	return append([]byte{code}, b...)
+}
+
+func seeds(t testing.TB) [][]byte {
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(2), GasLimit: 3}
+	return [][]byte{
+		seed(t, 0, &eth.StatusPacket{ProtocolVersion: eth.ETH66, NetworkID: 1, TD: big.NewInt(1)}),
+		seed(t, 1, &eth.NewBlockHashesPacket{{Hash: common.Hash{1}, Number: 1}}),
+		seed(t, 2, &eth.NewPooledTransactionHashesPacket{common.Hash{1}}),
+		seed(t, 3, &eth.GetBlockHeadersPacket66{RequestId: 1, GetBlockHeadersPacket: &eth.GetBlockHeadersPacket{Origin: eth.HashOrNumber{Number: 1}, Amount: 1}}),
+		seed(t, 4, &eth.BlockHeadersPacket66{RequestId: 1, BlockHeadersPacket: eth.BlockHeadersPacket{header}}),
+		seed(t, 5, &eth.GetBlockBodiesPacket66{RequestId: 1, GetBlockBodiesPacket: eth.GetBlockBodiesPacket{common.Hash{1}}}),
+		seed(t, 6, &eth.BlockRawBodiesPacket66{RequestId: 1, BlockRawBodiesPacket: eth.BlockRawBodiesPacket{{Transactions: [][]byte{}, Uncles: []*types.Header{header}}}}),
+		seed(t, 7, &eth.NewBlockPacket{Block: types.NewBlockWithHeader(header), TD: big.NewInt(1)}),
+		seed(t, 8, &eth.GetReceiptsPacket66{RequestId: 1, GetReceiptsPacket: eth.GetReceiptsPacket{common.Hash{1}}}),
+		seed(t, 9, &eth.GetNodeDataPacket66{RequestId: 1, GetNodeDataPacket: eth.GetNodeDataPacket{common.Hash{1}}}),
+		seed(t, 10, &eth.GetPooledTransactionsPacket66{RequestId: 1, GetPooledTransactionsPacket: eth.GetPooledTransactionsPacket{common.Hash{1}}}),
+		seed(t, 11, &eth.PooledTransactionsPacket66{RequestId: 1}),
+	}
+}
+
+func TestSeeds(t *testing.T) {
+	for _, input := range seeds(t) {
+		require.Equal(t, 1, Fuzz(input), "packet %d", input[0])
+		for i := 1; i < len(input); i++ {
+			require.Equal(t, 0, Fuzz(input[:i]), "packet %d truncated to %d", input[0], i)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(2)}
+	headers := make(eth.BlockHeadersPacket, eth.MaxHeadersServe+1)
+	for i := range headers {
+		headers[i] = header
+	}
+	for _, tt := range []struct {
+		name         string
+		packet, into interface{}
+	}{
+		{"announces", make(eth.NewPooledTransactionHashesPacket, 4097), new(eth.NewPooledTransactionHashesPacket)},
+		{"headers", &eth.BlockHeadersPacket66{BlockHeadersPacket: headers}, new(eth.BlockHeadersPacket66)},
+		{"uncles", &eth.BlockRawBodiesPacket66{BlockRawBodiesPacket: eth.BlockRawBodiesPacket{{Uncles: []*types.Header{header, header, header}}}}, new(eth.BlockRawBodiesPacket66)},
+		{"queried", &eth.GetBlockBodiesPacket66{GetBlockBodiesPacket: make(eth.GetBlockBodiesPacket, 4097)}, new(eth.GetBlockBodiesPacket66)},
+		{"TD", &eth.StatusPacket{TD: new(big.Int).Lsh(big.NewInt(1), 256)}, new(eth.StatusPacket)},
+	} {
+		b, err := rlp.EncodeToBytes(tt.packet)
+		require.NoError(t, err, tt.name)
+		require.ErrorIs(t, eth.DecodeMessage(b, tt.into), eth.ErrMalformedMessage, tt.name)
+	}
+	require.ErrorIs(t, eth.DecodeMessage(make([]byte, eth.ProtocolMaxMsgSize+1), new(eth.StatusPacket)), eth.ErrMalformedMessage)
+
+	// difficulties of AuRa chains are 128 bits
+	b, err := rlp.EncodeToBytes(&eth.BlockHeadersPacket66{BlockHeadersPacket: eth.BlockHeadersPacket{{Number: big.NewInt(1), Difficulty: new(big.Int).Lsh(big.NewInt(1), 127)}}})
+	require.NoError(t, err)
+	require.NoError(t, eth.DecodeMessage(b, new(eth.BlockHeadersPacket66)))
+}
+
+// FuzzMessages runs Fuzz with the native go fuzzing: go test -fuzz FuzzMessages ./tests/fuzzers/eth
+func FuzzMessages(f *testing.F) {
+	for _, input := range seeds(f) {
+		f.Add(input)
+	}
+	f.Fuzz(func(t *testing.T, input []byte) {
+		Fuzz(input)
+	})
+}
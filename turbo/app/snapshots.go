@@ -54,6 +54,19 @@ var snapshotCommand = cli.Command{
 				SnapshotSegmentSizeFlag,
 			}, debug.Flags...),
 		},
+		{
+			Name:   "publish",
+			Action: doPublishCommand,
+			Usage:  "Create snapshots for given range of blocks, their torrents and signed manifest, upload them",
+			Before: func(ctx *cli.Context) error { return debug.Setup(ctx) },
+			Flags: append([]cli.Flag{
+				utils.DataDirFlag,
+				SnapshotFromFlag,
+				SnapshotToFlag,
+				SnapshotPublishKeyFlag,
+				SnapshotUploadURLFlag,
+			}, debug.Flags...),
+		},
 		{
 			Name:   "index",
 			Action: doIndicesCommand,
@@ -130,6 +143,14 @@ var (
 		Name:  "rebuild",
 		Usage: "Force rebuild",
 	}
+	SnapshotPublishKeyFlag = cli.StringFlag{
+		Name:  "publish.key",
+		Usage: "File with hex secp256k1 private key signing the manifest, its address identifies the publisher",
+	}
+	SnapshotUploadURLFlag = cli.StringFlag{
+		Name:  "upload.url",
+		Usage: "Upload the files with HTTP PUT to <url>/<file name>, e.g. an object storage bucket. User and password of the url are sent as basic auth",
+	}
 )
 
 func preloadFileAsync(name string) {
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/dir"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/cmd/downloader/downloader"
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli"
+)
+
+// doPublishCommand - everything a snapshot publisher needs in one step: block segments of the range, their torrents,
+// the manifest listing them signed by the publisher, and optionally upload of all of it
+func doPublishCommand(cliCtx *cli.Context) error {
+	ctx, cancel := common.RootContext()
+	defer cancel()
+
+	fromBlock := cliCtx.Uint64(SnapshotFromFlag.Name)
+	toBlock := cliCtx.Uint64(SnapshotToFlag.Name)
+	if !cliCtx.IsSet(SnapshotPublishKeyFlag.Name) {
+		return fmt.Errorf("--%s is required", SnapshotPublishKeyFlag.Name)
+	}
+	key, err := crypto.LoadECDSA(cliCtx.String(SnapshotPublishKeyFlag.Name))
+	if err != nil {
+		return fmt.Errorf("--%s: %w", SnapshotPublishKeyFlag.Name, err)
+	}
+	var uploadURL *url.URL
+	if cliCtx.IsSet(SnapshotUploadURLFlag.Name) {
+		if uploadURL, err = url.Parse(cliCtx.String(SnapshotUploadURLFlag.Name)); err != nil {
+			return fmt.Errorf("--%s: %w", SnapshotUploadURLFlag.Name, err)
+		}
+	}
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	dir.MustExist(dirs.Snap)
+	dir.MustExist(dirs.Tmp)
+
+	db := mdbx.NewMDBX(log.New()).Label(kv.ChainDB).Path(dirs.Chaindata).MustOpen()
+	defer db.Close()
+
+	// only segments of the standard size are seedable, so they are the only ones published
+	if err := snapshotBlocks(ctx, db, fromBlock, toBlock, snap.Erigon2SegmentSize, dirs.Snap, dirs.Tmp); err != nil {
+		return err
+	}
+	allSnapshots := snapshotsync.NewRoSnapshots(ethconfig.NewSnapCfg(true, true, true), dirs.Snap)
+	if err := allSnapshots.ReopenFolder(); err != nil {
+		return err
+	}
+	if err := db.Update(ctx, func(tx kv.RwTx) error {
+		return rawdb.WriteSnapshots(tx, allSnapshots.Files())
+	}); err != nil {
+		return err
+	}
+
+	seedable, err := downloader.BuildTorrentFilesIfNeed(ctx, dirs.Snap)
+	if err != nil {
+		return err
+	}
+	names, err := publishedSegments(dirs.Snap, seedable, fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no seedable segments in blocks %d-%d", fromBlock, toBlock)
+	}
+
+	manifest, err := downloader.BuildManifest(dirs.Snap, names)
+	if err != nil {
+		return err
+	}
+	if err := manifest.Sign(key); err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(dirs.Snap, downloader.ManifestFileName)
+	if err := manifest.Write(manifestPath); err != nil {
+		return err
+	}
+	log.Info("[Snapshots] Manifest created", "path", manifestPath, "files", len(names), "publisher", manifest.Publisher)
+
+	if uploadURL == nil {
+		return nil
+	}
+	uploads := make([]string, 0, 2*len(names)+1)
+	for _, name := range names {
+		uploads = append(uploads, name, name+".torrent")
+	}
+	uploads = append(uploads, downloader.ManifestFileName) // the last one: complete uploads are these with the manifest
+	for i, name := range uploads {
+		log.Info("[Snapshots] Uploading", "file", name, "progress", fmt.Sprintf("%d/%d", i+1, len(uploads)))
+		if err := uploadFile(ctx, uploadURL, dirs.Snap, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishedSegments - seedable block segments within fromBlock..toBlock, toBlock 0 is unlimited
+func publishedSegments(snapDir string, seedable []string, fromBlock, toBlock uint64) ([]string, error) {
+	var names []string
+	for _, name := range seedable {
+		if strings.Contains(name, string(filepath.Separator)) { // state history of history/, it isn't of block ranges
+			continue
+		}
+		f, err := snap.ParseFileName(snapDir, name)
+		if err != nil {
+			return nil, err
+		}
+		if f.From >= fromBlock && (toBlock == 0 || f.To <= toBlock) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func uploadFile(ctx context.Context, base *url.URL, snapDir, name string) error {
+	f, err := os.Open(filepath.Join(snapDir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	target := *base
+	target.Path = path.Join(target.Path, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = stat.Size()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload of %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("upload of %s: %s", name, resp.Status)
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 var DefaultFlags = []cli.Flag{
 	utils.DataDirFlag,
 	utils.EthashDatasetDirFlag,
+	utils.EthashSealVerifierFlag,
 	utils.SnapshotFlag,
 	utils.LightClientFlag,
 	utils.TxPoolDisableFlag,
@@ -23,6 +24,7 @@ var DefaultFlags = []cli.Flag{
 	utils.TxPoolAccountQueueFlag,
 	utils.TxPoolGlobalQueueFlag,
 	utils.TxPoolLifetimeFlag,
+	utils.TxPoolCommitEveryFlag,
 	utils.TxPoolTraceSendersFlag,
 	PruneFlag,
 	PruneHistoryFlag,
@@ -45,6 +47,21 @@ var DefaultFlags = []cli.Flag{
 	TLSCACertFlag,
 	StateStreamDisableFlag,
 	SyncLoopThrottleFlag,
+	DirtyShutdownVerifyFlag,
+	NodeDataRateFlag,
+	RebuildReceiptsFlag,
+	RebuildReceiptsThrottleFlag,
+	ReshardIndexFlag,
+	ReshardIndexThrottleFlag,
+	SyncWatchdogFlag,
+	SyncWatchdogRestartFlag,
+	SyncModeFlag,
+	SyncExternalExecFlag,
+	SyncProfileFlag,
+	ExportSinkFlag,
+	ExportBatchFlag,
+	StateRecentBlocksFlag,
+	StateRecentMemoryFlag,
 	BadBlockFlag,
 
 	utils.HTTPEnabledFlag,
@@ -60,9 +77,20 @@ var DefaultFlags = []cli.Flag{
 	utils.HTTPApiFlag,
 	utils.WSEnabledFlag,
 	utils.WsCompressionFlag,
+	utils.WsMaxConnectionsFlag,
+	utils.WsIdleTimeoutFlag,
+	utils.WsMaxSubscriptionsFlag,
+	utils.WsSendQueueFlag,
 	utils.HTTPTraceFlag,
 	utils.StateCacheFlag,
 	utils.RpcBatchConcurrencyFlag,
+	utils.RpcWorkersCheapFlag,
+	utils.RpcWorkersTraceFlag,
+	utils.RpcWorkersLogsFlag,
+	utils.RpcWorkersQueueFlag,
+	utils.RpcReadersFreelistFlag,
+	utils.RpcReadersMaxAgeFlag,
+	utils.RpcReadersActionFlag,
 	utils.RpcStreamingDisableFlag,
 	utils.DBReadConcurrencyFlag,
 	utils.RpcAccessListFlag,
@@ -70,6 +98,26 @@ var DefaultFlags = []cli.Flag{
 	utils.RpcGasCapFlag,
 	utils.TxpoolApiAddrFlag,
 	utils.TraceMaxtracesFlag,
+	utils.RpcLogsMaxRangeFlag,
+	utils.RpcLogsMaxResultsFlag,
+	utils.RpcReceiptsRevertReasonFlag,
+	utils.RpcNonCanonicalTxsFlag,
+	utils.TxPoolScheduledLimitFlag,
+	utils.TxPoolScheduledSenderLimitFlag,
+	utils.TxPoolScheduledMaxBlocksFlag,
+	utils.TxPoolScheduledMaxTimeFlag,
+	utils.OtsLabelsPathFlag,
+	utils.OtsCreatorsPathFlag,
+	utils.OtsSourcifySourceFlag,
+	utils.OtsSourcifyCachePathFlag,
+	utils.OtsSignaturesPathFlag,
+	utils.OtsSearchWorkersFlag,
+	utils.OtsSearchCacheFlag,
+	utils.OtsOperationsPathFlag,
+	utils.RpcTraceExportDirFlag,
+	utils.RpcTraceExportURLFlag,
+	utils.RpcTraceExportRetentionFlag,
+	utils.RpcTraceBlockWorkersFlag,
 	HTTPReadTimeoutFlag,
 	HTTPWriteTimeoutFlag,
 	HTTPIdleTimeoutFlag,
@@ -81,6 +129,12 @@ var DefaultFlags = []cli.Flag{
 	utils.SnapKeepBlocksFlag,
 	utils.SnapStopFlag,
 	utils.DbPageSizeFlag,
+	utils.DbSizeLimitFlag,
+	utils.DbGrowthStepFlag,
+	utils.DbSyncModeFlag,
+	utils.DbSyncPeriodFlag,
+	utils.DbReadaheadFlag,
+	utils.DbDirtyPagesLimitFlag,
 	utils.TorrentPortFlag,
 	utils.TorrentMaxPeersFlag,
 	utils.TorrentConnsPerFileFlag,
@@ -90,6 +144,10 @@ var DefaultFlags = []cli.Flag{
 	utils.TorrentVerbosityFlag,
 	utils.ListenPortFlag,
 	utils.P2pProtocolVersionFlag,
+	utils.P2pTransportFlag,
+	utils.P2pPeerExchangeFlag,
+	utils.P2pMaxPeersPerSubnetFlag,
+	utils.P2pPeerSelectionFlag,
 	utils.NATFlag,
 	utils.NoDiscoverFlag,
 	utils.DiscoveryV5Flag,
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 
 	"github.com/c2h5oh/datasize"
@@ -16,6 +17,8 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/kvreaders"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/node/nodecfg"
 	"github.com/ledgerwatch/log/v3"
@@ -144,6 +147,86 @@ var (
 		Value: "",
 	}
 
+	DirtyShutdownVerifyFlag = cli.Uint64Flag{
+		Name:  "sync.dirtyshutdown.verify",
+		Usage: "Amount of last blocks of each stage to re-verify (and repair) on start, if the previous run was not shut down cleanly. 0 - disable",
+		Value: ethconfig.Defaults.Sync.DirtyShutdownVerifyBlocks,
+	}
+
+	NodeDataRateFlag = cli.IntFlag{
+		Name:  "sync.nodedata.rate",
+		Usage: "Serve trie nodes to fast-syncing peers by legacy GetNodeData (eth/66), at most this amount of nodes per second. 0 - disable",
+		Value: ethconfig.Defaults.Sync.NodeDataRate,
+	}
+
+	RebuildReceiptsFlag = cli.StringFlag{
+		Name:  "rebuild.receipts",
+		Usage: "Re-execute blocks FROM-TO in background to regenerate receipts, logs and call indices (e.g. after enabling receipts). Progress is saved, the rebuild continues after restart",
+		Value: "",
+	}
+	RebuildReceiptsThrottleFlag = cli.DurationFlag{
+		Name:  "rebuild.receipts.throttle",
+		Usage: "Pause between batches of blocks re-executed by --rebuild.receipts",
+		Value: ethconfig.Defaults.Sync.RebuildReceiptsThrottle,
+	}
+
+	ReshardIndexFlag = cli.StringFlag{
+		Name:  "reshard.index",
+		Usage: "Re-shard bitmaps of the call/log index in background to shards of at most SIZE bytes: TABLE:SIZE, e.g. CallToIndex:1950. Progress is saved, re-sharding continues after restart",
+		Value: "",
+	}
+	ReshardIndexThrottleFlag = cli.DurationFlag{
+		Name:  "reshard.index.throttle",
+		Usage: "Pause between batches of bitmaps re-sharded by --reshard.index",
+		Value: ethconfig.Defaults.Sync.ReshardIndexThrottle,
+	}
+
+	SyncWatchdogFlag = cli.DurationFlag{
+		Name:  "sync.watchdog",
+		Usage: "Dump goroutines, cycle transaction age and peers into <datadir>/diagnostics if a stage makes no progress for this long (e.g. 30m). 0 - disable",
+		Value: ethconfig.Defaults.Sync.WatchdogTimeout,
+	}
+	SyncWatchdogRestartFlag = cli.BoolFlag{
+		Name:  "sync.watchdog.restart",
+		Usage: "Restart the stage loop after --sync.watchdog dumped diagnostics",
+	}
+
+	SyncProfileFlag = cli.StringFlag{
+		Name:  "sync.profile",
+		Usage: "TOML file of stage flags (flag = value) overriding the stage profile shipped for --chain; flags set on the command line or in --config take precedence",
+	}
+
+	SyncModeFlag = cli.StringFlag{
+		Name:  "sync.mode",
+		Usage: "Stages to run: full, archive (full without --prune), blocks (headers, bodies and transactions, no execution), state (latest state, no history indices, needs --prune=hr), external (blocks, executed by --sync.exec.external)",
+		Value: string(ethconfig.Defaults.Sync.Mode),
+	}
+	SyncExternalExecFlag = cli.StringFlag{
+		Name:  "sync.exec.external",
+		Usage: "gRPC address of the external executor of blocks for --sync.mode=external, it gets blocks with senders and returns state roots and receipts hashes",
+	}
+
+	ExportSinkFlag = cli.StringFlag{
+		Name:  "export.sink",
+		Usage: "Stream executed blocks, receipts and logs to the sink: file:///path/blocks.jsonl or http(s)://host/path. Execution waits while the sink is unavailable",
+	}
+	ExportBatchFlag = cli.IntFlag{
+		Name:  "export.batch",
+		Usage: "Messages buffered before they're sent to --export.sink",
+		Value: ethconfig.Defaults.Export.BatchSize,
+	}
+
+	StateRecentBlocksFlag = cli.IntFlag{
+		Name:  "state.recent.blocks",
+		Usage: "State changes of that many last executed blocks are kept in memory: RPC reads state of them without history indices (0 - disabled)",
+		Value: ethconfig.Defaults.RecentStates.Blocks,
+	}
+	StateRecentMemoryFlag = cli.StringFlag{
+		Name:  "state.recent.memory",
+		Usage: "Memory limit of --state.recent.blocks, the oldest blocks are dropped above it",
+		Value: ethconfig.Defaults.RecentStates.Memory.String(),
+	}
+
 	BadBlockFlag = cli.StringFlag{
 		Name:  "bad.block",
 		Usage: "Marks block with given hex string as bad and forces initial reorg before normal staged sync",
@@ -231,6 +314,44 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 
 	cfg.StateStream = !ctx.GlobalBool(StateStreamDisableFlag.Name)
 	cfg.Sync.BlockDownloaderWindow = ctx.GlobalInt(BlockDownloaderWindowFlag.Name)
+	cfg.Sync.DirtyShutdownVerifyBlocks = ctx.GlobalUint64(DirtyShutdownVerifyFlag.Name)
+	cfg.Sync.NodeDataRate = ctx.GlobalInt(NodeDataRateFlag.Name)
+	if r := ctx.GlobalString(RebuildReceiptsFlag.Name); r != "" {
+		if _, err := fmt.Sscanf(r, "%d-%d", &cfg.Sync.RebuildReceiptsFrom, &cfg.Sync.RebuildReceiptsTo); err != nil || cfg.Sync.RebuildReceiptsTo == 0 {
+			utils.Fatalf("Invalid --%s, expected FROM-TO: %s", RebuildReceiptsFlag.Name, r)
+		}
+	}
+	cfg.Sync.RebuildReceiptsThrottle = ctx.GlobalDuration(RebuildReceiptsThrottleFlag.Name)
+	if r := ctx.GlobalString(ReshardIndexFlag.Name); r != "" {
+		i := strings.LastIndexByte(r, ':')
+		if i < 0 {
+			utils.Fatalf("Invalid --%s, expected TABLE:SIZE: %s", ReshardIndexFlag.Name, r)
+		}
+		size, err := datasize.ParseString(r[i+1:])
+		if err != nil {
+			utils.Fatalf("Invalid --%s, expected TABLE:SIZE: %s", ReshardIndexFlag.Name, r)
+		}
+		cfg.Sync.ReshardIndexTable, cfg.Sync.ReshardIndexShardSize = r[:i], size.Bytes()
+	}
+	cfg.Sync.ReshardIndexThrottle = ctx.GlobalDuration(ReshardIndexThrottleFlag.Name)
+	cfg.Sync.WatchdogTimeout = ctx.GlobalDuration(SyncWatchdogFlag.Name)
+	cfg.Sync.WatchdogRestart = ctx.GlobalBool(SyncWatchdogRestartFlag.Name)
+	if cfg.Sync.Mode, err = stages.ModeFromString(ctx.GlobalString(SyncModeFlag.Name)); err != nil {
+		utils.Fatalf("Invalid --%s: %v", SyncModeFlag.Name, err)
+	}
+	if err = cfg.Sync.Mode.Validate(cfg.Prune); err != nil {
+		utils.Fatalf("Invalid --%s: %v", SyncModeFlag.Name, err)
+	}
+	cfg.Sync.ExternalExecAddr = ctx.GlobalString(SyncExternalExecFlag.Name)
+	if (cfg.Sync.Mode == stages.ModeExternal) != (cfg.Sync.ExternalExecAddr != "") {
+		utils.Fatalf("--%s is required by --%s=%s and only by it", SyncExternalExecFlag.Name, SyncModeFlag.Name, stages.ModeExternal)
+	}
+	cfg.Export.Sink = ctx.GlobalString(ExportSinkFlag.Name)
+	cfg.Export.BatchSize = ctx.GlobalInt(ExportBatchFlag.Name)
+	cfg.RecentStates.Blocks = ctx.GlobalInt(StateRecentBlocksFlag.Name)
+	if err = cfg.RecentStates.Memory.UnmarshalText([]byte(ctx.GlobalString(StateRecentMemoryFlag.Name))); err != nil {
+		utils.Fatalf("Invalid --%s: %v", StateRecentMemoryFlag.Name, err)
+	}
 
 	if ctx.GlobalString(SyncLoopThrottleFlag.Name) != "" {
 		syncLoopThrottle, err := time.ParseDuration(ctx.GlobalString(SyncLoopThrottleFlag.Name))
@@ -358,15 +479,51 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 		},
 		EvmCallTimeout: ctx.GlobalDuration(EvmCallTimeoutFlag.Name),
 
-		WebsocketEnabled:     ctx.GlobalIsSet(utils.WSEnabledFlag.Name),
-		RpcBatchConcurrency:  ctx.GlobalUint(utils.RpcBatchConcurrencyFlag.Name),
+		WebsocketEnabled:    ctx.GlobalIsSet(utils.WSEnabledFlag.Name),
+		RpcBatchConcurrency: ctx.GlobalUint(utils.RpcBatchConcurrencyFlag.Name),
+		RpcWorkers: rpc.SchedulerConfig{
+			CheapWorkers: ctx.GlobalUint(utils.RpcWorkersCheapFlag.Name),
+			TraceWorkers: ctx.GlobalUint(utils.RpcWorkersTraceFlag.Name),
+			LogsWorkers:  ctx.GlobalUint(utils.RpcWorkersLogsFlag.Name),
+			QueueLimit:   ctx.GlobalUint(utils.RpcWorkersQueueFlag.Name),
+		},
+		WebsocketLimits: rpc.WebsocketLimits{
+			MaxConnections:   ctx.GlobalInt(utils.WsMaxConnectionsFlag.Name),
+			IdleTimeout:      ctx.GlobalDuration(utils.WsIdleTimeoutFlag.Name),
+			MaxSubscriptions: ctx.GlobalInt(utils.WsMaxSubscriptionsFlag.Name),
+			SendQueue:        ctx.GlobalInt(utils.WsSendQueueFlag.Name),
+		},
 		RpcStreamingDisable:  ctx.GlobalBool(utils.RpcStreamingDisableFlag.Name),
 		DBReadConcurrency:    ctx.GlobalInt(utils.DBReadConcurrencyFlag.Name),
 		RpcAllowListFilePath: ctx.GlobalString(utils.RpcAccessListFlag.Name),
 		Gascap:               ctx.GlobalUint64(utils.RpcGasCapFlag.Name),
 		MaxTraces:            ctx.GlobalUint64(utils.TraceMaxtracesFlag.Name),
+		LogsMaxRange:         ctx.GlobalUint64(utils.RpcLogsMaxRangeFlag.Name),
+		LogsMaxResults:       ctx.GlobalUint64(utils.RpcLogsMaxResultsFlag.Name),
+		ReceiptsRevertReason: ctx.GlobalBool(utils.RpcReceiptsRevertReasonFlag.Name),
+		NonCanonicalTxs:      ctx.GlobalUint64(utils.RpcNonCanonicalTxsFlag.Name),
 		TraceCompatibility:   ctx.GlobalBool(utils.RpcTraceCompatFlag.Name),
+		OtsLabelsPath:        ctx.GlobalString(utils.OtsLabelsPathFlag.Name),
+		OtsCreatorsPath:      ctx.GlobalString(utils.OtsCreatorsPathFlag.Name),
+		OtsSourcifySource:    ctx.GlobalString(utils.OtsSourcifySourceFlag.Name),
+		OtsSourcifyCachePath: ctx.GlobalString(utils.OtsSourcifyCachePathFlag.Name),
+		OtsSignaturesPath:    ctx.GlobalString(utils.OtsSignaturesPathFlag.Name),
+		OtsSearchWorkers:     ctx.GlobalInt(utils.OtsSearchWorkersFlag.Name),
+		OtsSearchCache:       ctx.GlobalInt(utils.OtsSearchCacheFlag.Name),
+		OtsOperationsPath:    ctx.GlobalString(utils.OtsOperationsPathFlag.Name),
+		TraceBlockWorkers:    ctx.GlobalInt(utils.RpcTraceBlockWorkersFlag.Name),
+		TraceExport: httpcfg.TraceExportCfg{
+			Dir:       ctx.GlobalString(utils.RpcTraceExportDirFlag.Name),
+			URL:       ctx.GlobalString(utils.RpcTraceExportURLFlag.Name),
+			Retention: ctx.GlobalDuration(utils.RpcTraceExportRetentionFlag.Name),
+		},
 
+		ScheduledTxs: httpcfg.ScheduledTxsCfg{
+			Limit:          ctx.GlobalInt(utils.TxPoolScheduledLimitFlag.Name),
+			SenderLimit:    ctx.GlobalInt(utils.TxPoolScheduledSenderLimitFlag.Name),
+			MaxBlocksAhead: ctx.GlobalUint64(utils.TxPoolScheduledMaxBlocksFlag.Name),
+			MaxTimeAhead:   ctx.GlobalDuration(utils.TxPoolScheduledMaxTimeFlag.Name),
+		},
 		TxPoolApiAddr: ctx.GlobalString(utils.TxpoolApiAddrFlag.Name),
 
 		StateCache: kvcache.DefaultCoherentConfig,
@@ -383,6 +540,11 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 	}
 
 	c.StateCache.CodeKeysLimit = ctx.GlobalInt(utils.StateCacheFlag.Name)
+	readerPolicy, err := kvreaders.ParsePolicy(ctx.GlobalString(utils.RpcReadersFreelistFlag.Name), ctx.GlobalDuration(utils.RpcReadersMaxAgeFlag.Name), ctx.GlobalString(utils.RpcReadersActionFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid policy of long readers: %v", err)
+	}
+	c.ReaderPolicy = readerPolicy
 
 	/*
 		rootCmd.PersistentFlags().BoolVar(&cfg.GRPCServerEnabled, "grpc", false, "Enable GRPC server")
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/params/networkname"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/pelletier/go-toml"
+	"github.com/urfave/cli"
+)
+
+// profiles - stage profiles shipped with the binary, profiles/<chain>.toml
+//
+//go:embed profiles
+var profiles embed.FS
+
+// profileFlags - flags a stage profile may set: parameters of stages, not of the node
+var profileFlags = map[string]struct{}{
+	BatchSizeFlag.Name:             {},
+	EtlBufferSizeFlag.Name:         {},
+	BlockDownloaderWindowFlag.Name: {},
+	SyncLoopThrottleFlag.Name:      {},
+	DirtyShutdownVerifyFlag.Name:   {},
+	SyncModeFlag.Name:              {},
+	PruneFlag.Name:                 {},
+	PruneHistoryFlag.Name:          {},
+	PruneReceiptFlag.Name:          {},
+	PruneTxIndexFlag.Name:          {},
+	PruneCallTracesFlag.Name:       {},
+	PruneHistoryBeforeFlag.Name:    {},
+	PruneReceiptBeforeFlag.Name:    {},
+	PruneTxIndexBeforeFlag.Name:    {},
+	PruneCallTracesBeforeFlag.Name: {},
+	utils.SnapshotFlag.Name:        {},
+	utils.SnapKeepBlocksFlag.Name:  {},
+	utils.SnapStopFlag.Name:        {},
+}
+
+// StageProfile - stage parameters of a chain: flag name -> value, like in --config files
+type StageProfile map[string]interface{}
+
+// ReadStageProfile returns the profile shipped for the chain (empty if there is none), overridden by the user's
+// profile file, if path is not empty
+func ReadStageProfile(chain, path string) (StageProfile, error) {
+	profile := StageProfile{}
+	shipped, err := profiles.ReadFile("profiles/" + chain + ".toml")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err = profile.merge(shipped); err != nil {
+			return nil, fmt.Errorf("profile of %s: %w", chain, err)
+		}
+	}
+	if path != "" {
+		user, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = profile.merge(user); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return profile, nil
+}
+
+func (p StageProfile) merge(data []byte) error {
+	values := map[string]interface{}{}
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for name, value := range values {
+		if _, ok := profileFlags[name]; !ok {
+			return fmt.Errorf("--%s is not a stage parameter", name)
+		}
+		p[name] = value
+	}
+	return nil
+}
+
+// ApplyStageProfile sets flags of the stage profile of --chain, which aren't set on the command line or in the
+// --config file: the profile replaces defaults of the flags, tuned for mainnet
+func ApplyStageProfile(ctx *cli.Context) error {
+	chain := ctx.GlobalString(utils.ChainFlag.Name)
+	if chain == "" {
+		chain = networkname.MainnetChainName
+	}
+	profile, err := ReadStageProfile(chain, ctx.GlobalString(SyncProfileFlag.Name))
+	if err != nil {
+		return err
+	}
+	var applied []string
+	for name, value := range profile {
+		if ctx.GlobalIsSet(name) {
+			continue
+		}
+		if err := ctx.GlobalSet(name, fmt.Sprintf("%v", value)); err != nil {
+			return fmt.Errorf("stage profile: --%s=%v: %w", name, value, err)
+		}
+		applied = append(applied, fmt.Sprintf("%s=%v", name, value))
+	}
+	if len(applied) > 0 {
+		sort.Strings(applied)
+		log.Info("Stage profile", "chain", chain, "flags", strings.Join(applied, " "))
+	}
+	return nil
+}
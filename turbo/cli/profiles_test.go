@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/params/networkname"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadStageProfile(t *testing.T) {
+	entries, err := profiles.ReadDir("profiles")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		_, err := ReadStageProfile(strings.TrimSuffix(entry.Name(), ".toml"), "")
+		require.NoError(t, err, entry.Name())
+	}
+
+	profile, err := ReadStageProfile(networkname.MainnetChainName, "")
+	require.NoError(t, err)
+	require.Empty(t, profile)
+	profile, err = ReadStageProfile("unknown", "")
+	require.NoError(t, err)
+	require.Empty(t, profile)
+
+	// the user's file overrides the shipped profile
+	path := filepath.Join(t.TempDir(), "profile.toml")
+	require.NoError(t, os.WriteFile(path, []byte("blockDownloaderWindow = 1024\nbatchSize = \"1G\"\n\"prune.h.older\" = 90000\n"), 0600))
+	profile, err = ReadStageProfile(networkname.GnosisChainName, path)
+	require.NoError(t, err)
+	require.Equal(t, StageProfile{"blockDownloaderWindow": int64(1024), "batchSize": "1G", "prune.h.older": int64(90000)}, profile)
+
+	// only stage flags
+	require.NoError(t, os.WriteFile(path, []byte("\"http.port\" = 8546\n"), 0600))
+	_, err = ReadStageProfile(networkname.MainnetChainName, path)
+	require.ErrorContains(t, err, "--http.port is not a stage parameter")
+	_, err = ReadStageProfile(networkname.MainnetChainName, filepath.Join(t.TempDir(), "missing.toml"))
+	require.Error(t, err)
+}
@@ -0,0 +1,192 @@
+// Package remoteexec - gRPC transport of stagedsync.ExternalExecutor: erigon with --sync.mode=external is the client,
+// an alternative execution backend registers the server. There is no .proto for it in erigon-lib, messages are
+// well-known types, blocks and results are RLP encoded in BytesValue.
+package remoteexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/rlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// MaxMsgSize - limit of a block with senders, the server has to accept it: grpc.MaxRecvMsgSize(remoteexec.MaxMsgSize)
+const MaxMsgSize = int(16 * datasize.MB)
+
+type headReply struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+type executeRequest struct {
+	Block   *types.Block
+	Senders []common.Address
+}
+
+type executeReply struct {
+	StateRoot   common.Hash
+	ReceiptHash common.Hash
+	GasUsed     uint64
+}
+
+func encode(val interface{}) (*wrapperspb.BytesValue, error) {
+	b, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return nil, err
+	}
+	return wrapperspb.Bytes(b), nil
+}
+
+// server - the gRPC face of the executor
+type server struct {
+	executor stagedsync.ExternalExecutor
+}
+
+func (s *server) Head(ctx context.Context, _ *emptypb.Empty) (*wrapperspb.BytesValue, error) {
+	num, hash, err := s.executor.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return encode(&headReply{Number: num, Hash: hash})
+}
+
+func (s *server) ExecuteBlock(ctx context.Context, in *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+	var req executeRequest
+	if err := rlp.DecodeBytes(in.Value, &req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	res, err := s.executor.ExecuteBlock(ctx, req.Block, req.Senders)
+	if errors.Is(err, stagedsync.ErrInvalidExternalBlock) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encode(&executeReply{StateRoot: res.StateRoot, ReceiptHash: res.ReceiptHash, GasUsed: res.GasUsed})
+}
+
+func (s *server) UnwindTo(ctx context.Context, in *wrapperspb.UInt64Value) (*emptypb.Empty, error) {
+	if err := s.executor.UnwindTo(ctx, in.Value); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+type executorServer interface {
+	Head(context.Context, *emptypb.Empty) (*wrapperspb.BytesValue, error)
+	ExecuteBlock(context.Context, *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error)
+	UnwindTo(context.Context, *wrapperspb.UInt64Value) (*emptypb.Empty, error)
+}
+
+const serviceName = "execution.ExternalExecutor"
+
+func unaryHandler[Req any, Reply any](method string, handle func(executorServer, context.Context, *Req) (*Reply, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(Req)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return handle(srv.(executorServer), ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + method}
+			return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return handle(srv.(executorServer), ctx, req.(*Req))
+			})
+		},
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*executorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryHandler("Head", executorServer.Head),
+		unaryHandler("ExecuteBlock", executorServer.ExecuteBlock),
+		unaryHandler("UnwindTo", executorServer.UnwindTo),
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// RegisterServer serves the executor to erigon. ExecuteBlock reports invalid blocks by wrapping
+// stagedsync.ErrInvalidExternalBlock, they're unwound by erigon then.
+func RegisterServer(s *grpc.Server, executor stagedsync.ExternalExecutor) {
+	s.RegisterService(&serviceDesc, &server{executor: executor})
+}
+
+// Client - stagedsync.ExternalExecutor served by RegisterServer
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// Dial connects to the executor at addr, it's waited for if not available yet
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	backoffCfg := backoff.DefaultConfig
+	backoffCfg.BaseDelay = 500 * time.Millisecond
+	backoffCfg.MaxDelay = 10 * time.Second
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoffCfg, MinConnectTimeout: 10 * time.Minute}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(MaxMsgSize), grpc.WaitForReady(true)),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating client connection to the external executor: %w", err)
+	}
+	return NewClient(conn), nil
+}
+
+func (c *Client) Head(ctx context.Context) (uint64, common.Hash, error) {
+	out := new(wrapperspb.BytesValue)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/Head", &emptypb.Empty{}, out); err != nil {
+		return 0, common.Hash{}, err
+	}
+	var reply headReply
+	if err := rlp.DecodeBytes(out.Value, &reply); err != nil {
+		return 0, common.Hash{}, err
+	}
+	return reply.Number, reply.Hash, nil
+}
+
+func (c *Client) ExecuteBlock(ctx context.Context, block *types.Block, senders []common.Address) (*stagedsync.ExternalExecResult, error) {
+	in, err := encode(&executeRequest{Block: block, Senders: senders})
+	if err != nil {
+		return nil, err
+	}
+	out := new(wrapperspb.BytesValue)
+	if err = c.cc.Invoke(ctx, "/"+serviceName+"/ExecuteBlock", in, out); err != nil {
+		if st, ok := status.FromError(err); ok && st.Code() == codes.FailedPrecondition {
+			return nil, fmt.Errorf("%w: %s", stagedsync.ErrInvalidExternalBlock, st.Message())
+		}
+		return nil, err
+	}
+	var reply executeReply
+	if err = rlp.DecodeBytes(out.Value, &reply); err != nil {
+		return nil, err
+	}
+	return &stagedsync.ExternalExecResult{StateRoot: reply.StateRoot, ReceiptHash: reply.ReceiptHash, GasUsed: reply.GasUsed}, nil
+}
+
+func (c *Client) UnwindTo(ctx context.Context, blockNum uint64) error {
+	return c.cc.Invoke(ctx, "/"+serviceName+"/UnwindTo", wrapperspb.UInt64(blockNum), &emptypb.Empty{})
+}
@@ -0,0 +1,77 @@
+package remoteexec
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testExecutor struct {
+	head     uint64
+	senders  []common.Address
+	unwindTo uint64
+}
+
+func (e *testExecutor) Head(context.Context) (uint64, common.Hash, error) {
+	return e.head, common.Hash{byte(e.head)}, nil
+}
+
+func (e *testExecutor) ExecuteBlock(_ context.Context, block *types.Block, senders []common.Address) (*stagedsync.ExternalExecResult, error) {
+	if block.GasUsed() > block.GasLimit() {
+		return nil, fmt.Errorf("%w: gas used above the limit", stagedsync.ErrInvalidExternalBlock)
+	}
+	e.head, e.senders = block.NumberU64(), senders
+	return &stagedsync.ExternalExecResult{StateRoot: block.Root(), ReceiptHash: block.Hash(), GasUsed: block.GasUsed()}, nil
+}
+
+func (e *testExecutor) UnwindTo(_ context.Context, blockNum uint64) error {
+	e.unwindTo = blockNum
+	return nil
+}
+
+func TestClientServer(t *testing.T) {
+	ctx := context.Background()
+	executor := &testExecutor{}
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(MaxMsgSize))
+	RegisterServer(server, executor)
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener) //nolint:errcheck
+	conn, err := grpc.DialContext(ctx, "", grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	client := NewClient(conn)
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7), Difficulty: big.NewInt(1), Root: common.Hash{1}, GasLimit: 10, GasUsed: 5})
+	senders := []common.Address{{1}, {2}}
+	res, err := client.ExecuteBlock(ctx, block, senders)
+	require.NoError(t, err)
+	require.Equal(t, &stagedsync.ExternalExecResult{StateRoot: common.Hash{1}, ReceiptHash: block.Hash(), GasUsed: 5}, res)
+	require.Equal(t, senders, executor.senders)
+
+	num, hash, err := client.Head(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), num)
+	require.Equal(t, common.Hash{7}, hash)
+
+	require.NoError(t, client.UnwindTo(ctx, 3))
+	require.Equal(t, uint64(3), executor.unwindTo)
+
+	invalid := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(8), Difficulty: big.NewInt(1), GasLimit: 10, GasUsed: 11})
+	_, err = client.ExecuteBlock(ctx, invalid, nil)
+	require.ErrorIs(t, err, stagedsync.ErrInvalidExternalBlock)
+}
@@ -47,9 +47,11 @@ type Filters struct {
 	logsSubs         *LogsFilterAggregator
 	logsRequestor    atomic.Value
 	onNewSnapshot    func()
+	done             <-chan struct{}
 
 	storeMu            sync.Mutex
 	logsStores         map[LogsSubID][]*types.Log
+	logsCriteria       map[LogsSubID]filters.FilterCriteria
 	pendingHeadsStores map[HeadsSubID][]*types.Header
 	pendingTxsStores   map[PendingTxsSubID][][]types.Transaction
 }
@@ -64,7 +66,9 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 		pendingBlockSubs:   make(map[PendingBlockSubID]chan *types.Block),
 		logsSubs:           NewLogsFilterAggregator(),
 		onNewSnapshot:      onNewSnapshot,
+		done:               ctx.Done(),
 		logsStores:         make(map[LogsSubID][]*types.Log),
+		logsCriteria:       make(map[LogsSubID]filters.FilterCriteria),
 		pendingHeadsStores: make(map[HeadsSubID][]*types.Header),
 		pendingTxsStores:   make(map[PendingTxsSubID][][]types.Transaction),
 	}
@@ -303,6 +307,12 @@ func (ff *Filters) HandlePendingLogs(reply *txpool.OnPendingLogsReply) {
 	}
 }
 
+// Done is closed when the filters stop receiving events: the context they were created with is cancelled. Consumers
+// of the subscriptions stop their goroutines then.
+func (ff *Filters) Done() <-chan struct{} {
+	return ff.done
+}
+
 func (ff *Filters) SubscribeNewHeads(out chan *types.Header) HeadsSubID {
 	ff.mu.Lock()
 	defer ff.mu.Unlock()
@@ -397,6 +407,9 @@ func (ff *Filters) SubscribeLogs(out chan *types.Log, crit filters.FilterCriteri
 	}
 	f.topicsOriginal = crit.Topics
 	ff.logsSubs.addLogsFilters(f)
+	ff.storeMu.Lock()
+	ff.logsCriteria[id] = crit
+	ff.storeMu.Unlock()
 	// if any filter in the aggregate needs all addresses or all topics then the global log subscription needs to
 	// allow all addresses or topics through
 	lfr := &remote.LogsFilterRequest{
@@ -418,6 +431,7 @@ func (ff *Filters) SubscribeLogs(out chan *types.Log, crit filters.FilterCriteri
 		if err := loaded.(func(*remote.LogsFilterRequest) error)(lfr); err != nil {
 			log.Warn("Could not update remote logs filter", "err", err)
 			ff.logsSubs.removeLogsFilter(id)
+			ff.deleteLogStore(id)
 		}
 	}
 
@@ -464,6 +478,15 @@ func (ff *Filters) deleteLogStore(id LogsSubID) {
 	ff.storeMu.Lock()
 	defer ff.storeMu.Unlock()
 	delete(ff.logsStores, id)
+	delete(ff.logsCriteria, id)
+}
+
+// LogsFilterCriteria returns the criteria the logs filter with the given id was created with.
+func (ff *Filters) LogsFilterCriteria(id LogsSubID) (filters.FilterCriteria, bool) {
+	ff.storeMu.Lock()
+	defer ff.storeMu.Unlock()
+	crit, ok := ff.logsCriteria[id]
+	return crit, ok
 }
 
 func (ff *Filters) OnNewEvent(event *remote.SubscribeReply) {
@@ -12,20 +12,12 @@ import (
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/adapter"
 	"github.com/ledgerwatch/log/v3"
 )
 
-// unable to decode supplied params, or an invalid number of parameters
-type nonCanonocalHashError struct{ hash common.Hash }
-
-func (e nonCanonocalHashError) ErrorCode() int { return -32603 }
-
-func (e nonCanonocalHashError) Error() string {
-	return fmt.Sprintf("hash %x is not currently canonical", e.hash)
-}
-
 func GetBlockNumber(blockNrOrHash rpc.BlockNumberOrHash, tx kv.Tx, filters *Filters) (uint64, common.Hash, bool, error) {
 	return _GetBlockNumber(blockNrOrHash.RequireCanonical, blockNrOrHash, tx, filters)
 }
@@ -66,14 +58,21 @@ func _GetBlockNumber(requireCanonical bool, blockNrOrHash rpc.BlockNumberOrHash,
 			pendingBlock := filters.LastPendingBlock()
 			if pendingBlock == nil {
 				log.Warn("no pending block found returning latest executed block")
-				blockNumber = plainStateBlockNumber
+				if blockNumber, err = GetLatestExecutedBlockNumber(tx); err != nil {
+					return 0, common.Hash{}, false, err
+				}
 			} else {
 				return pendingBlock.NumberU64(), pendingBlock.Hash(), false, nil
 			}
 		case rpc.LatestExecutedBlockNumber:
-			blockNumber = plainStateBlockNumber
+			if blockNumber, err = GetLatestExecutedBlockNumber(tx); err != nil {
+				return 0, common.Hash{}, false, err
+			}
 		default:
 			blockNumber = uint64(number.Int64())
+			if err = CheckReorgInProgress(tx, blockNumber); err != nil {
+				return 0, common.Hash{}, false, err
+			}
 		}
 		hash, err = rawdb.ReadCanonicalHash(tx, blockNumber)
 		if err != nil {
@@ -82,7 +81,7 @@ func _GetBlockNumber(requireCanonical bool, blockNrOrHash rpc.BlockNumberOrHash,
 	} else {
 		number := rawdb.ReadHeaderNumber(tx, hash)
 		if number == nil {
-			return 0, common.Hash{}, false, fmt.Errorf("block %x not found", hash)
+			return 0, common.Hash{}, false, rpc.NewNotFoundError("block", hash)
 		}
 		blockNumber = *number
 
@@ -91,7 +90,10 @@ func _GetBlockNumber(requireCanonical bool, blockNrOrHash rpc.BlockNumberOrHash,
 			return 0, common.Hash{}, false, err
 		}
 		if requireCanonical && ch != hash {
-			return 0, common.Hash{}, false, nonCanonocalHashError{hash}
+			return 0, common.Hash{}, false, &rpc.ReorgedError{Hash: hash}
+		}
+		if err = CheckReorgInProgress(tx, blockNumber); err != nil {
+			return 0, common.Hash{}, false, err
 		}
 	}
 	return blockNumber, hash, blockNumber == plainStateBlockNumber, nil
@@ -102,11 +104,18 @@ func GetAccount(tx kv.Tx, blockNumber uint64, address common.Address) (*accounts
 	return reader.ReadAccountData(address)
 }
 
-func CreateStateReader(ctx context.Context, tx kv.Tx, blockNrOrHash rpc.BlockNumberOrHash, filters *Filters, stateCache kvcache.Cache, historyV3 bool, agg *state2.Aggregator22) (state.StateReader, error) {
+// CreateStateReader returns a reader of the state after the block. State of recent blocks is read from recentStates
+// (nil if they aren't kept), otherwise from history.
+func CreateStateReader(ctx context.Context, tx kv.Tx, blockNrOrHash rpc.BlockNumberOrHash, filters *Filters, stateCache kvcache.Cache, recentStates *state.RecentStates, historyV3 bool, agg *state2.Aggregator22) (state.StateReader, error) {
 	blockNumber, _, latest, err := _GetBlockNumber(true, blockNrOrHash, tx, filters)
 	if err != nil {
 		return nil, err
 	}
+	if mode, err := stages.ReadMode(tx); err != nil {
+		return nil, err
+	} else if !mode.HasState() {
+		return nil, rpc.NewNotFoundError("state", blockNumber)
+	}
 	var stateReader state.StateReader
 	if latest {
 		cacheView, err := stateCache.View(ctx, tx)
@@ -127,8 +136,55 @@ func CreateStateReader(ctx context.Context, tx kv.Tx, blockNrOrHash rpc.BlockNum
 			r.SetTxNum(minTxNum)
 			stateReader = r
 		} else {
+			if r, err := recentStateReader(tx, recentStates, blockNumber); err != nil {
+				return nil, err
+			} else if r != nil {
+				return r, nil
+			}
+			if err = CheckHistoryNotPruned(tx, blockNumber); err != nil {
+				return nil, err
+			}
 			stateReader = state.NewPlainState(tx, blockNumber+1)
 		}
 	}
 	return stateReader, nil
 }
+
+// recentStateReader returns nil if the blocks after blockNumber up to the latest state of tx aren't kept
+func recentStateReader(tx kv.Tx, recentStates *state.RecentStates, blockNumber uint64) (state.StateReader, error) {
+	if recentStates == nil {
+		return nil, nil
+	}
+	head, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, err
+	}
+	headHash, err := rawdb.ReadCanonicalHash(tx, head)
+	if err != nil {
+		return nil, err
+	}
+	if r := recentStates.Reader(tx, blockNumber, head, headHash); r != nil {
+		return r, nil
+	}
+	return nil, nil
+}
+
+// CheckHistoryNotPruned - state after blockNumber is read from changesets of the next blocks,
+// returns rpc.PrunedError if they are pruned already
+func CheckHistoryNotPruned(tx kv.Tx, blockNumber uint64) error {
+	pm, err := prune.Get(tx)
+	if err != nil {
+		return err
+	}
+	if !pm.History.Enabled() {
+		return nil
+	}
+	progress, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if pruneTo := pm.History.PruneTo(progress); blockNumber+1 < pruneTo {
+		return &rpc.PrunedError{Resource: "state", Block: blockNumber, AvailableFrom: pruneTo - 1}
+	}
+	return nil
+}
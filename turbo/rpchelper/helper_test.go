@@ -0,0 +1,67 @@
+package rpchelper
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHistoryNotPruned(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, stages.SaveStageProgress(tx, stages.Execution, 1000))
+	require.NoError(t, CheckHistoryNotPruned(tx, 0)) // nothing is pruned by default
+
+	pm := prune.DefaultMode
+	pm.History = prune.Distance(100)
+	require.NoError(t, prune.Override(tx, pm))
+	require.NoError(t, CheckHistoryNotPruned(tx, 899))
+	var pruned *rpc.PrunedError
+	require.ErrorAs(t, CheckHistoryNotPruned(tx, 898), &pruned)
+	require.Equal(t, &rpc.PrunedError{Resource: "state", Block: 898, AvailableFrom: 899}, pruned)
+}
+
+func TestReorgInProgress(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, stages.SaveStageProgress(tx, stages.Execution, 1000))
+	require.NoError(t, CheckReorgInProgress(tx, 1000))
+
+	require.NoError(t, rawdb.WriteUnwindInProgress(tx, 900, 1000))
+	require.NoError(t, CheckReorgInProgress(tx, 900))
+	var reorg *rpc.ReorgInProgressError
+	require.ErrorAs(t, CheckReorgInProgress(tx, 901), &reorg)
+	require.Equal(t, &rpc.ReorgInProgressError{Block: 901, UnwindPoint: 900}, reorg)
+
+	// tags resolve to the unwind point, explicit blocks above it are refused
+	latest, err := GetLatestBlockNumber(tx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(900), latest)
+	executed, _, _, err := GetBlockNumber(rpc.BlockNumberOrHashWithNumber(rpc.LatestExecutedBlockNumber), tx, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(900), executed)
+	_, _, _, err = GetBlockNumber(rpc.BlockNumberOrHashWithNumber(950), tx, nil)
+	require.ErrorAs(t, err, &reorg)
+
+	require.NoError(t, rawdb.DeleteUnwindInProgress(tx))
+	latest, err = GetLatestBlockNumber(tx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), latest)
+}
+
+func TestLatestBlockOfSyncMode(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, stages.SaveStageProgress(tx, stages.Senders, 500))
+	latest, err := GetLatestBlockNumber(tx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), latest)
+
+	// blocks aren't executed, the head is at senders
+	require.NoError(t, stages.EnsureModeChangeAllowed(tx, stages.ModeBlocks))
+	latest, err = GetLatestBlockNumber(tx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(500), latest)
+}
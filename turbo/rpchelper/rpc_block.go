@@ -20,16 +20,20 @@ func GetLatestBlockNumber(tx kv.Tx) (uint64, error) {
 	if forkchoiceHeadHash != (common.Hash{}) {
 		forkchoiceHeadNum := rawdb.ReadHeaderNumber(tx, forkchoiceHeadHash)
 		if forkchoiceHeadNum != nil {
-			return *forkchoiceHeadNum, nil
+			return clampToUnwindPoint(tx, *forkchoiceHeadNum)
 		}
 	}
 
-	blockNum, err := stages.GetStageProgress(tx, stages.Execution)
+	mode, err := stages.ReadMode(tx)
+	if err != nil {
+		return 0, err
+	}
+	blockNum, err := stages.GetStageProgress(tx, mode.Head())
 	if err != nil {
 		return 0, fmt.Errorf("getting latest block number: %w", err)
 	}
 
-	return blockNum, nil
+	return clampToUnwindPoint(tx, blockNum)
 }
 
 func GetFinalizedBlockNumber(tx kv.Tx) (uint64, error) {
@@ -37,7 +41,7 @@ func GetFinalizedBlockNumber(tx kv.Tx) (uint64, error) {
 	if forkchoiceFinalizedHash != (common.Hash{}) {
 		forkchoiceFinalizedNum := rawdb.ReadHeaderNumber(tx, forkchoiceFinalizedHash)
 		if forkchoiceFinalizedNum != nil {
-			return *forkchoiceFinalizedNum, nil
+			return clampToUnwindPoint(tx, *forkchoiceFinalizedNum)
 		}
 	}
 
@@ -49,7 +53,7 @@ func GetSafeBlockNumber(tx kv.Tx) (uint64, error) {
 	if forkchoiceSafeHash != (common.Hash{}) {
 		forkchoiceSafeNum := rawdb.ReadHeaderNumber(tx, forkchoiceSafeHash)
 		if forkchoiceSafeNum != nil {
-			return *forkchoiceSafeNum, nil
+			return clampToUnwindPoint(tx, *forkchoiceSafeNum)
 		}
 	}
 	return 0, UnknownBlockError
@@ -60,5 +64,31 @@ func GetLatestExecutedBlockNumber(tx kv.Tx) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return blockNum, err
+	return clampToUnwindPoint(tx, blockNum)
+}
+
+// CheckReorgInProgress returns rpc.ReorgInProgressError if the stage loop is unwinding below the block: its data may
+// be of the old or of the new chain, differently in subsequent requests
+func CheckReorgInProgress(tx kv.Tx, blockNumber uint64) error {
+	unwindPoint, _, ok, err := rawdb.ReadUnwindInProgress(tx)
+	if err != nil {
+		return err
+	}
+	if ok && blockNumber > unwindPoint {
+		return &rpc.ReorgInProgressError{Block: blockNumber, UnwindPoint: unwindPoint}
+	}
+	return nil
+}
+
+// clampToUnwindPoint - during an unwind "latest" and other block tags resolve to the unwind point at most, blocks up
+// to it are the same before and after the reorg
+func clampToUnwindPoint(tx kv.Tx, blockNumber uint64) (uint64, error) {
+	unwindPoint, _, ok, err := rawdb.ReadUnwindInProgress(tx)
+	if err != nil {
+		return 0, err
+	}
+	if ok && blockNumber > unwindPoint {
+		return unwindPoint, nil
+	}
+	return blockNumber, nil
 }
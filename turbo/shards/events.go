@@ -49,6 +49,8 @@ func (e *Events) AddHeaderSubscription() (chan [][]byte, func()) {
 	id := e.id
 	e.headerSubscriptions[id] = ch
 	return ch, func() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
 		delete(e.headerSubscriptions, id)
 		close(ch)
 	}
@@ -142,4 +144,7 @@ type Notifications struct {
 	Events               *Events
 	Accumulator          *Accumulator
 	StateChangesConsumer StateChangeConsumer
+	// HeadersWatched - new headers are announced to Events by stagedsync.HeadersNotifier on commits,
+	// not by the stage loop
+	HeadersWatched bool
 }
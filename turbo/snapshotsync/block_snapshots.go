@@ -30,10 +30,12 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/hack/tool/fromdb"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/diskspace"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/ethconfig/estimate"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/params"
@@ -1138,6 +1140,9 @@ type DBEventNotifier interface {
 
 func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint256.Int, tmpDir string, snapshots *RoSnapshots, db kv.RoDB, workers int, downloader proto_downloader.DownloaderClient, lvl log.Lvl, notifier DBEventNotifier) error {
 	log.Log(lvl, "[snapshots] Retire Blocks", "range", fmt.Sprintf("%dk-%dk", blockFrom/1000, blockTo/1000))
+	if err := checkRetireDiskSpace(ctx, db, tmpDir, snapshots.Dir(), blockTo-blockFrom); err != nil {
+		return err
+	}
 	// in future we will do it in background
 	if err := DumpBlocks(ctx, blockFrom, blockTo, snap.Erigon2SegmentSize, tmpDir, snapshots.Dir(), db, workers, lvl); err != nil {
 		return fmt.Errorf("DumpBlocks: %w", err)
@@ -1154,6 +1159,13 @@ func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint25
 	if len(rangesToMerge) == 0 {
 		return nil
 	}
+	var mergeBlocks uint64
+	for _, r := range rangesToMerge {
+		mergeBlocks += r.to - r.from
+	}
+	if err := checkRetireDiskSpace(ctx, db, tmpDir, snapshots.Dir(), mergeBlocks); err != nil {
+		return err
+	}
 	err := merger.Merge(ctx, snapshots, rangesToMerge, snapshots.Dir(), true /* doIndex */)
 	if err != nil {
 		return err
@@ -1174,6 +1186,41 @@ func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint25
 	return RequestSnapshotsDownload(ctx, downloadRequest, downloader)
 }
 
+// checkRetireDiskSpace - compressor keeps raw words and dictionary candidates of segments in temp files,
+// raw size of blocks is estimated by the average size of blocks in the db (merged blocks are not there anymore,
+// but they are not bigger than recent ones)
+func checkRetireDiskSpace(ctx context.Context, db kv.RoDB, tmpDir, snapDir string, blocks uint64) error {
+	var rawPerBlock uint64
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		c, err := tx.Cursor(kv.Headers)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		count, err := c.Count()
+		if err != nil || count == 0 {
+			return err
+		}
+		var size uint64
+		for _, table := range []string{kv.Headers, kv.BlockBody, kv.EthTx} {
+			tableSize, err := tx.BucketSize(table)
+			if err != nil {
+				return err
+			}
+			size += tableSize
+		}
+		rawPerBlock = size / count
+		return nil
+	}); err != nil {
+		return err
+	}
+	raw := rawPerBlock * blocks
+	return diskspace.Check("snapshots",
+		diskspace.Requirement{Dir: tmpDir, Bytes: 2 * raw, Purpose: "compressor temp files"},
+		diskspace.Requirement{Dir: snapDir, Bytes: raw, Purpose: "segments"},
+	)
+}
+
 func DumpBlocks(ctx context.Context, blockFrom, blockTo, blocksPerFile uint64, tmpDir, snapDir string, chainDB kv.RoDB, workers int, lvl log.Lvl) error {
 	if blocksPerFile == 0 {
 		return nil
@@ -1307,6 +1354,9 @@ func DumpTxs(ctx context.Context, db kv.RoDB, segmentFile, tmpDir string, blockF
 			return nil
 		}
 
+		if tv, err = rawdb.DecompressTxn(tx, tv, nil); err != nil {
+			return err
+		}
 		parseCtx.WithSender(false)
 		valueBuf, err = parse(tv, valueBuf, nil, 0)
 		if err != nil {
@@ -1374,6 +1424,9 @@ func DumpTxs(ctx context.Context, db kv.RoDB, segmentFile, tmpDir string, blockF
 				panic(fmt.Sprintf("no gaps in tx ids are allowed: block %d does jump from %d to %d", blockNum, prevTxID, id))
 			}
 			prevTxID = id
+			if tv, err = rawdb.DecompressTxn(tx, tv, nil); err != nil {
+				return err
+			}
 			parseCtx.WithSender(len(senders) == 0)
 			valueBuf, err = parse(tv, valueBuf, senders, j)
 			if err != nil {
@@ -1947,6 +2000,28 @@ func (*Merger) FindMergeRanges(currentRanges []Range) (toMerge []Range) {
 	return toMerge
 }
 
+// FindRepackRanges - ranges of segmentSize blocks, aligned to segmentSize, which are covered by more than one segment.
+// Segments which cross the boundary of such range are left as is.
+func (*Merger) FindRepackRanges(currentRanges []Range, segmentSize uint64) (toMerge []Range) {
+	for i := 0; i < len(currentRanges); {
+		r := currentRanges[i]
+		if r.from%segmentSize != 0 {
+			i++
+			continue
+		}
+		to := r.from + segmentSize
+		j := i + 1
+		for j < len(currentRanges) && currentRanges[j].from == currentRanges[j-1].to && currentRanges[j].to <= to {
+			j++
+		}
+		if j-i > 1 && currentRanges[j-1].to == to {
+			toMerge = append(toMerge, Range{from: r.from, to: to})
+		}
+		i = j
+	}
+	return toMerge
+}
+
 func (m *Merger) filesByRange(snapshots *RoSnapshots, from, to uint64) (map[snap.Type][]string, error) {
 	toMerge := map[snap.Type][]string{}
 	err := snapshots.Headers.View(func(hSegments []*HeaderSegment) error {
@@ -2085,6 +2160,39 @@ func (m *Merger) removeOldFiles(toDel []string, snapDir string) {
 	}
 }
 
+// RepackBlocks - merges segments into segments of segmentSize blocks (see FindRepackRanges) and builds their indices.
+// Amount of workers is chosen by estimated RAM usage of compression and indexing.
+func RepackBlocks(ctx context.Context, snapshots *RoSnapshots, db kv.RoDB, segmentSize uint64, tmpDir string, chainID uint256.Int, lvl log.Lvl) error {
+	if segmentSize == 0 || segmentSize%1000 != 0 || segmentSize > snap.Erigon2SegmentSize {
+		return fmt.Errorf("segment size must be a multiple of 1000 and not bigger than %d, got %d", snap.Erigon2SegmentSize, segmentSize)
+	}
+	merger := NewMerger(tmpDir, estimate.CompressSnapshot.Workers(), lvl, chainID, nil)
+	rangesToMerge := merger.FindRepackRanges(snapshots.Ranges(), segmentSize)
+	if len(rangesToMerge) == 0 {
+		log.Log(lvl, "[snapshots] Nothing to repack", "segment.size", segmentSize)
+		return nil
+	}
+	var mergeBlocks uint64
+	for _, r := range rangesToMerge {
+		mergeBlocks += r.to - r.from
+	}
+	if err := checkRetireDiskSpace(ctx, db, tmpDir, snapshots.Dir(), mergeBlocks); err != nil {
+		return err
+	}
+	if err := merger.Merge(ctx, snapshots, rangesToMerge, snapshots.Dir(), false /* doIndex */); err != nil {
+		return err
+	}
+	dirs := datadir.Dirs{Snap: snapshots.Dir(), Tmp: tmpDir}
+	if err := BuildMissedIndices("Repack", ctx, dirs, chainID, estimate.IndexSnapshot.Workers()); err != nil {
+		return err
+	}
+	if err := snapshots.ReopenFolder(); err != nil {
+		return fmt.Errorf("reopen: %w", err)
+	}
+	snapshots.LogStat()
+	return nil
+}
+
 func NewDownloadRequest(ranges *Range, path string, torrentHash string) DownloadRequest {
 	return DownloadRequest{
 		ranges:      ranges,
@@ -105,6 +105,21 @@ func TestMergeSnapshots(t *testing.T) {
 	require.Equal(1, a)
 }
 
+func TestFindRepackRanges(t *testing.T) {
+	require := require.New(t)
+	merger := NewMerger("", 1, log.LvlInfo, uint256.Int{}, nil)
+	current := []Range{
+		{0, 500_000},
+		{500_000, 510_000}, {510_000, 520_000}, {520_000, 600_000}, // one 100k range
+		{600_000, 610_000}, {610_000, 700_000},
+		{700_000, 710_000}, {710_000, 800_000},
+		{800_000, 850_000}, {850_000, 950_000}, {950_000, 1_000_000}, // 850k-950k crosses 900k
+	}
+	require.Equal([]Range{{500_000, 600_000}, {600_000, 700_000}, {700_000, 800_000}}, merger.FindRepackRanges(current, 100_000))
+	require.Equal([]Range{{500_000, 1_000_000}}, merger.FindRepackRanges(current, 500_000))
+	require.Empty(merger.FindRepackRanges(current, 10_000))
+}
+
 func TestCanRetire(t *testing.T) {
 	require := require.New(t)
 	cases := []struct {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"runtime"
 
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/common/dbg"
@@ -20,6 +21,8 @@ import (
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/adapter"
 	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+	"golang.org/x/sync/errgroup"
 )
 
 const BlockBufferSize = 128
@@ -41,6 +44,12 @@ func (bd *BodyDownload) UpdateFromDb(db kv.Tx) (headHeight uint64, headHash comm
 	bd.lowWaitUntil = 0
 	bd.requestHigh = bd.requestedLow + (bd.outstandingLimit / 2)
 	bd.requestedMap = make(map[DoubleHash]uint64)
+	// Undelivered requests are sent again, deliveries of the previous ones remain in the deliveryCh and are matched by roots
+	for blockNum := range bd.verifiedRoots {
+		if blockNum < bd.requestedLow {
+			delete(bd.verifiedRoots, blockNum)
+		}
+	}
 	bd.delivered.Clear()
 	bd.deliveredCount = 0
 	bd.wastedCount = 0
@@ -157,10 +166,9 @@ func (bd *BodyDownload) RequestMoreBodies(tx kv.RwTx, blockReader services.FullB
 					// Perhaps we already have this block
 					block := rawdb.ReadBlock(tx, hash, blockNum)
 					if block == nil {
-						var doubleHash DoubleHash
-						copy(doubleHash[:], header.UncleHash.Bytes())
-						copy(doubleHash[common.HashLength:], header.TxHash.Bytes())
+						doubleHash := headerRoots(header)
 						bd.requestedMap[doubleHash] = blockNum
+						bd.knownRoots.Add(doubleHash, blockNum)
 					} else {
 						err = bd.addBodyToBucket(tx, blockNum, block.RawBody())
 						if err != nil {
@@ -278,8 +286,93 @@ func (bd *BodyDownload) DeliverySize(delivered float64, wasted float64) {
 	bd.wastedCount += wasted
 }
 
-func (bd *BodyDownload) GetDeliveries(tx kv.RwTx) (uint64, uint64, error) {
+// headerRoots - the uncle and transaction roots a body of the header must have
+func headerRoots(header *types.Header) DoubleHash {
+	var doubleHash DoubleHash
+	copy(doubleHash[:], header.UncleHash.Bytes())
+	copy(doubleHash[common.HashLength:], header.TxHash.Bytes())
+	return doubleHash
+}
+
+// bodyRoots - the uncle and transaction roots computed of the body
+func bodyRoots(txs [][]byte, uncles []*types.Header) DoubleHash {
+	var doubleHash DoubleHash
+	copy(doubleHash[:], types.CalcUncleHash(uncles).Bytes())
+	copy(doubleHash[common.HashLength:], types.DeriveSha(RawTransactions(txs)).Bytes())
+	return doubleHash
+}
+
+// deliveryRoots - roots of all bodies of the delivery, hashing of transactions is the expensive part so it's spread over workers
+func deliveryRoots(txs [][][]byte, uncles [][]*types.Header) []DoubleHash {
+	roots := make([]DoubleHash, len(txs))
+	var g errgroup.Group
+	g.SetLimit(runtime.NumCPU())
+	for i := range txs {
+		i := i
+		g.Go(func() error {
+			roots[i] = bodyRoots(txs[i], uncles[i])
+			return nil
+		})
+	}
+	_ = g.Wait() // workers don't fail
+	return roots
+}
+
+// VerifyBody checks the body has the uncle and transaction roots of its header
+func VerifyBody(header *types.Header, body *types.RawBody) error {
+	if hash := types.CalcUncleHash(body.Uncles); hash != header.UncleHash {
+		return fmt.Errorf("invalid uncles root: have %x, expected %x", hash, header.UncleHash)
+	}
+	if hash := types.DeriveSha(RawTransactions(body.Transactions)); hash != header.TxHash {
+		return fmt.Errorf("invalid transactions root: have %x, expected %x", hash, header.TxHash)
+	}
+	return nil
+}
+
+// PrepareVerification computes the roots of the bodies of blocks [from, from+count) in parallel, for bodies of other
+// sources than deliveries (bucket of an earlier run, database, prefetch), so that VerifyBody only compares them.
+func (bd *BodyDownload) PrepareVerification(tx kv.RwTx, from, count uint64) error {
+	var blockNums []uint64
+	var txs [][][]byte
+	var uncles [][]*types.Header
+	for blockNum := from; blockNum < from+count; blockNum++ {
+		if _, ok := bd.verifiedRoots[blockNum]; ok {
+			continue
+		}
+		body, err := bd.GetBlockFromCache(tx, blockNum)
+		if err != nil {
+			return err
+		}
+		if body == nil {
+			continue
+		}
+		blockNums = append(blockNums, blockNum)
+		txs, uncles = append(txs, body.Transactions), append(uncles, body.Uncles)
+	}
+	for i, doubleHash := range deliveryRoots(txs, uncles) {
+		bd.verifiedRoots[blockNums[i]] = doubleHash
+	}
+	return nil
+}
+
+// VerifyBody checks the body of the block has the roots of its header. Roots of delivered bodies are computed
+// in GetDeliveries already, ones of other bodies by PrepareVerification. Bodies with roots of neither are hashed here,
+// and so are mismatching ones: for the error.
+func (bd *BodyDownload) VerifyBody(blockNum uint64, header *types.Header, body *types.RawBody) error {
+	if doubleHash, ok := bd.verifiedRoots[blockNum]; ok {
+		delete(bd.verifiedRoots, blockNum)
+		if doubleHash == headerRoots(header) {
+			return nil
+		}
+	}
+	return VerifyBody(header, body)
+}
+
+// GetDeliveries stores the delivered bodies matching the roots of requested headers. Peers which delivered bodies
+// matching no header at all are returned as penalties, such bodies are dropped and the blocks remain requested.
+func (bd *BodyDownload) GetDeliveries(tx kv.RwTx) (uint64, uint64, []headerdownload.PenaltyItem, error) {
 	var delivered, undelivered int
+	var penalties []headerdownload.PenaltyItem
 Loop:
 	for {
 		var delivery Delivery
@@ -302,20 +395,25 @@ Loop:
 		}
 
 		reqMap := make(map[uint64]*BodyRequest)
-		txs, uncles, lenOfP2PMessage, _ := *delivery.txs, *delivery.uncles, delivery.lenOfP2PMessage, delivery.peerID
-
-		for i := range txs {
-			uncleHash := types.CalcUncleHash(uncles[i])
-			txHash := types.DeriveSha(RawTransactions(txs[i]))
-			var doubleHash DoubleHash
-			copy(doubleHash[:], uncleHash.Bytes())
-			copy(doubleHash[common.HashLength:], txHash.Bytes())
+		txs, uncles, lenOfP2PMessage, peerID := *delivery.txs, *delivery.uncles, delivery.lenOfP2PMessage, delivery.peerID
+		if len(txs) != len(uncles) {
+			log.Debug("delivery of mismatching transactions and uncles", "peer_id", peerID, "txs", len(txs), "uncles", len(uncles))
+			penalties = append(penalties, headerdownload.PenaltyItem{PeerID: peerID, Penalty: headerdownload.BadBlockPenalty})
+			continue
+		}
 
+		var invalid int
+		for i, doubleHash := range deliveryRoots(txs, uncles) {
 			// Block numbers are added to the bd.delivered bitmap here, only for blocks for which the body has been received, and their double hashes are present in the bd.requestedMap
 			// Also, block numbers can be added to bd.delivered for empty blocks, above
 			blockNum, ok := bd.requestedMap[doubleHash]
 			if !ok {
 				undelivered++
+				// Late or repeated deliveries are honest, bodies matching no header requested are corrupted or made up.
+				// Empty bodies are never requested, some peers may send them for blocks they don't have
+				if !bd.knownRoots.Contains(doubleHash) && (len(txs[i]) > 0 || len(uncles[i]) > 0) {
+					invalid++
+				}
 				continue
 			}
 			req := bd.requests[blockNum]
@@ -328,11 +426,16 @@ Loop:
 
 			err := bd.addBodyToBucket(tx, blockNum, &types.RawBody{Transactions: txs[i], Uncles: uncles[i]})
 			if err != nil {
-				return 0, 0, err
+				return 0, 0, nil, err
 			}
+			bd.verifiedRoots[blockNum] = doubleHash
 			bd.delivered.Add(blockNum)
 			delivered++
 		}
+		if invalid > 0 {
+			log.Debug("invalid bodies delivered", "peer_id", peerID, "invalid", invalid, "bodies", len(txs))
+			penalties = append(penalties, headerdownload.PenaltyItem{PeerID: peerID, Penalty: headerdownload.BadBlockPenalty})
+		}
 		// Clean up the requests
 		for _, req := range reqMap {
 			for _, blockNum := range req.BlockNums {
@@ -346,7 +449,7 @@ Loop:
 		}
 	}
 
-	return bd.requestedLow, uint64(delivered), nil
+	return bd.requestedLow, uint64(delivered), penalties, nil
 }
 
 // NextProcessingCount returns the count of contiguous block numbers ready to process from the
@@ -361,6 +464,31 @@ func (bd *BodyDownload) NextProcessingCount() uint64 {
 	return i
 }
 
+// RejectBody drops the body of the block, which failed verification after it was delivered, and requests it again.
+// Processing restarts from the block, bodies of the following blocks are kept.
+func (bd *BodyDownload) RejectBody(tx kv.RwTx, blockNum uint64, header *types.Header) error {
+	if !bd.UsingExternalTx {
+		if err := tx.Delete("BodiesStage", dbutils.EncodeBlockNumber(blockNum)); err != nil {
+			return err
+		}
+	} else {
+		delete(bd.bodyCache, blockNum)
+	}
+	doubleHash := headerRoots(header)
+	delete(bd.verifiedRoots, blockNum)
+	bd.requestedMap[doubleHash] = blockNum
+	bd.knownRoots.Add(doubleHash, blockNum)
+	bd.deliveriesH[blockNum] = header
+	bd.requests[blockNum] = nil
+	for next := blockNum + 1; next < bd.requestedLow; next++ {
+		bd.delivered.Add(next)
+	}
+	if blockNum < bd.requestedLow {
+		bd.requestedLow = blockNum
+	}
+	return nil
+}
+
 func (bd *BodyDownload) DeliveryCounts() (float64, float64) {
 	return bd.deliveredCount, bd.wastedCount
 }
@@ -406,23 +534,22 @@ func (bd *BodyDownload) AddMinedBlock(block *types.Block) error {
 // as the requestedLow count is incremented before a call to this function we need the process count so that we can anticipate this,
 // effectively reversing time a little to get the actual position we need in the slice prior to requestedLow being incremented
 func (bd *BodyDownload) GetHeader(blockNum uint64, blockReader services.FullBlockReader, tx kv.Tx) (*types.Header, common.Hash, error) {
-	var header *types.Header
-	if bd.deliveriesH[blockNum] != nil {
-		header = bd.deliveriesH[blockNum]
-	} else {
-		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
-		if err != nil {
-			return nil, common.Hash{}, err
-		}
-		header, err = blockReader.Header(context.Background(), tx, hash, blockNum)
-		if err != nil {
-			return nil, common.Hash{}, err
-		}
-		if header == nil {
-			return nil, common.Hash{}, fmt.Errorf("header not found: blockNum=%d, hash=%x, trace=%s", blockNum, hash, dbg.Stack())
-		}
+	if header := bd.deliveriesH[blockNum]; header != nil {
+		return header, header.Hash(), nil
+	}
+	// the canonical hash is known, no need to hash the header
+	hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	header, err := blockReader.Header(context.Background(), tx, hash, blockNum)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	if header == nil {
+		return nil, common.Hash{}, fmt.Errorf("header not found: blockNum=%d, hash=%x, trace=%s", blockNum, hash, dbg.Stack())
 	}
-	return header, header.Hash(), nil
+	return header, hash, nil
 }
 
 func (bd *BodyDownload) addBodyToBucket(tx kv.RwTx, key uint64, body *types.RawBody) error {
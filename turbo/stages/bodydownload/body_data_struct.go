@@ -2,6 +2,7 @@ package bodydownload
 
 import (
 	"github.com/RoaringBitmap/roaring/roaring64"
+	lru "github.com/hashicorp/golang-lru"
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus"
@@ -13,6 +14,9 @@ type DoubleHash [2 * common.HashLength]byte
 
 const MaxBodiesInRequest = 1024
 
+// knownRootsLimit - number of roots of requested headers remembered, deliveries matching them aren't penalised even when late
+const knownRootsLimit = 65536
+
 type Delivery struct {
 	peerID          [64]byte
 	txs             *[][][]byte
@@ -24,6 +28,8 @@ type Delivery struct {
 type BodyDownload struct {
 	peerMap          map[[64]byte]int
 	requestedMap     map[DoubleHash]uint64
+	knownRoots       *lru.Cache            // Roots of requested headers, late or repeated deliveries of them are honest, not invalid bodies
+	verifiedRoots    map[uint64]DoubleHash // Roots computed of the bodies in the bucket, these don't need to be hashed again
 	DeliveryNotify   chan struct{}
 	deliveryCh       chan Delivery
 	Engine           consensus.Engine
@@ -55,6 +61,7 @@ type BodyRequest struct {
 func NewBodyDownload(outstandingLimit int, engine consensus.Engine) *BodyDownload {
 	bd := &BodyDownload{
 		requestedMap:     make(map[DoubleHash]uint64),
+		verifiedRoots:    make(map[uint64]DoubleHash),
 		outstandingLimit: uint64(outstandingLimit),
 		delivered:        roaring64.New(),
 		deliveriesH:      make(map[uint64]*types.Header),
@@ -73,5 +80,6 @@ func NewBodyDownload(outstandingLimit int, engine consensus.Engine) *BodyDownloa
 		Engine:     engine,
 		bodyCache:  make(map[uint64]*types.RawBody),
 	}
+	bd.knownRoots, _ = lru.New(knownRootsLimit)
 	return bd
 }
@@ -1,11 +1,15 @@
 package bodydownload
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
 )
 
 func TestCreateBodyDownload(t *testing.T) {
@@ -15,3 +19,80 @@ func TestCreateBodyDownload(t *testing.T) {
 		t.Fatalf("update from db: %v", err)
 	}
 }
+
+func TestDeliveriesVerification(t *testing.T) {
+	require := require.New(t)
+	bd := NewBodyDownload(100, ethash.NewFaker())
+	bd.UsingExternalTx = true
+	bd.requestedLow = 1
+
+	body := &types.RawBody{Transactions: [][]byte{{0x01, 0x02}}}
+	header := &types.Header{Number: big.NewInt(1), UncleHash: types.CalcUncleHash(nil), TxHash: types.DeriveSha(RawTransactions(body.Transactions))}
+	require.NoError(VerifyBody(header, body))
+	require.Error(VerifyBody(header, &types.RawBody{Transactions: [][]byte{{0x03}}}))
+	bd.requestedMap[headerRoots(header)] = 1
+	bd.knownRoots.Add(headerRoots(header), uint64(1))
+	bd.deliveriesH[1] = header
+
+	deliver := func(peerID [64]byte, txs []byte) {
+		bodyTxs, uncles := [][][]byte{{txs}}, [][]*types.Header{nil}
+		bd.DeliverBodies(&bodyTxs, &uncles, 0, peerID)
+	}
+	honest, corrupting, late := [64]byte{1}, [64]byte{2}, [64]byte{3}
+	deliver(honest, []byte{0x01, 0x02})
+	deliver(corrupting, []byte{0x03})
+	_, delivered, penalties, err := bd.GetDeliveries(nil)
+	require.NoError(err)
+	require.Equal(uint64(1), delivered)
+	require.Equal([]headerdownload.PenaltyItem{{PeerID: corrupting, Penalty: headerdownload.BadBlockPenalty}}, penalties)
+	// roots of the delivered body are computed already, the bodies stage doesn't hash it again
+	require.Equal(headerRoots(header), bd.verifiedRoots[1])
+
+	// a late delivery of the same body isn't penalised
+	deliver(late, []byte{0x01, 0x02})
+	_, delivered, penalties, err = bd.GetDeliveries(nil)
+	require.NoError(err)
+	require.Zero(delivered)
+	require.Empty(penalties)
+	require.Equal(uint64(1), bd.NextProcessingCount())
+	require.NoError(bd.VerifyBody(1, header, body))
+	require.Empty(bd.verifiedRoots)
+
+	// rejected after delivery, the body is requested again
+	require.NoError(bd.RejectBody(nil, 1, header))
+	require.Nil(bd.bodyCache[1])
+	require.Zero(bd.NextProcessingCount())
+	deliver(late, []byte{0x01, 0x02})
+	_, delivered, penalties, err = bd.GetDeliveries(nil)
+	require.NoError(err)
+	require.Equal(uint64(1), delivered)
+	require.Empty(penalties)
+	require.Equal(uint64(1), bd.NextProcessingCount())
+
+	// deliveries of the requests of the previous cycle are still matched, late ones aren't penalised
+	bd.requestedMap = make(map[DoubleHash]uint64)
+	deliver(late, []byte{0x01, 0x02})
+	deliver(corrupting, []byte{0x04})
+	_, delivered, penalties, err = bd.GetDeliveries(nil)
+	require.NoError(err)
+	require.Zero(delivered)
+	require.Equal([]headerdownload.PenaltyItem{{PeerID: corrupting, Penalty: headerdownload.BadBlockPenalty}}, penalties)
+}
+
+func TestPrepareVerification(t *testing.T) {
+	require := require.New(t)
+	bd := NewBodyDownload(100, ethash.NewFaker())
+	bd.UsingExternalTx = true
+
+	// bodies of the bucket of an earlier run, the second one corrupted
+	body := &types.RawBody{Transactions: [][]byte{{0x01, 0x02}}}
+	header := &types.Header{Number: big.NewInt(1), UncleHash: types.CalcUncleHash(nil), TxHash: types.DeriveSha(RawTransactions(body.Transactions))}
+	bd.bodyCache[1], bd.bodyCache[2] = body, &types.RawBody{Transactions: [][]byte{{0x03}}}
+	require.NoError(bd.PrepareVerification(nil, 1, 3))
+	require.Equal(headerRoots(header), bd.verifiedRoots[1])
+	require.Len(bd.verifiedRoots, 2)
+
+	require.NoError(bd.VerifyBody(1, header, body))
+	require.ErrorContains(bd.VerifyBody(2, header, bd.bodyCache[2]), "invalid transactions root")
+	require.Empty(bd.verifiedRoots)
+}
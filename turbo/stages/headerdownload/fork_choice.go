@@ -0,0 +1,42 @@
+package headerdownload
+
+import (
+	"math/big"
+
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// ForkChoice decides whether a header fed to the HeaderInserter becomes the new head of the canonical chain.
+// A new head makes the HeaderInserter move the canonical chain to it, unwinding other stages to the forking
+// point if needed. Chains with their own finality rules plug in an implementation by their consensus engine,
+// see EngineForkChoice.
+type ForkChoice interface {
+	// NewCanonical is called for every inserted header with its total difficulty and the total difficulty
+	// of the current head, headTd is nil when the head isn't tracked (headers of the post-merge sync)
+	NewCanonical(header *types.Header, td, headTd *big.Int) bool
+}
+
+// TotalDifficultyForkChoice - the chain with the highest total difficulty wins, the rule of proof-of-work
+// and clique chains before the merge
+type TotalDifficultyForkChoice struct{}
+
+func (TotalDifficultyForkChoice) NewCanonical(header *types.Header, td, headTd *big.Int) bool {
+	return td != nil && headTd != nil && td.Cmp(headTd) > 0
+}
+
+// ExternalForkChoice - the head is chosen outside of the header stage, by forkchoiceUpdated of the consensus layer.
+// Headers are only stored, canonical markers are moved when the fork choice message arrives.
+type ExternalForkChoice struct{}
+
+func (ExternalForkChoice) NewCanonical(header *types.Header, td, headTd *big.Int) bool {
+	return false
+}
+
+// EngineForkChoice - the rule of the consensus engine if it implements ForkChoice, nil (total difficulty) otherwise
+func EngineForkChoice(engine consensus.Engine) ForkChoice {
+	if forkChoice, ok := engine.(ForkChoice); ok {
+		return forkChoice
+	}
+	return nil
+}
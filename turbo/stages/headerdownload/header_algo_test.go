@@ -7,7 +7,10 @@ import (
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/params"
@@ -37,7 +40,7 @@ func TestInserter1(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer tx.Rollback()
-	hi := NewHeaderInserter("headers", big.NewInt(0), 0, snapshotsync.NewBlockReader())
+	hi := NewHeaderInserter("headers", big.NewInt(0), 0, snapshotsync.NewBlockReader(), nil)
 	h1 := types.Header{
 		Number:     big.NewInt(1),
 		Difficulty: big.NewInt(10),
@@ -59,3 +62,116 @@ func TestInserter1(t *testing.T) {
 		t.Errorf("feed empty header 2: %v", err)
 	}
 }
+
+func TestInserterForkChoice(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	defer db.Close()
+	_, genesis, err := core.CommitGenesisBlock(db, &core.Genesis{Config: params.AllEthashProtocolChanges})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginRw(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	genesisTd, err := rawdb.ReadTd(tx, genesis.Hash(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h1 := types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(10), ParentHash: genesis.Hash()}
+	data1, _ := rlp.EncodeToBytes(&h1)
+
+	// the head of the external rule isn't moved by total difficulty, the header is stored anyway
+	hi := NewHeaderInserter("headers", new(big.Int).Set(genesisTd), 0, snapshotsync.NewBlockReader(), ExternalForkChoice{})
+	if _, err = hi.FeedHeaderPoW(tx, snapshotsync.NewBlockReader(), &h1, data1, h1.Hash(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if hi.BestHeaderChanged() {
+		t.Error("external fork choice moved the head")
+	}
+	if h, _ := snapshotsync.NewBlockReader().Header(context.Background(), tx, h1.Hash(), 1); h == nil {
+		t.Error("header wasn't stored")
+	}
+
+	// engines without a rule of their own keep total difficulty
+	h2 := types.Header{Number: big.NewInt(2), Difficulty: big.NewInt(10), ParentHash: h1.Hash()}
+	data2, _ := rlp.EncodeToBytes(&h2)
+	hi = NewHeaderInserter("headers", new(big.Int).Set(genesisTd), 0, snapshotsync.NewBlockReader(), EngineForkChoice(ethash.NewFaker()))
+	if _, err = hi.FeedHeaderPoW(tx, snapshotsync.NewBlockReader(), &h2, data2, h2.Hash(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if !hi.BestHeaderChanged() || hi.GetHighestHash() != h2.Hash() {
+		t.Error("heavier header didn't become the head")
+	}
+}
+
+func TestRequestParentHole(t *testing.T) {
+	hd := NewHeaderDownload(16, 128, nil, snapshotsync.NewBlockReader())
+	h := &types.Header{
+		Number:     big.NewInt(10),
+		Difficulty: big.NewInt(10),
+		ParentHash: common.HexToHash("0x01"),
+	}
+	raw, _ := rlp.EncodeToBytes(h)
+	hd.ProcessHeaders([]ChainSegmentHeader{{Header: h, HeaderRaw: raw, Hash: h.Hash(), Number: 10}}, true /* newBlock */, [64]byte{})
+
+	if req := hd.RequestParentHole(h, 8); req != nil {
+		t.Errorf("expected no request for a hole longer than the limit, got %+v", req)
+	}
+	req := hd.RequestParentHole(h, 32)
+	if req == nil {
+		t.Fatal("expected request for the parent hole")
+	}
+	if req.Hash != h.ParentHash || req.Number != 9 || req.Length != 9 || !req.Reverse {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
+
+func TestPeerLimits(t *testing.T) {
+	hd := NewHeaderDownload(16, 128, nil, snapshotsync.NewBlockReader())
+	hd.SetPeerLimits(PeerLimits{MaxSegmentLength: 4, AnnouncesPerSecond: 1, AnnounceBurst: 2, MaxUnverified: 3})
+	peer, other := [64]byte{1}, [64]byte{2}
+
+	if p := hd.AdmitSegment(5); p != TooLongSegmentPenalty {
+		t.Errorf("expected %s, got %s", TooLongSegmentPenalty, p)
+	}
+	if p := hd.AdmitAnnounces(peer, 2); p != NoPenalty {
+		t.Errorf("expected no penalty within the burst, got %s", p)
+	}
+	if p := hd.AdmitAnnounces(peer, 1); p != TooFrequentAnnouncesPenalty {
+		t.Errorf("expected %s, got %s", TooFrequentAnnouncesPenalty, p)
+	}
+	if p := hd.AdmitAnnounces(other, 1); p != NoPenalty {
+		t.Errorf("expected no penalty for another peer, got %s", p)
+	}
+
+	// unconnected chain of 5 headers, only 3 of them are buffered
+	var segment []ChainSegmentHeader
+	parent := common.HexToHash("0x01")
+	for i := int64(100); i < 105; i++ {
+		h := &types.Header{Number: big.NewInt(i), Difficulty: big.NewInt(10), ParentHash: parent}
+		raw, _ := rlp.EncodeToBytes(h)
+		segment = append(segment, ChainSegmentHeader{Header: h, HeaderRaw: raw, Hash: h.Hash(), Number: uint64(i)})
+		parent = h.Hash()
+	}
+	hd.ProcessHeaders(segment, false /* newBlock */, peer)
+	for i, h := range segment {
+		if hd.HasLink(h.Hash) != (i < 3) {
+			t.Errorf("header %d: unexpected link presence", h.Number)
+		}
+	}
+	hd.ProcessHeaders(segment[3:], false /* newBlock */, other)
+	if !hd.HasLink(segment[4].Hash) {
+		t.Error("expected headers of another peer to be buffered")
+	}
+
+	// removal of links releases the limit of the peer
+	hd.lock.Lock()
+	hd.removeUpwards(hd.links[segment[0].Hash])
+	hd.lock.Unlock()
+	hd.ProcessHeaders(segment[:1], false /* newBlock */, peer)
+	if !hd.HasLink(segment[0].Hash) {
+		t.Error("expected header to be buffered after release")
+	}
+}
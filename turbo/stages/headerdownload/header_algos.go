@@ -200,6 +200,7 @@ func (hd *HeaderDownload) removeAnchor(anchor *Anchor) {
 func (hd *HeaderDownload) pruneLinkQueue() {
 	for hd.linkQueue.Len() > hd.linkLimit {
 		link := heap.Pop(&hd.linkQueue).(*Link)
+		hd.releaseBufferedLink(link)
 		delete(hd.links, link.hash)
 		for child := link.fChild; child != nil; child, child.next = child.next, nil {
 		}
@@ -343,7 +344,7 @@ func (hd *HeaderDownload) RecoverFromDb(db kv.RoDB) error {
 					Hash:      types.RawRlpHash(v),
 					Number:    header.Number.Uint64(),
 				}
-				hd.addHeaderAsLink(h, true /* persisted */)
+				hd.addHeaderAsLink(h, true /* persisted */, [64]byte{})
 			}
 
 			select {
@@ -414,6 +415,31 @@ func (hd *HeaderDownload) RequestMoreHeaders(currentTime time.Time) (*HeaderRequ
 	return nil, penalties
 }
 
+// RequestParentHole produces a request for the missing ancestors of a freshly announced
+// block, if the block became an anchor and the hole between it and the highest header
+// in the db is not longer than maxGap. The request is meant to be sent to the peer which
+// announced the block, since it is known to have the ancestors.
+func (hd *HeaderDownload) RequestParentHole(header *types.Header, maxGap uint64) *HeaderRequest {
+	hd.lock.RLock()
+	defer hd.lock.RUnlock()
+	anchor, ok := hd.anchors[header.ParentHash]
+	if !ok || anchor.blockHeight != header.Number.Uint64() || anchor.blockHeight <= hd.highestInDb+1 {
+		return nil
+	}
+	gap := anchor.blockHeight - 1 - hd.highestInDb
+	if gap > maxGap {
+		return nil
+	}
+	return &HeaderRequest{
+		Anchor:  anchor,
+		Hash:    anchor.parentHash,
+		Number:  anchor.blockHeight - 1,
+		Length:  gap,
+		Skip:    0,
+		Reverse: true,
+	}
+}
+
 func (hd *HeaderDownload) requestMoreHeadersForPOS(currentTime time.Time) (timeout bool, request *HeaderRequest, penalties []PenaltyItem) {
 	anchor := hd.posAnchor
 	if anchor == nil {
@@ -748,13 +774,14 @@ func (hd *HeaderDownload) getLink(linkHash common.Hash) (*Link, bool) {
 }
 
 // addHeaderAsLink wraps header into a link and adds it to either queue of persisted links or queue of non-persisted links
-func (hd *HeaderDownload) addHeaderAsLink(h ChainSegmentHeader, persisted bool) *Link {
+func (hd *HeaderDownload) addHeaderAsLink(h ChainSegmentHeader, persisted bool, peerID [64]byte) *Link {
 	link := &Link{
 		blockHeight: h.Number,
 		hash:        h.Hash,
 		header:      h.Header,
 		headerRaw:   h.HeaderRaw,
 		persisted:   persisted,
+		peerID:      peerID,
 	}
 	if persisted {
 		link.linked = true
@@ -779,7 +806,7 @@ func (hi *HeaderInserter) NewFeedHeaderFunc(db kv.StatelessRwTx, headerReader se
 
 // Find the forking point - i.e. the latest header on the canonical chain which is an ancestor of this one
 // Most common case - forking point is the height of the parent header
-func (hi *HeaderInserter) ForkingPoint(db kv.StatelessRwTx, header, parent *types.Header) (forkingPoint uint64, err error) {
+func (hi *HeaderInserter) ForkingPoint(db kv.Getter, header, parent *types.Header) (forkingPoint uint64, err error) {
 	blockHeight := header.Number.Uint64()
 	var ch common.Hash
 	if fromCache, ok := hi.canonicalCache.Get(blockHeight - 1); ok {
@@ -852,15 +879,44 @@ func (hi *HeaderInserter) FeedHeaderPoW(db kv.StatelessRwTx, headerReader servic
 		// Fail on headers without parent
 		return nil, fmt.Errorf("could not find parent with hash %x and height %d for header %x %d", header.ParentHash, blockHeight-1, hash, blockHeight)
 	}
+	return hi.insertHeader(db, header, headerRaw, hash, blockHeight, parent)
+}
+
+func (hi *HeaderInserter) FeedHeaderPoS(db kv.GetPut, header *types.Header, hash common.Hash) error {
+	blockHeight := header.Number.Uint64()
+	// TODO(yperbasis): do we need to check if the header is already inserted (oldH)?
+	if _, err := hi.insertHeader(db, header, nil, hash, blockHeight, nil); err != nil {
+		return err
+	}
+
+	hi.highest = blockHeight
+	hi.highestHash = hash
+	hi.highestTimestamp = header.Time
+
+	return nil
+}
+
+// insertHeader stores the header with its total difficulty and moves the canonical head to it if the fork choice
+// rule says so. headerRaw is the RLP of the header, encoded here if nil; parent is read here if nil and needed for a new head.
+func (hi *HeaderInserter) insertHeader(db kv.GetPut, header *types.Header, headerRaw []byte, hash common.Hash, blockHeight uint64, parent *types.Header) (*big.Int, error) {
 	// Parent's total difficulty
 	parentTd, err := rawdb.ReadTd(db, header.ParentHash, blockHeight-1)
 	if err != nil || parentTd == nil {
 		return nil, fmt.Errorf("[%s] parent's total difficulty not found with hash %x and height %d for header %x %d: %v", hi.logPrefix, header.ParentHash, blockHeight-1, hash, blockHeight, err)
 	}
 	// Calculate total difficulty of this header using parent's total difficulty
-	td = new(big.Int).Add(parentTd, header.Difficulty)
+	td := new(big.Int).Add(parentTd, header.Difficulty)
 	// Now we can decide wether this header will create a change in the canonical head
-	if td.Cmp(hi.localTd) > 0 {
+	if hi.forkChoice.NewCanonical(header, td, hi.localTd) {
+		if parent == nil {
+			// headers of the post-merge sync are fed without their parent
+			if parent, err = hi.headerReader.Header(context.Background(), db, header.ParentHash, blockHeight-1); err != nil {
+				return nil, err
+			}
+			if parent == nil {
+				return nil, fmt.Errorf("[%s] could not find parent with hash %x and height %d for new head %x %d", hi.logPrefix, header.ParentHash, blockHeight-1, hash, blockHeight)
+			}
+		}
 		hi.newCanonical = true
 		forkingPoint, err := hi.ForkingPoint(db, header, parent)
 		if err != nil {
@@ -876,13 +932,18 @@ func (hi *HeaderInserter) FeedHeaderPoW(db kv.StatelessRwTx, headerReader servic
 			hi.unwind = true
 		}
 		// This makes sure we end up choosing the chain with the max total difficulty
+		if hi.localTd == nil {
+			hi.localTd = new(big.Int)
+		}
 		hi.localTd.Set(td)
 	}
 	if err = rawdb.WriteTd(db, hash, blockHeight, td); err != nil {
 		return nil, fmt.Errorf("[%s] failed to WriteTd: %w", hi.logPrefix, err)
 	}
 
-	if err = db.Put(kv.Headers, dbutils.HeaderKey(blockHeight, hash), headerRaw); err != nil {
+	if headerRaw == nil {
+		rawdb.WriteHeader(db, header)
+	} else if err = db.Put(kv.Headers, dbutils.HeaderKey(blockHeight, hash), headerRaw); err != nil {
 		return nil, fmt.Errorf("[%s] failed to store header: %w", hi.logPrefix, err)
 	}
 
@@ -890,27 +951,6 @@ func (hi *HeaderInserter) FeedHeaderPoW(db kv.StatelessRwTx, headerReader servic
 	return td, nil
 }
 
-func (hi *HeaderInserter) FeedHeaderPoS(db kv.GetPut, header *types.Header, hash common.Hash) error {
-	blockHeight := header.Number.Uint64()
-	// TODO(yperbasis): do we need to check if the header is already inserted (oldH)?
-
-	parentTd, err := rawdb.ReadTd(db, header.ParentHash, blockHeight-1)
-	if err != nil || parentTd == nil {
-		return fmt.Errorf("[%s] parent's total difficulty not found with hash %x and height %d for header %x %d: %v", hi.logPrefix, header.ParentHash, blockHeight-1, hash, blockHeight, err)
-	}
-	td := new(big.Int).Add(parentTd, header.Difficulty)
-	if err = rawdb.WriteTd(db, hash, blockHeight, td); err != nil {
-		return fmt.Errorf("[%s] failed to WriteTd: %w", hi.logPrefix, err)
-	}
-	rawdb.WriteHeader(db, header)
-
-	hi.highest = blockHeight
-	hi.highestHash = hash
-	hi.highestTimestamp = header.Time
-
-	return nil
-}
-
 func (hi *HeaderInserter) GetHighest() uint64 {
 	return hi.highest
 }
@@ -964,7 +1004,10 @@ func (hd *HeaderDownload) ProcessHeader(sh ChainSegmentHeader, newBlock bool, pe
 			return false
 		}
 	}
-	link := hd.addHeaderAsLink(sh, false /* persisted */)
+	if !hd.peerCanBuffer(peerID) {
+		return false
+	}
+	link := hd.addHeaderAsLink(sh, false /* persisted */, peerID)
 	if foundAnchor {
 		// The new link is what anchor was pointing to, so the link takes over the child links of the anchor and the anchor is removed
 		link.fChild = anchor.fLink
@@ -1236,7 +1279,7 @@ func (hd *HeaderDownload) AddHeadersFromSnapshot(tx kv.Tx, n uint64, r services.
 			Hash:      header.Hash(),
 			Number:    header.Number.Uint64(),
 		}
-		link := hd.addHeaderAsLink(h, true /* persisted */)
+		link := hd.addHeaderAsLink(h, true /* persisted */, [64]byte{})
 		link.verified = true
 	}
 	if hd.highestInDb < n {
@@ -38,11 +38,12 @@ type Link struct {
 	next        *Link       // Pointer to the next sibling, or nil if there are no siblings
 	hash        common.Hash // Hash of the header
 	blockHeight uint64
-	persisted   bool    // Whether this link comes from the database record
-	verified    bool    // Ancestor of pre-verified header or verified by consensus engine
-	linked      bool    // Whether this link is connected (via chain of ParentHash to one of the persisted links)
-	idx         int     // Index in the heap
-	queueId     QueueID // which queue this link belongs to
+	persisted   bool     // Whether this link comes from the database record
+	verified    bool     // Ancestor of pre-verified header or verified by consensus engine
+	linked      bool     // Whether this link is connected (via chain of ParentHash to one of the persisted links)
+	idx         int      // Index in the heap
+	queueId     QueueID  // which queue this link belongs to
+	peerID      [64]byte // Peer which delivered the header
 }
 
 // LinkQueue is the priority queue of links. It is instantiated once for persistent links, and once for non-persistent links
@@ -183,6 +184,8 @@ const (
 	TooFarPastPenalty
 	AbandonedAnchorPenalty
 	NewBlockGossipAfterMergePenalty
+	TooLongSegmentPenalty
+	TooFrequentAnnouncesPenalty
 )
 
 type PeerPenalty struct {
@@ -316,6 +319,10 @@ type HeaderDownload struct {
 	unsettledHeadHeight  uint64                       // Height of unsettledForkChoice.headBlockHash
 	posDownloaderTip     common.Hash                  // See https://hackmd.io/GDc0maGsQeKfP8o2C7L52w
 	badPoSHeaders        map[common.Hash]common.Hash  // Invalid Tip -> Last Valid Ancestor
+
+	// Anti-DoS limits per peer
+	peerLimits PeerLimits
+	peerUsage  map[[64]byte]*peerUsage
 }
 
 // HeaderRecord encapsulates two forms of the same header - raw RLP encoding (to avoid duplicated decodings and encodings), and parsed value types.Header
@@ -348,6 +355,8 @@ func NewHeaderDownload(
 		ShutdownCh:         make(chan struct{}),
 		headerReader:       headerReader,
 		badPoSHeaders:      make(map[common.Hash]common.Hash),
+		peerLimits:         DefaultPeerLimits,
+		peerUsage:          make(map[[64]byte]*peerUsage),
 	}
 	heap.Init(&hd.persistedLinkQueue)
 	heap.Init(&hd.linkQueue)
@@ -376,6 +385,10 @@ func (p Penalty) String() string {
 		return "TooFarPast"
 	case NewBlockGossipAfterMergePenalty:
 		return "NewBlockGossipAfterMerge"
+	case TooLongSegmentPenalty:
+		return "TooLongSegment"
+	case TooFrequentAnnouncesPenalty:
+		return "TooFrequentAnnounces"
 	default:
 		return fmt.Sprintf("Unknown(%d)", p)
 	}
@@ -389,6 +402,7 @@ func (hd *HeaderDownload) moveLinkToQueue(link *Link, queueId QueueID) {
 	if link.queueId == queueId {
 		return
 	}
+	hd.trackBufferedLink(link, link.queueId, queueId)
 	// Remove
 	switch link.queueId {
 	case NoQueue:
@@ -426,14 +440,20 @@ type HeaderInserter struct {
 	highestTimestamp uint64
 	canonicalCache   *lru.Cache
 	headerReader     services.HeaderAndCanonicalReader
+	forkChoice       ForkChoice
 }
 
-func NewHeaderInserter(logPrefix string, localTd *big.Int, headerProgress uint64, headerReader services.HeaderAndCanonicalReader) *HeaderInserter {
+// NewHeaderInserter - forkChoice decides which headers become the head, TotalDifficultyForkChoice if nil
+func NewHeaderInserter(logPrefix string, localTd *big.Int, headerProgress uint64, headerReader services.HeaderAndCanonicalReader, forkChoice ForkChoice) *HeaderInserter {
+	if forkChoice == nil {
+		forkChoice = TotalDifficultyForkChoice{}
+	}
 	hi := &HeaderInserter{
 		logPrefix:    logPrefix,
 		localTd:      localTd,
 		unwindPoint:  headerProgress,
 		headerReader: headerReader,
+		forkChoice:   forkChoice,
 	}
 	hi.canonicalCache, _ = lru.New(1000)
 	return hi
@@ -0,0 +1,125 @@
+package headerdownload
+
+import (
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"golang.org/x/time/rate"
+)
+
+// PeerLimits restrict what a single peer can make the downloader do, so that one peer can't make the node
+// buffer enormous header chains
+type PeerLimits struct {
+	MaxSegmentLength   int        // Maximum number of headers in one BlockHeaders message
+	AnnouncesPerSecond rate.Limit // Sustained rate of NewBlock and NewBlockHashes announcements from one peer
+	AnnounceBurst      int        // Number of announcements allowed in a burst above the sustained rate
+	MaxUnverified      int        // Maximum number of headers from one peer held until they are inserted into the database
+}
+
+var DefaultPeerLimits = PeerLimits{
+	MaxSegmentLength:   1024, // maxHeadersServe of eth protocol
+	AnnouncesPerSecond: 1,
+	AnnounceBurst:      64,
+	MaxUnverified:      128 * 1024,
+}
+
+// peerLimitsGCThreshold - number of tracked peers after which the idle ones are forgotten
+const peerLimitsGCThreshold = 1024
+
+var (
+	headersDroppedTooLong    = metrics.GetOrCreateCounter(`headers_dropped{reason="segment_too_long"}`)
+	headersDroppedUnverified = metrics.GetOrCreateCounter(`headers_dropped{reason="peer_unverified_limit"}`)
+	announcesDropped         = metrics.GetOrCreateCounter(`headers_dropped{reason="announce_rate"}`)
+)
+
+type peerUsage struct {
+	announces    *rate.Limiter
+	lastAnnounce time.Time
+	unverified   int // Number of links delivered by the peer in the entry and insert queues
+}
+
+// SetPeerLimits replaces DefaultPeerLimits, it is to be called before the download starts
+func (hd *HeaderDownload) SetPeerLimits(limits PeerLimits) {
+	hd.lock.Lock()
+	defer hd.lock.Unlock()
+	hd.peerLimits = limits
+	hd.peerUsage = map[[64]byte]*peerUsage{}
+}
+
+func (hd *HeaderDownload) usageOf(peerID [64]byte) *peerUsage {
+	u, ok := hd.peerUsage[peerID]
+	if !ok {
+		if len(hd.peerUsage) >= peerLimitsGCThreshold {
+			hd.forgetIdlePeers()
+		}
+		u = &peerUsage{announces: rate.NewLimiter(hd.peerLimits.AnnouncesPerSecond, hd.peerLimits.AnnounceBurst)}
+		hd.peerUsage[peerID] = u
+	}
+	return u
+}
+
+// AdmitAnnounces checks that the peer doesn't announce new blocks too often, n - number of announced blocks
+func (hd *HeaderDownload) AdmitAnnounces(peerID [64]byte, n int) Penalty {
+	hd.lock.Lock()
+	defer hd.lock.Unlock()
+	u := hd.usageOf(peerID)
+	u.lastAnnounce = time.Now()
+	if !u.announces.AllowN(u.lastAnnounce, n) {
+		announcesDropped.Add(n)
+		return TooFrequentAnnouncesPenalty
+	}
+	return NoPenalty
+}
+
+// AdmitSegment checks the length of a header segment delivered by the peer before it's processed
+func (hd *HeaderDownload) AdmitSegment(length int) Penalty {
+	hd.lock.RLock()
+	defer hd.lock.RUnlock()
+	if length > hd.peerLimits.MaxSegmentLength {
+		headersDroppedTooLong.Add(length)
+		return TooLongSegmentPenalty
+	}
+	return NoPenalty
+}
+
+// bufferedQueue - whether links of the queue are counted against the limit of unverified headers of their peer
+func bufferedQueue(queueId QueueID) bool {
+	return queueId == EntryQueueID || queueId == InsertQueueID
+}
+
+// peerCanBuffer - whether one more header of the peer can be held, headers over the limit are dropped without
+// penalty: the peer may be honest and just faster than the insertion, they will be requested again later
+func (hd *HeaderDownload) peerCanBuffer(peerID [64]byte) bool {
+	if hd.usageOf(peerID).unverified < hd.peerLimits.MaxUnverified {
+		return true
+	}
+	headersDroppedUnverified.Inc()
+	return false
+}
+
+func (hd *HeaderDownload) trackBufferedLink(link *Link, from, to QueueID) {
+	if bufferedQueue(from) == bufferedQueue(to) {
+		return
+	}
+	if bufferedQueue(to) {
+		hd.usageOf(link.peerID).unverified++
+		return
+	}
+	hd.releaseBufferedLink(link)
+}
+
+func (hd *HeaderDownload) releaseBufferedLink(link *Link) {
+	if u, ok := hd.peerUsage[link.peerID]; ok {
+		u.unverified--
+	}
+}
+
+// forgetIdlePeers - peers without buffered headers and with full announce allowance are indistinguishable from new ones
+func (hd *HeaderDownload) forgetIdlePeers() {
+	refill := time.Duration(float64(hd.peerLimits.AnnounceBurst) / float64(hd.peerLimits.AnnouncesPerSecond) * float64(time.Second))
+	for peerID, u := range hd.peerUsage {
+		if u.unverified <= 0 && time.Since(u.lastAnnounce) >= refill {
+			delete(hd.peerUsage, peerID)
+		}
+	}
+}
@@ -147,6 +147,9 @@ func (ms *MockSentry) SendMessageToAll(_ context.Context, r *proto_sentry.Outbou
 func (ms *MockSentry) SentMessage(i int) *proto_sentry.OutboundMessageData {
 	return ms.sentMessages[i]
 }
+func (ms *MockSentry) SentMessageCount() int {
+	return len(ms.sentMessages)
+}
 
 func (ms *MockSentry) Messages(req *proto_sentry.MessagesRequest, stream proto_sentry.Sentry_MessagesServer) error {
 	if ms.streams == nil {
@@ -205,7 +208,7 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 	dirs := datadir.New(tmpdir)
 	var err error
 
-	db := memdb.New()
+	db := rawdb.NewMemDB()
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
 	erigonGrpcServeer := remotedbserver.NewKvServer(ctx, db, nil, nil)
@@ -360,7 +363,8 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 				blockReader,
 				dirs.Tmp,
 				mock.Notifications,
-				engineapi.NewForkValidatorMock(1)),
+				engineapi.NewForkValidatorMock(1),
+				headerdownload.EngineForkChoice(mock.Engine)),
 			stagedsync.StageCumulativeIndexCfg(mock.DB),
 			stagedsync.StageBlockHashesCfg(mock.DB, mock.Dirs.Tmp, mock.ChainConfig),
 			stagedsync.StageBodiesCfg(mock.DB, mock.sentriesClient.Bd, sendBodyRequest, penalize, blockPropagator, cfg.Sync.BodyDownloadTimeoutSeconds, *mock.ChainConfig, cfg.BatchSize, allSnapshots, blockReader, cfg.HistoryV3),
@@ -384,11 +388,15 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 				mock.gspec,
 				1,
 				mock.agg,
+				nil,
+				nil,
 			),
+			stagedsync.StageExternalExecCfg(mock.DB, nil, blockReader, mock.sentriesClient.Hd, true),
 			stagedsync.StageHashStateCfg(mock.DB, mock.Dirs, cfg.HistoryV3, mock.agg),
-			stagedsync.StageTrieCfg(mock.DB, true, true, false, dirs.Tmp, blockReader, nil, cfg.HistoryV3, mock.agg),
+			stagedsync.StageTrieCfg(mock.DB, true, true, false, dirs, blockReader, nil, cfg.HistoryV3, mock.agg),
 			stagedsync.StageHistoryCfg(mock.DB, prune, dirs.Tmp),
 			stagedsync.StageLogIndexCfg(mock.DB, prune, dirs.Tmp),
+			stagedsync.StageTokenTransfersCfg(mock.DB, prune, dirs.Tmp),
 			stagedsync.StageCallTracesCfg(mock.DB, prune, 0, dirs.Tmp),
 			stagedsync.StageTxLookupCfg(mock.DB, prune, dirs.Tmp, allSnapshots, isBor, sprint),
 			stagedsync.StageFinishCfg(mock.DB, dirs.Tmp, nil),
@@ -418,7 +426,7 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 			stagedsync.StageMiningCreateBlockCfg(mock.DB, miner, *mock.ChainConfig, mock.Engine, mock.TxPool, nil, nil, dirs.Tmp),
 			stagedsync.StageMiningExecCfg(mock.DB, miner, nil, *mock.ChainConfig, mock.Engine, &vm.Config{}, dirs.Tmp, nil, 0),
 			stagedsync.StageHashStateCfg(mock.DB, dirs, cfg.HistoryV3, mock.agg),
-			stagedsync.StageTrieCfg(mock.DB, false, true, false, dirs.Tmp, blockReader, nil, cfg.HistoryV3, mock.agg),
+			stagedsync.StageTrieCfg(mock.DB, false, true, false, dirs, blockReader, nil, cfg.HistoryV3, mock.agg),
 			stagedsync.StageMiningFinishCfg(mock.DB, *mock.ChainConfig, mock.Engine, miner, miningCancel),
 		),
 		stagedsync.MiningUnwindOrder,
@@ -519,19 +527,25 @@ func (ms *MockSentry) insertPoWBlocks(chain *core.ChainPack) error {
 		}
 	}
 
-	// Send all the headers
-	b, err = rlp.EncodeToBytes(&eth.BlockHeadersPacket66{
-		RequestId:          1,
-		BlockHeadersPacket: chain.Headers[0:n],
-	})
-	if err != nil {
-		return err
-	}
-	ms.ReceiveWg.Add(1)
-	for _, err = range ms.Send(&proto_sentry.InboundMessage{Id: proto_sentry.MessageId_BLOCK_HEADERS_66, Data: b, PeerId: ms.PeerId}) {
+	// Send all the headers, in segments not longer than a peer is allowed to deliver
+	for from := 0; from < n; from += headerdownload.DefaultPeerLimits.MaxSegmentLength {
+		to := from + headerdownload.DefaultPeerLimits.MaxSegmentLength
+		if to > n {
+			to = n
+		}
+		b, err = rlp.EncodeToBytes(&eth.BlockHeadersPacket66{
+			RequestId:          1,
+			BlockHeadersPacket: chain.Headers[from:to],
+		})
 		if err != nil {
 			return err
 		}
+		ms.ReceiveWg.Add(1)
+		for _, err = range ms.Send(&proto_sentry.InboundMessage{Id: proto_sentry.MessageId_BLOCK_HEADERS_66, Data: b, PeerId: ms.PeerId}) {
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Send all the bodies
@@ -19,15 +19,18 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	state2 "github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/exporter"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/engineapi"
+	"github.com/ledgerwatch/erigon/turbo/remoteexec"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/shards"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
@@ -77,6 +80,9 @@ func StageLoop(
 ) {
 	defer close(waitForDone)
 	initialCycle := true
+	if err := sync.RepairUnwindMarker(db); err != nil {
+		log.Warn("Failed to repair unwind marker", "err", err)
+	}
 
 	for {
 		start := time.Now()
@@ -237,8 +243,10 @@ func StageLoopStep(
 
 				notifications.Accumulator.SendAndReset(ctx, notifications.StateChangesConsumer, pendingBaseFee.Uint64(), header.GasLimit)
 
-				if err = stagedsync.NotifyNewHeaders(ctx, finishProgressBefore, head, sync.PrevUnwindPoint(), notifications.Events, rotx); err != nil {
-					return headBlockHash, nil
+				if !notifications.HeadersWatched {
+					if err = stagedsync.NotifyNewHeaders(ctx, finishProgressBefore, head, sync.PrevUnwindPoint(), notifications.Events, rotx, db); err != nil {
+						return headBlockHash, nil
+					}
 				}
 			}
 		}
@@ -382,6 +390,8 @@ func NewStagedSync(ctx context.Context,
 	snapshots *snapshotsync.RoSnapshots,
 	agg *state.Aggregator22,
 	forkValidator *engineapi.ForkValidator,
+	exporter *exporter.Exporter,
+	recentStates *state2.RecentStates,
 ) (*stagedsync.Sync, error) {
 	dirs := cfg.Dirs
 	var blockReader services.FullBlockReader
@@ -391,6 +401,14 @@ func NewStagedSync(ctx context.Context,
 		blockReader = snapshotsync.NewBlockReader()
 	}
 	blockRetire := snapshotsync.NewBlockRetire(1, dirs.Tmp, snapshots, db, snapDownloader, notifications.Events)
+	var externalExecutor stagedsync.ExternalExecutor
+	if cfg.Sync.Mode == stages.ModeExternal {
+		executor, err := remoteexec.Dial(ctx, cfg.Sync.ExternalExecAddr)
+		if err != nil {
+			return nil, err
+		}
+		externalExecutor = executor
+	}
 
 	// During Import we don't want other services like header requests, body requests etc. to be running.
 	// Hence we run it in the test mode.
@@ -401,7 +419,7 @@ func NewStagedSync(ctx context.Context,
 		sprint = controlServer.ChainConfig.Bor.Sprint
 	}
 
-	return stagedsync.New(
+	sync := stagedsync.New(
 		stagedsync.DefaultStages(ctx, cfg.Prune,
 			stagedsync.StageSnapshotsCfg(db, *controlServer.ChainConfig, dirs, snapshots, blockRetire, snapDownloader, blockReader, notifications.Events, cfg.HistoryV3, agg),
 			stagedsync.StageHeadersCfg(
@@ -418,7 +436,8 @@ func NewStagedSync(ctx context.Context,
 				blockReader,
 				dirs.Tmp,
 				notifications,
-				forkValidator),
+				forkValidator,
+				headerdownload.EngineForkChoice(controlServer.Engine)),
 			stagedsync.StageCumulativeIndexCfg(db),
 			stagedsync.StageBlockHashesCfg(db, dirs.Tmp, controlServer.ChainConfig),
 			stagedsync.StageBodiesCfg(db, controlServer.Bd, controlServer.SendBodyRequest, controlServer.Penalize, controlServer.BroadcastNewBlock, cfg.Sync.BodyDownloadTimeoutSeconds, *controlServer.ChainConfig, cfg.BatchSize, snapshots, blockReader, cfg.HistoryV3),
@@ -442,17 +461,23 @@ func NewStagedSync(ctx context.Context,
 				cfg.Genesis,
 				cfg.Sync.ExecWorkerCount,
 				agg,
+				exporter,
+				recentStates,
 			),
+			stagedsync.StageExternalExecCfg(db, externalExecutor, blockReader, controlServer.Hd, false),
 			stagedsync.StageHashStateCfg(db, dirs, cfg.HistoryV3, agg),
-			stagedsync.StageTrieCfg(db, true, true, false, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV3, agg),
+			stagedsync.StageTrieCfg(db, true, true, false, dirs, blockReader, controlServer.Hd, cfg.HistoryV3, agg),
 			stagedsync.StageHistoryCfg(db, cfg.Prune, dirs.Tmp),
 			stagedsync.StageLogIndexCfg(db, cfg.Prune, dirs.Tmp),
+			stagedsync.StageTokenTransfersCfg(db, cfg.Prune, dirs.Tmp),
 			stagedsync.StageCallTracesCfg(db, cfg.Prune, 0, dirs.Tmp),
 			stagedsync.StageTxLookupCfg(db, cfg.Prune, dirs.Tmp, snapshots, isBor, sprint),
 			stagedsync.StageFinishCfg(db, dirs.Tmp, forkValidator), runInTestMode),
 		stagedsync.DefaultUnwindOrder,
 		stagedsync.DefaultPruneOrder,
-	), nil
+	)
+	sync.SetMode(cfg.Sync.Mode)
+	return sync, nil
 }
 
 func NewInMemoryExecution(ctx context.Context, db kv.RwDB, cfg *ethconfig.Config, controlServer *sentry.MultiClient, dirs datadir.Dirs, notifications *shards.Notifications, snapshots *snapshotsync.RoSnapshots, agg *state.Aggregator22) (*stagedsync.Sync, error) {
@@ -478,7 +503,7 @@ func NewInMemoryExecution(ctx context.Context, db kv.RwDB, cfg *ethconfig.Config
 				snapshots,
 				blockReader,
 				dirs.Tmp,
-				nil, nil,
+				nil, nil, headerdownload.EngineForkChoice(controlServer.Engine),
 			),
 			stagedsync.StageBodiesCfg(db, controlServer.Bd, controlServer.SendBodyRequest, controlServer.Penalize, controlServer.BroadcastNewBlock, cfg.Sync.BodyDownloadTimeoutSeconds, *controlServer.ChainConfig, cfg.BatchSize, snapshots, blockReader, cfg.HistoryV3),
 			stagedsync.StageBlockHashesCfg(db, dirs.Tmp, controlServer.ChainConfig),
@@ -501,9 +526,11 @@ func NewInMemoryExecution(ctx context.Context, db kv.RwDB, cfg *ethconfig.Config
 				cfg.Genesis,
 				cfg.Sync.ExecWorkerCount,
 				agg,
+				nil,
+				nil,
 			),
 			stagedsync.StageHashStateCfg(db, dirs, cfg.HistoryV3, agg),
-			stagedsync.StageTrieCfg(db, true, true, true, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV3, agg)),
+			stagedsync.StageTrieCfg(db, true, true, true, dirs, blockReader, controlServer.Hd, cfg.HistoryV3, agg)),
 		stagedsync.StateUnwindOrder,
 		nil,
 	), nil
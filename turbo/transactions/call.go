@@ -7,7 +7,6 @@ import (
 
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/kv"
-	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/state"
@@ -17,7 +16,6 @@ import (
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/services"
-	"github.com/ledgerwatch/log/v3"
 )
 
 func DoCall(
@@ -28,7 +26,7 @@ func DoCall(
 	gasCap uint64,
 	chainConfig *params.ChainConfig,
 	stateReader state.StateReader,
-	headerReader services.HeaderReader, callTimeout time.Duration,
+	headerReader services.HeaderAndCanonicalReader, callTimeout time.Duration,
 ) (*core.ExecutionResult, error) {
 	// todo: Pending state is only known by the miner
 	/*
@@ -75,7 +73,7 @@ func DoCall(
 	if err != nil {
 		return nil, err
 	}
-	blockCtx, txCtx := GetEvmContext(msg, header, blockNrOrHash.RequireCanonical, tx, headerReader)
+	blockCtx, txCtx := GetEvmContext(msg, header, tx, headerReader)
 
 	evm := vm.NewEVM(blockCtx, txCtx, state, chainConfig, vm.Config{NoBaseFee: true})
 
@@ -99,7 +97,28 @@ func DoCall(
 	return result, nil
 }
 
-func GetEvmContext(msg core.Message, header *types.Header, requireCanonical bool, tx kv.Tx, headerReader services.HeaderReader) (vm.BlockContext, vm.TxContext) {
+// ApplyMessage is core.ApplyMessage which stops when ctx is done: execution of a cancelled or timed out RPC request is
+// interrupted within a thousand EVM steps instead of running to the end, and ctx.Err() is returned.
+func ApplyMessage(ctx context.Context, evm *vm.EVM, msg core.Message, gp *core.GasPool, refunds bool, gasBailout bool) (*core.ExecutionResult, error) {
+	if ctx.Done() != nil {
+		finished := make(chan struct{})
+		defer close(finished)
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-finished:
+			}
+		}()
+	}
+	result, err := core.ApplyMessage(evm, msg, gp, refunds, gasBailout)
+	if ctx.Err() != nil && evm.Cancelled() {
+		return nil, ctx.Err()
+	}
+	return result, err
+}
+
+func GetEvmContext(msg core.Message, header *types.Header, tx kv.Tx, headerReader services.HeaderAndCanonicalReader) (vm.BlockContext, vm.TxContext) {
 	var baseFee uint256.Int
 	if header.Eip1559 {
 		overflow := baseFee.SetFromBig(header.BaseFee)
@@ -107,20 +126,10 @@ func GetEvmContext(msg core.Message, header *types.Header, requireCanonical bool
 			panic(fmt.Errorf("header.BaseFee higher than 2^256-1"))
 		}
 	}
-	return core.NewEVMBlockContext(header, getHashGetter(requireCanonical, tx, headerReader), ethash.NewFaker() /* TODO Discover correcrt engine type */, nil /* author */),
+	blockHashes := core.NewCanonicalBlockHashes(context.Background(), tx, headerReader)
+	return core.NewEVMBlockContext(header, core.BlockHashFn(header, blockHashes), ethash.NewFaker() /* TODO Discover correcrt engine type */, nil /* author */),
 		vm.TxContext{
 			Origin:   msg.From(),
 			GasPrice: msg.GasPrice().ToBig(),
 		}
 }
-
-func getHashGetter(requireCanonical bool, tx kv.Tx, headerReader services.HeaderReader) func(uint64) common.Hash {
-	return func(n uint64) common.Hash {
-		h, err := headerReader.HeaderByNumber(context.Background(), tx, n)
-		if err != nil {
-			log.Error("Can't get block hash by number", "number", n, "only-canonical", requireCanonical)
-			return common.Hash{}
-		}
-		return h.Hash()
-	}
-}
@@ -0,0 +1,47 @@
+package transactions
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+func TestApplyMessageCancelled(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	ibs := state.New(state.NewPlainStateReader(tx))
+	loop := common.HexToAddress("0x100")
+	ibs.SetCode(loop, []byte{byte(vm.JUMPDEST), byte(vm.PUSH1), 0, byte(vm.JUMP)})
+
+	blockCtx := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		GasLimit:    math.MaxUint64,
+		Difficulty:  new(big.Int),
+	}
+	evm := vm.NewEVM(blockCtx, vm.TxContext{}, ibs, params.MainnetChainConfig, vm.Config{})
+	msg := types.NewMessage(common.Address{}, &loop, 0, new(uint256.Int), math.MaxUint64/2, new(uint256.Int), nil, nil, nil, nil, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := ApplyMessage(ctx, evm, msg, new(core.GasPool).AddGas(msg.Gas()), true, false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("execution wasn't interrupted, took %v", elapsed)
+	}
+}
@@ -0,0 +1,62 @@
+package transactions
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// structLogsMemoryLimit - struct logs of a traced transaction above this size are kept in a temporary file
+const structLogsMemoryLimit = 32 * 1024 * 1024
+
+// spillBuffer keeps written data in memory up to the limit, the rest goes to a temporary file.
+// Struct logs of gas-heavy transactions (with memory and stack of every step) are gigabytes.
+type spillBuffer struct {
+	dir   string
+	limit int
+	mem   bytes.Buffer
+	file  *os.File
+}
+
+func newSpillBuffer(dir string, limit int) *spillBuffer {
+	return &spillBuffer{dir: dir, limit: limit}
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && b.mem.Len()+len(p) <= b.limit {
+		return b.mem.Write(p)
+	}
+	if b.file == nil {
+		f, err := os.CreateTemp(b.dir, "erigon-structlogs-*")
+		if err != nil {
+			return 0, err
+		}
+		b.file = f
+	}
+	return b.file.Write(p)
+}
+
+// WriteTo writes all data in the order it was written, the buffer must not be written after it
+func (b *spillBuffer) WriteTo(w io.Writer) (int64, error) {
+	n, err := b.mem.WriteTo(w)
+	if err != nil || b.file == nil {
+		return n, err
+	}
+	if _, err = b.file.Seek(0, io.SeekStart); err != nil {
+		return n, err
+	}
+	m, err := io.CopyBuffer(w, b.file, make([]byte, 256*1024))
+	return n + m, err
+}
+
+// Close removes the temporary file
+func (b *spillBuffer) Close() error {
+	b.mem = bytes.Buffer{}
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	b.file.Close()
+	b.file = nil
+	return os.Remove(name)
+}
@@ -0,0 +1,31 @@
+package transactions
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	dir := t.TempDir()
+	b := newSpillBuffer(dir, 8)
+	for _, s := range []string{"abc", "defgh", "ijk", "lmnopqrstu"} {
+		_, err := b.Write([]byte(s))
+		require.NoError(t, err)
+	}
+	require.Equal(t, "abcdefgh", b.mem.String())
+	require.NotNil(t, b.file)
+
+	var out bytes.Buffer
+	n, err := b.WriteTo(&out)
+	require.NoError(t, err)
+	require.Equal(t, int64(21), n)
+	require.Equal(t, "abcdefghijklmnopqrstu", out.String())
+
+	require.NoError(t, b.Close())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"os"
 	"sort"
 	"time"
 
@@ -20,6 +21,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/tracers"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
 type BlockGetter interface {
@@ -31,8 +33,13 @@ type BlockGetter interface {
 }
 
 // ComputeTxEnv returns the execution environment of a certain transaction.
-func ComputeTxEnv(ctx context.Context, block *types.Block, cfg *params.ChainConfig, getHeader func(hash common.Hash, number uint64) *types.Header, engine consensus.Engine, dbtx kv.Tx, blockHash common.Hash, txIndex uint64) (core.Message, vm.BlockContext, vm.TxContext, *state.IntraBlockState, *state.PlainState, error) {
+func ComputeTxEnv(ctx context.Context, block *types.Block, cfg *params.ChainConfig, blockHashes core.BlockHashReader, engine consensus.Engine, dbtx kv.Tx, blockHash common.Hash, txIndex uint64) (core.Message, vm.BlockContext, vm.TxContext, *state.IntraBlockState, *state.PlainState, error) {
 	// Create the parent state database
+	if block.NumberU64() > 0 {
+		if err := rpchelper.CheckHistoryNotPruned(dbtx, block.NumberU64()-1); err != nil {
+			return nil, vm.BlockContext{}, vm.TxContext{}, nil, nil, err
+		}
+	}
 	reader := state.NewPlainState(dbtx, block.NumberU64())
 	statedb := state.New(reader)
 
@@ -43,7 +50,7 @@ func ComputeTxEnv(ctx context.Context, block *types.Block, cfg *params.ChainConf
 	signer := types.MakeSigner(cfg, block.NumberU64())
 
 	header := block.Header()
-	BlockContext := core.NewEVMBlockContext(header, core.GetHashFn(header, getHeader), engine, nil)
+	BlockContext := core.NewEVMBlockContext(header, core.BlockHashFn(header, blockHashes), engine, nil)
 	vmenv := vm.NewEVM(BlockContext, vm.TxContext{}, statedb, cfg, vm.Config{})
 	rules := vmenv.ChainRules()
 	for idx, tx := range block.Transactions() {
@@ -62,7 +69,7 @@ func ComputeTxEnv(ctx context.Context, block *types.Block, cfg *params.ChainConf
 		}
 		vmenv.Reset(TxContext, statedb)
 		// Not yet the searched for transaction, execute on top of the current state
-		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.GetGas()), true /* refunds */, false /* gasBailout */); err != nil {
+		if _, err := ApplyMessage(ctx, vmenv, msg, new(core.GasPool).AddGas(tx.GetGas()), true /* refunds */, false /* gasBailout */); err != nil {
 			return nil, vm.BlockContext{}, vm.TxContext{}, nil, nil, fmt.Errorf("transaction %x failed: %w", tx.Hash(), err)
 		}
 		// Ensure any modifications are committed to the state
@@ -91,12 +98,16 @@ func TraceTx(
 	stream *jsoniter.Stream,
 	callTimeout time.Duration,
 ) error {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger or the native or JavaScript tracer
 	var (
 		tracer vm.Tracer
 		err    error
 	)
 	var streaming bool
+	// struct logs are written aside while the transaction is executed and copied into the response after it:
+	// memory of the tracer is bounded, and slow client doesn't hold execution (and its db transaction)
+	var structLogs *spillBuffer
+	var structLogsStream *jsoniter.Stream
 	switch {
 	case config != nil && config.Tracer != nil:
 		// Define a meaningful timeout of a single transaction trace
@@ -107,10 +118,10 @@ func TraceTx(
 				return err
 			}
 		}
-		// Construct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer, &tracers.Context{
+		// Construct the native or JavaScript tracer to execute with
+		if tracer, err = tracers.NewTracer(*config.Tracer, &tracers.Context{
 			TxHash: txCtx.TxHash,
-		}); err != nil {
+		}, config.TracerConfig); err != nil {
 			stream.WriteNil()
 			return err
 		}
@@ -118,17 +129,20 @@ func TraceTx(
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			tracer.(tracers.ResultTracer).Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 		streaming = false
 
-	case config == nil:
-		tracer = NewJsonStreamLogger(nil, ctx, stream)
-		streaming = true
-
 	default:
-		tracer = NewJsonStreamLogger(config.LogConfig, ctx, stream)
+		structLogs = newSpillBuffer(os.TempDir(), structLogsMemoryLimit)
+		defer structLogs.Close()
+		structLogsStream = jsoniter.NewStream(jsoniter.ConfigDefault, structLogs, 4096)
+		var logConfig *vm.LogConfig
+		if config != nil {
+			logConfig = config.LogConfig
+		}
+		tracer = NewJsonStreamLogger(logConfig, ctx, structLogsStream)
 		streaming = true
 	}
 	// Run the transaction with tracing enabled.
@@ -137,12 +151,24 @@ func TraceTx(
 	if config != nil && config.NoRefunds != nil && *config.NoRefunds {
 		refunds = false
 	}
+	if t, ok := tracer.(tracers.TxStartTracer); ok {
+		t.CaptureTxStart(ibs, message.From(), message.To(), blockCtx.Coinbase)
+	}
+	result, err := ApplyMessage(ctx, vmenv, message, new(core.GasPool).AddGas(message.Gas()), refunds, false /* gasBailout */)
 	if streaming {
+		if flushErr := structLogsStream.Flush(); flushErr != nil && err == nil {
+			err = fmt.Errorf("writing struct logs: %w", flushErr)
+		}
 		stream.WriteObjectStart()
 		stream.WriteObjectField("structLogs")
 		stream.WriteArrayStart()
+		if err == nil {
+			_ = stream.Flush()
+			if _, err = structLogs.WriteTo(stream); err != nil {
+				err = fmt.Errorf("writing struct logs: %w", err)
+			}
+		}
 	}
-	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()), refunds, false /* gasBailout */)
 	if err != nil {
 		if streaming {
 			stream.WriteArrayEnd()
@@ -169,7 +195,7 @@ func TraceTx(
 		stream.WriteString(returnVal)
 		stream.WriteObjectEnd()
 	} else {
-		if r, err1 := tracer.(*tracers.Tracer).GetResult(); err1 == nil {
+		if r, err1 := tracer.(tracers.ResultTracer).GetResult(); err1 == nil {
 			stream.Write(r)
 		} else {
 			return err1
@@ -192,7 +218,7 @@ type JsonStreamLogger struct {
 
 	locations common.Hashes // For sorting
 	storage   map[common.Address]vm.Storage
-	logs      []vm.StructLog
+	steps     int    // amount of written struct logs
 	output    []byte //nolint
 	err       error  //nolint
 }
@@ -229,9 +255,10 @@ func (l *JsonStreamLogger) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, ga
 	default:
 	}
 	// check if already accumulated the specified number of logs
-	if l.cfg.Limit != 0 && l.cfg.Limit <= len(l.logs) {
+	if l.cfg.Limit != 0 && l.cfg.Limit <= l.steps {
 		return
 	}
+	l.steps++
 	if !l.firstCapture {
 		l.stream.WriteMore()
 	} else {
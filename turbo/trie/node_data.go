@@ -0,0 +1,304 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// ErrNodeTooExpensive - resolving of the node needs more db reads than allowed
+var ErrNodeTooExpensive = errors.New("trie node is too expensive to resolve")
+
+// NodePath - location of a node: nibbles of the path from the root of the accounts trie,
+// or from the root of the storage trie of the account if Storage is set
+type NodePath struct {
+	Storage     bool
+	AddrHash    common.Hash
+	Incarnation uint64
+	Nibbles     []byte
+}
+
+// NodeRef - child referenced by its hash from a resolved node: the child of a branch or extension node,
+// or the storage root of an account
+type NodeRef struct {
+	Hash common.Hash
+	Path NodePath
+}
+
+// ResolveNode builds RLP of the trie node at the given path. Erigon doesn't store trie nodes: they are built from
+// HashedAccounts/HashedStorage, hashes of subtries are taken from TrieOfAccounts/TrieOfStorage, children without
+// stored hashes are built recursively. budget - max amount of db reads. Returns nil if there is no node at the path.
+func ResolveNode(tx kv.Tx, path NodePath, budget int) (enc []byte, refs []NodeRef, err error) {
+	r := &nodeResolver{tx: tx, budget: budget}
+	if path.Storage {
+		r.base = StorageKey(path.AddrHash[:], path.Incarnation, nil)
+		if r.c, err = tx.Cursor(kv.HashedStorage); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if r.c, err = tx.Cursor(kv.HashedAccounts); err != nil {
+			return nil, nil, err
+		}
+	}
+	defer r.c.Close()
+	if r.sc, err = tx.Cursor(kv.HashedStorage); err != nil {
+		return nil, nil, err
+	}
+	defer r.sc.Close()
+	enc, refs, err = r.node(path.Nibbles, true)
+	for i := range refs {
+		if refs[i].Path.Storage {
+			continue
+		}
+		refs[i].Path.Storage, refs[i].Path.AddrHash, refs[i].Path.Incarnation = path.Storage, path.AddrHash, path.Incarnation
+	}
+	return enc, refs, err
+}
+
+type nodeResolver struct {
+	tx     kv.Tx
+	budget int
+	base   []byte    // addrHash+incarnation of storage trie, nil for accounts trie
+	c      kv.Cursor // HashedAccounts or HashedStorage
+	sc     kv.Cursor // HashedStorage, for storage roots of accounts
+}
+
+func (r *nodeResolver) spend() error {
+	if r.budget--; r.budget < 0 {
+		return ErrNodeTooExpensive
+	}
+	return nil
+}
+
+// keys returns nibbles of the first and the last keys under the prefix, and the value of the first one
+func (r *nodeResolver) keys(c kv.Cursor, base, prefix []byte) (first, last, firstV []byte, err error) {
+	if err = r.spend(); err != nil {
+		return nil, nil, nil, err
+	}
+	k, v, err := c.Seek(append(common.CopyBytes(base), nibblesToKey(prefix)...))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if k == nil || !bytes.HasPrefix(k, base) || !hasNibblesPrefix(k[len(base):], prefix) {
+		return nil, nil, nil, nil
+	}
+	first, firstV = keyToNibbles(k[len(base):]), common.CopyBytes(v)
+
+	if err = r.spend(); err != nil {
+		return nil, nil, nil, err
+	}
+	var after []byte // the smallest key after all keys under the prefix
+	if next := nextNibbles(prefix); next != nil {
+		after = append(common.CopyBytes(base), nibblesToKey(next)...)
+	} else if base != nil {
+		after = nextKey(base)
+	}
+	k = nil
+	if after != nil {
+		if k, _, err = c.Seek(after); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if k == nil {
+		k, _, err = c.Last()
+	} else {
+		k, _, err = c.Prev()
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return first, keyToNibbles(k[len(base):]), firstV, nil
+}
+
+func (r *nodeResolver) node(prefix []byte, withRefs bool) (enc []byte, refs []NodeRef, err error) {
+	first, last, firstV, err := r.keys(r.c, r.base, prefix)
+	if err != nil || first == nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(first, last) {
+		return r.leaf(first, prefix, firstV, withRefs)
+	}
+	if cp := prefixLen(first, last); cp > len(prefix) { // extension
+		child, err := r.ref(first[:cp])
+		if err != nil {
+			return nil, nil, err
+		}
+		var item interface{} = rlp.RawValue(child)
+		if len(child) == common.HashLength {
+			item = child
+			if withRefs {
+				refs = append(refs, NodeRef{Hash: common.BytesToHash(child), Path: NodePath{Nibbles: common.CopyBytes(first[:cp])}})
+			}
+		}
+		enc, err = rlp.EncodeToBytes([]interface{}{hexToCompact(first[len(prefix):cp]), item})
+		return enc, refs, err
+	}
+
+	// branch
+	if err = r.spend(); err != nil {
+		return nil, nil, err
+	}
+	var hasState, hasHash uint16
+	var hashes []byte
+	table := kv.TrieOfAccounts
+	if r.base != nil {
+		table = kv.TrieOfStorage
+	}
+	ih, err := r.tx.GetOne(table, append(common.CopyBytes(r.base), prefix...))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ih) > 0 {
+		hasState, _, hasHash, hashes, _ = UnmarshalTrieNode(ih)
+	}
+	items := make([]interface{}, 17)
+	for i := 0; i < 16; i++ {
+		items[i] = []byte{}
+		childPath := append(common.CopyBytes(prefix), byte(i))
+		var child []byte
+		switch {
+		case hasHash&(1<<i) != 0:
+			hashID := bits.OnesCount16(hasHash & (1<<i - 1))
+			child = hashes[hashID*common.HashLength : (hashID+1)*common.HashLength]
+		case len(ih) > 0 && hasState&(1<<i) == 0:
+			continue
+		default:
+			if child, err = r.ref(childPath); err != nil {
+				return nil, nil, err
+			}
+		}
+		if child == nil {
+			continue
+		}
+		if len(child) == common.HashLength {
+			items[i] = child
+			if withRefs {
+				refs = append(refs, NodeRef{Hash: common.BytesToHash(child), Path: NodePath{Nibbles: childPath}})
+			}
+		} else {
+			items[i] = rlp.RawValue(child)
+		}
+	}
+	items[16] = []byte{}
+	enc, err = rlp.EncodeToBytes(items)
+	return enc, refs, err
+}
+
+// ref - how the node is referenced by its parent: hash, or the node itself if it is shorter than a hash
+func (r *nodeResolver) ref(prefix []byte) ([]byte, error) {
+	enc, _, err := r.node(prefix, false)
+	if err != nil || enc == nil {
+		return nil, err
+	}
+	if len(enc) < common.HashLength {
+		return enc, nil
+	}
+	return crypto.Keccak256(enc), nil
+}
+
+func (r *nodeResolver) leaf(key, prefix, v []byte, withRefs bool) (enc []byte, refs []NodeRef, err error) {
+	var value []byte
+	if r.base != nil {
+		if value, err = rlp.EncodeToBytes(v); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var acc accounts.Account
+		if err = acc.DecodeForStorage(v); err != nil {
+			return nil, nil, err
+		}
+		addrHash := common.BytesToHash(nibblesToKey(key))
+		if acc.Incarnation > 0 {
+			if acc.Root, err = r.storageRoot(addrHash, acc.Incarnation); err != nil {
+				return nil, nil, err
+			}
+		}
+		if withRefs && acc.Root != EmptyRoot {
+			refs = append(refs, NodeRef{Hash: acc.Root, Path: NodePath{Storage: true, AddrHash: addrHash, Incarnation: acc.Incarnation}})
+		}
+		value = make([]byte, acc.EncodingLengthForHashing())
+		acc.EncodeForHashing(value)
+	}
+	enc, err = rlp.EncodeToBytes([]interface{}{hexToCompact(append(common.CopyBytes(key[len(prefix):]), 16)), value})
+	return enc, refs, err
+}
+
+func (r *nodeResolver) storageRoot(addrHash common.Hash, incarnation uint64) (common.Hash, error) {
+	if err := r.spend(); err != nil {
+		return common.Hash{}, err
+	}
+	base := StorageKey(addrHash[:], incarnation, nil)
+	ih, err := r.tx.GetOne(kv.TrieOfStorage, base)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(ih) > 0 {
+		if _, _, _, _, root := UnmarshalTrieNode(ih); len(root) > 0 {
+			return common.BytesToHash(root), nil
+		}
+	}
+	storage := &nodeResolver{tx: r.tx, budget: r.budget, base: base, c: r.sc, sc: r.sc}
+	enc, _, err := storage.node(nil, false)
+	r.budget = storage.budget
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if enc == nil {
+		return EmptyRoot, nil
+	}
+	return crypto.Keccak256Hash(enc), nil
+}
+
+func keyToNibbles(k []byte) []byte {
+	nibbles := keybytesToHex(k)
+	return nibbles[:len(nibbles)-1]
+}
+
+func nibblesToKey(nibbles []byte) []byte {
+	k := make([]byte, (len(nibbles)+1)/2)
+	decodeNibbles(nibbles, k)
+	return k
+}
+
+func hasNibblesPrefix(k, prefix []byte) bool {
+	for i, n := range prefix {
+		b := k[i/2]
+		if i%2 == 0 {
+			b >>= 4
+		}
+		if b&0x0f != n {
+			return false
+		}
+	}
+	return true
+}
+
+// nextNibbles - the smallest nibbles which are bigger than all nibbles with the prefix, nil if there are no such
+func nextNibbles(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0x0f {
+			next := common.CopyBytes(prefix[:i+1])
+			next[i]++
+			return next
+		}
+	}
+	return nil
+}
+
+// nextKey - the smallest key which is bigger than all keys with the prefix, nil if there are no such
+func nextKey(prefix []byte) []byte {
+	next := common.CopyBytes(prefix)
+	for i := len(next) - 1; i >= 0; i-- {
+		if next[i] < 0xff {
+			next[i]++
+			return next[:i+1]
+		}
+	}
+	return nil
+}
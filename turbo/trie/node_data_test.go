@@ -0,0 +1,97 @@
+package trie
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveNode(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+	rnd := rand.New(rand.NewSource(1))
+	randHash := func() (h common.Hash) {
+		rnd.Read(h[:])
+		return h
+	}
+	for i := 0; i < 2000; i++ {
+		acc := accounts.NewAccount()
+		acc.Nonce = uint64(i)
+		acc.Balance.SetUint64(uint64(i) * 1000)
+		slots := 0
+		if i%10 == 0 {
+			acc.Incarnation, acc.CodeHash = 1, randHash()
+			slots = i % 7
+		}
+		if i == 1000 {
+			slots = 500
+		}
+		addrHash := randHash()
+		v := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(v)
+		require.NoError(tx.Put(kv.HashedAccounts, addrHash[:], v))
+		for j := 0; j < slots; j++ {
+			loc := randHash()
+			val := uint256.NewInt(uint64(j + 1))
+			require.NoError(tx.Put(kv.HashedStorage, StorageKey(addrHash[:], acc.Incarnation, loc[:]), val.Bytes()))
+		}
+	}
+
+	// intermediate hashes, filtered the same way as IntermediateHashes stage does
+	accIH, storageIH := map[string][]byte{}, map[string][]byte{}
+	loader := NewFlatDBTrieLoader("test")
+	require.NoError(loader.Reset(NewRetainList(0), func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, _ []byte) error {
+		if len(keyHex) > 0 && hasState != 0 {
+			accIH[string(keyHex)] = MarshalTrieNode(hasState, hasTree, hasHash, hashes, nil, make([]byte, 1024))
+		}
+		return nil
+	}, func(accWithInc []byte, keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+		if hasState == 0 || (len(keyHex) > 0 && hasHash == 0 && hasTree == 0) {
+			return nil
+		}
+		storageIH[string(accWithInc)+string(keyHex)] = MarshalTrieNode(hasState, hasTree, hasHash, hashes, rootHash, make([]byte, 1024))
+		return nil
+	}, false))
+	root, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(err)
+	require.NotEmpty(accIH)
+	require.NotEmpty(storageIH)
+	for k, v := range accIH {
+		require.NoError(tx.Put(kv.TrieOfAccounts, []byte(k), v))
+	}
+	for k, v := range storageIH {
+		require.NoError(tx.Put(kv.TrieOfStorage, []byte(k), v))
+	}
+
+	// every node is reachable from the root and matches the hash it is referenced by
+	queue := []NodeRef{{Hash: root}}
+	var accountNodes, storageNodes int
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+		enc, refs, err := ResolveNode(tx, ref.Path, 1_000_000)
+		require.NoError(err)
+		require.Equal(ref.Hash, crypto.Keccak256Hash(enc), "%+v", ref.Path)
+		if ref.Path.Storage {
+			storageNodes++
+		} else {
+			accountNodes++
+		}
+		queue = append(queue, refs...)
+	}
+	require.Greater(accountNodes, 2000)
+	require.Greater(storageNodes, 500)
+
+	_, _, err = ResolveNode(tx, NodePath{}, 10)
+	require.ErrorIs(err, ErrNodeTooExpensive)
+	enc, _, err := ResolveNode(tx, NodePath{Storage: true, AddrHash: randHash(), Incarnation: 1}, 10)
+	require.NoError(err)
+	require.Nil(enc)
+}
@@ -16,7 +16,7 @@ import (
 // with the node that proves the absence of the key.
 func (t *Trie) Prove(key []byte, fromLevel int, storage bool) ([][]byte, error) {
 	var proof [][]byte
-	hasher := newHasher(false)
+	hasher := t.newHasherFunc()
 	defer returnHasherToPool(hasher)
 	// Collect all nodes on the path to key.
 	key = keybytesToHex(key)
@@ -93,5 +93,14 @@ func (t *Trie) Prove(key []byte, fromLevel int, storage bool) ([][]byte, error)
 			panic(fmt.Sprintf("%T: invalid node: %v", tn, tn))
 		}
 	}
+	// The leaf of a key ending at a branch node has an empty path, e.g. RLP encoded
+	// index 0 (0x80) of a list with more than 128 items (0x8180...)
+	if n, ok := tn.(*shortNode); ok && len(key) == 0 && fromLevel == 0 {
+		if rlp, err := hasher.hashChildren(n, 0); err == nil {
+			proof = append(proof, common.CopyBytes(rlp))
+		} else {
+			return nil, err
+		}
+	}
 	return proof, nil
 }
@@ -122,6 +122,9 @@ type RootHashAggregator struct {
 	a              accounts.Account
 	leafData       GenStructStepLeafData
 	accData        GenStructStepAccountData
+	proofs         *RetainList // nodes on the paths to its keys are built, nil - only hashes are calculated
+	proofKey       []byte      // buffer of the nibbles of account with incarnation and storage prefix for proofs
+	rootNode       node
 }
 
 type StreamReceiver interface {
@@ -173,6 +176,27 @@ func (l *FlatDBTrieLoader) SetStreamReceiver(receiver StreamReceiver) {
 	l.receiver = receiver
 }
 
+// CalcProofTrie - calculates the root like CalcTrieRoot, but builds the nodes on the paths to the keys of rl: hashes
+// of accounts, and hashes of accounts with incarnations and hashes of storage slots. Other parts of the trie are
+// hashNode, the result is enough to call Trie.Prove for these keys. AccTrie and StorageTrie records on the paths
+// are not used, so the loader walks only the state under them.
+func (l *FlatDBTrieLoader) CalcProofTrie(tx kv.Tx, rl *RetainList, quit <-chan struct{}) (*Trie, error) {
+	if err := l.Reset(rl, nil, nil, false); err != nil {
+		return nil, err
+	}
+	l.defaultReceiver.proofs = rl
+	defer func() { l.defaultReceiver.proofs = nil }()
+	root, err := l.CalcTrieRoot(tx, nil, quit)
+	if err != nil {
+		return nil, err
+	}
+	t := New(root)
+	if l.defaultReceiver.rootNode != nil {
+		t.root = l.defaultReceiver.rootNode
+	}
+	return t, nil
+}
+
 // CalcTrieRoot algo:
 //
 //		for iterateIHOfAccounts {
@@ -327,6 +351,19 @@ func (r *RootHashAggregator) RetainNothing(_ []byte) bool {
 	return false
 }
 
+func (r *RootHashAggregator) retainAccount(prefix []byte) bool {
+	return r.proofs != nil && r.proofs.Retain(prefix)
+}
+
+func (r *RootHashAggregator) retainStorage(prefix []byte) bool {
+	if r.proofs == nil {
+		return false
+	}
+	hexutil.DecompressNibbles(r.currAccK, &r.proofKey)
+	r.proofKey = append(r.proofKey, prefix...)
+	return r.proofs.Retain(r.proofKey)
+}
+
 func (r *RootHashAggregator) Reset(hc HashCollector2, shc StorageHashCollector2, trace bool) {
 	r.hc = hc
 	r.shc = shc
@@ -344,6 +381,7 @@ func (r *RootHashAggregator) Reset(hc HashCollector2, shc StorageHashCollector2,
 	r.valueStorage = nil
 	r.wasIHStorage = false
 	r.root = common.Hash{}
+	r.rootNode = nil
 	r.trace = trace
 	r.hb.trace = trace
 }
@@ -493,6 +531,7 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		}
 		if r.hb.hasRoot() {
 			r.root = r.hb.rootHash()
+			r.rootNode = r.hb.root()
 		} else {
 			r.root = EmptyRoot
 		}
@@ -563,7 +602,7 @@ func (r *RootHashAggregator) genStructStorage() error {
 		r.leafData.Value = rlphacks.RlpSerializableBytes(r.valueStorage)
 		data = &r.leafData
 	}
-	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.RetainNothing, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.retainStorage, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.shc == nil {
 			return nil
 		}
@@ -631,7 +670,7 @@ func (r *RootHashAggregator) genStructAccount() error {
 	r.currStorage.Reset()
 	r.succStorage.Reset()
 	var err error
-	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.RetainNothing, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.retainAccount, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.hc == nil {
 			return nil
 		}
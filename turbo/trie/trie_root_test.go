@@ -0,0 +1,110 @@
+package trie
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalcProofTrie(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+
+	// proofs of the trie built in memory are expected
+	expected := New(common.Hash{})
+	storageTries := map[common.Hash]*Trie{}
+	for i := 0; i < 100; i++ {
+		addrHash := crypto.Keccak256Hash([]byte(fmt.Sprintf("account %d", i)))
+		acc := accounts.NewAccount()
+		acc.Nonce = uint64(i)
+		acc.Balance.SetUint64(uint64(i) * 1000)
+		if i%10 == 0 {
+			acc.Incarnation = 1
+			acc.CodeHash = crypto.Keccak256Hash([]byte{byte(i)})
+			st := New(common.Hash{})
+			for j := 0; j < 3*i+1; j++ {
+				locHash := crypto.Keccak256Hash([]byte(fmt.Sprintf("slot %d", j)))
+				v := uint256.NewInt(uint64(j + 1)).Bytes()
+				st.Update(locHash[:], v)
+				require.NoError(tx.Put(kv.HashedStorage, StorageKey(addrHash[:], acc.Incarnation, locHash[:]), v))
+			}
+			acc.Root = st.Hash()
+			storageTries[addrHash] = st
+		}
+		v := make([]byte, acc.EncodingLengthForStorage())
+		acc.EncodeForStorage(v)
+		require.NoError(tx.Put(kv.HashedAccounts, addrHash[:], v))
+		expected.UpdateAccount(addrHash[:], &acc)
+	}
+
+	// intermediate hashes of the state, most of the trie is loaded from them
+	accTrie, storageTrie := map[string][]byte{}, map[string][]byte{}
+	loader := NewFlatDBTrieLoader("test")
+	require.NoError(loader.Reset(NewRetainList(0), func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, _ []byte) error {
+		if len(keyHex) > 0 && hasState != 0 {
+			accTrie[string(keyHex)] = MarshalTrieNode(hasState, hasTree, hasHash, hashes, nil, make([]byte, 0, 1024))
+		}
+		return nil
+	}, func(accWithInc []byte, keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+		if hasState != 0 && (len(keyHex) == 0 || hasHash != 0 || hasTree != 0) {
+			storageTrie[string(append(common.CopyBytes(accWithInc), keyHex...))] = MarshalTrieNode(hasState, hasTree, hasHash, hashes, rootHash, make([]byte, 0, 1024))
+		}
+		return nil
+	}, false))
+	root, err := loader.CalcTrieRoot(tx, nil, nil)
+	require.NoError(err)
+	require.Equal(expected.Hash(), root)
+	require.NotEmpty(accTrie)
+	require.NotEmpty(storageTrie)
+	for k, v := range accTrie {
+		require.NoError(tx.Put(kv.TrieOfAccounts, []byte(k), v))
+	}
+	for k, v := range storageTrie {
+		require.NoError(tx.Put(kv.TrieOfStorage, []byte(k), v))
+	}
+
+	contract := crypto.Keccak256Hash([]byte("account 50"))
+	missing := crypto.Keccak256Hash([]byte("account 100"))
+	slots := []common.Hash{
+		crypto.Keccak256Hash([]byte("slot 7")),
+		crypto.Keccak256Hash([]byte("slot 1000")), // absent
+	}
+	rl := NewRetainList(0)
+	rl.AddKey(contract[:])
+	rl.AddKey(missing[:])
+	for _, slot := range slots {
+		rl.AddKey(StorageKey(contract[:], 1, slot[:]))
+	}
+	proofTrie, err := loader.CalcProofTrie(tx, rl, nil)
+	require.NoError(err)
+	require.Equal(root, proofTrie.Hash())
+
+	for _, addrHash := range []common.Hash{contract, missing} {
+		proof, err := proofTrie.Prove(addrHash[:], 0, false)
+		require.NoError(err)
+		expectedProof, err := expected.Prove(addrHash[:], 0, false)
+		require.NoError(err)
+		require.Equal(expectedProof, proof)
+	}
+	acc, ok := proofTrie.GetAccount(contract[:])
+	require.True(ok)
+	require.Equal(storageTries[contract].Hash(), acc.Root)
+	for _, slot := range slots {
+		proof, err := proofTrie.Prove(append(common.CopyBytes(contract[:]), slot[:]...), 64, true)
+		require.NoError(err)
+		expectedProof, err := storageTries[contract].Prove(slot[:], 0, false)
+		require.NoError(err)
+		require.Equal(expectedProof, proof)
+		v, ok := proofTrie.Get(append(common.CopyBytes(contract[:]), slot[:]...))
+		require.True(ok)
+		expectedV, _ := storageTries[contract].Get(slot[:])
+		require.Equal(expectedV, v)
+	}
+}